@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	kubectlnfd "github.com/openshift/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+// Options for accessing the nfd-master node query API
+var queryAPIOpts kubectlnfd.QueryAPIOptions
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show the node label changes of a NodeFeatureRule file",
+	Long: `Show which nodes would gain, lose or change which labels if a NodeFeatureRule file was applied to the cluster,
+compared to the current labels of the nodes and the NodeFeatureRule object of the same name, if any.
+Requires the node query API of nfd-master (-enable-query-api).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		changes, errs := kubectlnfd.Diff(nodefeaturerule, kubeconfig, queryAPIOpts)
+		for _, e := range errs {
+			cmd.PrintErrln(e)
+		}
+		if changes == nil {
+			os.Exit(1)
+		}
+		node := ""
+		for _, c := range changes {
+			if c.Node != node {
+				node = c.Node
+				fmt.Printf("Node %s\n", node)
+			}
+			fmt.Printf("  %s\n", c)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No node labels would change")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&nodefeaturerule, "nodefeaturerule-file", "f", "", "Path to the proposed NodeFeatureRule file")
+	diffCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "kubeconfig file to use")
+	diffCmd.Flags().StringVar(&queryAPIOpts.URL, "master-url", "", "Base URL of the nfd-master metrics server serving the node query API, e.g. https://localhost:8081")
+	diffCmd.Flags().StringVar(&queryAPIOpts.Token, "token", "", "Bearer token for the node query API. Defaults to the token of the kubeconfig")
+	diffCmd.Flags().StringVar(&queryAPIOpts.CAFile, "ca-file", "", "CA certificate for verifying the certificate of nfd-master")
+	diffCmd.Flags().BoolVar(&queryAPIOpts.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "Do not verify the certificate of nfd-master")
+	for _, f := range []string{"nodefeaturerule-file", "master-url"} {
+		if err := diffCmd.MarkFlagRequired(f); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package subcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	kubectlnfd "github.com/openshift/node-feature-discovery/pkg/kubectl-nfd"
+)
+
+var (
+	// Container image to operate on
+	image string
+	// Path to the image compatibility spec file
+	imageCompatSpec string
+	// Registry access options
+	registryOpts kubectlnfd.RegistryOptions
+)
+
+var imageCompatCmd = &cobra.Command{
+	Use:   "image-compat",
+	Short: "Manage image compatibility artifacts",
+	Long:  `Attach image compatibility specifications to container images and validate nodes against them`,
+}
+
+var imageCompatAttachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach an image compatibility spec to an image",
+	Long:  `Attach an image compatibility spec file to a container image in the registry as an OCI artifact`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := kubectlnfd.AttachImageCompat(image, imageCompatSpec, registryOpts); err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var imageCompatValidateNodeCmd = &cobra.Command{
+	Use:   "validate-node",
+	Short: "Validate a NodeFeature file against the compatibility spec of an image",
+	Long:  `Evaluate the image compatibility spec of a container image, or a spec file, against a NodeFeature file`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (image == "") == (imageCompatSpec == "") {
+			cmd.PrintErrln("exactly one of --image and --spec-file must be specified")
+			os.Exit(1)
+		}
+		results, err := kubectlnfd.ValidateNodeImageCompat(image, imageCompatSpec, nodefeature, registryOpts)
+		if err != nil {
+			cmd.PrintErrln(err)
+			os.Exit(1)
+		}
+		compatible := true
+		for _, r := range results {
+			status := "OK"
+			if !r.Matched {
+				status = "FAIL"
+				if r.Optional {
+					status = "FAIL (optional)"
+				} else {
+					compatible = false
+				}
+			}
+			fmt.Printf("%-20s %s\t%s\n", r.Name, status, r.Description)
+		}
+		if !compatible {
+			fmt.Printf("NodeFeature %q is not compatible\n", nodefeature)
+			// Return non-zero exit code to indicate failure
+			os.Exit(1)
+		}
+		fmt.Printf("NodeFeature %q is compatible\n", nodefeature)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(imageCompatCmd)
+	imageCompatCmd.AddCommand(imageCompatAttachCmd)
+	imageCompatCmd.AddCommand(imageCompatValidateNodeCmd)
+
+	imageCompatCmd.PersistentFlags().StringVar(&registryOpts.AuthFile, "registry-auth-file", "", "Path to a docker config.json style file with registry credentials")
+	imageCompatCmd.PersistentFlags().StringVar(&registryOpts.Username, "username", "", "Registry username")
+	imageCompatCmd.PersistentFlags().StringVar(&registryOpts.Password, "password", "", "Registry password")
+	imageCompatCmd.PersistentFlags().BoolVar(&registryOpts.PlainHTTP, "plain-http", false, "Use plain http to connect to the registry")
+
+	imageCompatAttachCmd.Flags().StringVarP(&image, "image", "i", "", "Container image to attach the spec to")
+	imageCompatAttachCmd.Flags().StringVarP(&imageCompatSpec, "spec-file", "f", "", "Path to the image compatibility spec file")
+	for _, f := range []string{"image", "spec-file"} {
+		if err := imageCompatAttachCmd.MarkFlagRequired(f); err != nil {
+			panic(err)
+		}
+	}
+
+	imageCompatValidateNodeCmd.Flags().StringVarP(&image, "image", "i", "", "Container image whose spec to validate against")
+	imageCompatValidateNodeCmd.Flags().StringVarP(&imageCompatSpec, "spec-file", "f", "", "Path to the image compatibility spec file")
+	imageCompatValidateNodeCmd.Flags().StringVarP(&nodefeature, "nodefeature-file", "n", "", "Path to the NodeFeature file to validate")
+	if err := imageCompatValidateNodeCmd.MarkFlagRequired("nodefeature-file"); err != nil {
+		panic(err)
+	}
+}
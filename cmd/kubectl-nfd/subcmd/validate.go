@@ -25,9 +25,10 @@ import (
 )
 
 var validateCmd = &cobra.Command{
-	Use:   "validate",
-	Short: "Validate a NodeFeatureRule file",
-	Long:  `Validate a NodeFeatureRule file to ensure it is valid before applying it to a cluster`,
+	Use:     "validate",
+	Aliases: []string{"verify"},
+	Short:   "Validate a NodeFeatureRule file",
+	Long:    `Validate a NodeFeatureRule file to ensure it is valid before applying it to a cluster`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("Validating NodeFeatureRule %s\n", nodefeaturerule)
 		err := kubectlnfd.ValidateNFR(nodefeaturerule)
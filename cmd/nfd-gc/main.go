@@ -24,7 +24,10 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	nfdgarbagecollector "github.com/openshift/node-feature-discovery/pkg/nfd-gc"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"
 	"github.com/openshift/node-feature-discovery/pkg/version"
 )
 
@@ -37,8 +40,13 @@ func main() {
 	flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
 
 	printVersion := flags.Bool("version", false, "Print version and exit.")
+	loggingFormat := klogutils.InitLoggingFormatFlag(flags)
 
 	args := parseArgs(flags, os.Args[1:]...)
+	if err := klogutils.SetLoggingFormat(*loggingFormat); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		os.Exit(2)
+	}
 
 	if *printVersion {
 		fmt.Println(ProgramName, version.Get())
@@ -81,10 +89,20 @@ func initFlags(flagset *flag.FlagSet) *nfdgarbagecollector.Args {
 
 	flagset.DurationVar(&args.GCPeriod, "gc-interval", time.Duration(1)*time.Hour,
 		"interval between cleanup of obsolete api objects")
+	flagset.DurationVar(&args.NRTMinAge, "nrt-min-age", 0,
+		"minimum age of NodeResourceTopology objects without a node to remove them in periodic cleanup")
+	flagset.Float64Var(&args.DeleteQPS, "delete-qps", 10,
+		"Maximum number of api object deletions per second, 0 means no limit.")
+	flagset.IntVar(&args.DeleteBurst, "delete-burst", 100,
+		"Maximum burst of api object deletions.")
+	flagset.BoolVar(&args.EnableLeaderElection, "enable-leader-election", false,
+		"Enable leader election so that only one nfd-gc replica is active at a time.")
 	flagset.StringVar(&args.Kubeconfig, "kubeconfig", "",
 		"Kubeconfig to use")
 	flagset.IntVar(&args.MetricsPort, "metrics", 8081,
 		"Port on which to expose metrics.")
+	utils.InitMetricsSecurityFlags(flagset, &args.MetricsSecurity)
+	features.InitFlags(flagset)
 
 	klog.InitFlags(flagset)
 
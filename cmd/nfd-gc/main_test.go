@@ -37,5 +37,27 @@ func TestArgsParse(t *testing.T) {
 			})
 		})
 
+		Convey("When valid -nrt-min-age is specified", func() {
+			args := parseArgs(flags,
+				"-nrt-min-age=10m")
+
+			Convey("args.NRTMinAge is set to appropriate values", func() {
+				So(args.NRTMinAge, ShouldEqual, 10*time.Minute)
+			})
+		})
+
+		Convey("When deletion rate limits and leader election are specified", func() {
+			args := parseArgs(flags,
+				"-delete-qps=2.5",
+				"-delete-burst=5",
+				"-enable-leader-election")
+
+			Convey("args are set to appropriate values", func() {
+				So(args.DeleteQPS, ShouldEqual, 2.5)
+				So(args.DeleteBurst, ShouldEqual, 5)
+				So(args.EnableLeaderElection, ShouldBeTrue)
+			})
+		})
+
 	})
 }
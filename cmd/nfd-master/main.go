@@ -27,6 +27,7 @@ import (
 
 	master "github.com/openshift/node-feature-discovery/pkg/nfd-master"
 
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
 	"github.com/openshift/node-feature-discovery/pkg/version"
 )
@@ -41,6 +42,7 @@ func main() {
 	flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
 
 	printVersion := flags.Bool("version", false, "Print version and exit.")
+	loggingFormat := klogutils.InitLoggingFormatFlag(flags)
 
 	args, overrides := initFlags(flags)
 
@@ -50,6 +52,10 @@ func main() {
 		flags.Usage()
 		os.Exit(2)
 	}
+	if err := klogutils.SetLoggingFormat(*loggingFormat); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		os.Exit(2)
+	}
 
 	// Check deprecated flags
 	flags.Visit(func(f *flag.Flag) {
@@ -75,6 +81,8 @@ func main() {
 			args.Overrides.ResyncPeriod = overrides.ResyncPeriod
 		case "nfd-api-parallelism":
 			args.Overrides.NfdApiParallelism = overrides.NfdApiParallelism
+		case "rule-evaluation-parallelism":
+			args.Overrides.RuleEvaluationParallelism = overrides.RuleEvaluationParallelism
 		case "enable-nodefeature-api":
 			klog.InfoS("-enable-nodefeature-api is deprecated, will be removed in a future release along with the deprecated gRPC API")
 		case "ca-file":
@@ -133,6 +141,9 @@ func initFlags(flagset *flag.FlagSet) (*master.Args, *master.ConfigOverrideArgs)
 			" DEPRECATED: will be removed in a future release along with the deprecated gRPC API.")
 	flagset.StringVar(&args.ConfigFile, "config", "/etc/kubernetes/node-feature-discovery/nfd-master.conf",
 		"Config file to use.")
+	flagset.StringVar(&args.ConfigObject, "config-object", "",
+		"Name of the NFDConfig object to read configuration from, in addition to the config file. "+
+			"Settings of the object override the config file and are overridden by -options.")
 	flagset.StringVar(&args.Kubeconfig, "kubeconfig", "",
 		"Kubeconfig to use")
 	flagset.BoolVar(&args.EnableNodeFeatureApi, "enable-nodefeature-api", true,
@@ -148,8 +159,13 @@ func initFlags(flagset *flag.FlagSet) (*master.Args, *master.ConfigOverrideArgs)
 			" DEPRECATED: will be removed in a future release along with the deprecated gRPC API.")
 	flagset.IntVar(&args.MetricsPort, "metrics", 8081,
 		"Port on which to expose metrics.")
+	utils.InitMetricsSecurityFlags(flagset, &args.MetricsSecurity)
+	features.InitFlags(flagset)
 	flagset.BoolVar(&args.Prune, "prune", false,
-		"Prune all NFD related attributes from all nodes of the cluster and exit.")
+		"Prune all NFD related attributes (labels, annotations, extended resources and taints) from all nodes of the cluster, "+
+			"delete all NodeFeature and NodeResourceTopology objects and exit.")
+	flagset.BoolVar(&args.PruneDryRun, "prune-dry-run", false,
+		"Print what -prune would remove from the cluster, without modifying anything, and exit.")
 	flagset.BoolVar(&args.VerifyNodeName, "verify-node-name", false,
 		"Verify worker node name against the worker's TLS certificate. "+
 			"Only takes effect when TLS authentication has been enabled."+
@@ -159,6 +175,27 @@ func initFlags(flagset *flag.FlagSet) (*master.Args, *master.ConfigOverrideArgs)
 			"in the same format as in the config file (i.e. json or yaml). These options")
 	flagset.BoolVar(&args.EnableLeaderElection, "enable-leader-election", false,
 		"Enables a leader election. Enable this when running more than one replica on nfd master.")
+	flagset.BoolVar(&args.EnableQueryApi, "enable-query-api", false,
+		"Enable the read-only node query API on the metrics port. Clients can POST a rule to "+
+			"/api/v1alpha1/matchnodes and get the list of nodes matching it. Requires "+
+			"-metrics-token-auth or -metrics-client-ca-file.")
+	flagset.BoolVar(&args.EnableProfiling, "enable-profiling", false,
+		"Expose pprof profiles under /debug/pprof/ and the time spent in each rule and on each node "+
+			"under /debug/nfd/stats and the rules depending on each feature under /debug/nfd/rule-dependencies on the metrics port. Requires -metrics-token-auth or -metrics-client-ca-file.")
+	flagset.IntVar(&args.ImageCompatWebhook.Port, "image-compat-webhook-port", 0,
+		"Port on which to serve the image compatibility admission webhook, which denies pods whose "+
+			"images require node features that no node has. Zero disables the webhook.")
+	flagset.StringVar(&args.ImageCompatWebhook.CertFile, "image-compat-webhook-cert-file", "",
+		"Certificate used for serving the image compatibility admission webhook.")
+	flagset.StringVar(&args.ImageCompatWebhook.KeyFile, "image-compat-webhook-key-file", "",
+		"Private key matching -image-compat-webhook-cert-file.")
+	flagset.StringVar(&args.ImageCompatWebhook.RegistryAuthFile, "image-compat-registry-auth-file", "",
+		"Docker config.json style file with the credentials for fetching image compatibility "+
+			"artifacts from container registries.")
+	flagset.BoolVar(&args.ImageCompatWebhook.NodeAffinity, "enable-node-affinity-webhook", false,
+		"Serve a mutating admission webhook on the image compatibility webhook port that adds the node affinity "+
+			"requested in the nfd.node.kubernetes.io/required-features and nfd.node.kubernetes.io/scheduling-hints "+
+			"pod annotations to pods.")
 
 	args.Klog = klogutils.InitKlogFlags(flagset)
 
@@ -187,6 +224,8 @@ func initFlags(flagset *flag.FlagSet) (*master.Args, *master.ConfigOverrideArgs)
 			"It has an effect when the NodeFeature API has been enabled (with -enable-nodefeature-api).")
 	overrides.NfdApiParallelism = flagset.Int("nfd-api-parallelism", 10, "Defines the maximum number of goroutines responsible of updating nodes. "+
 		"Can be used for the throttling mechanism. It has effect only when -enable-nodefeature-api has been set.")
+	overrides.RuleEvaluationParallelism = flagset.Int("rule-evaluation-parallelism", 0, "Defines the maximum number of nodes whose NodeFeatureRule objects are evaluated concurrently. "+
+		"Zero means no limit other than -nfd-api-parallelism.")
 
 	return args, overrides
 }
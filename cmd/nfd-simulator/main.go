@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/klog/v2"
+
+	nfdsimulator "github.com/openshift/node-feature-discovery/pkg/nfd-simulator"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/version"
+)
+
+const (
+	// ProgramName is the canonical name of this program
+	ProgramName = "nfd-simulator"
+)
+
+func main() {
+	flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
+
+	printVersion := flags.Bool("version", false, "Print version and exit.")
+
+	args := parseArgs(flags, os.Args[1:]...)
+
+	if *printVersion {
+		fmt.Println(ProgramName, version.Get())
+		os.Exit(0)
+	}
+
+	sim, err := nfdsimulator.New(args)
+	if err != nil {
+		klog.ErrorS(err, "failed to initialize nfd simulator instance")
+		os.Exit(1)
+	}
+
+	// Stop gracefully so that the simulated nodes can be cleaned up
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		sim.Stop()
+	}()
+
+	if err = sim.Run(); err != nil {
+		klog.ErrorS(err, "error while running")
+		os.Exit(1)
+	}
+}
+
+func parseArgs(flags *flag.FlagSet, osArgs ...string) *nfdsimulator.Args {
+	args := initFlags(flags)
+
+	_ = flags.Parse(osArgs)
+	if len(flags.Args()) > 0 {
+		fmt.Fprintf(flags.Output(), "unknown command line argument: %s\n", flags.Args()[0])
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	return args
+}
+
+func initFlags(flagset *flag.FlagSet) *nfdsimulator.Args {
+	args := &nfdsimulator.Args{}
+
+	flagset.StringVar(&args.Kubeconfig, "kubeconfig", "",
+		"Kubeconfig to use")
+	flagset.StringVar(&args.Namespace, "namespace", utils.GetKubernetesNamespace(),
+		"Namespace to create the NodeFeature objects in.")
+	flagset.StringVar(&args.NodeNamePrefix, "node-name-prefix", "nfd-simulated-node-",
+		"Prefix of the simulated node names.")
+	flagset.IntVar(&args.Nodes, "nodes", 100,
+		"Number of simulated nodes.")
+	flagset.IntVar(&args.Features, "features", 50,
+		"Number of feature elements of each type (flag, attribute, instance) per node.")
+	flagset.IntVar(&args.Labels, "labels", 10,
+		"Number of feature labels per node.")
+	flagset.IntVar(&args.ValueCardinality, "value-cardinality", 10,
+		"Number of distinct values of each feature attribute and label across the nodes.")
+	flagset.Float64Var(&args.Rate, "rate", 10,
+		"Maximum number of NodeFeature objects published per second.")
+	flagset.DurationVar(&args.UpdateInterval, "update-interval", 0,
+		"Interval at which the features of all nodes are re-generated and published. Zero publishes them only once.")
+	flagset.BoolVar(&args.CreateNodes, "create-nodes", false,
+		"Create (tainted, unschedulable) Node objects for the simulated nodes.")
+	flagset.BoolVar(&args.Cleanup, "cleanup", true,
+		"Delete the created objects on exit.")
+	flagset.Int64Var(&args.Seed, "seed", 0,
+		"Seed of the random feature generator.")
+
+	klog.InitFlags(flagset)
+
+	return args
+}
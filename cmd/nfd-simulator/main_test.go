@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestArgsParse(t *testing.T) {
+	Convey("When parsing command line arguments", t, func() {
+		flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
+
+		Convey("When load parameters are specified", func() {
+			args := parseArgs(flags,
+				"-nodes=5000",
+				"-rate=50.5",
+				"-update-interval=10m",
+				"-create-nodes")
+
+			Convey("args are set to appropriate values", func() {
+				So(args.Nodes, ShouldEqual, 5000)
+				So(args.Rate, ShouldEqual, 50.5)
+				So(args.UpdateInterval, ShouldEqual, 10*time.Minute)
+				So(args.CreateNodes, ShouldBeTrue)
+				So(args.Cleanup, ShouldBeTrue)
+			})
+		})
+
+	})
+}
@@ -25,10 +25,12 @@ import (
 
 	"k8s.io/klog/v2"
 
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	topology "github.com/openshift/node-feature-discovery/pkg/nfd-topology-updater"
 	"github.com/openshift/node-feature-discovery/pkg/resourcemonitor"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"
 	"github.com/openshift/node-feature-discovery/pkg/version"
 )
 
@@ -42,8 +44,13 @@ var DefaultKubeletStateDir = path.Join(string(hostpath.VarDir), "lib", "kubelet"
 
 func main() {
 	flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
+	loggingFormat := klogutils.InitLoggingFormatFlag(flags)
 
 	args, resourcemonitorArgs := parseArgs(flags, os.Args[1:]...)
+	if err := klogutils.SetLoggingFormat(*loggingFormat); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		os.Exit(2)
+	}
 
 	// Assert that the version is known
 	if version.Undefined() {
@@ -82,14 +89,16 @@ func parseArgs(flags *flag.FlagSet, osArgs ...string) (*topology.Args, *resource
 		os.Exit(0)
 	}
 
+	// Fall back to accessing the kubelet via the API server node proxy if
+	// NODE_ADDRESS is not available
 	if len(resourcemonitorArgs.KubeletConfigURI) == 0 {
-		nodeAddress := os.Getenv("NODE_ADDRESS")
-		if len(nodeAddress) == 0 {
-			fmt.Fprintf(flags.Output(), "unable to determine the default kubelet config endpoint 'https://${NODE_ADDRESS}:%d/configz' due to empty NODE_ADDRESS environment, "+
-				"please either define the NODE_ADDRESS environment variable or specify endpoint with the -kubelet-config-uri flag\n", kubeletSecurePort)
-			os.Exit(1)
+		if nodeAddress := os.Getenv("NODE_ADDRESS"); len(nodeAddress) > 0 {
+			resourcemonitorArgs.KubeletConfigURI = fmt.Sprintf("https://%s:%d/configz", nodeAddress, kubeletSecurePort)
 		}
-		resourcemonitorArgs.KubeletConfigURI = fmt.Sprintf("https://%s:%d/configz", nodeAddress, kubeletSecurePort)
+	}
+
+	if len(resourcemonitorArgs.MemoryManagerStateFile) == 0 && len(args.KubeletStateDir) > 0 {
+		resourcemonitorArgs.MemoryManagerStateFile = path.Join(args.KubeletStateDir, resourcemonitor.MemoryManagerStateFileName)
 	}
 
 	return args, resourcemonitorArgs
@@ -107,12 +116,14 @@ func initFlags(flagset *flag.FlagSet) (*topology.Args, *resourcemonitor.Args) {
 		"Kube config file.")
 	flagset.IntVar(&args.MetricsPort, "metrics", 8081,
 		"Port on which to expose metrics.")
+	utils.InitMetricsSecurityFlags(flagset, &args.MetricsSecurity)
+	features.InitFlags(flagset)
 	flagset.DurationVar(&resourcemonitorArgs.SleepInterval, "sleep-interval", time.Duration(60)*time.Second,
 		"Time to sleep between CR updates. zero means no CR updates on interval basis. [Default: 60s]")
 	flagset.StringVar(&resourcemonitorArgs.Namespace, "watch-namespace", "*",
 		"Namespace to watch pods (for testing/debugging purpose). Use * for all namespaces.")
 	flagset.StringVar(&resourcemonitorArgs.KubeletConfigURI, "kubelet-config-uri", "",
-		"Kubelet config URI path. Default to kubelet configz endpoint.")
+		"Kubelet config URI path. Default to kubelet configz endpoint at 'https://${NODE_ADDRESS}:10250/configz', or to the configz endpoint accessed via the API server node proxy if NODE_ADDRESS is not set.")
 	flagset.StringVar(&resourcemonitorArgs.APIAuthTokenFile, "api-auth-token-file", "/var/run/secrets/kubernetes.io/serviceaccount/token",
 		"API auth token file path. It is used to request kubelet configz endpoint, only takes effect when kubelet-config-uri is https. Default to /var/run/secrets/kubernetes.io/serviceaccount/token.")
 	flagset.StringVar(&resourcemonitorArgs.PodResourceSocketPath, "podresources-socket", hostpath.VarDir.Path("lib/kubelet/pod-resources/kubelet.sock"),
@@ -121,6 +132,10 @@ func initFlags(flagset *flag.FlagSet) (*topology.Args, *resourcemonitor.Args) {
 		"Config file to use.")
 	flagset.BoolVar(&resourcemonitorArgs.PodSetFingerprint, "pods-fingerprint", true, "Compute and report the pod set fingerprint")
 	flagset.StringVar(&args.KubeletStateDir, "kubelet-state-dir", DefaultKubeletStateDir, "Kubelet state directory path for watching state and checkpoint files")
+	flagset.StringVar(&resourcemonitorArgs.MemoryManagerStateFile, "memory-manager-state-file", "",
+		"Kubelet memory manager state file, used for the memory reserved per NUMA node. Defaults to "+resourcemonitor.MemoryManagerStateFileName+" in the kubelet state directory.")
+	flagset.BoolVar(&args.PodEvents, "pod-events", true, "Watch the podresources API, or the pods running on the node if the kubelet does not support it, "+
+		"and update NodeResourceTopology on pod changes")
 
 	klog.InitFlags(flagset)
 
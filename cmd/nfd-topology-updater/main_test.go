@@ -34,9 +34,11 @@ func TestArgsParse(t *testing.T) {
 			Convey("noPublish is set and args.sources is set to the default value", func() {
 				So(args.NoPublish, ShouldBeTrue)
 				So(args.Oneshot, ShouldBeTrue)
+				So(args.PodEvents, ShouldBeTrue)
 				So(args.ConfigFile, ShouldEqual, "/etc/kubernetes/node-feature-discovery/nfd-topology-updater.conf")
 				So(finderArgs.SleepInterval, ShouldEqual, 60*time.Second)
 				So(finderArgs.PodResourceSocketPath, ShouldEqual, "/host-var/lib/kubelet/pod-resources/kubelet.sock")
+				So(finderArgs.MemoryManagerStateFile, ShouldEqual, "/host-var/lib/kubelet/memory_manager_state")
 			})
 		})
 
@@ -82,6 +84,34 @@ func TestArgsParse(t *testing.T) {
 			})
 		})
 
+		Convey("When -kubelet-config-uri is not specified and NODE_ADDRESS is not set", func() {
+			t.Setenv("NODE_ADDRESS", "")
+			_, finderArgs := parseArgs(flags)
+
+			Convey("kubelet config is read via the API server", func() {
+				So(finderArgs.KubeletConfigURI, ShouldEqual, "")
+			})
+		})
+
+		Convey("When -kubelet-config-uri is not specified and NODE_ADDRESS is set", func() {
+			t.Setenv("NODE_ADDRESS", "192.0.2.1")
+			_, finderArgs := parseArgs(flags)
+
+			Convey("kubelet config is read from the kubelet configz endpoint", func() {
+				So(finderArgs.KubeletConfigURI, ShouldEqual, "https://192.0.2.1:10250/configz")
+			})
+		})
+
+		Convey("When -pod-events is disabled", func() {
+			args, _ := parseArgs(flags,
+				"-kubelet-config-uri=https://%s:%d/configz",
+				"-pod-events=false")
+
+			Convey("args.PodEvents is unset", func() {
+				So(args.PodEvents, ShouldBeFalse)
+			})
+		})
+
 		Convey("When All valid args are specified", func() {
 			args, finderArgs := parseArgs(flags,
 				"-no-publish",
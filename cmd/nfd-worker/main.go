@@ -24,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"
 
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	worker "github.com/openshift/node-feature-discovery/pkg/nfd-worker"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
 	"github.com/openshift/node-feature-discovery/pkg/version"
@@ -38,8 +39,13 @@ func main() {
 	flags := flag.NewFlagSet(ProgramName, flag.ExitOnError)
 
 	printVersion := flags.Bool("version", false, "Print version and exit.")
+	loggingFormat := klogutils.InitLoggingFormatFlag(flags)
 
 	args := parseArgs(flags, os.Args[1:]...)
+	if err := klogutils.SetLoggingFormat(*loggingFormat); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		os.Exit(2)
+	}
 
 	if *printVersion {
 		fmt.Println(ProgramName, version.Get())
@@ -121,6 +127,9 @@ func initFlags(flagset *flag.FlagSet) (*worker.Args, *worker.ConfigOverrideArgs)
 			" DEPRECATED: will be removed in a future release along with the deprecated gRPC API.")
 	flagset.StringVar(&args.ConfigFile, "config", "/etc/kubernetes/node-feature-discovery/nfd-worker.conf",
 		"Config file to use.")
+	flagset.StringVar(&args.ConfigObject, "config-object", "",
+		"Name of the NFDConfig object to read configuration from, in addition to the config file. "+
+			"Settings of the object override the config file and are overridden by -options.")
 	flagset.StringVar(&args.KeyFile, "key-file", "",
 		"Private key matching -cert-file."+
 			" DEPRECATED: will be removed in a future release along with the deprecated gRPC API.")
@@ -131,8 +140,30 @@ func initFlags(flagset *flag.FlagSet) (*worker.Args, *worker.ConfigOverrideArgs)
 		"Kubeconfig to use")
 	flagset.BoolVar(&args.Oneshot, "oneshot", false,
 		"Do not publish feature labels")
+	flagset.BoolVar(&args.MinimalPrivileges, "minimal-privileges", false,
+		"Run with minimal privileges. Inputs of feature sources that require elevated "+
+			"privileges (e.g. root-only files or executing hooks) are not accessed and sources "+
+			"without any unprivileged inputs are disabled.")
+	flagset.StringVar(&args.Output, "output", "",
+		"Write the discovered features and labels to stdout in the given format (json or yaml). "+
+			"Combined with -oneshot and -no-publish this can be used for inspecting feature "+
+			"discovery on a host without a cluster.")
+	flagset.StringVar(&args.OutputFile, "output-file", "",
+		"Write the discovered features and labels to the given file in json format after each discovery. "+
+			"The file is replaced atomically.")
+	flagset.BoolVar(&args.Standalone, "standalone", false,
+		"Run without any access to the Kubernetes API, e.g. in provisioning pipelines. Nothing is published, "+
+			"the features are only output with -output, -output-file or -socket.")
+	flagset.StringVar(&args.Socket, "socket", "",
+		"Serve the latest discovered features in json format over HTTP (GET /features) on a unix socket "+
+			"at the given path. Only supported with -standalone.")
 	flagset.IntVar(&args.MetricsPort, "metrics", 8081,
 		"Port on which to expose metrics.")
+	utils.InitMetricsSecurityFlags(flagset, &args.MetricsSecurity)
+	flagset.BoolVar(&args.EnableProfiling, "enable-profiling", false,
+		"Expose pprof profiles under /debug/pprof/ and the time spent in each feature source under "+
+			"/debug/nfd/stats on the metrics port. Requires -metrics-token-auth or -metrics-client-ca-file.")
+	features.InitFlags(flagset)
 	flagset.StringVar(&args.Options, "options", "",
 		"Specify config options from command line. Config options are specified "+
 			"in the same format as in the config file (i.e. json or yaml). These options")
@@ -53,5 +53,18 @@ func TestParseArgs(t *testing.T) {
 				So(*args.Overrides.LabelSources, ShouldResemble, utils.StringSliceVal{"fake1", "fake2", "fake3"})
 			})
 		})
+
+		Convey("When standalone args are specified", func() {
+			args := parseArgs(flags,
+				"-standalone",
+				"-output-file=/tmp/features.json",
+				"-socket=/run/nfd/features.sock")
+
+			Convey("args are set to appropriate values", func() {
+				So(args.Standalone, ShouldBeTrue)
+				So(args.OutputFile, ShouldEqual, "/tmp/features.json")
+				So(args.Socket, ShouldEqual, "/run/nfd/features.sock")
+			})
+		})
 	})
 }
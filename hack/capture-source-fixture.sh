@@ -0,0 +1,130 @@
+#!/bin/bash -e
+set -o pipefail
+
+this=`basename $0`
+
+# Host paths read by the feature sources. Directories are copied recursively
+# (following symlinks) up to the maximum depth.
+default_paths="
+/boot
+/etc/machine-config-daemon/currentconfig
+/etc/os-release
+/etc/tuned/active_profile
+/lib/modules/`uname -r`/modules.builtin
+/proc/cmdline
+/proc/config.gz
+/proc/cpuinfo
+/proc/crypto
+/proc/modules
+/proc/swaps
+/proc/sys/kernel/osrelease
+/proc/sys/kernel/seccomp/actions_avail
+/proc/sys/kernel/version
+/sys/block
+/sys/bus/node/devices
+/sys/bus/pci/drivers/4xxx
+/sys/bus/pci/drivers/c6xx
+/sys/bus/pci/drivers/vfio-pci
+/sys/bus/pci/devices
+/sys/bus/platform/drivers/caam
+/sys/bus/platform/drivers/caam_jr
+/sys/bus/usb/devices
+/sys/class/iommu
+/sys/class/mdev_bus
+/sys/class/misc/kvm/dev
+/sys/class/net
+/sys/class/powercap
+/sys/devices/system/cpu
+/sys/devices/system/node
+/sys/devices/virtual/dmi/id
+/sys/firmware/devicetree/base/compatible
+/sys/fs/selinux/enforce
+/sys/kernel/iommu_groups
+/sys/kernel/mm/hugepages
+/sys/kernel/security/lsm
+/sys/kernel/mm/transparent_hugepage
+/sys/kernel/realtime
+/sys/module/apparmor/parameters/enabled
+/sys/module/kvm_amd/parameters
+/sys/module/kvm_intel/parameters
+/sys/module/vfio/parameters
+/usr/src/linux/.config
+/var/lib/kubelet/config.yaml
+"
+max_depth=3
+max_size=1024k
+
+usage () {
+cat << EOF
+Usage: $this [-h] [-d MAX_DEPTH] [-s MAX_SIZE] OUTPUT_DIR [PATH...]
+
+Capture a test fixture for the feature sources from the running system. The
+given host paths (by default all paths read by the feature sources) are copied
+under OUTPUT_DIR, e.g. /sys/block to OUTPUT_DIR/sys/block. Symlinks are
+dereferenced so that the fixture can be used without the rest of the tree.
+
+Options:
+  -h         show this help and exit
+  -d         maximum depth of directories to copy (default: $max_depth)
+  -s         skip files bigger than this, in find(1) -size format
+             (default: $max_size)
+
+Example:
+
+  $this source/pci/testdata/my-server /sys/bus/pci/devices
+
+
+NOTE: Run as root to capture files only readable by root. Review the captured
+      files for sensitive information (e.g. serial numbers) before committing.
+      Generate the golden file with 'go test ./source/<source> -update-golden'.
+EOF
+}
+
+#
+# Parse command line
+#
+while getopts "hd:s:" opt; do
+    case $opt in
+        h)  usage
+            exit 0
+            ;;
+        d)  max_depth="$OPTARG"
+            ;;
+        s)  max_size="$OPTARG"
+            ;;
+        *)  usage
+            exit 1
+            ;;
+    esac
+done
+shift "$((OPTIND - 1))"
+
+if [ $# -lt 1 ]; then
+    usage
+    exit 1
+fi
+out_dir="$1"
+shift
+paths="${*:-$default_paths}"
+
+mkdir -p "$out_dir"
+
+#
+# Copy files
+#
+for path in $paths; do
+    if [ ! -e "$path" ]; then
+        echo "$this: skipping non-existent path $path" >&2
+        continue
+    fi
+    echo "Capturing $path"
+    # Sysfs attributes that cannot be read (e.g. write-only or mmap-only
+    # attributes) are skipped
+    find -L "$path" -maxdepth "$max_depth" -type f -readable -size -"$max_size" 2>/dev/null | \
+    while read -r f; do
+        mkdir -p "$out_dir/`dirname "$f"`"
+        cat "$f" > "$out_dir/$f" 2>/dev/null || rm -f "${out_dir:?}/${f:?}"
+    done
+done
+
+echo "Fixture captured in $out_dir"
@@ -0,0 +1,65 @@
+#!/bin/bash -e
+set -o pipefail
+
+this=`basename $0`
+
+url="https://pci-ids.ucw.cz/v2.2/pci.ids"
+
+usage () {
+cat << EOF
+Usage: $this [-h] [-u URL] [-f FILE] OUTPUT_FILE
+
+Download the PCI ID database and store it gzip-compressed in OUTPUT_FILE.
+The class code section is dropped as only vendor and device names are used.
+
+Options:
+  -h         show this help and exit
+  -u         url of the pci.ids file (default: $url)
+  -f         read a local pci.ids file instead of downloading, e.g.
+             /usr/share/hwdata/pci.ids
+
+Example:
+
+  $this source/pci/pci.ids.gz
+EOF
+}
+
+#
+# Parse command line
+#
+while getopts "hu:f:" opt; do
+    case $opt in
+        h)  usage
+            exit 0
+            ;;
+        u)  url="$OPTARG"
+            ;;
+        f)  in_file="$OPTARG"
+            ;;
+        *)  usage
+            exit 1
+            ;;
+    esac
+done
+shift "$((OPTIND - 1))"
+
+if [ $# -ne 1 ]; then
+    usage
+    exit 1
+fi
+out_file="$1"
+
+tmp_file=`mktemp`
+trap 'rm -f "${tmp_file:?}"' EXIT
+
+if [ -n "$in_file" ]; then
+    echo "Reading $in_file"
+    sed '/^C /,$d' "$in_file" | gzip -9 -n > "$tmp_file"
+else
+    echo "Downloading $url"
+    curl -sSfL "$url" | sed '/^C /,$d' | gzip -9 -n > "$tmp_file"
+fi
+mv "$tmp_file" "$out_file"
+chmod 644 "$out_file"
+
+echo "PCI ID database updated in $out_file"
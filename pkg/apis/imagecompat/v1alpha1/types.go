@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// Version is the version of the specification.
+	Version = "v1alpha1"
+
+	// ArtifactType is the artifact type of the OCI artifact holding the
+	// image compatibility specification.
+	ArtifactType = "application/vnd.nfd.image-compatibility.v1alpha1"
+
+	// SpecMediaType is the media type of the specification (in JSON format)
+	// stored as the single layer of the artifact.
+	SpecMediaType = "application/vnd.nfd.image-compatibility.spec.v1alpha1+json"
+)
+
+// Spec is the image compatibility specification: the node features a
+// container image requires.
+type Spec struct {
+	// Version of the specification, must be v1alpha1.
+	Version string `json:"version"`
+	// Compatibilities is the list of compatibility sets. A node is
+	// compatible with the image if it satisfies all of the sets that are not
+	// optional.
+	Compatibilities []Compatibility `json:"compatibilities"`
+}
+
+// Compatibility is a set of node feature requirements, expressed the same
+// way as the matchers of NodeFeatureRule rules.
+type Compatibility struct {
+	// Name of the compatibility set.
+	Name string `json:"name"`
+	// Description is a human readable description of the requirements.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Optional compatibility sets are informative only, nodes not
+	// satisfying them are still compatible with the image.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+	// MatchFeatures specifies a set of matcher terms all of which must match.
+	// +optional
+	MatchFeatures nfdv1alpha1.FeatureMatcher `json:"matchFeatures,omitempty"`
+	// MatchAny specifies a list of matchers one of which must match.
+	// +optional
+	MatchAny []nfdv1alpha1.MatchAnyElem `json:"matchAny,omitempty"`
+}
@@ -47,9 +47,25 @@ const (
 	// ExtendedResourceAnnotation is the annotation that holds all extended resources managed by NFD.
 	ExtendedResourceAnnotation = AnnotationNs + "/extended-resources"
 
+	// ExtendedResourcesZeroedAnnotation is the annotation that holds the
+	// extended resources nfd-master has zeroed because no rule produces them
+	// anymore, with the time they were zeroed. The resources are removed when
+	// the extendedResourceGracePeriod has passed.
+	ExtendedResourcesZeroedAnnotation = AnnotationNs + "/extended-resources-zeroed"
+
 	// FeatureLabelsAnnotation is the annotation that holds all feature labels managed by NFD.
 	FeatureLabelsAnnotation = AnnotationNs + "/feature-labels"
 
+	// FeatureLabelsHashAnnotation is the annotation that holds the hashes of
+	// all feature labels managed by NFD. Used instead of
+	// FeatureLabelsAnnotation if nfd-master is configured to use hashed
+	// ownership records.
+	FeatureLabelsHashAnnotation = AnnotationNs + "/feature-labels-hash"
+
+	// ExtendedResourceHashAnnotation is the annotation that holds the hashes
+	// of all extended resources managed by NFD.
+	ExtendedResourceHashAnnotation = AnnotationNs + "/extended-resources-hash"
+
 	// MasterVersionAnnotation is the annotation that holds the version of nfd-master running on the node
 	// DEPRECATED: will not be used in NFD v0.15 or later.
 	MasterVersionAnnotation = AnnotationNs + "/master.version"
@@ -60,15 +76,85 @@ const (
 	// NodeTaintsAnnotation is the annotation that holds the taints that nfd-master set on the node
 	NodeTaintsAnnotation = AnnotationNs + "/taints"
 
+	// NodeCordonAnnotation is the annotation that holds the rules that made
+	// nfd-master cordon the node
+	NodeCordonAnnotation = AnnotationNs + "/cordoned-by"
+
 	// FeatureAnnotationsTrackingAnnotation is the annotation that holds all feature annotations that nfd-master set on the node
 	FeatureAnnotationsTrackingAnnotation = AnnotationNs + "/feature-annotations"
 
+	// FeatureAnnotationsHashAnnotation is the annotation that holds the
+	// hashes of all feature annotations that nfd-master set on the node
+	FeatureAnnotationsHashAnnotation = AnnotationNs + "/feature-annotations-hash"
+
+	// ForceResyncAnnotation is the annotation that triggers immediate
+	// re-evaluation of a node. nfd-master removes the annotation after the
+	// node has been processed.
+	ForceResyncAnnotation = AnnotationNs + "/force-resync"
+
+	// NodeFeatureHeartbeatAnnotation is the annotation of NodeFeature objects
+	// holding the time nfd-worker last refreshed the object.
+	NodeFeatureHeartbeatAnnotation = AnnotationNs + "/heartbeat"
+
+	// NodeFeatureSignatureAnnotation is the annotation of NodeFeature objects
+	// holding the signature of the object spec, see the
+	// nodeFeatureSigningKeyFile config options of nfd-worker and nfd-master.
+	NodeFeatureSignatureAnnotation = AnnotationNs + "/signature"
+
+	// PodRequiredFeaturesAnnotation is the pod annotation listing the feature
+	// labels the pod requires, as a comma-separated list of <label>,
+	// <label>=<value>, <label>!=<value> or !<label> items. The node affinity
+	// webhook of nfd-master turns it into a required node affinity. Labels
+	// without a namespace are in the feature.node.kubernetes.io namespace.
+	PodRequiredFeaturesAnnotation = AnnotationNs + "/required-features"
+
+	// PodSchedulingHintsAnnotation is the pod annotation listing the names of
+	// the scheduling hints (see the schedulingHints config option of
+	// nfd-master) whose node affinity the node affinity webhook of
+	// nfd-master adds to the pod.
+	PodSchedulingHintsAnnotation = AnnotationNs + "/scheduling-hints"
+
+	// PodFeatureRequirementsAnnotation is the pod annotation holding the node
+	// feature requirements of the pod, in YAML or JSON format, evaluated by
+	// the NodeFeatures scheduler plugin directly against the NodeFeature
+	// objects of the nodes.
+	PodFeatureRequirementsAnnotation = AnnotationNs + "/feature-requirements"
+
+	// NodeExcludeLabel is the node label (or annotation) that opts the node
+	// out of NFD. nfd-master does not update nodes that have it set to "true".
+	NodeExcludeLabel = AnnotationNs + "/exclude"
+
 	// NodeFeatureObjNodeNameLabel is the label that specifies which node the
 	// NodeFeature object is targeting. Creators of NodeFeature objects must
 	// set this label and consumers of the objects are supposed to use the
 	// label for filtering features designated for a certain node.
 	NodeFeatureObjNodeNameLabel = "nfd.node.kubernetes.io/node-name"
 
+	// NodeFeatureObjGroupLabel is the label nfd-worker sets on the NodeFeature
+	// objects holding a separately published group of feature sources. It
+	// specifies the name of the group.
+	NodeFeatureObjGroupLabel = "nfd.node.kubernetes.io/feature-group"
+
+	// HubClusterNameLabel is the label of the NodeFeature objects that
+	// nfd-master mirrors to a hub cluster. It holds the name of the cluster
+	// the features were discovered in.
+	HubClusterNameLabel = "nfd.node.kubernetes.io/cluster-name"
+
+	// HubNodeNameLabel is the label of the NodeFeature objects mirrored to a
+	// hub cluster that holds the name of the node in its own cluster.
+	HubNodeNameLabel = "nfd.node.kubernetes.io/cluster-node-name"
+
+	// NodeResourceTopologyOwnerLabel is the label that nfd-topology-updater
+	// sets on the NodeResourceTopology objects it manages. Pruning in
+	// nfd-master only deletes objects carrying it, leaving the objects of
+	// other exporters intact.
+	NodeResourceTopologyOwnerLabel = "nfd.node.kubernetes.io/topology-updater"
+
+	// NodeCleanupFinalizer is the finalizer that nfd-master (optionally) sets
+	// on NodeFeature objects. It ensures that the node labels, taints et al.
+	// derived from the object are removed before the object is deleted.
+	NodeCleanupFinalizer = "nfd.node.kubernetes.io/node-cleanup"
+
 	// FeatureAnnotationNs is the (default) namespace for feature annotations.
 	FeatureAnnotationNs = "feature.node.kubernetes.io"
 
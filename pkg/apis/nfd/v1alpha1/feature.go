@@ -16,7 +16,12 @@ limitations under the License.
 
 package v1alpha1
 
-import "maps"
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
 
 // NewNodeFeatureSpec creates a new emprty instance of NodeFeatureSpec type,
 // initializing all fields to proper empty values.
@@ -53,11 +58,118 @@ func NewAttributeFeatures(values map[string]string) AttributeFeatureSet {
 	return AttributeFeatureSet{Elements: values}
 }
 
+// NewTypedAttributeFeatures creates a new instance of AttributeFeatureSet
+// from typed values, see FormatAttributeValue.
+func NewTypedAttributeFeatures(values map[string]interface{}) AttributeFeatureSet {
+	s := AttributeFeatureSet{Elements: make(map[string]string, len(values))}
+	for name, value := range values {
+		var t AttributeType
+		s.Elements[name], t = FormatAttributeValue(value)
+		s.Types = setAttributeType(s.Types, name, t)
+	}
+	return s
+}
+
+// WithTypes sets the type of the elements of an AttributeFeatureSet. Types of
+// elements not present in the set are ignored.
+func (in AttributeFeatureSet) WithTypes(types map[string]AttributeType) AttributeFeatureSet {
+	for name, t := range types {
+		if _, ok := in.Elements[name]; ok {
+			in.Types = setAttributeType(in.Types, name, t)
+		}
+	}
+	return in
+}
+
 // NewInstanceFeatures creates a new instance of InstanceFeatureSet.
 func NewInstanceFeatures(instances []InstanceFeature) InstanceFeatureSet {
 	return InstanceFeatureSet{Elements: instances}
 }
 
+// NewTypedInstanceFeatures creates a new instance of InstanceFeatureSet from
+// instances with typed attribute values, see FormatAttributeValue.
+func NewTypedInstanceFeatures(instances []map[string]interface{}) InstanceFeatureSet {
+	s := InstanceFeatureSet{Elements: make([]InstanceFeature, 0, len(instances))}
+	for _, attrs := range instances {
+		i := InstanceFeature{Attributes: make(map[string]string, len(attrs))}
+		for name, value := range attrs {
+			var t AttributeType
+			i.Attributes[name], t = FormatAttributeValue(value)
+			s.Types = setAttributeType(s.Types, name, t)
+		}
+		s.Elements = append(s.Elements, i)
+	}
+	return s
+}
+
+// setAttributeType records the type of an attribute. Strings are the
+// default and not recorded.
+func setAttributeType(types map[string]AttributeType, name string, t AttributeType) map[string]AttributeType {
+	if t == AttributeTypeString || t == "" {
+		delete(types, name)
+		return types
+	}
+	if types == nil {
+		types = make(map[string]AttributeType)
+	}
+	types[name] = t
+	return types
+}
+
+// FormatAttributeValue converts a typed value into the string form stored in
+// the features and returns its type. Strings, booleans, integers and string
+// slices are supported, other values are formatted as strings.
+func FormatAttributeValue(value interface{}) (string, AttributeType) {
+	switch v := value.(type) {
+	case string:
+		return v, AttributeTypeString
+	case bool:
+		return strconv.FormatBool(v), AttributeTypeBool
+	case int:
+		return strconv.Itoa(v), AttributeTypeInt
+	case int32:
+		return strconv.FormatInt(int64(v), 10), AttributeTypeInt
+	case int64:
+		return strconv.FormatInt(v, 10), AttributeTypeInt
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), AttributeTypeInt
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), AttributeTypeInt
+	case uint64:
+		return strconv.FormatUint(v, 10), AttributeTypeInt
+	case []string:
+		return strings.Join(v, ","), AttributeTypeList
+	}
+	return fmt.Sprintf("%v", value), AttributeTypeString
+}
+
+// ParseAttributeValue converts an attribute value in string form into a
+// typed value: string, int64, bool or []string, depending on the type.
+func ParseAttributeValue(value string, t AttributeType) (interface{}, error) {
+	switch t {
+	case "", AttributeTypeString:
+		return value, nil
+	case AttributeTypeInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int value %q", value)
+		}
+		return v, nil
+	case AttributeTypeBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value %q", value)
+		}
+		return v, nil
+	case AttributeTypeList:
+		if value == "" {
+			return []string{}, nil
+		}
+		return strings.Split(value, ","), nil
+	}
+	return nil, fmt.Errorf("unknown attribute type %q", t)
+}
+
 // NewInstanceFeature creates a new InstanceFeature instance.
 func NewInstanceFeature(attrs map[string]string) *InstanceFeature {
 	if attrs == nil {
@@ -73,11 +185,17 @@ func (f *Features) InsertAttributeFeatures(domain, feature string, values map[st
 	}
 	key := domain + "." + feature
 	if _, ok := f.Attributes[key]; !ok {
-		f.Attributes[key] = NewAttributeFeatures(values)
+		// Copy the values as subsequent inserts modify the map
+		f.Attributes[key] = NewAttributeFeatures(maps.Clone(values))
 		return
 	}
 
-	maps.Copy(f.Attributes[key].Elements, values)
+	s := f.Attributes[key]
+	maps.Copy(s.Elements, values)
+	// The new values are untyped
+	for name := range values {
+		delete(s.Types, name)
+	}
 }
 
 // Exists returns a non-empty string if a feature exists. The return value is
@@ -160,6 +278,9 @@ func (in *AttributeFeatureSet) MergeInto(out *AttributeFeatureSet) {
 			out.Elements = make(map[string]string, len(in.Elements))
 		}
 		maps.Copy(out.Elements, in.Elements)
+		for name := range in.Elements {
+			out.Types = setAttributeType(out.Types, name, in.Types[name])
+		}
 	}
 }
 
@@ -173,4 +294,7 @@ func (in *InstanceFeatureSet) MergeInto(out *InstanceFeatureSet) {
 			out.Elements = append(out.Elements, *e.DeepCopy())
 		}
 	}
+	for name, t := range in.Types {
+		out.Types = setAttributeType(out.Types, name, t)
+	}
 }
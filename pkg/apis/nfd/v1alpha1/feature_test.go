@@ -76,6 +76,35 @@ func TestAttributeFeatureSet(t *testing.T) {
 	assert.Equal(t, expectedElems, f1.Elements)
 }
 
+func TestTypedAttributeFeatureSet(t *testing.T) {
+	f1 := NewTypedAttributeFeatures(map[string]interface{}{"s": "str", "i": 42, "u": uint64(7), "b": true, "l": []string{"a", "b"}})
+	assert.Equal(t, map[string]string{"s": "str", "i": "42", "u": "7", "b": "true", "l": "a,b"}, f1.Elements)
+	assert.Equal(t, map[string]AttributeType{"i": AttributeTypeInt, "u": AttributeTypeInt, "b": AttributeTypeBool, "l": AttributeTypeList}, f1.Types)
+
+	// Untyped values override the type
+	f2 := NewAttributeFeatures(map[string]string{"i": "forty-two"})
+	f2.MergeInto(&f1)
+	assert.Equal(t, "forty-two", f1.Elements["i"])
+	assert.NotContains(t, f1.Types, "i")
+
+	f2 = NewTypedAttributeFeatures(map[string]interface{}{"s": 1})
+	f2.MergeInto(&f1)
+	assert.Equal(t, AttributeTypeInt, f1.Types["s"])
+
+	v, err := ParseAttributeValue("a,b", AttributeTypeList)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, v)
+	v, err = ParseAttributeValue("-1", AttributeTypeInt)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), v)
+	_, err = ParseAttributeValue("1", AttributeTypeBool)
+	assert.Nil(t, err)
+	_, err = ParseAttributeValue("yes", AttributeTypeBool)
+	assert.NotNil(t, err)
+	_, err = ParseAttributeValue("1", "float")
+	assert.NotNil(t, err)
+}
+
 func TestInstanceFeatureSet(t *testing.T) {
 	f1 := InstanceFeatureSet{}
 	f2 := InstanceFeatureSet{}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is the class of an error of the rule engine. The codes are
+// errors themselves so that they can be tested with errors.Is, e.g.
+// errors.Is(err, ErrBadRegexp). The codes are stable and suitable e.g. as
+// metric labels and condition reasons.
+type ErrorCode string
+
+// Error implements the error interface.
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+const (
+	// ErrInvalidExpression is an invalid match expression, e.g. a wrong
+	// number of values for the operator.
+	ErrInvalidExpression ErrorCode = "InvalidExpression"
+	// ErrInvalidOp is an unknown operator or an operator not supported in
+	// the context of the expression.
+	ErrInvalidOp ErrorCode = "InvalidOp"
+	// ErrNotANumber is a non-numeric value (in the expression or in the
+	// input) of a numeric operator.
+	ErrNotANumber ErrorCode = "NotANumber"
+	// ErrBadRegexp is an invalid or too complex regexp.
+	ErrBadRegexp ErrorCode = "BadRegexp"
+	// ErrInvalidParser is an unknown value parser or a parser not supported
+	// with the operator.
+	ErrInvalidParser ErrorCode = "InvalidParser"
+	// ErrParseValue is a value (in the expression or in the input) that
+	// the parser of the expression failed to parse.
+	ErrParseValue ErrorCode = "ParseValue"
+	// ErrUnboundVariable is a reference to a variable that has not been
+	// bound.
+	ErrUnboundVariable ErrorCode = "UnboundVariable"
+	// ErrInvalidTerm is an invalid feature matcher term, e.g. an invalid
+	// presence or bind.
+	ErrInvalidTerm ErrorCode = "InvalidTerm"
+	// ErrFeatureNotAvailable is a required feature missing from the input.
+	ErrFeatureNotAvailable ErrorCode = "FeatureNotAvailable"
+	// ErrTemplate is a template that failed to parse or execute.
+	ErrTemplate ErrorCode = "Template"
+	// ErrInvalidMinFeatureVersion is an invalid minFeatureVersion.
+	ErrInvalidMinFeatureVersion ErrorCode = "InvalidMinFeatureVersion"
+	// ErrUnknown is the code of errors not originating from the rule engine.
+	ErrUnknown ErrorCode = "Unknown"
+)
+
+// RuleError is an error of the rule engine. All errors returned by Compile
+// and Evaluate are (or join) RuleErrors.
+type RuleError struct {
+	// Code is the class of the error.
+	Code ErrorCode
+	// Rule is the name of the rule.
+	Rule string
+	// Feature is the feature of the matcher term, if any.
+	Feature string
+	// Path locates the error within the term or rule, e.g.
+	// matchExpressions["vendor"], matchName or labelsTemplate.
+	Path string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RuleError) Error() string {
+	var b strings.Builder
+	if e.Rule != "" {
+		fmt.Fprintf(&b, "rule %q: ", e.Rule)
+	}
+	if e.Feature != "" {
+		fmt.Fprintf(&b, "feature %q: ", e.Feature)
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns the code and the underlying error.
+func (e *RuleError) Unwrap() []error {
+	return []error{e.Code, e.Err}
+}
+
+// newError returns a new RuleError with the given code and message.
+func newError(code ErrorCode, format string, a ...interface{}) *RuleError {
+	return &RuleError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// annotate returns a copy of a RuleError with the empty location fields set
+// to the given values. Errors are not modified in place as compiled
+// expressions return the same error on each evaluation. Other errors are
+// wrapped with ErrUnknown.
+func annotate(err error, rule, feature, path string) error {
+	if err == nil {
+		return nil
+	}
+	var ret RuleError
+	if re, ok := err.(*RuleError); ok {
+		ret = *re
+	} else {
+		ret = RuleError{Code: ErrUnknown, Err: err}
+	}
+	if ret.Rule == "" {
+		ret.Rule = rule
+	}
+	if ret.Feature == "" {
+		ret.Feature = feature
+	}
+	if ret.Path == "" {
+		ret.Path = path
+	}
+	return &ret
+}
+
+// Code returns the code of an error of the rule engine. ErrUnknown is
+// returned for other errors. The code of the first RuleError is returned
+// for joined errors.
+func Code(err error) ErrorCode {
+	var re *RuleError
+	if errors.As(err, &re) {
+		return re.Code
+	}
+	return ErrUnknown
+}
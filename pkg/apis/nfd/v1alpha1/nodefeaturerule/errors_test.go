@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestRuleErrors(t *testing.T) {
+	features := nfdv1alpha1.NewFeatures()
+	features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6"})
+
+	rule := func(term nfdv1alpha1.FeatureMatcherTerm) *nfdv1alpha1.Rule {
+		return &nfdv1alpha1.Rule{
+			Name:          "test-rule",
+			Labels:        map[string]string{"label": "true"},
+			MatchFeatures: nfdv1alpha1.FeatureMatcher{term},
+		}
+	}
+	expr := func(op nfdv1alpha1.MatchOp, values ...string) *nfdv1alpha1.MatchExpressionSet {
+		return &nfdv1alpha1.MatchExpressionSet{"major": &nfdv1alpha1.MatchExpression{Op: op, Value: values}}
+	}
+
+	tcs := []struct {
+		name    string
+		term    nfdv1alpha1.FeatureMatcherTerm
+		code    ErrorCode
+		path    string
+		message string
+	}{
+		{
+			name:    "invalid op",
+			term:    nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchExpressions: expr("Foo")},
+			code:    ErrInvalidOp,
+			path:    `matchExpressions["major"]`,
+			message: `rule "test-rule": feature "kernel.version": matchExpressions["major"]: invalid Op "Foo"`,
+		},
+		{
+			name: "not a number",
+			term: nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchExpressions: expr(nfdv1alpha1.MatchGt, "five")},
+			code: ErrNotANumber,
+			path: `matchExpressions["major"]`,
+		},
+		{
+			name: "bad regexp",
+			term: nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchExpressions: expr(nfdv1alpha1.MatchInRegexp, "(")},
+			code: ErrBadRegexp,
+			path: `matchExpressions["major"]`,
+		},
+		{
+			name: "invalid expression",
+			term: nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchExpressions: expr(nfdv1alpha1.MatchIn)},
+			code: ErrInvalidExpression,
+			path: `matchExpressions["major"]`,
+		},
+		{
+			name: "invalid matchName",
+			term: nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchName: &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt}},
+			code: ErrInvalidExpression,
+			path: "matchName",
+		},
+		{
+			name: "unbound variable",
+			term: nfdv1alpha1.FeatureMatcherTerm{
+				Feature:          "kernel.version",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"major": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, ValueFrom: "foo"}},
+			},
+			code: ErrUnboundVariable,
+			path: `matchExpressions["major"]`,
+		},
+		{
+			name: "invalid presence",
+			term: nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", Presence: "Maybe"},
+			code: ErrInvalidTerm,
+			path: "presence",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rule(tc.term)
+
+			_, err := Compile(r)
+			assert.ErrorIs(t, err, tc.code)
+			assert.Equal(t, tc.code, Code(err))
+
+			_, err = Evaluate(features, r)
+			assert.ErrorIs(t, err, tc.code)
+			assert.Equal(t, tc.code, Code(err))
+
+			var re *RuleError
+			if assert.ErrorAs(t, err, &re) {
+				assert.Equal(t, "test-rule", re.Rule)
+				assert.Equal(t, "kernel.version", re.Feature)
+				assert.Equal(t, tc.path, re.Path)
+			}
+			if tc.message != "" {
+				assert.EqualError(t, err, tc.message)
+			}
+		})
+	}
+
+	// Errors of the input
+	r := rule(nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.version", MatchExpressions: expr(nfdv1alpha1.MatchGt, "5")})
+	features.Attributes["kernel.version"].Elements["major"] = "six"
+	_, err := Evaluate(features, r)
+	assert.ErrorIs(t, err, ErrNotANumber)
+
+	r = rule(nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.config", MatchExpressions: expr(nfdv1alpha1.MatchExists)})
+	_, err = Evaluate(features, r)
+	assert.ErrorIs(t, err, ErrFeatureNotAvailable)
+	assert.EqualError(t, err, `rule "test-rule": feature "kernel.config": feature not available`)
+
+	// Template errors
+	r = &nfdv1alpha1.Rule{Name: "test-rule", LabelsTemplate: "{{ .foo "}
+	_, err = Compile(r)
+	assert.ErrorIs(t, err, ErrTemplate)
+
+	// Other errors
+	assert.Equal(t, ErrUnknown, Code(errors.New("foo")))
+	assert.Equal(t, ErrUnknown, Code(nil))
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nodefeaturerule implements the evaluation of NodeFeatureRules
+// against a set of node features. The package is usable as a library, e.g. by
+// schedulers or admission controllers that want to apply the matching
+// semantics of NFD. Evaluate and CompiledRule.Evaluate are its stable entry
+// points. The package has no global state and does not log unless a logger is
+// given with WithLogger.
+package nodefeaturerule
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// EvaluateOption is an option of Evaluate.
+type EvaluateOption func(*evaluateOptions)
+
+type evaluateOptions struct {
+	logger logr.Logger
+}
+
+// WithLogger makes the evaluation log the matched (and unmatched) features
+// to the given logger. The details are logged at verbosity levels 2 to 4.
+func WithLogger(logger logr.Logger) EvaluateOption {
+	return func(o *evaluateOptions) {
+		o.logger = logger
+	}
+}
+
+// Evaluate evaluates a rule against a set of features. The rule is compiled
+// on each call, use Compile and CompiledRule.Evaluate for evaluating the same
+// rule repeatedly.
+func Evaluate(features *nfdv1alpha1.Features, rule *nfdv1alpha1.Rule, opts ...EvaluateOption) (RuleOutput, error) {
+	c, _ := Compile(rule)
+	return c.Evaluate(features, opts...)
+}
+
+// Evaluate evaluates the compiled rule against a set of features. It is safe
+// for concurrent use.
+func (c *CompiledRule) Evaluate(features *nfdv1alpha1.Features, opts ...EvaluateOption) (RuleOutput, error) {
+	o := evaluateOptions{logger: logr.Discard()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	out, err := c.execute(o.logger, features)
+	if err != nil {
+		return RuleOutput{}, annotate(err, c.rule.Name, "", "")
+	}
+	return out, nil
+}
+
+// delayedDump delays dumping an object in YAML format until (or if) it is
+// actually logged.
+type delayedDump struct {
+	obj interface{}
+}
+
+// String implements the fmt.Stringer interface.
+func (d delayedDump) String() string {
+	out, err := yaml.Marshal(d.obj)
+	if err != nil {
+		return fmt.Sprintf("<!!! FAILED TO MARSHAL %T (%v) !!!>\n", d.obj, err)
+	}
+	return string(out)
+}
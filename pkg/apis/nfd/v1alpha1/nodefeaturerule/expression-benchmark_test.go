@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"fmt"
+	"testing"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// benchmarkCpuidFlags returns a realistic number of cpuid flags.
+func benchmarkCpuidFlags() map[string]nfdv1alpha1.Nil {
+	flags := map[string]nfdv1alpha1.Nil{
+		"AVX": {}, "AVX2": {}, "AVX512F": {}, "AVX512BW": {}, "AVX512VNNI": {},
+		"SSE4": {}, "SSE42": {}, "AESNI": {}, "FMA3": {}, "VMX": {},
+	}
+	for i := 0; i < 300; i++ {
+		flags[fmt.Sprintf("FLAG_%03d", i)] = nfdv1alpha1.Nil{}
+	}
+	return flags
+}
+
+// benchmarkKconfig returns a realistic number of kernel config options.
+func benchmarkKconfig() map[string]string {
+	values := map[string]string{
+		"NO_HZ_FULL":   "y",
+		"PREEMPT_RT":   "y",
+		"NR_CPUS":      "8192",
+		"HZ":           "1000",
+		"X86_64":       "y",
+		"LOCALVERSION": "",
+	}
+	for i := 0; i < 2000; i++ {
+		values[fmt.Sprintf("OPTION_%04d", i)] = []string{"y", "m", "n"}[i%3]
+	}
+	return values
+}
+
+// benchmarkPciDevices returns a realistic number of PCI device instances.
+func benchmarkPciDevices() []nfdv1alpha1.InstanceFeature {
+	classes := []string{"0200", "0300", "0604", "0880", "0c03", "1200"}
+	vendors := []string{"8086", "10de", "15b3", "1af4"}
+	instances := make([]nfdv1alpha1.InstanceFeature, 48)
+	for i := range instances {
+		instances[i] = *nfdv1alpha1.NewInstanceFeature(map[string]string{
+			"class":            classes[i%len(classes)],
+			"vendor":           vendors[i%len(vendors)],
+			"device":           fmt.Sprintf("%04x", 0x1000+i),
+			"subsystem_vendor": vendors[(i+1)%len(vendors)],
+			"subsystem_device": fmt.Sprintf("%04x", 0x2000+i),
+			"sriov_totalvfs":   fmt.Sprintf("%d", i%8),
+		})
+	}
+	return instances
+}
+
+func BenchmarkMatchGetKeys(b *testing.B) {
+	keys := benchmarkCpuidFlags()
+	m := &nfdv1alpha1.MatchExpressionSet{
+		"AVX512F":    newMatchExpression(nfdv1alpha1.MatchExists),
+		"AVX512VNNI": newMatchExpression(nfdv1alpha1.MatchExists),
+		"AESNI":      newMatchExpression(nfdv1alpha1.MatchExists),
+		"SGX":        newMatchExpression(nfdv1alpha1.MatchDoesNotExist),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ok, _, err := MatchGetKeys(m, keys); !ok || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkMatchKeys(b *testing.B) {
+	keys := benchmarkCpuidFlags()
+	m := &nfdv1alpha1.MatchExpressionSet{
+		"AVX512F":    newMatchExpression(nfdv1alpha1.MatchExists),
+		"AVX512VNNI": newMatchExpression(nfdv1alpha1.MatchExists),
+		"AESNI":      newMatchExpression(nfdv1alpha1.MatchExists),
+		"SGX":        newMatchExpression(nfdv1alpha1.MatchDoesNotExist),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ok, err := MatchKeys(m, keys); !ok || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkMatchGetValues(b *testing.B) {
+	values := benchmarkKconfig()
+	m := &nfdv1alpha1.MatchExpressionSet{
+		"NO_HZ_FULL": newMatchExpression(nfdv1alpha1.MatchIn, "y"),
+		"PREEMPT_RT": newMatchExpression(nfdv1alpha1.MatchIsTrue),
+		"NR_CPUS":    newMatchExpression(nfdv1alpha1.MatchGt, "1024"),
+		"HZ":         newMatchExpression(nfdv1alpha1.MatchInRegexp, "^[0-9]+$"),
+	}
+	values["PREEMPT_RT"] = "true"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ok, _, err := MatchGetValues(m, values); !ok || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkMatchGetInstances(b *testing.B) {
+	instances := benchmarkPciDevices()
+	m := &nfdv1alpha1.MatchExpressionSet{
+		"vendor":         newMatchExpression(nfdv1alpha1.MatchIn, "15b3", "10de"),
+		"class":          newMatchExpression(nfdv1alpha1.MatchInRegexp, "^02", "^12"),
+		"sriov_totalvfs": newMatchExpression(nfdv1alpha1.MatchGt, "0"),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ret, err := MatchGetInstances(m, instances); len(ret) == 0 || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ret, err)
+		}
+	}
+}
+
+func BenchmarkMatchInstances(b *testing.B) {
+	instances := benchmarkPciDevices()
+	m := &nfdv1alpha1.MatchExpressionSet{
+		"vendor": newMatchExpression(nfdv1alpha1.MatchIn, "15b3"),
+		"class":  newMatchExpression(nfdv1alpha1.MatchIn, "0200"),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ok, err := MatchInstances(m, instances); !ok || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkMatchInRegexp(b *testing.B) {
+	keys := benchmarkCpuidFlags()
+	m := newMatchExpression(nfdv1alpha1.MatchInRegexp, "^AVX512", "^SSE4")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if ok, _, err := MatchKeyNames(m, keys); !ok || err != nil {
+			b.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+	}
+}
@@ -19,12 +19,13 @@ package nodefeaturerule
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	strings "strings"
 
+	"github.com/go-logr/logr"
 	"golang.org/x/exp/maps"
-	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 )
@@ -43,128 +44,479 @@ var matchOps = map[nfdv1alpha1.MatchOp]struct{}{
 	nfdv1alpha1.MatchIsFalse:      {},
 }
 
-// evaluateMatchExpression evaluates the MatchExpression against a single input value.
-func evaluateMatchExpression(m *nfdv1alpha1.MatchExpression, valid bool, value interface{}) (bool, error) {
+// compiledMatchExpression is a MatchExpression with its values pre-parsed and
+// validated, so that they are not re-parsed for every evaluated value.
+type compiledMatchExpression struct {
+	op    nfdv1alpha1.MatchOp
+	value nfdv1alpha1.MatchValue
+
+	// ints contains the values of MatchGt, MatchLt and MatchGtLt.
+	ints []int64
+	// regexps contains the values of MatchInRegexp.
+	regexps []*regexp.Regexp
+	// hasCaptures is true if some of the regexps have named capture groups.
+	hasCaptures bool
+	// parse is the parser normalizing the values, and parsed contains the
+	// normalized values of the expression, if the expression has a parser.
+	parse  nfdv1alpha1.ValueParser
+	parsed [][]int64
+	// valueFrom is the variable providing the values of the expression. The
+	// expression must be bound with bindValues before it is evaluated.
+	valueFrom string
+	// err is the error in the expression detected before parsing the input
+	// value.
+	err error
+	// valueErr is the error in the expression values (of numeric ops)
+	// detected after parsing the input value.
+	valueErr error
+}
+
+// compileMatchExpression parses and validates a MatchExpression. Errors in
+// the expression are stored in the compiled expression and reported when it
+// is evaluated.
+func compileMatchExpression(m *nfdv1alpha1.MatchExpression) *compiledMatchExpression {
+	if m == nil {
+		return &compiledMatchExpression{err: newError(ErrInvalidExpression, "invalid expression, must not be empty")}
+	}
+
+	c := &compiledMatchExpression{op: m.Op, value: slices.Clone(m.Value)}
 	if _, ok := matchOps[m.Op]; !ok {
-		return false, fmt.Errorf("invalid Op %q", m.Op)
+		c.err = newError(ErrInvalidOp, "invalid Op %q", m.Op)
+		return c
+	}
+
+	if m.Parse != "" {
+		if c.err = c.compileParsedValues(m); c.err != nil {
+			return c
+		}
+	}
+
+	if m.ValueFrom != "" {
+		switch {
+		case m.Op != nfdv1alpha1.MatchIn && m.Op != nfdv1alpha1.MatchNotIn:
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'valueFrom' is not supported with Op %q", m.Op)
+		case len(m.Value) != 0:
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' and 'valueFrom' must not be used together")
+		default:
+			c.valueFrom = m.ValueFrom
+		}
+		return c
 	}
 
 	switch m.Op {
-	case nfdv1alpha1.MatchAny:
+	case nfdv1alpha1.MatchAny, nfdv1alpha1.MatchExists, nfdv1alpha1.MatchDoesNotExist:
 		if len(m.Value) != 0 {
-			return false, fmt.Errorf("invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
 		}
-		return true, nil
-	case nfdv1alpha1.MatchExists:
-		if len(m.Value) != 0 {
-			return false, fmt.Errorf("invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
+	case nfdv1alpha1.MatchIn, nfdv1alpha1.MatchNotIn:
+		if len(m.Value) == 0 {
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' field must be non-empty for Op %q", m.Op)
 		}
-		return valid, nil
-	case nfdv1alpha1.MatchDoesNotExist:
+	case nfdv1alpha1.MatchInRegexp:
+		if len(m.Value) == 0 {
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' field must be non-empty for Op %q", m.Op)
+			break
+		}
+		c.regexps = make([]*regexp.Regexp, len(m.Value))
+		for i, v := range m.Value {
+			re, err := compileRegexp(v)
+			if err != nil {
+				c.err = newError(ErrBadRegexp, "invalid expressiom, 'value' field must only contain valid regexps for Op %q (have %v): %w", m.Op, m.Value, err)
+				break
+			}
+			c.regexps[i] = re
+			c.hasCaptures = c.hasCaptures || slices.ContainsFunc(re.SubexpNames(), func(n string) bool { return n != "" })
+		}
+	case nfdv1alpha1.MatchGt, nfdv1alpha1.MatchLt:
+		if len(m.Value) != 1 {
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' field must contain exactly one element for Op %q (have %v)", m.Op, m.Value)
+			break
+		}
+		if c.parsed != nil {
+			break
+		}
+		c.ints = make([]int64, 1)
+		var err error
+		if c.ints[0], err = strconv.ParseInt(m.Value[0], 10, 64); err != nil {
+			c.valueErr = newError(ErrNotANumber, "not a number %q in %v", m.Value[0], m)
+		}
+	case nfdv1alpha1.MatchGtLt:
+		if len(m.Value) != 2 {
+			c.err = newError(ErrInvalidExpression, "invalid expression, value' field must contain exactly two elements for Op %q (have %v)", m.Op, m.Value)
+			break
+		}
+		if c.parsed != nil {
+			if compareParsed(c.parsed[0], c.parsed[1]) >= 0 {
+				c.valueErr = newError(ErrInvalidExpression, "invalid expression, value[0] must be less than Value[1] for Op %q (have %v)", m.Op, m.Value)
+			}
+			break
+		}
+		c.ints = make([]int64, 2)
+		for i := 0; i < 2; i++ {
+			var err error
+			if c.ints[i], err = strconv.ParseInt(m.Value[i], 10, 64); err != nil {
+				c.valueErr = newError(ErrNotANumber, "not a number %q in %v", m.Value[i], m)
+				break
+			}
+		}
+		if c.valueErr == nil && c.ints[0] >= c.ints[1] {
+			c.valueErr = newError(ErrInvalidExpression, "invalid expression, value[0] must be less than Value[1] for Op %q (have %v)", m.Op, m.Value)
+		}
+	case nfdv1alpha1.MatchIsTrue, nfdv1alpha1.MatchIsFalse:
 		if len(m.Value) != 0 {
-			return false, fmt.Errorf("invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
+			c.err = newError(ErrInvalidExpression, "invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
 		}
-		return !valid, nil
 	}
+	return c
+}
+
+// compileParsedValues validates the parser of an expression and normalizes
+// the values of the expression with it.
+func (c *compiledMatchExpression) compileParsedValues(m *nfdv1alpha1.MatchExpression) error {
+	if !isValidParser(m.Parse) {
+		return newError(ErrInvalidParser, "invalid expression, unknown parser %q", m.Parse)
+	}
+	switch m.Op {
+	case nfdv1alpha1.MatchIn, nfdv1alpha1.MatchNotIn, nfdv1alpha1.MatchGt, nfdv1alpha1.MatchLt, nfdv1alpha1.MatchGtLt:
+	default:
+		return newError(ErrInvalidParser, "invalid expression, parser %q is not supported with Op %q", m.Parse, m.Op)
+	}
+
+	c.parse = m.Parse
+	c.parsed = make([][]int64, len(m.Value))
+	for i, v := range m.Value {
+		p, err := parseValue(v, m.Parse)
+		if err != nil {
+			return newError(ErrParseValue, "invalid expression, cannot parse value %q as %s: %w", v, m.Parse, err)
+		}
+		c.parsed[i] = p
+	}
+	return nil
+}
+
+// namedMatchExpression is a compiled expression of a MatchExpressionSet,
+// together with the name of the feature element it applies to.
+type namedMatchExpression struct {
+	name string
+	expr *compiledMatchExpression
+}
+
+// compiledMatchExpressionSet is a compiled MatchExpressionSet. The
+// expressions are sorted by name so that they are always evaluated in the
+// same order.
+type compiledMatchExpressionSet []namedMatchExpression
 
-	if valid {
-		value := fmt.Sprintf("%v", value)
-		switch m.Op {
-		case nfdv1alpha1.MatchIn:
-			if len(m.Value) == 0 {
-				return false, fmt.Errorf("invalid expression, 'value' field must be non-empty for Op %q", m.Op)
+// compileMatchExpressionSet compiles all expressions of a MatchExpressionSet.
+func compileMatchExpressionSet(m *nfdv1alpha1.MatchExpressionSet) compiledMatchExpressionSet {
+	names := maps.Keys(*m)
+	sort.Strings(names)
+
+	ret := make(compiledMatchExpressionSet, len(names))
+	for i, n := range names {
+		ret[i] = namedMatchExpression{name: n, expr: compileMatchExpression((*m)[n])}
+	}
+	return ret
+}
+
+// matchExpressionPath returns the path of an expression of a
+// MatchExpressionSet used in errors.
+func matchExpressionPath(name string) string {
+	return fmt.Sprintf("matchExpressions[%q]", name)
+}
+
+// bindValues returns the expression with the values of its variable, if it
+// has one, filled in from the given variables. Unbound variables are reported
+// as errors when the expression is evaluated.
+func (c *compiledMatchExpression) bindValues(vars map[string][]string) *compiledMatchExpression {
+	if c.valueFrom == "" {
+		return c
+	}
+	values, ok := vars[c.valueFrom]
+	if !ok {
+		return c
+	}
+	return compileMatchExpression(&nfdv1alpha1.MatchExpression{Op: c.op, Value: values, Parse: c.parse})
+}
+
+// bindValues returns the set with the variables of its expressions filled in
+// from the given variables. The set is returned as is if none of the
+// expressions has a variable.
+func (s compiledMatchExpressionSet) bindValues(vars map[string][]string) compiledMatchExpressionSet {
+	ret := s
+	cloned := false
+	for i, e := range s {
+		if e.expr.valueFrom == "" {
+			continue
+		}
+		if !cloned {
+			ret = slices.Clone(s)
+			cloned = true
+		}
+		ret[i].expr = e.expr.bindValues(vars)
+	}
+	return ret
+}
+
+// evaluate evaluates the expression against a single input value.
+func (c *compiledMatchExpression) evaluate(valid bool, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case string:
+		return c.evaluateString(valid, v)
+	case int:
+		return c.evaluateInt(valid, int64(v))
+	case bool:
+		return c.evaluateBool(valid, v)
+	}
+	return c.evaluateString(valid, fmt.Sprintf("%v", value))
+}
+
+// preEvaluate evaluates the parts of the expression that do not depend on the
+// input value. Done is true if the result does not depend on the input value.
+func (c *compiledMatchExpression) preEvaluate(valid bool) (done, matched bool, err error) {
+	if _, ok := matchOps[c.op]; !ok {
+		return true, false, c.err
+	}
+	if c.valueFrom != "" {
+		return true, false, newError(ErrUnboundVariable, "variable %q is not bound", c.valueFrom)
+	}
+
+	switch c.op {
+	case nfdv1alpha1.MatchAny:
+		return true, c.err == nil, c.err
+	case nfdv1alpha1.MatchExists:
+		return true, c.err == nil && valid, c.err
+	case nfdv1alpha1.MatchDoesNotExist:
+		return true, c.err == nil && !valid, c.err
+	}
+
+	if !valid {
+		return true, false, nil
+	}
+	if c.err != nil {
+		return true, false, c.err
+	}
+	return false, false, nil
+}
+
+// evaluateString evaluates the expression against a single input value in
+// string form.
+func (c *compiledMatchExpression) evaluateString(valid bool, value string) (bool, error) {
+	if done, matched, err := c.preEvaluate(valid); done {
+		return matched, err
+	}
+	if c.parse != "" {
+		return c.evaluateParsed(value)
+	}
+
+	switch c.op {
+	case nfdv1alpha1.MatchIn:
+		for _, v := range c.value {
+			if value == v {
+				return true, nil
 			}
-			for _, v := range m.Value {
-				if value == v {
-					return true, nil
-				}
+		}
+	case nfdv1alpha1.MatchNotIn:
+		for _, v := range c.value {
+			if value == v {
+				return false, nil
 			}
-		case nfdv1alpha1.MatchNotIn:
-			if len(m.Value) == 0 {
-				return false, fmt.Errorf("invalid expression, 'value' field must be non-empty for Op %q", m.Op)
+		}
+		return true, nil
+	case nfdv1alpha1.MatchInRegexp:
+		for _, re := range c.regexps {
+			if re.MatchString(value) {
+				return true, nil
 			}
-			for _, v := range m.Value {
-				if value == v {
-					return false, nil
-				}
+		}
+	case nfdv1alpha1.MatchGt, nfdv1alpha1.MatchLt, nfdv1alpha1.MatchGtLt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, newError(ErrNotANumber, "not a number %q", value)
+		}
+		return c.compareInt(v)
+	case nfdv1alpha1.MatchIsTrue:
+		return value == "true", nil
+	case nfdv1alpha1.MatchIsFalse:
+		return value == "false", nil
+	}
+	return false, nil
+}
+
+// evaluateParsed evaluates the expression against a single input value in
+// string form, normalizing the value with the parser of the expression.
+func (c *compiledMatchExpression) evaluateParsed(value string) (bool, error) {
+	if c.valueErr != nil {
+		return false, c.valueErr
+	}
+	v, err := parseValue(value, c.parse)
+	if err != nil {
+		return false, newError(ErrParseValue, "cannot parse %q as %s: %w", value, c.parse, err)
+	}
+
+	switch c.op {
+	case nfdv1alpha1.MatchIn:
+		for _, p := range c.parsed {
+			if compareParsed(v, p) == 0 {
+				return true, nil
 			}
-			return true, nil
-		case nfdv1alpha1.MatchInRegexp:
-			if len(m.Value) == 0 {
-				return false, fmt.Errorf("invalid expression, 'value' field must be non-empty for Op %q", m.Op)
+		}
+	case nfdv1alpha1.MatchNotIn:
+		for _, p := range c.parsed {
+			if compareParsed(v, p) == 0 {
+				return false, nil
 			}
-			valueRe := make([]*regexp.Regexp, len(m.Value))
-			for i, v := range m.Value {
-				re, err := regexp.Compile(v)
-				if err != nil {
-					return false, fmt.Errorf("invalid expressiom, 'value' field must only contain valid regexps for Op %q (have %v)", m.Op, m.Value)
+		}
+		return true, nil
+	case nfdv1alpha1.MatchGt:
+		return compareParsed(v, c.parsed[0]) > 0, nil
+	case nfdv1alpha1.MatchLt:
+		return compareParsed(v, c.parsed[0]) < 0, nil
+	case nfdv1alpha1.MatchGtLt:
+		return compareParsed(v, c.parsed[0]) > 0 && compareParsed(v, c.parsed[1]) < 0, nil
+	}
+	return false, nil
+}
+
+// evaluateInt evaluates the expression against a single integer input value.
+// Numeric comparisons are done without converting the value to a string.
+func (c *compiledMatchExpression) evaluateInt(valid bool, value int64) (bool, error) {
+	if c.parse != "" {
+		return c.evaluateString(valid, strconv.FormatInt(value, 10))
+	}
+	switch c.op {
+	case nfdv1alpha1.MatchGt, nfdv1alpha1.MatchLt, nfdv1alpha1.MatchGtLt:
+		if done, matched, err := c.preEvaluate(valid); done {
+			return matched, err
+		}
+		return c.compareInt(value)
+	}
+	return c.evaluateString(valid, strconv.FormatInt(value, 10))
+}
+
+// evaluateBool evaluates the expression against a single boolean input value.
+func (c *compiledMatchExpression) evaluateBool(valid bool, value bool) (bool, error) {
+	switch c.op {
+	case nfdv1alpha1.MatchIsTrue, nfdv1alpha1.MatchIsFalse:
+		if done, matched, err := c.preEvaluate(valid); done {
+			return matched, err
+		}
+		return value == (c.op == nfdv1alpha1.MatchIsTrue), nil
+	}
+	return c.evaluateString(valid, strconv.FormatBool(value))
+}
+
+// evaluateTyped evaluates the expression against a single input value in
+// string form, having the given type. Numeric and boolean values are matched
+// like evaluateInt and evaluateBool do. The In, NotIn and InRegexp operators
+// match the individual items of list values: In and InRegexp match if any
+// item matches, NotIn if no item matches.
+func (c *compiledMatchExpression) evaluateTyped(valid bool, value string, t nfdv1alpha1.AttributeType) (bool, error) {
+	if !valid || t == "" || t == nfdv1alpha1.AttributeTypeString {
+		return c.evaluateString(valid, value)
+	}
+	typed, err := nfdv1alpha1.ParseAttributeValue(value, t)
+	if err != nil {
+		return c.evaluateString(valid, value)
+	}
+
+	switch v := typed.(type) {
+	case int64:
+		return c.evaluateInt(valid, v)
+	case bool:
+		return c.evaluateBool(valid, v)
+	case []string:
+		switch c.op {
+		case nfdv1alpha1.MatchIn, nfdv1alpha1.MatchInRegexp:
+			for _, item := range v {
+				if matched, err := c.evaluateString(valid, item); err != nil || matched {
+					return matched, err
 				}
-				valueRe[i] = re
 			}
-			for _, re := range valueRe {
-				if re.MatchString(value) {
-					return true, nil
+			return false, nil
+		case nfdv1alpha1.MatchNotIn:
+			for _, item := range v {
+				if matched, err := c.evaluateString(valid, item); err != nil || !matched {
+					return matched, err
 				}
 			}
-		case nfdv1alpha1.MatchGt, nfdv1alpha1.MatchLt:
-			if len(m.Value) != 1 {
-				return false, fmt.Errorf("invalid expression, 'value' field must contain exactly one element for Op %q (have %v)", m.Op, m.Value)
-			}
+			return true, nil
+		}
+	}
+	return c.evaluateString(valid, value)
+}
 
-			l, err := strconv.Atoi(value)
-			if err != nil {
-				return false, fmt.Errorf("not a number %q", value)
-			}
-			r, err := strconv.Atoi(m.Value[0])
-			if err != nil {
-				return false, fmt.Errorf("not a number %q in %v", m.Value[0], m)
+// captures returns the named capture groups of the regexp of an InRegexp
+// expression that matches the value, having the given type. The regexps are
+// tried in the same order as in evaluateTyped. Nil is returned if the
+// matching regexp has no named capture groups.
+func (c *compiledMatchExpression) captures(value string, t nfdv1alpha1.AttributeType) map[string]string {
+	if !c.hasCaptures {
+		return nil
+	}
+	items := []string{value}
+	if t != "" && t != nfdv1alpha1.AttributeTypeString {
+		if typed, err := nfdv1alpha1.ParseAttributeValue(value, t); err == nil {
+			if list, ok := typed.([]string); ok {
+				items = list
 			}
+		}
+	}
 
-			if (l < r && m.Op == nfdv1alpha1.MatchLt) || (l > r && m.Op == nfdv1alpha1.MatchGt) {
-				return true, nil
-			}
-		case nfdv1alpha1.MatchGtLt:
-			if len(m.Value) != 2 {
-				return false, fmt.Errorf("invalid expression, value' field must contain exactly two elements for Op %q (have %v)", m.Op, m.Value)
-			}
-			v, err := strconv.Atoi(value)
-			if err != nil {
-				return false, fmt.Errorf("not a number %q", value)
+	for _, item := range items {
+		for _, re := range c.regexps {
+			m := re.FindStringSubmatch(item)
+			if m == nil {
+				continue
 			}
-			lr := make([]int, 2)
-			for i := 0; i < 2; i++ {
-				lr[i], err = strconv.Atoi(m.Value[i])
-				if err != nil {
-					return false, fmt.Errorf("not a number %q in %v", m.Value[i], m)
+			var ret map[string]string
+			for i, name := range re.SubexpNames() {
+				if name == "" {
+					continue
 				}
+				if ret == nil {
+					ret = make(map[string]string)
+				}
+				ret[name] = m[i]
 			}
-			if lr[0] >= lr[1] {
-				return false, fmt.Errorf("invalid expression, value[0] must be less than Value[1] for Op %q (have %v)", m.Op, m.Value)
-			}
-			return v > lr[0] && v < lr[1], nil
-		case nfdv1alpha1.MatchIsTrue:
-			if len(m.Value) != 0 {
-				return false, fmt.Errorf("invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
-			}
-			return value == "true", nil
-		case nfdv1alpha1.MatchIsFalse:
-			if len(m.Value) != 0 {
-				return false, fmt.Errorf("invalid expression, 'value' field must be empty for Op %q (have %v)", m.Op, m.Value)
-			}
-			return value == "false", nil
-		default:
-			return false, fmt.Errorf("unsupported Op %q", m.Op)
+			return ret
+		}
+	}
+	return nil
+}
+
+// addCaptures adds the named capture groups of a regexp match to a matched
+// element. Captures do not override the existing keys of the element, e.g.
+// Name and Value.
+func (e MatchedElement) addCaptures(captures map[string]string) {
+	for k, v := range captures {
+		if _, ok := e[k]; !ok {
+			e[k] = v
 		}
 	}
-	return false, nil
 }
 
-// evaluateMatchExpressionKeys evaluates the MatchExpression against a set of keys.
-func evaluateMatchExpressionKeys(m *nfdv1alpha1.MatchExpression, name string, keys map[string]nfdv1alpha1.Nil) (bool, error) {
+// compareInt evaluates a numeric expression against an integer value.
+func (c *compiledMatchExpression) compareInt(value int64) (bool, error) {
+	if c.valueErr != nil {
+		return false, c.valueErr
+	}
+	switch c.op {
+	case nfdv1alpha1.MatchGt:
+		return value > c.ints[0], nil
+	case nfdv1alpha1.MatchLt:
+		return value < c.ints[0], nil
+	case nfdv1alpha1.MatchGtLt:
+		return value > c.ints[0] && value < c.ints[1], nil
+	}
+	return false, newError(ErrInvalidOp, "unsupported Op %q", c.op)
+}
+
+// evaluateKeys evaluates the expression against a set of keys.
+func (c *compiledMatchExpression) evaluateKeys(logger logr.Logger, name string, keys map[string]nfdv1alpha1.Nil) (bool, error) {
 	matched := false
 
 	_, ok := keys[name]
-	switch m.Op {
+	switch c.op {
 	case nfdv1alpha1.MatchAny:
 		matched = true
 	case nfdv1alpha1.MatchExists:
@@ -172,31 +524,31 @@ func evaluateMatchExpressionKeys(m *nfdv1alpha1.MatchExpression, name string, ke
 	case nfdv1alpha1.MatchDoesNotExist:
 		matched = !ok
 	default:
-		return false, fmt.Errorf("invalid Op %q when matching keys", m.Op)
+		return false, newError(ErrInvalidOp, "invalid Op %q when matching keys", c.op)
 	}
 
-	if klogV := klog.V(3); klogV.Enabled() {
-		klogV.InfoS("matched keys", "matchResult", matched, "matchKey", name, "matchOp", m.Op)
-	} else if klogV := klog.V(4); klogV.Enabled() {
+	if l := logger.V(3); l.Enabled() {
+		l.Info("matched keys", "matchResult", matched, "matchKey", name, "matchOp", c.op)
+	} else if l := logger.V(4); l.Enabled() {
 		k := maps.Keys(keys)
 		sort.Strings(k)
-		klogV.InfoS("matched keys", "matchResult", matched, "matchKey", name, "matchOp", m.Op, "inputKeys", k)
+		l.Info("matched keys", "matchResult", matched, "matchKey", name, "matchOp", c.op, "inputKeys", k)
 	}
 	return matched, nil
 }
 
-// evaluateMatchExpressionValues evaluates the MatchExpression against a set of key-value pairs.
-func evaluateMatchExpressionValues(m *nfdv1alpha1.MatchExpression, name string, values map[string]string) (bool, error) {
+// evaluateValues evaluates the expression against a set of key-value pairs.
+func (c *compiledMatchExpression) evaluateValues(logger logr.Logger, name string, values map[string]string, types map[string]nfdv1alpha1.AttributeType) (bool, error) {
 	v, ok := values[name]
-	matched, err := evaluateMatchExpression(m, ok, v)
+	matched, err := c.evaluateTyped(ok, v, types[name])
 	if err != nil {
 		return false, err
 	}
 
-	if klogV := klog.V(3); klogV.Enabled() {
-		klogV.InfoS("matched values", "matchResult", matched, "matchKey", name, "matchOp", m.Op, "matchValue", m.Value)
-	} else if klogV := klog.V(4); klogV.Enabled() {
-		klogV.InfoS("matched values", "matchResult", matched, "matchKey", name, "matchOp", m.Op, "matchValue", m.Value, "inputValues", values)
+	if l := logger.V(3); l.Enabled() {
+		l.Info("matched values", "matchResult", matched, "matchKey", name, "matchOp", c.op, "matchValue", c.value)
+	} else if l := logger.V(4); l.Enabled() {
+		l.Info("matched values", "matchResult", matched, "matchKey", name, "matchOp", c.op, "matchValue", c.value, "inputValues", values)
 	}
 
 	return matched, nil
@@ -204,34 +556,36 @@ func evaluateMatchExpressionValues(m *nfdv1alpha1.MatchExpression, name string,
 
 // MatchKeyNames evaluates the MatchExpression against names of a set of key features.
 func MatchKeyNames(m *nfdv1alpha1.MatchExpression, keys map[string]nfdv1alpha1.Nil) (bool, []MatchedElement, error) {
+	return compileMatchExpression(m).matchKeyNames(logr.Discard(), keys)
+}
+
+func (c *compiledMatchExpression) matchKeyNames(logger logr.Logger, keys map[string]nfdv1alpha1.Nil) (bool, []MatchedElement, error) {
 	ret := []MatchedElement{}
 
-	for k := range keys {
-		if match, err := evaluateMatchExpression(m, true, k); err != nil {
-			return false, nil, err
+	// Evaluate in sorted order for reproducible output
+	names := maps.Keys(keys)
+	sort.Strings(names)
+	for _, k := range names {
+		if match, err := c.evaluateString(true, k); err != nil {
+			return false, nil, annotate(err, "", "", "matchName")
 		} else if match {
-			ret = append(ret, MatchedElement{"Name": k})
+			elem := MatchedElement{"Name": k}
+			elem.addCaptures(c.captures(k, ""))
+			ret = append(ret, elem)
 		}
 	}
-	// Sort for reproducible output
-	sort.Slice(ret, func(i, j int) bool { return ret[i]["Name"] < ret[j]["Name"] })
 
-	if klogV3 := klog.V(3); klogV3.Enabled() {
+	if l := logger.V(3); l.Enabled() {
 		mk := make([]string, len(ret))
 		for i, v := range ret {
 			mk[i] = v["Name"]
 		}
 		mkMsg := strings.Join(mk, ", ")
 
-		if klogV4 := klog.V(4); klogV4.Enabled() {
-			k := make([]string, 0, len(keys))
-			for n := range keys {
-				k = append(k, n)
-			}
-			sort.Strings(k)
-			klogV3.InfoS("matched (key) names", "matchResult", mkMsg, "matchOp", m.Op, "matchValue", m.Value, "inputKeys", k)
+		if logger.V(4).Enabled() {
+			l.Info("matched (key) names", "matchResult", mkMsg, "matchOp", c.op, "matchValue", c.value, "inputKeys", names)
 		} else {
-			klogV3.InfoS("matched (key) names", "matchResult", mkMsg, "matchOp", m.Op, "matchValue", m.Value)
+			l.Info("matched (key) names", "matchResult", mkMsg, "matchOp", c.op, "matchValue", c.value)
 		}
 	}
 
@@ -240,29 +594,36 @@ func MatchKeyNames(m *nfdv1alpha1.MatchExpression, keys map[string]nfdv1alpha1.N
 
 // MatchValueNames evaluates the MatchExpression against names of a set of value features.
 func MatchValueNames(m *nfdv1alpha1.MatchExpression, values map[string]string) (bool, []MatchedElement, error) {
+	return compileMatchExpression(m).matchValueNames(logr.Discard(), values)
+}
+
+func (c *compiledMatchExpression) matchValueNames(logger logr.Logger, values map[string]string) (bool, []MatchedElement, error) {
 	ret := []MatchedElement{}
 
-	for k, v := range values {
-		if match, err := evaluateMatchExpression(m, true, k); err != nil {
-			return false, nil, err
+	// Evaluate in sorted order for reproducible output
+	names := maps.Keys(values)
+	sort.Strings(names)
+	for _, k := range names {
+		if match, err := c.evaluateString(true, k); err != nil {
+			return false, nil, annotate(err, "", "", "matchName")
 		} else if match {
-			ret = append(ret, MatchedElement{"Name": k, "Value": v})
+			elem := MatchedElement{"Name": k, "Value": values[k]}
+			elem.addCaptures(c.captures(k, ""))
+			ret = append(ret, elem)
 		}
 	}
-	// Sort for reproducible output
-	sort.Slice(ret, func(i, j int) bool { return ret[i]["Name"] < ret[j]["Name"] })
 
-	if klogV3 := klog.V(3); klogV3.Enabled() {
+	if l := logger.V(3); l.Enabled() {
 		mk := make([]string, len(ret))
 		for i, v := range ret {
 			mk[i] = v["Name"]
 		}
 		mkMsg := strings.Join(mk, ", ")
 
-		if klogV4 := klog.V(4); klogV4.Enabled() {
-			klogV3.InfoS("matched (value) names", "matchResult", mkMsg, "matchOp", m.Op, "matchValue", m.Value, "inputValues", values)
+		if logger.V(4).Enabled() {
+			l.Info("matched (value) names", "matchResult", mkMsg, "matchOp", c.op, "matchValue", c.value, "inputValues", values)
 		} else {
-			klogV3.InfoS("matched (value) names", "matchResult", mkMsg, "matchOp", m.Op, "matchValue", m.Value)
+			l.Info("matched (value) names", "matchResult", mkMsg, "matchOp", c.op, "matchValue", c.value)
 		}
 	}
 
@@ -272,10 +633,14 @@ func MatchValueNames(m *nfdv1alpha1.MatchExpression, values map[string]string) (
 // MatchInstanceAttributeNames evaluates the MatchExpression against a set of
 // instance features, matching against the names of their attributes.
 func MatchInstanceAttributeNames(m *nfdv1alpha1.MatchExpression, instances []nfdv1alpha1.InstanceFeature) ([]MatchedElement, error) {
+	return compileMatchExpression(m).matchInstanceAttributeNames(logr.Discard(), instances)
+}
+
+func (c *compiledMatchExpression) matchInstanceAttributeNames(logger logr.Logger, instances []nfdv1alpha1.InstanceFeature) ([]MatchedElement, error) {
 	ret := []MatchedElement{}
 
 	for _, i := range instances {
-		if match, _, err := MatchValueNames(m, i.Attributes); err != nil {
+		if match, _, err := c.matchValueNames(logger, i.Attributes); err != nil {
 			return nil, err
 		} else if match {
 			ret = append(ret, i.Attributes)
@@ -286,7 +651,7 @@ func MatchInstanceAttributeNames(m *nfdv1alpha1.MatchExpression, instances []nfd
 
 // MatchKeys evaluates the MatchExpressionSet against a set of keys.
 func MatchKeys(m *nfdv1alpha1.MatchExpressionSet, keys map[string]nfdv1alpha1.Nil) (bool, error) {
-	matched, _, err := MatchGetKeys(m, keys)
+	matched, _, err := compileMatchExpressionSet(m).matchKeys(logr.Discard(), keys, false)
 	return matched, err
 }
 
@@ -297,26 +662,36 @@ type MatchedElement map[string]string
 // returns all matched keys or nil if no match was found. Note that an empty
 // MatchExpressionSet returns a match with an empty slice of matched features.
 func MatchGetKeys(m *nfdv1alpha1.MatchExpressionSet, keys map[string]nfdv1alpha1.Nil) (bool, []MatchedElement, error) {
-	ret := make([]MatchedElement, 0, len(*m))
+	return compileMatchExpressionSet(m).matchKeys(logr.Discard(), keys, true)
+}
 
-	for n, e := range *m {
-		match, err := evaluateMatchExpressionKeys(e, n, keys)
+// matchKeys implements MatchKeys and MatchGetKeys. The matched keys are only
+// collected if requested. The expressions are evaluated in sorted order, so
+// the result (an error or no match) and the output are reproducible.
+func (s compiledMatchExpressionSet) matchKeys(logger logr.Logger, keys map[string]nfdv1alpha1.Nil, collect bool) (bool, []MatchedElement, error) {
+	var ret []MatchedElement
+	if collect {
+		ret = make([]MatchedElement, 0, len(s))
+	}
+
+	for _, e := range s {
+		match, err := e.expr.evaluateKeys(logger, e.name, keys)
 		if err != nil {
-			return false, nil, err
+			return false, nil, annotate(err, "", "", matchExpressionPath(e.name))
 		}
 		if !match {
 			return false, nil, nil
 		}
-		ret = append(ret, MatchedElement{"Name": n})
+		if collect {
+			ret = append(ret, MatchedElement{"Name": e.name})
+		}
 	}
-	// Sort for reproducible output
-	sort.Slice(ret, func(i, j int) bool { return ret[i]["Name"] < ret[j]["Name"] })
 	return true, ret, nil
 }
 
 // MatchValues evaluates the MatchExpressionSet against a set of key-value pairs.
 func MatchValues(m *nfdv1alpha1.MatchExpressionSet, values map[string]string) (bool, error) {
-	matched, _, err := MatchGetValues(m, values)
+	matched, _, err := compileMatchExpressionSet(m).matchValues(logr.Discard(), values, nil, false)
 	return matched, err
 }
 
@@ -324,20 +699,37 @@ func MatchValues(m *nfdv1alpha1.MatchExpressionSet, values map[string]string) (b
 // pairs and returns all matched key-value pairs. Note that an empty
 // MatchExpressionSet returns a match with an empty slice of matched features.
 func MatchGetValues(m *nfdv1alpha1.MatchExpressionSet, values map[string]string) (bool, []MatchedElement, error) {
-	ret := make([]MatchedElement, 0, len(*m))
+	return compileMatchExpressionSet(m).matchValues(logr.Discard(), values, nil, true)
+}
+
+// matchValues implements MatchValues and MatchGetValues. The matched
+// key-value pairs are only collected if requested. The expressions are
+// evaluated in sorted order, so the result (an error or no match) and the
+// output are reproducible. The matched elements of typed values contain the
+// type of the value.
+func (s compiledMatchExpressionSet) matchValues(logger logr.Logger, values map[string]string, types map[string]nfdv1alpha1.AttributeType, collect bool) (bool, []MatchedElement, error) {
+	var ret []MatchedElement
+	if collect {
+		ret = make([]MatchedElement, 0, len(s))
+	}
 
-	for n, e := range *m {
-		match, err := evaluateMatchExpressionValues(e, n, values)
+	for _, e := range s {
+		match, err := e.expr.evaluateValues(logger, e.name, values, types)
 		if err != nil {
-			return false, nil, err
+			return false, nil, annotate(err, "", "", matchExpressionPath(e.name))
 		}
 		if !match {
 			return false, nil, nil
 		}
-		ret = append(ret, MatchedElement{"Name": n, "Value": values[n]})
+		if collect {
+			elem := MatchedElement{"Name": e.name, "Value": values[e.name]}
+			if t, ok := types[e.name]; ok {
+				elem["Type"] = string(t)
+			}
+			elem.addCaptures(e.expr.captures(values[e.name], types[e.name]))
+			ret = append(ret, elem)
+		}
 	}
-	// Sort for reproducible output
-	sort.Slice(ret, func(i, j int) bool { return ret[i]["Name"] < ret[j]["Name"] })
 	return true, ret, nil
 }
 
@@ -354,13 +746,27 @@ func MatchInstances(m *nfdv1alpha1.MatchExpressionSet, instances []nfdv1alpha1.I
 // (attributes). A slice containing all matching instances is returned. An
 // empty (non-nil) slice is returned if no matching instances were found.
 func MatchGetInstances(m *nfdv1alpha1.MatchExpressionSet, instances []nfdv1alpha1.InstanceFeature) ([]MatchedElement, error) {
+	return compileMatchExpressionSet(m).matchInstances(logr.Discard(), instances, nil)
+}
+
+func (s compiledMatchExpressionSet) matchInstances(logger logr.Logger, instances []nfdv1alpha1.InstanceFeature, types map[string]nfdv1alpha1.AttributeType) ([]MatchedElement, error) {
 	ret := []MatchedElement{}
 
+	hasCaptures := slices.ContainsFunc(s, func(e namedMatchExpression) bool { return e.expr.hasCaptures })
 	for _, i := range instances {
-		if match, err := MatchValues(m, i.Attributes); err != nil {
+		if match, _, err := s.matchValues(logger, i.Attributes, types, false); err != nil {
 			return nil, err
 		} else if match {
-			ret = append(ret, i.Attributes)
+			if !hasCaptures {
+				ret = append(ret, i.Attributes)
+				continue
+			}
+			// Don't modify the attributes of the input feature
+			elem := MatchedElement(maps.Clone(i.Attributes))
+			for _, e := range s {
+				elem.addCaptures(e.expr.captures(i.Attributes[e.name], types[e.name]))
+			}
+			ret = append(ret, elem)
 		}
 	}
 	return ret, nil
@@ -19,6 +19,7 @@ package nodefeaturerule
 import (
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
@@ -91,7 +92,7 @@ func TestEvaluateMatchExpression(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
-			res, err := evaluateMatchExpression(me, tc.valid, tc.input)
+			res, err := compileMatchExpression(me).evaluate(tc.valid, tc.input)
 			tc.result(t, res)
 			assert.Nil(t, err)
 		})
@@ -139,7 +140,7 @@ func TestEvaluateMatchExpression(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
-			res, err := evaluateMatchExpression(me, true, tc.input)
+			res, err := compileMatchExpression(me).evaluate(true, tc.input)
 			assert.False(t, res)
 			assert.NotNil(t, err)
 		})
@@ -185,7 +186,7 @@ func TestEvaluateMatchExpressionKeys(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
-			res, err := evaluateMatchExpressionKeys(me, tc.key, tc.input)
+			res, err := compileMatchExpression(me).evaluateKeys(logr.Discard(), tc.key, tc.input)
 			tc.result(t, res)
 			tc.err(t, err)
 		})
@@ -254,7 +255,232 @@ func TestEvaluateMatchExpressionValues(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
-			res, err := evaluateMatchExpressionValues(me, tc.key, tc.input)
+			res, err := compileMatchExpression(me).evaluateValues(logr.Discard(), tc.key, tc.input, nil)
+			tc.result(t, res)
+			tc.err(t, err)
+		})
+	}
+}
+
+func TestEvaluateMatchExpressionTyped(t *testing.T) {
+	type V = nfdv1alpha1.MatchValue
+	type TC struct {
+		name   string
+		op     nfdv1alpha1.MatchOp
+		values V
+		input  interface{}
+		result BoolAssertionFunc
+		err    ValueAssertionFunc
+	}
+
+	tcs := []TC{
+		{name: "int-MatchGt-1", op: nfdv1alpha1.MatchGt, values: V{"1024"}, input: 2048, result: assert.True, err: assert.Nil},
+		{name: "int-MatchGt-2", op: nfdv1alpha1.MatchGt, values: V{"1024"}, input: 1024, result: assert.False, err: assert.Nil},
+		{name: "int-MatchGt-3", op: nfdv1alpha1.MatchGt, values: V{"str"}, input: 1024, result: assert.False, err: assert.NotNil},
+		{name: "int-MatchLt", op: nfdv1alpha1.MatchLt, values: V{"1024"}, input: -1, result: assert.True, err: assert.Nil},
+		{name: "int-MatchGtLt-1", op: nfdv1alpha1.MatchGtLt, values: V{"1", "10"}, input: 5, result: assert.True, err: assert.Nil},
+		{name: "int-MatchGtLt-2", op: nfdv1alpha1.MatchGtLt, values: V{"10", "1"}, input: 5, result: assert.False, err: assert.NotNil},
+		{name: "int-MatchIn", op: nfdv1alpha1.MatchIn, values: V{"1", "5"}, input: 5, result: assert.True, err: assert.Nil},
+		{name: "bool-MatchIsTrue-1", op: nfdv1alpha1.MatchIsTrue, input: true, result: assert.True, err: assert.Nil},
+		{name: "bool-MatchIsTrue-2", op: nfdv1alpha1.MatchIsTrue, values: V{"true"}, input: true, result: assert.False, err: assert.NotNil},
+		{name: "bool-MatchIsFalse", op: nfdv1alpha1.MatchIsFalse, input: true, result: assert.False, err: assert.Nil},
+		{name: "bool-MatchIn", op: nfdv1alpha1.MatchIn, values: V{"false"}, input: false, result: assert.True, err: assert.Nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
+			res, err := compileMatchExpression(me).evaluate(true, tc.input)
+			tc.result(t, res)
+			tc.err(t, err)
+		})
+	}
+}
+
+func TestEvaluateMatchExpressionValuesTyped(t *testing.T) {
+	type V = nfdv1alpha1.MatchValue
+	type TC struct {
+		name   string
+		op     nfdv1alpha1.MatchOp
+		values V
+		value  string
+		typ    nfdv1alpha1.AttributeType
+		result assert.BoolAssertionFunc
+		err    assert.ValueAssertionFunc
+	}
+
+	tcs := []TC{
+		{name: "int Gt", op: nfdv1alpha1.MatchGt, values: V{"2"}, value: "3", typ: nfdv1alpha1.AttributeTypeInt, result: assert.True, err: assert.Nil},
+		{name: "int In", op: nfdv1alpha1.MatchIn, values: V{"3"}, value: "3", typ: nfdv1alpha1.AttributeTypeInt, result: assert.True, err: assert.Nil},
+		{name: "int64 Gt", op: nfdv1alpha1.MatchGt, values: V{"4294967296"}, value: "4294967297", typ: nfdv1alpha1.AttributeTypeInt, result: assert.True, err: assert.Nil},
+		{name: "int64 GtLt", op: nfdv1alpha1.MatchGtLt, values: V{"-4294967297", "4294967296"}, value: "-4294967296", typ: nfdv1alpha1.AttributeTypeInt, result: assert.True, err: assert.Nil},
+		{name: "invalid int", op: nfdv1alpha1.MatchGt, values: V{"2"}, value: "x", typ: nfdv1alpha1.AttributeTypeInt, result: assert.False, err: assert.NotNil},
+		{name: "bool IsTrue", op: nfdv1alpha1.MatchIsTrue, value: "true", typ: nfdv1alpha1.AttributeTypeBool, result: assert.True, err: assert.Nil},
+		{name: "bool IsFalse", op: nfdv1alpha1.MatchIsFalse, value: "true", typ: nfdv1alpha1.AttributeTypeBool, result: assert.False, err: assert.Nil},
+		{name: "list In", op: nfdv1alpha1.MatchIn, values: V{"b"}, value: "a,b,c", typ: nfdv1alpha1.AttributeTypeList, result: assert.True, err: assert.Nil},
+		{name: "list In no match", op: nfdv1alpha1.MatchIn, values: V{"d"}, value: "a,b,c", typ: nfdv1alpha1.AttributeTypeList, result: assert.False, err: assert.Nil},
+		{name: "list InRegexp", op: nfdv1alpha1.MatchInRegexp, values: V{"^c$"}, value: "a,b,c", typ: nfdv1alpha1.AttributeTypeList, result: assert.True, err: assert.Nil},
+		{name: "list NotIn", op: nfdv1alpha1.MatchNotIn, values: V{"d"}, value: "a,b,c", typ: nfdv1alpha1.AttributeTypeList, result: assert.True, err: assert.Nil},
+		{name: "list NotIn no match", op: nfdv1alpha1.MatchNotIn, values: V{"a"}, value: "a,b,c", typ: nfdv1alpha1.AttributeTypeList, result: assert.False, err: assert.Nil},
+		{name: "empty list In", op: nfdv1alpha1.MatchIn, values: V{""}, value: "", typ: nfdv1alpha1.AttributeTypeList, result: assert.False, err: assert.Nil},
+		{name: "string In", op: nfdv1alpha1.MatchIn, values: V{"a,b"}, value: "a,b", result: assert.True, err: assert.Nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values}
+			types := map[string]nfdv1alpha1.AttributeType{}
+			if tc.typ != "" {
+				types["foo"] = tc.typ
+			}
+			res, err := compileMatchExpression(me).evaluateValues(logr.Discard(), "foo", map[string]string{"foo": tc.value}, types)
+			tc.result(t, res)
+			tc.err(t, err)
+		})
+	}
+
+	// Matched typed values should have their type recorded
+	set := compileMatchExpressionSet(&nfdv1alpha1.MatchExpressionSet{"foo": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists}})
+	_, out, err := set.matchValues(logr.Discard(), map[string]string{"foo": "1"}, map[string]nfdv1alpha1.AttributeType{"foo": nfdv1alpha1.AttributeTypeInt}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "foo", "Value": "1", "Type": "int"}}, out)
+}
+
+func TestMatchRegexpCaptures(t *testing.T) {
+	re := &nfdv1alpha1.MatchExpression{
+		Op:    nfdv1alpha1.MatchInRegexp,
+		Value: nfdv1alpha1.MatchValue{`^(?P<major>[0-9]+)\.(?P<minor>[0-9]+)`, `^(?P<Name>x)(?P<other>.*)$`},
+	}
+
+	// Named captures are added to matched values
+	_, out, err := MatchGetValues(&nfdv1alpha1.MatchExpressionSet{"full": re}, map[string]string{"full": "6.8.0-rt"})
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "full", "Value": "6.8.0-rt", "major": "6", "minor": "8"}}, out)
+
+	// Captures do not override the existing keys of the element
+	_, out, err = MatchGetValues(&nfdv1alpha1.MatchExpressionSet{"full": re}, map[string]string{"full": "xyz"})
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "full", "Value": "xyz", "other": "yz"}}, out)
+
+	// The matching item of list values is captured
+	set := compileMatchExpressionSet(&nfdv1alpha1.MatchExpressionSet{"versions": re})
+	_, out, err = set.matchValues(logr.Discard(), map[string]string{"versions": "a,5.14,6.1"}, map[string]nfdv1alpha1.AttributeType{"versions": nfdv1alpha1.AttributeTypeList}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "5", out[0]["major"])
+	assert.Equal(t, "14", out[0]["minor"])
+
+	// Key and value names
+	_, out, err = MatchKeyNames(re, map[string]nfdv1alpha1.Nil{"1.2": {}, "foo": {}})
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "1.2", "major": "1", "minor": "2"}}, out)
+	_, out, err = MatchValueNames(re, map[string]string{"3.4": "bar"})
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "3.4", "Value": "bar", "major": "3", "minor": "4"}}, out)
+
+	// Instances are copied, not modified
+	instances := []nfdv1alpha1.InstanceFeature{*nfdv1alpha1.NewInstanceFeature(map[string]string{"version": "2.0"})}
+	out, err = MatchGetInstances(&nfdv1alpha1.MatchExpressionSet{"version": re}, instances)
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"version": "2.0", "major": "2", "minor": "0"}}, out)
+	assert.Equal(t, map[string]string{"version": "2.0"}, instances[0].Attributes)
+
+	// Regexps without named captures produce no extra keys
+	_, out, err = MatchGetValues(&nfdv1alpha1.MatchExpressionSet{"full": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchInRegexp, Value: nfdv1alpha1.MatchValue{"^([0-9]+)"}}}, map[string]string{"full": "6.8"})
+	assert.Nil(t, err)
+	assert.Equal(t, []MatchedElement{{"Name": "full", "Value": "6.8"}}, out)
+}
+
+func TestCompileMatchExpression(t *testing.T) {
+	c := compileMatchExpression(&nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchInRegexp, Value: nfdv1alpha1.MatchValue{"^val-[0-9]$"}})
+	assert.Nil(t, c.err)
+	assert.Len(t, c.regexps, 1)
+
+	c = compileMatchExpression(&nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchInRegexp, Value: nfdv1alpha1.MatchValue{"val-["}})
+	assert.NotNil(t, c.err)
+
+	c = compileMatchExpression(&nfdv1alpha1.MatchExpression{Op: "unknown"})
+	assert.NotNil(t, c.err)
+
+	c = compileMatchExpression(nil)
+	assert.NotNil(t, c.err)
+
+	// The compiled expression is not affected by later modifications of the
+	// expression it was compiled from
+	me := &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, Value: nfdv1alpha1.MatchValue{"1"}}
+	c = compileMatchExpression(me)
+	me.Value[0] = "2"
+	res, err := c.evaluate(true, "1")
+	assert.Nil(t, err)
+	assert.True(t, res)
+}
+
+func TestMatchExpressionSetOrder(t *testing.T) {
+	// The invalid expression of "a" is always evaluated before the
+	// non-matching expression of "b", regardless of map iteration order
+	mes := &nfdv1alpha1.MatchExpressionSet{
+		"a": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: nfdv1alpha1.MatchValue{"1"}},
+		"b": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, Value: nfdv1alpha1.MatchValue{"no"}},
+	}
+	for i := 0; i < 20; i++ {
+		_, err := MatchValues(mes, map[string]string{"a": "str", "b": "1"})
+		assert.NotNil(t, err)
+	}
+
+	mes = &nfdv1alpha1.MatchExpressionSet{
+		"a": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, Value: nfdv1alpha1.MatchValue{"no"}},
+		"b": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: nfdv1alpha1.MatchValue{"1"}},
+	}
+	for i := 0; i < 20; i++ {
+		res, err := MatchValues(mes, map[string]string{"a": "1", "b": "str"})
+		assert.Nil(t, err)
+		assert.False(t, res)
+	}
+}
+
+func TestEvaluateMatchExpressionParsed(t *testing.T) {
+	type V = nfdv1alpha1.MatchValue
+	type TC struct {
+		name   string
+		op     nfdv1alpha1.MatchOp
+		parse  nfdv1alpha1.ValueParser
+		values V
+		value  string
+		typ    nfdv1alpha1.AttributeType
+		result assert.BoolAssertionFunc
+		err    assert.ValueAssertionFunc
+	}
+
+	tcs := []TC{
+		{name: "size Gt", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserSize, values: V{"16Mi"}, value: "16384 kB", result: assert.False, err: assert.Nil},
+		{name: "size Gt match", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserSize, values: V{"16Mi"}, value: "16385 kB", result: assert.True, err: assert.Nil},
+		{name: "size In", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserSize, values: V{"1G", "2G"}, value: "2097152K", result: assert.True, err: assert.Nil},
+		{name: "size fraction", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserSize, values: V{"1536Ki"}, value: "1.5MiB", result: assert.True, err: assert.Nil},
+		{name: "size plain", op: nfdv1alpha1.MatchLt, parse: nfdv1alpha1.ValueParserSize, values: V{"1k"}, value: "1023", result: assert.True, err: assert.Nil},
+		{name: "size typed int", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserSize, values: V{"1k"}, value: "2048", typ: nfdv1alpha1.AttributeTypeInt, result: assert.True, err: assert.Nil},
+		{name: "invalid size", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserSize, values: V{"1k"}, value: "1 foo", result: assert.False, err: assert.NotNil},
+		{name: "hex In", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserHex, values: V{"0x8086"}, value: "8086", result: assert.True, err: assert.Nil},
+		{name: "hex NotIn", op: nfdv1alpha1.MatchNotIn, parse: nfdv1alpha1.ValueParserHex, values: V{"0x10de"}, value: "0X10DE", result: assert.False, err: assert.Nil},
+		{name: "hex GtLt", op: nfdv1alpha1.MatchGtLt, parse: nfdv1alpha1.ValueParserHex, values: V{"0x0300", "0x0400"}, value: "0302", result: assert.True, err: assert.Nil},
+		{name: "invalid hex", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserHex, values: V{"0x8086"}, value: "xyz", result: assert.False, err: assert.NotNil},
+		{name: "version Gt", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserVersion, values: V{"4.18"}, value: "4.18.0-372.el8_6.x86_64", result: assert.True, err: assert.Nil},
+		{name: "version Lt", op: nfdv1alpha1.MatchLt, parse: nfdv1alpha1.ValueParserVersion, values: V{"5.14"}, value: "4.18.0-372", result: assert.True, err: assert.Nil},
+		{name: "version In", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserVersion, values: V{"5.14"}, value: "v5.14.0", result: assert.True, err: assert.Nil},
+		{name: "version GtLt", op: nfdv1alpha1.MatchGtLt, parse: nfdv1alpha1.ValueParserVersion, values: V{"5.4", "5.10"}, value: "5.9.2", result: assert.True, err: assert.Nil},
+		{name: "invalid version", op: nfdv1alpha1.MatchGt, parse: nfdv1alpha1.ValueParserVersion, values: V{"5.4"}, value: "rc1", result: assert.False, err: assert.NotNil},
+		{name: "invalid GtLt range", op: nfdv1alpha1.MatchGtLt, parse: nfdv1alpha1.ValueParserVersion, values: V{"5.10", "5.4"}, value: "5.9", result: assert.False, err: assert.NotNil},
+		{name: "invalid value", op: nfdv1alpha1.MatchIn, parse: nfdv1alpha1.ValueParserSize, values: V{"1 foo"}, value: "1", result: assert.False, err: assert.NotNil},
+		{name: "unsupported op", op: nfdv1alpha1.MatchInRegexp, parse: nfdv1alpha1.ValueParserHex, values: V{"^80"}, value: "8086", result: assert.False, err: assert.NotNil},
+		{name: "unknown parser", op: nfdv1alpha1.MatchIn, parse: "foo", values: V{"1"}, value: "1", result: assert.False, err: assert.NotNil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			me := &nfdv1alpha1.MatchExpression{Op: tc.op, Value: tc.values, Parse: tc.parse}
+			types := map[string]nfdv1alpha1.AttributeType{}
+			if tc.typ != "" {
+				types["foo"] = tc.typ
+			}
+			res, err := compileMatchExpression(me).evaluateValues(logr.Discard(), "foo", map[string]string{"foo": tc.value}, types)
 			tc.result(t, res)
 			tc.err(t, err)
 		})
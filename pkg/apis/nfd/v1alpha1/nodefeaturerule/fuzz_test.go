@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// The fuzz targets check that malformed input does not crash the rule
+// engine. Their seed corpus is run by "go test", "make fuzz" runs the fuzzer
+// on each of them.
+
+// fuzzFeatures returns the input features of the fuzz targets.
+func fuzzFeatures(value string) *nfdv1alpha1.Features {
+	features := nfdv1alpha1.NewFeatures()
+	features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX", "AVX512F", value)
+	features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6", "full": "6.8.0-45-generic", "fuzz": value})
+	features.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "8086", "class": "0200", "sriov_totalvfs": "64"}),
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "10de", "class": "0300", "fuzz": value}),
+	})
+	return features
+}
+
+func FuzzMatchExpression(f *testing.F) {
+	// op, values (separated by newlines), parser, attribute type, input
+	f.Add("In", "foo\nbar", "", "", "foo")
+	f.Add("NotIn", "", "", "list", "a,b,c")
+	f.Add("InRegexp", "^eth(?P<num>[0-9]+)$", "", "", "eth0")
+	f.Add("InRegexp", "(a*)*$\n\xff\xfe", "", "", "\xff")
+	f.Add("Gt", "99999999999999999999999999", "", "int", "5")
+	f.Add("Lt", "-9223372036854775808", "", "", "-9223372036854775809")
+	f.Add("GtLt", "1\n1e400", "size", "", "16Gi")
+	f.Add("GtLt", "0x0\n0xffffffffffffffffffffffff", "hex", "", "0x8086")
+	f.Add("In", "4.18.0-372.el8", "version", "", "4.18.0.372.99999999999999999999")
+	f.Add("IsTrue", "", "", "bool", "true")
+	f.Add("Exists", "x", "", "", "")
+	f.Add("Foo", "", "bar", "", "")
+	f.Add("In", strings.Repeat("x\n", 10000), "", "", "x")
+
+	f.Fuzz(func(t *testing.T, op, values, parser, attrType, input string) {
+		m := &nfdv1alpha1.MatchExpression{
+			Op:    nfdv1alpha1.MatchOp(op),
+			Parse: nfdv1alpha1.ValueParser(parser),
+		}
+		if values != "" {
+			m.Value = strings.Split(values, "\n")
+		}
+		c := compileMatchExpression(m)
+
+		for _, valid := range []bool{true, false} {
+			if _, err := c.evaluateTyped(valid, input, nfdv1alpha1.AttributeType(attrType)); err != nil && Code(err) == ErrUnknown {
+				t.Errorf("unclassified error: %v", err)
+			}
+		}
+		if _, _, err := c.matchValueNames(logr.Discard(), map[string]string{input: "", "foo": input}); err != nil && Code(err) == ErrUnknown {
+			t.Errorf("unclassified error: %v", err)
+		}
+		if c.hasCaptures {
+			_ = c.captures(input, nfdv1alpha1.AttributeType(attrType))
+		}
+
+		set := compileMatchExpressionSet(&nfdv1alpha1.MatchExpressionSet{"fuzz": m, input: m})
+		if _, _, err := set.matchValues(logr.Discard(), map[string]string{"fuzz": input}, map[string]nfdv1alpha1.AttributeType{"fuzz": nfdv1alpha1.AttributeType(attrType)}, true); err != nil && Code(err) == ErrUnknown {
+			t.Errorf("unclassified error: %v", err)
+		}
+		if _, _, err := set.matchKeys(logr.Discard(), map[string]nfdv1alpha1.Nil{input: {}}, true); err != nil && Code(err) == ErrUnknown {
+			t.Errorf("unclassified error: %v", err)
+		}
+	})
+}
+
+func FuzzTemplate(f *testing.F) {
+	f.Add(`{{range .pci.device}}vendor-{{.vendor}}=true{{"\n"}}{{end}}`, "x")
+	f.Add(`sriov={{ sum .pci.device "sriov_totalvfs" }}`, "1e400")
+	f.Add(`max={{ max .pci.device "fuzz" }}`, "99999999999999999999999999Ei")
+	f.Add(`{{range $i, $e := .cpu.cpuid}}{{range $.cpu.cpuid}}{{end}}{{end}}`, "\xff")
+	f.Add(`{{ .kernel.version }}`, "")
+	f.Add(`{{ template "x" }}`, "")
+	f.Add(`{{define "x"}}{{template "x"}}{{end}}{{template "x"}}`, "")
+	f.Add(`{{ printf "%0999999d" 1 }}`, "")
+	f.Add(`{{`, "")
+
+	f.Fuzz(func(t *testing.T, text, value string) {
+		h, err := newTemplateHelper(text)
+		if err != nil {
+			return
+		}
+		features := fuzzFeatures(value)
+		in := matchedFeatures{
+			"cpu":    {"cpuid": []MatchedElement{{"Name": "AVX"}, {"Name": value}}},
+			"kernel": {"version": []MatchedElement{{"Name": "full", "Value": features.Attributes["kernel.version"].Elements["full"]}}},
+			"pci":    {"device": []MatchedElement{features.Instances["pci.device"].Elements[0].Attributes, features.Instances["pci.device"].Elements[1].Attributes}},
+		}
+		_, _ = h.expandMap(in)
+	})
+}
+
+func FuzzRuleYAML(f *testing.F) {
+	f.Add([]byte(`
+rules:
+  - name: "avx512"
+    labels:
+      avx512: "true"
+    matchFeatures:
+      - feature: cpu.cpuid
+        matchExpressions:
+          AVX512F: {op: Exists}
+      - feature: kernel.version
+        matchExpressions:
+          major: {op: Gt, value: ["5"]}
+`), "AVX512F")
+	f.Add([]byte(`
+rules:
+  - name: "bind"
+    labelsTemplate: "{{range .pci.device}}vendor-{{.vendor}}={{.class}}\n{{end}}"
+    vars:
+      foo: bar
+    matchFeatures:
+      - feature: pci.device
+        bind: {v: vendor}
+        matchExpressions:
+          class: {op: InRegexp, value: ["^0[23]"]}
+      - feature: pci.device
+        matchExpressions:
+          vendor: {op: In, valueFrom: v}
+    matchAny:
+      - matchFeatures:
+          - feature: kernel.version
+            presence: Optional
+            matchName: {op: InRegexp, value: ["^f"]}
+    extendedResources:
+      vfs: "{{ sum .pci.device \"sriov_totalvfs\" }}"
+tests:
+  - name: "test"
+    features:
+      flags:
+        cpu.cpuid: {elements: {AVX: {}}}
+    labels:
+      vendor-8086: "0200"
+`), "")
+	f.Add([]byte(`rules: [{name: x, minFeatureVersion: "v9999999999999999999.0", matchFeatures: [{feature: a.b, presence: Absent}]}]`), "")
+	f.Add([]byte(`rules: [{name: x, sortBy: [fuzz], matchFeatures: [{feature: kernel.version, matchExpressions: {fuzz: {op: GtLt, value: ["1", "99999999999999999999"], parse: size}}}]}]`), "1Ki")
+	f.Add([]byte("rules: [{name: \"\xff\", labels: {\"\xfe\": \"\xff\"}}]"), "\xff")
+
+	f.Fuzz(func(t *testing.T, data []byte, value string) {
+		spec := &nfdv1alpha1.NodeFeatureRuleSpec{}
+		if err := yaml.Unmarshal(data, spec); err != nil {
+			return
+		}
+		features := fuzzFeatures(value)
+		for i := range spec.Rules {
+			c, _ := Compile(&spec.Rules[i])
+			_ = c.Features()
+			_, _ = c.Evaluate(features)
+		}
+		_ = RunTests(spec)
+	})
+}
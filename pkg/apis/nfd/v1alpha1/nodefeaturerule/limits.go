@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Limits of rule evaluation, protecting the evaluating process (e.g.
+// nfd-master) from pathological or malicious rules.
+const (
+	// MaxRegexpLength is the maximum length of a regexp in a match
+	// expression.
+	MaxRegexpLength = 1024
+	// MaxRegexpProgramSize is the maximum number of instructions of a
+	// compiled regexp, limiting the memory and time used for matching.
+	MaxRegexpProgramSize = 10000
+	// MaxTemplateSteps is the maximum number of steps of a template, in
+	// total. Each write to the output and each iteration of a range action is
+	// a step.
+	MaxTemplateSteps = 100000
+	// MaxTemplateOutputSize is the maximum size of the output of a template.
+	MaxTemplateOutputSize = 64 << 10
+	// TemplateTimeout is the maximum time for executing a template.
+	TemplateTimeout = time.Second
+)
+
+// ErrTemplateTimeout is returned if executing a template takes longer than
+// TemplateTimeout.
+var ErrTemplateTimeout = fmt.Errorf("template execution exceeded the time limit of %v", TemplateTimeout)
+
+// errTemplateOutputSize is returned by the template writer when the output
+// grows over MaxTemplateOutputSize.
+var errTemplateOutputSize = fmt.Errorf("template output exceeds the size limit of %d bytes", MaxTemplateOutputSize)
+
+// errTemplateSteps is returned when a template exceeds MaxTemplateSteps.
+var errTemplateSteps = fmt.Errorf("template execution exceeded the limit of %d steps", MaxTemplateSteps)
+
+// templateDeadlineInterval is the number of steps between checks of the
+// template execution deadline.
+const templateDeadlineInterval = 1000
+
+// compileRegexp compiles a regexp of a match expression, rejecting regexps
+// that exceed the size limits.
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if len(expr) > MaxRegexpLength {
+		return nil, fmt.Errorf("regexp is longer than %d characters", MaxRegexpLength)
+	}
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > MaxRegexpProgramSize {
+		return nil, fmt.Errorf("regexp is too complex (%d instructions, max %d)", len(prog.Inst), MaxRegexpProgramSize)
+	}
+	return regexp.Compile(expr)
+}
+
+// ParseTemplate parses a rule template (labelsTemplate, varsTemplate or a
+// templated extended resource).
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Funcs(TemplateFuncs()).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Tree != nil {
+		insertSteps(tmpl.Tree.Root)
+	}
+	return tmpl, nil
+}
+
+// insertSteps inserts an empty text node at the beginning of the body of all
+// range actions of a template parse tree. Executing a text node always
+// writes to the output, making the template writer see each iteration, also
+// of ranges without output.
+func insertSteps(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			insertSteps(c)
+		}
+	case *parse.RangeNode:
+		insertSteps(n.List)
+		insertSteps(n.ElseList)
+		step := &parse.TextNode{NodeType: parse.NodeText, Pos: n.Pos, Text: []byte{}}
+		if n.List == nil {
+			n.List = &parse.ListNode{NodeType: parse.NodeList, Pos: n.Pos}
+		}
+		n.List.Nodes = append([]parse.Node{step}, n.List.Nodes...)
+	case *parse.IfNode:
+		insertSteps(n.List)
+		insertSteps(n.ElseList)
+	case *parse.WithNode:
+		insertSteps(n.List)
+		insertSteps(n.ElseList)
+	}
+}
+
+// templateWriter is the output of a template. It counts the writes as
+// execution steps and fails writes that exceed MaxTemplateSteps,
+// MaxTemplateOutputSize or the execution deadline, which stops the
+// execution.
+type templateWriter struct {
+	buf      bytes.Buffer
+	steps    int
+	deadline time.Time
+}
+
+// Write implements the io.Writer interface.
+func (w *templateWriter) Write(p []byte) (int, error) {
+	if w.steps++; w.steps > MaxTemplateSteps {
+		return 0, errTemplateSteps
+	}
+	if w.steps%templateDeadlineInterval == 0 && time.Now().After(w.deadline) {
+		return 0, ErrTemplateTimeout
+	}
+	if w.buf.Len()+len(p) > MaxTemplateOutputSize {
+		return 0, errTemplateOutputSize
+	}
+	return w.buf.Write(p)
+}
+
+// executeTemplate executes a template within the time, step and output size
+// limits.
+func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
+	out := &templateWriter{deadline: time.Now().Add(TemplateTimeout)}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.buf.String(), nil
+}
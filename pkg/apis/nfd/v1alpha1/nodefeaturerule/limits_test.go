@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestRegexpLimits(t *testing.T) {
+	_, err := compileRegexp(`^eth[0-9]+$`)
+	assert.NoError(t, err)
+
+	_, err = compileRegexp(strings.Repeat("a", MaxRegexpLength+1))
+	assert.ErrorContains(t, err, "longer than")
+
+	_, err = compileRegexp(strings.Repeat("[a-z]{1000}", 11))
+	assert.ErrorContains(t, err, "too complex")
+
+	// Invalid regexps make the rule fail
+	r := &nfdv1alpha1.Rule{
+		Name: "complex-regexp",
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "kernel.version",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"full": newMatchExpression(nfdv1alpha1.MatchInRegexp, strings.Repeat("[a-z]{1000}", 11)),
+				},
+			},
+		},
+	}
+	_, err = Compile(r)
+	assert.Error(t, err)
+}
+
+func TestTemplateLimits(t *testing.T) {
+	execute := func(text string) (string, error) {
+		tmpl, err := ParseTemplate(text)
+		if err != nil {
+			return "", err
+		}
+		return executeTemplate(tmpl, map[string][]string{"items": {"a", "b"}})
+	}
+
+	out, err := execute(`{{ range .items }}{{ . }}={{ len $.items }}{{ "\n" }}{{ end }}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a=2\nb=2\n", out)
+
+	// Output size limit
+	_, err = execute(`{{ range 1000 }}` + strings.Repeat("x", 100) + `{{ end }}`)
+	assert.ErrorContains(t, err, "size limit")
+
+	// Step limit, also for ranges without output
+	_, err = execute(`{{ range 1000 }}{{ range 1000 }}{{ end }}{{ end }}`)
+	assert.ErrorContains(t, err, "steps")
+	_, err = execute(`{{ range 1000 }}{{ else }}{{ range 1000000 }}{{ end }}{{ end }}{{ if true }}{{ range 1000000 }}{{ end }}{{ end }}`)
+	assert.ErrorContains(t, err, "steps")
+
+	// Time limit, checked by the writer
+	tmpl, err := ParseTemplate(`{{ range 2000 }}{{ end }}`)
+	assert.NoError(t, err)
+	err = tmpl.Execute(&templateWriter{deadline: time.Now()}, nil)
+	assert.ErrorIs(t, err, ErrTemplateTimeout)
+}
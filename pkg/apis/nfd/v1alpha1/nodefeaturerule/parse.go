@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// isValidParser returns true if p is a known value parser.
+func isValidParser(p nfdv1alpha1.ValueParser) bool {
+	switch p {
+	case nfdv1alpha1.ValueParserSize, nfdv1alpha1.ValueParserHex, nfdv1alpha1.ValueParserVersion:
+		return true
+	}
+	return false
+}
+
+// parseValue normalizes a value with the given parser. The result is a list
+// of integers (only one for sizes and hex numbers) to be compared with
+// compareParsed.
+func parseValue(value string, p nfdv1alpha1.ValueParser) ([]int64, error) {
+	value = strings.TrimSpace(value)
+	switch p {
+	case nfdv1alpha1.ValueParserSize:
+		v, err := parseSize(value)
+		return []int64{v}, err
+	case nfdv1alpha1.ValueParserHex:
+		v, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a hexadecimal number")
+		}
+		return []int64{v}, nil
+	case nfdv1alpha1.ValueParserVersion:
+		return parseVersion(value)
+	}
+	return nil, fmt.Errorf("unknown parser %q", p)
+}
+
+// parseSize parses a size with an optional unit suffix into bytes.
+func parseSize(value string) (int64, error) {
+	i := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	if i < 0 {
+		i = len(value)
+	}
+	num, unit := value[:i], strings.TrimSpace(value[i:])
+
+	mult, ok := sizeUnit(unit)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	if strings.Contains(num, ".") {
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a size")
+		}
+		f *= float64(mult)
+		if f >= math.MaxInt64 {
+			return 0, fmt.Errorf("size out of range")
+		}
+		return int64(math.Round(f)), nil
+	}
+	v, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a size")
+	}
+	if v > math.MaxInt64/mult {
+		return 0, fmt.Errorf("size out of range")
+	}
+	return v * mult, nil
+}
+
+// sizeUnit returns the multiplier of a size unit. Units are case-insensitive
+// and the "B" and "iB" suffixes are optional, i.e. "k", "kB", "Ki" and "KiB"
+// are all equal to 1024.
+func sizeUnit(unit string) (int64, bool) {
+	u := strings.ToLower(unit)
+	u = strings.TrimSuffix(u, "b")
+	if len(u) == 2 {
+		u = strings.TrimSuffix(u, "i")
+	}
+	switch u {
+	case "":
+		return 1, true
+	case "k":
+		return 1 << 10, true
+	case "m":
+		return 1 << 20, true
+	case "g":
+		return 1 << 30, true
+	case "t":
+		return 1 << 40, true
+	case "p":
+		return 1 << 50, true
+	case "e":
+		return 1 << 60, true
+	}
+	return 0, false
+}
+
+// parseVersion parses the leading numeric components of a version, with an
+// optional "v" prefix.
+func parseVersion(value string) ([]int64, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "v"), "V")
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '.' || r == '-' || r == '_' || r == '+'
+	})
+
+	var ret []int64
+	for _, f := range fields {
+		v, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			break
+		}
+		ret = append(ret, v)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("not a version")
+	}
+	return ret, nil
+}
+
+// compareParsed compares two parsed values, returning -1, 0 or +1. Missing
+// components of the shorter value are treated as zero.
+func compareParsed(a, b []int64) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
@@ -17,78 +17,305 @@ limitations under the License.
 package nodefeaturerule
 
 import (
-	"bytes"
+	"cmp"
+	"errors"
 	"fmt"
 	"maps"
 	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/klog/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
-	"github.com/openshift/node-feature-discovery/pkg/utils"
 )
 
 // RuleOutput contains the output out rule execution.
 // +k8s:deepcopy-gen=false
 type RuleOutput struct {
+	// Matched is true if the rule matched.
+	Matched           bool
 	ExtendedResources map[string]string
 	Labels            map[string]string
 	Annotations       map[string]string
 	Vars              map[string]string
 	Taints            []corev1.Taint
+	Cordon            bool
+}
+
+// CompiledRule is a Rule prepared for repeated execution. The templates of
+// the rule are parsed and its match expressions are validated and pre-parsed
+// only once, when the rule is compiled.
+// +k8s:deepcopy-gen=false
+type CompiledRule struct {
+	rule *nfdv1alpha1.Rule
+
+	matchFeatures compiledFeatureMatcher
+	matchAny      []compiledFeatureMatcher
+
+	labelsTemplate             *compiledTemplate
+	varsTemplate               *compiledTemplate
+	extendedResourcesTemplates map[string]*compiledTemplate
+
+	minFeatureVersion    *version.Version
+	minFeatureVersionErr error
+}
+
+// compiledFeatureMatcher is a FeatureMatcher with its match expressions
+// compiled.
+type compiledFeatureMatcher []compiledFeatureMatcherTerm
+
+// compiledFeatureMatcherTerm is a compiled FeatureMatcherTerm. The compiled
+// expressions are nil if they were not specified in the term.
+type compiledFeatureMatcherTerm struct {
+	feature          string
+	matchExpressions compiledMatchExpressionSet
+	matchName        *compiledMatchExpression
+	bind             map[string]string
+	presence         nfdv1alpha1.FeaturePresence
+	// err is the error in the term itself, reported when it is evaluated
+	err error
+}
+
+// compiledTemplate is a parsed template, or the error from parsing it. Parse
+// errors are only reported when the template is executed.
+type compiledTemplate struct {
+	helper *templateHelper
+	err    error
+}
+
+// Compile prepares a rule for repeated execution. The returned error reports
+// invalid templates and match expressions found in the rule. The compiled
+// rule is usable even if an error is returned, in which case executing it
+// fails in the same way as Execute would.
+func Compile(r *nfdv1alpha1.Rule) (*CompiledRule, error) {
+	c := &CompiledRule{
+		rule:                       r,
+		extendedResourcesTemplates: make(map[string]*compiledTemplate),
+	}
+	var errs []error
+
+	if r.LabelsTemplate != "" {
+		th, err := newTemplateHelper(r.LabelsTemplate)
+		if err != nil {
+			err = newError(ErrTemplate, "failed to parse LabelsTemplate: %w", err)
+			errs = append(errs, err)
+		}
+		c.labelsTemplate = &compiledTemplate{helper: th, err: err}
+	}
+	if r.VarsTemplate != "" {
+		th, err := newTemplateHelper(r.VarsTemplate)
+		if err != nil {
+			err = newError(ErrTemplate, "failed to parse VarsTemplate: %w", err)
+			errs = append(errs, err)
+		}
+		c.varsTemplate = &compiledTemplate{helper: th, err: err}
+	}
+	for name, value := range r.ExtendedResources {
+		if !IsTemplate(value) {
+			continue
+		}
+		th, err := newTemplateHelper(value)
+		if err != nil {
+			err = newError(ErrTemplate, "failed to parse template of extended resource %q: %w", name, err)
+			errs = append(errs, err)
+		}
+		c.extendedResourcesTemplates[name] = &compiledTemplate{helper: th, err: err}
+	}
+
+	if r.MinFeatureVersion != "" {
+		c.minFeatureVersion, c.minFeatureVersionErr = version.ParseGeneric(r.MinFeatureVersion)
+		if c.minFeatureVersionErr != nil {
+			c.minFeatureVersionErr = newError(ErrInvalidMinFeatureVersion, "invalid minFeatureVersion: %w", c.minFeatureVersionErr)
+			errs = append(errs, c.minFeatureVersionErr)
+		}
+	}
+
+	var matcherErrs []error
+	c.matchFeatures, matcherErrs = compileFeatureMatcher(&r.MatchFeatures)
+	errs = append(errs, matcherErrs...)
+	c.matchAny = make([]compiledFeatureMatcher, len(r.MatchAny))
+	for i := range r.MatchAny {
+		c.matchAny[i], matcherErrs = compileFeatureMatcher(&r.MatchAny[i].MatchFeatures)
+		errs = append(errs, matcherErrs...)
+	}
+
+	for i := range errs {
+		errs[i] = annotate(errs[i], r.Name, "", "")
+	}
+	return c, errors.Join(errs...)
+}
+
+// compileFeatureMatcher compiles all match expressions of a feature matcher,
+// returning the errors found in them.
+func compileFeatureMatcher(m *nfdv1alpha1.FeatureMatcher) (compiledFeatureMatcher, []error) {
+	var errs []error
+	// Variables bound by the preceding terms
+	bound := make(map[string]struct{})
+	check := func(c *compiledMatchExpression, feature, path string) {
+		switch {
+		case c.err != nil:
+			errs = append(errs, annotate(c.err, "", feature, path))
+		case c.valueErr != nil:
+			errs = append(errs, annotate(c.valueErr, "", feature, path))
+		}
+		if _, ok := bound[c.valueFrom]; c.valueFrom != "" && !ok {
+			err := newError(ErrUnboundVariable, "variable %q is not bound by a preceding term", c.valueFrom)
+			errs = append(errs, annotate(err, "", feature, path))
+		}
+	}
+
+	ret := make(compiledFeatureMatcher, len(*m))
+	for i, term := range *m {
+		ret[i].feature = term.Feature
+		ret[i].presence = term.Presence
+		switch term.Presence {
+		case "", nfdv1alpha1.FeatureRequired, nfdv1alpha1.FeatureOptional:
+		case nfdv1alpha1.FeatureAbsent:
+			if term.MatchExpressions != nil || term.MatchName != nil || len(term.Bind) > 0 {
+				ret[i].err = newError(ErrInvalidTerm, "invalid term, matchExpressions, matchName and bind must not be used with presence %q", term.Presence)
+			}
+		default:
+			ret[i].err = newError(ErrInvalidTerm, "invalid presence %q", term.Presence)
+		}
+		if ret[i].err != nil {
+			ret[i].err = annotate(ret[i].err, "", term.Feature, "presence")
+			errs = append(errs, ret[i].err)
+		}
+		if term.MatchExpressions != nil {
+			ret[i].matchExpressions = compileMatchExpressionSet(term.MatchExpressions)
+			for _, e := range ret[i].matchExpressions {
+				check(e.expr, term.Feature, matchExpressionPath(e.name))
+			}
+		}
+		if term.MatchName != nil {
+			ret[i].matchName = compileMatchExpression(term.MatchName)
+			check(ret[i].matchName, term.Feature, "matchName")
+		}
+		if len(term.Bind) > 0 {
+			ret[i].bind = maps.Clone(term.Bind)
+			for v, attr := range term.Bind {
+				if v == "" || attr == "" {
+					err := newError(ErrInvalidTerm, "invalid bind, variable and attribute names must not be empty")
+					errs = append(errs, annotate(err, "", term.Feature, "bind"))
+				}
+				bound[v] = struct{}{}
+			}
+		}
+	}
+	return ret, errs
+}
+
+// Rule returns the rule that was compiled.
+func (c *CompiledRule) Rule() *nfdv1alpha1.Rule {
+	return c.rule
+}
+
+// MinFeatureVersion returns the minimum nfd-worker version required by the
+// rule, or nil if the rule does not specify one.
+func (c *CompiledRule) MinFeatureVersion() *version.Version {
+	return c.minFeatureVersion
+}
+
+// Features returns the (lowercase) names of the features the rule
+// references, sorted. The output of the rule only depends on these features.
+func (c *CompiledRule) Features() []string {
+	var names []string
+	for _, term := range c.matchFeatures {
+		names = append(names, strings.ToLower(term.feature))
+	}
+	for _, m := range c.matchAny {
+		for _, term := range m {
+			names = append(names, strings.ToLower(term.feature))
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
 }
 
 // Execute the rule against a set of input features.
+//
+// Deprecated: use Evaluate instead.
 func Execute(r *nfdv1alpha1.Rule, features *nfdv1alpha1.Features) (RuleOutput, error) {
+	return Evaluate(features, r)
+}
+
+// Execute the compiled rule against a set of input features.
+//
+// Deprecated: use CompiledRule.Evaluate instead.
+func (c *CompiledRule) Execute(features *nfdv1alpha1.Features) (RuleOutput, error) {
+	return c.Evaluate(features)
+}
+
+// execute implements Evaluate, logging the details of the evaluation to the
+// given logger.
+func (c *CompiledRule) execute(logger logr.Logger, features *nfdv1alpha1.Features) (RuleOutput, error) {
+	r := c.rule
 	labels := make(map[string]string)
 	vars := make(map[string]string)
+	extendedResources := make(map[string]string)
 
-	if len(r.MatchAny) > 0 {
+	if c.minFeatureVersionErr != nil {
+		return RuleOutput{}, c.minFeatureVersionErr
+	}
+
+	if len(c.matchAny) > 0 {
 		// Logical OR over the matchAny matchers
 		matched := false
-		for _, matcher := range r.MatchAny {
-			if isMatch, matches, err := evaluateMatchAnyElem(&matcher, features); err != nil {
+		for _, matcher := range c.matchAny {
+			if isMatch, matches, err := matcher.evaluate(logger, features); err != nil {
 				return RuleOutput{}, err
 			} else if isMatch {
 				matched = true
-				klog.V(4).InfoS("matchAny matched", "ruleName", r.Name, "matchedFeatures", utils.DelayedDumper(matches))
+				logger.V(4).Info("matchAny matched", "ruleName", r.Name, "matchedFeatures", delayedDump{matches})
 
-				if r.LabelsTemplate == "" && r.VarsTemplate == "" {
+				if c.labelsTemplate == nil && c.varsTemplate == nil && len(c.extendedResourcesTemplates) == 0 {
 					// there's no need to evaluate other matchers in MatchAny
 					// if there are no templates to be executed on them - so
 					// short-circuit and stop on first match here
 					break
 				}
 
-				if err := executeLabelsTemplate(r, matches, labels); err != nil {
+				sortMatchedFeatures(matches, r.SortBy)
+				if err := c.executeLabelsTemplate(matches, labels); err != nil {
 					return RuleOutput{}, err
 				}
-				if err := executeVarsTemplate(r, matches, vars); err != nil {
+				if err := c.executeVarsTemplate(matches, vars); err != nil {
+					return RuleOutput{}, err
+				}
+				if err := c.executeExtendedResourcesTemplate(matches, extendedResources); err != nil {
 					return RuleOutput{}, err
 				}
 			}
 		}
 		if !matched {
-			klog.V(2).InfoS("rule did not match", "ruleName", r.Name)
+			logger.V(2).Info("rule did not match", "ruleName", r.Name)
 			return RuleOutput{}, nil
 		}
 	}
 
-	if len(r.MatchFeatures) > 0 {
-		if isMatch, matches, err := evaluateFeatureMatcher(&r.MatchFeatures, features); err != nil {
+	if len(c.matchFeatures) > 0 {
+		if isMatch, matches, err := c.matchFeatures.evaluate(logger, features); err != nil {
 			return RuleOutput{}, err
 		} else if !isMatch {
-			klog.V(2).InfoS("rule did not match", "ruleName", r.Name)
+			logger.V(2).Info("rule did not match", "ruleName", r.Name)
 			return RuleOutput{}, nil
 		} else {
-			klog.V(4).InfoS("matchFeatures matched", "ruleName", r.Name, "matchedFeatures", utils.DelayedDumper(matches))
-			if err := executeLabelsTemplate(r, matches, labels); err != nil {
+			logger.V(4).Info("matchFeatures matched", "ruleName", r.Name, "matchedFeatures", delayedDump{matches})
+			if c.labelsTemplate != nil || c.varsTemplate != nil || len(c.extendedResourcesTemplates) > 0 {
+				sortMatchedFeatures(matches, r.SortBy)
+			}
+			if err := c.executeLabelsTemplate(matches, labels); err != nil {
 				return RuleOutput{}, err
 			}
-			if err := executeVarsTemplate(r, matches, vars); err != nil {
+			if err := c.executeVarsTemplate(matches, vars); err != nil {
+				return RuleOutput{}, err
+			}
+			if err := c.executeExtendedResourcesTemplate(matches, extendedResources); err != nil {
 				return RuleOutput{}, err
 			}
 		}
@@ -96,31 +323,37 @@ func Execute(r *nfdv1alpha1.Rule, features *nfdv1alpha1.Features) (RuleOutput, e
 
 	maps.Copy(labels, r.Labels)
 	maps.Copy(vars, r.Vars)
+	for k, v := range r.ExtendedResources {
+		// Templated values are only available if some matcher was evaluated
+		if !IsTemplate(v) {
+			extendedResources[k] = v
+		}
+	}
 
 	ret := RuleOutput{
+		Matched:           true,
 		Labels:            labels,
 		Vars:              vars,
 		Annotations:       maps.Clone(r.Annotations),
-		ExtendedResources: maps.Clone(r.ExtendedResources),
+		ExtendedResources: extendedResources,
 		Taints:            slices.Clone(r.Taints),
+		Cordon:            r.Cordon,
 	}
-	klog.V(2).InfoS("rule matched", "ruleName", r.Name, "ruleOutput", utils.DelayedDumper(ret))
+	logger.V(2).Info("rule matched", "ruleName", r.Name, "ruleOutput", delayedDump{ret})
 	return ret, nil
 }
 
-func executeLabelsTemplate(r *nfdv1alpha1.Rule, in matchedFeatures, out map[string]string) error {
-	if r.LabelsTemplate == "" {
+func (c *CompiledRule) executeLabelsTemplate(in matchedFeatures, out map[string]string) error {
+	if c.labelsTemplate == nil {
 		return nil
 	}
-
-	th, err := newTemplateHelper(r.LabelsTemplate)
-	if err != nil {
-		return fmt.Errorf("failed to parse LabelsTemplate: %w", err)
+	if c.labelsTemplate.err != nil {
+		return c.labelsTemplate.err
 	}
 
-	labels, err := th.expandMap(in)
+	labels, err := c.labelsTemplate.helper.expandMap(in)
 	if err != nil {
-		return fmt.Errorf("failed to expand LabelsTemplate: %w", err)
+		return newError(ErrTemplate, "failed to expand LabelsTemplate: %w", err)
 	}
 	for k, v := range labels {
 		out[k] = v
@@ -128,19 +361,17 @@ func executeLabelsTemplate(r *nfdv1alpha1.Rule, in matchedFeatures, out map[stri
 	return nil
 }
 
-func executeVarsTemplate(r *nfdv1alpha1.Rule, in matchedFeatures, out map[string]string) error {
-	if r.VarsTemplate == "" {
+func (c *CompiledRule) executeVarsTemplate(in matchedFeatures, out map[string]string) error {
+	if c.varsTemplate == nil {
 		return nil
 	}
-
-	th, err := newTemplateHelper(r.VarsTemplate)
-	if err != nil {
-		return err
+	if c.varsTemplate.err != nil {
+		return c.varsTemplate.err
 	}
 
-	vars, err := th.expandMap(in)
+	vars, err := c.varsTemplate.helper.expandMap(in)
 	if err != nil {
-		return err
+		return newError(ErrTemplate, "failed to expand VarsTemplate: %w", err)
 	}
 	for k, v := range vars {
 		out[k] = v
@@ -148,25 +379,45 @@ func executeVarsTemplate(r *nfdv1alpha1.Rule, in matchedFeatures, out map[string
 	return nil
 }
 
-type matchedFeatures map[string]domainMatchedFeatures
+// executeExtendedResourcesTemplate expands extended resource values that are
+// templates. Each template is expected to expand to a single quantity.
+func (c *CompiledRule) executeExtendedResourcesTemplate(in matchedFeatures, out map[string]string) error {
+	for name, t := range c.extendedResourcesTemplates {
+		if t.err != nil {
+			return t.err
+		}
 
-type domainMatchedFeatures map[string][]MatchedElement
+		expanded, err := t.helper.execute(in)
+		if err != nil {
+			return newError(ErrTemplate, "failed to expand template of extended resource %q: %w", name, err)
+		}
+		out[name] = strings.TrimSpace(expanded)
+	}
+	return nil
+}
 
-func evaluateMatchAnyElem(e *nfdv1alpha1.MatchAnyElem, features *nfdv1alpha1.Features) (bool, matchedFeatures, error) {
-	return evaluateFeatureMatcher(&e.MatchFeatures, features)
+// IsTemplate returns true if the given string contains template actions.
+func IsTemplate(s string) bool {
+	return strings.Contains(s, "{{")
 }
 
-func evaluateFeatureMatcher(m *nfdv1alpha1.FeatureMatcher, features *nfdv1alpha1.Features) (bool, matchedFeatures, error) {
-	matches := make(matchedFeatures, len(*m))
+type matchedFeatures map[string]domainMatchedFeatures
+
+type domainMatchedFeatures map[string][]MatchedElement
+
+func (m compiledFeatureMatcher) evaluate(logger logr.Logger, features *nfdv1alpha1.Features) (bool, matchedFeatures, error) {
+	matches := make(matchedFeatures, len(m))
+	// Variables bound by the evaluated terms
+	var vars map[string][]string
 
 	// Logical AND over the terms
-	for _, term := range *m {
+	for _, term := range m {
 		// Ignore case
-		featureName := strings.ToLower(term.Feature)
+		featureName := strings.ToLower(term.feature)
 
-		nameSplit := strings.SplitN(term.Feature, ".", 2)
+		nameSplit := strings.SplitN(term.feature, ".", 2)
 		if len(nameSplit) != 2 {
-			klog.InfoS("invalid feature name (not <domain>.<feature>), cannot be used for templating", "featureName", term.Feature)
+			logger.Info("invalid feature name (not <domain>.<feature>), cannot be used for templating", "featureName", term.feature)
 			nameSplit = []string{featureName, ""}
 		}
 
@@ -176,59 +427,206 @@ func evaluateFeatureMatcher(m *nfdv1alpha1.FeatureMatcher, features *nfdv1alpha1
 			matches[dom] = make(domainMatchedFeatures)
 		}
 
+		if term.err != nil {
+			return false, nil, term.err
+		}
+		if term.presence == nfdv1alpha1.FeatureAbsent {
+			absent := features.Exists(featureName) == ""
+			logger.V(3).Info("matched feature absence", "featureName", featureName, "matchResult", absent)
+			if !absent {
+				return false, nil, nil
+			}
+			continue
+		}
+
+		matchExpressions := term.matchExpressions.bindValues(vars)
+		matchName := term.matchName
+		if matchName != nil {
+			matchName = matchName.bindValues(vars)
+		}
+
 		var isMatch = true
 		var matchedElems []MatchedElement
+		var bindValues func(attr string) []string
 		var err error
 		if f, ok := features.Flags[featureName]; ok {
-			if term.MatchExpressions != nil {
-				isMatch, matchedElems, err = MatchGetKeys(term.MatchExpressions, f.Elements)
+			if matchExpressions != nil {
+				isMatch, matchedElems, err = matchExpressions.matchKeys(logger, f.Elements, true)
 			}
 			var meTmp []MatchedElement
-			if err == nil && isMatch && term.MatchName != nil {
-				isMatch, meTmp, err = MatchKeyNames(term.MatchName, f.Elements)
+			if err == nil && isMatch && matchName != nil {
+				isMatch, meTmp, err = matchName.matchKeyNames(logger, f.Elements)
 				matchedElems = append(matchedElems, meTmp...)
 			}
 		} else if f, ok := features.Attributes[featureName]; ok {
-			if term.MatchExpressions != nil {
-				isMatch, matchedElems, err = MatchGetValues(term.MatchExpressions, f.Elements)
+			if matchExpressions != nil {
+				isMatch, matchedElems, err = matchExpressions.matchValues(logger, f.Elements, f.Types, true)
 			}
 			var meTmp []MatchedElement
-			if err == nil && isMatch && term.MatchName != nil {
-				isMatch, meTmp, err = MatchValueNames(term.MatchName, f.Elements)
+			if err == nil && isMatch && matchName != nil {
+				isMatch, meTmp, err = matchName.matchValueNames(logger, f.Elements)
 				matchedElems = append(matchedElems, meTmp...)
 			}
+			bindValues = func(attr string) []string {
+				if v, ok := f.Elements[attr]; ok {
+					return []string{v}
+				}
+				return nil
+			}
 		} else if f, ok := features.Instances[featureName]; ok {
-			if term.MatchExpressions != nil {
-				matchedElems, err = MatchGetInstances(term.MatchExpressions, f.Elements)
+			if matchExpressions != nil {
+				matchedElems, err = matchExpressions.matchInstances(logger, f.Elements, f.Types)
 				isMatch = len(matchedElems) > 0
 			}
 			var meTmp []MatchedElement
-			if err == nil && isMatch && term.MatchName != nil {
-				meTmp, err = MatchInstanceAttributeNames(term.MatchName, f.Elements)
+			if err == nil && isMatch && matchName != nil {
+				meTmp, err = matchName.matchInstanceAttributeNames(logger, f.Elements)
 				isMatch = len(meTmp) > 0
 				matchedElems = append(matchedElems, meTmp...)
 
 			}
+			bindValues = func(attr string) []string {
+				instances := matchedElems
+				if matchExpressions == nil && matchName == nil {
+					// All instances match a term without expressions
+					instances = make([]MatchedElement, len(f.Elements))
+					for i := range f.Elements {
+						instances[i] = f.Elements[i].Attributes
+					}
+				}
+				return instanceValues(instances, attr)
+			}
+		} else if term.presence == nfdv1alpha1.FeatureOptional {
+			logger.V(3).Info("optional feature not available, term does not match", "featureName", featureName)
+			return false, nil, nil
 		} else {
-			return false, nil, fmt.Errorf("feature %q not available", featureName)
+			return false, nil, annotate(newError(ErrFeatureNotAvailable, "feature not available"), "", featureName, "")
 		}
 		matches[dom][nam] = append(matches[dom][nam], matchedElems...)
 
 		if err != nil {
-			return false, nil, err
+			return false, nil, annotate(err, "", featureName, "")
 		} else if !isMatch {
 			return false, nil, nil
 		}
+
+		if len(term.bind) > 0 {
+			if bindValues == nil {
+				return false, nil, newError(ErrInvalidTerm, "cannot bind variables to feature %q, only attribute and instance features are supported", featureName)
+			}
+			if vars == nil {
+				vars = make(map[string][]string)
+			}
+			for v, attr := range term.bind {
+				values := bindValues(attr)
+				if len(values) == 0 {
+					logger.V(3).Info("attribute to bind not found in matched elements", "featureName", featureName, "variable", v, "attribute", attr)
+					return false, nil, nil
+				}
+				vars[v] = values
+			}
+		}
 	}
 	return true, matches, nil
 }
 
+// sortMatchedFeatures sorts the matched elements of each feature so that the
+// output of the templates, e.g. the "first" matching instance, does not
+// depend on the order of the input features. The elements are sorted by the
+// given attributes first, elements lacking an attribute last, and then by all
+// their attributes.
+func sortMatchedFeatures(matches matchedFeatures, sortBy []string) {
+	for _, dom := range matches {
+		for _, elems := range dom {
+			if len(elems) < 2 {
+				continue
+			}
+			type keyedElement struct {
+				key  string
+				elem MatchedElement
+			}
+			keyed := make([]keyedElement, len(elems))
+			for i, e := range elems {
+				keyed[i] = keyedElement{key: matchedElementKey(e), elem: e}
+			}
+			slices.SortFunc(keyed, func(a, b keyedElement) int {
+				for _, attr := range sortBy {
+					if c := compareAttributes(a.elem, b.elem, attr); c != 0 {
+						return c
+					}
+				}
+				return strings.Compare(a.key, b.key)
+			})
+			for i := range keyed {
+				elems[i] = keyed[i].elem
+			}
+		}
+	}
+}
+
+// matchedElementKey returns all attributes of a matched element as one
+// string, in sorted order.
+func matchedElementKey(e MatchedElement) string {
+	names := make([]string, 0, len(e))
+	for n := range e {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for _, n := range names {
+		b.WriteString(n)
+		b.WriteByte('=')
+		b.WriteString(e[n])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// compareAttributes compares one attribute of two matched elements. Integer
+// values are compared numerically and elements lacking the attribute sort
+// last.
+func compareAttributes(a, b MatchedElement, attr string) int {
+	va, oka := a[attr]
+	vb, okb := b[attr]
+	switch {
+	case !oka && !okb:
+		return 0
+	case !oka:
+		return 1
+	case !okb:
+		return -1
+	}
+	ia, erra := strconv.ParseInt(va, 0, 64)
+	ib, errb := strconv.ParseInt(vb, 0, 64)
+	if erra == nil && errb == nil {
+		return cmp.Compare(ia, ib)
+	}
+	return strings.Compare(va, vb)
+}
+
+// instanceValues returns the distinct values of an attribute in a list of
+// matched instances, sorted.
+func instanceValues(instances []MatchedElement, attr string) []string {
+	set := make(map[string]struct{})
+	for _, i := range instances {
+		if v, ok := i[attr]; ok {
+			set[v] = struct{}{}
+		}
+	}
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	slices.Sort(values)
+	return values
+}
+
 type templateHelper struct {
 	template *template.Template
 }
 
 func newTemplateHelper(name string) (*templateHelper, error) {
-	tmpl, err := template.New("").Option("missingkey=error").Parse(name)
+	tmpl, err := ParseTemplate(name)
 	if err != nil {
 		return nil, fmt.Errorf("invalid template: %w", err)
 	}
@@ -236,11 +634,7 @@ func newTemplateHelper(name string) (*templateHelper, error) {
 }
 
 func (h *templateHelper) execute(data interface{}) (string, error) {
-	var tmp bytes.Buffer
-	if err := h.template.Execute(&tmp, data); err != nil {
-		return "", err
-	}
-	return tmp.String(), nil
+	return executeTemplate(h.template, data)
 }
 
 // expandMap is a helper for expanding a template in to a map of strings. Data
@@ -266,3 +660,81 @@ func (h *templateHelper) expandMap(data interface{}) (map[string]string, error)
 	}
 	return out, nil
 }
+
+// TemplateFuncs returns the functions available in rule templates, in addition
+// to the builtin functions of text/template. The aggregate functions operate
+// on one attribute of a list of matched elements, e.g.
+// {{ sum .pci.device "sriov_totalvfs" }}. Attribute values are parsed as
+// resource quantities so values with units (e.g. "16Gi") are supported.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"sum": templateSum,
+		"min": templateMin,
+		"max": templateMax,
+	}
+}
+
+func templateSum(elems []MatchedElement, attr string) (string, error) {
+	q, err := aggregateQuantities(elems, attr, func(acc *resource.Quantity, q resource.Quantity) {
+		acc.Add(q)
+	})
+	if err != nil {
+		return "", err
+	}
+	if q == nil {
+		return "0", nil
+	}
+	return q.String(), nil
+}
+
+func templateMin(elems []MatchedElement, attr string) (string, error) {
+	q, err := aggregateQuantities(elems, attr, func(acc *resource.Quantity, q resource.Quantity) {
+		if q.Cmp(*acc) < 0 {
+			*acc = q
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if q == nil {
+		return "", fmt.Errorf("min: no values of attribute %q", attr)
+	}
+	return q.String(), nil
+}
+
+func templateMax(elems []MatchedElement, attr string) (string, error) {
+	q, err := aggregateQuantities(elems, attr, func(acc *resource.Quantity, q resource.Quantity) {
+		if q.Cmp(*acc) > 0 {
+			*acc = q
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if q == nil {
+		return "", fmt.Errorf("max: no values of attribute %q", attr)
+	}
+	return q.String(), nil
+}
+
+// aggregateQuantities folds the values of attribute attr of all elements that
+// have it. Returns nil if none of the elements have the attribute.
+func aggregateQuantities(elems []MatchedElement, attr string, fn func(*resource.Quantity, resource.Quantity)) (*resource.Quantity, error) {
+	var acc *resource.Quantity
+	for _, e := range elems {
+		v, ok := e[attr]
+		if !ok {
+			continue
+		}
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of attribute %q: %w", v, attr, err)
+		}
+		if acc == nil {
+			acc = &q
+		} else {
+			fn(acc, q)
+		}
+	}
+	return acc, nil
+}
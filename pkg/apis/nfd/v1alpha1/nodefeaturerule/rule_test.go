@@ -17,8 +17,10 @@ limitations under the License.
 package nodefeaturerule
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/assert"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
@@ -32,6 +34,40 @@ func newMatchExpression(op nfdv1alpha1.MatchOp, values ...string) *nfdv1alpha1.M
 	}
 }
 
+func TestEvaluateLogging(t *testing.T) {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["domain-1.kf-1"] = nfdv1alpha1.NewFlagFeatures("key-1")
+	r := &nfdv1alpha1.Rule{
+		Name:   "rule-1",
+		Labels: map[string]string{"label-1": "true"},
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "domain-1.kf-1",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"key-1": newMatchExpression(nfdv1alpha1.MatchExists),
+				},
+			},
+		},
+	}
+
+	var logged []string
+	logger := funcr.New(func(prefix, args string) {
+		logged = append(logged, args)
+	}, funcr.Options{Verbosity: 4})
+
+	m, err := Evaluate(f, r, WithLogger(logger))
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.True(t, m.Matched)
+	assert.NotEmpty(t, logged, "evaluation should have been logged")
+
+	// Without a logger nothing should be logged
+	logged = nil
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.True(t, m.Matched)
+	assert.Empty(t, logged)
+}
+
 func TestRule(t *testing.T) {
 	f := &nfdv1alpha1.Features{}
 	r1 := &nfdv1alpha1.Rule{Labels: map[string]string{"label-1": "", "label-2": "true"}}
@@ -52,6 +88,7 @@ func TestRule(t *testing.T) {
 	m, err := Execute(r1, f)
 	assert.Nilf(t, err, "unexpected error: %v", err)
 	assert.Equal(t, r1.Labels, m.Labels, "empty matcher should have matched empty features")
+	assert.True(t, m.Matched, "empty matcher should have matched empty features")
 
 	_, err = Execute(r2, f)
 	assert.Error(t, err, "matching against a missing feature should have returned an error")
@@ -105,6 +142,7 @@ func TestRule(t *testing.T) {
 	m, err = Execute(r2, f)
 	assert.Nilf(t, err, "unexpected error: %v", err)
 	assert.Nil(t, m.Labels, "keys should not have matched")
+	assert.False(t, m.Matched, "keys should not have matched")
 
 	f.Flags["domain-1.kf-1"].Elements["key-1"] = nfdv1alpha1.Nil{}
 	m, err = Execute(r2, f)
@@ -442,3 +480,370 @@ var-2=
 	assert.Nilf(t, err, "unexpected error: %v", err)
 	assert.Equal(t, map[string]string(nil), m.Labels, "instances should have matched")
 }
+
+func TestTemplateRegexpCaptures(t *testing.T) {
+	f := nfdv1alpha1.NewFeatures()
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"full": "6.8.0-31-generic"})
+
+	r := &nfdv1alpha1.Rule{
+		Name:           "kernel-version",
+		LabelsTemplate: `{{ range .kernel.version }}kernel-major={{ .major }}{{ "\n" }}kernel-flavor={{ .flavor }}{{ end }}`,
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "kernel.version",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"full": newMatchExpression(nfdv1alpha1.MatchInRegexp, `^(?P<major>[0-9]+)\..*-(?P<flavor>[a-z]+)$`),
+				},
+			},
+		},
+	}
+	m, err := Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Equal(t, map[string]string{"kernel-major": "6", "kernel-flavor": "generic"}, m.Labels)
+}
+
+func TestTemplateAggregates(t *testing.T) {
+	f := &nfdv1alpha1.Features{
+		Instances: map[string]nfdv1alpha1.InstanceFeatureSet{
+			"pci.device": {
+				Elements: []nfdv1alpha1.InstanceFeature{
+					{Attributes: map[string]string{"class": "0200", "sriov_totalvfs": "8", "vram": "1Gi"}},
+					{Attributes: map[string]string{"class": "0200", "sriov_totalvfs": "16"}},
+					{Attributes: map[string]string{"class": "0200", "sriov_totalvfs": "4", "vram": "512Mi"}},
+					{Attributes: map[string]string{"class": "0300"}},
+				},
+			},
+		},
+	}
+
+	r := &nfdv1alpha1.Rule{
+		LabelsTemplate: `
+vfs-sum={{ sum .pci.device "sriov_totalvfs" }}
+vfs-min={{ min .pci.device "sriov_totalvfs" }}
+vfs-max={{ max .pci.device "sriov_totalvfs" }}
+vram-sum={{ sum .pci.device "vram" }}
+missing-sum={{ sum .pci.device "foo" }}`,
+		ExtendedResources: map[string]string{
+			"vfs":    `{{ sum .pci.device "sriov_totalvfs" }}`,
+			"static": "1",
+		},
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "pci.device",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"class": newMatchExpression(nfdv1alpha1.MatchIn, "0200"),
+				},
+			},
+		},
+	}
+
+	m, err := Execute(r, f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Equal(t, map[string]string{
+		"vfs-sum":     "28",
+		"vfs-min":     "4",
+		"vfs-max":     "16",
+		"vram-sum":    "1536Mi",
+		"missing-sum": "0",
+	}, m.Labels)
+	assert.Equal(t, map[string]string{"vfs": "28", "static": "1"}, m.ExtendedResources)
+
+	// Templated extended resources are dropped if no matcher was evaluated
+	r2 := &nfdv1alpha1.Rule{ExtendedResources: r.ExtendedResources}
+	m, err = Execute(r2, f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Equal(t, map[string]string{"static": "1"}, m.ExtendedResources)
+
+	// Test error cases
+	r.ExtendedResources = nil
+	r.LabelsTemplate = `vfs-min={{ min .pci.device "foo" }}`
+	_, err = Execute(r, f)
+	assert.Error(t, err, "min of no values should fail")
+
+	r.LabelsTemplate = `class-sum={{ sum .pci.device "class" }}`
+	f.Instances["pci.device"].Elements[0].Attributes["class"] = "vga"
+	r.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{"class": newMatchExpression(nfdv1alpha1.MatchExists)}
+	_, err = Execute(r, f)
+	assert.Error(t, err, "sum of non-numeric values should fail")
+}
+
+func TestTemplateOrdering(t *testing.T) {
+	nics := []nfdv1alpha1.InstanceFeature{
+		{Attributes: map[string]string{"name": "eth1", "speed": "10000"}},
+		{Attributes: map[string]string{"name": "eth0", "speed": "25000"}},
+		{Attributes: map[string]string{"name": "eth2", "speed": "9000"}},
+		{Attributes: map[string]string{"name": "eth3"}},
+	}
+	r := &nfdv1alpha1.Rule{
+		Name:           "first-nic",
+		LabelsTemplate: `{{ with index .network.device 0 }}first-nic={{ .name }}{{ end }}`,
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature:          "network.device",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"name": newMatchExpression(nfdv1alpha1.MatchExists)},
+			},
+		},
+	}
+	evaluate := func(elems []nfdv1alpha1.InstanceFeature) map[string]string {
+		f := nfdv1alpha1.NewFeatures()
+		f.Instances["network.device"] = nfdv1alpha1.InstanceFeatureSet{Elements: elems}
+		m, err := Evaluate(f, r)
+		assert.Nilf(t, err, "unexpected error: %v", err)
+		return m.Labels
+	}
+
+	// The output does not depend on the order of the instances
+	assert.Equal(t, map[string]string{"first-nic": "eth0"}, evaluate(nics))
+	reversed := slices.Clone(nics)
+	slices.Reverse(reversed)
+	assert.Equal(t, map[string]string{"first-nic": "eth0"}, evaluate(reversed))
+
+	// Integer attributes are compared as numbers, missing attributes last
+	r.SortBy = []string{"speed"}
+	assert.Equal(t, map[string]string{"first-nic": "eth2"}, evaluate(nics))
+	assert.Equal(t, map[string]string{"first-nic": "eth2"}, evaluate(reversed))
+
+	r.LabelsTemplate = `{{ with index .network.device 3 }}last-nic={{ .name }}{{ end }}`
+	assert.Equal(t, map[string]string{"last-nic": "eth3"}, evaluate(nics))
+}
+
+func TestFeaturePresence(t *testing.T) {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["domain-1.kf-1"] = nfdv1alpha1.NewFlagFeatures("key-1")
+
+	// Missing features fail evaluation by default
+	r := &nfdv1alpha1.Rule{
+		Name:   "presence-rule",
+		Labels: map[string]string{"label-1": "true"},
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{Feature: "domain-1.kf-2"},
+		},
+	}
+	_, err := Evaluate(f, r)
+	assert.Error(t, err)
+
+	r.MatchFeatures[0].Presence = nfdv1alpha1.FeatureRequired
+	_, err = Evaluate(f, r)
+	assert.Error(t, err)
+
+	// Optional features don't match but don't fail evaluation either
+	r.MatchFeatures[0].Presence = nfdv1alpha1.FeatureOptional
+	m, err := Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	// Optional features that are present are matched as usual
+	r.MatchFeatures[0].Feature = "domain-1.kf-1"
+	r.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{"key-1": newMatchExpression(nfdv1alpha1.MatchExists)}
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.True(t, m.Matched)
+	assert.Equal(t, r.Labels, m.Labels)
+
+	// Absent features match only if the feature is missing
+	r.MatchFeatures[0].Presence = nfdv1alpha1.FeatureAbsent
+	r.MatchFeatures[0].MatchExpressions = nil
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	// An empty feature is present
+	f.Flags["domain-1.kf-2"] = nfdv1alpha1.NewFlagFeatures()
+	r.MatchFeatures[0].Feature = "domain-1.kf-2"
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	r.MatchFeatures[0].Feature = "domain-1.kf-3"
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.True(t, m.Matched)
+	assert.Equal(t, r.Labels, m.Labels)
+
+	// Absent terms can't have expressions
+	r.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{"key-1": newMatchExpression(nfdv1alpha1.MatchExists)}
+	_, err = Compile(r)
+	assert.Error(t, err)
+	_, err = Evaluate(f, r)
+	assert.Error(t, err)
+
+	// Invalid presence
+	r.MatchFeatures[0].MatchExpressions = nil
+	r.MatchFeatures[0].Presence = "Sometimes"
+	_, err = Compile(r)
+	assert.Error(t, err)
+}
+
+func TestCompile(t *testing.T) {
+	f := nfdv1alpha1.NewFeatures()
+	f.Attributes["kernel.config"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"NR_CPUS": "8192", "HZ": "1000"})
+
+	r := &nfdv1alpha1.Rule{
+		Name:           "compiled-rule",
+		LabelsTemplate: `{{ range .kernel.config }}kconfig-{{ .Name }}={{ .Value }}{{ end }}`,
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "kernel.config",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"NR_CPUS": newMatchExpression(nfdv1alpha1.MatchGt, "1024"),
+					"HZ":      newMatchExpression(nfdv1alpha1.MatchExists),
+				},
+			},
+		},
+	}
+
+	c, err := Compile(r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Same(t, r, c.Rule())
+	assert.Equal(t, []string{"kernel.config"}, c.Features())
+
+	// Features referenced by the rule are sorted, deduplicated and lowercase
+	r3 := &nfdv1alpha1.Rule{
+		Name: "feature-rule",
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.config"},
+			nfdv1alpha1.FeatureMatcherTerm{Feature: "CPU.cpuid"},
+		},
+		MatchAny: []nfdv1alpha1.MatchAnyElem{
+			{MatchFeatures: nfdv1alpha1.FeatureMatcher{nfdv1alpha1.FeatureMatcherTerm{Feature: "kernel.config"}}},
+			{MatchFeatures: nfdv1alpha1.FeatureMatcher{nfdv1alpha1.FeatureMatcherTerm{Feature: "rule.matched"}}},
+		},
+	}
+	c3, err := Compile(r3)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Equal(t, []string{"cpu.cpuid", "kernel.config", "rule.matched"}, c3.Features())
+	assert.Nil(t, c3.MinFeatureVersion())
+
+	// The minimum worker version is parsed at compile time
+	r3.MinFeatureVersion = "v0.17.0-devel"
+	c3, err = Compile(r3)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.Equal(t, "0.17.0", c3.MinFeatureVersion().String())
+
+	r3.MinFeatureVersion = "latest"
+	c3, err = Compile(r3)
+	assert.ErrorContains(t, err, "minFeatureVersion")
+	_, err = c3.Evaluate(f)
+	assert.ErrorContains(t, err, "minFeatureVersion")
+
+	// The compiled rule gives the same result as executing the rule directly
+	expected, err := Execute(r, f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	for i := 0; i < 2; i++ {
+		m, err := c.Execute(f)
+		assert.Nilf(t, err, "unexpected error: %v", err)
+		assert.Equal(t, expected, m)
+	}
+	assert.True(t, expected.Matched)
+
+	// Invalid templates and expressions are reported at compile time, but
+	// only fail execution when used
+	r2 := &nfdv1alpha1.Rule{
+		Name:           "invalid-rule",
+		LabelsTemplate: "{{ .invalid",
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "kernel.config",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"NR_CPUS": newMatchExpression(nfdv1alpha1.MatchGt, "1", "2"),
+					"FOO":     newMatchExpression(nfdv1alpha1.MatchInRegexp, "("),
+				},
+			},
+		},
+	}
+	c, err = Compile(r2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "LabelsTemplate")
+	assert.Contains(t, err.Error(), "exactly one element")
+	assert.Contains(t, err.Error(), "valid regexps")
+
+	// Matching a missing feature with an invalid expression doesn't fail, use
+	// an existing one for deterministic results
+	r2.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{"NR_CPUS": newMatchExpression(nfdv1alpha1.MatchGt, "1", "2")}
+	c, _ = Compile(r2)
+	_, err = c.Execute(f)
+	assert.Error(t, err)
+
+	r2.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{"FOO": newMatchExpression(nfdv1alpha1.MatchExists)}
+	c, _ = Compile(r2)
+	m, err := c.Execute(f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+}
+
+func TestRuleBind(t *testing.T) {
+	f := nfdv1alpha1.NewFeatures()
+	f.Instances["network.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"name": "eth0", "sriov_capable": "true", "numa_node": "0"}),
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"name": "eth1", "sriov_capable": "false", "numa_node": "1"}),
+	})
+	f.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"class": "0302", "numa_node": "1"}),
+	})
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "5"})
+
+	r := &nfdv1alpha1.Rule{
+		Labels: map[string]string{"gpu-nic-numa": "true"},
+		MatchFeatures: nfdv1alpha1.FeatureMatcher{
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "network.device",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"sriov_capable": newMatchExpression(nfdv1alpha1.MatchIsTrue),
+				},
+				Bind: map[string]string{"nicNuma": "numa_node"},
+			},
+			nfdv1alpha1.FeatureMatcherTerm{
+				Feature: "pci.device",
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+					"class":     newMatchExpression(nfdv1alpha1.MatchIn, "0302"),
+					"numa_node": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, ValueFrom: "nicNuma"},
+				},
+			},
+		},
+	}
+
+	c, err := Compile(r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+
+	// The GPU is on a different NUMA node than the SR-IOV capable NIC
+	m, err := c.Evaluate(f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	f.Instances["network.device"].Elements[1].Attributes["sriov_capable"] = "true"
+	m, err = c.Evaluate(f)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.True(t, m.Matched)
+
+	// Bind to an attribute feature
+	r.MatchFeatures[0] = nfdv1alpha1.FeatureMatcherTerm{
+		Feature: "kernel.version",
+		Bind:    map[string]string{"nicNuma": "major"},
+	}
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	// The term should not match if the attribute to bind is missing
+	r.MatchFeatures[0] = nfdv1alpha1.FeatureMatcherTerm{
+		Feature: "network.device",
+		Bind:    map[string]string{"nicNuma": "numa"},
+	}
+	m, err = Evaluate(f, r)
+	assert.Nilf(t, err, "unexpected error: %v", err)
+	assert.False(t, m.Matched)
+
+	// Variables must be bound by a preceding term
+	r.MatchFeatures[0], r.MatchFeatures[1] = r.MatchFeatures[1], r.MatchFeatures[0]
+	_, err = Compile(r)
+	assert.Error(t, err)
+	_, err = Evaluate(f, r)
+	assert.Error(t, err)
+
+	// ValueFrom is only supported with In and NotIn
+	r.MatchFeatures[0].MatchExpressions = &nfdv1alpha1.MatchExpressionSet{
+		"numa_node": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, ValueFrom: "nicNuma"},
+	}
+	_, err = Compile(r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"sort"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// RunTests runs the tests of a rule set. The rules are evaluated in order
+// against the input features of each test, like nfd-master does, and the
+// labels created are compared against the expected ones. The returned errors
+// describe the failed tests.
+func RunTests(spec *nfdv1alpha1.NodeFeatureRuleSpec) []error {
+	var errs []error
+	for i := range spec.Tests {
+		test := &spec.Tests[i]
+		if err := runTest(spec, test); err != nil {
+			errs = append(errs, fmt.Errorf("test %q failed: %w", test.Name, err))
+		}
+	}
+	return errs
+}
+
+// runTest runs one test of a rule set.
+func runTest(spec *nfdv1alpha1.NodeFeatureRuleSpec, test *nfdv1alpha1.RuleTest) error {
+	features := test.Features.DeepCopy()
+	labels := make(map[string]string)
+
+	for i := range spec.Rules {
+		rule := &spec.Rules[i]
+		ruleOut, err := Evaluate(features, rule)
+		if err != nil {
+			// nfd-master skips rules failing to process, e.g. because of
+			// features missing on the node
+			continue
+		}
+		if spec.LabelNamespace != "" {
+			ruleOut.Labels = addLabelNamespace(ruleOut.Labels, spec.LabelNamespace)
+		}
+		maps.Copy(labels, ruleOut.Labels)
+
+		// Feed back rule output to features map for subsequent rules to match
+		features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
+		features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
+	}
+
+	return diffLabels(test.Labels, labels)
+}
+
+// addLabelNamespace adds the namespace to unprefixed label keys. Labels
+// already having the prefixed key take precedence.
+func addLabelNamespace(labels map[string]string, ns string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if strings.Contains(k, "/") {
+			out[k] = v
+		} else if fqn := path.Join(ns, k); !hasKey(labels, fqn) {
+			out[fqn] = v
+		}
+	}
+	return out
+}
+
+// diffLabels returns an error describing the differences between the
+// expected and actual labels, or nil if there are none.
+func diffLabels(expected, actual map[string]string) error {
+	var errs []error
+	for _, k := range sortedKeys(expected) {
+		if v, ok := actual[k]; !ok {
+			errs = append(errs, fmt.Errorf("missing label %q", k))
+		} else if v != expected[k] {
+			errs = append(errs, fmt.Errorf("label %q: expected %q, got %q", k, expected[k], v))
+		}
+	}
+	for _, k := range sortedKeys(actual) {
+		if !hasKey(expected, k) {
+			errs = append(errs, fmt.Errorf("unexpected label %q=%q", k, actual[k]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
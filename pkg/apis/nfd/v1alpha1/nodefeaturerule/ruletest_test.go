@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodefeaturerule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestRunTests(t *testing.T) {
+	features := nfdv1alpha1.NewFeatures()
+	features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+
+	spec := &nfdv1alpha1.NodeFeatureRuleSpec{
+		LabelNamespace: "example.com",
+		Rules: []nfdv1alpha1.Rule{
+			{
+				Name:   "avx512",
+				Labels: map[string]string{"avx512": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					{
+						Feature:          "cpu.cpuid",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"AVX512F": newMatchExpression(nfdv1alpha1.MatchExists)},
+					},
+				},
+			},
+			{
+				Name:   "backref",
+				Labels: map[string]string{"fast": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					{
+						Feature:          "rule.matched",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"example.com/avx512": newMatchExpression(nfdv1alpha1.MatchIsTrue)},
+					},
+				},
+			},
+		},
+		Tests: []nfdv1alpha1.RuleTest{
+			{
+				Name:     "avx512",
+				Features: *features,
+				Labels:   map[string]string{"example.com/avx512": "true", "example.com/fast": "true"},
+			},
+			{
+				Name:     "no features",
+				Features: *nfdv1alpha1.NewFeatures(),
+			},
+		},
+	}
+
+	assert.Empty(t, RunTests(spec))
+
+	// Test that differences are reported
+	spec.Tests[0].Labels = map[string]string{"example.com/avx512": "false", "example.com/other": "true"}
+	spec.Tests[1].Labels = map[string]string{"example.com/avx512": "true"}
+	errs := RunTests(spec)
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], `test "avx512" failed: label "example.com/avx512": expected "false", got "true"`+"\n"+
+		`missing label "example.com/other"`+"\n"+
+		`unexpected label "example.com/fast"="true"`)
+	assert.EqualError(t, errs[1], `test "no features" failed: missing label "example.com/avx512"`)
+
+	// The input features of the tests must not be modified
+	assert.NotContains(t, spec.Tests[0].Features.Attributes, "rule.matched")
+}
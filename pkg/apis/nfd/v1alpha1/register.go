@@ -40,8 +40,16 @@ func Resource(resource string) schema.GroupResource {
 
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ClusterFeatureSummary{},
+		&ClusterFeatureSummaryList{},
+		&NFDConfig{},
+		&NFDConfigList{},
+		&NamespacedNodeFeatureRule{},
+		&NamespacedNodeFeatureRuleList{},
 		&NodeFeature{},
+		&NodeFeatureList{},
 		&NodeFeatureRule{},
+		&NodeFeatureRuleList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // NodeFeatureList contains a list of NodeFeature objects.
@@ -34,6 +35,7 @@ type NodeFeatureList struct {
 // NodeFeature resource holds the features discovered for one node in the
 // cluster.
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +genclient
 type NodeFeature struct {
@@ -41,6 +43,9 @@ type NodeFeature struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec NodeFeatureSpec `json:"spec"`
+
+	// +optional
+	Status NodeFeatureStatus `json:"status,omitempty"`
 }
 
 // NodeFeatureSpec describes a NodeFeature object.
@@ -52,6 +57,32 @@ type NodeFeatureSpec struct {
 	Labels map[string]string `json:"labels"`
 }
 
+// NodeFeatureStatus is the status of a NodeFeature, maintained by nfd-master.
+type NodeFeatureStatus struct {
+	// ObservedGeneration is the generation of the NodeFeature object most
+	// recently processed by nfd-master.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the NodeFeature. nfd-master maintains the Processed
+	// condition reporting whether the features of the observed generation
+	// were successfully applied to the node.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// NodeFeatureProcessedCondition is the condition of NodeFeature objects
+	// reporting whether nfd-master has successfully updated the node based
+	// on the object.
+	NodeFeatureProcessedCondition = "Processed"
+	// NodeFeatureSchemaValidCondition is the condition of NodeFeature
+	// objects reporting whether the features conform to the schemas of
+	// their sources.
+	NodeFeatureSchemaValidCondition = "SchemaValid"
+)
+
 // Features is the collection of all discovered features.
 //
 // +protobuf=true
@@ -73,6 +104,12 @@ type FlagFeatureSet struct {
 // +protobuf=true
 type AttributeFeatureSet struct {
 	Elements map[string]string `json:"elements" protobuf:"bytes,1,rep,name=elements"`
+	// Types specifies the type of the values of the elements. Elements
+	// without a type are strings. The values are always stored in string
+	// form so that consumers unaware of the types keep working.
+	// NOTE: the types are not carried over the deprecated gRPC API.
+	// +optional
+	Types map[string]AttributeType `json:"types,omitempty"`
 }
 
 // InstanceFeatureSet is a set of features each of which is an instance having multiple attributes.
@@ -80,6 +117,11 @@ type AttributeFeatureSet struct {
 // +protobuf=true
 type InstanceFeatureSet struct {
 	Elements []InstanceFeature `json:"elements" protobuf:"bytes,1,rep,name=elements"`
+	// Types specifies the type of the values of the instance attributes,
+	// shared by all instances. Attributes without a type are strings.
+	// NOTE: the types are not carried over the deprecated gRPC API.
+	// +optional
+	Types map[string]AttributeType `json:"types,omitempty"`
 }
 
 // InstanceFeature represents one instance of a complex features, e.g. a device.
@@ -89,6 +131,22 @@ type InstanceFeature struct {
 	Attributes map[string]string `json:"attributes" protobuf:"bytes,1,rep,name=attributes"`
 }
 
+// AttributeType is the type of the value of an attribute.
+// +kubebuilder:validation:Enum="string";"int";"bool";"list"
+type AttributeType string
+
+const (
+	// AttributeTypeString is a plain string value, the default.
+	AttributeTypeString AttributeType = "string"
+	// AttributeTypeInt is a (64-bit) integer value in decimal form.
+	AttributeTypeInt AttributeType = "int"
+	// AttributeTypeBool is a boolean value, "true" or "false".
+	AttributeTypeBool AttributeType = "bool"
+	// AttributeTypeList is a list of strings, stored as comma-separated
+	// items.
+	AttributeTypeList AttributeType = "list"
+)
+
 // Nil is a dummy empty struct for protobuf compatibility
 //
 // +protobuf=true
@@ -108,6 +166,7 @@ type NodeFeatureRuleList struct {
 // customization of node objects, such as node labeling.
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster,shortName=nfr
+// +kubebuilder:subresource:status
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +genclient
 // +genclient:nonNamespaced
@@ -116,12 +175,207 @@ type NodeFeatureRule struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
 	Spec NodeFeatureRuleSpec `json:"spec"`
+
+	// +optional
+	Status NodeFeatureRuleStatus `json:"status,omitempty"`
+}
+
+// NodeFeatureRuleStatus is the status of a NodeFeatureRule.
+type NodeFeatureRuleStatus struct {
+	// Conditions of the NodeFeatureRule. nfd-master maintains the
+	// TestsPassed condition reporting the result of the tests of the rules
+	// and the MinFeatureVersionSatisfied condition reporting nodes where
+	// rules are not evaluated because of an older nfd-worker.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// NodeFeatureRuleTestsPassedCondition is the condition of NodeFeatureRule
+	// objects reporting whether the tests in spec.tests pass.
+	NodeFeatureRuleTestsPassedCondition = "TestsPassed"
+
+	// NodeFeatureRuleMinFeatureVersionCondition is the condition of
+	// NodeFeatureRule objects reporting whether nfd-worker on all nodes
+	// satisfies the minFeatureVersion of the rules.
+	NodeFeatureRuleMinFeatureVersionCondition = "MinFeatureVersionSatisfied"
+)
+
+// ClusterFeatureSummaryList contains a list of ClusterFeatureSummary objects.
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ClusterFeatureSummaryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterFeatureSummary `json:"items"`
+}
+
+// ClusterFeatureSummary is a cluster-wide summary of the feature labels,
+// maintained by nfd-master.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=cfs
+// +kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+type ClusterFeatureSummary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Status ClusterFeatureSummaryStatus `json:"status,omitempty"`
+}
+
+// ClusterFeatureSummaryStatus is the aggregated feature label data of the
+// cluster.
+type ClusterFeatureSummaryStatus struct {
+	// NodeCount is the number of nodes in the cluster.
+	NodeCount int `json:"nodeCount"`
+	// Labels contains the number of nodes having each value of each feature
+	// label, i.e. a map of label names to a map of label values to node
+	// counts.
+	// +optional
+	Labels map[string]map[string]int `json:"labels,omitempty"`
+}
+
+// NFDConfigList contains a list of NFDConfig objects.
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NFDConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NFDConfig `json:"items"`
+}
+
+// NFDConfig is the cluster-wide configuration of the NFD daemons. It is read
+// by nfd-master and nfd-worker in addition to their configuration files and
+// command line flags, enabling GitOps-managed configuration.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=nfdconf
+// +kubebuilder:subresource:status
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:nonNamespaced
+type NFDConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NFDConfigSpec `json:"spec"`
+
+	// +optional
+	Status NFDConfigStatus `json:"status,omitempty"`
+}
+
+// NFDConfigSpec holds the configuration of the NFD daemons.
+type NFDConfigSpec struct {
+	// Master is the configuration of nfd-master, in the format of the
+	// nfd-master configuration file. It overrides the settings of the
+	// configuration file and is overridden by command line flags.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	// +optional
+	Master *runtime.RawExtension `json:"master,omitempty"`
+
+	// Worker is the configuration of nfd-worker, in the format of the
+	// nfd-worker configuration file. It overrides the settings of the
+	// configuration file and is overridden by command line flags.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	// +optional
+	Worker *runtime.RawExtension `json:"worker,omitempty"`
+}
+
+// NFDConfigStatus reports whether the configuration was accepted.
+type NFDConfigStatus struct {
+	// Conditions of the configuration. nfd-master maintains the
+	// MasterConfigValid and nfd-worker the WorkerConfigValid condition.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// NFDConfigMasterValidCondition is the condition of NFDConfig objects
+	// reporting whether the nfd-master configuration is valid.
+	NFDConfigMasterValidCondition = "MasterConfigValid"
+	// NFDConfigWorkerValidCondition is the condition of NFDConfig objects
+	// reporting whether the nfd-worker configuration is valid.
+	NFDConfigWorkerValidCondition = "WorkerConfigValid"
+)
+
+// NamespacedNodeFeatureRuleList contains a list of NamespacedNodeFeatureRule
+// objects.
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NamespacedNodeFeatureRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NamespacedNodeFeatureRule `json:"items"`
+}
+
+// NamespacedNodeFeatureRule is the namespaced counterpart of NodeFeatureRule,
+// allowing rule management to be delegated to namespace (tenant) owners. The
+// output of the rules is restricted by the nfd-master rule delegation policy
+// of the namespace.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,shortName=nnfr
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+type NamespacedNodeFeatureRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodeFeatureRuleSpec `json:"spec"`
 }
 
 // NodeFeatureRuleSpec describes a NodeFeatureRule.
 type NodeFeatureRuleSpec struct {
+	// NodeSelector limits the nodes the rules are evaluated for. If not
+	// specified the rules are evaluated for all nodes.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// LabelNamespace is the namespace (prefix) of the unprefixed label keys
+	// created by the rules, e.g. "vendor.example.com". Unprefixed label keys
+	// are left as is if not specified. Subsequent rules match the labels
+	// (in the rule.matched feature) with the namespace included. The
+	// namespace is subject to the same restrictions as label namespaces in
+	// general, the rules are not processed if the namespace is not allowed by
+	// nfd-master.
+	// +optional
+	LabelNamespace string `json:"labelNamespace,omitempty"`
+
 	// Rules is a list of node customization rules.
 	Rules []Rule `json:"rules"`
+
+	// Tests is a list of test cases of the rules, each specifying sample
+	// input features and the labels the rules are expected to create from
+	// them. The tests are run by nfd-master, which reports the result in the
+	// status of NodeFeatureRule objects, and by kubectl nfd validate.
+	// +optional
+	Tests []RuleTest `json:"tests,omitempty"`
+}
+
+// RuleTest is a test case of the rules of a NodeFeatureRule.
+type RuleTest struct {
+	// Name of the test.
+	Name string `json:"name"`
+
+	// Features are the input features of the test.
+	Features Features `json:"features"`
+
+	// Labels are the node labels the rules are expected to create from the
+	// input features. The labelNamespace of the rules is applied to the
+	// created labels before comparing them. Any difference fails the test.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // Rule defines a rule for node customization such as labeling.
@@ -156,11 +410,43 @@ type Rule struct {
 	// +optional
 	VarsTemplate string `json:"varsTemplate"`
 
+	// SortBy specifies the attributes by which the matched elements of each
+	// feature are sorted before expanding the templates, in order of
+	// priority. Numeric values are compared as numbers. Elements are always
+	// sorted by all their attributes in the end so that the output of the
+	// templates does not depend on the order of the input features.
+	// +optional
+	SortBy []string `json:"sortBy,omitempty"`
+
 	// Taints to create if the rule matches.
 	// +optional
 	Taints []corev1.Taint `json:"taints,omitempty"`
 
-	// ExtendedResources to create if the rule matches.
+	// Cordon marks the node unschedulable if the rule matches. Requires
+	// the NodeCordon feature gate to be enabled in nfd-master.
+	// +optional
+	Cordon bool `json:"cordon,omitempty"`
+
+	// ExpireAfter makes the output of the rule expire if the features of the
+	// node have not been refreshed by nfd-worker within the given duration.
+	// nfd-worker refreshes the features at least every 10 minutes, values
+	// below 15 minutes are raised to 15 minutes.
+	// +optional
+	ExpireAfter *metav1.Duration `json:"expireAfter,omitempty"`
+
+	// MinFeatureVersion is the minimum version of nfd-worker required for
+	// discovering the features the rule relies on. The rule is not evaluated
+	// on nodes running an older nfd-worker, e.g. during a rolling upgrade,
+	// so that features not yet discovered there do not cause incorrect
+	// negatives. The previous output of the rule is retained on such nodes
+	// if it is known to nfd-master. Nodes whose nfd-worker version is not
+	// known are not affected.
+	// +optional
+	MinFeatureVersion string `json:"minFeatureVersion,omitempty"`
+
+	// ExtendedResources to create if the rule matches. Values may be
+	// templates that expand to a quantity, e.g. using the sum, min and max
+	// template functions over the matched features.
 	// +optional
 	ExtendedResources map[string]string `json:"extendedResources"`
 
@@ -197,8 +483,43 @@ type FeatureMatcherTerm struct {
 	// element in the feature set.
 	// +optional
 	MatchName *MatchExpression `json:"matchName"`
+	// Bind binds variables to attribute values of the matched feature, for
+	// use with ValueFrom in the match expressions of subsequent terms of the
+	// same feature matcher. The keys are variable names and the values are
+	// attribute names. For instance features a variable is bound to the
+	// values of the attribute in all matched instances, for attribute
+	// features to the value of the attribute. The term does not match if the
+	// attribute is not present in any matched element.
+	// +optional
+	Bind map[string]string `json:"bind,omitempty"`
+	// Presence specifies how the availability of the feature is handled.
+	// With Required (the default) evaluating the rule fails if the feature
+	// is not available, e.g. on nodes running an older nfd-worker that does
+	// not discover it. With Optional the term does not match if the feature
+	// is not available. With Absent the term matches only if the feature is
+	// not available, matchExpressions, matchName and bind must not be used
+	// with it.
+	// +kubebuilder:validation:Enum="";Required;Optional;Absent
+	// +optional
+	Presence FeaturePresence `json:"presence,omitempty"`
 }
 
+// FeaturePresence specifies how the availability of a feature is handled
+// when matching it.
+type FeaturePresence string
+
+const (
+	// FeatureRequired fails the evaluation of the rule if the feature is not
+	// available.
+	FeatureRequired FeaturePresence = "Required"
+	// FeatureOptional makes the term not match if the feature is not
+	// available.
+	FeatureOptional FeaturePresence = "Optional"
+	// FeatureAbsent makes the term match only if the feature is not
+	// available.
+	FeatureAbsent FeaturePresence = "Absent"
+)
+
 // MatchExpressionSet contains a set of MatchExpressions, each of which is
 // evaluated against a set of input values.
 type MatchExpressionSet map[string]*MatchExpression
@@ -217,8 +538,46 @@ type MatchExpression struct {
 	// In other cases Value should contain at least one element.
 	// +optional
 	Value MatchValue `json:"value,omitempty"`
+
+	// Parse specifies how the input and the values of the expression are
+	// normalized before the operator is applied. Parse is only supported
+	// with the In, NotIn, Gt, Lt and GtLt operators. If the input cannot be
+	// parsed an error is returned.
+	// +optional
+	Parse ValueParser `json:"parse,omitempty"`
+
+	// ValueFrom is the name of a variable bound by a preceding term of the
+	// same feature matcher. The values bound to the variable are used as the
+	// values of the expression, e.g. for matching instances with the same
+	// NUMA node as the instances matched by a preceding term. ValueFrom is
+	// only supported with the In and NotIn operators and must not be used
+	// together with Value.
+	// +optional
+	ValueFrom string `json:"valueFrom,omitempty"`
 }
 
+// ValueParser is the normalization applied on values when evaluating a
+// MatchExpression.
+// +kubebuilder:validation:Enum="size";"hex";"version"
+type ValueParser string
+
+const (
+	// ValueParserSize parses values as sizes in bytes with an optional unit
+	// suffix, e.g. "16384 kB", "32K" or "16Gi". Following the convention of
+	// the Linux kernel, units are powers of 1024 regardless of their
+	// spelling.
+	ValueParserSize ValueParser = "size"
+	// ValueParserHex parses values as hexadecimal numbers with an optional
+	// "0x" prefix, e.g. "0x8086" or "8086".
+	ValueParserHex ValueParser = "hex"
+	// ValueParserVersion parses values as version numbers, e.g. "5.14" or
+	// "4.18.0-372.el8". Only the leading numeric components of the version,
+	// separated by '.', '-', '_' or '+', are compared and missing components
+	// are treated as zero, i.e. "4.18.0-372.el8" is parsed as 4.18.0.372 and
+	// "5.14" equals "5.14.0".
+	ValueParserVersion ValueParser = "version"
+)
+
 // MatchOp is the match operator that is applied on values when evaluating a
 // MatchExpression.
 // +kubebuilder:validation:Enum="In";"NotIn";"InRegexp";"Exists";"DoesNotExist";"Gt";"Lt";"GtLt";"IsTrue";"IsFalse"
@@ -237,7 +596,9 @@ const (
 	// equal to the input.
 	MatchNotIn MatchOp = "NotIn"
 	// MatchInRegexp treats values of the expression as regular expressions and
-	// returns true if any of them matches the input.
+	// returns true if any of them matches the input. Named capture groups of
+	// the matching regular expression are available in templates as
+	// additional fields of the matched element, e.g. {{ .major }}.
 	MatchInRegexp MatchOp = "InRegexp"
 	// MatchExists returns true if the input is valid. The expression must not
 	// have any values.
@@ -277,6 +638,34 @@ const (
 	RuleBackrefFeature = "matched"
 )
 
+const (
+	// NodeFeatureDomain is the special feature domain for matching the
+	// current state of the node object. The features are provided by
+	// nfd-master, they are not published by nfd-worker.
+	NodeFeatureDomain = "node"
+	// NodeLabelsFeature is the special feature name for matching the labels
+	// of the node.
+	NodeLabelsFeature = "labels"
+	// NodeAnnotationsFeature is the special feature name for matching the
+	// annotations of the node.
+	NodeAnnotationsFeature = "annotations"
+	// NodeTaintsFeature is the special feature name for matching the taints
+	// of the node.
+	NodeTaintsFeature = "taints"
+	// NodeInfoFeature is the special feature name for matching the system
+	// info (status.nodeInfo) of the node.
+	NodeInfoFeature = "nodeinfo"
+	// NodeKubeletVersionFeature is the special feature name for matching the
+	// kubelet version of the node.
+	NodeKubeletVersionFeature = "kubeletversion"
+	// NodeCapacityFeature is the special feature name for matching the
+	// resource capacity of the node.
+	NodeCapacityFeature = "capacity"
+	// NodeAllocatableFeature is the special feature name for matching the
+	// allocatable resources of the node.
+	NodeAllocatableFeature = "allocatable"
+)
+
 // MatchAllNames is a special key in MatchExpressionSet to use field names
 // (keys from the input) instead of values when matching.
 const MatchAllNames = "*"
@@ -19,13 +19,14 @@ package validate
 import (
 	"fmt"
 	"strings"
-	"text/template"
 
 	corev1 "k8s.io/api/core/v1"
 	k8sQuantity "k8s.io/apimachinery/pkg/api/resource"
 	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
 )
 
 var (
@@ -61,18 +62,35 @@ func MatchFeatures(matchFeature nfdv1alpha1.FeatureMatcher) []error {
 		if len(nameSplit) != 2 {
 			validationErr = append(validationErr, fmt.Errorf("invalid feature name %v (not <domain>.<feature>), cannot be used for templating", match.Feature))
 		}
+		switch match.Presence {
+		case "", nfdv1alpha1.FeatureRequired, nfdv1alpha1.FeatureOptional:
+		case nfdv1alpha1.FeatureAbsent:
+			if match.MatchExpressions != nil || match.MatchName != nil || len(match.Bind) > 0 {
+				validationErr = append(validationErr, fmt.Errorf("matchExpressions, matchName and bind of feature %v must not be used with presence %q", match.Feature, match.Presence))
+			}
+		default:
+			validationErr = append(validationErr, fmt.Errorf("invalid presence %q of feature %v", match.Presence, match.Feature))
+		}
 	}
 
 	return validationErr
 }
 
+// FeatureVersion validates the minimum nfd-worker version of a rule.
+func FeatureVersion(v string) error {
+	if _, err := version.ParseGeneric(v); err != nil {
+		return fmt.Errorf("invalid minFeatureVersion: %w", err)
+	}
+	return nil
+}
+
 // Template validates a template string and returns a slice of errors if the
 // template is invalid.
 func Template(labelsTemplate string) []error {
 	var validationErr []error
 
 	// Validate template
-	_, err := template.New("").Option("missingkey=error").Parse(labelsTemplate)
+	_, err := nodefeaturerule.ParseTemplate(labelsTemplate)
 	if err != nil {
 		validationErr = append(validationErr, fmt.Errorf("invalid template: %w", err))
 	}
@@ -121,6 +139,21 @@ func Label(key, value string) error {
 	return nil
 }
 
+// LabelNamespace validates a label namespace (prefix) and returns an error if
+// it is invalid or not allowed.
+func LabelNamespace(ns string) error {
+	if err := k8svalidation.IsDNS1123Subdomain(ns); len(err) > 0 {
+		return fmt.Errorf("invalid label namespace %q: %s", ns, strings.Join(err, "; "))
+	}
+	if ns == "kubernetes.io" || strings.HasSuffix(ns, ".kubernetes.io") {
+		if ns != nfdv1alpha1.FeatureLabelNs && ns != nfdv1alpha1.ProfileLabelNs &&
+			!strings.HasSuffix(ns, nfdv1alpha1.FeatureLabelSubNsSuffix) && !strings.HasSuffix(ns, nfdv1alpha1.ProfileLabelSubNsSuffix) {
+			return ErrNSNotAllowed
+		}
+	}
+	return nil
+}
+
 // Annotations validates a map of annotations and returns a slice of errors if
 // any of the annotations are invalid.
 func Annotations(annotations map[string]string) []error {
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 )
 
 func TestAnnotation(t *testing.T) {
@@ -234,3 +236,82 @@ func TestExtendedResource(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		ns   string
+		fail bool
+	}{
+		{name: "Vendor namespace", ns: "vendor.example.com"},
+		{name: "Default namespace", ns: "feature.node.kubernetes.io"},
+		{name: "Sub namespace", ns: "vendor.feature.node.kubernetes.io"},
+		{name: "Denied namespace", ns: "node.kubernetes.io", fail: true},
+		{name: "Invalid namespace", ns: "Vendor_Example", fail: true},
+		{name: "Empty namespace", ns: "", fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LabelNamespace(tt.ns)
+			if (err != nil) != tt.fail {
+				t.Errorf("LabelNamespace() = %v, want failure %v", err, tt.fail)
+			}
+		})
+	}
+}
+
+func TestMatchFeatures(t *testing.T) {
+	tests := []struct {
+		name string
+		term nfdv1alpha1.FeatureMatcherTerm
+		fail bool
+	}{
+		{name: "Valid term", term: nfdv1alpha1.FeatureMatcherTerm{Feature: "domain.feature"}},
+		{name: "Optional term", term: nfdv1alpha1.FeatureMatcherTerm{Feature: "domain.feature", Presence: nfdv1alpha1.FeatureOptional}},
+		{name: "Absent term", term: nfdv1alpha1.FeatureMatcherTerm{Feature: "domain.feature", Presence: nfdv1alpha1.FeatureAbsent}},
+		{name: "Invalid feature name", term: nfdv1alpha1.FeatureMatcherTerm{Feature: "feature"}, fail: true},
+		{name: "Invalid presence", term: nfdv1alpha1.FeatureMatcherTerm{Feature: "domain.feature", Presence: "Sometimes"}, fail: true},
+		{
+			name: "Absent term with expressions",
+			term: nfdv1alpha1.FeatureMatcherTerm{
+				Feature:          "domain.feature",
+				Presence:         nfdv1alpha1.FeatureAbsent,
+				MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"foo": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists}},
+			},
+			fail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := MatchFeatures(nfdv1alpha1.FeatureMatcher{tt.term})
+			if (len(errs) > 0) != tt.fail {
+				t.Errorf("MatchFeatures() = %v, want failure %v", errs, tt.fail)
+			}
+		})
+	}
+}
+
+func TestFeatureVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		fail    bool
+	}{
+		{name: "Release version", version: "v0.17.0"},
+		{name: "Development version", version: "v0.17.0-devel-12-gabcdef"},
+		{name: "Without v prefix", version: "0.17"},
+		{name: "Invalid version", version: "latest", fail: true},
+		{name: "Empty version", version: "", fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FeatureVersion(tt.version)
+			if (err != nil) != tt.fail {
+				t.Errorf("FeatureVersion() = %v, want failure %v", err, tt.fail)
+			}
+		})
+	}
+}
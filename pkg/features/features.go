@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// NodeFeatureGroupAPI enables the NodeFeatureGroup API.
+	NodeFeatureGroupAPI featuregate.Feature = "NodeFeatureGroupAPI"
+	// CELMatching enables CEL expressions in NodeFeatureRule matchers.
+	CELMatching featuregate.Feature = "CELMatching"
+	// Sharding enables splitting the nodes between multiple nfd-master
+	// instances.
+	Sharding featuregate.Feature = "Sharding"
+	// ClusterFeatureSummary enables maintaining the ClusterFeatureSummary
+	// object in nfd-master.
+	ClusterFeatureSummary featuregate.Feature = "ClusterFeatureSummary"
+	// NodeCordon enables cordoning nodes with the cordon output of
+	// NodeFeatureRules.
+	NodeCordon featuregate.Feature = "NodeCordon"
+	// LabelAliases enables publishing renamed feature labels also under
+	// their deprecated names.
+	LabelAliases featuregate.Feature = "LabelAliases"
+)
+
+// DefaultNFDFeatureGates contains the default state of all feature gates.
+// To add a new gate, define a constant for it above and add its spec here.
+var DefaultNFDFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	NodeFeatureGroupAPI:   {Default: false, PreRelease: featuregate.Alpha},
+	CELMatching:           {Default: false, PreRelease: featuregate.Alpha},
+	Sharding:              {Default: false, PreRelease: featuregate.Alpha},
+	ClusterFeatureSummary: {Default: false, PreRelease: featuregate.Alpha},
+	NodeCordon:            {Default: false, PreRelease: featuregate.Alpha},
+	LabelAliases:          {Default: false, PreRelease: featuregate.Alpha},
+}
+
+var (
+	// NFDMutableFeatureGate is the feature gate shared by all NFD daemons.
+	NFDMutableFeatureGate featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+	// NFDFeatureGate is the read-only view of NFDMutableFeatureGate that
+	// should be used for checking if a feature is enabled.
+	NFDFeatureGate featuregate.FeatureGate = NFDMutableFeatureGate
+
+	// FeatureEnabled is a metric reporting the state of each feature gate.
+	// Daemons add it to their metrics server.
+	FeatureEnabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "nfd_feature_enabled",
+			Help: "Whether a feature gate is enabled (1) or not (0).",
+		},
+		[]string{"name", "stage"},
+	)
+
+	// flagGates holds the gates set on the command line. These take
+	// precedence over the ones set in the configuration file.
+	flagGates   = map[string]bool{}
+	flagGatesMu sync.Mutex
+)
+
+func init() {
+	utilruntime.Must(NFDMutableFeatureGate.Add(DefaultNFDFeatureGates))
+	updateMetrics()
+}
+
+// InitFlags registers the -feature-gates command line flag.
+func InitFlags(flagset *flag.FlagSet) {
+	flagset.Var(flagValue{}, "feature-gates",
+		"A set of key=value pairs that describe feature gates for alpha/experimental features. "+
+			"Options are: "+strings.Join(NFDMutableFeatureGate.KnownFeatures(), ", "))
+}
+
+// Apply sets the feature gates from a configuration file. Gates given on the
+// command line override the configuration and gates specified in neither are
+// reset to their defaults, so that removing a gate from the configuration
+// file takes effect on reload.
+func Apply(config map[string]bool) error {
+	gates := make(map[string]bool, len(DefaultNFDFeatureGates))
+	for name, spec := range DefaultNFDFeatureGates {
+		gates[string(name)] = spec.Default
+	}
+	for name, enabled := range config {
+		gates[name] = enabled
+	}
+	flagGatesMu.Lock()
+	for name, enabled := range flagGates {
+		gates[name] = enabled
+	}
+	flagGatesMu.Unlock()
+
+	// Validate before touching the shared gate
+	if err := NFDMutableFeatureGate.DeepCopy().SetFromMap(gates); err != nil {
+		return fmt.Errorf("invalid feature gates: %w", err)
+	}
+	if err := NFDMutableFeatureGate.SetFromMap(gates); err != nil {
+		return err
+	}
+	updateMetrics()
+	return nil
+}
+
+func updateMetrics() {
+	for name, spec := range DefaultNFDFeatureGates {
+		v := 0.0
+		if NFDFeatureGate.Enabled(name) {
+			v = 1
+		}
+		FeatureEnabled.WithLabelValues(string(name), string(spec.PreRelease)).Set(v)
+	}
+}
+
+// flagValue implements flag.Value for the -feature-gates flag.
+type flagValue struct{}
+
+func (flagValue) String() string {
+	flagGatesMu.Lock()
+	defer flagGatesMu.Unlock()
+
+	pairs := make([]string, 0, len(flagGates))
+	for k, v := range flagGates {
+		pairs = append(pairs, k+"="+strconv.FormatBool(v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (flagValue) Set(value string) error {
+	m := map[string]bool{}
+	for _, s := range strings.Split(value, ",") {
+		if len(s) == 0 {
+			continue
+		}
+		k, v, ok := strings.Cut(s, "=")
+		k = strings.TrimSpace(k)
+		if !ok {
+			return fmt.Errorf("missing bool value for %s", k)
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid value of %s=%s: %w", k, v, err)
+		}
+		m[k] = b
+	}
+	if err := NFDMutableFeatureGate.SetFromMap(m); err != nil {
+		return err
+	}
+
+	flagGatesMu.Lock()
+	for k, v := range m {
+		flagGates[k] = v
+	}
+	flagGatesMu.Unlock()
+	updateMetrics()
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func resetFlagGates() {
+	flagGatesMu.Lock()
+	flagGates = map[string]bool{}
+	flagGatesMu.Unlock()
+	_ = Apply(nil)
+}
+
+func TestApply(t *testing.T) {
+	defer resetFlagGates()
+
+	assert.False(t, NFDFeatureGate.Enabled(NodeFeatureGroupAPI))
+
+	assert.Nil(t, Apply(map[string]bool{"NodeFeatureGroupAPI": true}))
+	assert.True(t, NFDFeatureGate.Enabled(NodeFeatureGroupAPI))
+	assert.Equal(t, 1.0, testutil.ToFloat64(FeatureEnabled.WithLabelValues("NodeFeatureGroupAPI", "ALPHA")))
+
+	// Removing a gate from the config resets it to the default
+	assert.Nil(t, Apply(nil))
+	assert.False(t, NFDFeatureGate.Enabled(NodeFeatureGroupAPI))
+	assert.Equal(t, 0.0, testutil.ToFloat64(FeatureEnabled.WithLabelValues("NodeFeatureGroupAPI", "ALPHA")))
+
+	// Invalid config must not change the state
+	assert.Nil(t, Apply(map[string]bool{"CELMatching": true}))
+	assert.NotNil(t, Apply(map[string]bool{"Sharding": true, "NonExistent": true}))
+	assert.True(t, NFDFeatureGate.Enabled(CELMatching))
+	assert.False(t, NFDFeatureGate.Enabled(Sharding))
+}
+
+func TestFlags(t *testing.T) {
+	defer resetFlagGates()
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	InitFlags(flags)
+
+	assert.NotNil(t, flags.Parse([]string{"-feature-gates=NonExistent=true"}))
+	assert.NotNil(t, flags.Parse([]string{"-feature-gates=Sharding"}))
+	assert.NotNil(t, flags.Parse([]string{"-feature-gates=Sharding=foo"}))
+
+	assert.Nil(t, flags.Parse([]string{"-feature-gates=Sharding=true,CELMatching=false"}))
+	assert.True(t, NFDFeatureGate.Enabled(Sharding))
+	assert.Equal(t, "CELMatching=false,Sharding=true", flags.Lookup("feature-gates").Value.String())
+
+	// Command line flags take precedence over the config
+	assert.Nil(t, Apply(map[string]bool{"Sharding": false, "CELMatching": true, "NodeFeatureGroupAPI": true}))
+	assert.True(t, NFDFeatureGate.Enabled(Sharding))
+	assert.False(t, NFDFeatureGate.Enabled(CELMatching))
+	assert.True(t, NFDFeatureGate.Enabled(NodeFeatureGroupAPI))
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package featureschema holds the schemas of the features published by each
+// feature source, i.e. the names of the features and the names and types of
+// their attributes, and validates features against them.
+package featureschema
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// FeatureKind is the kind of a feature set.
+type FeatureKind string
+
+const (
+	// FlagFeature is a set of flags, see nfdv1alpha1.FlagFeatureSet.
+	FlagFeature FeatureKind = "flag"
+	// AttributeFeature is a set of attributes, see
+	// nfdv1alpha1.AttributeFeatureSet.
+	AttributeFeature FeatureKind = "attribute"
+	// InstanceFeature is a set of instances, see
+	// nfdv1alpha1.InstanceFeatureSet.
+	InstanceFeature FeatureKind = "instance"
+)
+
+// Schema describes the features published by one feature source.
+type Schema struct {
+	// Source is the name of the feature source.
+	Source string `json:"source"`
+	// Features are the features of the source, by feature name (without the
+	// source prefix).
+	Features map[string]FeatureSchema `json:"features"`
+}
+
+// FeatureSchema describes one feature set.
+type FeatureSchema struct {
+	// Kind is the kind of the feature set.
+	Kind FeatureKind `json:"kind"`
+	// Attributes are the known elements of an attribute feature, or the
+	// known attributes of the instances of an instance feature, with their
+	// types.
+	Attributes map[string]nfdv1alpha1.AttributeType `json:"attributes,omitempty"`
+	// AdditionalAttributes allows attributes not listed in Attributes, for
+	// features whose attribute names depend on the system, e.g. kernel
+	// config options.
+	AdditionalAttributes bool `json:"additionalAttributes,omitempty"`
+}
+
+// schemaFiles are the schemas of the built-in feature sources.
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Schema{}
+)
+
+func init() {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		data, err := schemaFiles.ReadFile(path.Join("schemas", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		s, err := Parse(data)
+		if err != nil {
+			panic(fmt.Sprintf("invalid feature schema %q: %v", e.Name(), err))
+		}
+		if err := Register(s); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Parse parses a schema in JSON form.
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Source == "" {
+		return nil, fmt.Errorf("source name missing")
+	}
+	for name, f := range s.Features {
+		switch f.Kind {
+		case FlagFeature:
+			if len(f.Attributes) > 0 {
+				return nil, fmt.Errorf("feature %q: flag features cannot have attributes", name)
+			}
+		case AttributeFeature, InstanceFeature:
+		default:
+			return nil, fmt.Errorf("feature %q: invalid kind %q", name, f.Kind)
+		}
+		for attr, t := range f.Attributes {
+			switch t {
+			case nfdv1alpha1.AttributeTypeString, nfdv1alpha1.AttributeTypeInt, nfdv1alpha1.AttributeTypeBool, nfdv1alpha1.AttributeTypeList:
+			default:
+				return nil, fmt.Errorf("feature %q: attribute %q has invalid type %q", name, attr, t)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Register adds the schema of a feature source to the registry. Sources
+// outside this repository, e.g. third-party NodeFeature producers, may
+// register their own schemas.
+func Register(s *Schema) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[s.Source]; ok {
+		return fmt.Errorf("schema of source %q already registered", s.Source)
+	}
+	registry[s.Source] = s
+	return nil
+}
+
+// Lookup returns the schema of a feature source, nil if no schema has been
+// registered for it.
+func Lookup(source string) *Schema {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[source]
+}
+
+// Sources returns the names of the feature sources having a schema, sorted.
+func Sources() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
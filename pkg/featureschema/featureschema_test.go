@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/source"
+	_ "github.com/openshift/node-feature-discovery/source/cloud"
+	_ "github.com/openshift/node-feature-discovery/source/cpu"
+	_ "github.com/openshift/node-feature-discovery/source/crypto"
+	_ "github.com/openshift/node-feature-discovery/source/dpdk"
+	"github.com/openshift/node-feature-discovery/source/fake"
+	_ "github.com/openshift/node-feature-discovery/source/kernel"
+	_ "github.com/openshift/node-feature-discovery/source/local"
+	_ "github.com/openshift/node-feature-discovery/source/memory"
+	_ "github.com/openshift/node-feature-discovery/source/network"
+	_ "github.com/openshift/node-feature-discovery/source/pci"
+	_ "github.com/openshift/node-feature-discovery/source/power"
+	_ "github.com/openshift/node-feature-discovery/source/storage"
+	_ "github.com/openshift/node-feature-discovery/source/system"
+	_ "github.com/openshift/node-feature-discovery/source/usb"
+	_ "github.com/openshift/node-feature-discovery/source/virtualization"
+)
+
+func TestBuiltinSchemas(t *testing.T) {
+	for name := range source.GetAllFeatureSources() {
+		assert.NotNil(t, Lookup(name), "no schema for feature source %q", name)
+	}
+
+	// The features of the fake source are valid
+	s := source.GetFeatureSource(fake.Name)
+	cs := source.GetConfigurableSource(fake.Name)
+	cs.SetConfig(cs.NewConfig())
+	assert.NoError(t, s.Discover())
+	assert.Empty(t, Validate(s.GetFeatures()))
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte(`{"features": {}}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"source": "foo", "features": {"bar": {"kind": "unknown"}}}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"source": "foo", "features": {"bar": {"kind": "flag", "attributes": {"a": "string"}}}}`))
+	assert.Error(t, err)
+	_, err = Parse([]byte(`{"source": "foo", "features": {"bar": {"kind": "attribute", "attributes": {"a": "float"}}}}`))
+	assert.Error(t, err)
+
+	s, err := Parse([]byte(`{"source": "foo", "features": {"bar": {"kind": "instance", "attributes": {"a": "int"}}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, nfdv1alpha1.AttributeTypeInt, s.Features["bar"].Attributes["a"])
+
+	assert.Error(t, Register(&Schema{Source: "pci"}), "duplicate registration should fail")
+}
+
+func newTestFeatures() *nfdv1alpha1.Features {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+	f.Flags["kernel.version"] = nfdv1alpha1.NewFlagFeatures("6")
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6", "minor": "x", "revision": ""})
+	f.Attributes["kernel.config"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"NO_HZ": "y"})
+	f.Attributes["cpu.topology"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"socket_count": "2"}).
+		WithTypes(map[string]nfdv1alpha1.AttributeType{"socket_count": nfdv1alpha1.AttributeTypeBool})
+	f.Attributes["pci.devices"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"foo": "bar"})
+	f.Attributes["thirdparty.feature"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"foo": "bar"})
+	f.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "8086", "sriov_totalvfs ": "8"}),
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "15b3", "sriov_totalvfs ": "16"}),
+	})
+	return f
+}
+
+func TestValidate(t *testing.T) {
+	f := newTestFeatures()
+	violations := Validate(f)
+
+	reasons := map[string]Reason{}
+	for _, v := range violations {
+		reasons[v.Feature+"/"+v.Attribute] = v.Reason
+	}
+	assert.Equal(t, map[string]Reason{
+		"cpu.topology/socket_count":  ReasonTypeMismatch,
+		"kernel.version/":            ReasonWrongKind,
+		"kernel.version/minor":       ReasonInvalidValue,
+		"pci.device/sriov_totalvfs ": ReasonUnknownAttribute,
+		"pci.devices/":               ReasonUnknownFeature,
+	}, reasons)
+	assert.Len(t, violations, 5, "violations of instance attributes should be reported once")
+	assert.Equal(t, `pci.device: attribute "sriov_totalvfs ": unknown attribute`, violations[3].String())
+
+	// Validation does not modify the features
+	assert.Equal(t, newTestFeatures(), f)
+}
+
+func TestStrip(t *testing.T) {
+	f := newTestFeatures()
+	assert.Len(t, Strip(f), 5)
+	assert.Empty(t, Validate(f))
+
+	assert.NotContains(t, f.Flags, "kernel.version")
+	assert.Contains(t, f.Flags, "cpu.cpuid")
+	assert.Equal(t, map[string]string{"major": "6", "revision": ""}, f.Attributes["kernel.version"].Elements)
+	assert.Empty(t, f.Attributes["cpu.topology"].Elements)
+	assert.Empty(t, f.Attributes["cpu.topology"].Types)
+	assert.NotContains(t, f.Attributes, "pci.devices")
+	assert.Contains(t, f.Attributes, "thirdparty.feature")
+	for _, inst := range f.Instances["pci.device"].Elements {
+		assert.NotContains(t, inst.Attributes, "sriov_totalvfs ")
+		assert.Contains(t, inst.Attributes, "vendor")
+	}
+}
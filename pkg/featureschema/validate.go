@@ -0,0 +1,207 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// Reason tells why a feature violates its schema.
+type Reason string
+
+const (
+	// ReasonUnknownFeature is a feature not present in the schema of its
+	// source.
+	ReasonUnknownFeature Reason = "UnknownFeature"
+	// ReasonWrongKind is a feature of a different kind than in the schema,
+	// e.g. a flag feature published as an attribute feature.
+	ReasonWrongKind Reason = "WrongKind"
+	// ReasonUnknownAttribute is an attribute not present in the schema of
+	// its feature.
+	ReasonUnknownAttribute Reason = "UnknownAttribute"
+	// ReasonTypeMismatch is an attribute declared with a different type
+	// than in the schema.
+	ReasonTypeMismatch Reason = "TypeMismatch"
+	// ReasonInvalidValue is an attribute value not matching the type in
+	// the schema.
+	ReasonInvalidValue Reason = "InvalidValue"
+)
+
+// Violation is one deviation of features from their schema.
+type Violation struct {
+	// Feature is the name of the feature, e.g. "pci.device".
+	Feature string
+	// Attribute is the name of the attribute, empty if the violation
+	// concerns the whole feature.
+	Attribute string
+	Reason    Reason
+	// Detail describes the violation.
+	Detail string
+}
+
+func (v Violation) String() string {
+	if v.Attribute == "" {
+		return fmt.Sprintf("%s: %s", v.Feature, v.Detail)
+	}
+	return fmt.Sprintf("%s: attribute %q: %s", v.Feature, v.Attribute, v.Detail)
+}
+
+// Validate checks features against the schemas of their sources. Features
+// of sources without a schema are not checked. Empty attribute values are
+// accepted for all types. The returned violations are sorted.
+func Validate(features *nfdv1alpha1.Features) []Violation {
+	return check(features, false)
+}
+
+// Strip removes the features and attributes violating their schema from
+// features, returning the violations.
+func Strip(features *nfdv1alpha1.Features) []Violation {
+	return check(features, true)
+}
+
+func check(features *nfdv1alpha1.Features, strip bool) []Violation {
+	var violations []Violation
+
+	for _, name := range sortedKeys(features.Flags) {
+		_, vs := lookupFeature(name, FlagFeature)
+		violations = append(violations, vs...)
+		if len(vs) > 0 && strip {
+			delete(features.Flags, name)
+		}
+	}
+
+	for _, name := range sortedKeys(features.Attributes) {
+		fs, vs := lookupFeature(name, AttributeFeature)
+		violations = append(violations, vs...)
+		if fs == nil {
+			if len(vs) > 0 && strip {
+				delete(features.Attributes, name)
+			}
+			continue
+		}
+		set := features.Attributes[name]
+		for _, attr := range sortedKeys(set.Elements) {
+			v := checkAttribute(name, fs, attr, set.Elements[attr], set.Types[attr])
+			if v == nil {
+				continue
+			}
+			violations = append(violations, *v)
+			if strip {
+				delete(set.Elements, attr)
+				delete(set.Types, attr)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(features.Instances) {
+		fs, vs := lookupFeature(name, InstanceFeature)
+		violations = append(violations, vs...)
+		if fs == nil {
+			if len(vs) > 0 && strip {
+				delete(features.Instances, name)
+			}
+			continue
+		}
+		set := features.Instances[name]
+		// Report each attribute once, not once per instance
+		seen := map[string]bool{}
+		for _, inst := range set.Elements {
+			for _, attr := range sortedKeys(inst.Attributes) {
+				v := checkAttribute(name, fs, attr, inst.Attributes[attr], set.Types[attr])
+				if v == nil {
+					continue
+				}
+				if !seen[attr] {
+					seen[attr] = true
+					violations = append(violations, *v)
+				}
+				if strip {
+					delete(inst.Attributes, attr)
+				}
+			}
+		}
+		if strip {
+			for attr := range seen {
+				delete(set.Types, attr)
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Feature != violations[j].Feature {
+			return violations[i].Feature < violations[j].Feature
+		}
+		return violations[i].Attribute < violations[j].Attribute
+	})
+	return violations
+}
+
+// lookupFeature returns the schema of a feature. The returned schema is nil
+// if the feature violates the schema of its source or if the source has no
+// schema.
+func lookupFeature(name string, kind FeatureKind) (*FeatureSchema, []Violation) {
+	source, feature, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, nil
+	}
+	s := Lookup(source)
+	if s == nil {
+		return nil, nil
+	}
+	fs, ok := s.Features[feature]
+	if !ok {
+		return nil, []Violation{{Feature: name, Reason: ReasonUnknownFeature, Detail: fmt.Sprintf("unknown feature of source %q", source)}}
+	}
+	if fs.Kind != kind {
+		return nil, []Violation{{Feature: name, Reason: ReasonWrongKind, Detail: fmt.Sprintf("%s feature published as %s feature", fs.Kind, kind)}}
+	}
+	return &fs, nil
+}
+
+// checkAttribute checks one attribute against the schema of its feature.
+func checkAttribute(feature string, fs *FeatureSchema, attr, value string, declared nfdv1alpha1.AttributeType) *Violation {
+	t, ok := fs.Attributes[attr]
+	if !ok {
+		if fs.AdditionalAttributes {
+			return nil
+		}
+		return &Violation{Feature: feature, Attribute: attr, Reason: ReasonUnknownAttribute, Detail: "unknown attribute"}
+	}
+	if declared != "" && declared != t {
+		return &Violation{Feature: feature, Attribute: attr, Reason: ReasonTypeMismatch, Detail: fmt.Sprintf("declared type %q, expected %q", declared, t)}
+	}
+	if value == "" {
+		return nil
+	}
+	if _, err := nfdv1alpha1.ParseAttributeValue(value, t); err != nil {
+		return &Violation{Feature: feature, Attribute: attr, Reason: ReasonInvalidValue, Detail: err.Error()}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
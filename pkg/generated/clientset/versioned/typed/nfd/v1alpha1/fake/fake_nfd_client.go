@@ -28,6 +28,18 @@ type FakeNfdV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeNfdV1alpha1) ClusterFeatureSummaries() v1alpha1.ClusterFeatureSummaryInterface {
+	return &FakeClusterFeatureSummaries{c}
+}
+
+func (c *FakeNfdV1alpha1) NFDConfigs() v1alpha1.NFDConfigInterface {
+	return &FakeNFDConfigs{c}
+}
+
+func (c *FakeNfdV1alpha1) NamespacedNodeFeatureRules(namespace string) v1alpha1.NamespacedNodeFeatureRuleInterface {
+	return &FakeNamespacedNodeFeatureRules{c, namespace}
+}
+
 func (c *FakeNfdV1alpha1) NodeFeatures(namespace string) v1alpha1.NodeFeatureInterface {
 	return &FakeNodeFeatures{c, namespace}
 }
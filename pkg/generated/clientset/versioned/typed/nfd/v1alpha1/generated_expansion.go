@@ -18,6 +18,12 @@ limitations under the License.
 
 package v1alpha1
 
+type ClusterFeatureSummaryExpansion interface{}
+
+type NFDConfigExpansion interface{}
+
+type NamespacedNodeFeatureRuleExpansion interface{}
+
 type NodeFeatureExpansion interface{}
 
 type NodeFeatureRuleExpansion interface{}
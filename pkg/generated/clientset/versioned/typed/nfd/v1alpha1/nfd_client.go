@@ -28,6 +28,9 @@ import (
 
 type NfdV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterFeatureSummariesGetter
+	NFDConfigsGetter
+	NamespacedNodeFeatureRulesGetter
 	NodeFeaturesGetter
 	NodeFeatureRulesGetter
 }
@@ -37,6 +40,18 @@ type NfdV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *NfdV1alpha1Client) ClusterFeatureSummaries() ClusterFeatureSummaryInterface {
+	return newClusterFeatureSummaries(c)
+}
+
+func (c *NfdV1alpha1Client) NFDConfigs() NFDConfigInterface {
+	return newNFDConfigs(c)
+}
+
+func (c *NfdV1alpha1Client) NamespacedNodeFeatureRules(namespace string) NamespacedNodeFeatureRuleInterface {
+	return newNamespacedNodeFeatureRules(c, namespace)
+}
+
 func (c *NfdV1alpha1Client) NodeFeatures(namespace string) NodeFeatureInterface {
 	return newNodeFeatures(c, namespace)
 }
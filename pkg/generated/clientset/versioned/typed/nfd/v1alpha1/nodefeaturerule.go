@@ -40,6 +40,7 @@ type NodeFeatureRulesGetter interface {
 type NodeFeatureRuleInterface interface {
 	Create(ctx context.Context, nodeFeatureRule *v1alpha1.NodeFeatureRule, opts v1.CreateOptions) (*v1alpha1.NodeFeatureRule, error)
 	Update(ctx context.Context, nodeFeatureRule *v1alpha1.NodeFeatureRule, opts v1.UpdateOptions) (*v1alpha1.NodeFeatureRule, error)
+	UpdateStatus(ctx context.Context, nodeFeatureRule *v1alpha1.NodeFeatureRule, opts v1.UpdateOptions) (*v1alpha1.NodeFeatureRule, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.NodeFeatureRule, error)
@@ -128,6 +129,21 @@ func (c *nodeFeatureRules) Update(ctx context.Context, nodeFeatureRule *v1alpha1
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *nodeFeatureRules) UpdateStatus(ctx context.Context, nodeFeatureRule *v1alpha1.NodeFeatureRule, opts v1.UpdateOptions) (result *v1alpha1.NodeFeatureRule, err error) {
+	result = &v1alpha1.NodeFeatureRule{}
+	err = c.client.Put().
+		Resource("nodefeaturerules").
+		Name(nodeFeatureRule.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(nodeFeatureRule).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the nodeFeatureRule and deletes it. Returns an error if one occurs.
 func (c *nodeFeatureRules) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -53,6 +53,12 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=nfd.openshift.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterfeaturesummaries"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Nfd().V1alpha1().ClusterFeatureSummaries().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("nfdconfigs"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Nfd().V1alpha1().NFDConfigs().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("namespacednodefeaturerules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Nfd().V1alpha1().NamespacedNodeFeatureRules().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("nodefeatures"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Nfd().V1alpha1().NodeFeatures().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("nodefeaturerules"):
@@ -24,6 +24,12 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterFeatureSummaries returns a ClusterFeatureSummaryInformer.
+	ClusterFeatureSummaries() ClusterFeatureSummaryInformer
+	// NFDConfigs returns a NFDConfigInformer.
+	NFDConfigs() NFDConfigInformer
+	// NamespacedNodeFeatureRules returns a NamespacedNodeFeatureRuleInformer.
+	NamespacedNodeFeatureRules() NamespacedNodeFeatureRuleInformer
 	// NodeFeatures returns a NodeFeatureInformer.
 	NodeFeatures() NodeFeatureInformer
 	// NodeFeatureRules returns a NodeFeatureRuleInformer.
@@ -41,6 +47,21 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterFeatureSummaries returns a ClusterFeatureSummaryInformer.
+func (v *version) ClusterFeatureSummaries() ClusterFeatureSummaryInformer {
+	return &clusterFeatureSummaryInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// NFDConfigs returns a NFDConfigInformer.
+func (v *version) NFDConfigs() NFDConfigInformer {
+	return &nFDConfigInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// NamespacedNodeFeatureRules returns a NamespacedNodeFeatureRuleInformer.
+func (v *version) NamespacedNodeFeatureRules() NamespacedNodeFeatureRuleInformer {
+	return &namespacedNodeFeatureRuleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // NodeFeatures returns a NodeFeatureInformer.
 func (v *version) NodeFeatures() NodeFeatureInformer {
 	return &nodeFeatureInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -18,6 +18,22 @@ limitations under the License.
 
 package v1alpha1
 
+// ClusterFeatureSummaryListerExpansion allows custom methods to be added to
+// ClusterFeatureSummaryLister.
+type ClusterFeatureSummaryListerExpansion interface{}
+
+// NFDConfigListerExpansion allows custom methods to be added to
+// NFDConfigLister.
+type NFDConfigListerExpansion interface{}
+
+// NamespacedNodeFeatureRuleListerExpansion allows custom methods to be added to
+// NamespacedNodeFeatureRuleLister.
+type NamespacedNodeFeatureRuleListerExpansion interface{}
+
+// NamespacedNodeFeatureRuleNamespaceListerExpansion allows custom methods to be added to
+// NamespacedNodeFeatureRuleNamespaceLister.
+type NamespacedNodeFeatureRuleNamespaceListerExpansion interface{}
+
 // NodeFeatureListerExpansion allows custom methods to be added to
 // NodeFeatureLister.
 type NodeFeatureListerExpansion interface{}
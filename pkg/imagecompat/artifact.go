@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagecompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+)
+
+const annotationCreated = "org.opencontainers.image.created"
+
+// emptyJSON is the content of the empty config blob of OCI artifacts.
+var emptyJSON = []byte("{}")
+
+// Attach attaches an image compatibility specification to an image in the
+// registry as an OCI artifact referring to the image manifest. It returns
+// the descriptor of the artifact manifest.
+func Attach(ctx context.Context, c *Client, image string, spec *compatv1alpha1.Spec) (Descriptor, error) {
+	if err := ValidateSpec(spec); err != nil {
+		return Descriptor{}, err
+	}
+	ref, err := ParseReference(image)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	subject, err := c.Resolve(ctx, ref)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	specData, err := json.Marshal(spec)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	config, err := c.PushBlob(ctx, ref, mediaTypeEmptyJSON, emptyJSON)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	layer, err := c.PushBlob(ctx, ref, compatv1alpha1.SpecMediaType, specData)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	manifest := &Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeImageManifest,
+		ArtifactType:  compatv1alpha1.ArtifactType,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+		Subject:       &Descriptor{MediaType: subject.MediaType, Digest: subject.Digest, Size: subject.Size},
+		Annotations:   map[string]string{annotationCreated: time.Now().UTC().Format(time.RFC3339)},
+	}
+	return c.PushManifest(ctx, ref, manifest)
+}
+
+// Fetch looks up the image compatibility specification attached to an
+// image. ErrNotFound is returned if the image has no specification
+// attached. If there are several the most recently created one is used.
+func Fetch(ctx context.Context, c *Client, image string) (*compatv1alpha1.Spec, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := c.Resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	referrers, err := c.Referrers(ctx, ref, subject, compatv1alpha1.ArtifactType)
+	if err != nil {
+		return nil, err
+	}
+	if len(referrers) == 0 {
+		return nil, fmt.Errorf("no image compatibility specification attached to %s: %w", image, ErrNotFound)
+	}
+
+	// RFC 3339 timestamps in UTC sort lexicographically
+	latest := referrers[0]
+	for _, r := range referrers[1:] {
+		if r.Annotations[annotationCreated] > latest.Annotations[annotationCreated] {
+			latest = r
+		}
+	}
+
+	manifest, err := c.FetchManifest(ctx, ref, latest)
+	if err != nil {
+		return nil, err
+	}
+	var layer *Descriptor
+	for i := range manifest.Layers {
+		if manifest.Layers[i].MediaType == compatv1alpha1.SpecMediaType {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil {
+		return nil, fmt.Errorf("artifact %s has no layer of type %q", latest.Digest, compatv1alpha1.SpecMediaType)
+	}
+
+	data, err := c.FetchBlob(ctx, ref, *layer)
+	if err != nil {
+		return nil, err
+	}
+	spec := &compatv1alpha1.Spec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse image compatibility specification of %s: %w", image, err)
+	}
+	if err := ValidateSpec(spec); err != nil {
+		return nil, fmt.Errorf("invalid image compatibility specification attached to %s: %w", image, err)
+	}
+	return spec, nil
+}
+
+// IsNotFound returns true if the error indicates that the image or its
+// compatibility specification was not found.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagecompat
+
+import (
+	"errors"
+	"fmt"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/validate"
+)
+
+// Result is the result of evaluating one compatibility set against the
+// features of a node.
+type Result struct {
+	// Name of the compatibility set.
+	Name string
+	// Description of the compatibility set.
+	Description string
+	// Optional is true if the compatibility set is informative only.
+	Optional bool
+	// Matched is true if the node satisfies the compatibility set.
+	Matched bool
+}
+
+// ValidateSpec validates an image compatibility specification.
+func ValidateSpec(spec *compatv1alpha1.Spec) error {
+	if spec.Version != compatv1alpha1.Version {
+		return fmt.Errorf("unsupported image compatibility specification version %q, expected %q", spec.Version, compatv1alpha1.Version)
+	}
+	if len(spec.Compatibilities) == 0 {
+		return fmt.Errorf("image compatibility specification has no compatibilities")
+	}
+
+	errs := []error{}
+	names := make(map[string]struct{}, len(spec.Compatibilities))
+	for i, c := range spec.Compatibilities {
+		if c.Name == "" {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: name must be specified", i))
+		} else if _, ok := names[c.Name]; ok {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: duplicate name %q", i, c.Name))
+		}
+		names[c.Name] = struct{}{}
+
+		if len(c.MatchFeatures) == 0 && len(c.MatchAny) == 0 {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: matchFeatures or matchAny must be specified", i))
+		}
+		for _, err := range validate.MatchFeatures(c.MatchFeatures) {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: %w", i, err))
+		}
+		for _, err := range validate.MatchAny(c.MatchAny) {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: %w", i, err))
+		}
+		if _, err := nodefeaturerule.Compile(Rule(&spec.Compatibilities[i])); err != nil {
+			errs = append(errs, fmt.Errorf("compatibilities[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rule returns a NodeFeatureRule rule that matches nodes satisfying the
+// compatibility set.
+func Rule(c *compatv1alpha1.Compatibility) *nfdv1alpha1.Rule {
+	return &nfdv1alpha1.Rule{
+		Name:          c.Name,
+		MatchFeatures: c.MatchFeatures,
+		MatchAny:      c.MatchAny,
+	}
+}
+
+// Evaluate evaluates all compatibility sets of the specification against the
+// features of a node.
+func Evaluate(spec *compatv1alpha1.Spec, features *nfdv1alpha1.Features) ([]Result, error) {
+	results := make([]Result, 0, len(spec.Compatibilities))
+	for i := range spec.Compatibilities {
+		c := &spec.Compatibilities[i]
+		out, err := nodefeaturerule.Evaluate(features, Rule(c))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate compatibility %q: %w", c.Name, err)
+		}
+		results = append(results, Result{
+			Name:        c.Name,
+			Description: c.Description,
+			Optional:    c.Optional,
+			Matched:     out.Matched,
+		})
+	}
+	return results, nil
+}
+
+// Compatible returns true if all required compatibility sets matched.
+func Compatible(results []Result) bool {
+	for _, r := range results {
+		if !r.Optional && !r.Matched {
+			return false
+		}
+	}
+	return true
+}
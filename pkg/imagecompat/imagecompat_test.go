@@ -0,0 +1,278 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagecompat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// fakeRegistry is a minimal in-memory OCI registry serving a single
+// repository.
+type fakeRegistry struct {
+	sync.Mutex
+	referrersAPI bool
+	token        string
+	blobs        map[string][]byte
+	manifests    map[string][]byte
+	mediaTypes   map[string]string
+	tags         map[string]string
+}
+
+func newFakeRegistry(referrersAPI bool) *fakeRegistry {
+	r := &fakeRegistry{
+		referrersAPI: referrersAPI,
+		blobs:        map[string][]byte{},
+		manifests:    map[string][]byte{},
+		mediaTypes:   map[string]string{},
+		tags:         map[string]string{},
+	}
+	image := []byte(`{"schemaVersion":2,"mediaType":"` + mediaTypeImageManifest + `","config":{},"layers":[]}`)
+	r.addManifest("latest", mediaTypeImageManifest, image)
+	return r
+}
+
+func (r *fakeRegistry) addManifest(tag, mediaType string, data []byte) string {
+	d := digestOf(data)
+	r.manifests[d] = data
+	r.mediaTypes[d] = mediaType
+	if tag != "" {
+		r.tags[tag] = d
+	}
+	return d
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	if req.URL.Path == "/token" {
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		return
+	}
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+req.Host+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/test/image/")
+	switch {
+	case strings.HasPrefix(path, "manifests/"):
+		ref := strings.TrimPrefix(path, "manifests/")
+		if req.Method == http.MethodPut {
+			data, _ := io.ReadAll(req.Body)
+			tag := ""
+			if !strings.HasPrefix(ref, "sha256:") {
+				tag = ref
+			}
+			r.addManifest(tag, req.Header.Get("Content-Type"), data)
+			if r.referrersAPI {
+				w.Header().Set("OCI-Subject", "present")
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if d, ok := r.tags[ref]; ok {
+			ref = d
+		}
+		data, ok := r.manifests[ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", r.mediaTypes[ref])
+		w.Header().Set("Docker-Content-Digest", ref)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		} else {
+			w.Header().Set("Content-Length", "0")
+		}
+	case strings.HasPrefix(path, "blobs/uploads/"):
+		if req.Method == http.MethodPost {
+			w.Header().Set("Location", "/v2/test/image/blobs/uploads/1")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "blobs/"):
+		data, ok := r.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	case strings.HasPrefix(path, "referrers/") && r.referrersAPI:
+		subject := strings.TrimPrefix(path, "referrers/")
+		idx := index{SchemaVersion: 2, MediaType: mediaTypeImageIndex, Manifests: []Descriptor{}}
+		for d, data := range r.manifests {
+			m := Manifest{}
+			_ = json.Unmarshal(data, &m)
+			if m.Subject != nil && m.Subject.Digest == subject {
+				idx.Manifests = append(idx.Manifests, Descriptor{
+					MediaType:    r.mediaTypes[d],
+					ArtifactType: m.ArtifactType,
+					Digest:       d,
+					Size:         int64(len(data)),
+					Annotations:  m.Annotations,
+				})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(idx)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestSpec() *compatv1alpha1.Spec {
+	return &compatv1alpha1.Spec{
+		Version: compatv1alpha1.Version,
+		Compatibilities: []compatv1alpha1.Compatibility{
+			{
+				Name: "avx512",
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					{
+						Feature: "cpu.cpuid",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"AVX512F": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+						},
+					},
+				},
+			},
+			{
+				Name:     "kernel",
+				Optional: true,
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					{
+						Feature: "kernel.version",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"major": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: []string{"5"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	tcs := []struct {
+		ref      string
+		expected Reference
+		err      bool
+	}{
+		{ref: "busybox", expected: Reference{Registry: "registry-1.docker.io", Repository: "library/busybox", Tag: "latest"}},
+		{ref: "docker.io/foo/bar:v1", expected: Reference{Registry: "registry-1.docker.io", Repository: "foo/bar", Tag: "v1"}},
+		{ref: "localhost:5000/foo:v2", expected: Reference{Registry: "localhost:5000", Repository: "foo", Tag: "v2"}},
+		{ref: "quay.io/a/b@sha256:abcd", expected: Reference{Registry: "quay.io", Repository: "a/b", Digest: "sha256:abcd"}},
+		{ref: "quay.io/a/b:v1@sha256:abcd", expected: Reference{Registry: "quay.io", Repository: "a/b", Tag: "v1", Digest: "sha256:abcd"}},
+		{ref: "quay.io/A/b", err: true},
+		{ref: "quay.io/a/b:@", err: true},
+	}
+	for _, tc := range tcs {
+		r, err := ParseReference(tc.ref)
+		if tc.err {
+			assert.Error(t, err, tc.ref)
+			continue
+		}
+		assert.NoError(t, err, tc.ref)
+		assert.Equal(t, tc.expected, r, tc.ref)
+	}
+}
+
+func TestAttachFetch(t *testing.T) {
+	for _, referrersAPI := range []bool{true, false} {
+		reg := newFakeRegistry(referrersAPI)
+		reg.token = "secret"
+		srv := httptest.NewServer(reg)
+		image := strings.TrimPrefix(srv.URL, "http://") + "/test/image:latest"
+		c := &Client{PlainHTTP: true}
+
+		_, err := Fetch(context.TODO(), c, image)
+		assert.True(t, IsNotFound(err), "expected not found, got %v", err)
+
+		spec := newTestSpec()
+		desc, err := Attach(context.TODO(), c, image, spec)
+		assert.NoError(t, err)
+		assert.Equal(t, compatv1alpha1.ArtifactType, desc.ArtifactType)
+
+		fetched, err := Fetch(context.TODO(), c, image)
+		assert.NoError(t, err)
+		assert.Equal(t, spec, fetched)
+
+		_, err = Fetch(context.TODO(), c, strings.TrimPrefix(srv.URL, "http://")+"/test/image:missing")
+		assert.True(t, IsNotFound(err), "expected not found, got %v", err)
+
+		srv.Close()
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	assert.NoError(t, ValidateSpec(newTestSpec()))
+
+	spec := newTestSpec()
+	spec.Version = "v2"
+	assert.Error(t, ValidateSpec(spec))
+
+	spec = newTestSpec()
+	spec.Compatibilities[1].Name = spec.Compatibilities[0].Name
+	assert.Error(t, ValidateSpec(spec))
+
+	spec = newTestSpec()
+	spec.Compatibilities[0].MatchFeatures = nil
+	assert.Error(t, ValidateSpec(spec))
+
+	spec = newTestSpec()
+	(*spec.Compatibilities[0].MatchFeatures[0].MatchExpressions)["AVX512F"].Value = []string{"x"}
+	assert.Error(t, ValidateSpec(spec))
+}
+
+func TestEvaluate(t *testing.T) {
+	spec := newTestSpec()
+
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "4"})
+
+	results, err := Evaluate(spec, f)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Name: "avx512", Matched: true},
+		{Name: "kernel", Optional: true, Matched: false},
+	}, results)
+	assert.True(t, Compatible(results))
+
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX2")
+	results, err = Evaluate(spec, f)
+	assert.NoError(t, err)
+	assert.False(t, Compatible(results))
+}
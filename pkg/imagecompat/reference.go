@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagecompat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+var (
+	repositoryRe = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRe        = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRe     = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+)
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	// Registry is the host (and port) of the registry.
+	Registry string
+	// Repository is the name of the repository in the registry.
+	Repository string
+	// Tag of the image, empty if the reference has a digest.
+	Tag string
+	// Digest of the image manifest, empty if not specified.
+	Digest string
+}
+
+// ParseReference parses an image reference of the form
+// [registry/]repository[:tag][@digest]. References without a registry refer
+// to Docker Hub and references without a tag or digest to the latest tag.
+func ParseReference(ref string) (Reference, error) {
+	r := Reference{}
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, r.Digest = name[:i], name[i+1:]
+		if !digestRe.MatchString(r.Digest) {
+			return r, fmt.Errorf("invalid digest in image reference %q", ref)
+		}
+	}
+	// A colon after the last slash separates the tag
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, r.Tag = name[:i], name[i+1:]
+		if !tagRe.MatchString(r.Tag) {
+			return r, fmt.Errorf("invalid tag in image reference %q", ref)
+		}
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+
+	// The first component is a registry if it looks like a host name
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		r.Registry, r.Repository = name[:i], name[i+1:]
+	} else {
+		r.Registry, r.Repository = dockerHub, name
+	}
+	if r.Registry == dockerHub {
+		r.Registry = dockerHubRegistry
+		if !strings.Contains(r.Repository, "/") {
+			r.Repository = "library/" + r.Repository
+		}
+	}
+	if !repositoryRe.MatchString(r.Repository) {
+		return r, fmt.Errorf("invalid repository in image reference %q", ref)
+	}
+	return r, nil
+}
+
+// reference returns the tag or digest used to look up the manifest. The
+// digest takes precedence.
+func (r Reference) reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the reference in canonical form.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
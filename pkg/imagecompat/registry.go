@@ -0,0 +1,587 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagecompat
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	mediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerV2      = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeEmptyJSON     = "application/vnd.oci.empty.v1+json"
+
+	// maxBlobSize limits the size of manifests and blobs read from a registry.
+	maxBlobSize = 4 << 20
+)
+
+var manifestMediaTypes = []string{
+	mediaTypeImageManifest,
+	mediaTypeImageIndex,
+	mediaTypeDockerV2,
+	mediaTypeDockerList,
+}
+
+// ErrNotFound is returned when the requested object does not exist in the
+// registry.
+var ErrNotFound = errors.New("not found")
+
+// Descriptor describes content stored in a registry.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// index is an OCI image index, used for the referrers API response.
+type index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Credentials for authenticating to a registry.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client is a minimal client for the OCI distribution API.
+type Client struct {
+	// HTTPClient used for requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// PlainHTTP uses http instead of https to connect to registries.
+	PlainHTTP bool
+	// Credentials returns the credentials for a registry host, or nil for
+	// anonymous access.
+	Credentials func(registry string) *Credentials
+
+	// tokens caches bearer tokens per registry and repository
+	tokens map[string]string
+}
+
+// digestOf returns the sha256 digest of data.
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// StaticCredentials returns a credentials function that uses the same
+// username and password for all registries.
+func StaticCredentials(username, password string) func(string) *Credentials {
+	return func(string) *Credentials {
+		return &Credentials{Username: username, Password: password}
+	}
+}
+
+// DockerConfigCredentials reads registry credentials from a docker
+// config.json style file.
+func DockerConfigCredentials(path string) (func(string) *Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+
+	creds := make(map[string]*Credentials, len(config.Auths))
+	for host, a := range config.Auths {
+		c := &Credentials{Username: a.Username, Password: a.Password}
+		if a.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for registry %q in %q: %w", host, path, err)
+			}
+			c.Username, c.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		creds[normalizeRegistryHost(host)] = c
+	}
+	return func(registry string) *Credentials {
+		return creds[normalizeRegistryHost(registry)]
+	}, nil
+}
+
+// normalizeRegistryHost strips the scheme and path from a registry host
+// and maps the Docker Hub aliases to the registry host name.
+func normalizeRegistryHost(host string) string {
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case dockerHub, "index.docker.io":
+		return dockerHubRegistry
+	}
+	return host
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) url(registry, format string, a ...any) string {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	return scheme + "://" + registry + fmt.Sprintf(format, a...)
+}
+
+// do sends a request to the registry, authenticating and retrying once if
+// the registry requires it.
+func (c *Client) do(ctx context.Context, ref Reference, method, u string, header http.Header, body []byte) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u, r)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if body != nil {
+			req.ContentLength = int64(len(body))
+		}
+		if token, ok := c.tokens[ref.Registry+"/"+ref.Repository]; ok {
+			req.Header.Set("Authorization", token)
+		}
+		return c.httpClient().Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	drainAndClose(resp)
+
+	if err := c.authenticate(ctx, ref, challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authenticate handles a WWW-Authenticate challenge from the registry.
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string) error {
+	var creds *Credentials
+	if c.Credentials != nil {
+		creds = c.Credentials(ref.Registry)
+	}
+
+	scheme, params := parseChallenge(challenge)
+	var token string
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if creds == nil {
+			return fmt.Errorf("registry %q requires authentication", ref.Registry)
+		}
+		token = "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password))
+	case "bearer":
+		t, err := c.fetchToken(ctx, ref, params, creds)
+		if err != nil {
+			return err
+		}
+		token = "Bearer " + t
+	default:
+		return fmt.Errorf("unsupported authentication challenge from registry %q: %q", ref.Registry, challenge)
+	}
+
+	if c.tokens == nil {
+		c.tokens = make(map[string]string)
+	}
+	c.tokens[ref.Registry+"/"+ref.Repository] = token
+	return nil
+}
+
+// fetchToken gets a bearer token from the token service of a registry.
+func (c *Client) fetchToken(ctx context.Context, ref Reference, params map[string]string, creds *Credentials) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("missing realm in authentication challenge from registry %q", ref.Registry)
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q in authentication challenge: %w", realm, err)
+	}
+	q := u.Query()
+	if s := params["service"]; s != "" {
+		q.Set("service", s)
+	}
+	q.Set("scope", "repository:"+ref.Repository+":pull,push")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token for registry %q: %s", ref.Registry, resp.Status)
+	}
+
+	t := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&t); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if t.Token != "" {
+		return t.Token, nil
+	}
+	if t.AccessToken != "" {
+		return t.AccessToken, nil
+	}
+	return "", fmt.Errorf("empty token from registry %q", ref.Registry)
+}
+
+// parseChallenge parses a WWW-Authenticate header value of the form
+// `Scheme key="value",key2="value2"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for _, p := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(k)] = strings.Trim(v, `"`)
+	}
+	return scheme, params
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBlobSize))
+	resp.Body.Close()
+}
+
+func statusError(resp *http.Response, what string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	return fmt.Errorf("%s: unexpected response %s", what, resp.Status)
+}
+
+// Resolve returns the descriptor of the manifest an image reference points
+// to.
+func (c *Client) Resolve(ctx context.Context, ref Reference) (Descriptor, error) {
+	header := http.Header{"Accept": {strings.Join(manifestMediaTypes, ", ")}}
+	u := c.url(ref.Registry, "/v2/%s/manifests/%s", ref.Repository, ref.reference())
+	resp, err := c.do(ctx, ref, http.MethodHead, u, header, nil)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return Descriptor{}, statusError(resp, fmt.Sprintf("failed to resolve %s", ref))
+	}
+
+	d := Descriptor{
+		MediaType: resp.Header.Get("Content-Type"),
+		Digest:    resp.Header.Get("Docker-Content-Digest"),
+		Size:      resp.ContentLength,
+	}
+	if d.Digest == "" || d.Size < 0 {
+		// The registry did not tell us, fetch the manifest to find out
+		data, mediaType, err := c.fetchManifest(ctx, ref, ref.reference())
+		if err != nil {
+			return Descriptor{}, err
+		}
+		d.MediaType, d.Digest, d.Size = mediaType, digestOf(data), int64(len(data))
+	}
+	if ref.Digest != "" && d.Digest != ref.Digest {
+		return Descriptor{}, fmt.Errorf("digest mismatch for %s: registry returned %s", ref, d.Digest)
+	}
+	return d, nil
+}
+
+func (c *Client) fetchManifest(ctx context.Context, ref Reference, reference string) ([]byte, string, error) {
+	header := http.Header{"Accept": {strings.Join(manifestMediaTypes, ", ")}}
+	u := c.url(ref.Registry, "/v2/%s/manifests/%s", ref.Repository, reference)
+	resp, err := c.do(ctx, ref, http.MethodGet, u, header, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", statusError(resp, fmt.Sprintf("failed to fetch manifest %s", reference))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobSize))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// FetchBlob downloads a blob from the repository and verifies its digest.
+func (c *Client) FetchBlob(ctx context.Context, ref Reference, desc Descriptor) ([]byte, error) {
+	if desc.Size > maxBlobSize {
+		return nil, fmt.Errorf("blob %s too large (%d bytes)", desc.Digest, desc.Size)
+	}
+	u := c.url(ref.Registry, "/v2/%s/blobs/%s", ref.Repository, desc.Digest)
+	resp, err := c.do(ctx, ref, http.MethodGet, u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, fmt.Sprintf("failed to fetch blob %s", desc.Digest))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobSize))
+	if err != nil {
+		return nil, err
+	}
+	if d := digestOf(data); d != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch for blob %s: got %s", desc.Digest, d)
+	}
+	return data, nil
+}
+
+// PushBlob uploads a blob to the repository, unless it already exists.
+func (c *Client) PushBlob(ctx context.Context, ref Reference, mediaType string, data []byte) (Descriptor, error) {
+	desc := Descriptor{MediaType: mediaType, Digest: digestOf(data), Size: int64(len(data))}
+
+	u := c.url(ref.Registry, "/v2/%s/blobs/%s", ref.Repository, desc.Digest)
+	resp, err := c.do(ctx, ref, http.MethodHead, u, nil, nil)
+	if err != nil {
+		return desc, err
+	}
+	drainAndClose(resp)
+	if resp.StatusCode == http.StatusOK {
+		return desc, nil
+	}
+
+	u = c.url(ref.Registry, "/v2/%s/blobs/uploads/", ref.Repository)
+	resp, err = c.do(ctx, ref, http.MethodPost, u, nil, nil)
+	if err != nil {
+		return desc, err
+	}
+	drainAndClose(resp)
+	if resp.StatusCode != http.StatusAccepted {
+		return desc, statusError(resp, "failed to start blob upload")
+	}
+
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return desc, fmt.Errorf("invalid upload location: %w", err)
+	}
+	q := loc.Query()
+	q.Set("digest", desc.Digest)
+	loc.RawQuery = q.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = c.do(ctx, ref, http.MethodPut, loc.String(), header, data)
+	if err != nil {
+		return desc, err
+	}
+	drainAndClose(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return desc, statusError(resp, fmt.Sprintf("failed to upload blob %s", desc.Digest))
+	}
+	return desc, nil
+}
+
+// PushManifest uploads a manifest to the repository, referenced by its
+// digest. If the manifest has a subject and the registry does not support
+// the referrers API the referrers tag index of the subject is updated.
+func (c *Client) PushManifest(ctx context.Context, ref Reference, manifest *Manifest) (Descriptor, error) {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	desc := Descriptor{
+		MediaType:    manifest.MediaType,
+		ArtifactType: manifest.ArtifactType,
+		Digest:       digestOf(data),
+		Size:         int64(len(data)),
+		Annotations:  manifest.Annotations,
+	}
+
+	resp, err := c.putManifest(ctx, ref, desc.Digest, manifest.MediaType, data)
+	if err != nil {
+		return desc, err
+	}
+	if manifest.Subject != nil && resp.Header.Get("OCI-Subject") == "" {
+		if err := c.addReferrerByTag(ctx, ref, *manifest.Subject, desc); err != nil {
+			return desc, err
+		}
+	}
+	return desc, nil
+}
+
+func (c *Client) putManifest(ctx context.Context, ref Reference, reference, mediaType string, data []byte) (*http.Response, error) {
+	header := http.Header{"Content-Type": {mediaType}}
+	u := c.url(ref.Registry, "/v2/%s/manifests/%s", ref.Repository, reference)
+	resp, err := c.do(ctx, ref, http.MethodPut, u, header, data)
+	if err != nil {
+		return nil, err
+	}
+	drainAndClose(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return nil, statusError(resp, fmt.Sprintf("failed to upload manifest %s", reference))
+	}
+	return resp, nil
+}
+
+// addReferrerByTag adds a descriptor to the referrers tag index of the
+// subject, as described in the fallback tag schema of the distribution spec.
+func (c *Client) addReferrerByTag(ctx context.Context, ref Reference, subject, desc Descriptor) error {
+	tag := referrersTag(subject)
+	idx := index{SchemaVersion: 2, MediaType: mediaTypeImageIndex}
+
+	data, _, err := c.fetchManifest(ctx, ref, tag)
+	if err == nil {
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("failed to parse referrers index: %w", err)
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	for _, d := range idx.Manifests {
+		if d.Digest == desc.Digest {
+			return nil
+		}
+	}
+	idx.Manifests = append(idx.Manifests, desc)
+
+	data, err = json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	_, err = c.putManifest(ctx, ref, tag, mediaTypeImageIndex, data)
+	return err
+}
+
+// FetchManifest downloads an image manifest by digest.
+func (c *Client) FetchManifest(ctx context.Context, ref Reference, desc Descriptor) (*Manifest, error) {
+	data, _, err := c.fetchManifest(ctx, ref, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	if d := digestOf(data); d != desc.Digest {
+		return nil, fmt.Errorf("digest mismatch for manifest %s: got %s", desc.Digest, d)
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", desc.Digest, err)
+	}
+	return m, nil
+}
+
+// Referrers lists the manifests of the given artifact type that refer to the
+// subject manifest.
+func (c *Client) Referrers(ctx context.Context, ref Reference, subject Descriptor, artifactType string) ([]Descriptor, error) {
+	u := c.url(ref.Registry, "/v2/%s/referrers/%s?artifactType=%s", ref.Repository, subject.Digest, url.QueryEscape(artifactType))
+	header := http.Header{"Accept": {mediaTypeImageIndex}}
+	resp, err := c.do(ctx, ref, http.MethodGet, u, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		// Registry does not support the referrers API, fall back to the
+		// referrers tag schema.
+		return c.referrersByTag(ctx, ref, subject, artifactType)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "failed to list referrers")
+	}
+
+	idx := index{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBlobSize)).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to parse referrers response: %w", err)
+	}
+	return filterArtifactType(idx.Manifests, artifactType), nil
+}
+
+// referrersByTag lists referrers using the fallback tag schema of the
+// distribution spec, i.e. an image index tagged <alg>-<hex>.
+func (c *Client) referrersByTag(ctx context.Context, ref Reference, subject Descriptor, artifactType string) ([]Descriptor, error) {
+	data, _, err := c.fetchManifest(ctx, ref, referrersTag(subject))
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	idx := index{}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse referrers index: %w", err)
+	}
+	return filterArtifactType(idx.Manifests, artifactType), nil
+}
+
+func referrersTag(subject Descriptor) string {
+	return strings.Replace(subject.Digest, ":", "-", 1)
+}
+
+func filterArtifactType(descs []Descriptor, artifactType string) []Descriptor {
+	ret := []Descriptor{}
+	for _, d := range descs {
+		if d.ArtifactType == artifactType {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+// matchNodesPath is the path of the node query API of nfd-master.
+const matchNodesPath = "/api/v1alpha1/matchnodes"
+
+// QueryAPIOptions holds the settings for accessing the node query API of
+// nfd-master (enabled with -enable-query-api).
+type QueryAPIOptions struct {
+	// URL is the base URL of the nfd-master metrics server, e.g.
+	// https://localhost:8081 when using kubectl port-forward.
+	URL string
+	// Token is the bearer token to authenticate with. Defaults to the token
+	// of the kubeconfig, if any.
+	Token string
+	// CAFile is the CA certificate for verifying the server certificate.
+	CAFile string
+	// InsecureSkipTLSVerify disables verifying the server certificate.
+	InsecureSkipTLSVerify bool
+}
+
+// LabelChange is a change of one node label caused by a NodeFeatureRule.
+type LabelChange struct {
+	Node  string
+	Label string
+	// OldValue is the current value of the label, nil if the label does
+	// not exist.
+	OldValue *string
+	// NewValue is the value of the label after the change, nil if the
+	// label would be removed.
+	NewValue *string
+}
+
+// String returns the change in diff format, i.e. "+key=value" for added,
+// "-key=value" for removed and "~key=old->new" for changed labels.
+func (c LabelChange) String() string {
+	switch {
+	case c.OldValue == nil:
+		return fmt.Sprintf("+%s=%s", c.Label, *c.NewValue)
+	case c.NewValue == nil:
+		return fmt.Sprintf("-%s=%s", c.Label, *c.OldValue)
+	default:
+		return fmt.Sprintf("~%s=%s->%s", c.Label, *c.OldValue, *c.NewValue)
+	}
+}
+
+// nodeLabels is the set of labels per node.
+type nodeLabels map[string]map[string]string
+
+// Diff computes the node labels that would be added, changed or removed if
+// the NodeFeatureRule in the given file was applied in the cluster. The
+// nodes matching the rules of the proposed and of the currently applied
+// NodeFeatureRule object (with the same name) are queried from the node
+// query API of nfd-master and compared to the current labels of the nodes.
+// Labels that can not be resolved without evaluating the rules against the
+// node features (labelsTemplate and "@" values) are not included and a
+// warning is returned for them.
+func Diff(nodefeaturerulepath, kubeconfig string, opts QueryAPIOptions) ([]LabelChange, []error) {
+	nfrFile, err := os.ReadFile(nodefeaturerulepath)
+	if err != nil {
+		return nil, []error{fmt.Errorf("error reading NodeFeatureRule file: %w", err)}
+	}
+	proposed := nfdv1alpha1.NodeFeatureRule{}
+	if err := yaml.Unmarshal(nfrFile, &proposed); err != nil {
+		return nil, []error{fmt.Errorf("error parsing NodeFeatureRule: %w", err)}
+	}
+	if proposed.Name == "" {
+		return nil, []error{fmt.Errorf("NodeFeatureRule %q has no name", nodefeaturerulepath)}
+	}
+
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, []error{fmt.Errorf("error building kubeconfig: %w", err)}
+	}
+	if opts.Token == "" {
+		opts.Token = config.BearerToken
+	}
+	cli, err := k8sclient.NewForConfig(config)
+	if err != nil {
+		return nil, []error{err}
+	}
+	nfdCli, err := nfdclientset.NewForConfig(config)
+	if err != nil {
+		return nil, []error{err}
+	}
+	httpCli, err := newQueryAPIClient(opts)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	ctx := context.Background()
+	nodes, err := cli.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to list nodes: %w", err)}
+	}
+
+	current, err := nfdCli.NfdV1alpha1().NodeFeatureRules().Get(ctx, proposed.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		return nil, []error{fmt.Errorf("failed to get NodeFeatureRule %q: %w", proposed.Name, err)}
+	}
+
+	newLabels, errs := ruleLabels(httpCli, opts, &proposed, nodes.Items)
+	oldLabels := nodeLabels{}
+	if current != nil {
+		var e []error
+		oldLabels, e = ruleLabels(httpCli, opts, current, nodes.Items)
+		errs = append(errs, e...)
+	}
+
+	return diffNodeLabels(nodes.Items, oldLabels, newLabels), errs
+}
+
+// ruleLabels returns the labels that the rules of a NodeFeatureRule would
+// create on each node.
+func ruleLabels(cli *http.Client, opts QueryAPIOptions, nfr *nfdv1alpha1.NodeFeatureRule, nodes []corev1.Node) (nodeLabels, []error) {
+	var errs []error
+
+	nodeSelector := k8sLabels.Everything()
+	if nfr.Spec.NodeSelector != nil {
+		var err error
+		if nodeSelector, err = metav1.LabelSelectorAsSelector(nfr.Spec.NodeSelector); err != nil {
+			return nil, []error{fmt.Errorf("invalid nodeSelector of NodeFeatureRule %q: %w", nfr.Name, err)}
+		}
+	}
+	labelNs := nfr.Spec.LabelNamespace
+	if labelNs == "" {
+		labelNs = nfdv1alpha1.FeatureLabelNs
+	}
+
+	out := nodeLabels{}
+	for i := range nfr.Spec.Rules {
+		rule := &nfr.Spec.Rules[i]
+		if rule.LabelsTemplate != "" {
+			errs = append(errs, fmt.Errorf("warning: labelsTemplate of rule %q in NodeFeatureRule %q is not included in the diff", rule.Name, nfr.Name))
+		}
+		labels := map[string]string{}
+		for k, v := range rule.Labels {
+			if strings.HasPrefix(v, "@") {
+				errs = append(errs, fmt.Errorf("warning: dynamic value of label %q in rule %q of NodeFeatureRule %q is not included in the diff", k, rule.Name, nfr.Name))
+				continue
+			}
+			if !strings.Contains(k, "/") {
+				k = labelNs + "/" + k
+			}
+			labels[k] = v
+		}
+		if len(labels) == 0 {
+			continue
+		}
+
+		var matched []string
+		if len(rule.MatchFeatures) == 0 && len(rule.MatchAny) == 0 {
+			// Rules without match terms match all nodes
+			for _, n := range nodes {
+				if nodeSelector.Matches(k8sLabels.Set(n.Labels)) {
+					matched = append(matched, n.Name)
+				}
+			}
+		} else {
+			resp, err := queryMatchingNodes(cli, opts, rule, nfr.Spec.NodeSelector)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to query nodes matching rule %q of NodeFeatureRule %q: %w", rule.Name, nfr.Name, err))
+				continue
+			}
+			for node, msg := range resp.Errors {
+				errs = append(errs, fmt.Errorf("failed to evaluate rule %q of NodeFeatureRule %q on node %q: %s", rule.Name, nfr.Name, node, msg))
+			}
+			matched = resp.Nodes
+		}
+
+		for _, node := range matched {
+			if out[node] == nil {
+				out[node] = map[string]string{}
+			}
+			for k, v := range labels {
+				out[node][k] = v
+			}
+		}
+	}
+	return out, errs
+}
+
+// matchNodesRequest is the request of the node query API.
+type matchNodesRequest struct {
+	nfdv1alpha1.Rule `json:",inline"`
+	NodeSelector     *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+}
+
+// matchNodesResponse is the response of the node query API.
+type matchNodesResponse struct {
+	Nodes  []string          `json:"nodes"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// queryMatchingNodes gets the nodes matching a rule from the node query API.
+func queryMatchingNodes(cli *http.Client, opts QueryAPIOptions, rule *nfdv1alpha1.Rule, nodeSelector *metav1.LabelSelector) (*matchNodesResponse, error) {
+	// Only the match terms are of interest to the query API. Rules that
+	// depend on the output of previous rules (rule.matched) are evaluated
+	// in isolation.
+	body, err := json.Marshal(matchNodesRequest{
+		Rule: nfdv1alpha1.Rule{
+			Name:          rule.Name,
+			MatchFeatures: rule.MatchFeatures,
+			MatchAny:      rule.MatchAny,
+		},
+		NodeSelector: nodeSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(opts.URL, "/")+matchNodesPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query API returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	out := &matchNodesResponse{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("failed to parse query API response: %w", err)
+	}
+	return out, nil
+}
+
+// newQueryAPIClient creates an http client for accessing the node query API.
+func newQueryAPIClient(opts QueryAPIOptions) (*http.Client, error) {
+	if opts.URL == "" {
+		return nil, fmt.Errorf("URL of the nfd-master query API must be specified")
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipTLSVerify,
+	}
+	if opts.CAFile != "" {
+		caCert, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		if ok := caPool.AppendCertsFromPEM(caCert); !ok {
+			return nil, fmt.Errorf("failed to add certificate from '%s'", opts.CAFile)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// diffNodeLabels compares the current labels of the nodes to the labels
+// created by the currently applied (oldLabels) and the proposed (newLabels)
+// rules. Labels of the current rules that the proposed rules would not
+// create anymore are reported as removed.
+func diffNodeLabels(nodes []corev1.Node, oldLabels, newLabels nodeLabels) []LabelChange {
+	changes := []LabelChange{}
+	for _, node := range nodes {
+		for k, v := range newLabels[node.Name] {
+			newValue := v
+			if cur, ok := node.Labels[k]; !ok {
+				changes = append(changes, LabelChange{Node: node.Name, Label: k, NewValue: &newValue})
+			} else if cur != v {
+				changes = append(changes, LabelChange{Node: node.Name, Label: k, OldValue: &cur, NewValue: &newValue})
+			}
+		}
+		for k := range oldLabels[node.Name] {
+			if _, ok := newLabels[node.Name][k]; ok {
+				continue
+			}
+			if cur, ok := node.Labels[k]; ok {
+				changes = append(changes, LabelChange{Node: node.Name, Label: k, OldValue: &cur})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Node != changes[j].Node {
+			return changes[i].Node < changes[j].Node
+		}
+		return changes[i].Label < changes[j].Label
+	})
+	return changes
+}
@@ -70,9 +70,9 @@ func processNodeFeatureRule(nodeFeatureRule nfdv1alpha1.NodeFeatureRule, nodeFea
 
 	for _, rule := range nodeFeatureRule.Spec.Rules {
 		fmt.Println("Processing rule: ", rule.Name)
-		ruleOut, err := nodefeaturerule.Execute(&rule, &nodeFeature.Features)
+		ruleOut, err := nodefeaturerule.Evaluate(&nodeFeature.Features, &rule)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("failed to process rule: %q - %w", rule.Name, err))
+			errs = append(errs, fmt.Errorf("failed to process rule: %w", err))
 			continue
 		}
 		// taints
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubectlnfd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/imagecompat"
+)
+
+// RegistryOptions specify how to access the container image registry.
+type RegistryOptions struct {
+	// AuthFile is a docker config.json style file with registry credentials
+	AuthFile string
+	// Username and Password used for all registries, override AuthFile
+	Username string
+	Password string
+	// PlainHTTP connects to the registry over http instead of https
+	PlainHTTP bool
+}
+
+func newRegistryClient(opts RegistryOptions) (*imagecompat.Client, error) {
+	c := &imagecompat.Client{PlainHTTP: opts.PlainHTTP}
+	switch {
+	case opts.Username != "":
+		c.Credentials = imagecompat.StaticCredentials(opts.Username, opts.Password)
+	case opts.AuthFile != "":
+		creds, err := imagecompat.DockerConfigCredentials(opts.AuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading registry auth file: %w", err)
+		}
+		c.Credentials = creds
+	}
+	return c, nil
+}
+
+func readImageCompatSpec(path string) (*compatv1alpha1.Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading image compatibility spec file: %w", err)
+	}
+	spec := &compatv1alpha1.Spec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("error parsing image compatibility spec: %w", err)
+	}
+	if err := imagecompat.ValidateSpec(spec); err != nil {
+		return nil, fmt.Errorf("invalid image compatibility spec: %w", err)
+	}
+	return spec, nil
+}
+
+// AttachImageCompat attaches the image compatibility spec read from a file to
+// an image in the registry, as an OCI artifact.
+func AttachImageCompat(image, specPath string, opts RegistryOptions) error {
+	spec, err := readImageCompatSpec(specPath)
+	if err != nil {
+		return err
+	}
+	c, err := newRegistryClient(opts)
+	if err != nil {
+		return err
+	}
+	desc, err := imagecompat.Attach(context.Background(), c, image, spec)
+	if err != nil {
+		return fmt.Errorf("failed to attach image compatibility spec to %s: %w", image, err)
+	}
+	fmt.Printf("Attached image compatibility artifact %s to %s\n", desc.Digest, image)
+	return nil
+}
+
+// ValidateNodeImageCompat evaluates an image compatibility spec against a
+// NodeFeature file. The spec is read from specPath if specified, otherwise it
+// is fetched from the registry for the given image.
+func ValidateNodeImageCompat(image, specPath, nodefeaturepath string, opts RegistryOptions) ([]imagecompat.Result, error) {
+	var spec *compatv1alpha1.Spec
+	var err error
+	if specPath != "" {
+		spec, err = readImageCompatSpec(specPath)
+	} else {
+		var c *imagecompat.Client
+		if c, err = newRegistryClient(opts); err == nil {
+			spec, err = imagecompat.Fetch(context.Background(), c, image)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nfFile, err := os.ReadFile(nodefeaturepath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading NodeFeature file: %w", err)
+	}
+	nf := nfdv1alpha1.NodeFeature{}
+	if err := yaml.Unmarshal(nfFile, &nf); err != nil {
+		return nil, fmt.Errorf("error parsing NodeFeature: %w", err)
+	}
+
+	return imagecompat.Evaluate(spec, &nf.Spec.Features)
+}
@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
 	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/validate"
 )
 
@@ -44,6 +45,13 @@ func ValidateNFR(filepath string) []error {
 		return []error{fmt.Errorf("error reading NodeFeatureRule file: %w", err)}
 	}
 
+	labelNs := nfr.Spec.LabelNamespace
+	if labelNs != "" {
+		if err := validate.LabelNamespace(labelNs); err != nil {
+			validationErr = append(validationErr, fmt.Errorf("invalid labelNamespace %q: %w", labelNs, err))
+		}
+	}
+
 	for _, rule := range nfr.Spec.Rules {
 		fmt.Println("Validating rule: ", rule.Name)
 		// Validate Rule Name
@@ -56,11 +64,15 @@ func ValidateNFR(filepath string) []error {
 
 		// Validate labels
 		// Dummy dynamic values before validating labels
-		labels := rule.Labels
-		for k, v := range labels {
+		labels := make(map[string]string, len(rule.Labels))
+		for k, v := range rule.Labels {
+			if labelNs != "" && !strings.Contains(k, "/") {
+				k = labelNs + "/" + k
+			}
 			if strings.HasPrefix(v, "@") {
-				labels[k] = resource.NewQuantity(0, resource.DecimalSI).String()
+				v = resource.NewQuantity(0, resource.DecimalSI).String()
 			}
+			labels[k] = v
 		}
 		validationErr = append(validationErr, validate.Labels(labels)...)
 
@@ -71,7 +83,10 @@ func ValidateNFR(filepath string) []error {
 		// Dummy dynamic values before validating extended resources
 		extendedResources := rule.ExtendedResources
 		for k, v := range extendedResources {
-			if strings.HasPrefix(v, "@") {
+			if nodefeaturerule.IsTemplate(v) {
+				validationErr = append(validationErr, validate.Template(v)...)
+				extendedResources[k] = resource.NewQuantity(0, resource.DecimalSI).String()
+			} else if strings.HasPrefix(v, "@") {
 				extendedResources[k] = resource.NewQuantity(0, resource.DecimalSI).String()
 			}
 		}
@@ -83,6 +98,13 @@ func ValidateNFR(filepath string) []error {
 		// Validate VarsTemplate
 		validationErr = append(validationErr, validate.Template(rule.VarsTemplate)...)
 
+		// Validate minFeatureVersion
+		if rule.MinFeatureVersion != "" {
+			if err := validate.FeatureVersion(rule.MinFeatureVersion); err != nil {
+				validationErr = append(validationErr, err)
+			}
+		}
+
 		// Validate matchFeatures
 		validationErr = append(validationErr, validate.MatchFeatures(rule.MatchFeatures)...)
 
@@ -90,5 +112,8 @@ func ValidateNFR(filepath string) []error {
 		validationErr = append(validationErr, validate.MatchAny(rule.MatchAny)...)
 	}
 
+	// Run the tests of the rules
+	validationErr = append(validationErr, nodefeaturerule.RunTests(&nfr.Spec)...)
+
 	return validationErr
 }
@@ -26,6 +26,10 @@ const (
 	buildInfoQuery          = "nfd_gc_build_info"
 	objectsDeletedQuery     = "nfd_gc_objects_deleted_total"
 	objectDeleteErrorsQuery = "nfd_gc_object_delete_failures_total"
+	deletedObjectAgeQuery   = "nfd_gc_deleted_object_age_seconds"
+	orphanedObjectsQuery    = "nfd_gc_orphaned_objects"
+	nrtAPIAvailableQuery    = "nfd_gc_nrt_api_available"
+	leaderStatusQuery       = "nfd_gc_leader"
 )
 
 var (
@@ -46,6 +50,26 @@ var (
 		Help: "Number of errors in deleting NodeFeature and NodeResourceTopology objects."},
 		[]string{"kind"},
 	)
+	deletedObjectAge = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    deletedObjectAgeQuery,
+		Help:    "Age of garbage collected NodeFeature and NodeResourceTopology objects.",
+		Buckets: prometheus.ExponentialBuckets(60, 4, 8),
+	},
+		[]string{"kind"},
+	)
+	orphanedObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: orphanedObjectsQuery,
+		Help: "Number of objects without a node that are younger than the minimum age for garbage collection."},
+		[]string{"kind"},
+	)
+	nrtAPIAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: nrtAPIAvailableQuery,
+		Help: "Whether the NodeResourceTopology API is available (1) or not (0).",
+	})
+	leaderStatus = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: leaderStatusQuery,
+		Help: "Whether this nfd-gc instance is the leader (1) or not (0), when leader election is enabled.",
+	})
 )
 
 // registerVersion exposes the Operator build version.
@@ -18,30 +18,76 @@ package nfdgarbagecollector
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
 	"github.com/openshift/node-feature-discovery/pkg/version"
 )
 
+const (
+	// nodeDeletedReason is the reason of events of objects deleted because
+	// their node was deleted.
+	nodeDeletedReason = "NodeDeleted"
+	// orphanedReason is the reason of events of objects deleted in periodic
+	// garbage collection because their node does not exist.
+	orphanedReason = "Orphaned"
+	// nrtResource is the resource name of NodeResourceTopology objects.
+	nrtResource = "noderesourcetopologies"
+
+	nodeFeatureKind = "NodeFeature"
+	nrtKind         = "NodeResourceTopology"
+
+	// gcWorkers is the number of workers deleting objects.
+	gcWorkers = 4
+	// maxDeleteRetries is the number of times the deletion of an object is
+	// retried before giving up. The object is picked up again by the next
+	// periodic garbage collection.
+	maxDeleteRetries = 5
+	// leaseName is the name of the Lease object used for leader election.
+	leaseName = "nfd-gc.nfd.kubernetes.io"
+)
+
 // Args are the command line arguments
 type Args struct {
-	GCPeriod    time.Duration
-	Kubeconfig  string
-	MetricsPort int
+	GCPeriod        time.Duration
+	Kubeconfig      string
+	MetricsPort     int
+	MetricsSecurity utils.MetricsSecurityArgs
+	// NRTMinAge is the minimum age of NodeResourceTopology objects without
+	// a node to garbage collect them in periodic garbage collection.
+	NRTMinAge time.Duration
+	// DeleteQPS and DeleteBurst limit the rate of object deletions. Zero
+	// DeleteQPS means no limit.
+	DeleteQPS   float64
+	DeleteBurst int
+	// EnableLeaderElection makes only one replica of nfd-gc active at a time.
+	EnableLeaderElection bool
 }
 
 type NfdGarbageCollector interface {
@@ -52,9 +98,31 @@ type NfdGarbageCollector interface {
 type nfdGarbageCollector struct {
 	args       *Args
 	stopChan   chan struct{}
+	k8sClient  kubernetes.Interface
 	nfdClient  nfdclientset.Interface
 	topoClient topologyclientset.Interface
 	factory    informers.SharedInformerFactory
+	// queue holds the objects to delete
+	queue workqueue.RateLimitingInterface
+
+	eventBroadcaster record.EventBroadcaster
+	recorder         record.EventRecorder
+	// nrtAvailable is true if the NodeResourceTopology API is served, i.e.
+	// the CRD is installed
+	nrtAvailable atomic.Bool
+}
+
+// gcItem is an object queued for deletion.
+type gcItem struct {
+	kind      string
+	namespace string
+	name      string
+	uid       types.UID
+	// created is the creation time of the object in Unix seconds, zero if
+	// not known
+	created  int64
+	nodeName string
+	reason   string
 }
 
 func New(args *Args) (NfdGarbageCollector, error) {
@@ -65,47 +133,174 @@ func New(args *Args) (NfdGarbageCollector, error) {
 
 	clientset := kubernetes.NewForConfigOrDie(kubeconfig)
 
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+
 	return &nfdGarbageCollector{
-		args:       args,
-		stopChan:   make(chan struct{}),
-		topoClient: topologyclientset.NewForConfigOrDie(kubeconfig),
-		nfdClient:  nfdclientset.NewForConfigOrDie(kubeconfig),
-		factory:    informers.NewSharedInformerFactory(clientset, 5*time.Minute),
+		args:             args,
+		stopChan:         make(chan struct{}),
+		k8sClient:        clientset,
+		topoClient:       topologyclientset.NewForConfigOrDie(kubeconfig),
+		nfdClient:        nfdclientset.NewForConfigOrDie(kubeconfig),
+		factory:          informers.NewSharedInformerFactory(clientset, 5*time.Minute),
+		eventBroadcaster: eventBroadcaster,
+		recorder:         eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "nfd-gc"}),
 	}, nil
 }
 
-func (n *nfdGarbageCollector) deleteNodeFeature(namespace, name string) {
-	kind := "NodeFeature"
-	if err := n.nfdClient.NfdV1alpha1().NodeFeatures(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
-		if errors.IsNotFound(err) {
-			klog.V(2).InfoS("NodeFeature not found, omitting deletion", "nodefeature", klog.KRef(namespace, name))
-			return
-		} else {
-			klog.ErrorS(err, "failed to delete NodeFeature object", "nodefeature", klog.KRef(namespace, name))
-			objectDeleteErrors.WithLabelValues(kind).Inc()
-			return
+// recordDeletion records an event about an object deleted because its node
+// does not exist.
+func (n *nfdGarbageCollector) recordDeletion(item gcItem) {
+	if n.recorder == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		Kind:      item.kind,
+		Namespace: item.namespace,
+		Name:      item.name,
+		UID:       item.uid,
+	}
+	switch item.kind {
+	case nodeFeatureKind:
+		ref.APIVersion = nfdv1alpha1.SchemeGroupVersion.String()
+	case nrtKind:
+		ref.APIVersion = v1alpha2.SchemeGroupVersion.String()
+	}
+	n.recorder.Eventf(ref, corev1.EventTypeNormal, item.reason, "%s garbage collected, node %q does not exist", item.kind, item.nodeName)
+}
+
+// observeDeletion updates the metrics of a deleted object.
+func observeDeletion(item gcItem) {
+	objectsDeleted.WithLabelValues(item.kind).Inc()
+	if item.created != 0 {
+		deletedObjectAge.WithLabelValues(item.kind).Observe(time.Since(time.Unix(item.created, 0)).Seconds())
+	}
+}
+
+// enqueue queues an object for deletion. Deletions are rate limited.
+func (n *nfdGarbageCollector) enqueue(kind string, obj metav1.Object, nodeName, reason string) {
+	item := gcItem{
+		kind:      kind,
+		namespace: obj.GetNamespace(),
+		name:      obj.GetName(),
+		uid:       obj.GetUID(),
+		nodeName:  nodeName,
+		reason:    reason,
+	}
+	if created := obj.GetCreationTimestamp(); !created.IsZero() {
+		item.created = created.Unix()
+	}
+	n.queue.AddRateLimited(item)
+}
+
+// deleteObject deletes a queued object. Objects that do not exist anymore are
+// not an error.
+func (n *nfdGarbageCollector) deleteObject(item gcItem) error {
+	var err error
+	switch item.kind {
+	case nodeFeatureKind:
+		err = n.nfdClient.NfdV1alpha1().NodeFeatures(item.namespace).Delete(context.TODO(), item.name, metav1.DeleteOptions{})
+	case nrtKind:
+		err = n.topoClient.TopologyV1alpha2().NodeResourceTopologies().Delete(context.TODO(), item.name, metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("unknown kind %q", item.kind)
+	}
+	if errors.IsNotFound(err) {
+		klog.V(2).InfoS("object not found, omitting deletion", "kind", item.kind, "object", klog.KRef(item.namespace, item.name))
+		return nil
+	} else if err != nil {
+		objectDeleteErrors.WithLabelValues(item.kind).Inc()
+		return err
+	}
+	klog.InfoS("object has been deleted", "kind", item.kind, "object", klog.KRef(item.namespace, item.name), "nodeName", item.nodeName, "reason", item.reason)
+	observeDeletion(item)
+	n.recordDeletion(item)
+	return nil
+}
+
+// processNextItem deletes the next queued object, retrying failed deletions
+// with exponential backoff. Returns false when the queue has been shut down.
+func (n *nfdGarbageCollector) processNextItem() bool {
+	obj, quit := n.queue.Get()
+	if quit {
+		return false
+	}
+	defer n.queue.Done(obj)
+
+	item := obj.(gcItem)
+	if err := n.deleteObject(item); err != nil {
+		if n.queue.NumRequeues(item) <= maxDeleteRetries {
+			klog.V(2).InfoS("failed to delete object, retrying", "kind", item.kind, "object", klog.KRef(item.namespace, item.name), "error", err)
+			n.queue.AddRateLimited(item)
+			return true
 		}
+		klog.ErrorS(err, "failed to delete object, giving up", "kind", item.kind, "object", klog.KRef(item.namespace, item.name))
 	}
-	klog.InfoS("NodeFeature object has been deleted", "nodefeature", klog.KRef(namespace, name))
-	objectsDeleted.WithLabelValues(kind).Inc()
+	n.queue.Forget(item)
+	return true
 }
 
-func (n *nfdGarbageCollector) deleteNRT(nodeName string) {
-	kind := "NodeResourceTopology"
-	if err := n.topoClient.TopologyV1alpha2().NodeResourceTopologies().Delete(context.TODO(), nodeName, metav1.DeleteOptions{}); err != nil {
-		if errors.IsNotFound(err) {
-			klog.V(2).InfoS("NodeResourceTopology not found, omitting deletion", "nodeName", nodeName)
-			return
+// startWorkers creates the deletion queue and starts the workers processing
+// it.
+func (n *nfdGarbageCollector) startWorkers() {
+	limit := rate.Inf
+	if n.args.DeleteQPS > 0 {
+		limit = rate.Limit(n.args.DeleteQPS)
+	}
+	// Mimic workqueue.DefaultControllerRateLimiter() but with configurable
+	// overall rate limiting
+	rl := workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(100*time.Millisecond, 5*time.Minute),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(limit, max(n.args.DeleteBurst, 1))},
+	)
+	n.queue = workqueue.NewRateLimitingQueueWithConfig(rl, workqueue.RateLimitingQueueConfig{Name: "nfd_gc"})
+
+	for i := 0; i < gcWorkers; i++ {
+		go func() {
+			for n.processNextItem() {
+			}
+		}()
+	}
+}
+
+// checkNRTAPI checks if the NodeResourceTopology API is available. The CRD
+// may be removed and reinstalled at any time, NodeResourceTopology objects
+// are only garbage collected while it is installed. The previous state is
+// returned if the check fails.
+func (n *nfdGarbageCollector) checkNRTAPI() bool {
+	available := false
+	resources, err := n.topoClient.Discovery().ServerResourcesForGroupVersion(v1alpha2.SchemeGroupVersion.String())
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		klog.ErrorS(err, "failed to check availability of the NodeResourceTopology API")
+		return n.nrtAvailable.Load()
+	default:
+		for _, r := range resources.APIResources {
+			if r.Name == nrtResource {
+				available = true
+				break
+			}
+		}
+	}
+
+	if old := n.nrtAvailable.Swap(available); old != available {
+		if available {
+			klog.InfoS("NodeResourceTopology API available, garbage collecting NodeResourceTopology objects")
 		} else {
-			klog.ErrorS(err, "failed to delete NodeResourceTopology object", "nodeName", nodeName)
-			objectDeleteErrors.WithLabelValues(kind).Inc()
-			return
+			klog.InfoS("NodeResourceTopology API not available, not garbage collecting NodeResourceTopology objects")
 		}
 	}
-	klog.InfoS("NodeResourceTopology object has been deleted", "nodeName", nodeName)
-	objectsDeleted.WithLabelValues(kind).Inc()
+	if available {
+		nrtAPIAvailable.Set(1)
+	} else {
+		nrtAPIAvailable.Set(0)
+	}
+	return available
 }
 
+// deleteNodeHandler queues the NodeFeature and NodeResourceTopology objects
+// of a deleted node for deletion.
 func (n *nfdGarbageCollector) deleteNodeHandler(object interface{}) {
 	// handle a case when we are starting up and need to clear stale NRT resources
 	obj := object
@@ -120,20 +315,22 @@ func (n *nfdGarbageCollector) deleteNodeHandler(object interface{}) {
 		return
 	}
 
-	n.deleteNRT(node.GetName())
+	if n.nrtAvailable.Load() {
+		n.enqueue(nrtKind, &metav1.ObjectMeta{Name: node.GetName()}, node.GetName(), nodeDeletedReason)
+	}
 
 	// Delete all NodeFeature objects (from all namespaces) targeting the deleted node
 	nfListOptions := metav1.ListOptions{LabelSelector: nfdv1alpha1.NodeFeatureObjNodeNameLabel + "=" + node.GetName()}
 	if nfs, err := n.nfdClient.NfdV1alpha1().NodeFeatures("").List(context.TODO(), nfListOptions); err != nil {
 		klog.ErrorS(err, "failed to list NodeFeature objects")
 	} else {
-		for _, nf := range nfs.Items {
-			n.deleteNodeFeature(nf.Namespace, nf.Name)
+		for i := range nfs.Items {
+			n.enqueue(nodeFeatureKind, &nfs.Items[i], node.GetName(), nodeDeletedReason)
 		}
 	}
 }
 
-// garbageCollect removes all stale API objects
+// garbageCollect queues all stale API objects for deletion
 func (n *nfdGarbageCollector) garbageCollect() {
 	klog.InfoS("performing garbage collection")
 	nodes, err := n.factory.Core().V1().Nodes().Lister().List(labels.Everything())
@@ -153,29 +350,51 @@ func (n *nfdGarbageCollector) garbageCollect() {
 	} else if err != nil {
 		klog.ErrorS(err, "failed to list NodeFeature objects")
 	} else {
-		for _, nf := range nfs.Items {
+		for i := range nfs.Items {
+			nf := &nfs.Items[i]
+			// Objects mirrored from other clusters are managed by the
+			// nfd-master instances of those clusters
+			if _, ok := nf.GetLabels()[nfdv1alpha1.HubClusterNameLabel]; ok {
+				continue
+			}
 			nodeName, ok := nf.GetLabels()[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
 			if !ok {
-				klog.InfoS("node name label missing from NodeFeature object", "nodefeature", klog.KObj(&nf))
+				klog.InfoS("node name label missing from NodeFeature object", "nodefeature", klog.KObj(nf))
 			}
 			if !nodeNames.Has(nodeName) {
-				n.deleteNodeFeature(nf.Namespace, nf.Name)
+				n.enqueue(nodeFeatureKind, nf, nodeName, orphanedReason)
 			}
 		}
 	}
 
 	// Handle NodeResourceTopology objects
+	if !n.checkNRTAPI() {
+		klog.V(2).InfoS("NodeResourceTopology CRD does not exist")
+		orphanedObjects.WithLabelValues(nrtKind).Set(0)
+		return
+	}
 	nrts, err := n.topoClient.TopologyV1alpha2().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{})
 	if errors.IsNotFound(err) {
 		klog.V(2).InfoS("NodeResourceTopology CRD does not exist")
 	} else if err != nil {
 		klog.ErrorS(err, "failed to list NodeResourceTopology objects")
 	} else {
-		for _, nrt := range nrts.Items {
-			if !nodeNames.Has(nrt.Name) {
-				n.deleteNRT(nrt.Name)
+		// Objects younger than the minimum age are left alone, e.g. the
+		// node may not have registered yet after re-installation of the CRD
+		pending := 0
+		for i := range nrts.Items {
+			nrt := &nrts.Items[i]
+			if nodeNames.Has(nrt.Name) {
+				continue
+			}
+			if age := time.Since(nrt.CreationTimestamp.Time); age < n.args.NRTMinAge {
+				klog.V(2).InfoS("NodeResourceTopology object without node younger than the minimum age, omitting deletion", "nodeName", nrt.Name, "age", age)
+				pending++
+				continue
 			}
+			n.enqueue(nrtKind, nrt, nrt.Name, orphanedReason)
 		}
+		orphanedObjects.WithLabelValues(nrtKind).Set(float64(pending))
 	}
 }
 
@@ -219,12 +438,32 @@ func (n *nfdGarbageCollector) Run() error {
 		m := utils.CreateMetricsServer(n.args.MetricsPort,
 			buildInfo,
 			objectsDeleted,
-			objectDeleteErrors)
+			objectDeleteErrors,
+			deletedObjectAge,
+			orphanedObjects,
+			nrtAPIAvailable,
+			leaderStatus,
+			features.FeatureEnabled)
+		if err := m.Secure(n.args.MetricsSecurity, n.args.Kubeconfig); err != nil {
+			return fmt.Errorf("failed to configure metrics server: %w", err)
+		}
 		go m.Run()
 		registerVersion(version.Get())
 		defer m.Stop()
 	}
 
+	if n.args.EnableLeaderElection {
+		return n.runWithLeaderElection()
+	}
+	return n.run()
+}
+
+// run garbage collects objects until stopped.
+func (n *nfdGarbageCollector) run() error {
+	n.startWorkers()
+	defer n.queue.ShutDown()
+
+	n.checkNRTAPI()
 	if err := n.startNodeInformer(); err != nil {
 		return err
 	}
@@ -234,6 +473,73 @@ func (n *nfdGarbageCollector) Run() error {
 	return nil
 }
 
+// runWithLeaderElection garbage collects objects only while holding the
+// leader lease. An error is returned if the lease is lost.
+func (n *nfdGarbageCollector) runWithLeaderElection() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-n.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	namespace := utils.GetKubernetesNamespace()
+	klog.InfoS("starting leader election", "lease", klog.KRef(namespace, leaseName))
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: namespace,
+		},
+		Client: n.k8sClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			// add uuid to prevent situation where 2 nfd-gc replicas run on same node
+			Identity: utils.NodeName() + "_" + uuid.NewString(),
+		},
+	}
+	runErr := make(chan error, 1)
+	leaderElector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(_ context.Context) {
+				klog.InfoS("acquired leadership")
+				leaderStatus.Set(1)
+				runErr <- n.run()
+			},
+			OnStoppedLeading: func() {
+				klog.InfoS("leaderelection lock was lost")
+				leaderStatus.Set(0)
+			},
+			OnNewLeader: func(identity string) {
+				klog.V(2).InfoS("new leader elected", "identity", identity)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create leader elector: %w", err)
+	}
+
+	leaderElector.Run(ctx)
+
+	select {
+	case <-n.stopChan:
+		return nil
+	case err := <-runErr:
+		return err
+	default:
+		return fmt.Errorf("leader election lost")
+	}
+}
+
 func (n *nfdGarbageCollector) Stop() {
 	close(n.stopChan)
+	if n.eventBroadcaster != nil {
+		n.eventBroadcaster.Shutdown()
+	}
 }
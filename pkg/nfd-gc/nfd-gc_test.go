@@ -18,6 +18,7 @@ package nfdgarbagecollector
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -28,9 +29,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/informers"
-	k8sclientset "k8s.io/client-go/kubernetes"
 	fakek8sclientset "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	fakenfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -91,19 +94,129 @@ func TestNRTGC(t *testing.T) {
 	})
 }
 
+func TestNRTGCOrphaned(t *testing.T) {
+	Convey("When garbage collecting orphaned NRTs", t, func() {
+		gc := newMockGC([]string{"node1"}, []string{"node1", "node2"})
+		gc.factory.Core().V1().Nodes().Informer()
+		gc.factory.Start(gc.stopChan)
+		gc.factory.WaitForCacheSync(gc.stopChan)
+		gc.startWorkers()
+		defer gc.Stop()
+		defer gc.queue.ShutDown()
+
+		Convey("NRTs should be deleted and an event recorded for each deletion", func() {
+			gc.garbageCollect()
+			So(waitForNRT(gc.topoClient, "node1"), ShouldBeTrue)
+			recorder := gc.recorder.(*record.FakeRecorder)
+			select {
+			case event := <-recorder.Events:
+				So(event, ShouldContainSubstring, orphanedReason)
+			case <-time.After(time.Second):
+				t.Error("no event recorded")
+			}
+			So(recorder.Events, ShouldHaveLength, 0)
+		})
+		Convey("NRTs younger than the minimum age should be kept", func() {
+			gc.args.NRTMinAge = time.Hour
+			_, err := gc.topoClient.TopologyV1alpha2().NodeResourceTopologies().Create(context.TODO(), &v1alpha2.NodeResourceTopology{
+				ObjectMeta: metav1.ObjectMeta{Name: "node3", CreationTimestamp: metav1.Now()},
+			}, metav1.CreateOptions{})
+			So(err, ShouldBeNil)
+			gc.garbageCollect()
+			So(waitForNRT(gc.topoClient, "node1", "node3"), ShouldBeTrue)
+		})
+		Convey("NRTs should not be touched if the API is not available", func() {
+			setNRTAPIAvailable(gc.topoClient.(*faketopologyv1alpha2.Clientset), false)
+			gc.garbageCollect()
+			So(gc.nrtAvailable.Load(), ShouldBeFalse)
+			So(waitForNRT(gc.topoClient, "node1", "node2"), ShouldBeTrue)
+
+			Convey("NRTs should be deleted again once the API is available", func() {
+				setNRTAPIAvailable(gc.topoClient.(*faketopologyv1alpha2.Clientset), true)
+				gc.garbageCollect()
+				So(gc.nrtAvailable.Load(), ShouldBeTrue)
+				So(waitForNRT(gc.topoClient, "node1"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestNRTGCLeaderElection(t *testing.T) {
+	t.Setenv("KUBERNETES_NAMESPACE", "node-feature-discovery")
+	Convey("When leader election is enabled", t, func() {
+		gc := newMockGC([]string{"node1"}, []string{"node1", "node2"})
+		gc.args.EnableLeaderElection = true
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- gc.Run() }()
+
+		Convey("The lease should be acquired and garbage collection run", func() {
+			So(waitForNRT(gc.topoClient, "node1"), ShouldBeTrue)
+			lease, err := gc.k8sClient.CoordinationV1().Leases("node-feature-discovery").Get(context.TODO(), leaseName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(lease.Spec.HolderIdentity, ShouldNotBeNil)
+
+			gc.Stop()
+			So(<-errChan, ShouldBeNil)
+		})
+	})
+}
+
+func TestDeletionRetries(t *testing.T) {
+	Convey("When deleting an object fails", t, func() {
+		gc := newMockGC(nil, []string{"node1"})
+		gc.args.DeleteQPS = 0
+		topoClient := gc.topoClient.(*faketopologyv1alpha2.Clientset)
+		attempts := 0
+		topoClient.PrependReactor("delete", nrtResource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+			attempts++
+			if attempts < 3 {
+				return true, nil, fmt.Errorf("fake error")
+			}
+			return false, nil, nil
+		})
+		gc.startWorkers()
+		defer gc.queue.ShutDown()
+
+		Convey("The deletion should be retried", func() {
+			gc.enqueue(nrtKind, &metav1.ObjectMeta{Name: "node1"}, "node1", nodeDeletedReason)
+			So(waitForNRT(gc.topoClient), ShouldBeTrue)
+			So(attempts, ShouldEqual, 3)
+		})
+	})
+}
+
 func newMockGC(nodes, nrts []string) *mockGC {
 	k8sClient := fakek8sclientset.NewSimpleClientset(createFakeNodes(nodes...)...)
+	topoClient := faketopologyv1alpha2.NewSimpleClientset(createFakeNRTs(nrts...)...)
+	setNRTAPIAvailable(topoClient, true)
 	return &mockGC{
 		nfdGarbageCollector: nfdGarbageCollector{
+			k8sClient:  k8sClient,
 			factory:    informers.NewSharedInformerFactory(k8sClient, 5*time.Minute),
 			nfdClient:  fakenfdclientset.NewSimpleClientset(),
-			topoClient: faketopologyv1alpha2.NewSimpleClientset(createFakeNRTs(nrts...)...),
+			topoClient: topoClient,
 			stopChan:   make(chan struct{}, 1),
+			recorder:   record.NewFakeRecorder(100),
 			args: &Args{
-				GCPeriod: 10 * time.Minute,
+				GCPeriod:    10 * time.Minute,
+				DeleteQPS:   10,
+				DeleteBurst: 100,
 			},
 		},
-		k8sClient: k8sClient,
+	}
+}
+
+// setNRTAPIAvailable makes the NodeResourceTopology API (un)available in the
+// discovery of a fake client.
+func setNRTAPIAvailable(cli *faketopologyv1alpha2.Clientset, available bool) {
+	discovery := cli.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = nil
+	if available {
+		discovery.Resources = []*metav1.APIResourceList{{
+			GroupVersion: v1alpha2.SchemeGroupVersion.String(),
+			APIResources: []metav1.APIResource{{Name: nrtResource, Kind: "NodeResourceTopology"}},
+		}}
 	}
 }
 
@@ -131,8 +244,6 @@ func createFakeNRTs(names ...string) []runtime.Object {
 
 type mockGC struct {
 	nfdGarbageCollector
-
-	k8sClient k8sclientset.Interface
 }
 
 func waitForNRT(cli topologyclientset.Interface, names ...string) bool {
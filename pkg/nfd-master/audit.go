@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// AuditLogConfig contains the configuration of the audit log of node
+// modifications.
+type AuditLogConfig struct {
+	// Path of the JSONL file the records are appended to. "-" writes the
+	// records to stdout. Empty disables the audit log.
+	Path string
+	// MaxSize is the size (in bytes) after which the file is rotated. Zero
+	// disables rotation.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+}
+
+// Kinds of node properties recorded in the audit log.
+const (
+	auditKindLabel            = "label"
+	auditKindAnnotation       = "annotation"
+	auditKindExtendedResource = "extendedResource"
+	auditKindTaint            = "taint"
+	auditKindCordon           = "cordon"
+)
+
+// auditRecord describes one modification of a node property.
+type auditRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Node      string    `json:"node"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	// Op is one of "add", "replace" or "remove"
+	Op       string `json:"op"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+	// Rule is the rule requesting the property, in the form
+	// [<namespace>/]<object name>/<rule name>
+	Rule string `json:"rule,omitempty"`
+}
+
+// ruleOrigins maps the node properties created from NodeFeatureRules, keyed
+// by "<kind>/<name>", to the rule that produced them.
+type ruleOrigins map[string]string
+
+func (o ruleOrigins) set(kind, name, rule string) {
+	o[kind+"/"+name] = rule
+}
+
+func (o ruleOrigins) get(kind, name string) string {
+	return o[kind+"/"+name]
+}
+
+// taintName returns the name of a taint used in the audit log.
+func taintName(t corev1.Taint) string {
+	return t.Key + ":" + string(t.Effect)
+}
+
+// taintNames returns the audit log names of a list of taints.
+func taintNames(taints []corev1.Taint) []string {
+	names := make([]string, 0, len(taints))
+	for _, t := range taints {
+		names = append(names, taintName(t))
+	}
+	return names
+}
+
+// taintValues maps the audit log names of a list of taints to their values.
+func taintValues(taints []corev1.Taint) map[string]string {
+	values := make(map[string]string, len(taints))
+	for _, t := range taints {
+		values[taintName(t)] = t.Value
+	}
+	return values
+}
+
+// capacityValues returns the current capacity of the given extended
+// resources of a node.
+func capacityValues(n *corev1.Node, names []string, extendedResources ExtendedResources) map[string]string {
+	values := make(map[string]string)
+	add := func(name string) {
+		if q, ok := n.Status.Capacity[corev1.ResourceName(name)]; ok {
+			val, _ := q.AsInt64()
+			values[name] = strconv.FormatInt(val, 10)
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	for name := range extendedResources {
+		add(name)
+	}
+	return values
+}
+
+// auditLog writes a record of every modification nfd-master makes to node
+// objects. A nil auditLog discards all records.
+type auditLog struct {
+	lock sync.Mutex
+	sink exportSink
+}
+
+// writerSink writes records to an io.Writer, e.g. stdout.
+type writerSink struct {
+	w io.Writer
+}
+
+func (s writerSink) name() string { return "stdout" }
+
+func (s writerSink) write(data []byte) error {
+	_, err := s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s writerSink) close() error { return nil }
+
+// newAuditLog creates a new audit log. Returns nil if no path has been
+// configured.
+func newAuditLog(config *AuditLogConfig) (*auditLog, error) {
+	switch config.Path {
+	case "":
+		return nil, nil
+	case "-":
+		return &auditLog{sink: writerSink{w: os.Stdout}}, nil
+	}
+	s, err := newFileSink(config.Path, config.MaxSize, config.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{sink: s}, nil
+}
+
+// record writes one record to the audit log.
+func (a *auditLog) record(r auditRecord) {
+	if a == nil {
+		return
+	}
+	r.Timestamp = time.Now().UTC()
+	data, err := json.Marshal(r)
+	if err != nil {
+		klog.ErrorS(err, "failed to marshal audit record")
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := a.sink.write(data); err != nil {
+		klog.ErrorS(err, "failed to write audit record", "nodeName", r.Node)
+	}
+}
+
+// recordChanges records the difference between the old and new values of
+// node properties of one kind. Only the properties listed in removeKeys are
+// considered removed if they are missing from newItems, mirroring
+// createPatches.
+func (a *auditLog) recordChanges(nodeName, kind string, removeKeys []string, oldItems, newItems map[string]string, origins ruleOrigins) {
+	if a == nil {
+		return
+	}
+	for _, key := range removeKeys {
+		if oldVal, ok := oldItems[key]; ok {
+			if _, ok := newItems[key]; !ok {
+				a.record(auditRecord{Node: nodeName, Kind: kind, Name: key, Op: "remove", OldValue: oldVal})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(newItems))
+	for key := range newItems {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		r := auditRecord{Node: nodeName, Kind: kind, Name: key, NewValue: newItems[key], Rule: origins.get(kind, key)}
+		if oldVal, ok := oldItems[key]; !ok {
+			r.Op = "add"
+		} else if oldVal != r.NewValue {
+			r.Op = "replace"
+			r.OldValue = oldVal
+		} else {
+			continue
+		}
+		a.record(r)
+	}
+}
+
+// stop closes the audit log.
+func (a *auditLog) stop() {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if err := a.sink.close(); err != nil {
+		klog.ErrorS(err, "failed to close audit log")
+	}
+}
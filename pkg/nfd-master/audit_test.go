@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func readAuditFile(path string) []auditRecord {
+	f, err := os.Open(path)
+	So(err, ShouldBeNil)
+	defer f.Close()
+
+	records := []auditRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := auditRecord{}
+		So(json.Unmarshal(scanner.Bytes(), &r), ShouldBeNil)
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAuditLog(t *testing.T) {
+	Convey("When no path is configured", t, func() {
+		a, err := newAuditLog(&AuditLogConfig{})
+		So(err, ShouldBeNil)
+		So(a, ShouldBeNil)
+		Convey("the audit log should be a no-op", func() {
+			a.recordChanges("node-1", auditKindLabel, nil, nil, map[string]string{"foo": "bar"}, nil)
+			a.stop()
+		})
+	})
+
+	Convey("When nfd-master updates a node", t, func() {
+		path := filepath.Join(t.TempDir(), "audit.jsonl")
+		testNode := newTestNode()
+		testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/old-feature"] = "old-value"
+		testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/changed-feature"] = "old-value"
+		testNode.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = "old-feature,changed-feature"
+
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(testNode))
+		var err error
+		fakeMaster.audit, err = newAuditLog(&AuditLogConfig{Path: path})
+		So(err, ShouldBeNil)
+
+		labels := Labels{
+			nfdv1alpha1.FeatureLabelNs + "/changed-feature": "new-value",
+			nfdv1alpha1.FeatureLabelNs + "/new-feature":     "true",
+		}
+		origins := ruleOrigins{}
+		origins.set(auditKindLabel, nfdv1alpha1.FeatureLabelNs+"/new-feature", "my-rule-object/my-rule")
+		So(fakeMaster.updateNodeObject(testNodeName, labels, Annotations{}, ExtendedResources{}, nil, nil, origins), ShouldBeNil)
+		fakeMaster.audit.stop()
+
+		Convey("every label change should be recorded", func() {
+			records := []auditRecord{}
+			for _, r := range readAuditFile(path) {
+				So(r.Node, ShouldEqual, testNodeName)
+				So(r.Timestamp.IsZero(), ShouldBeFalse)
+				if r.Kind == auditKindLabel {
+					r.Timestamp = time.Time{}
+					records = append(records, r)
+				}
+			}
+			So(records, ShouldResemble, []auditRecord{
+				{Node: testNodeName, Kind: auditKindLabel, Name: nfdv1alpha1.FeatureLabelNs + "/old-feature", Op: "remove", OldValue: "old-value"},
+				{Node: testNodeName, Kind: auditKindLabel, Name: nfdv1alpha1.FeatureLabelNs + "/changed-feature", Op: "replace", OldValue: "old-value", NewValue: "new-value"},
+				{Node: testNodeName, Kind: auditKindLabel, Name: nfdv1alpha1.FeatureLabelNs + "/new-feature", Op: "add", NewValue: "true", Rule: "my-rule-object/my-rule"},
+			})
+		})
+	})
+}
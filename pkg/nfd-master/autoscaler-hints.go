@@ -0,0 +1,199 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/klog/v2"
+)
+
+// autoscalerHintsSyncPeriod is the interval at which the autoscaler hints
+// are refreshed.
+const autoscalerHintsSyncPeriod = time.Minute
+
+// AutoscalerHintsConfig contains the configuration for publishing the
+// expected feature labels of node groups on their templates, making it
+// possible for the cluster-autoscaler to scale node groups from zero.
+type AutoscalerHintsConfig struct {
+	// Annotation of the template objects where the labels are written to, as
+	// a comma-separated list of key=value pairs.
+	Annotation string
+	// Templates are the node group template objects to annotate.
+	Templates []AutoscalerTemplate
+}
+
+// AutoscalerTemplate identifies one node group template object, e.g. a
+// MachineSet, and the nodes belonging to the node group.
+type AutoscalerTemplate struct {
+	// APIVersion of the template object, e.g. machine.openshift.io/v1beta1.
+	APIVersion string
+	// Resource is the plural resource name of the object, e.g. machinesets.
+	Resource  string
+	Namespace string
+	Name      string
+	// NodeSelector selects the nodes belonging to the node group.
+	NodeSelector map[string]string
+}
+
+func (c *AutoscalerHintsConfig) validate() error {
+	if len(c.Templates) > 0 && c.Annotation == "" {
+		return fmt.Errorf("annotation must not be empty")
+	}
+	for i, t := range c.Templates {
+		if _, err := schema.ParseGroupVersion(t.APIVersion); err != nil || t.APIVersion == "" {
+			return fmt.Errorf("templates[%d]: invalid apiVersion %q", i, t.APIVersion)
+		}
+		if t.Resource == "" || t.Name == "" {
+			return fmt.Errorf("templates[%d]: resource and name must be specified", i)
+		}
+		if len(t.NodeSelector) == 0 {
+			return fmt.Errorf("templates[%d]: nodeSelector must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// commonFeatureLabels returns the NFD-managed feature labels that all of the
+// given nodes have with the same value.
+func commonFeatureLabels(nodes []*corev1.Node, ownership ownershipRecord) map[string]string {
+	var common map[string]string
+	for _, node := range nodes {
+		labels := ownership.ownedLabels(node)
+
+		if common == nil {
+			common = labels
+			continue
+		}
+		for name, value := range common {
+			if v, ok := labels[name]; !ok || v != value {
+				delete(common, name)
+			}
+		}
+	}
+	return common
+}
+
+// formatAutoscalerLabels formats labels in the key=value,... format
+// understood by the cluster-autoscaler.
+func formatAutoscalerLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// startNodeInformer starts an informer for the nodes of the cluster. The node
+// groups are looked up from the informer cache instead of listing the nodes
+// from the apiserver on every sync.
+func (m *nfdMaster) startNodeInformer() {
+	m.stopNodeInformer()
+
+	m.nodeInformerStop = make(chan struct{})
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(m.k8sClient, 0,
+		informers.WithTweakListOptions(m.config.Informers.tweakNodeListOptions))
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	if m.config.Informers.StripManagedFields {
+		if err := nodeInformer.Informer().SetTransform(stripManagedFields); err != nil {
+			klog.ErrorS(err, "failed to set node informer transform")
+		}
+	}
+	m.nodeLister = nodeInformer.Lister()
+	m.nodeInformerSynced = nodeInformer.Informer().HasSynced
+	informerFactory.Start(m.nodeInformerStop)
+}
+
+// stopNodeInformer stops the node informer, if running.
+func (m *nfdMaster) stopNodeInformer() {
+	if m.nodeInformerStop != nil {
+		close(m.nodeInformerStop)
+	}
+	m.nodeInformerStop = nil
+	m.nodeLister = nil
+	m.nodeInformerSynced = nil
+}
+
+// syncAutoscalerHints updates the feature labels annotation of all
+// configured node group templates.
+func (m *nfdMaster) syncAutoscalerHints() {
+	if m.nodeLister == nil {
+		return
+	}
+	if !m.nodeInformerSynced() {
+		klog.V(2).InfoS("node cache not synced yet, skipping update of autoscaler hints")
+		return
+	}
+	for _, t := range m.config.AutoscalerHints.Templates {
+		if err := m.syncAutoscalerTemplate(t); err != nil {
+			klog.ErrorS(err, "failed to update autoscaler hints", "resource", t.Resource, "object", klog.KRef(t.Namespace, t.Name))
+		}
+	}
+}
+
+func (m *nfdMaster) syncAutoscalerTemplate(t AutoscalerTemplate) error {
+	nodes, err := m.nodeLister.List(k8slabels.SelectorFromSet(t.NodeSelector))
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	// Keep the last known labels if the node group has been scaled to zero
+	if len(nodes) == 0 {
+		return nil
+	}
+	value := formatAutoscalerLabels(commonFeatureLabels(nodes, m.labelOwnership()))
+
+	gv, err := schema.ParseGroupVersion(t.APIVersion)
+	if err != nil {
+		return err
+	}
+	cli := m.dynamicClient.Resource(gv.WithResource(t.Resource)).Namespace(t.Namespace)
+
+	obj, err := cli.Get(context.TODO(), t.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	annotation := m.config.AutoscalerHints.Annotation
+	if v, ok := obj.GetAnnotations()[annotation]; ok && v == value {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{annotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := cli.Patch(context.TODO(), t.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	klog.InfoS("autoscaler hints updated", "resource", t.Resource, "object", klog.KRef(t.Namespace, t.Name), "labels", value)
+	return nil
+}
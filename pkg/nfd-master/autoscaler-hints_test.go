@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestCommonFeatureLabels(t *testing.T) {
+	Convey("When determining the common feature labels of nodes", t, func() {
+		newNode := func(labels map[string]string, managed string) *corev1.Node {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Labels:      labels,
+				Annotations: map[string]string{nfdv1alpha1.FeatureLabelsAnnotation: managed},
+			}}
+		}
+		nodes := []*corev1.Node{
+			newNode(map[string]string{
+				nfdv1alpha1.FeatureLabelNs + "/cpu-foo":        "true",
+				nfdv1alpha1.FeatureLabelNs + "/kernel-version": "6.1",
+				"example.com/unmanaged":                        "true",
+			}, "cpu-foo,kernel-version"),
+			newNode(map[string]string{
+				nfdv1alpha1.FeatureLabelNs + "/cpu-foo":        "true",
+				nfdv1alpha1.FeatureLabelNs + "/kernel-version": "6.2",
+				"example.com/unmanaged":                        "true",
+			}, "cpu-foo,kernel-version"),
+		}
+
+		Convey("Only managed labels with the same value on all nodes should be returned", func() {
+			labels := commonFeatureLabels(nodes, (&nfdMaster{}).labelOwnership())
+			So(labels, ShouldResemble, map[string]string{nfdv1alpha1.FeatureLabelNs + "/cpu-foo": "true"})
+		})
+
+		Convey("Labels missing from one node should not be returned", func() {
+			nodes = append(nodes, newNode(map[string]string{}, ""))
+			So(commonFeatureLabels(nodes, (&nfdMaster{}).labelOwnership()), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestFormatAutoscalerLabels(t *testing.T) {
+	Convey("When formatting autoscaler labels", t, func() {
+		Convey("Labels should be sorted key=value pairs", func() {
+			So(formatAutoscalerLabels(map[string]string{"b": "2", "a": "1"}), ShouldEqual, "a=1,b=2")
+			So(formatAutoscalerLabels(nil), ShouldEqual, "")
+		})
+	})
+}
+
+func TestAutoscalerHintsConfigValidate(t *testing.T) {
+	Convey("When validating autoscaler hints config", t, func() {
+		template := AutoscalerTemplate{
+			APIVersion:   "machine.openshift.io/v1beta1",
+			Resource:     "machinesets",
+			Namespace:    "openshift-machine-api",
+			Name:         "worker-gpu",
+			NodeSelector: map[string]string{"machine.openshift.io/cluster-api-machineset": "worker-gpu"},
+		}
+		c := AutoscalerHintsConfig{Annotation: "example.com/labels", Templates: []AutoscalerTemplate{template}}
+
+		Convey("A valid config should be accepted", func() {
+			So(c.validate(), ShouldBeNil)
+		})
+		Convey("An empty annotation should be rejected", func() {
+			c.Annotation = ""
+			So(c.validate(), ShouldNotBeNil)
+		})
+		Convey("An invalid apiVersion should be rejected", func() {
+			c.Templates[0].APIVersion = "a/b/c"
+			So(c.validate(), ShouldNotBeNil)
+		})
+		Convey("An empty nodeSelector should be rejected", func() {
+			c.Templates[0].NodeSelector = nil
+			So(c.validate(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNodeInformer(t *testing.T) {
+	Convey("When starting the node informer", t, func() {
+		newNode := func(name, group string) *corev1.Node {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"node-group": group},
+			}}
+		}
+		fakeCli := fakeclient.NewSimpleClientset(newNode("node-1", "a"), newNode("node-2", "b"))
+		fakeMaster := newFakeMaster(fakeCli)
+		fakeMaster.startNodeInformer()
+		defer fakeMaster.stopNodeInformer()
+
+		So(func() interface{} { return fakeMaster.nodeInformerSynced() }, withTimeout, 2*time.Second, ShouldBeTrue)
+
+		Convey("Nodes of a node group should be listed from the cache", func() {
+			nodes, err := fakeMaster.nodeLister.List(k8slabels.SelectorFromSet(map[string]string{"node-group": "a"}))
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].Name, ShouldEqual, "node-1")
+		})
+
+		Convey("The informer should be removed when stopped", func() {
+			fakeMaster.stopNodeInformer()
+			So(fakeMaster.nodeLister, ShouldBeNil)
+			So(fakeMaster.syncAutoscalerHints, ShouldNotPanic)
+		})
+	})
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// ruleEvaluation is the output of evaluating all rules for a node.
+type ruleEvaluation struct {
+	labels            Labels
+	annotations       Annotations
+	extendedResources ExtendedResources
+	taints            []corev1.Taint
+	cordonedBy        []string
+	origins           ruleOrigins
+}
+
+// evaluationCacheEntry is the cached rule evaluation output of one node.
+type evaluationCacheEntry struct {
+	featureHash string
+	out         ruleEvaluation
+}
+
+// evaluationCache caches the output of rule evaluation per node, keyed by
+// the hash of the input features and the hash of the rule set, so that
+// resyncs of nodes whose features and rules have not changed skip the
+// evaluation. All entries are dropped when the rule set changes. The zero
+// value is ready for use.
+type evaluationCache struct {
+	sync.Mutex
+	rulesHash string
+	entries   map[string]evaluationCacheEntry
+}
+
+// get returns the cached evaluation output of a node, if the features and
+// the rules are unchanged.
+func (c *evaluationCache) get(nodeName, featureHash, rulesHash string) (ruleEvaluation, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if rulesHash != c.rulesHash {
+		return ruleEvaluation{}, false
+	}
+	entry, ok := c.entries[nodeName]
+	if !ok || entry.featureHash != featureHash {
+		return ruleEvaluation{}, false
+	}
+	return entry.out, true
+}
+
+// set stores the evaluation output of a node.
+func (c *evaluationCache) set(nodeName, featureHash, rulesHash string, out ruleEvaluation) {
+	c.Lock()
+	defer c.Unlock()
+
+	if rulesHash != c.rulesHash || c.entries == nil {
+		c.rulesHash = rulesHash
+		c.entries = make(map[string]evaluationCacheEntry)
+	}
+	c.entries[nodeName] = evaluationCacheEntry{featureHash: featureHash, out: out}
+}
+
+// reset drops all entries, e.g. when the configuration affecting rule
+// evaluation changes.
+func (c *evaluationCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rulesHash = ""
+	c.entries = nil
+}
+
+// prune drops the entries of nodes that are not in the given set, e.g.
+// nodes that have been deleted.
+func (c *evaluationCache) prune(nodeNames sets.Set[string]) {
+	c.Lock()
+	defer c.Unlock()
+
+	for name := range c.entries {
+		if !nodeNames.Has(name) {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// ruleEvaluationKey returns the hashes identifying the input of rule
+// evaluation: the features and labels of the node, and the rule objects.
+// The output of rules with expireAfter depends on the current time and the
+// output of rules with minFeatureVersion on the version of nfd-worker, making
+// it uncacheable, in which case false is returned.
+func ruleEvaluationKey(objs []ruleObject, nodeLabels map[string]string, hasher *featureHasher) (featureHash, rulesHash string, ok bool) {
+	h := sha256.New()
+	for _, obj := range objs {
+		for i := range obj.spec.Rules {
+			if obj.spec.Rules[i].ExpireAfter != nil || obj.spec.Rules[i].MinFeatureVersion != "" {
+				return "", "", false
+			}
+		}
+		fmt.Fprintf(h, "%s %s\n", obj.key(), obj.GetResourceVersion())
+	}
+	rulesHash = hex.EncodeToString(h.Sum(nil))
+
+	return hasher.nodeHash(nodeLabels), rulesHash, true
+}
+
+// ruleOutputCacheEntry is the cached output of one rule for one node.
+type ruleOutputCacheEntry struct {
+	inputHash string
+	out       nodefeaturerule.RuleOutput
+	err       error
+}
+
+// ruleOutputCache caches the output of each rule per node, keyed by the
+// hash of the features the rule references. When the features of a node
+// change, only the rules referencing the changed features are re-evaluated.
+// The zero value is ready for use.
+type ruleOutputCache struct {
+	sync.Mutex
+	nodes map[string]map[string]ruleOutputCacheEntry
+}
+
+// load returns the cached rule outputs of a node. The returned map must not
+// be modified.
+func (c *ruleOutputCache) load(nodeName string) map[string]ruleOutputCacheEntry {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.nodes[nodeName]
+}
+
+// store replaces the cached rule outputs of a node.
+func (c *ruleOutputCache) store(nodeName string, entries map[string]ruleOutputCacheEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.nodes == nil {
+		c.nodes = make(map[string]map[string]ruleOutputCacheEntry)
+	}
+	c.nodes[nodeName] = entries
+}
+
+// reset drops all entries.
+func (c *ruleOutputCache) reset() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.nodes = nil
+}
+
+// prune drops the entries of nodes that are not in the given set.
+func (c *ruleOutputCache) prune(nodeNames sets.Set[string]) {
+	c.Lock()
+	defer c.Unlock()
+
+	for name := range c.nodes {
+		if !nodeNames.Has(name) {
+			delete(c.nodes, name)
+		}
+	}
+}
+
+// featureHasher computes the hashes of the features of a node, memoizing
+// the hash of each feature.
+type featureHasher struct {
+	features *nfdv1alpha1.Features
+	hashes   map[string]string
+}
+
+func newFeatureHasher(features *nfdv1alpha1.Features) *featureHasher {
+	return &featureHasher{features: features, hashes: make(map[string]string)}
+}
+
+// hash returns the hash of a feature, which is the same for a missing
+// feature and for an empty one.
+func (h *featureHasher) hash(name string) string {
+	if sum, ok := h.hashes[name]; ok {
+		return sum
+	}
+
+	s := sha256.New()
+	if h.features != nil {
+		if f, ok := h.features.Flags[name]; ok {
+			for _, k := range sortedKeys(f.Elements) {
+				fmt.Fprintf(s, "f %q\n", k)
+			}
+		}
+		if f, ok := h.features.Attributes[name]; ok {
+			writeStringMap(s, "a", f.Elements)
+		}
+		if f, ok := h.features.Instances[name]; ok {
+			for _, i := range f.Elements {
+				fmt.Fprintln(s, "i")
+				writeStringMap(s, "ia", i.Attributes)
+			}
+		}
+	}
+	h.hashes[name] = hex.EncodeToString(s.Sum(nil))
+	return h.hashes[name]
+}
+
+// nodeHash returns the hash of all features and the labels of the node,
+// combining the memoized hashes of the features.
+func (h *featureHasher) nodeHash(nodeLabels map[string]string) string {
+	names := sets.New[string]()
+	if h.features != nil {
+		names = sets.KeySet(h.features.Flags).Union(sets.KeySet(h.features.Attributes)).Union(sets.KeySet(h.features.Instances))
+	}
+
+	s := sha256.New()
+	for _, name := range sets.List(names) {
+		fmt.Fprintln(s, name, h.hash(name))
+	}
+	writeStringMap(s, "l", nodeLabels)
+	return hex.EncodeToString(s.Sum(nil))
+}
+
+// invalidate drops the memoized hash of a feature that has been modified.
+func (h *featureHasher) invalidate(name string) {
+	delete(h.hashes, name)
+}
+
+// ruleInputHash returns the hash of the input of a rule, i.e. the version
+// of the rule object and the features referenced by the rule.
+func (h *featureHasher) ruleInputHash(resourceVersion string, features []string) string {
+	s := sha256.New()
+	fmt.Fprintln(s, resourceVersion)
+	for _, name := range features {
+		fmt.Fprintln(s, name, h.hash(name))
+	}
+	return hex.EncodeToString(s.Sum(nil))
+}
+
+// writeStringMap writes the entries of a map to a hash in sorted order.
+func writeStringMap(s hash.Hash, prefix string, m map[string]string) {
+	for _, k := range sortedKeys(m) {
+		fmt.Fprintf(s, "%s %q=%q\n", prefix, k, m[k])
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
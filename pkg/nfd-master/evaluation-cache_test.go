@@ -0,0 +1,161 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestEvaluationCache(t *testing.T) {
+	Convey("When caching rule evaluation output", t, func() {
+		c := evaluationCache{}
+		features := nfdv1alpha1.NewFeatures()
+		features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+		objs := []ruleObject{newTestRuleObject(newTestNodeFeatureRule("nfr-1", "1"))}
+		nodeLabels := map[string]string{"foo": "bar"}
+
+		featureHash, rulesHash, ok := ruleEvaluationKey(objs, nodeLabels, newFeatureHasher(features))
+		So(ok, ShouldBeTrue)
+		out := ruleEvaluation{labels: Labels{"foo": "bar"}}
+		c.set("node-1", featureHash, rulesHash, out)
+
+		Convey("the output should be returned for unchanged input", func() {
+			featureHash2, rulesHash2, _ := ruleEvaluationKey(objs, nodeLabels, newFeatureHasher(features.DeepCopy()))
+			cached, ok := c.get("node-1", featureHash2, rulesHash2)
+			So(ok, ShouldBeTrue)
+			So(cached, ShouldResemble, out)
+			_, ok = c.get("node-2", featureHash2, rulesHash2)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("changed features or node labels should miss the cache", func() {
+			features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F", "AVX512BW")
+			featureHash2, rulesHash2, _ := ruleEvaluationKey(objs, nodeLabels, newFeatureHasher(features))
+			_, ok := c.get("node-1", featureHash2, rulesHash2)
+			So(ok, ShouldBeFalse)
+
+			featureHash2, rulesHash2, _ = ruleEvaluationKey(objs, map[string]string{"foo": "baz"}, newFeatureHasher(features))
+			_, ok = c.get("node-1", featureHash2, rulesHash2)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("changed rules should drop all entries", func() {
+			objs2 := []ruleObject{newTestRuleObject(newTestNodeFeatureRule("nfr-1", "2"))}
+			featureHash2, rulesHash2, _ := ruleEvaluationKey(objs2, nodeLabels, newFeatureHasher(features))
+			So(rulesHash2, ShouldNotEqual, rulesHash)
+			_, ok := c.get("node-1", featureHash2, rulesHash2)
+			So(ok, ShouldBeFalse)
+
+			c.set("node-2", featureHash2, rulesHash2, out)
+			_, ok = c.get("node-1", featureHash, rulesHash)
+			So(ok, ShouldBeFalse)
+			So(c.entries, ShouldHaveLength, 1)
+		})
+		Convey("prune should drop the entries of removed nodes", func() {
+			c.set("node-2", featureHash, rulesHash, out)
+			c.prune(sets.New("node-2"))
+			_, ok := c.get("node-1", featureHash, rulesHash)
+			So(ok, ShouldBeFalse)
+			_, ok = c.get("node-2", featureHash, rulesHash)
+			So(ok, ShouldBeTrue)
+		})
+		Convey("reset should drop all entries", func() {
+			c.reset()
+			_, ok := c.get("node-1", featureHash, rulesHash)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("rules with expireAfter should not be cached", func() {
+			nfr := newTestNodeFeatureRule("nfr-2", "1")
+			nfr.Spec.Rules[0].ExpireAfter = &metav1.Duration{Duration: time.Hour}
+			_, _, ok := ruleEvaluationKey(append(objs, newTestRuleObject(nfr)), nodeLabels, newFeatureHasher(features))
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestIncrementalRuleEvaluation(t *testing.T) {
+	Convey("When processing NodeFeatureRules repeatedly", t, func() {
+		nfr := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "nfr-1", ResourceVersion: "1"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:   "cpu-rule",
+						Labels: map[string]string{"avx512": "true"},
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{Feature: "cpu.cpuid", MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"AVX512F": {Op: nfdv1alpha1.MatchExists}}},
+						},
+					},
+					{
+						Name:           "local-rule",
+						LabelsTemplate: "{{ range .local.label }}local-{{ .Name }}={{ .Value }}\n{{ end }}",
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{Feature: "local.label", MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"foo": {Op: nfdv1alpha1.MatchExists}}},
+						},
+					},
+				},
+			},
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(nfr))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		newFeatures := func(localValue string) *nfdv1alpha1.Features {
+			f := nfdv1alpha1.NewFeatures()
+			f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+			f.Attributes["local.label"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"foo": localValue})
+			return f
+		}
+		labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule(testNodeName, nil, newFeatures("1"))
+		So(labels, ShouldResemble, Labels{"avx512": "true", "local-foo": "1"})
+		prev := fakeMaster.ruleOutputCache.load(testNodeName)
+		So(prev, ShouldHaveLength, 2)
+
+		Convey("only rules referencing changed features should be re-evaluated", func() {
+			labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule(testNodeName, nil, newFeatures("2"))
+			So(labels, ShouldResemble, Labels{"avx512": "true", "local-foo": "2"})
+
+			cur := fakeMaster.ruleOutputCache.load(testNodeName)
+			So(cur["nfr-1#0"].inputHash, ShouldEqual, prev["nfr-1#0"].inputHash)
+			So(cur["nfr-1#1"].inputHash, ShouldNotEqual, prev["nfr-1#1"].inputHash)
+		})
+		Convey("prune should drop the rule outputs of removed nodes", func() {
+			fakeMaster.ruleOutputCache.prune(sets.New("node-2"))
+			So(fakeMaster.ruleOutputCache.load(testNodeName), ShouldBeEmpty)
+		})
+		Convey("cached rule output should not be modified", func() {
+			_, _, _, _, _, _ = fakeMaster.processNodeFeatureRule(testNodeName, nil, newFeatures("2"))
+			So(prev["nfr-1#0"].out.Labels, ShouldResemble, map[string]string{"avx512": "true"})
+			So(prev["nfr-1#1"].out.Labels, ShouldResemble, map[string]string{"local-foo": "1"})
+		})
+		Convey("the input hash should depend on the object version and the referenced features", func() {
+			h := newFeatureHasher(newFeatures("1"))
+			hash := h.ruleInputHash("1", []string{"cpu.cpuid"})
+			So(h.ruleInputHash("1", []string{"cpu.cpuid"}), ShouldEqual, hash)
+			So(h.ruleInputHash("2", []string{"cpu.cpuid"}), ShouldNotEqual, hash)
+			So(h.ruleInputHash("1", []string{"cpu.cpuid", "local.label"}), ShouldNotEqual, hash)
+			So(newFeatureHasher(newFeatures("2")).ruleInputHash("1", []string{"cpu.cpuid"}), ShouldEqual, hash)
+		})
+	})
+}
@@ -0,0 +1,424 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// ExporterConfig contains the configuration of the feature inventory
+// exporter. The exporter is disabled if no sink is configured.
+type ExporterConfig struct {
+	Webhook WebhookExporterConfig
+	File    FileExporterConfig
+	Hub     HubExporterConfig
+	// QueueSize is the maximum number of records waiting to be exported.
+	// Records are dropped if the queue is full.
+	QueueSize int
+}
+
+// WebhookExporterConfig contains the configuration of the HTTP sink.
+type WebhookExporterConfig struct {
+	// URL to POST the records to. Empty disables the sink.
+	URL     string
+	Timeout utils.DurationVal
+	// BearerTokenFile is a file containing a token that is sent in the
+	// Authorization header. The file is re-read on every request so that
+	// the token can be rotated. Requires an https URL.
+	BearerTokenFile string
+	// CAFile is the CA bundle used for verifying the server certificate,
+	// instead of the system roots.
+	CAFile string
+	// CertFile and KeyFile specify a client certificate for mTLS.
+	CertFile string
+	KeyFile  string
+}
+
+// FileExporterConfig contains the configuration of the file sink.
+type FileExporterConfig struct {
+	// Path of the JSONL file to write the records to. Empty disables the
+	// sink.
+	Path string
+	// MaxSize is the size (in bytes) after which the file is rotated.
+	MaxSize int64
+	// MaxBackups is the number of rotated files to keep.
+	MaxBackups int
+}
+
+// exportRecord is the inventory record of one node.
+type exportRecord struct {
+	Node      string               `json:"node"`
+	Timestamp time.Time            `json:"timestamp"`
+	Features  nfdv1alpha1.Features `json:"features"`
+	Labels    map[string]string    `json:"labels,omitempty"`
+}
+
+// exportSink is a destination of exported records.
+type exportSink interface {
+	name() string
+	write(data []byte) error
+	close() error
+}
+
+// nodePruner is implemented by sinks that keep per-node state that needs to
+// be cleaned up when nodes are removed.
+type nodePruner interface {
+	pruneNodes(nodeNames sets.Set[string]) error
+}
+
+// exportItem is a record waiting in the export queue.
+type exportItem struct {
+	node string
+	hash string
+	data []byte
+}
+
+// featureExporter streams the feature inventory of nodes to the configured
+// sinks. Records are only exported when the features of a node change.
+type featureExporter struct {
+	sinks []exportSink
+	queue chan exportItem
+	done  chan struct{}
+
+	// lock protects hashes, pending and stopped
+	lock sync.Mutex
+	// hashes holds the features hash of the last record of each node that
+	// was written to all sinks
+	hashes map[string]string
+	// pending holds the features hash of the queued record of each node
+	pending map[string]string
+	stopped bool
+}
+
+// newFeatureExporter creates a new exporter and starts it. Returns nil if no
+// sinks have been configured.
+func newFeatureExporter(config *ExporterConfig) (*featureExporter, error) {
+	sinks := []exportSink{}
+	if config.Webhook.URL != "" {
+		s, err := newWebhookSink(&config.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if config.File.Path != "" {
+		s, err := newFileSink(config.File.Path, config.File.MaxSize, config.File.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if config.Hub.Kubeconfig != "" {
+		s, err := newHubSink(&config.Hub)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	e := &featureExporter{
+		sinks:   sinks,
+		queue:   make(chan exportItem, config.QueueSize),
+		done:    make(chan struct{}),
+		hashes:  make(map[string]string),
+		pending: make(map[string]string),
+	}
+	go e.run()
+	return e, nil
+}
+
+// export queues the features of a node for exporting, if they have changed
+// since the previous call. Never blocks.
+func (e *featureExporter) export(nodeName string, spec *nfdv1alpha1.NodeFeatureSpec) {
+	if e == nil {
+		return
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		klog.ErrorS(err, "failed to marshal node features for exporting", "nodeName", nodeName)
+		return
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.stopped || e.hashes[nodeName] == hash || e.pending[nodeName] == hash {
+		return
+	}
+
+	data, err = json.Marshal(&exportRecord{
+		Node:      nodeName,
+		Timestamp: time.Now().UTC(),
+		Features:  spec.Features,
+		Labels:    spec.Labels,
+	})
+	if err != nil {
+		klog.ErrorS(err, "failed to marshal export record", "nodeName", nodeName)
+		return
+	}
+
+	select {
+	case e.queue <- exportItem{node: nodeName, hash: hash, data: data}:
+		e.pending[nodeName] = hash
+	default:
+		klog.InfoS("export queue full, dropping record", "nodeName", nodeName)
+		exportErrors.WithLabelValues("queue").Inc()
+	}
+}
+
+func (e *featureExporter) run() {
+	defer close(e.done)
+	for item := range e.queue {
+		ok := true
+		for _, s := range e.sinks {
+			if err := s.write(item.data); err != nil {
+				klog.ErrorS(err, "failed to export node features", "sink", s.name(), "nodeName", item.node)
+				exportErrors.WithLabelValues(s.name()).Inc()
+				ok = false
+			} else {
+				exportedRecords.WithLabelValues(s.name()).Inc()
+			}
+		}
+
+		e.lock.Lock()
+		if e.pending[item.node] == item.hash {
+			delete(e.pending, item.node)
+		}
+		// If a sink failed the record is exported again on the next update
+		// of the node
+		if ok {
+			e.hashes[item.node] = item.hash
+		}
+		e.lock.Unlock()
+	}
+}
+
+// prune forgets the nodes that are not in the given set, e.g. nodes that
+// have been deleted, and removes their state from the sinks.
+func (e *featureExporter) prune(nodeNames sets.Set[string]) {
+	if e == nil {
+		return
+	}
+	e.lock.Lock()
+	for name := range e.hashes {
+		if !nodeNames.Has(name) {
+			delete(e.hashes, name)
+		}
+	}
+	e.lock.Unlock()
+
+	for _, s := range e.sinks {
+		if p, ok := s.(nodePruner); ok {
+			if err := p.pruneNodes(nodeNames); err != nil {
+				klog.ErrorS(err, "failed to prune exporter sink", "sink", s.name())
+				exportErrors.WithLabelValues(s.name()).Inc()
+			}
+		}
+	}
+}
+
+// stop stops the exporter after flushing the queue.
+func (e *featureExporter) stop() {
+	if e == nil {
+		return
+	}
+	e.lock.Lock()
+	e.stopped = true
+	close(e.queue)
+	e.lock.Unlock()
+
+	<-e.done
+	for _, s := range e.sinks {
+		if err := s.close(); err != nil {
+			klog.ErrorS(err, "failed to close exporter sink", "sink", s.name())
+		}
+	}
+}
+
+// webhookSink POSTs each record as a JSON document to an HTTP endpoint.
+type webhookSink struct {
+	url       string
+	client    *http.Client
+	tokenFile string
+}
+
+func newWebhookSink(config *WebhookExporterConfig) (*webhookSink, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	// Don't let tokens travel in plaintext
+	if config.BearerTokenFile != "" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook bearerTokenFile requires an https url")
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, fmt.Errorf("webhook certFile and keyFile must be specified together")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		caCert, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM(caCert); !ok {
+			return nil, fmt.Errorf("failed to add certificate from '%s'", config.CAFile)
+		}
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load webhook client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &webhookSink{
+		url:       config.URL,
+		client:    &http.Client{Timeout: config.Timeout.Duration, Transport: transport},
+		tokenFile: config.BearerTokenFile,
+	}, nil
+}
+
+func (s *webhookSink) name() string { return "webhook" }
+
+func (s *webhookSink) write(data []byte) error {
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tokenFile != "" {
+		token, err := os.ReadFile(s.tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %q from %s", resp.Status, s.url)
+	}
+	return nil
+}
+
+func (s *webhookSink) close() error { return nil }
+
+// fileSink appends each record as one line to a JSONL file, rotating the
+// file when it grows too big.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileSink(path string, maxSize int64, maxBackups int) (*fileSink, error) {
+	s := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) name() string { return "file" }
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileSink) write(data []byte) error {
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(data))+1 > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(append(data, '\n'))
+	s.size += int64(n)
+	return err
+}
+
+// rotate renames the current file to <path>.1, shifting older backups and
+// dropping the oldest one, and opens a new file.
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			src := fmt.Sprintf("%s.%d", s.path, i)
+			if _, err := os.Stat(src); err == nil {
+				if err := os.Rename(src, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func newExportTestSpec(value string) *nfdv1alpha1.NodeFeatureSpec {
+	spec := nfdv1alpha1.NewNodeFeatureSpec()
+	spec.Features.Attributes["system.dmiid"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"sys_vendor": value})
+	spec.Labels["feature.node.kubernetes.io/foo"] = value
+	return spec
+}
+
+func readExportFile(path string) []exportRecord {
+	f, err := os.Open(path)
+	So(err, ShouldBeNil)
+	defer f.Close()
+
+	records := []exportRecord{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := exportRecord{}
+		So(json.Unmarshal(scanner.Bytes(), &r), ShouldBeNil)
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestFeatureExporter(t *testing.T) {
+	Convey("When no sinks are configured", t, func() {
+		e, err := newFeatureExporter(&ExporterConfig{QueueSize: 10})
+		So(err, ShouldBeNil)
+		So(e, ShouldBeNil)
+		Convey("the exporter should be a no-op", func() {
+			e.export("node-1", newExportTestSpec("a"))
+			e.stop()
+		})
+	})
+
+	Convey("When exporting to a file", t, func() {
+		path := filepath.Join(t.TempDir(), "inventory.jsonl")
+		e, err := newFeatureExporter(&ExporterConfig{File: FileExporterConfig{Path: path}, QueueSize: 10})
+		So(err, ShouldBeNil)
+
+		e.export("node-1", newExportTestSpec("a"))
+		e.export("node-2", newExportTestSpec("a"))
+		e.export("node-1", newExportTestSpec("a"))
+		e.export("node-1", newExportTestSpec("b"))
+		e.stop()
+
+		Convey("only changed features should be exported", func() {
+			records := readExportFile(path)
+			So(records, ShouldHaveLength, 3)
+			So(records[0].Node, ShouldEqual, "node-1")
+			So(records[1].Node, ShouldEqual, "node-2")
+			So(records[2].Node, ShouldEqual, "node-1")
+			So(records[2].Labels["feature.node.kubernetes.io/foo"], ShouldEqual, "b")
+			So(records[2].Features.Attributes["system.dmiid"].Elements["sys_vendor"], ShouldEqual, "b")
+		})
+
+		Convey("deleted nodes should be forgotten", func() {
+			e, err := newFeatureExporter(&ExporterConfig{File: FileExporterConfig{Path: path}, QueueSize: 10})
+			So(err, ShouldBeNil)
+			e.export("node-1", newExportTestSpec("b"))
+			e.export("node-2", newExportTestSpec("a"))
+			e.stop()
+			e.prune(sets.New("node-1"))
+			So(e.hashes, ShouldContainKey, "node-1")
+			So(e.hashes, ShouldNotContainKey, "node-2")
+		})
+
+		Convey("exporting after stop should be ignored", func() {
+			e.export("node-3", newExportTestSpec("a"))
+			So(readExportFile(path), ShouldHaveLength, 3)
+		})
+	})
+
+	Convey("When the export file grows too big", t, func() {
+		path := filepath.Join(t.TempDir(), "inventory.jsonl")
+		s, err := newFileSink(path, 10, 2)
+		So(err, ShouldBeNil)
+		for _, data := range []string{"1111111", "2222222", "3333333", "4444444"} {
+			So(s.write([]byte(data)), ShouldBeNil)
+		}
+		So(s.close(), ShouldBeNil)
+
+		Convey("it should be rotated", func() {
+			for name, expected := range map[string]string{"": "4444444\n", ".1": "3333333\n", ".2": "2222222\n"} {
+				data, err := os.ReadFile(path + name)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, expected)
+			}
+			_, err := os.Stat(path + ".3")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+
+	Convey("When exporting to a webhook", t, func() {
+		var lock sync.Mutex
+		received := []exportRecord{}
+		status := http.StatusOK
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			body, _ := io.ReadAll(r.Body)
+			rec := exportRecord{}
+			_ = json.Unmarshal(body, &rec)
+			received = append(received, rec)
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+
+		sink := &webhookSink{url: srv.URL, client: &http.Client{Timeout: time.Second}}
+
+		Convey("records should be posted", func() {
+			e, err := newFeatureExporter(&ExporterConfig{
+				Webhook:   WebhookExporterConfig{URL: srv.URL, Timeout: utils.DurationVal{Duration: time.Second}},
+				QueueSize: 10,
+			})
+			So(err, ShouldBeNil)
+			e.export("node-1", newExportTestSpec("a"))
+			e.stop()
+			So(received, ShouldHaveLength, 1)
+			So(received[0].Node, ShouldEqual, "node-1")
+		})
+
+		Convey("error responses should be reported", func() {
+			status = http.StatusInternalServerError
+			So(sink.write([]byte("{}")), ShouldNotBeNil)
+		})
+
+		Convey("failed records should be exported again", func() {
+			status = http.StatusInternalServerError
+			e, err := newFeatureExporter(&ExporterConfig{
+				Webhook:   WebhookExporterConfig{URL: srv.URL, Timeout: utils.DurationVal{Duration: time.Second}},
+				QueueSize: 10,
+			})
+			So(err, ShouldBeNil)
+			e.export("node-1", newExportTestSpec("a"))
+			// Wait for the record to be processed
+			for {
+				e.lock.Lock()
+				n := len(e.pending)
+				e.lock.Unlock()
+				if n == 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			lock.Lock()
+			status = http.StatusOK
+			lock.Unlock()
+			e.export("node-1", newExportTestSpec("a"))
+			e.stop()
+			So(received, ShouldHaveLength, 2)
+			So(e.hashes, ShouldContainKey, "node-1")
+		})
+
+		Convey("bearer tokens should require https", func() {
+			_, err := newFeatureExporter(&ExporterConfig{
+				Webhook:   WebhookExporterConfig{URL: srv.URL, BearerTokenFile: "/token"},
+				QueueSize: 10,
+			})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("When exporting to a webhook over TLS", t, func() {
+		var authHeader string
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader = r.Header.Get("Authorization")
+		}))
+		defer srv.Close()
+
+		dir := t.TempDir()
+		caFile := filepath.Join(dir, "ca.crt")
+		tokenFile := filepath.Join(dir, "token")
+		So(os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644), ShouldBeNil)
+		So(os.WriteFile(tokenFile, []byte("secret\n"), 0600), ShouldBeNil)
+
+		sink, err := newWebhookSink(&WebhookExporterConfig{
+			URL:             srv.URL,
+			Timeout:         utils.DurationVal{Duration: time.Second},
+			CAFile:          caFile,
+			BearerTokenFile: tokenFile,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("the server should be verified and the token sent", func() {
+			So(sink.write([]byte("{}")), ShouldBeNil)
+			So(authHeader, ShouldEqual, "Bearer secret")
+		})
+
+		Convey("untrusted servers should be rejected", func() {
+			sink, err := newWebhookSink(&WebhookExporterConfig{URL: srv.URL, Timeout: utils.DurationVal{Duration: time.Second}})
+			So(err, ShouldBeNil)
+			So(sink.write([]byte("{}")), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"maps"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// zeroedExtendedResources maps the extended resources zeroed by nfd-master to
+// the time they were zeroed.
+type zeroedExtendedResources map[string]time.Time
+
+// extendedResourcesZeroedAnnotation returns the name of the annotation
+// tracking the zeroed extended resources.
+func (m *nfdMaster) extendedResourcesZeroedAnnotation() string {
+	return m.instanceAnnotation(nfdv1alpha1.ExtendedResourcesZeroedAnnotation)
+}
+
+// getZeroedExtendedResources parses the zeroed extended resources recorded in
+// the node annotations.
+func (m *nfdMaster) getZeroedExtendedResources(node *corev1.Node) zeroedExtendedResources {
+	zeroed := zeroedExtendedResources{}
+	val, ok := node.Annotations[m.extendedResourcesZeroedAnnotation()]
+	if !ok {
+		return zeroed
+	}
+	if err := json.Unmarshal([]byte(val), &zeroed); err != nil {
+		// Start the grace period from scratch
+		klog.ErrorS(err, "invalid annotation, ignoring", "nodeName", node.Name, "annotation", m.extendedResourcesZeroedAnnotation())
+		return zeroedExtendedResources{}
+	}
+	return zeroed
+}
+
+// applyExtendedResourceGracePeriod keeps the extended resources that are no
+// longer produced on the node with zero capacity for the grace period, so
+// that no new pods get scheduled on them while running pods are not
+// disrupted by the resource disappearing. The resources are removed once the
+// grace period has passed. Returns the extended resources to set on the node
+// and the annotation value tracking the zeroed resources, empty if there are
+// none.
+func (m *nfdMaster) applyExtendedResourceGracePeriod(node *corev1.Node, extendedResources ExtendedResources) (ExtendedResources, string) {
+	grace := m.config.ExtendedResourceGracePeriod.Duration
+	// Pruning removes everything right away
+	if grace <= 0 || m.args.Prune {
+		return extendedResources, ""
+	}
+
+	oldZeroed := m.getZeroedExtendedResources(node)
+	zeroed := zeroedExtendedResources{}
+	out := maps.Clone(extendedResources)
+	if out == nil {
+		out = ExtendedResources{}
+	}
+	now := time.Now()
+	var requeueAfter time.Duration
+
+	oldResources := m.extendedResourceOwnership().ownedResources(node)
+	sort.Strings(oldResources)
+	for _, name := range oldResources {
+		if _, ok := extendedResources[name]; ok {
+			if _, ok := oldZeroed[name]; ok {
+				klog.InfoS("extended resource produced again, restoring it", "nodeName", node.Name, "extendedResourceName", name)
+			}
+			continue
+		}
+		if _, ok := node.Status.Capacity[corev1.ResourceName(name)]; !ok {
+			// Nothing to keep
+			continue
+		}
+
+		zeroedAt, ok := oldZeroed[name]
+		if !ok {
+			zeroedAt = now.Truncate(time.Second)
+			klog.InfoS("extended resource not produced anymore, zeroing it", "nodeName", node.Name, "extendedResourceName", name, "gracePeriod", grace)
+			extendedResourcesZeroed.Inc()
+		}
+		remaining := zeroedAt.Add(grace).Sub(now)
+		if remaining <= 0 {
+			klog.InfoS("grace period of zeroed extended resource passed, removing it", "nodeName", node.Name, "extendedResourceName", name)
+			continue
+		}
+		out[name] = "0"
+		zeroed[name] = zeroedAt
+		if requeueAfter == 0 || remaining < requeueAfter {
+			requeueAfter = remaining
+		}
+	}
+
+	if len(zeroed) == 0 {
+		return out, ""
+	}
+	// Re-evaluate the node when the first grace period ends
+	if m.nodeUpdaterPool != nil && m.nodeUpdaterPool.queue != nil {
+		m.nodeUpdaterPool.queue.AddAfter(node.Name, requeueAfter)
+	}
+	val, err := json.Marshal(zeroed)
+	if err != nil {
+		klog.ErrorS(err, "failed to marshal zeroed extended resources", "nodeName", node.Name)
+		return out, ""
+	}
+	return out, string(val)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func TestApplyExtendedResourceGracePeriod(t *testing.T) {
+	Convey("When applying the extended resource grace period", t, func() {
+		extRes := nfdv1alpha1.FeatureLabelNs + "/res"
+		otherRes := nfdv1alpha1.FeatureLabelNs + "/other"
+
+		node := newTestNode()
+		node.Annotations[nfdv1alpha1.ExtendedResourceAnnotation] = "res,other"
+		node.Status.Capacity[corev1.ResourceName(extRes)] = resource.MustParse("2")
+		node.Status.Capacity[corev1.ResourceName(otherRes)] = resource.MustParse("1")
+
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.config.ExtendedResourceGracePeriod = utils.DurationVal{Duration: time.Hour}
+
+		zeroedAt := func(val string) zeroedExtendedResources {
+			zeroed := zeroedExtendedResources{}
+			So(json.Unmarshal([]byte(val), &zeroed), ShouldBeNil)
+			return zeroed
+		}
+
+		Convey("Nothing should be kept if the grace period is disabled", func() {
+			fakeMaster.config.ExtendedResourceGracePeriod = utils.DurationVal{}
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{otherRes: "1"})
+			So(annotation, ShouldBeEmpty)
+		})
+		Convey("Nothing should be kept when pruning", func() {
+			fakeMaster.args.Prune = true
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{})
+			So(out, ShouldBeEmpty)
+			So(annotation, ShouldBeEmpty)
+		})
+		Convey("Resources not produced anymore should be zeroed", func() {
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{extRes: "0", otherRes: "1"})
+			So(zeroedAt(annotation), ShouldContainKey, extRes)
+			So(zeroedAt(annotation), ShouldNotContainKey, otherRes)
+		})
+		Convey("The zeroing time of resources should be preserved", func() {
+			ts := time.Now().Add(-time.Minute).Truncate(time.Second)
+			val, _ := json.Marshal(zeroedExtendedResources{extRes: ts})
+			node.Annotations[nfdv1alpha1.ExtendedResourcesZeroedAnnotation] = string(val)
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{extRes: "0", otherRes: "1"})
+			So(zeroedAt(annotation)[extRes].Equal(ts), ShouldBeTrue)
+		})
+		Convey("Resources should be removed after the grace period", func() {
+			val, _ := json.Marshal(zeroedExtendedResources{extRes: time.Now().Add(-2 * time.Hour)})
+			node.Annotations[nfdv1alpha1.ExtendedResourcesZeroedAnnotation] = string(val)
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{otherRes: "1"})
+			So(annotation, ShouldBeEmpty)
+		})
+		Convey("Resources produced again should be restored", func() {
+			val, _ := json.Marshal(zeroedExtendedResources{extRes: time.Now()})
+			node.Annotations[nfdv1alpha1.ExtendedResourcesZeroedAnnotation] = string(val)
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{extRes: "3", otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{extRes: "3", otherRes: "1"})
+			So(annotation, ShouldBeEmpty)
+		})
+		Convey("Resources not present on the node should not be zeroed", func() {
+			delete(node.Status.Capacity, corev1.ResourceName(extRes))
+			out, annotation := fakeMaster.applyExtendedResourceGracePeriod(node, ExtendedResources{otherRes: "1"})
+			So(out, ShouldResemble, ExtendedResources{otherRes: "1"})
+			So(annotation, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/featureschema"
+)
+
+const (
+	// FeatureSchemaValidationNone disables feature schema validation.
+	FeatureSchemaValidationNone = "none"
+	// FeatureSchemaValidationWarn reports features violating their schema
+	// but uses them as-is.
+	FeatureSchemaValidationWarn = "warn"
+	// FeatureSchemaValidationReject drops the features and attributes
+	// violating their schema.
+	FeatureSchemaValidationReject = "reject"
+
+	nodeFeatureSchemaValidReason      = "SchemaValid"
+	nodeFeatureSchemaViolationsReason = "SchemaViolations"
+
+	// maxReportedSchemaViolations is the maximum number of violations
+	// listed in the SchemaValid condition
+	maxReportedSchemaViolations = 5
+)
+
+// validateFeatureSchema checks the features of a NodeFeature object against
+// the schemas of their sources. Depending on the featureSchemaValidation
+// setting the violations are only reported or also stripped from features.
+func (m *nfdMaster) validateFeatureSchema(obj *nfdv1alpha1.NodeFeature, features *nfdv1alpha1.Features) {
+	var violations []featureschema.Violation
+	switch m.config.FeatureSchemaValidation {
+	case FeatureSchemaValidationWarn:
+		violations = featureschema.Validate(features)
+	case FeatureSchemaValidationReject:
+		violations = featureschema.Strip(features)
+	default:
+		return
+	}
+	if len(violations) == 0 {
+		return
+	}
+
+	for _, v := range violations {
+		featureSchemaViolations.WithLabelValues(string(v.Reason)).Inc()
+	}
+	klog.V(1).InfoS("features violate their schema", "nodefeature", klog.KObj(obj), "rejected", m.config.FeatureSchemaValidation == FeatureSchemaValidationReject, "violations", violations)
+}
+
+// nodeFeatureSchemaCondition returns the SchemaValid condition of a
+// NodeFeature object, nil if schema validation is disabled.
+func (m *nfdMaster) nodeFeatureSchemaCondition(obj *nfdv1alpha1.NodeFeature) *metav1.Condition {
+	if m.config.FeatureSchemaValidation == "" || m.config.FeatureSchemaValidation == FeatureSchemaValidationNone {
+		return nil
+	}
+	cond := &metav1.Condition{
+		Type:               nfdv1alpha1.NodeFeatureSchemaValidCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: obj.Generation,
+		Reason:             nodeFeatureSchemaValidReason,
+		Message:            "Features conform to their schemas",
+	}
+	violations := featureschema.Validate(&obj.Spec.Features)
+	if len(violations) == 0 {
+		return cond
+	}
+
+	msgs := make([]string, 0, maxReportedSchemaViolations)
+	for _, v := range violations[:min(len(violations), maxReportedSchemaViolations)] {
+		msgs = append(msgs, v.String())
+	}
+	if len(violations) > maxReportedSchemaViolations {
+		msgs = append(msgs, fmt.Sprintf("and %d more", len(violations)-maxReportedSchemaViolations))
+	}
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = nodeFeatureSchemaViolationsReason
+	cond.Message = strings.Join(msgs, "; ")
+	if m.config.FeatureSchemaValidation == FeatureSchemaValidationReject {
+		cond.Message = "Violating features ignored: " + cond.Message
+	}
+	return cond
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestFeatureSchemaValidation(t *testing.T) {
+	Convey("When validating NodeFeature objects against the feature schemas", t, func() {
+		nf := &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{Name: "nf-1", Namespace: "nfd", Generation: 2},
+			Spec:       *nfdv1alpha1.NewNodeFeatureSpec(),
+		}
+		nf.Spec.Features.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+			*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "8086", "sriov_totalvfs ": "8"}),
+		})
+		nf.Spec.Features.Attributes["thirdparty.feature"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"foo": "bar"})
+
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+
+		Convey("Nothing should be done if validation is disabled", func() {
+			fakeMaster.config.FeatureSchemaValidation = FeatureSchemaValidationNone
+			features := nf.Spec.Features.DeepCopy()
+			fakeMaster.validateFeatureSchema(nf, features)
+			So(features, ShouldResemble, &nf.Spec.Features)
+			So(fakeMaster.nodeFeatureSchemaCondition(nf), ShouldBeNil)
+		})
+		Convey("Violations should be reported but kept in warn mode", func() {
+			fakeMaster.config.FeatureSchemaValidation = FeatureSchemaValidationWarn
+			features := nf.Spec.Features.DeepCopy()
+			fakeMaster.validateFeatureSchema(nf, features)
+			So(features, ShouldResemble, &nf.Spec.Features)
+
+			cond := fakeMaster.nodeFeatureSchemaCondition(nf)
+			So(cond.Type, ShouldEqual, nfdv1alpha1.NodeFeatureSchemaValidCondition)
+			So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+			So(cond.ObservedGeneration, ShouldEqual, 2)
+			So(cond.Message, ShouldEqual, `pci.device: attribute "sriov_totalvfs ": unknown attribute`)
+		})
+		Convey("Violations should be dropped in reject mode", func() {
+			fakeMaster.config.FeatureSchemaValidation = FeatureSchemaValidationReject
+			features := nf.Spec.Features.DeepCopy()
+			fakeMaster.validateFeatureSchema(nf, features)
+			So(features.Instances["pci.device"].Elements[0].Attributes, ShouldResemble, map[string]string{"vendor": "8086"})
+			So(features.Attributes, ShouldContainKey, "thirdparty.feature")
+			So(fakeMaster.nodeFeatureSchemaCondition(nf).Message, ShouldStartWith, "Violating features ignored: ")
+		})
+		Convey("Valid features should be reported as such", func() {
+			fakeMaster.config.FeatureSchemaValidation = FeatureSchemaValidationWarn
+			delete(nf.Spec.Features.Instances, "pci.device")
+			So(fakeMaster.nodeFeatureSchemaCondition(nf).Status, ShouldEqual, metav1.ConditionTrue)
+		})
+	})
+}
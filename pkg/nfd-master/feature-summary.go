@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+)
+
+// featureSummarySyncPeriod is the interval at which changes in the cluster
+// feature summary are written to the API server.
+const featureSummarySyncPeriod = 10 * time.Second
+
+// featureSummary aggregates the NFD-managed feature labels of all nodes in
+// the cluster. It is updated incrementally from node informer events.
+type featureSummary struct {
+	sync.Mutex
+
+	// labels is the ownership record of the feature labels managed by this
+	// nfd-master instance.
+	labels ownershipRecord
+	// nodes holds the feature labels of each node.
+	nodes map[string]map[string]string
+	// counts holds the number of nodes per label value.
+	counts map[string]map[string]int
+	dirty  bool
+}
+
+func newFeatureSummary(labels ownershipRecord) *featureSummary {
+	return &featureSummary{
+		labels: labels,
+		nodes:  make(map[string]map[string]string),
+		counts: make(map[string]map[string]int),
+		dirty:  true,
+	}
+}
+
+// featureLabels returns the feature labels of a node that are managed by NFD.
+func (s *featureSummary) featureLabels(node *corev1.Node) map[string]string {
+	return s.labels.ownedLabels(node)
+}
+
+// updateNode updates the summary with the current state of a node.
+func (s *featureSummary) updateNode(node *corev1.Node) {
+	labels := s.featureLabels(node)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if old, ok := s.nodes[node.Name]; ok {
+		if maps.Equal(old, labels) {
+			return
+		}
+		s.addLabels(old, -1)
+	}
+	s.addLabels(labels, 1)
+	s.nodes[node.Name] = labels
+	s.dirty = true
+}
+
+// removeNode removes a node from the summary.
+func (s *featureSummary) removeNode(nodeName string) {
+	s.Lock()
+	defer s.Unlock()
+
+	old, ok := s.nodes[nodeName]
+	if !ok {
+		return
+	}
+	s.addLabels(old, -1)
+	delete(s.nodes, nodeName)
+	s.dirty = true
+}
+
+// addLabels adds delta to the counts of the given label values. Must be
+// called with the lock held.
+func (s *featureSummary) addLabels(labels map[string]string, delta int) {
+	for name, value := range labels {
+		values, ok := s.counts[name]
+		if !ok {
+			values = make(map[string]int)
+			s.counts[name] = values
+		}
+		values[value] += delta
+		if values[value] <= 0 {
+			delete(values, value)
+		}
+		if len(values) == 0 {
+			delete(s.counts, name)
+		}
+	}
+}
+
+// status returns a snapshot of the summary and whether it has changed since
+// the previous call. The summary is marked clean.
+func (s *featureSummary) status() (nfdv1alpha1.ClusterFeatureSummaryStatus, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	status := nfdv1alpha1.ClusterFeatureSummaryStatus{NodeCount: len(s.nodes)}
+	if len(s.counts) > 0 {
+		status.Labels = make(map[string]map[string]int, len(s.counts))
+		for name, values := range s.counts {
+			status.Labels[name] = maps.Clone(values)
+		}
+	}
+	changed := s.dirty
+	s.dirty = false
+	return status, changed
+}
+
+// markDirty marks the summary as changed, e.g. after a failed write.
+func (s *featureSummary) markDirty() {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty = true
+}
+
+// writeFeatureSummary creates or updates the ClusterFeatureSummary object
+// with the given status.
+func writeFeatureSummary(cli nfdclientset.Interface, name string, status nfdv1alpha1.ClusterFeatureSummaryStatus) error {
+	summaries := cli.NfdV1alpha1().ClusterFeatureSummaries()
+
+	obj, err := summaries.Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		obj = &nfdv1alpha1.ClusterFeatureSummary{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     status,
+		}
+		if _, err := summaries.Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ClusterFeatureSummary %q: %w", name, err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get ClusterFeatureSummary %q: %w", name, err)
+	}
+
+	obj = obj.DeepCopy()
+	obj.Status = status
+	if _, err := summaries.Update(context.TODO(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ClusterFeatureSummary %q: %w", name, err)
+	}
+	return nil
+}
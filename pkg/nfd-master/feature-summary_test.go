@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func newSummaryTestNode(name string, labels map[string]string) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{"kubernetes.io/hostname": name},
+			Annotations: map[string]string{},
+		},
+	}
+	names := ""
+	for label, value := range labels {
+		node.Labels[nfdv1alpha1.FeatureLabelNs+"/"+label] = value
+		if names != "" {
+			names += ","
+		}
+		names += label
+	}
+	node.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = names
+	return node
+}
+
+func TestFeatureSummary(t *testing.T) {
+	Convey("When maintaining the cluster feature summary", t, func() {
+		s := newFeatureSummary((&nfdMaster{}).labelOwnership())
+		_, changed := s.status()
+		So(changed, ShouldBeTrue)
+
+		s.updateNode(newSummaryTestNode("node-1", map[string]string{"cpu-foo": "true", "kernel-version": "6.1"}))
+		s.updateNode(newSummaryTestNode("node-2", map[string]string{"cpu-foo": "true", "kernel-version": "6.2"}))
+
+		Convey("Only NFD-managed labels should be counted", func() {
+			status, changed := s.status()
+			So(changed, ShouldBeTrue)
+			So(status, ShouldResemble, nfdv1alpha1.ClusterFeatureSummaryStatus{
+				NodeCount: 2,
+				Labels: map[string]map[string]int{
+					nfdv1alpha1.FeatureLabelNs + "/cpu-foo":        {"true": 2},
+					nfdv1alpha1.FeatureLabelNs + "/kernel-version": {"6.1": 1, "6.2": 1},
+				},
+			})
+
+			_, changed = s.status()
+			So(changed, ShouldBeFalse)
+		})
+
+		Convey("Unchanged nodes should not mark the summary dirty", func() {
+			s.status()
+			s.updateNode(newSummaryTestNode("node-1", map[string]string{"cpu-foo": "true", "kernel-version": "6.1"}))
+			_, changed := s.status()
+			So(changed, ShouldBeFalse)
+		})
+
+		Convey("Updated nodes should replace their previous labels", func() {
+			s.updateNode(newSummaryTestNode("node-1", map[string]string{"kernel-version": "6.2"}))
+			status, _ := s.status()
+			So(status.NodeCount, ShouldEqual, 2)
+			So(status.Labels, ShouldResemble, map[string]map[string]int{
+				nfdv1alpha1.FeatureLabelNs + "/cpu-foo":        {"true": 1},
+				nfdv1alpha1.FeatureLabelNs + "/kernel-version": {"6.2": 2},
+			})
+		})
+
+		Convey("Removed nodes should be dropped from the summary", func() {
+			s.removeNode("node-1")
+			s.removeNode("node-2")
+			s.removeNode("node-3")
+			status, changed := s.status()
+			So(changed, ShouldBeTrue)
+			So(status, ShouldResemble, nfdv1alpha1.ClusterFeatureSummaryStatus{NodeCount: 0})
+		})
+	})
+}
+
+func TestWriteFeatureSummary(t *testing.T) {
+	Convey("When writing the cluster feature summary", t, func() {
+		cli := fakenfdclient.NewSimpleClientset()
+		status := nfdv1alpha1.ClusterFeatureSummaryStatus{
+			NodeCount: 1,
+			Labels:    map[string]map[string]int{nfdv1alpha1.FeatureLabelNs + "/cpu-foo": {"true": 1}},
+		}
+
+		Convey("The object should be created if it doesn't exist", func() {
+			So(writeFeatureSummary(cli, "nfd-master", status), ShouldBeNil)
+			obj, err := cli.NfdV1alpha1().ClusterFeatureSummaries().Get(context.TODO(), "nfd-master", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(obj.Status, ShouldResemble, status)
+
+			Convey("and updated if it exists", func() {
+				status.NodeCount = 0
+				status.Labels = nil
+				So(writeFeatureSummary(cli, "nfd-master", status), ShouldBeNil)
+				obj, err := cli.NfdV1alpha1().ClusterFeatureSummaries().Get(context.TODO(), "nfd-master", metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				So(obj.Status, ShouldResemble, status)
+			})
+		})
+	})
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// hubRequestTimeout is the timeout of requests to the hub cluster.
+const hubRequestTimeout = 30 * time.Second
+
+// HubExporterConfig contains the configuration of the hub cluster sink.
+type HubExporterConfig struct {
+	// Kubeconfig is the path of the kubeconfig file for accessing the hub
+	// cluster. Empty disables the sink.
+	Kubeconfig string
+	// Namespace is the namespace in the hub cluster where the NodeFeature
+	// objects of this cluster are stored.
+	Namespace string
+	// ClusterName identifies this cluster in the hub.
+	ClusterName string
+}
+
+func (c *HubExporterConfig) validate() error {
+	if c.Kubeconfig == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(c.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %v", c.Namespace, errs)
+	}
+	if errs := validation.IsDNS1123Label(c.ClusterName); len(errs) > 0 {
+		return fmt.Errorf("invalid clusterName %q: %v", c.ClusterName, errs)
+	}
+	return nil
+}
+
+// hubSink mirrors the features of each node into a NodeFeature object in a
+// hub cluster, so that the hardware inventory of a fleet of clusters can be
+// queried centrally. The objects are named <cluster>.<node> and labeled with
+// the names of the cluster and the node. They don't have the node-name label
+// so nfd-master running in the hub does not apply them to any node, and
+// nfd-gc leaves them alone.
+type hubSink struct {
+	client      nfdclientset.Interface
+	namespace   string
+	clusterName string
+}
+
+func newHubSink(config *HubExporterConfig) (*hubSink, error) {
+	kubeconfig, err := utils.GetKubeconfig(config.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hub kubeconfig: %w", err)
+	}
+	kubeconfig.Timeout = hubRequestTimeout
+	cli, err := nfdclientset.NewForConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return &hubSink{client: cli, namespace: config.Namespace, clusterName: config.ClusterName}, nil
+}
+
+func (s *hubSink) name() string { return "hub" }
+
+func (s *hubSink) objName(nodeName string) string {
+	return s.clusterName + "." + nodeName
+}
+
+func (s *hubSink) write(data []byte) error {
+	record := exportRecord{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("failed to unmarshal export record: %w", err)
+	}
+
+	spec := nfdv1alpha1.NodeFeatureSpec{Features: record.Features, Labels: record.Labels}
+	labels := map[string]string{
+		nfdv1alpha1.HubClusterNameLabel: s.clusterName,
+		nfdv1alpha1.HubNodeNameLabel:    record.Node,
+	}
+
+	cli := s.client.NfdV1alpha1().NodeFeatures(s.namespace)
+	obj, err := cli.Get(context.TODO(), s.objName(record.Node), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		obj = &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   s.objName(record.Node),
+				Labels: labels,
+			},
+			Spec: spec,
+		}
+		_, err = cli.Create(context.TODO(), obj, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	obj = obj.DeepCopy()
+	obj.Labels = labels
+	obj.Spec = spec
+	_, err = cli.Update(context.TODO(), obj, metav1.UpdateOptions{})
+	return err
+}
+
+// pruneNodes deletes the objects of nodes that are not in the given set,
+// i.e. nodes that have been removed from the cluster.
+func (s *hubSink) pruneNodes(nodeNames sets.Set[string]) error {
+	cli := s.client.NfdV1alpha1().NodeFeatures(s.namespace)
+	objs, err := cli.List(context.TODO(), metav1.ListOptions{LabelSelector: nfdv1alpha1.HubClusterNameLabel + "=" + s.clusterName})
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeature objects: %w", err)
+	}
+	for _, obj := range objs.Items {
+		if nodeNames.Has(obj.Labels[nfdv1alpha1.HubNodeNameLabel]) {
+			continue
+		}
+		klog.InfoS("deleting hub NodeFeature object of removed node", "nodefeature", klog.KObj(&obj))
+		if err := cli.Delete(context.TODO(), obj.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NodeFeature %s/%s: %w", obj.Namespace, obj.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *hubSink) close() error { return nil }
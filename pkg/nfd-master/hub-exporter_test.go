@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestHubSink(t *testing.T) {
+	Convey("When mirroring node features to a hub cluster", t, func() {
+		cli := fakenfdclient.NewSimpleClientset()
+		s := &hubSink{client: cli, namespace: "fleet", clusterName: "cluster-1"}
+
+		writeRecord := func(node, value string) {
+			spec := newExportTestSpec(value)
+			data, err := json.Marshal(&exportRecord{Node: node, Timestamp: time.Now(), Features: spec.Features, Labels: spec.Labels})
+			So(err, ShouldBeNil)
+			So(s.write(data), ShouldBeNil)
+		}
+		getObj := func(node string) (*nfdv1alpha1.NodeFeature, error) {
+			return cli.NfdV1alpha1().NodeFeatures("fleet").Get(context.TODO(), "cluster-1."+node, metav1.GetOptions{})
+		}
+
+		writeRecord("node-1", "foo")
+		writeRecord("node-2", "foo")
+
+		Convey("An object should be created for each node", func() {
+			obj, err := getObj("node-1")
+			So(err, ShouldBeNil)
+			So(obj.Labels, ShouldResemble, map[string]string{
+				nfdv1alpha1.HubClusterNameLabel: "cluster-1",
+				nfdv1alpha1.HubNodeNameLabel:    "node-1",
+			})
+			So(obj.Spec, ShouldResemble, *newExportTestSpec("foo"))
+		})
+
+		Convey("The object should be updated when the features change", func() {
+			writeRecord("node-1", "bar")
+			obj, err := getObj("node-1")
+			So(err, ShouldBeNil)
+			So(obj.Spec, ShouldResemble, *newExportTestSpec("bar"))
+		})
+
+		Convey("Objects of removed nodes should be deleted", func() {
+			So(s.pruneNodes(sets.New("node-1")), ShouldBeNil)
+			_, err := getObj("node-1")
+			So(err, ShouldBeNil)
+			_, err = getObj("node-2")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestHubExporterConfig(t *testing.T) {
+	Convey("When validating the hub exporter config", t, func() {
+		Convey("A disabled sink should not be validated", func() {
+			So((&HubExporterConfig{}).validate(), ShouldBeNil)
+		})
+		Convey("The namespace and cluster name are required", func() {
+			So((&HubExporterConfig{Kubeconfig: "hub.conf", Namespace: "fleet"}).validate(), ShouldNotBeNil)
+			So((&HubExporterConfig{Kubeconfig: "hub.conf", ClusterName: "cluster-1"}).validate(), ShouldNotBeNil)
+			So((&HubExporterConfig{Kubeconfig: "hub.conf", Namespace: "fleet", ClusterName: "cluster-1"}).validate(), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/klog/v2"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/imagecompat"
+)
+
+const (
+	// imageCompatWebhookPath is the path of the pod validation endpoint.
+	imageCompatWebhookPath = "/validate-pod-image-compatibility"
+	// maxAdmissionReviewSize is the maximum size of an admission review
+	// request body.
+	maxAdmissionReviewSize = 4 << 20
+	// Image compatibility specs are cached, including the information that
+	// an image has none, to not query the registry on each pod admission.
+	imageCompatCacheTTL  = 5 * time.Minute
+	imageCompatCacheSize = 1024
+	// imageCompatFetchTimeout bounds the time spent querying the registry,
+	// the API server times out the whole review after 10s by default.
+	imageCompatFetchTimeout = 5 * time.Second
+)
+
+// ImageCompatWebhookArgs are the settings of the image compatibility
+// admission webhook.
+type ImageCompatWebhookArgs struct {
+	// Port is the https port of the webhook, zero disables the webhook.
+	Port int
+	// CertFile and KeyFile are the TLS certificate and key of the webhook.
+	CertFile string
+	KeyFile  string
+	// RegistryAuthFile is a docker config.json style file with the
+	// credentials for accessing image registries.
+	RegistryAuthFile string
+	// NodeAffinity enables the node affinity mutating webhook on the same
+	// server.
+	NodeAffinity bool
+}
+
+// imageCompatWebhook is a validating admission webhook that denies pods whose
+// container images have image compatibility specs attached that no node in
+// the cluster satisfies.
+type imageCompatWebhook struct {
+	m     *nfdMaster
+	srv   *http.Server
+	cache *cache.LRUExpireCache
+	// fetch looks up the compatibility spec of an image
+	fetch func(ctx context.Context, image string) (*compatv1alpha1.Spec, error)
+}
+
+// imageCompatCacheEntry is a cached registry lookup, spec is nil if the
+// image has no compatibility spec attached.
+type imageCompatCacheEntry struct {
+	spec *compatv1alpha1.Spec
+}
+
+func newImageCompatWebhook(m *nfdMaster, args ImageCompatWebhookArgs) (*imageCompatWebhook, error) {
+	client := &imagecompat.Client{}
+	if args.RegistryAuthFile != "" {
+		creds, err := imagecompat.DockerConfigCredentials(args.RegistryAuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry credentials: %w", err)
+		}
+		client.Credentials = creds
+	}
+
+	cert, err := tls.LoadX509KeyPair(args.CertFile, args.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook certificate: %w", err)
+	}
+
+	w := &imageCompatWebhook{
+		m:     m,
+		cache: cache.NewLRUExpireCache(imageCompatCacheSize),
+		fetch: func(ctx context.Context, image string) (*compatv1alpha1.Spec, error) {
+			return imagecompat.Fetch(ctx, client, image)
+		},
+	}
+	mux := http.NewServeMux()
+	mux.Handle(imageCompatWebhookPath, http.HandlerFunc(w.handler))
+	if args.NodeAffinity {
+		mux.Handle(nodeAffinityWebhookPath, http.HandlerFunc(w.nodeAffinityHandler))
+	}
+	w.srv = &http.Server{
+		Addr:    fmt.Sprintf(":%d", args.Port),
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+	return w, nil
+}
+
+func (w *imageCompatWebhook) run() {
+	klog.InfoS("image compatibility webhook starting", "port", w.srv.Addr)
+	klog.InfoS("image compatibility webhook stopped", "exitCode", w.srv.ListenAndServeTLS("", ""))
+}
+
+func (w *imageCompatWebhook) stop() {
+	klog.InfoS("stopping image compatibility webhook", "port", w.srv.Addr)
+	w.srv.Close()
+}
+
+// handler serves AdmissionReview requests for pods.
+func (w *imageCompatWebhook) handler(rw http.ResponseWriter, r *http.Request) {
+	serveAdmissionReview(rw, r, w.review)
+}
+
+// serveAdmissionReview decodes an AdmissionReview request and responds with
+// the result of the review function.
+func serveAdmissionReview(rw http.ResponseWriter, r *http.Request, review func(context.Context, *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ar := admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxAdmissionReviewSize)).Decode(&ar); err != nil {
+		http.Error(rw, fmt.Sprintf("failed to parse admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if ar.Request == nil {
+		http.Error(rw, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	ar.Response = review(r.Context(), ar.Request)
+	ar.Response.UID = ar.Request.UID
+	ar.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(ar); err != nil {
+		klog.ErrorS(err, "failed to write admission review response")
+	}
+}
+
+// review validates one pod admission request. Errors in looking up the image
+// compatibility specs or evaluating them do not block the pod, they are
+// returned as warnings instead.
+func (w *imageCompatWebhook) review(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != "Pod" || req.SubResource != "" {
+		return resp
+	}
+	pod := corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		imageCompatReviews.WithLabelValues("error").Inc()
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("image compatibility not verified: failed to decode pod: %v", err))
+		return resp
+	}
+	logger := klog.LoggerWithValues(klog.Background(), "pod", klog.KRef(req.Namespace, podName(&pod)))
+
+	msg, warnings := w.validatePod(ctx, logger, &pod)
+	resp.Warnings = warnings
+	switch {
+	case msg != "":
+		imageCompatReviews.WithLabelValues("denied").Inc()
+		logger.V(1).Info("pod denied by image compatibility webhook", "reason", msg)
+		resp.Allowed = false
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+			Message: msg,
+		}
+	case len(warnings) > 0:
+		imageCompatReviews.WithLabelValues("error").Inc()
+	default:
+		imageCompatReviews.WithLabelValues("allowed").Inc()
+	}
+	return resp
+}
+
+// podName returns the name of the pod, falling back to the generate name
+// for pods that are being created.
+func podName(pod *corev1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return pod.GenerateName
+}
+
+// imageCompatibility is a required compatibility set of one image.
+type imageCompatibility struct {
+	image         string
+	compatibility *compatv1alpha1.Compatibility
+}
+
+// validatePod checks that at least one node satisfies the required
+// compatibility sets of all images of the pod. It returns the reason for
+// denying the pod, or an empty string if it is allowed, and warnings.
+func (w *imageCompatWebhook) validatePod(ctx context.Context, logger klog.Logger, pod *corev1.Pod) (string, []string) {
+	var warnings []string
+
+	required := []imageCompatibility{}
+	for _, image := range podImages(pod) {
+		spec, err := w.getSpec(ctx, image)
+		if err != nil {
+			logger.Info("failed to get image compatibility spec, ignoring image", "image", image, "err", err)
+			warnings = append(warnings, fmt.Sprintf("image compatibility of %s not verified: %v", image, err))
+			continue
+		}
+		if spec == nil {
+			continue
+		}
+		for i := range spec.Compatibilities {
+			if c := &spec.Compatibilities[i]; !c.Optional {
+				required = append(required, imageCompatibility{image: image, compatibility: c})
+			}
+		}
+	}
+	if len(required) == 0 {
+		return "", warnings
+	}
+
+	if w.m.nfdController == nil {
+		return "", append(warnings, "image compatibility not verified: node features not available")
+	}
+
+	nodeSelector := k8sLabels.SelectorFromSet(pod.Spec.NodeSelector)
+	var candidates map[string]struct{}
+	if pod.Spec.NodeName != "" {
+		candidates = map[string]struct{}{pod.Spec.NodeName: {}}
+	}
+	for _, r := range required {
+		matched, err := w.m.matchNodes(imagecompat.Rule(r.compatibility), nodeSelector)
+		if err != nil {
+			logger.Error(err, "failed to evaluate image compatibility", "image", r.image, "compatibility", r.compatibility.Name)
+			return "", append(warnings, fmt.Sprintf("image compatibility not verified: %v", err))
+		}
+
+		next := make(map[string]struct{}, len(matched.Nodes))
+		for _, n := range matched.Nodes {
+			if _, ok := candidates[n]; candidates == nil || ok {
+				next[n] = struct{}{}
+			}
+		}
+		if len(next) == 0 {
+			return noCompatibleNodeMessage(pod, r), warnings
+		}
+		candidates = next
+	}
+
+	logger.V(2).Info("pod images are compatible", "numCompatibleNodes", len(candidates))
+	return "", warnings
+}
+
+func noCompatibleNodeMessage(pod *corev1.Pod, r imageCompatibility) string {
+	var where string
+	switch {
+	case pod.Spec.NodeName != "":
+		where = fmt.Sprintf("node %q", pod.Spec.NodeName)
+	case len(pod.Spec.NodeSelector) > 0:
+		where = "any node matching the nodeSelector of the pod"
+	default:
+		where = "any node"
+	}
+	msg := fmt.Sprintf("image %s is not compatible with %s: compatibility %q", r.image, where, r.compatibility.Name)
+	if r.compatibility.Description != "" {
+		msg += " (" + r.compatibility.Description + ")"
+	}
+	return msg + " is not satisfied"
+}
+
+// podImages returns the distinct images of all containers of the pod.
+func podImages(pod *corev1.Pod) []string {
+	images := map[string]struct{}{}
+	for _, c := range pod.Spec.InitContainers {
+		images[c.Image] = struct{}{}
+	}
+	for _, c := range pod.Spec.Containers {
+		images[c.Image] = struct{}{}
+	}
+	ret := make([]string, 0, len(images))
+	for image := range images {
+		if strings.TrimSpace(image) != "" {
+			ret = append(ret, image)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// getSpec returns the compatibility spec of an image from the cache or the
+// registry, nil if the image has none.
+func (w *imageCompatWebhook) getSpec(ctx context.Context, image string) (*compatv1alpha1.Spec, error) {
+	if e, ok := w.cache.Get(image); ok {
+		return e.(imageCompatCacheEntry).spec, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imageCompatFetchTimeout)
+	defer cancel()
+	spec, err := w.fetch(ctx, image)
+	if imagecompat.IsNotFound(err) {
+		spec, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.cache.Add(image, imageCompatCacheEntry{spec: spec}, imageCompatCacheTTL)
+	return spec, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/cache"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	compatv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/imagecompat/v1alpha1"
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+	"github.com/openshift/node-feature-discovery/pkg/imagecompat"
+)
+
+func TestImageCompatWebhook(t *testing.T) {
+	Convey("When validating pods with the image compatibility webhook", t, func() {
+		newNodeFeature := func(name string, flags ...string) *nfdv1alpha1.NodeFeature {
+			nf := &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "nfd",
+					Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: name},
+				},
+				Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+			}
+			nf.Spec.Features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures(flags...)
+			return nf
+		}
+		newNode := func(name, pool string) *corev1.Node {
+			n := newTestNode()
+			n.Name = name
+			n.Labels = map[string]string{"pool": pool}
+			return n
+		}
+		newSpec := func(optional bool, flags ...string) *compatv1alpha1.Spec {
+			spec := &compatv1alpha1.Spec{Version: compatv1alpha1.Version}
+			for _, f := range flags {
+				spec.Compatibilities = append(spec.Compatibilities, compatv1alpha1.Compatibility{
+					Name:     f,
+					Optional: optional,
+					MatchFeatures: nfdv1alpha1.FeatureMatcher{{
+						Feature:          "cpu.cpuid",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{f: &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists}},
+					}},
+				})
+			}
+			return spec
+		}
+
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(
+			newNode("node-1", "a"),
+			newNode("node-2", "b"),
+		))
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			newNodeFeature("node-1", "AVX512F"),
+			newNodeFeature("node-2", "AVX512F", "AMX"),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		specs := map[string]*compatv1alpha1.Spec{
+			"avx512":   newSpec(false, "AVX512F"),
+			"amx":      newSpec(false, "AVX512F", "AMX"),
+			"sve":      newSpec(false, "SVE"),
+			"optional": newSpec(true, "SVE"),
+		}
+		numFetches := 0
+		wh := &imageCompatWebhook{
+			m:     fakeMaster,
+			cache: cache.NewLRUExpireCache(imageCompatCacheSize),
+			fetch: func(ctx context.Context, image string) (*compatv1alpha1.Spec, error) {
+				numFetches++
+				if image == "broken" {
+					return nil, errors.New("registry unavailable")
+				}
+				if s, ok := specs[image]; ok {
+					return s, nil
+				}
+				return nil, imagecompat.ErrNotFound
+			},
+		}
+
+		review := func(pod *corev1.Pod) *admissionv1.AdmissionResponse {
+			raw, err := json.Marshal(pod)
+			So(err, ShouldBeNil)
+			body, err := json.Marshal(admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("uid-1"),
+					Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Namespace: "default",
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			So(err, ShouldBeNil)
+
+			rec := httptest.NewRecorder()
+			wh.handler(rec, httptest.NewRequest(http.MethodPost, imageCompatWebhookPath, bytes.NewReader(body)))
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := admissionv1.AdmissionReview{}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Response, ShouldNotBeNil)
+			So(resp.Response.UID, ShouldEqual, types.UID("uid-1"))
+			return resp.Response
+		}
+		newPod := func(images ...string) *corev1.Pod {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1"}}
+			for _, image := range images {
+				pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Image: image})
+			}
+			return pod
+		}
+
+		Convey("pods whose images have no spec should be allowed", func() {
+			resp := review(newPod("plain"))
+			So(resp.Allowed, ShouldBeTrue)
+			So(resp.Warnings, ShouldBeEmpty)
+		})
+		Convey("pods should be allowed if a node is compatible", func() {
+			So(review(newPod("avx512", "amx")).Allowed, ShouldBeTrue)
+		})
+		Convey("pods should be denied if no node is compatible", func() {
+			resp := review(newPod("avx512", "sve"))
+			So(resp.Allowed, ShouldBeFalse)
+			So(resp.Result.Message, ShouldContainSubstring, `compatibility "SVE"`)
+		})
+		Convey("optional compatibilities should not be enforced", func() {
+			So(review(newPod("optional")).Allowed, ShouldBeTrue)
+		})
+		Convey("init containers should be validated", func() {
+			pod := newPod("avx512")
+			pod.Spec.InitContainers = []corev1.Container{{Image: "sve"}}
+			So(review(pod).Allowed, ShouldBeFalse)
+		})
+		Convey("the nodeSelector and nodeName of the pod should be honored", func() {
+			pod := newPod("amx")
+			pod.Spec.NodeSelector = map[string]string{"pool": "a"}
+			So(review(pod).Allowed, ShouldBeFalse)
+
+			pod = newPod("amx")
+			pod.Spec.NodeName = "node-1"
+			So(review(pod).Allowed, ShouldBeFalse)
+
+			pod.Spec.NodeName = "node-2"
+			So(review(pod).Allowed, ShouldBeTrue)
+		})
+		Convey("registry errors should fail open with a warning", func() {
+			resp := review(newPod("broken"))
+			So(resp.Allowed, ShouldBeTrue)
+			So(resp.Warnings, ShouldHaveLength, 1)
+		})
+		Convey("specs should be cached", func() {
+			review(newPod("avx512", "plain"))
+			review(newPod("avx512", "plain"))
+			So(numFetches, ShouldEqual, 2)
+		})
+		Convey("invalid requests should be rejected", func() {
+			rec := httptest.NewRecorder()
+			wh.handler(rec, httptest.NewRequest(http.MethodGet, imageCompatWebhookPath, nil))
+			So(rec.Code, ShouldEqual, http.StatusMethodNotAllowed)
+
+			rec = httptest.NewRecorder()
+			wh.handler(rec, httptest.NewRequest(http.MethodPost, imageCompatWebhookPath, bytes.NewReader([]byte(`{}`))))
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
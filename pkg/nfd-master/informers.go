@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// InformersConfig contains the configuration of the informers nfd-master
+// uses for watching the cluster. Narrowing down the watched objects reduces
+// the memory footprint and apiserver load in very large clusters.
+type InformersConfig struct {
+	// ResyncPeriod is the resync period of the NodeFeature and
+	// NodeFeatureRule informers. Zero disables the resync, the periodic
+	// re-evaluation of nodes is controlled by the top-level resyncPeriod.
+	ResyncPeriod utils.DurationVal
+	// NodeLabelSelector restricts the nodes managed by nfd-master. Other
+	// nodes are not watched or modified.
+	NodeLabelSelector string
+	// NodeFieldSelector restricts the nodes managed by nfd-master. Only the
+	// metadata.name and spec.unschedulable fields are supported.
+	NodeFieldSelector string
+	// NodeFeatureLabelSelector restricts the NodeFeature objects watched.
+	// Objects not matching the selector are ignored as if they did not
+	// exist.
+	NodeFeatureLabelSelector string
+	// StripManagedFields drops the managedFields of the watched objects
+	// before storing them in the informer caches.
+	StripManagedFields bool
+}
+
+func (c *InformersConfig) validate() error {
+	if c.ResyncPeriod.Duration < 0 {
+		return fmt.Errorf("resyncPeriod must not be negative")
+	}
+	if _, err := k8slabels.Parse(c.NodeLabelSelector); err != nil {
+		return fmt.Errorf("invalid nodeLabelSelector: %w", err)
+	}
+	sel, err := fields.ParseSelector(c.NodeFieldSelector)
+	if err != nil {
+		return fmt.Errorf("invalid nodeFieldSelector: %w", err)
+	}
+	for _, r := range sel.Requirements() {
+		if r.Field != "metadata.name" && r.Field != "spec.unschedulable" {
+			return fmt.Errorf("invalid nodeFieldSelector: unsupported field %q", r.Field)
+		}
+	}
+	if _, err := k8slabels.Parse(c.NodeFeatureLabelSelector); err != nil {
+		return fmt.Errorf("invalid nodeFeatureLabelSelector: %w", err)
+	}
+	return nil
+}
+
+// tweakNodeListOptions applies the node selectors to list/watch requests of
+// nodes.
+func (c *InformersConfig) tweakNodeListOptions(opts *metav1.ListOptions) {
+	opts.LabelSelector = c.NodeLabelSelector
+	opts.FieldSelector = c.NodeFieldSelector
+}
+
+// tweakNodeFeatureListOptions applies the NodeFeature selector to
+// list/watch requests of NodeFeature objects.
+func (c *InformersConfig) tweakNodeFeatureListOptions(opts *metav1.ListOptions) {
+	opts.LabelSelector = c.NodeFeatureLabelSelector
+}
+
+// nodeSelected returns true if the node matches the node selectors. The
+// selectors have been validated in configure.
+func (c *InformersConfig) nodeSelected(node *corev1.Node) bool {
+	if c.NodeLabelSelector != "" {
+		sel, _ := k8slabels.Parse(c.NodeLabelSelector)
+		if !sel.Matches(k8slabels.Set(node.Labels)) {
+			return false
+		}
+	}
+	if c.NodeFieldSelector != "" {
+		sel, _ := fields.ParseSelector(c.NodeFieldSelector)
+		return sel.Matches(fields.Set{
+			"metadata.name":      node.Name,
+			"spec.unschedulable": strconv.FormatBool(node.Spec.Unschedulable),
+		})
+	}
+	return true
+}
+
+// stripManagedFields is an informer transform function dropping the
+// managedFields of objects.
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, ok := obj.(metav1.Object); ok {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func TestInformersConfig(t *testing.T) {
+	Convey("When validating the informers config", t, func() {
+		So((&InformersConfig{}).validate(), ShouldBeNil)
+		So((&InformersConfig{NodeLabelSelector: "node-role.kubernetes.io/worker", NodeFieldSelector: "spec.unschedulable=false"}).validate(), ShouldBeNil)
+		So((&InformersConfig{NodeLabelSelector: "=foo"}).validate(), ShouldBeError)
+		So((&InformersConfig{NodeFieldSelector: "status.phase=Running"}).validate(), ShouldBeError)
+		So((&InformersConfig{NodeFeatureLabelSelector: "a in ("}).validate(), ShouldBeError)
+		So((&InformersConfig{ResyncPeriod: utils.DurationVal{Duration: -1}}).validate(), ShouldBeError)
+	})
+
+	Convey("When matching nodes against the node selectors", t, func() {
+		worker := newTestNode()
+		worker.Labels["node-role.kubernetes.io/worker"] = ""
+		master := newTestNode()
+		master.Name = "master"
+
+		c := &InformersConfig{}
+		So(c.nodeSelected(worker), ShouldBeTrue)
+		So(c.nodeSelected(master), ShouldBeTrue)
+
+		c = &InformersConfig{NodeLabelSelector: "node-role.kubernetes.io/worker"}
+		So(c.nodeSelected(worker), ShouldBeTrue)
+		So(c.nodeSelected(master), ShouldBeFalse)
+
+		c = &InformersConfig{NodeFieldSelector: "metadata.name!=master,spec.unschedulable=false"}
+		So(c.nodeSelected(worker), ShouldBeTrue)
+		So(c.nodeSelected(master), ShouldBeFalse)
+		worker.Spec.Unschedulable = true
+		So(c.nodeSelected(worker), ShouldBeFalse)
+
+		opts := metav1.ListOptions{}
+		c.tweakNodeListOptions(&opts)
+		So(opts.FieldSelector, ShouldEqual, c.NodeFieldSelector)
+	})
+
+	Convey("When stripping managed fields", t, func() {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubelet"}}}}
+		obj, err := stripManagedFields(node)
+		So(err, ShouldBeNil)
+		So(obj.(*corev1.Node).ManagedFields, ShouldBeNil)
+		So(obj.(*corev1.Node).Name, ShouldEqual, "node")
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// LabelAlias publishes a renamed label also under its former name, so that
+// node selectors using the old name keep working during a deprecation
+// window.
+type LabelAlias struct {
+	// Name is the current name of the label.
+	Name string
+	// Alias is the former name of the label.
+	Alias string
+	// DeprecatedIn is the NFD version the label was renamed in.
+	DeprecatedIn string
+	// RemovedIn is the first NFD version not publishing the alias anymore.
+	// Empty means that the alias is published until removed from the
+	// alias table.
+	RemovedIn string
+}
+
+// builtinLabelAliases is the table of the labels renamed between NFD
+// versions. Entries stay in the table until RemovedIn has been released.
+var builtinLabelAliases = []LabelAlias{
+	{
+		Name:         nfdv1alpha1.FeatureLabelNs + "/cpu-security.sgx.enabled",
+		Alias:        nfdv1alpha1.FeatureLabelNs + "/cpu-sgx.enabled",
+		DeprecatedIn: "v0.10.0",
+		RemovedIn:    "v0.20.0",
+	},
+}
+
+// validate checks that the alias is well-formed.
+func (a *LabelAlias) validate() error {
+	for _, name := range []string{a.Name, a.Alias} {
+		if errs := validation.IsQualifiedName(name); len(errs) > 0 {
+			return fmt.Errorf("invalid label name %q: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	if a.Name == a.Alias {
+		return fmt.Errorf("label %q aliased to itself", a.Name)
+	}
+	for _, v := range []string{a.DeprecatedIn, a.RemovedIn} {
+		if v == "" {
+			continue
+		}
+		if _, err := version.ParseGeneric(v); err != nil {
+			return fmt.Errorf("invalid version %q of alias %q: %w", v, a.Alias, err)
+		}
+	}
+	return nil
+}
+
+// activeLabelAliases returns the aliases whose deprecation window has not
+// ended in the given NFD version. All aliases are active if the version is
+// not known, e.g. in development builds.
+func activeLabelAliases(aliases []LabelAlias, nfdVersion string) []LabelAlias {
+	cur, err := version.ParseGeneric(nfdVersion)
+	if err != nil {
+		return aliases
+	}
+	active := make([]LabelAlias, 0, len(aliases))
+	for _, a := range aliases {
+		if a.RemovedIn != "" {
+			if removedIn, err := version.ParseGeneric(a.RemovedIn); err == nil && cur.AtLeast(removedIn) {
+				continue
+			}
+		}
+		active = append(active, a)
+	}
+	return active
+}
+
+// addLabelAliases adds the aliases of the labels present in labels. Labels
+// explicitly set under the alias name take precedence.
+func addLabelAliases(labels Labels, aliases []LabelAlias) Labels {
+	for _, a := range aliases {
+		value, ok := labels[a.Name]
+		if !ok {
+			continue
+		}
+		if _, ok := labels[a.Alias]; ok {
+			continue
+		}
+		klog.V(4).InfoS("publishing deprecated label alias", "labelKey", a.Name, "alias", a.Alias)
+		labels[a.Alias] = value
+	}
+	return labels
+}
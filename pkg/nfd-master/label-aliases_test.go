@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestLabelAliases(t *testing.T) {
+	Convey("When handling label aliases", t, func() {
+		name := nfdv1alpha1.FeatureLabelNs + "/new"
+		alias := nfdv1alpha1.FeatureLabelNs + "/old"
+		aliases := []LabelAlias{
+			{Name: name, Alias: alias, DeprecatedIn: "v0.16.0", RemovedIn: "v0.18.0"},
+			{Name: nfdv1alpha1.FeatureLabelNs + "/foo", Alias: nfdv1alpha1.FeatureLabelNs + "/bar", DeprecatedIn: "v0.16.0"},
+		}
+
+		Convey("Built-in aliases should be valid", func() {
+			for i := range builtinLabelAliases {
+				So(builtinLabelAliases[i].validate(), ShouldBeNil)
+			}
+		})
+		Convey("Invalid aliases should be rejected", func() {
+			So((&LabelAlias{Name: name, Alias: name}).validate(), ShouldNotBeNil)
+			So((&LabelAlias{Name: name, Alias: "invalid label"}).validate(), ShouldNotBeNil)
+			So((&LabelAlias{Name: name, Alias: alias, RemovedIn: "next"}).validate(), ShouldNotBeNil)
+		})
+		Convey("Aliases should be active until their removal version", func() {
+			So(activeLabelAliases(aliases, "v0.17.1"), ShouldResemble, aliases)
+			So(activeLabelAliases(aliases, "v0.18.0"), ShouldResemble, aliases[1:])
+			So(activeLabelAliases(aliases, "undefined"), ShouldResemble, aliases)
+		})
+		Convey("Aliases of present labels should be added", func() {
+			labels := addLabelAliases(Labels{name: "true"}, aliases)
+			So(labels, ShouldResemble, Labels{name: "true", alias: "true"})
+		})
+		Convey("Explicitly set aliases should not be overridden", func() {
+			labels := addLabelAliases(Labels{name: "true", alias: "false"}, aliases)
+			So(labels, ShouldResemble, Labels{name: "true", alias: "false"})
+		})
+	})
+}
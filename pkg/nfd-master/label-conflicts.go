@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nfdFieldManager is the field manager of the node updates made by
+	// nfd-master
+	nfdFieldManager = "nfd-master"
+
+	labelConflictInitialBackoff = time.Minute
+	labelConflictMaxBackoff     = time.Hour
+)
+
+// labelConflicts tracks the nodes where other controllers overwrite labels
+// managed by nfd-master. Instead of fighting over the labels on every update
+// nfd-master backs off exponentially.
+type labelConflicts struct {
+	sync.Mutex
+	nodes map[string]*labelConflictState
+}
+
+type labelConflictState struct {
+	// count is the number of consecutive conflicts
+	count int
+	// retryAt is the time after which nfd-master overwrites the
+	// conflicting labels again
+	retryAt time.Time
+}
+
+// backoff returns the time to wait before overwriting conflicting labels
+// again.
+func (s *labelConflictState) backoff() time.Duration {
+	if s.count > 6 {
+		return labelConflictMaxBackoff
+	}
+	return min(labelConflictInitialBackoff<<(s.count-1), labelConflictMaxBackoff)
+}
+
+// conflictingLabelManagers returns the labels managed by nfd-master whose
+// value on the node differs from the desired one and that were last written
+// by another field manager, mapped to the name of that manager.
+func conflictingLabelManagers(node *corev1.Node, owned []string, labels Labels) map[string]string {
+	changed := []string{}
+	for _, name := range owned {
+		cur, ok := node.Labels[name]
+		if !ok {
+			continue
+		}
+		if val, ok := labels[name]; ok && val != cur {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	conflicts := map[string]string{}
+	writtenAt := map[string]time.Time{}
+	for _, entry := range node.ManagedFields {
+		if entry.Manager == nfdFieldManager || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Metadata struct {
+				Labels map[string]json.RawMessage `json:"f:labels"`
+			} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			klog.V(2).InfoS("failed to parse managed fields", "nodeName", node.Name, "manager", entry.Manager, "err", err)
+			continue
+		}
+		var ts time.Time
+		if entry.Time != nil {
+			ts = entry.Time.Time
+		}
+		for _, name := range changed {
+			if _, ok := fields.Metadata.Labels["f:"+name]; !ok {
+				continue
+			}
+			if prev, ok := writtenAt[name]; !ok || ts.After(prev) {
+				conflicts[name] = entry.Manager
+				writtenAt[name] = ts
+			}
+		}
+	}
+	return conflicts
+}
+
+// resolveLabelConflicts detects labels managed by nfd-master that have been
+// overwritten by other controllers. The labels are overwritten again with an
+// exponential backoff, in between the current values on the node are left
+// in place. Returns the labels to set on the node.
+func (m *nfdMaster) resolveLabelConflicts(node *corev1.Node, owned []string, labels Labels) Labels {
+	conflicts := conflictingLabelManagers(node, owned, labels)
+	now := time.Now()
+
+	m.labelConflicts.Lock()
+	defer m.labelConflicts.Unlock()
+
+	state := m.labelConflicts.nodes[node.Name]
+	if len(conflicts) == 0 {
+		// Forget the conflict once the labels have stayed intact for the
+		// duration of the last backoff
+		if state != nil && now.After(state.retryAt.Add(state.backoff())) {
+			delete(m.labelConflicts.nodes, node.Name)
+		}
+		return labels
+	}
+
+	names := keysOf(conflicts)
+	sort.Strings(names)
+
+	if state != nil && now.Before(state.retryAt) {
+		klog.V(2).InfoS("backing off from overwriting conflicting labels", "nodeName", node.Name, "labels", names, "retryAt", state.retryAt)
+		out := maps.Clone(labels)
+		for _, name := range names {
+			out[name] = node.Labels[name]
+		}
+		if m.nodeUpdaterPool != nil && m.nodeUpdaterPool.queue != nil {
+			m.nodeUpdaterPool.queue.AddAfter(node.Name, state.retryAt.Sub(now))
+		}
+		return out
+	}
+
+	if state == nil {
+		state = &labelConflictState{}
+		if m.labelConflicts.nodes == nil {
+			m.labelConflicts.nodes = map[string]*labelConflictState{}
+		}
+		m.labelConflicts.nodes[node.Name] = state
+	}
+	state.count++
+	backoff := state.backoff()
+	state.retryAt = now.Add(backoff)
+
+	managers := map[string]struct{}{}
+	for _, name := range names {
+		labelConflictsTotal.WithLabelValues(conflicts[name]).Inc()
+		managers[conflicts[name]] = struct{}{}
+	}
+	managerNames := keysOf(managers)
+	sort.Strings(managerNames)
+
+	klog.InfoS("labels managed by nfd-master overwritten by another controller", "nodeName", node.Name, "labels", names, "managers", managerNames, "backoff", backoff)
+	if m.recorder != nil {
+		m.recorder.Eventf(node, corev1.EventTypeWarning, "LabelConflict",
+			"Labels %s managed by nfd-master were overwritten by %s, backing off for %s before overwriting them again",
+			strings.Join(names, ", "), strings.Join(managerNames, ", "), backoff)
+	}
+	return labels
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestResolveLabelConflicts(t *testing.T) {
+	Convey("When resolving label conflicts", t, func() {
+		label := nfdv1alpha1.FeatureLabelNs + "/foo"
+		otherLabel := nfdv1alpha1.FeatureLabelNs + "/bar"
+
+		node := newTestNode()
+		node.Labels[label] = "external"
+		node.Labels[otherLabel] = "true"
+		node.ManagedFields = []metav1.ManagedFieldsEntry{
+			{
+				Manager:  nfdFieldManager,
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:` + otherLabel + `":{}}}}`)},
+			},
+			{
+				Manager:  "other-controller",
+				Time:     &metav1.Time{Time: time.Now()},
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:` + label + `":{}}}}`)},
+			},
+		}
+		owned := []string{label, otherLabel}
+		labels := Labels{label: "nfd", otherLabel: "false"}
+
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		recorder := record.NewFakeRecorder(10)
+		fakeMaster.recorder = recorder
+
+		Convey("Labels written by another field manager should be detected", func() {
+			So(conflictingLabelManagers(node, owned, labels), ShouldResemble, map[string]string{label: "other-controller"})
+		})
+		Convey("Labels not owned by nfd-master should not conflict", func() {
+			So(conflictingLabelManagers(node, []string{otherLabel}, labels), ShouldBeEmpty)
+		})
+		Convey("Labels with the desired value should not conflict", func() {
+			So(conflictingLabelManagers(node, owned, Labels{label: "external"}), ShouldBeEmpty)
+		})
+		Convey("The first conflict should be overwritten and reported", func() {
+			So(fakeMaster.resolveLabelConflicts(node, owned, labels), ShouldResemble, labels)
+			So(recorder.Events, ShouldHaveLength, 1)
+			So(<-recorder.Events, ShouldContainSubstring, "other-controller")
+			So(fakeMaster.labelConflicts.nodes[testNodeName].count, ShouldEqual, 1)
+
+			Convey("Repeated conflicts should be backed off", func() {
+				out := fakeMaster.resolveLabelConflicts(node, owned, labels)
+				So(out, ShouldResemble, Labels{label: "external", otherLabel: "false"})
+				So(recorder.Events, ShouldBeEmpty)
+			})
+			Convey("Conflicts should be overwritten again after the backoff", func() {
+				fakeMaster.labelConflicts.nodes[testNodeName].retryAt = time.Now().Add(-time.Second)
+				So(fakeMaster.resolveLabelConflicts(node, owned, labels), ShouldResemble, labels)
+				So(recorder.Events, ShouldHaveLength, 1)
+				So(fakeMaster.labelConflicts.nodes[testNodeName].count, ShouldEqual, 2)
+				So(fakeMaster.labelConflicts.nodes[testNodeName].backoff(), ShouldEqual, 2*labelConflictInitialBackoff)
+			})
+			Convey("The conflict should be forgotten once labels stay intact", func() {
+				node.Labels[label] = "nfd"
+				fakeMaster.labelConflicts.nodes[testNodeName].retryAt = time.Now().Add(-time.Hour)
+				So(fakeMaster.resolveLabelConflicts(node, owned, labels), ShouldResemble, labels)
+				So(fakeMaster.labelConflicts.nodes, ShouldNotContainKey, testNodeName)
+			})
+		})
+	})
+}
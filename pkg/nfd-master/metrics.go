@@ -19,19 +19,35 @@ package nfdmaster
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/openshift/node-feature-discovery/pkg/version"
+	"k8s.io/client-go/util/workqueue"
 )
 
 // When adding metric names, see https://prometheus.io/docs/practices/naming/#metric-names
 const (
-	buildInfoQuery           = "nfd_master_build_info"
-	nodeUpdateRequestsQuery  = "nfd_node_update_requests_total"
-	nodeUpdatesQuery         = "nfd_node_updates_total"
-	nodeUpdateFailuresQuery  = "nfd_node_update_failures_total"
-	nodeLabelsRejectedQuery  = "nfd_node_labels_rejected_total"
-	nodeERsRejectedQuery     = "nfd_node_extendedresources_rejected_total"
-	nodeTaintsRejectedQuery  = "nfd_node_taints_rejected_total"
-	nfrProcessingTimeQuery   = "nfd_nodefeaturerule_processing_duration_seconds"
-	nfrProcessingErrorsQuery = "nfd_nodefeaturerule_processing_errors_total"
+	buildInfoQuery                    = "nfd_master_build_info"
+	nodeUpdateRequestsQuery           = "nfd_node_update_requests_total"
+	nodeUpdatesQuery                  = "nfd_node_updates_total"
+	nodeUpdateFailuresQuery           = "nfd_node_update_failures_total"
+	nodeLabelsRejectedQuery           = "nfd_node_labels_rejected_total"
+	nodeERsRejectedQuery              = "nfd_node_extendedresources_rejected_total"
+	nodeTaintsRejectedQuery           = "nfd_node_taints_rejected_total"
+	nfrProcessingTimeQuery            = "nfd_nodefeaturerule_processing_duration_seconds"
+	nfrProcessingErrorsQuery          = "nfd_nodefeaturerule_processing_errors_total"
+	nodeFeatureConflictsQuery         = "nfd_nodefeature_conflicts_total"
+	nodeFeaturesRejectedQuery         = "nfd_nodefeatures_rejected_total"
+	nodeFeatureSignatureFailuresQuery = "nfd_nodefeature_signature_failures_total"
+	ruleEvaluationCacheHitsQuery      = "nfd_rule_evaluation_cache_hits_total"
+	ruleEvaluationCacheMissesQuery    = "nfd_rule_evaluation_cache_misses_total"
+	ruleEvaluationWaitTimeQuery       = "nfd_rule_evaluation_wait_duration_seconds"
+	nodeUpdateQueueTimeQuery          = "nfd_node_update_queue_duration_seconds"
+	nodeUpdateTimeQuery               = "nfd_node_update_duration_seconds"
+	leaderStatusQuery                 = "nfd_master_leader"
+	exportedRecordsQuery              = "nfd_master_exported_records_total"
+	exportErrorsQuery                 = "nfd_master_export_errors_total"
+	imageCompatReviewsQuery           = "nfd_image_compatibility_admission_reviews_total"
+	extendedResourcesZeroedQuery      = "nfd_node_extendedresources_zeroed_total"
+	labelConflictsQuery               = "nfd_node_label_conflicts_total"
+	featureSchemaViolationsQuery      = "nfd_feature_schema_violations_total"
 )
 
 var (
@@ -77,12 +93,119 @@ var (
 			"node",
 		},
 	)
-	nfrProcessingErrors = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: nfrProcessingErrorsQuery,
-		Help: "Number of errors encountered while processing NodeFeatureRule objects.",
+	nfrProcessingErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: nfrProcessingErrorsQuery,
+			Help: "Number of errors encountered while processing NodeFeatureRule objects, by error code.",
+		},
+		[]string{
+			"code",
+		},
+	)
+	nodeFeatureConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: nodeFeatureConflictsQuery,
+		Help: "Number of conflicting labels and attributes found when merging the NodeFeature objects of a node.",
+	})
+	nodeFeaturesRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: nodeFeaturesRejectedQuery,
+		Help: "Number of NodeFeature objects ignored because they were not from an allowed producer.",
+	})
+	nodeFeatureSignatureFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: nodeFeatureSignatureFailuresQuery,
+		Help: "Number of NodeFeature objects ignored because of a missing or invalid signature.",
+	})
+	ruleEvaluationCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: ruleEvaluationCacheHitsQuery,
+		Help: "Number of node updates that used the cached output of rule evaluation.",
+	})
+	ruleEvaluationCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: ruleEvaluationCacheMissesQuery,
+		Help: "Number of node updates that required the rules to be evaluated.",
 	})
+	ruleEvaluationWaitTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    ruleEvaluationWaitTimeQuery,
+		Help:    "Time spent waiting for a free slot for evaluating the rules of a node.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+	nodeUpdateQueueTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    nodeUpdateQueueTimeQuery,
+		Help:    "Time node update requests spend in the queue before being processed.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+	nodeUpdateTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    nodeUpdateTimeQuery,
+		Help:    "Time processing node update requests.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+	leaderStatus = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: leaderStatusQuery,
+		Help: "Whether this nfd-master instance is the leader (1) or not (0).",
+	})
+	exportedRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: exportedRecordsQuery,
+		Help: "Number of node feature records exported, by sink.",
+	}, []string{"sink"})
+	exportErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: exportErrorsQuery,
+		Help: "Number of node feature records that failed to be exported, by sink.",
+	}, []string{"sink"})
+	extendedResourcesZeroed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: extendedResourcesZeroedQuery,
+		Help: "Number of extended resources zeroed by nfd-master for the grace period before removal.",
+	})
+	labelConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: labelConflictsQuery,
+		Help: "Number of labels managed by nfd-master found overwritten by another field manager.",
+	}, []string{"manager"})
+	featureSchemaViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: featureSchemaViolationsQuery,
+		Help: "Number of feature schema violations found in NodeFeature objects processed by nfd-master.",
+	}, []string{"reason"})
+	imageCompatReviews = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: imageCompatReviewsQuery,
+		Help: "Number of pod admission reviews by the image compatibility webhook, by result.",
+	}, []string{"result"})
 )
 
+// nodeUpdateQueueMetricsProvider provides the metrics of the node update
+// queue. Only the queue and processing times are recorded.
+type nodeUpdateQueueMetricsProvider struct{}
+
+func (nodeUpdateQueueMetricsProvider) NewDepthMetric(string) workqueue.GaugeMetric {
+	return noopQueueMetric{}
+}
+
+func (nodeUpdateQueueMetricsProvider) NewAddsMetric(string) workqueue.CounterMetric {
+	return noopQueueMetric{}
+}
+
+func (nodeUpdateQueueMetricsProvider) NewLatencyMetric(string) workqueue.HistogramMetric {
+	return nodeUpdateQueueTime
+}
+
+func (nodeUpdateQueueMetricsProvider) NewWorkDurationMetric(string) workqueue.HistogramMetric {
+	return nodeUpdateTime
+}
+
+func (nodeUpdateQueueMetricsProvider) NewUnfinishedWorkSecondsMetric(string) workqueue.SettableGaugeMetric {
+	return noopQueueMetric{}
+}
+
+func (nodeUpdateQueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(string) workqueue.SettableGaugeMetric {
+	return noopQueueMetric{}
+}
+
+func (nodeUpdateQueueMetricsProvider) NewRetriesMetric(string) workqueue.CounterMetric {
+	return noopQueueMetric{}
+}
+
+type noopQueueMetric struct{}
+
+func (noopQueueMetric) Inc()            {}
+func (noopQueueMetric) Dec()            {}
+func (noopQueueMetric) Set(float64)     {}
+func (noopQueueMetric) Observe(float64) {}
+
 // registerVersion exposes the Operator build version.
 func registerVersion(version string) {
 	buildInfo.SetToCurrentTime()
@@ -18,10 +18,15 @@ package nfdmaster
 
 import (
 	"fmt"
-	"time"
+	"maps"
+	"slices"
 
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -35,18 +40,41 @@ import (
 )
 
 type nfdController struct {
-	featureLister nfdlisters.NodeFeatureLister
-	ruleLister    nfdlisters.NodeFeatureRuleLister
+	featureLister        nfdlisters.NodeFeatureLister
+	ruleLister           nfdlisters.NodeFeatureRuleLister
+	namespacedRuleLister nfdlisters.NamespacedNodeFeatureRuleLister
+	cacheSynced          []cache.InformerSynced
+
+	nfdClient      nfdclientset.Interface
+	featureSummary *featureSummary
 
 	stopChan chan struct{}
 
 	updateAllNodesChan chan struct{}
 	updateOneNodeChan  chan string
+	// ruleTestsChan signals changes in NodeFeatureRule objects that may
+	// require running the tests of the rules.
+	ruleTestsChan chan struct{}
 }
 
 type nfdApiControllerOptions struct {
 	DisableNodeFeature bool
-	ResyncPeriod       time.Duration
+	// ForceResyncAnnotation is the node annotation that triggers immediate
+	// re-evaluation of the node. Nodes are not watched if empty.
+	ForceResyncAnnotation string
+	// ResyncNode is called for nodes that have the force-resync annotation.
+	// It must not block the node informer.
+	ResyncNode func(nodeName string)
+	// EnableNamespacedRules enables watching NamespacedNodeFeatureRule
+	// objects.
+	EnableNamespacedRules bool
+	// FeatureSummaryLabels is the ownership record of the feature labels to
+	// aggregate into the cluster feature summary. The summary is not
+	// maintained if nil.
+	FeatureSummaryLabels *ownershipRecord
+	// Informers configures the selectors, resync period and transforms of
+	// the informers.
+	Informers *InformersConfig
 }
 
 func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiControllerOptions) (*nfdController, error) {
@@ -54,16 +82,33 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 		stopChan:           make(chan struct{}, 1),
 		updateAllNodesChan: make(chan struct{}, 1),
 		updateOneNodeChan:  make(chan string),
+		ruleTestsChan:      make(chan struct{}, 1),
 	}
 
 	nfdClient := nfdclientset.NewForConfigOrDie(config)
+	c.nfdClient = nfdClient
 	klog.V(2).InfoS("initializing new NFD API controller", "options", utils.DelayedDumper(nfdApiControllerOptions))
 
-	informerFactory := nfdinformers.NewSharedInformerFactory(nfdClient, nfdApiControllerOptions.ResyncPeriod)
+	informersConfig := nfdApiControllerOptions.Informers
+	if informersConfig == nil {
+		informersConfig = &InformersConfig{}
+	}
+
+	// Periodic resync of nodes is handled by the node updater pool in order
+	// to spread the load, the informers are not resynced by default.
+	informerFactory := nfdinformers.NewSharedInformerFactory(nfdClient, informersConfig.ResyncPeriod.Duration)
 
-	// Add informer for NodeFeature objects
+	// Add informer for NodeFeature objects. A separate factory is used as
+	// the label selector only applies to NodeFeature objects.
 	if !nfdApiControllerOptions.DisableNodeFeature {
-		featureInformer := informerFactory.Nfd().V1alpha1().NodeFeatures()
+		featureInformerFactory := nfdinformers.NewSharedInformerFactoryWithOptions(nfdClient, informersConfig.ResyncPeriod.Duration,
+			nfdinformers.WithTweakListOptions(informersConfig.tweakNodeFeatureListOptions))
+		featureInformer := featureInformerFactory.Nfd().V1alpha1().NodeFeatures()
+		// Deduplicate the feature names shared by all nodes in order to
+		// keep the memory footprint small in big clusters
+		if err := featureInformer.Informer().SetTransform(newStringPool().compactNodeFeature); err != nil {
+			return nil, err
+		}
 		if _, err := featureInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				nfr := obj.(*nfdv1alpha1.NodeFeature)
@@ -72,6 +117,11 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 			},
 			UpdateFunc: func(oldObj, newObj interface{}) {
 				nfr := newObj.(*nfdv1alpha1.NodeFeature)
+				// Status is written by nfd-master itself after processing
+				if nodeFeatureStatusOnlyUpdate(oldObj.(*nfdv1alpha1.NodeFeature), nfr) {
+					klog.V(4).InfoS("NodeFeature status updated, ignoring", "nodefeature", klog.KObj(nfr))
+					return
+				}
 				klog.V(2).InfoS("NodeFeature updated", "nodefeature", klog.KObj(nfr))
 				c.updateOneNode("NodeFeature", nfr)
 			},
@@ -84,36 +134,50 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 			return nil, err
 		}
 		c.featureLister = featureInformer.Lister()
+		c.cacheSynced = append(c.cacheSynced, featureInformer.Informer().HasSynced)
+		featureInformerFactory.Start(c.stopChan)
+	} else {
+		// Node updates are not triggered by the node informer in gRPC mode
+		nfdApiControllerOptions.ForceResyncAnnotation = ""
+	}
+
+	// Add informer for Node objects
+	if nfdApiControllerOptions.FeatureSummaryLabels != nil {
+		c.featureSummary = newFeatureSummary(*nfdApiControllerOptions.FeatureSummaryLabels)
+	}
+	if nfdApiControllerOptions.ForceResyncAnnotation != "" || c.featureSummary != nil {
+		if err := c.watchNodes(kubernetes.NewForConfigOrDie(config), nfdApiControllerOptions.ForceResyncAnnotation, nfdApiControllerOptions.ResyncNode, informersConfig); err != nil {
+			return nil, err
+		}
 	}
 
 	// Add informer for NodeFeatureRule objects
 	ruleInformer := informerFactory.Nfd().V1alpha1().NodeFeatureRules()
-	if _, err := ruleInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(object interface{}) {
-			klog.V(2).InfoS("NodeFeatureRule added", "nodefeaturerule", klog.KObj(object.(metav1.Object)))
-			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
-			}
-			// else: rules will be processed only when gRPC requests are received
-		},
-		UpdateFunc: func(oldObject, newObject interface{}) {
-			klog.V(2).InfoS("NodeFeatureRule updated", "nodefeaturerule", klog.KObj(newObject.(metav1.Object)))
-			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
-			}
-			// else: rules will be processed only when gRPC requests are received
-		},
-		DeleteFunc: func(object interface{}) {
-			klog.V(2).InfoS("NodeFeatureRule deleted", "nodefeaturerule", klog.KObj(object.(metav1.Object)))
-			if !nfdApiControllerOptions.DisableNodeFeature {
-				c.updateAllNodes()
-			}
-			// else: rules will be processed only when gRPC requests are received
-		},
-	}); err != nil {
+	if informersConfig.StripManagedFields {
+		if err := ruleInformer.Informer().SetTransform(stripManagedFields); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := ruleInformer.Informer().AddEventHandler(c.ruleEventHandler("NodeFeatureRule", nfdApiControllerOptions.DisableNodeFeature)); err != nil {
 		return nil, err
 	}
 	c.ruleLister = ruleInformer.Lister()
+	c.cacheSynced = append(c.cacheSynced, ruleInformer.Informer().HasSynced)
+
+	// Add informer for NamespacedNodeFeatureRule objects
+	if nfdApiControllerOptions.EnableNamespacedRules {
+		namespacedRuleInformer := informerFactory.Nfd().V1alpha1().NamespacedNodeFeatureRules()
+		if informersConfig.StripManagedFields {
+			if err := namespacedRuleInformer.Informer().SetTransform(stripManagedFields); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := namespacedRuleInformer.Informer().AddEventHandler(c.ruleEventHandler("NamespacedNodeFeatureRule", nfdApiControllerOptions.DisableNodeFeature)); err != nil {
+			return nil, err
+		}
+		c.namespacedRuleLister = namespacedRuleInformer.Lister()
+		c.cacheSynced = append(c.cacheSynced, namespacedRuleInformer.Informer().HasSynced)
+	}
 
 	// Start informers
 	informerFactory.Start(c.stopChan)
@@ -122,6 +186,52 @@ func newNfdController(config *restclient.Config, nfdApiControllerOptions nfdApiC
 	return c, nil
 }
 
+// nodeFeatureStatusOnlyUpdate returns true if nothing but the status of a
+// NodeFeature object was updated. Periodic resyncs are not status updates.
+func nodeFeatureStatusOnlyUpdate(oldObj, newObj *nfdv1alpha1.NodeFeature) bool {
+	return oldObj.ResourceVersion != newObj.ResourceVersion &&
+		oldObj.Generation == newObj.Generation &&
+		!apiequality.Semantic.DeepEqual(oldObj.Status, newObj.Status) &&
+		maps.Equal(oldObj.Labels, newObj.Labels) &&
+		maps.Equal(oldObj.Annotations, newObj.Annotations) &&
+		slices.Equal(oldObj.Finalizers, newObj.Finalizers) &&
+		apiequality.Semantic.DeepEqual(oldObj.OwnerReferences, newObj.OwnerReferences) &&
+		oldObj.DeletionTimestamp.Equal(newObj.DeletionTimestamp)
+}
+
+// ruleEventHandler returns an event handler for rule objects of the given
+// kind, triggering an update of all nodes on any change. Updates of the
+// status of NodeFeatureRule objects are ignored.
+func (c *nfdController) ruleEventHandler(kind string, disableNodeFeature bool) cache.ResourceEventHandlerFuncs {
+	handle := func(event string, obj interface{}) {
+		klog.V(2).InfoS(kind+" "+event, "nodefeaturerule", klog.KObj(obj.(metav1.Object)))
+		if _, ok := obj.(*nfdv1alpha1.NodeFeatureRule); ok && event != "deleted" {
+			c.runRuleTests()
+		}
+		if !disableNodeFeature {
+			c.updateAllNodes()
+		}
+		// else: rules will be processed only when gRPC requests are received
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { handle("added", obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldNfr, ok := oldObj.(*nfdv1alpha1.NodeFeatureRule); ok && oldNfr.Generation == newObj.(*nfdv1alpha1.NodeFeatureRule).Generation {
+				klog.V(4).InfoS(kind+" status updated", "nodefeaturerule", klog.KObj(oldNfr))
+				return
+			}
+			handle("updated", newObj)
+		},
+		DeleteFunc: func(obj interface{}) { handle("deleted", obj) },
+	}
+}
+
+// waitForCacheSync blocks until all informer caches have been synced or the
+// controller is stopped. Returns false if the caches could not be synced.
+func (c *nfdController) waitForCacheSync() bool {
+	return cache.WaitForCacheSync(c.stopChan, c.cacheSynced...)
+}
+
 func (c *nfdController) stop() {
 	close(c.stopChan)
 }
@@ -135,6 +245,84 @@ func (c *nfdController) updateOneNode(typ string, obj metav1.Object) {
 	c.updateOneNodeChan <- nodeName
 }
 
+// watchNodes starts a node informer that triggers an update of nodes that
+// have the force-resync annotation set and feeds the cluster feature summary.
+func (c *nfdController) watchNodes(cli kubernetes.Interface, forceResyncAnnotation string, resyncNode func(string), informersConfig *InformersConfig) error {
+	informerFactory := k8sinformers.NewSharedInformerFactoryWithOptions(cli, 0,
+		k8sinformers.WithTweakListOptions(informersConfig.tweakNodeListOptions))
+	nodeInformer := informerFactory.Core().V1().Nodes().Informer()
+
+	// Only the name, labels and annotations of the nodes are needed, drop
+	// everything else in order to keep the memory footprint small in big
+	// clusters.
+	if err := nodeInformer.SetTransform(func(obj interface{}) (interface{}, error) {
+		if node, ok := obj.(*corev1.Node); ok {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            node.Name,
+					ResourceVersion: node.ResourceVersion,
+					Labels:          node.Labels,
+					Annotations:     node.Annotations,
+				},
+			}, nil
+		}
+		return obj, nil
+	}); err != nil {
+		return err
+	}
+
+	handler := func(obj interface{}) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return
+		}
+		if c.featureSummary != nil {
+			c.featureSummary.updateNode(node)
+		}
+		if forceResyncAnnotation == "" || resyncNode == nil {
+			return
+		}
+		if _, ok := node.Annotations[forceResyncAnnotation]; ok {
+			klog.V(2).InfoS("node resync requested", "nodeName", node.Name)
+			resyncNode(node.Name)
+		}
+	}
+	if _, err := nodeInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    handler,
+		UpdateFunc: func(oldObj, newObj interface{}) { handler(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if node, ok := obj.(*corev1.Node); ok && c.featureSummary != nil {
+				c.featureSummary.removeNode(node.Name)
+			}
+		},
+	}); err != nil {
+		return err
+	}
+	c.cacheSynced = append(c.cacheSynced, nodeInformer.HasSynced)
+
+	informerFactory.Start(c.stopChan)
+	return nil
+}
+
+// syncFeatureSummary writes the cluster feature summary if it has changed
+// since the previous sync.
+func (c *nfdController) syncFeatureSummary(name string) {
+	if c.featureSummary == nil {
+		return
+	}
+	status, changed := c.featureSummary.status()
+	if !changed {
+		return
+	}
+	if err := writeFeatureSummary(c.nfdClient, name, status); err != nil {
+		klog.ErrorS(err, "failed to write cluster feature summary")
+		c.featureSummary.markDirty()
+	}
+}
+
 func getNodeNameForObj(obj metav1.Object) (string, error) {
 	nodeName, ok := obj.GetLabels()[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
 	if !ok {
@@ -146,6 +334,13 @@ func getNodeNameForObj(obj metav1.Object) (string, error) {
 	return nodeName, nil
 }
 
+func (c *nfdController) runRuleTests() {
+	select {
+	case c.ruleTestsChan <- struct{}{}:
+	default:
+	}
+}
+
 func (c *nfdController) updateAllNodes() {
 	select {
 	case c.updateAllNodesChan <- struct{}{}:
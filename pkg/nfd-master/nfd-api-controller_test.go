@@ -42,3 +42,26 @@ func TestGetNodeNameForObj(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, n, "node-1")
 }
+
+func TestNodeFeatureStatusOnlyUpdate(t *testing.T) {
+	oldObj := &nfdv1alpha1.NodeFeature{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar", ResourceVersion: "1"}}
+
+	// Test resync
+	assert.False(t, nodeFeatureStatusOnlyUpdate(oldObj, oldObj.DeepCopy()))
+
+	// Test status update
+	newObj := oldObj.DeepCopy()
+	newObj.ResourceVersion = "2"
+	newObj.Status.ObservedGeneration = 1
+	assert.True(t, nodeFeatureStatusOnlyUpdate(oldObj, newObj))
+
+	// Test status and metadata update
+	newObj.Annotations = map[string]string{"foo": "bar"}
+	assert.False(t, nodeFeatureStatusOnlyUpdate(oldObj, newObj))
+
+	// Test spec update
+	newObj = oldObj.DeepCopy()
+	newObj.ResourceVersion = "2"
+	newObj.Generation = 1
+	assert.False(t, nodeFeatureStatusOnlyUpdate(oldObj, newObj))
+}
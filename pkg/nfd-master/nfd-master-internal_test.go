@@ -17,9 +17,12 @@ limitations under the License.
 package nfdmaster
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -29,6 +32,7 @@ import (
 	"time"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
 	nfdscheme "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/scheme"
 	nfdinformers "github.com/openshift/node-feature-discovery/pkg/generated/informers/externalversions"
@@ -68,6 +72,8 @@ func newFakeNfdAPIController(client *fakenfdclient.Clientset) *nfdController {
 		stopChan:           make(chan struct{}, 1),
 		updateAllNodesChan: make(chan struct{}, 1),
 		updateOneNodeChan:  make(chan string),
+		ruleTestsChan:      make(chan struct{}, 1),
+		nfdClient:          client,
 	}
 
 	informerFactory := nfdinformers.NewSharedInformerFactory(client, 1*time.Hour)
@@ -82,6 +88,7 @@ func newFakeNfdAPIController(client *fakenfdclient.Clientset) *nfdController {
 		return nil
 	}
 	c.featureLister = featureInformer.Lister()
+	c.cacheSynced = append(c.cacheSynced, featureInformer.Informer().HasSynced)
 
 	// Add informer for NodeFeatureRule objects
 	ruleInformer := informerFactory.Nfd().V1alpha1().NodeFeatureRules()
@@ -93,6 +100,7 @@ func newFakeNfdAPIController(client *fakenfdclient.Clientset) *nfdController {
 		return nil
 	}
 	c.ruleLister = ruleInformer.Lister()
+	c.cacheSynced = append(c.cacheSynced, ruleInformer.Informer().HasSynced)
 
 	// Start informers
 	informerFactory.Start(c.stopChan)
@@ -148,13 +156,15 @@ func TestUpdateNodeObject(t *testing.T) {
 		testNode := newTestNode()
 		testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/old-feature"] = "old-value"
 		testNode.Annotations[nfdv1alpha1.AnnotationNs+"/feature-labels"] = "old-feature"
+		// Force-resync annotation should get removed
+		testNode.Annotations[nfdv1alpha1.ForceResyncAnnotation] = ""
 
 		// Create fake api client and initialize NfdMaster instance
 		fakeCli := fakeclient.NewSimpleClientset(testNode)
 		fakeMaster := newFakeMaster(fakeCli)
 
 		Convey("When I successfully update the node with feature labels", func() {
-			err := fakeMaster.updateNodeObject(testNodeName, featureLabels, featureAnnotations, featureExtResources, nil)
+			err := fakeMaster.updateNodeObject(testNodeName, featureLabels, featureAnnotations, featureExtResources, nil, nil, nil)
 			Convey("Error is nil", func() {
 				So(err, ShouldBeNil)
 			})
@@ -183,7 +193,7 @@ func TestUpdateNodeObject(t *testing.T) {
 		})
 
 		Convey("When I fail to get a node while updating feature labels", func() {
-			err := fakeMaster.updateNodeObject("non-existent-node", featureLabels, featureAnnotations, featureExtResources, nil)
+			err := fakeMaster.updateNodeObject("non-existent-node", featureLabels, featureAnnotations, featureExtResources, nil, nil, nil)
 
 			Convey("Error is produced", func() {
 				So(err, ShouldBeError)
@@ -194,7 +204,7 @@ func TestUpdateNodeObject(t *testing.T) {
 			fakeCli.CoreV1().(*fakecorev1client.FakeCoreV1).PrependReactor("patch", "nodes", func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
 				return true, &v1.Node{}, errors.New("Fake error when patching node")
 			})
-			err := fakeMaster.updateNodeObject(testNodeName, nil, featureAnnotations, ExtendedResources{"": ""}, nil)
+			err := fakeMaster.updateNodeObject(testNodeName, nil, featureAnnotations, ExtendedResources{"": ""}, nil, nil, nil)
 
 			Convey("Error is produced", func() {
 				So(err, ShouldBeError)
@@ -204,6 +214,127 @@ func TestUpdateNodeObject(t *testing.T) {
 	})
 }
 
+func TestRefreshNodeFeatures(t *testing.T) {
+	Convey("When refreshing node features", t, func() {
+		featureLabels := map[string]string{nfdv1alpha1.FeatureLabelNs + "/feature-1": "true"}
+		testNode := newTestNode()
+		// JSON patches can't add keys to labels/annotations that are missing
+		testNode.Labels["kubernetes.io/hostname"] = testNodeName
+		testNode.Annotations["node.alpha.kubernetes.io/ttl"] = "0"
+
+		Convey("When the node has not opted out", func() {
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			err := fakeMaster.refreshNodeFeatures(testNodeName, featureLabels, nfdv1alpha1.NewFeatures())
+			So(err, ShouldBeNil)
+
+			updatedNode, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(updatedNode.Labels, ShouldContainKey, nfdv1alpha1.FeatureLabelNs+"/feature-1")
+		})
+
+		Convey("When the node has opted out", func() {
+			testNode.Labels[nfdv1alpha1.NodeExcludeLabel] = "true"
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			err := fakeMaster.refreshNodeFeatures(testNodeName, featureLabels, nfdv1alpha1.NewFeatures())
+			So(err, ShouldBeNil)
+
+			updatedNode, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(updatedNode.Labels, ShouldNotContainKey, nfdv1alpha1.FeatureLabelNs+"/feature-1")
+		})
+
+		Convey("When a labeled node opts out", func() {
+			testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/feature-1"] = "true"
+			testNode.Labels[nfdv1alpha1.NodeExcludeLabel] = "true"
+			testNode.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = "feature-1"
+			testNode.Annotations[nfdv1alpha1.NodeTaintsAnnotation] = "example.com/t=:NoSchedule"
+			testNode.Spec.Taints = []corev1.Taint{{Key: "example.com/t", Effect: corev1.TaintEffectNoSchedule}}
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			err := fakeMaster.refreshNodeFeatures(testNodeName, featureLabels, nfdv1alpha1.NewFeatures())
+			So(err, ShouldBeNil)
+
+			updatedNode, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			Convey("NFD-owned labels and taints should be removed", func() {
+				So(updatedNode.Labels, ShouldNotContainKey, nfdv1alpha1.FeatureLabelNs+"/feature-1")
+				So(updatedNode.Labels, ShouldContainKey, nfdv1alpha1.NodeExcludeLabel)
+				So(updatedNode.Annotations, ShouldNotContainKey, nfdv1alpha1.FeatureLabelsAnnotation)
+				So(updatedNode.Annotations, ShouldNotContainKey, nfdv1alpha1.NodeTaintsAnnotation)
+				So(updatedNode.Spec.Taints, ShouldBeEmpty)
+			})
+		})
+	})
+}
+
+func TestSetCordon(t *testing.T) {
+	Convey("When cordoning nodes", t, func() {
+		So(features.Apply(map[string]bool{string(features.NodeCordon): true}), ShouldBeNil)
+		defer func() { So(features.Apply(nil), ShouldBeNil) }()
+
+		testNode := newTestNode()
+		getNode := func(cli k8sclient.Interface) *corev1.Node {
+			node, err := cli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			return node
+		}
+
+		Convey("A matching rule should cordon the node", func() {
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			So(fakeMaster.setCordon([]string{"rule-b/r", "rule-a/r"}, testNodeName), ShouldBeNil)
+
+			node := getNode(fakeCli)
+			So(node.Spec.Unschedulable, ShouldBeTrue)
+			So(node.Annotations[nfdv1alpha1.NodeCordonAnnotation], ShouldEqual, "rule-a/r,rule-b/r")
+
+			Convey("and the node should be uncordoned when no rule matches", func() {
+				So(fakeMaster.setCordon(nil, testNodeName), ShouldBeNil)
+
+				node := getNode(fakeCli)
+				So(node.Spec.Unschedulable, ShouldBeFalse)
+				So(node.Annotations, ShouldNotContainKey, nfdv1alpha1.NodeCordonAnnotation)
+			})
+		})
+
+		Convey("The annotation should be prefixed with the instance name", func() {
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			fakeMaster.args.Instance = "foo"
+			So(fakeMaster.setCordon([]string{"rule-a/r"}, testNodeName), ShouldBeNil)
+
+			node := getNode(fakeCli)
+			So(node.Spec.Unschedulable, ShouldBeTrue)
+			So(node.Annotations["foo."+nfdv1alpha1.NodeCordonAnnotation], ShouldEqual, "rule-a/r")
+			So(node.Annotations, ShouldNotContainKey, nfdv1alpha1.NodeCordonAnnotation)
+
+			So(fakeMaster.setCordon(nil, testNodeName), ShouldBeNil)
+			So(getNode(fakeCli).Spec.Unschedulable, ShouldBeFalse)
+		})
+
+		Convey("A node cordoned by someone else should not be touched", func() {
+			testNode.Spec.Unschedulable = true
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			So(fakeMaster.setCordon([]string{"rule-a/r"}, testNodeName), ShouldBeNil)
+			So(getNode(fakeCli).Annotations, ShouldNotContainKey, nfdv1alpha1.NodeCordonAnnotation)
+
+			So(fakeMaster.setCordon(nil, testNodeName), ShouldBeNil)
+			So(getNode(fakeCli).Spec.Unschedulable, ShouldBeTrue)
+		})
+
+		Convey("Nodes should not be cordoned if the feature gate is disabled", func() {
+			So(features.Apply(nil), ShouldBeNil)
+			fakeCli := fakeclient.NewSimpleClientset(testNode)
+			fakeMaster := newFakeMaster(fakeCli)
+			So(fakeMaster.setCordon([]string{"rule-a/r"}, testNodeName), ShouldBeNil)
+			So(getNode(fakeCli).Spec.Unschedulable, ShouldBeFalse)
+		})
+	})
+}
+
 func TestUpdateMasterNode(t *testing.T) {
 	Convey("When updating the nfd-master node", t, func() {
 		testNode := newTestNode()
@@ -324,6 +455,16 @@ func TestRemovingExtResources(t *testing.T) {
 			patches := fakeMaster.createExtendedResourcePatches(testNode, resourceLabels)
 			So(len(patches), ShouldBeGreaterThan, 0)
 		})
+		Convey("When the extended resource is only left in allocatable", func() {
+			testNode := newTestNode()
+			testNode.Status.Allocatable = corev1.ResourceList{corev1.ResourceName(nfdv1alpha1.FeatureLabelNs + "/feature-1"): *resource.NewQuantity(1, resource.BinarySI)}
+			testNode.Annotations[nfdv1alpha1.AnnotationNs+"/extended-resources"] = "feature-1"
+			expectedPatches := []utils.JsonPatch{
+				utils.NewJsonPatch("remove", "/status/allocatable", nfdv1alpha1.FeatureLabelNs+"/feature-1", ""),
+			}
+			patches := fakeMaster.createExtendedResourcePatches(testNode, ExtendedResources{})
+			So(patches, ShouldResemble, expectedPatches)
+		})
 	})
 }
 
@@ -622,6 +763,11 @@ leaderElection:
   leaseDuration: 20s
   renewDeadline: 4s
   retryPeriod: 30s
+  leaseName: test-lease
+  leaseNamespace: test-ns
+ruleDelegation:
+  team-a:
+    labelNs: ["team-a.feature.node.kubernetes.io"]
 `)
 		f.Close()
 		So(err, ShouldBeNil)
@@ -640,6 +786,11 @@ leaderElection:
 				So(master.config.LeaderElection.LeaseDuration.Seconds(), ShouldEqual, float64(20))
 				So(master.config.LeaderElection.RenewDeadline.Seconds(), ShouldEqual, float64(4))
 				So(master.config.LeaderElection.RetryPeriod.Seconds(), ShouldEqual, float64(30))
+				So(master.config.LeaderElection.LeaseName, ShouldEqual, "test-lease")
+				So(master.config.LeaderElection.LeaseNamespace, ShouldEqual, "test-ns")
+				So(master.config.RuleDelegation, ShouldResemble, map[string]RuleDelegationPolicy{
+					"team-a": {LabelNs: []string{"team-a.feature.node.kubernetes.io"}},
+				})
 			})
 		})
 
@@ -742,6 +893,150 @@ nfdApiParallelism: 100
 	})
 }
 
+func TestMatchNodes(t *testing.T) {
+	Convey("When querying nodes matching a rule", t, func() {
+		newNodeFeature := func(name, vendor string) *nfdv1alpha1.NodeFeature {
+			nf := &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "nfd",
+					Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: name},
+				},
+				Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+			}
+			nf.Spec.Features.Attributes["system.dmiid"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"sys_vendor": vendor})
+			return nf
+		}
+		newNode := func(name string, labels map[string]string) *corev1.Node {
+			n := newTestNode()
+			n.Name = name
+			n.Labels = labels
+			return n
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(
+			newNode("node-1", map[string]string{"pool": "a"}),
+			newNode("node-2", map[string]string{"pool": "a"}),
+			newNode("node-3", map[string]string{"pool": "b"}),
+			// Opted out of NFD
+			newNode("node-4", map[string]string{nfdv1alpha1.NodeExcludeLabel: "true"}),
+		))
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			newNodeFeature("node-1", "acme"),
+			newNodeFeature("node-2", "other"),
+			newNodeFeature("node-3", "acme"),
+			newNodeFeature("node-4", "acme"),
+			newNodeFeature("no-such-node", "acme"),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		query := func(method, body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, matchNodesPath, strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			fakeMaster.matchNodesHandler(rec, req)
+			return rec
+		}
+
+		Convey("matching nodes should be returned", func() {
+			rec := query(http.MethodPost, `{"matchFeatures": [{"feature": "system.dmiid", "matchExpressions": {"sys_vendor": {"op": "In", "value": ["acme"]}}}]}`)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := matchNodesResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Nodes, ShouldResemble, []string{"node-1", "node-3"})
+			So(resp.Errors, ShouldBeEmpty)
+		})
+
+		Convey("the nodeSelector should restrict the nodes", func() {
+			rec := query(http.MethodPost, `{"nodeSelector": {"matchLabels": {"pool": "a"}}, "matchFeatures": [{"feature": "system.dmiid", "matchExpressions": {"sys_vendor": {"op": "In", "value": ["acme"]}}}]}`)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := matchNodesResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Nodes, ShouldResemble, []string{"node-1"})
+		})
+
+		Convey("rules should match the features of the node object", func() {
+			rec := query(http.MethodPost, `{"matchFeatures": [{"feature": "node.labels", "matchExpressions": {"pool": {"op": "In", "value": ["b"]}}}]}`)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := matchNodesResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Nodes, ShouldResemble, []string{"node-3"})
+		})
+
+		Convey("evaluation errors should be reported per node", func() {
+			rec := query(http.MethodPost, `{"matchFeatures": [{"feature": "system.dmiid", "matchExpressions": {"sys_vendor": {"op": "Gt", "value": ["1"]}}}]}`)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := matchNodesResponse{}
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.Nodes, ShouldBeEmpty)
+			So(resp.Errors, ShouldHaveLength, 3)
+		})
+
+		Convey("invalid requests should be rejected", func() {
+			So(query(http.MethodGet, "").Code, ShouldEqual, http.StatusMethodNotAllowed)
+			So(query(http.MethodPost, "not json").Code, ShouldEqual, http.StatusBadRequest)
+			So(query(http.MethodPost, `{"foo": "bar"}`).Code, ShouldEqual, http.StatusBadRequest)
+			So(query(http.MethodPost, `{"name": "no-matchers"}`).Code, ShouldEqual, http.StatusBadRequest)
+			So(query(http.MethodPost, `{"name": "`+strings.Repeat("a", maxMatchNodesRequestSize)+`"}`).Code, ShouldEqual, http.StatusBadRequest)
+			So(query(http.MethodPost, `{"nodeSelector": {"matchLabels": {"-": "-"}}, "matchFeatures": [{"feature": "system.dmiid"}]}`).Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func TestRuleLabelNamespace(t *testing.T) {
+	Convey("When processing NodeFeatureRules with a labelNamespace", t, func() {
+		newRule := func(name, ns string) *nfdv1alpha1.NodeFeatureRule {
+			return &nfdv1alpha1.NodeFeatureRule{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+					LabelNamespace: ns,
+					Rules: []nfdv1alpha1.Rule{
+						{
+							Name:   "rule",
+							Labels: map[string]string{name: "true", "other.example.com/" + name: "true"},
+						},
+						{
+							Name:   "backref",
+							Labels: map[string]string{name + "-backref": "true"},
+							MatchFeatures: nfdv1alpha1.FeatureMatcher{
+								{
+									Feature:          "rule.matched",
+									MatchExpressions: &nfdv1alpha1.MatchExpressionSet{addNs(name, ns): {Op: nfdv1alpha1.MatchIsTrue}},
+								},
+							},
+						},
+					},
+				},
+			}
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.config.DenyLabelNs = utils.StringSetVal{"denied.example.com": {}}
+		fakeMaster.deniedNs.normal, fakeMaster.deniedNs.wildcard = preProcessDeniedNamespaces(fakeMaster.config.DenyLabelNs)
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			newRule("a", "vendor.example.com"),
+			newRule("b", "denied.example.com"),
+			newRule("c", "kubernetes.io"),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule(testNodeName, nil, nfdv1alpha1.NewFeatures())
+		Convey("unprefixed labels should be created in the namespace", func() {
+			So(labels, ShouldResemble, Labels{
+				"vendor.example.com/a":         "true",
+				"other.example.com/a":          "true",
+				"vendor.example.com/a-backref": "true",
+			})
+		})
+
+		Convey("namespaces allowed with extraLabelNs should be accepted", func() {
+			fakeMaster.config.ExtraLabelNs = utils.StringSetVal{"denied.example.com": {}}
+			// Changing the config invalidates the cached rule output
+			fakeMaster.evalCache.reset()
+			labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule(testNodeName, nil, nfdv1alpha1.NewFeatures())
+			So(labels, ShouldContainKey, "denied.example.com/b")
+			So(labels, ShouldNotContainKey, "kubernetes.io/c")
+		})
+	})
+}
+
 func newTestNodeList() *corev1.NodeList {
 	l := corev1.NodeList{}
 
@@ -775,6 +1070,93 @@ func BenchmarkNfdAPIUpdateAllNodes(b *testing.B) {
 	fmt.Println(b.Elapsed())
 }
 
+// newBenchmarkNfdObjects creates NodeFeature objects for every node in
+// newTestNodeList() and a set of NodeFeatureRules matching against them.
+func newBenchmarkNfdObjects(numRules int) []runtime.Object {
+	objs := []runtime.Object{}
+	for i := 0; i < 1000; i++ {
+		nodeName := fmt.Sprintf("node %v", i)
+		nf := nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("node-%v", i),
+				Namespace: "nfd",
+				Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+			},
+			Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+		}
+		nf.Spec.Features.Attributes["test.attr"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"a": "1", "b": "2"})
+		nf.Spec.Labels[nfdv1alpha1.FeatureLabelNs+"/feature-1"] = "true"
+		objs = append(objs, &nf)
+	}
+	for i := 0; i < numRules; i++ {
+		objs = append(objs, &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("rule-%v", i)},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:   fmt.Sprintf("rule-%v", i),
+						Labels: map[string]string{nfdv1alpha1.FeatureLabelNs + fmt.Sprintf("/rule-label-%v", i): "true"},
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							nfdv1alpha1.FeatureMatcherTerm{
+								Feature: "test.attr",
+								MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+									"a": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+	return objs
+}
+
+// BenchmarkNfdAPIDesiredState measures the cost of reconstructing the desired
+// state of one node from the informer caches, i.e. the work done on every
+// reconcile, excluding the node update itself.
+func BenchmarkNfdAPIDesiredState(b *testing.B) {
+	fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+	fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(newBenchmarkNfdObjects(100)...))
+	if !fakeMaster.nfdController.waitForCacheSync() {
+		b.Fatal("failed to sync caches")
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		features, err := fakeMaster.getNodeFeatureSpec(fmt.Sprintf("node %v", i%1000))
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, _, _, _, _, _ = fakeMaster.processNodeFeatureRule("", nil, &features.Features)
+	}
+}
+
+// BenchmarkNfdAPIUpdateOneNode measures the full reconcile cost of one node,
+// including updating the node object.
+func BenchmarkNfdAPIUpdateOneNode(b *testing.B) {
+	nodes := newTestNodeList()
+	for i := range nodes.Items {
+		// JSON patches can't add keys to labels/annotations that are missing
+		nodes.Items[i].Labels["kubernetes.io/hostname"] = nodes.Items[i].Name
+		nodes.Items[i].Annotations["node.alpha.kubernetes.io/ttl"] = "0"
+	}
+	fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(nodes))
+	fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(newBenchmarkNfdObjects(100)...))
+	if !fakeMaster.nfdController.waitForCacheSync() {
+		b.Fatal("failed to sync caches")
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := fakeMaster.nfdAPIUpdateOneNode(fmt.Sprintf("node %v", i%1000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // withTimeout is a custom assertion for polling a value asynchronously
 // actual is a function for getting the actual value
 // expected[0] is a time.Duration value specifying the timeout
@@ -883,3 +1265,86 @@ func TestGetDynamicValue(t *testing.T) {
 		})
 	}
 }
+
+func TestFindNodeFeatureConflicts(t *testing.T) {
+	Convey("When merging NodeFeature objects", t, func() {
+		out := nfdv1alpha1.NewNodeFeatureSpec()
+		out.Labels = map[string]string{"foo": "1", "bar": "1"}
+		out.Features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6", "minor": "1"})
+		out.Features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX")
+
+		Convey("disjoint and equal values should not conflict", func() {
+			in := nfdv1alpha1.NewNodeFeatureSpec()
+			in.Labels = map[string]string{"foo": "1", "baz": "2"}
+			in.Features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6", "patch": "3"})
+			in.Features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX2")
+			So(findNodeFeatureConflicts(in, out), ShouldBeEmpty)
+		})
+
+		Convey("differing values should be reported", func() {
+			in := nfdv1alpha1.NewNodeFeatureSpec()
+			in.Labels = map[string]string{"bar": "2"}
+			in.Features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"minor": "2"})
+			So(findNodeFeatureConflicts(in, out), ShouldResemble, []string{"feature kernel.version.minor", "label bar"})
+		})
+	})
+}
+
+func TestNodeFeatureProducers(t *testing.T) {
+	newNodeFeature := func(ns, name string, annotations map[string]string) *nfdv1alpha1.NodeFeature {
+		return &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   ns,
+				Name:        name,
+				Labels:      map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: testNodeName},
+				Annotations: annotations,
+			},
+			Spec: nfdv1alpha1.NodeFeatureSpec{Labels: map[string]string{name: "true"}},
+		}
+	}
+
+	Convey("When NodeFeature producers are restricted", t, func() {
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.namespace = "nfd"
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			newNodeFeature("nfd", "worker", nil),
+			newNodeFeature("vendor", "vendor-annotated", map[string]string{"example.com/producer": "vendor-agent"}),
+			newNodeFeature("vendor", "vendor-plain", nil),
+			newNodeFeature("rogue", "rogue", map[string]string{"example.com/producer": "vendor-agent"}),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		Convey("all objects should be merged without an allow-list", func() {
+			features, err := fakeMaster.getNodeFeatureSpec(testNodeName)
+			So(err, ShouldBeNil)
+			So(features.Labels, ShouldHaveLength, 4)
+		})
+
+		Convey("only objects of allowed producers should be merged", func() {
+			fakeMaster.config.NodeFeatureProducers = []NodeFeatureProducer{
+				{Namespace: "vendor", Annotations: map[string]string{"example.com/producer": "vendor-agent"}},
+			}
+			features, err := fakeMaster.getNodeFeatureSpec(testNodeName)
+			So(err, ShouldBeNil)
+			So(features.Labels, ShouldResemble, map[string]string{"worker": "true", "vendor-annotated": "true"})
+		})
+
+		Convey("only objects with a valid signature should be merged", func() {
+			fakeMaster.signingKeys = [][]byte{[]byte("key")}
+			obj, err := fakeMaster.nfdController.featureLister.NodeFeatures("vendor").Get("vendor-plain")
+			So(err, ShouldBeNil)
+			sig, err := utils.SignNodeFeature([]byte("key"), testNodeName, &obj.Spec)
+			So(err, ShouldBeNil)
+			obj.Annotations = map[string]string{nfdv1alpha1.NodeFeatureSignatureAnnotation: sig}
+
+			features, err := fakeMaster.getNodeFeatureSpec(testNodeName)
+			So(err, ShouldBeNil)
+			So(features.Labels, ShouldResemble, map[string]string{"vendor-plain": "true"})
+		})
+
+		Convey("invalid producers should be rejected", func() {
+			So((&NodeFeatureProducer{}).validate(), ShouldNotBeNil)
+			So((&NodeFeatureProducer{Namespace: "vendor", Annotations: map[string]string{"a/b/c": ""}}).validate(), ShouldNotBeNil)
+		})
+	})
+}
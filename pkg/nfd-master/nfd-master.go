@@ -23,13 +23,16 @@ import (
 	"fmt"
 	"maps"
 	"net"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -43,9 +46,16 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sLabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
 	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	controller "k8s.io/kubernetes/pkg/controller"
 
@@ -55,8 +65,10 @@ import (
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
 	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/validate"
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	pb "github.com/openshift/node-feature-discovery/pkg/labeler"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/configobject"
 	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"
 	"github.com/openshift/node-feature-discovery/pkg/version"
 )
@@ -80,9 +92,68 @@ type NFDConfig struct {
 	ResourceLabels    utils.StringSetVal
 	EnableTaints      bool
 	ResyncPeriod      utils.DurationVal
+	ResyncJitter      float64
 	LeaderElection    LeaderElectionConfig
 	NfdApiParallelism int
-	Klog              klogutils.KlogConfigOpts
+	// RuleEvaluationParallelism is the maximum number of nodes whose rules
+	// are evaluated concurrently. Zero means no limit other than
+	// NfdApiParallelism.
+	RuleEvaluationParallelism int
+	Klog                      klogutils.KlogConfigOpts
+	// LogVerbosity overrides the log verbosity of the evaluation of
+	// individual NodeFeatureRule objects ("<object>") or rules
+	// ("<object>/<rule>").
+	LogVerbosity klogutils.VerbosityOverrides
+	// FeatureGates enables or disables feature gates. Gates specified with
+	// the -feature-gates command line flag take precedence.
+	FeatureGates map[string]bool
+	Exporter     ExporterConfig
+	// RuleDelegation maps namespaces to the policy restricting the output of
+	// their NamespacedNodeFeatureRule objects. NamespacedNodeFeatureRule
+	// objects are ignored in namespaces without a policy.
+	RuleDelegation map[string]RuleDelegationPolicy
+	// AutoscalerHints configures publishing the expected feature labels of
+	// node groups for the cluster-autoscaler.
+	AutoscalerHints AutoscalerHintsConfig
+	// SchedulingHints configures publishing node affinities selecting the
+	// nodes labeled by NodeFeatureRules.
+	SchedulingHints SchedulingHintsConfig
+	// NodeFeatureFinalizer enables setting a finalizer on NodeFeature
+	// objects so that the node modifications derived from an object are
+	// removed before the object is deleted, even if nfd-master is not
+	// running at the time of deletion. Run nfd-master with -prune to remove
+	// the finalizers when uninstalling NFD.
+	NodeFeatureFinalizer bool
+	// AuditLog configures recording the node modifications made by
+	// nfd-master.
+	AuditLog AuditLogConfig
+	// Informers configures the informers used for watching the cluster.
+	Informers InformersConfig
+	// OwnershipRecordFormat is the format of the node annotations recording
+	// the labels, annotations and extended resources managed by nfd-master,
+	// either "list" or "hash".
+	OwnershipRecordFormat string
+	// RulePresets is a list of built-in rule presets to enable. The rules of
+	// the presets are processed before the NodeFeatureRule objects.
+	RulePresets []string
+	// NodeFeatureProducers is the allow-list of producers of NodeFeature
+	// objects. If non-empty, objects outside the nfd-master namespace that
+	// are not from any of the listed producers are ignored.
+	NodeFeatureProducers []NodeFeatureProducer
+	// NodeFeatureSigningKeyFile is a file containing the keys (one per line)
+	// for verifying the signatures of NodeFeature objects. If set, objects
+	// without a valid signature are ignored.
+	NodeFeatureSigningKeyFile string
+	// ExtendedResourceGracePeriod is the time extended resources that are
+	// no longer produced by any rule are kept on the node with zero capacity
+	// before they are removed. Zero removes them immediately.
+	ExtendedResourceGracePeriod utils.DurationVal
+	// FeatureSchemaValidation specifies how features violating the schema
+	// of their source are handled, one of "none", "warn" or "reject".
+	FeatureSchemaValidation string
+	// LabelAliases are additional label aliases published with the
+	// LabelAliases feature gate, on top of the built-in alias table.
+	LabelAliases []LabelAlias
 }
 
 // LeaderElectionConfig contains the configuration for leader election
@@ -90,18 +161,24 @@ type LeaderElectionConfig struct {
 	LeaseDuration utils.DurationVal
 	RenewDeadline utils.DurationVal
 	RetryPeriod   utils.DurationVal
+	// LeaseName is the name of the Lease object used as the lock
+	LeaseName string
+	// LeaseNamespace is the namespace of the Lease object. Defaults to the
+	// namespace nfd-master is running in.
+	LeaseNamespace string
 }
 
 // ConfigOverrideArgs are args that override config file options
 type ConfigOverrideArgs struct {
-	DenyLabelNs       *utils.StringSetVal
-	ExtraLabelNs      *utils.StringSetVal
-	LabelWhiteList    *utils.RegexpVal
-	ResourceLabels    *utils.StringSetVal
-	EnableTaints      *bool
-	NoPublish         *bool
-	ResyncPeriod      *utils.DurationVal
-	NfdApiParallelism *int
+	DenyLabelNs               *utils.StringSetVal
+	ExtraLabelNs              *utils.StringSetVal
+	LabelWhiteList            *utils.RegexpVal
+	ResourceLabels            *utils.StringSetVal
+	EnableTaints              *bool
+	NoPublish                 *bool
+	ResyncPeriod              *utils.DurationVal
+	NfdApiParallelism         *int
+	RuleEvaluationParallelism *int
 }
 
 // Args holds command line arguments
@@ -120,10 +197,23 @@ type Args struct {
 	// Could be removed when gRPC labler service is dropped (when nfd-worker tests stop running nfd-master).
 	GrpcHealthPort       int
 	Prune                bool
+	PruneDryRun          bool
 	VerifyNodeName       bool
 	Options              string
 	EnableLeaderElection bool
 	MetricsPort          int
+	MetricsSecurity      utils.MetricsSecurityArgs
+	// EnableQueryApi enables the node query API on the metrics server.
+	EnableQueryApi bool
+	// EnableProfiling enables the pprof and runtime stats endpoints on the
+	// metrics server.
+	EnableProfiling bool
+	// ConfigObject is the name of the NFDConfig object to read the
+	// configuration from, in addition to the configuration file.
+	ConfigObject string
+	// ImageCompatWebhook configures the image compatibility admission
+	// webhook, disabled if the port is zero.
+	ImageCompatWebhook ImageCompatWebhookArgs
 
 	Overrides ConfigOverrideArgs
 }
@@ -146,12 +236,49 @@ type nfdMaster struct {
 	namespace       string
 	nodeName        string
 	configFilePath  string
+	configObject    *configobject.Watcher
 	server          *grpc.Server
 	healthServer    *grpc.Server
 	stop            chan struct{}
 	ready           chan bool
 	k8sClient       k8sclient.Interface
+	dynamicClient   dynamic.Interface
 	nodeUpdaterPool *nodeUpdaterPool
+	// Node informer used for the autoscaler hints
+	nodeLister         corev1listers.NodeLister
+	nodeInformerSynced cache.InformerSynced
+	nodeInformerStop   chan struct{}
+	exporter           *featureExporter
+	audit              *auditLog
+	// stats records the time spent in each rule and on each node if
+	// profiling has been enabled
+	stats     *utils.RuntimeStats
+	ruleCache ruleCache
+	// evalCache caches the output of rule evaluation of each node
+	evalCache evaluationCache
+	// ruleOutputCache caches the output of each rule of each node
+	ruleOutputCache ruleOutputCache
+	// evalLimiter bounds the number of concurrent rule evaluations
+	evalLimiter ruleEvaluationLimiter
+	// versionSkew tracks the nodes where rules are not evaluated because of
+	// an older nfd-worker
+	versionSkew versionSkew
+	// rulePresets are the built-in rule presets enabled in the config
+	rulePresets []ruleObject
+	// signingKeys are the keys for verifying NodeFeature signatures, nil if
+	// verification is disabled
+	signingKeys [][]byte
+	// labelAliases are the label aliases to publish, empty unless the
+	// LabelAliases feature gate is enabled
+	labelAliases []LabelAlias
+	// labelConflicts tracks the nodes where other controllers overwrite
+	// labels managed by nfd-master
+	labelConflicts   labelConflicts
+	eventBroadcaster record.EventBroadcaster
+	recorder         record.EventRecorder
+	// updatingNodes is set while this instance handles node updates, i.e.
+	// it holds the leader lock or leader election is disabled
+	updatingNodes atomic.Bool
 	deniedNs
 	config *NFDConfig
 }
@@ -186,6 +313,41 @@ func NewNfdMaster(args *Args) (NfdMaster, error) {
 		}
 	}
 
+	// The query API evaluates rules against the NodeFeature informer cache
+	if args.EnableQueryApi {
+		if !args.EnableNodeFeatureApi || !args.CrdController {
+			return nfd, fmt.Errorf("-enable-query-api requires the NodeFeature API and the CRD controller to be enabled")
+		}
+		if args.MetricsPort <= 0 {
+			return nfd, fmt.Errorf("-enable-query-api requires the metrics server to be enabled (-metrics)")
+		}
+		// The API exposes the features of all nodes
+		if !args.MetricsSecurity.TokenAuth && args.MetricsSecurity.ClientCAFile == "" {
+			return nfd, fmt.Errorf("-enable-query-api requires client authentication (-metrics-token-auth or -metrics-client-ca-file)")
+		}
+	}
+
+	// The webhook evaluates the image requirements against the NodeFeature
+	// informer cache
+	if args.ImageCompatWebhook.Port > 0 {
+		if !args.EnableNodeFeatureApi || !args.CrdController {
+			return nfd, fmt.Errorf("-image-compat-webhook-port requires the NodeFeature API and the CRD controller to be enabled")
+		}
+		// The API server only talks https to webhooks
+		if args.ImageCompatWebhook.CertFile == "" || args.ImageCompatWebhook.KeyFile == "" {
+			return nfd, fmt.Errorf("-image-compat-webhook-port requires -image-compat-webhook-cert-file and -image-compat-webhook-key-file")
+		}
+	} else if args.ImageCompatWebhook.NodeAffinity {
+		return nfd, fmt.Errorf("-enable-node-affinity-webhook requires the webhook server to be enabled (-image-compat-webhook-port)")
+	}
+
+	if args.EnableProfiling {
+		if args.MetricsPort <= 0 {
+			return nfd, fmt.Errorf("-enable-profiling requires the metrics server to be enabled (-metrics)")
+		}
+		nfd.stats = utils.NewRuntimeStats()
+	}
+
 	if args.ConfigFile != "" {
 		nfd.configFilePath = filepath.Clean(args.ConfigFile)
 	}
@@ -206,12 +368,36 @@ func newDefaultConfig() *NFDConfig {
 		ResourceLabels:    utils.StringSetVal{},
 		EnableTaints:      false,
 		ResyncPeriod:      utils.DurationVal{Duration: time.Duration(1) * time.Hour},
+		ResyncJitter:      0.1,
 		LeaderElection: LeaderElectionConfig{
 			LeaseDuration: utils.DurationVal{Duration: time.Duration(15) * time.Second},
 			RetryPeriod:   utils.DurationVal{Duration: time.Duration(2) * time.Second},
 			RenewDeadline: utils.DurationVal{Duration: time.Duration(10) * time.Second},
+			LeaseName:     "nfd-master.nfd.kubernetes.io",
+		},
+		Exporter: ExporterConfig{
+			Webhook: WebhookExporterConfig{
+				Timeout: utils.DurationVal{Duration: time.Duration(10) * time.Second},
+			},
+			File: FileExporterConfig{
+				MaxSize:    100 * 1024 * 1024,
+				MaxBackups: 3,
+			},
+			QueueSize: 1000,
+		},
+		AuditLog: AuditLogConfig{
+			MaxSize:    100 * 1024 * 1024,
+			MaxBackups: 3,
 		},
-		Klog: make(map[string]string),
+		AutoscalerHints: AutoscalerHintsConfig{
+			Annotation: "capacity.cluster-autoscaler.kubernetes.io/labels",
+		},
+		SchedulingHints: SchedulingHintsConfig{
+			ConfigMap: "nfd-scheduling-hints",
+		},
+		OwnershipRecordFormat:   OwnershipRecordFormatList,
+		FeatureSchemaValidation: FeatureSchemaValidationWarn,
+		Klog:                    make(map[string]string),
 	}
 }
 
@@ -223,12 +409,29 @@ func (m *nfdMaster) Run() error {
 		klog.InfoS("Master instance", "instance", m.args.Instance)
 	}
 
+	if m.args.ConfigObject != "" {
+		kubeconfig, err := utils.GetKubeconfig(m.args.Kubeconfig)
+		if err != nil {
+			return err
+		}
+		m.configObject, err = configobject.NewWatcher(kubeconfig, m.args.ConfigObject, configobject.Master)
+		if err != nil {
+			return fmt.Errorf("failed to watch NFDConfig %q: %w", m.args.ConfigObject, err)
+		}
+		defer m.configObject.Stop()
+	}
+
 	// Read initial configuration
 	if err := m.configure(m.configFilePath, m.args.Options); err != nil {
 		return err
 	}
 
-	if m.args.Prune {
+	if err := m.startAuditLog(); err != nil {
+		return err
+	}
+	defer func() { m.audit.stop() }()
+
+	if m.args.Prune || m.args.PruneDryRun {
 		return m.prune()
 	}
 
@@ -239,6 +442,11 @@ func (m *nfdMaster) Run() error {
 		}
 	}
 
+	if err := m.startExporter(); err != nil {
+		return err
+	}
+	defer func() { m.exporter.stop() }()
+
 	m.nodeUpdaterPool.start(m.config.NfdApiParallelism)
 
 	// Create watcher for config file
@@ -256,7 +464,7 @@ func (m *nfdMaster) Run() error {
 
 	// Register to metrics server
 	if m.args.MetricsPort > 0 {
-		m := utils.CreateMetricsServer(m.args.MetricsPort,
+		ms := utils.CreateMetricsServer(m.args.MetricsPort,
 			buildInfo,
 			nodeUpdateRequests,
 			nodeUpdates,
@@ -265,10 +473,47 @@ func (m *nfdMaster) Run() error {
 			nodeERsRejected,
 			nodeTaintsRejected,
 			nfrProcessingTime,
-			nfrProcessingErrors)
-		go m.Run()
+			nfrProcessingErrors,
+			nodeFeatureConflicts,
+			nodeFeaturesRejected,
+			nodeFeatureSignatureFailures,
+			ruleEvaluationCacheHits,
+			ruleEvaluationCacheMisses,
+			ruleEvaluationWaitTime,
+			nodeUpdateQueueTime,
+			nodeUpdateTime,
+			leaderStatus,
+			exportedRecords,
+			exportErrors,
+			imageCompatReviews,
+			extendedResourcesZeroed,
+			labelConflictsTotal,
+			featureSchemaViolations,
+			features.FeatureEnabled)
+		if m.args.EnableQueryApi {
+			ms.Handle(matchNodesPath, http.HandlerFunc(m.matchNodesHandler))
+		}
+		if m.args.EnableProfiling {
+			if err := ms.EnableProfiling(m.args.MetricsSecurity, m.stats); err != nil {
+				return err
+			}
+			ms.Handle(ruleDependenciesPath, http.HandlerFunc(m.ruleDependenciesHandler))
+		}
+		if err := ms.Secure(m.args.MetricsSecurity, m.args.Kubeconfig); err != nil {
+			return fmt.Errorf("failed to configure metrics server: %w", err)
+		}
+		go ms.Run()
 		registerVersion(version.Get())
-		defer m.Stop()
+		defer ms.Stop()
+	}
+
+	if m.args.ImageCompatWebhook.Port > 0 {
+		wh, err := newImageCompatWebhook(m, m.args.ImageCompatWebhook)
+		if err != nil {
+			return fmt.Errorf("failed to create image compatibility webhook: %w", err)
+		}
+		go wh.run()
+		defer wh.stop()
 	}
 
 	// Run gRPC server
@@ -310,25 +555,21 @@ func (m *nfdMaster) Run() error {
 			if err := m.configure(m.configFilePath, m.args.Options); err != nil {
 				return err
 			}
-
-			// restart NFD API controller
-			if m.nfdController != nil {
-				klog.InfoS("stopping the nfd api controller")
-				m.nfdController.stop()
+			if err := m.reloadComponents(); err != nil {
+				return err
 			}
-			if m.args.CrdController {
-				err := m.startNfdApiController()
-				if err != nil {
-					return nil
-				}
+
+		case <-m.configObject.Changes():
+			klog.InfoS("reloading configuration", "nfdconfig", m.args.ConfigObject)
+			// Keep running with the previous configuration if the new one
+			// is invalid. The error is reported in the object status.
+			if err := m.configure(m.configFilePath, m.args.Options); err != nil {
+				klog.ErrorS(err, "failed to reload configuration", "nfdconfig", m.args.ConfigObject)
+				continue
 			}
-			// Update all nodes when the configuration changes
-			if m.nfdController != nil && m.args.EnableNodeFeatureApi {
-				m.nfdController.updateAllNodesChan <- struct{}{}
+			if err := m.reloadComponents(); err != nil {
+				return err
 			}
-			// Restart the node updater pool
-			m.nodeUpdaterPool.stop()
-			m.nodeUpdaterPool.start(m.config.NfdApiParallelism)
 
 		case <-m.stop:
 			klog.InfoS("shutting down nfd-master")
@@ -337,6 +578,38 @@ func (m *nfdMaster) Run() error {
 	}
 }
 
+// reloadComponents restarts the components of nfd-master after the
+// configuration has changed.
+func (m *nfdMaster) reloadComponents() error {
+	// restart NFD API controller
+	if m.nfdController != nil {
+		klog.InfoS("stopping the nfd api controller")
+		m.nfdController.stop()
+	}
+	if m.args.CrdController {
+		if err := m.startNfdApiController(); err != nil {
+			return err
+		}
+	}
+	// Update all nodes when the configuration changes. Don't block
+	// here as there is no consumer if we're not the leader.
+	if m.nfdController != nil && m.args.EnableNodeFeatureApi {
+		m.nfdController.updateAllNodes()
+	}
+	// Restart the node updater pool, the exporter and the audit log
+	m.nodeUpdaterPool.stop()
+	m.exporter.stop()
+	if err := m.startExporter(); err != nil {
+		return err
+	}
+	m.audit.stop()
+	if err := m.startAuditLog(); err != nil {
+		return err
+	}
+	m.nodeUpdaterPool.start(m.config.NfdApiParallelism)
+	return nil
+}
+
 // startGrpcHealthServer starts a gRPC health server for Kubernetes readiness/liveness probes.
 // TODO: improve status checking e.g. with watchdog in the main event loop and
 // cheking that node updater pool is alive.
@@ -427,8 +700,33 @@ func (m *nfdMaster) nfdAPIUpdateHandler() {
 	updateAll := m.args.EnableNodeFeatureApi
 	updateNodes := make(map[string]struct{})
 	rateLimit := time.After(time.Second)
+	m.updatingNodes.Store(true)
+	summaryTicker := time.NewTicker(featureSummarySyncPeriod)
+	defer summaryTicker.Stop()
+	autoscalerTicker := time.NewTicker(autoscalerHintsSyncPeriod)
+	defer autoscalerTicker.Stop()
+	schedulingHintsTicker := time.NewTicker(schedulingHintsSyncPeriod)
+	defer schedulingHintsTicker.Stop()
+	versionSkewTicker := time.NewTicker(versionSkewSyncPeriod)
+	defer versionSkewTicker.Stop()
+	resync := m.resyncTimer()
 	for {
 		select {
+		case <-resync:
+			if err := m.nfdAPIScheduleResyncAllNodes(); err != nil {
+				klog.ErrorS(err, "failed to schedule resync of nodes")
+			}
+			resync = m.resyncTimer()
+		case <-autoscalerTicker.C:
+			m.syncAutoscalerHints()
+		case <-schedulingHintsTicker.C:
+			m.syncSchedulingHints()
+		case <-summaryTicker.C:
+			m.nfdController.syncFeatureSummary(m.featureSummaryName())
+		case <-m.nfdController.ruleTestsChan:
+			m.syncRuleTests()
+		case <-versionSkewTicker.C:
+			m.syncVersionSkew()
 		case <-m.nfdController.updateAllNodesChan:
 			updateAll = true
 		case nodeName := <-m.nfdController.updateOneNodeChan:
@@ -454,6 +752,23 @@ func (m *nfdMaster) nfdAPIUpdateHandler() {
 	}
 }
 
+// resyncNode queues a node for update. Nodes are only queued while this
+// instance handles node updates.
+func (m *nfdMaster) resyncNode(nodeName string) {
+	if m.updatingNodes.Load() {
+		m.nodeUpdaterPool.enqueue(nodeName)
+	}
+}
+
+// featureSummaryName returns the name of the ClusterFeatureSummary object
+// maintained by this nfd-master instance.
+func (m *nfdMaster) featureSummaryName() string {
+	if m.args.Instance == "" {
+		return "nfd-master"
+	}
+	return "nfd-master-" + m.args.Instance
+}
+
 // Stop NfdMaster
 func (m *nfdMaster) Stop() {
 	if m.server != nil {
@@ -468,6 +783,10 @@ func (m *nfdMaster) Stop() {
 	}
 
 	m.nodeUpdaterPool.stop()
+	m.stopNodeInformer()
+	if m.eventBroadcaster != nil {
+		m.eventBroadcaster.Shutdown()
+	}
 
 	close(m.stop)
 }
@@ -487,44 +806,6 @@ func (m *nfdMaster) WaitForReady(timeout time.Duration) bool {
 	return false
 }
 
-// Prune erases all NFD related properties from the node objects of the cluster.
-func (m *nfdMaster) prune() error {
-	if m.config.NoPublish {
-		klog.InfoS("skipping pruning of nodes as noPublish config option is set")
-		return nil
-	}
-
-	nodes, err := m.getNodes()
-	if err != nil {
-		return err
-	}
-
-	for _, node := range nodes.Items {
-		klog.InfoS("pruning node...", "nodeName", node.Name)
-
-		// Prune labels and extended resources
-		err := m.updateNodeObject(node.Name, Labels{}, Annotations{}, ExtendedResources{}, []corev1.Taint{})
-		if err != nil {
-			nodeUpdateFailures.Inc()
-			return fmt.Errorf("failed to prune node %q: %v", node.Name, err)
-		}
-
-		// Prune annotations
-		node, err := m.getNode(node.Name)
-		if err != nil {
-			return err
-		}
-		maps.DeleteFunc(node.Annotations, func(k, v string) bool {
-			return strings.HasPrefix(k, m.instanceAnnotation(nfdv1alpha1.AnnotationNs))
-		})
-		_, err = m.k8sClient.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to prune annotations from node %q: %v", node.Name, err)
-		}
-	}
-	return nil
-}
-
 // Update annotations on the node where nfd-master is running. Currently the
 // only function is to remove the deprecated
 // "nfd.node.kubernetes.io/master.version" annotation, if it exists.
@@ -615,6 +896,20 @@ func (m *nfdMaster) filterFeatureLabel(name, value string, features *nfdv1alpha1
 	return filteredValue, nil
 }
 
+// validateLabelNamespace checks that a label namespace is valid and allowed
+// by the configuration.
+func (m *nfdMaster) validateLabelNamespace(ns string) error {
+	err := validate.LabelNamespace(ns)
+	if err == validate.ErrNSNotAllowed || (err == nil && isNamespaceDenied(ns, m.deniedNs.wildcard, m.deniedNs.normal)) {
+		if _, ok := m.config.ExtraLabelNs[ns]; !ok {
+			return fmt.Errorf("namespace %q is not allowed", ns)
+		}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
 func getDynamicValue(value string, features *nfdv1alpha1.Features) (string, error) {
 	// value is a string in the form of attribute.featureset.elements
 	split := strings.SplitN(value[1:], ".", 3)
@@ -709,10 +1004,40 @@ func (m *nfdMaster) nfdAPIUpdateAllNodes() error {
 		return err
 	}
 
+	nodeNames := sets.New[string]()
 	for _, node := range nodes.Items {
 		m.nodeUpdaterPool.queue.Add(node.Name)
+		nodeNames.Insert(node.Name)
+	}
+	m.evalCache.prune(nodeNames)
+	m.ruleOutputCache.prune(nodeNames)
+	m.exporter.prune(nodeNames)
+
+	return nil
+}
+
+// resyncTimer returns a channel for the next periodic scan of the nodes of
+// the cluster, or nil if the periodic resync is disabled.
+func (m *nfdMaster) resyncTimer() <-chan time.Time {
+	if !m.args.EnableNodeFeatureApi || m.config.ResyncPeriod.Duration <= 0 {
+		return nil
+	}
+	return time.After(m.config.ResyncPeriod.Duration)
+}
+
+// nfdAPIScheduleResyncAllNodes schedules a resync of all nodes in the
+// cluster. Nodes that have not been processed before, e.g. new nodes without
+// NodeFeature objects, get queued for their first resync. Nodes already
+// waiting for their resync are not affected.
+func (m *nfdMaster) nfdAPIScheduleResyncAllNodes() error {
+	nodes, err := m.getNodes()
+	if err != nil {
+		return err
 	}
 
+	for _, node := range nodes.Items {
+		m.nodeUpdaterPool.scheduleResync(m.nodeUpdaterPool.queue, node.Name)
+	}
 	return nil
 }
 
@@ -721,12 +1046,56 @@ func (m *nfdMaster) nfdAPIUpdateOneNode(nodeName string) error {
 		return nil
 	}
 
+	if m.config.NoPublish && m.exporter == nil {
+		return nil
+	}
+
+	klog.V(1).InfoS("processing of node initiated by NodeFeature API", "nodeName", nodeName)
+
+	features, err := m.getNodeFeatureSpec(nodeName)
+	if err != nil {
+		return err
+	}
+
+	m.exporter.export(nodeName, features)
+	if m.config.NoPublish {
+		return nil
+	}
+
+	// Update node labels et al. This may also mean removing all NFD-owned
+	// labels (et al.), for example  in the case no NodeFeature objects are
+	// present.
+	err = m.refreshNodeFeatures(nodeName, features.Labels, &features.Features)
+	// Let the producers of the NodeFeature objects know the outcome
+	m.updateNodeFeatureStatus(nodeName, err)
+	if err != nil {
+		return err
+	}
+
+	// Update owner references and finalizers only after the node has been
+	// updated, releasing the NodeFeature objects being deleted.
+	m.updateNodeFeatureObjects(nodeName)
+
+	return nil
+}
+
+// getNodeFeatureSpec merges all NodeFeature objects targeting the given node
+// into one NodeFeatureSpec. The result is constructed from the informer cache
+// only, nothing is retained between calls.
+func (m *nfdMaster) getNodeFeatureSpec(nodeName string) (*nfdv1alpha1.NodeFeatureSpec, error) {
 	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
 	objs, err := m.nfdController.featureLister.List(sel)
 	if err != nil {
-		return fmt.Errorf("failed to get NodeFeature resources for node %q: %w", nodeName, err)
+		return nil, fmt.Errorf("failed to get NodeFeature resources for node %q: %w", nodeName, err)
 	}
 
+	// Objects being deleted are dropped so that the node modifications
+	// derived from them get removed. So are objects from untrusted producers
+	// and objects that fail signature verification.
+	objs = slices.DeleteFunc(objs, func(o *nfdv1alpha1.NodeFeature) bool {
+		return o.DeletionTimestamp != nil || !m.nodeFeatureAllowed(o) || !m.nodeFeatureVerified(o)
+	})
+
 	// Sort our objects
 	sort.Slice(objs, func(i, j int) bool {
 		// Objects in our nfd namespace gets into the beginning of the list
@@ -744,12 +1113,6 @@ func (m *nfdMaster) nfdAPIUpdateOneNode(nodeName string) error {
 		return objs[i].Namespace < objs[j].Namespace
 	})
 
-	if m.config.NoPublish {
-		return nil
-	}
-
-	klog.V(1).InfoS("processing of node initiated by NodeFeature API", "nodeName", nodeName)
-
 	features := nfdv1alpha1.NewNodeFeatureSpec()
 
 	if len(objs) > 0 {
@@ -758,28 +1121,52 @@ func (m *nfdMaster) nfdAPIUpdateOneNode(nodeName string) error {
 		// NOTE: changing the rule api to support handle multiple objects instead
 		// of merging would probably perform better with lot less data to copy.
 		features = objs[0].Spec.DeepCopy()
+		m.validateFeatureSchema(objs[0], &features.Features)
 		if m.config.AutoDefaultNs {
 			features.Labels = addNsToMapKeys(features.Labels, nfdv1alpha1.FeatureLabelNs)
 		}
 		for _, o := range objs[1:] {
 			s := o.Spec.DeepCopy()
+			m.validateFeatureSchema(o, &s.Features)
 			if m.config.AutoDefaultNs {
 				s.Labels = addNsToMapKeys(s.Labels, nfdv1alpha1.FeatureLabelNs)
 			}
+			if c := findNodeFeatureConflicts(s, features); len(c) > 0 {
+				klog.InfoS("conflicting features in NodeFeature objects, values from the later object take precedence", "nodeName", nodeName, "nodefeature", klog.KObj(o), "conflicts", c)
+				nodeFeatureConflicts.Add(float64(len(c)))
+			}
 			s.MergeInto(features)
 		}
 
 		klog.V(4).InfoS("merged nodeFeatureSpecs", "newNodeFeatureSpec", utils.DelayedDumper(features))
 	}
 
-	// Update node labels et al. This may also mean removing all NFD-owned
-	// labels (et al.), for example  in the case no NodeFeature objects are
-	// present.
-	if err := m.refreshNodeFeatures(nodeName, features.Labels, &features.Features); err != nil {
-		return err
-	}
+	return features, nil
+}
 
-	return nil
+// findNodeFeatureConflicts returns the labels and attribute feature elements
+// of in that would override a different value in out when merged. Flags and
+// instances are merged as a union and never conflict.
+func findNodeFeatureConflicts(in, out *nfdv1alpha1.NodeFeatureSpec) []string {
+	conflicts := []string{}
+	for k, v := range in.Labels {
+		if o, ok := out.Labels[k]; ok && o != v {
+			conflicts = append(conflicts, "label "+k)
+		}
+	}
+	for name, f := range in.Features.Attributes {
+		of, ok := out.Features.Attributes[name]
+		if !ok {
+			continue
+		}
+		for k, v := range f.Elements {
+			if o, ok := of.Elements[k]; ok && o != v {
+				conflicts = append(conflicts, "feature "+name+"."+k)
+			}
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
 }
 
 // filterExtendedResources filters extended resources and returns a map
@@ -821,17 +1208,40 @@ func filterExtendedResource(name, value string, features *nfdv1alpha1.Features)
 }
 
 func (m *nfdMaster) refreshNodeFeatures(nodeName string, labels map[string]string, features *nfdv1alpha1.Features) error {
+	node, err := m.getNode(nodeName)
+	if err != nil {
+		return err
+	}
+	if isNodeExcluded(node) {
+		// Strip everything NFD owns from nodes that have opted out
+		klog.V(1).InfoS("node opted out of NFD, removing NFD-owned labels, annotations, extended resources, taints and cordon", "nodeName", nodeName)
+		return m.updateNodeObject(nodeName, Labels{}, Annotations{}, ExtendedResources{}, nil, nil, nil)
+	}
+	if !m.config.Informers.nodeSelected(node) {
+		klog.V(2).InfoS("node does not match the node selectors, skipping", "nodeName", nodeName)
+		return nil
+	}
+
 	if m.config.AutoDefaultNs {
 		labels = addNsToMapKeys(labels, nfdv1alpha1.FeatureLabelNs)
 	} else if labels == nil {
 		labels = make(map[string]string)
 	}
 
-	crLabels, crAnnotations, crExtendedResources, crTaints := m.processNodeFeatureRule(nodeName, features)
+	if features != nil {
+		m.addNodeFeatures(features, node)
+	}
+
+	release := m.evalLimiter.acquire()
+	crLabels, crAnnotations, crExtendedResources, crTaints, crCordonedBy, origins := m.processNodeFeatureRule(nodeName, node.Labels, features)
+	release()
 
 	// Mix in CR-originated labels
 	maps.Copy(labels, crLabels)
 
+	// Publish renamed labels also under their deprecated names
+	labels = addLabelAliases(labels, m.labelAliases)
+
 	// Remove labels which are intended to be extended resources via
 	// -resource-labels or their NS is not whitelisted
 	labels, extendedResources := m.filterFeatureLabels(labels, features)
@@ -849,7 +1259,7 @@ func (m *nfdMaster) refreshNodeFeatures(nodeName string, labels map[string]strin
 		taints = filterTaints(crTaints)
 	}
 
-	err := m.updateNodeObject(nodeName, labels, annotations, extendedResources, taints)
+	err = m.updateNodeObject(nodeName, labels, annotations, extendedResources, taints, crCordonedBy, origins)
 	if err != nil {
 		klog.ErrorS(err, "failed to update node", "nodeName", nodeName)
 		return err
@@ -858,10 +1268,16 @@ func (m *nfdMaster) refreshNodeFeatures(nodeName string, labels map[string]strin
 	return nil
 }
 
+// isNodeExcluded returns true if the node has opted out of NFD with the
+// exclude label or annotation.
+func isNodeExcluded(node *corev1.Node) bool {
+	return node.Labels[nfdv1alpha1.NodeExcludeLabel] == "true" || node.Annotations[nfdv1alpha1.NodeExcludeLabel] == "true"
+}
+
 // setTaints sets node taints and annotations based on the taints passed via
 // nodeFeatureRule custom resorce. If empty list of taints is passed, currently
 // NFD owned taints and annotations are removed from the node.
-func (m *nfdMaster) setTaints(taints []corev1.Taint, nodeName string) error {
+func (m *nfdMaster) setTaints(taints []corev1.Taint, nodeName string, origins ruleOrigins) error {
 	// Fetch the node object.
 	node, err := m.getNode(nodeName)
 	if err != nil {
@@ -910,6 +1326,7 @@ func (m *nfdMaster) setTaints(taints []corev1.Taint, nodeName string) error {
 			return fmt.Errorf("failed to patch the node %v", node.Name)
 		}
 		klog.InfoS("updated node taints", "nodeName", nodeName)
+		m.audit.recordChanges(nodeName, auditKindTaint, taintNames(oldTaints), taintValues(node.Spec.Taints), taintValues(newNode.Spec.Taints), origins)
 	}
 
 	// Update node annotation that holds the taints managed by us
@@ -935,6 +1352,65 @@ func (m *nfdMaster) setTaints(taints []corev1.Taint, nodeName string) error {
 	return nil
 }
 
+// setCordon cordons the node if some rules requested it, recording the rules
+// in an annotation. If no rules are passed, a node previously cordoned by NFD
+// is uncordoned. Nodes cordoned by someone else are left untouched.
+func (m *nfdMaster) setCordon(cordonedBy []string, nodeName string) error {
+	if !features.NFDFeatureGate.Enabled(features.NodeCordon) {
+		cordonedBy = nil
+	}
+
+	node, err := m.getNode(nodeName)
+	if err != nil {
+		return err
+	}
+
+	oldVal, owned := node.Annotations[m.instanceAnnotation(nfdv1alpha1.NodeCordonAnnotation)]
+	var spec, annotations map[string]interface{}
+	switch {
+	case len(cordonedBy) > 0:
+		if node.Spec.Unschedulable && !owned {
+			klog.V(1).InfoS("node already cordoned, not taking ownership", "nodeName", nodeName)
+			return nil
+		}
+		sort.Strings(cordonedBy)
+		newVal := strings.Join(cordonedBy, ",")
+		if node.Spec.Unschedulable && oldVal == newVal {
+			return nil
+		}
+		spec = map[string]interface{}{"unschedulable": true}
+		annotations = map[string]interface{}{m.instanceAnnotation(nfdv1alpha1.NodeCordonAnnotation): newVal}
+	case owned:
+		spec = map[string]interface{}{"unschedulable": nil}
+		annotations = map[string]interface{}{m.instanceAnnotation(nfdv1alpha1.NodeCordonAnnotation): nil}
+	default:
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"spec":     spec,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := m.k8sClient.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.MergePatchType, data, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch the node %v: %w", nodeName, err)
+	}
+	if len(cordonedBy) > 0 {
+		klog.InfoS("node cordoned", "nodeName", nodeName, "rules", cordonedBy)
+		op := "add"
+		if owned {
+			op = "replace"
+		}
+		m.audit.record(auditRecord{Node: nodeName, Kind: auditKindCordon, Name: "unschedulable", Op: op, OldValue: oldVal, NewValue: strings.Join(cordonedBy, ","), Rule: strings.Join(cordonedBy, ",")})
+	} else {
+		klog.InfoS("node uncordoned", "nodeName", nodeName)
+		m.audit.record(auditRecord{Node: nodeName, Kind: auditKindCordon, Name: "unschedulable", Op: "remove", OldValue: oldVal})
+	}
+	return nil
+}
+
 func authorizeClient(c context.Context, checkNodeName bool, nodeName string) error {
 	if checkNodeName {
 		// Client authorization.
@@ -959,43 +1435,114 @@ func authorizeClient(c context.Context, checkNodeName bool, nodeName string) err
 	return nil
 }
 
-func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha1.Features) (Labels, Annotations, ExtendedResources, []corev1.Taint) {
+func (m *nfdMaster) processNodeFeatureRule(nodeName string, nodeLabels map[string]string, features *nfdv1alpha1.Features) (Labels, Annotations, ExtendedResources, []corev1.Taint, []string, ruleOrigins) {
 	if m.nfdController == nil {
-		return nil, nil, nil, nil
+		return nil, nil, nil, nil, nil, nil
 	}
 
 	extendedResources := ExtendedResources{}
 	labels := make(map[string]string)
 	annotations := make(map[string]string)
 	var taints []corev1.Taint
-	ruleSpecs, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
-	sort.Slice(ruleSpecs, func(i, j int) bool {
-		return ruleSpecs[i].Name < ruleSpecs[j].Name
-	})
-
+	var cordonedBy []string
+	origins := ruleOrigins{}
+	ruleObjs, err := m.listRuleObjects()
 	if err != nil {
 		klog.ErrorS(err, "failed to list NodeFeatureRule resources")
-		return nil, nil, nil, nil
+		return nil, nil, nil, nil, nil, nil
+	}
+	m.ruleCache.prune(ruleObjs)
+
+	// The hasher is shared by the evaluation cache and the rule output cache
+	hasher := newFeatureHasher(features)
+	featureHash, rulesHash, cacheable := ruleEvaluationKey(ruleObjs, nodeLabels, hasher)
+	if cacheable {
+		if out, ok := m.evalCache.get(nodeName, featureHash, rulesHash); ok {
+			ruleEvaluationCacheHits.Inc()
+			klog.V(2).InfoS("features and rules unchanged, using cached output of NodeFeatureRule objects", "nodeName", nodeName)
+			return out.labels, out.annotations, out.extendedResources, out.taints, out.cordonedBy, out.origins
+		}
+		ruleEvaluationCacheMisses.Inc()
 	}
+	heartbeat := m.nodeFeatureHeartbeat(nodeName)
+	workerVersion := m.nodeWorkerVersion(nodeName)
+	skewedObjs := make(map[string]struct{})
+
+	// Only re-evaluate rules whose input features have changed
+	prevOutputs := m.ruleOutputCache.load(nodeName)
+	ruleOutputs := make(map[string]ruleOutputCacheEntry)
+	var reused int
 
 	// Process all rule CRs
 	processStart := time.Now()
-	for _, spec := range ruleSpecs {
+	for _, obj := range ruleObjs {
+		compiledSpec := m.ruleCache.get(obj)
+		if !compiledSpec.matchesNode(nodeLabels) {
+			klog.V(3).InfoS("node does not match nodeSelector, skipping NodeFeatureRule", "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
+			continue
+		}
+		labelNs := obj.spec.LabelNamespace
+		if labelNs != "" {
+			if err := m.validateLabelNamespace(labelNs); err != nil {
+				klog.ErrorS(err, "invalid labelNamespace, skipping NodeFeatureRule", "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
+				nfrProcessingErrors.WithLabelValues("InvalidLabelNamespace").Inc()
+				continue
+			}
+		}
+
 		t := time.Now()
 		switch {
 		case klog.V(3).Enabled():
-			klog.InfoS("executing NodeFeatureRule", "nodefeaturerule", klog.KObj(spec), "nodeName", nodeName, "nodeFeatureRuleSpec", utils.DelayedDumper(spec.Spec))
+			klog.InfoS("executing NodeFeatureRule", "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "nodeFeatureRuleSpec", utils.DelayedDumper(obj.spec))
 		case klog.V(1).Enabled():
-			klog.InfoS("executing NodeFeatureRule", "nodefeaturerule", klog.KObj(spec), "nodeName", nodeName)
+			klog.InfoS("executing NodeFeatureRule", "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName)
 		}
-		for _, rule := range spec.Spec.Rules {
-			ruleOut, err := nodefeaturerule.Execute(&rule, features)
+		for i, compiled := range compiledSpec.rules {
+			rule := compiled.Rule()
+			ruleRef := obj.key() + "/" + rule.Name
+			outputKey := obj.key() + "#" + strconv.Itoa(i)
+			inputHash := hasher.ruleInputHash(compiledSpec.resourceVersion, compiledSpec.features[i])
+			entry, ok := prevOutputs[outputKey]
+			switch {
+			case workerVersionTooOld(compiled, workerVersion):
+				// Do not remove the output of the rule because of features
+				// the worker does not discover yet
+				skewedObjs[obj.key()] = struct{}{}
+				if !ok {
+					klog.V(1).InfoS("nfd-worker older than minFeatureVersion of rule, skipping", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "workerVersion", workerVersion, "minFeatureVersion", rule.MinFeatureVersion)
+					continue
+				}
+				klog.V(1).InfoS("nfd-worker older than minFeatureVersion of rule, retaining previous output", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "workerVersion", workerVersion, "minFeatureVersion", rule.MinFeatureVersion)
+			case ok && entry.inputHash == inputHash:
+				reused++
+			default:
+				ruleStart := time.Now()
+				logger := m.config.LogVerbosity.Logger(klog.Background(), ruleRef, obj.key())
+				out, err := compiled.Evaluate(features, nodefeaturerule.WithLogger(logger))
+				m.stats.Observe("rule", ruleRef, time.Since(ruleStart))
+				entry = ruleOutputCacheEntry{inputHash: inputHash, out: out, err: err}
+			}
+			ruleOutputs[outputKey] = entry
+			ruleOut, err := entry.out, entry.err
 			if err != nil {
-				klog.ErrorS(err, "failed to process rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(spec), "nodeName", nodeName)
-				nfrProcessingErrors.Inc()
+				klog.ErrorS(err, "failed to process rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "errorCode", nodefeaturerule.Code(err))
+				nfrProcessingErrors.WithLabelValues(string(nodefeaturerule.Code(err))).Inc()
+				continue
+			}
+			if ruleOut.Matched && rule.ExpireAfter != nil && m.ruleOutputExpired(nodeName, ruleExpireAfter(rule), heartbeat) {
+				klog.V(1).InfoS("output of rule expired, ignoring", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "nodeName", nodeName, "heartbeat", heartbeat)
 				continue
 			}
+			if labelNs != "" {
+				ruleOut.Labels = addNsToMapKeys(ruleOut.Labels, labelNs)
+			}
+			if obj.policy != nil {
+				ruleOut = obj.policy.filterRuleOutput(ruleOut, m.config.AutoDefaultNs, obj)
+			}
 			taints = append(taints, ruleOut.Taints...)
+			if ruleOut.Cordon {
+				cordonedBy = append(cordonedBy, ruleRef)
+			}
 
 			l := ruleOut.Labels
 			e := ruleOut.ExtendedResources
@@ -1009,22 +1556,96 @@ func (m *nfdMaster) processNodeFeatureRule(nodeName string, features *nfdv1alpha
 			maps.Copy(extendedResources, e)
 			maps.Copy(annotations, a)
 
+			// Record the rule producing each output for the audit log
+			for k := range l {
+				origins.set(auditKindLabel, k, ruleRef)
+			}
+			for k := range e {
+				origins.set(auditKindExtendedResource, k, ruleRef)
+			}
+			for k := range a {
+				origins.set(auditKindAnnotation, k, ruleRef)
+			}
+			for _, t := range ruleOut.Taints {
+				origins.set(auditKindTaint, taintName(t), ruleRef)
+			}
+
 			// Feed back rule output to features map for subsequent rules to match
 			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Labels)
 			features.InsertAttributeFeatures(nfdv1alpha1.RuleBackrefDomain, nfdv1alpha1.RuleBackrefFeature, ruleOut.Vars)
+			hasher.invalidate(nfdv1alpha1.RuleBackrefDomain + "." + nfdv1alpha1.RuleBackrefFeature)
 		}
-		nfrProcessingTime.WithLabelValues(spec.Name, nodeName).Observe(time.Since(t).Seconds())
+		nfrProcessingTime.WithLabelValues(obj.key(), nodeName).Observe(time.Since(t).Seconds())
 	}
 	processingTime := time.Since(processStart)
-	klog.V(2).InfoS("processed NodeFeatureRule objects", "nodeName", nodeName, "objectCount", len(ruleSpecs), "duration", processingTime)
+	m.stats.Observe("node", nodeName, processingTime)
+	m.ruleOutputCache.store(nodeName, ruleOutputs)
+	if workerVersion != nil {
+		m.versionSkew.setNode(nodeName, workerVersion.String(), skewedObjs)
+	} else {
+		m.versionSkew.setNode(nodeName, "", nil)
+	}
+	klog.V(2).InfoS("processed NodeFeatureRule objects", "nodeName", nodeName, "objectCount", len(ruleObjs), "ruleCount", len(ruleOutputs), "reusedRuleCount", reused, "duration", processingTime)
+
+	if cacheable {
+		m.evalCache.set(nodeName, featureHash, rulesHash, ruleEvaluation{
+			labels:            labels,
+			annotations:       annotations,
+			extendedResources: extendedResources,
+			taints:            taints,
+			cordonedBy:        cordonedBy,
+			origins:           origins,
+		})
+	}
+
+	return labels, annotations, extendedResources, taints, cordonedBy, origins
+}
+
+// listRuleObjects returns the enabled rule presets, followed by all
+// NodeFeatureRule objects, sorted by name, followed by the NamespacedNodeFeatureRule objects of namespaces that have a
+// rule delegation policy, sorted by namespace and name.
+func (m *nfdMaster) listRuleObjects() ([]ruleObject, error) {
+	nfrs, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nfrs, func(i, j int) bool {
+		return nfrs[i].Name < nfrs[j].Name
+	})
+	objs := make([]ruleObject, 0, len(m.rulePresets)+len(nfrs))
+	objs = append(objs, m.rulePresets...)
+	for _, nfr := range nfrs {
+		objs = append(objs, ruleObject{Object: nfr, spec: &nfr.Spec})
+	}
 
-	return labels, annotations, extendedResources, taints
+	if m.nfdController.namespacedRuleLister == nil {
+		return objs, nil
+	}
+	nnfrs, err := m.nfdController.namespacedRuleLister.List(k8sLabels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nnfrs, func(i, j int) bool {
+		if nnfrs[i].Namespace != nnfrs[j].Namespace {
+			return nnfrs[i].Namespace < nnfrs[j].Namespace
+		}
+		return nnfrs[i].Name < nnfrs[j].Name
+	})
+	for _, nnfr := range nnfrs {
+		policy, ok := m.config.RuleDelegation[nnfr.Namespace]
+		if !ok {
+			klog.V(2).InfoS("no rule delegation policy for namespace, ignoring NamespacedNodeFeatureRule", "namespacednodefeaturerule", klog.KObj(nnfr))
+			continue
+		}
+		objs = append(objs, ruleObject{Object: nnfr, spec: &nnfr.Spec, policy: &policy})
+	}
+	return objs, nil
 }
 
 // updateNodeObject ensures the Kubernetes node object is up to date,
 // creating new labels and extended resources where necessary and removing
 // outdated ones. Also updates the corresponding annotations.
-func (m *nfdMaster) updateNodeObject(nodeName string, labels Labels, featureAnnotations Annotations, extendedResources ExtendedResources, taints []corev1.Taint) error {
+func (m *nfdMaster) updateNodeObject(nodeName string, labels Labels, featureAnnotations Annotations, extendedResources ExtendedResources, taints []corev1.Taint, cordonedBy []string, origins ruleOrigins) error {
 	// Get the worker node object
 	node, err := m.getNode(nodeName)
 	if err != nil {
@@ -1033,49 +1654,35 @@ func (m *nfdMaster) updateNodeObject(nodeName string, labels Labels, featureAnno
 
 	annotations := make(Annotations)
 
-	// Store names of labels in an annotation
-	if len(labels) > 0 {
-		labelKeys := make([]string, 0, len(labels))
-		for key := range labels {
-			// Drop the ns part for labels in the default ns
-			labelKeys = append(labelKeys, strings.TrimPrefix(key, nfdv1alpha1.FeatureLabelNs+"/"))
-		}
-		sort.Strings(labelKeys)
-		annotations[m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation)] = strings.Join(labelKeys, ",")
+	// Zero extended resources that are going away for the grace period
+	extendedResources, zeroedAnnotation := m.applyExtendedResourceGracePeriod(node, extendedResources)
+	if zeroedAnnotation != "" {
+		annotations[m.extendedResourcesZeroedAnnotation()] = zeroedAnnotation
 	}
 
-	// Store names of extended resources in an annotation
-	if len(extendedResources) > 0 {
-		extendedResourceKeys := make([]string, 0, len(extendedResources))
-		for key := range extendedResources {
-			// Drop the ns part if in the default ns
-			extendedResourceKeys = append(extendedResourceKeys, strings.TrimPrefix(key, nfdv1alpha1.FeatureLabelNs+"/"))
-		}
-		sort.Strings(extendedResourceKeys)
-		annotations[m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation)] = strings.Join(extendedResourceKeys, ",")
-	}
+	// Store names of labels, extended resources and feature annotations in
+	// ownership annotations
+	format := m.config.OwnershipRecordFormat
+	m.labelOwnership().setOwnedNames(annotations, keysOf(labels), format)
+	m.extendedResourceOwnership().setOwnedNames(annotations, keysOf(extendedResources), format)
+	m.annotationOwnership().setOwnedNames(annotations, keysOf(featureAnnotations), format)
+	maps.Copy(annotations, featureAnnotations)
 
-	// Store feature annotations
-	if len(featureAnnotations) > 0 {
-		// Store names of feature annotations in an annotation
-		annotationKeys := make([]string, 0, len(featureAnnotations))
-		for key := range featureAnnotations {
-			// Drop the ns part for annotations in the default ns
-			annotationKeys = append(annotationKeys, strings.TrimPrefix(key, nfdv1alpha1.FeatureAnnotationNs+"/"))
-		}
-		sort.Strings(annotationKeys)
-		annotations[m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation)] = strings.Join(annotationKeys, ",")
-		maps.Copy(annotations, featureAnnotations)
-	}
+	// Back off from overwriting labels that another controller is fighting
+	// over
+	oldLabels := m.labelOwnership().ownedNames(node.Annotations, keysOf(node.Labels))
+	labels = m.resolveLabelConflicts(node, oldLabels, labels)
 
 	// Create JSON patches for changes in labels and annotations
-	oldLabels := stringToNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation)], nfdv1alpha1.FeatureLabelNs)
-	oldAnnotations := stringToNsNames(node.Annotations[m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation)], nfdv1alpha1.FeatureAnnotationNs)
+	oldAnnotations := m.annotationOwnership().ownedNames(node.Annotations, keysOf(node.Annotations))
 	patches := createPatches(oldLabels, node.Labels, labels, "/metadata/labels")
+	oldAnnotations = append(oldAnnotations, m.labelOwnership().annotations()...)
+	oldAnnotations = append(oldAnnotations, m.extendedResourceOwnership().annotations()...)
+	oldAnnotations = append(oldAnnotations, m.annotationOwnership().annotations()...)
+	oldAnnotations = append(oldAnnotations, m.extendedResourcesZeroedAnnotation())
 	oldAnnotations = append(oldAnnotations, []string{
-		m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation),
-		m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation),
-		m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation),
+		// The node has now been re-evaluated, drop the resync request
+		m.instanceAnnotation(nfdv1alpha1.ForceResyncAnnotation),
 		// Clean up deprecated/stale nfd version annotations
 		m.instanceAnnotation(nfdv1alpha1.MasterVersionAnnotation),
 		m.instanceAnnotation(nfdv1alpha1.WorkerVersionAnnotation)}...)
@@ -1087,12 +1694,20 @@ func (m *nfdMaster) updateNodeObject(nodeName string, labels Labels, featureAnno
 	if err != nil {
 		return fmt.Errorf("error while patching extended resources: %w", err)
 	}
+	if len(statusPatches) > 0 {
+		oldResources := m.extendedResourceOwnership().ownedResources(node)
+		m.audit.recordChanges(nodeName, auditKindExtendedResource, oldResources, capacityValues(node, oldResources, extendedResources), extendedResources, origins)
+	}
 
 	// Patch the node object in the apiserver
 	err = m.patchNode(node.Name, patches)
 	if err != nil {
 		return fmt.Errorf("error while patching node object: %w", err)
 	}
+	if len(patches) > 0 {
+		m.audit.recordChanges(nodeName, auditKindLabel, oldLabels, node.Labels, labels, origins)
+		m.audit.recordChanges(nodeName, auditKindAnnotation, oldAnnotations, node.Annotations, annotations, origins)
+	}
 
 	if len(patches) > 0 || len(statusPatches) > 0 {
 		nodeUpdates.Inc()
@@ -1102,7 +1717,13 @@ func (m *nfdMaster) updateNodeObject(nodeName string, labels Labels, featureAnno
 	}
 
 	// Set taints
-	err = m.setTaints(taints, node.Name)
+	err = m.setTaints(taints, node.Name, origins)
+	if err != nil {
+		return err
+	}
+
+	// Cordon or uncordon
+	err = m.setCordon(cordonedBy, node.Name)
 	if err != nil {
 		return err
 	}
@@ -1143,16 +1764,20 @@ func (m *nfdMaster) createExtendedResourcePatches(n *corev1.Node, extendedResour
 	patches := []utils.JsonPatch{}
 
 	// Form a list of namespaced resource names managed by us
-	oldResources := stringToNsNames(n.Annotations[m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation)], nfdv1alpha1.FeatureLabelNs)
+	oldResources := m.extendedResourceOwnership().ownedResources(n)
 
-	// figure out which resources to remove
+	// figure out which resources to remove, capacity and allocatable are
+	// checked separately as a failed remove would reject the whole patch
 	for _, resource := range oldResources {
+		// check if the ext resource is still needed
+		if _, extResNeeded := extendedResources[resource]; extResNeeded {
+			continue
+		}
 		if _, ok := n.Status.Capacity[corev1.ResourceName(resource)]; ok {
-			// check if the ext resource is still needed
-			if _, extResNeeded := extendedResources[resource]; !extResNeeded {
-				patches = append(patches, utils.NewJsonPatch("remove", "/status/capacity", resource, ""))
-				patches = append(patches, utils.NewJsonPatch("remove", "/status/allocatable", resource, ""))
-			}
+			patches = append(patches, utils.NewJsonPatch("remove", "/status/capacity", resource, ""))
+		}
+		if _, ok := n.Status.Allocatable[corev1.ResourceName(resource)]; ok {
+			patches = append(patches, utils.NewJsonPatch("remove", "/status/allocatable", resource, ""))
 		}
 	}
 
@@ -1175,10 +1800,18 @@ func (m *nfdMaster) createExtendedResourcePatches(n *corev1.Node, extendedResour
 }
 
 // Parse configuration options
-func (m *nfdMaster) configure(filepath string, overrides string) error {
+func (m *nfdMaster) configure(filepath string, overrides string) (err error) {
 	// Create a new default config
 	c := newDefaultConfig()
 
+	objData, objGeneration, err := m.configObject.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read NFDConfig object: %w", err)
+	}
+	if objData != nil {
+		defer func() { m.configObject.SetStatus(objGeneration, err) }()
+	}
+
 	// Try to read and parse config file
 	if filepath != "" {
 		data, err := os.ReadFile(filepath)
@@ -1198,6 +1831,14 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 		}
 	}
 
+	// Parse the configuration of the NFDConfig object
+	if objData != nil {
+		if err := yaml.Unmarshal(objData, c); err != nil {
+			return fmt.Errorf("failed to parse NFDConfig %q: %w", m.args.ConfigObject, err)
+		}
+		klog.InfoS("NFDConfig object parsed", "nfdconfig", m.args.ConfigObject, "generation", objGeneration)
+	}
+
 	// Parse config overrides
 	if err := yaml.Unmarshal([]byte(overrides), c); err != nil {
 		return fmt.Errorf("failed to parse -options: %w", err)
@@ -1226,12 +1867,98 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 	if m.args.Overrides.NfdApiParallelism != nil {
 		c.NfdApiParallelism = *m.args.Overrides.NfdApiParallelism
 	}
+	if m.args.Overrides.RuleEvaluationParallelism != nil {
+		c.RuleEvaluationParallelism = *m.args.Overrides.RuleEvaluationParallelism
+	}
 
 	if c.NfdApiParallelism <= 0 {
 		return fmt.Errorf("the maximum number of concurrent labelers should be a non-zero positive number")
 	}
+	if c.RuleEvaluationParallelism < 0 {
+		return fmt.Errorf("ruleEvaluationParallelism must not be negative")
+	}
+	if c.ResyncPeriod.Duration < 0 {
+		return fmt.Errorf("resyncPeriod must not be negative")
+	}
+	if c.ResyncJitter < 0 {
+		return fmt.Errorf("resyncJitter must not be negative")
+	}
+	if c.ExtendedResourceGracePeriod.Duration < 0 {
+		return fmt.Errorf("extendedResourceGracePeriod must not be negative")
+	}
+	if c.LeaderElection.LeaseName == "" {
+		return fmt.Errorf("leaderElection.leaseName must not be empty")
+	}
+	if c.Exporter.QueueSize <= 0 {
+		return fmt.Errorf("exporter.queueSize must be a positive number")
+	}
+	if err := c.Exporter.Hub.validate(); err != nil {
+		return fmt.Errorf("invalid exporter.hub: %w", err)
+	}
+	for ns, policy := range c.RuleDelegation {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("invalid ruleDelegation policy for namespace %q: %w", ns, err)
+		}
+	}
+	for i := range c.NodeFeatureProducers {
+		if err := c.NodeFeatureProducers[i].validate(); err != nil {
+			return fmt.Errorf("invalid nodeFeatureProducers[%d]: %w", i, err)
+		}
+	}
+	if err := c.AutoscalerHints.validate(); err != nil {
+		return fmt.Errorf("invalid autoscalerHints: %w", err)
+	}
+	if err := c.SchedulingHints.validate(); err != nil {
+		return fmt.Errorf("invalid schedulingHints: %w", err)
+	}
+	if err := c.Informers.validate(); err != nil {
+		return fmt.Errorf("invalid informers: %w", err)
+	}
+	if c.OwnershipRecordFormat != OwnershipRecordFormatList && c.OwnershipRecordFormat != OwnershipRecordFormatHash {
+		return fmt.Errorf("invalid ownershipRecordFormat %q, must be %q or %q", c.OwnershipRecordFormat, OwnershipRecordFormatList, OwnershipRecordFormatHash)
+	}
+	switch c.FeatureSchemaValidation {
+	case FeatureSchemaValidationNone, FeatureSchemaValidationWarn, FeatureSchemaValidationReject:
+	default:
+		return fmt.Errorf("invalid featureSchemaValidation %q, must be %q, %q or %q", c.FeatureSchemaValidation, FeatureSchemaValidationNone, FeatureSchemaValidationWarn, FeatureSchemaValidationReject)
+	}
+	rulePresets, err := loadRulePresets(c.RulePresets)
+	if err != nil {
+		return fmt.Errorf("invalid rulePresets: %w", err)
+	}
+	var signingKeys [][]byte
+	if c.NodeFeatureSigningKeyFile != "" {
+		if signingKeys, err = utils.ReadSigningKeys(c.NodeFeatureSigningKeyFile); err != nil {
+			return fmt.Errorf("invalid nodeFeatureSigningKeyFile: %w", err)
+		}
+	}
+	aliases := map[string]bool{}
+	for i := range c.LabelAliases {
+		if err := c.LabelAliases[i].validate(); err != nil {
+			return fmt.Errorf("invalid labelAliases: %w", err)
+		}
+		if aliases[c.LabelAliases[i].Alias] {
+			return fmt.Errorf("invalid labelAliases: duplicate alias %q", c.LabelAliases[i].Alias)
+		}
+		aliases[c.LabelAliases[i].Alias] = true
+	}
+	if err := features.Apply(c.FeatureGates); err != nil {
+		return err
+	}
 
 	m.config = c
+	m.rulePresets = rulePresets
+	// The output of rules depends on the config, e.g. label namespace
+	// restrictions and rule delegation policies
+	m.evalCache.reset()
+	m.ruleOutputCache.reset()
+	m.evalLimiter.setLimit(c.RuleEvaluationParallelism)
+	m.signingKeys = signingKeys
+	m.labelAliases = nil
+	if features.NFDFeatureGate.Enabled(features.LabelAliases) {
+		m.labelAliases = activeLabelAliases(append(slices.Clone(builtinLabelAliases), c.LabelAliases...), version.Get())
+		klog.InfoS("publishing deprecated label aliases", "aliases", len(m.labelAliases))
+	}
 
 	if err := klogutils.MergeKlogConfiguration(m.args.Klog, c.Klog); err != nil {
 		return err
@@ -1247,6 +1974,24 @@ func (m *nfdMaster) configure(filepath string, overrides string) error {
 			return err
 		}
 		m.k8sClient = cli
+
+		if m.eventBroadcaster == nil {
+			m.eventBroadcaster = record.NewBroadcaster()
+			m.eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cli.CoreV1().Events("")})
+			m.recorder = m.eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "nfd-master"})
+		}
+
+		m.stopNodeInformer()
+		if len(c.AutoscalerHints.Templates) > 0 {
+			dynamicCli, err := dynamic.NewForConfig(kubeconfig)
+			if err != nil {
+				return err
+			}
+			m.dynamicClient = dynamicCli
+		}
+		if len(c.AutoscalerHints.Templates) > 0 || len(c.SchedulingHints.Hints) > 0 {
+			m.startNodeInformer()
+		}
 	}
 
 	// Pre-process DenyLabelNS into 2 lists: one for normal ns, and the other for wildcard ns
@@ -1333,28 +2078,76 @@ func (m *nfdMaster) instanceAnnotation(name string) string {
 	return m.args.Instance + "." + name
 }
 
+// startExporter starts the feature inventory exporter if any sinks have been
+// configured.
+func (m *nfdMaster) startExporter() error {
+	var err error
+	m.exporter, err = newFeatureExporter(&m.config.Exporter)
+	if err != nil {
+		return fmt.Errorf("failed to start feature exporter: %w", err)
+	}
+	return nil
+}
+
+// startAuditLog opens the audit log of node modifications if it has been
+// configured.
+func (m *nfdMaster) startAuditLog() error {
+	var err error
+	m.audit, err = newAuditLog(&m.config.AuditLog)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return nil
+}
+
 func (m *nfdMaster) startNfdApiController() error {
 	kubeconfig, err := utils.GetKubeconfig(m.args.Kubeconfig)
 	if err != nil {
 		return err
 	}
 	klog.InfoS("starting the nfd api controller")
-	m.nfdController, err = newNfdController(kubeconfig, nfdApiControllerOptions{
-		DisableNodeFeature: !m.args.EnableNodeFeatureApi,
-		ResyncPeriod:       m.config.ResyncPeriod.Duration,
-	})
+	opts := nfdApiControllerOptions{
+		DisableNodeFeature:    !m.args.EnableNodeFeatureApi,
+		ForceResyncAnnotation: m.instanceAnnotation(nfdv1alpha1.ForceResyncAnnotation),
+		ResyncNode:            m.resyncNode,
+		EnableNamespacedRules: len(m.config.RuleDelegation) > 0,
+		Informers:             &m.config.Informers,
+	}
+	if features.NFDFeatureGate.Enabled(features.ClusterFeatureSummary) {
+		labels := m.labelOwnership()
+		opts.FeatureSummaryLabels = &labels
+	}
+	m.nfdController, err = newNfdController(kubeconfig, opts)
 	if err != nil {
 		return fmt.Errorf("failed to initialize CRD controller: %w", err)
 	}
+
+	// All desired state is derived from the informer caches so we must not
+	// reconcile anything before they're populated. Otherwise NFD-owned labels
+	// of nodes whose NodeFeature objects have not been seen yet would be
+	// removed.
+	klog.InfoS("waiting for nfd api controller caches to sync")
+	if !m.nfdController.waitForCacheSync() {
+		return fmt.Errorf("failed to sync nfd api controller caches")
+	}
 	return nil
 }
 
+// nfdAPIUpdateHandlerWithLeaderElection runs the nfd API update handler only
+// while holding the leader lease. Non-leader replicas keep their informer
+// caches warm and serve health and metrics endpoints but don't touch nodes,
+// allowing fast takeover if the leader goes away.
 func (m *nfdMaster) nfdAPIUpdateHandlerWithLeaderElection() {
 	ctx := context.Background()
+	leaseNamespace := m.config.LeaderElection.LeaseNamespace
+	if leaseNamespace == "" {
+		leaseNamespace = m.namespace
+	}
+	klog.InfoS("starting leader election", "lease", klog.KRef(leaseNamespace, m.config.LeaderElection.LeaseName))
 	lock := &resourcelock.LeaseLock{
 		LeaseMeta: metav1.ObjectMeta{
-			Name:      "nfd-master.nfd.kubernetes.io",
-			Namespace: m.namespace,
+			Name:      m.config.LeaderElection.LeaseName,
+			Namespace: leaseNamespace,
 		},
 		Client: m.k8sClient.CoordinationV1(),
 		LockConfig: resourcelock.ResourceLockConfig{
@@ -1363,20 +2156,25 @@ func (m *nfdMaster) nfdAPIUpdateHandlerWithLeaderElection() {
 		},
 	}
 	config := leaderelection.LeaderElectionConfig{
-		Lock: lock,
-		// make it configurable?
+		Lock:          lock,
 		LeaseDuration: m.config.LeaderElection.LeaseDuration.Duration,
 		RetryPeriod:   m.config.LeaderElection.RetryPeriod.Duration,
 		RenewDeadline: m.config.LeaderElection.RenewDeadline.Duration,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(_ context.Context) {
+				klog.InfoS("acquired leadership")
+				leaderStatus.Set(1)
 				m.nfdAPIUpdateHandler()
 			},
 			OnStoppedLeading: func() {
 				// We lost the lock.
 				klog.InfoS("leaderelection lock was lost")
+				leaderStatus.Set(0)
 				m.Stop()
 			},
+			OnNewLeader: func(identity string) {
+				klog.V(2).InfoS("new leader elected", "identity", identity)
+			},
 		},
 	}
 	leaderElector, err := leaderelection.NewLeaderElector(config)
@@ -1409,8 +2207,11 @@ func (m *nfdMaster) getNode(nodeName string) (*corev1.Node, error) {
 	return m.k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
 }
 
+// getNodes lists the nodes matching the node selectors.
 func (m *nfdMaster) getNodes() (*corev1.NodeList, error) {
-	return m.k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	opts := metav1.ListOptions{}
+	m.config.Informers.tweakNodeListOptions(&opts)
+	return m.k8sClient.CoreV1().Nodes().List(context.TODO(), opts)
 }
 
 func (m *nfdMaster) patchNode(nodeName string, patches []utils.JsonPatch, subresources ...string) error {
@@ -1419,7 +2220,7 @@ func (m *nfdMaster) patchNode(nodeName string, patches []utils.JsonPatch, subres
 	}
 	data, err := json.Marshal(patches)
 	if err == nil {
-		_, err = m.k8sClient.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.JSONPatchType, data, metav1.PatchOptions{}, subresources...)
+		_, err = m.k8sClient.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.JSONPatchType, data, metav1.PatchOptions{FieldManager: nfdFieldManager}, subresources...)
 	}
 	return err
 }
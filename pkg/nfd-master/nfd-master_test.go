@@ -35,6 +35,12 @@ func TestNewNfdMaster(t *testing.T) {
 				So(err3, ShouldNotBeNil)
 			})
 		})
+		Convey("When the query API is enabled without client authentication", func() {
+			_, err := m.NewNfdMaster(&m.Args{EnableQueryApi: true, EnableNodeFeatureApi: true, CrdController: true, MetricsPort: 8081})
+			Convey("An error should be returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
 		Convey("When -config is supplied", func() {
 			_, err := m.NewNfdMaster(&m.Args{CertFile: "crt", KeyFile: "key", CaFile: "ca", ConfigFile: "master-config.yaml"})
 			Convey("An error should not be returned", func() {
@@ -0,0 +1,239 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// nodeAffinityWebhookPath is the path of the pod mutation endpoint.
+const nodeAffinityWebhookPath = "/mutate-pod-node-affinity"
+
+// jsonPatchOp is one operation of a JSON patch.
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// nodeAffinityHandler serves AdmissionReview requests for pods, adding the
+// node affinity requested in the pod annotations.
+func (w *imageCompatWebhook) nodeAffinityHandler(rw http.ResponseWriter, r *http.Request) {
+	serveAdmissionReview(rw, r, w.mutateNodeAffinity)
+}
+
+// mutateNodeAffinity adds the node affinity derived from the
+// PodRequiredFeaturesAnnotation and PodSchedulingHintsAnnotation annotations
+// to the pod. Pods with invalid annotations are denied.
+func (w *imageCompatWebhook) mutateNodeAffinity(_ context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != "Pod" || req.SubResource != "" {
+		return resp
+	}
+	pod := corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, &pod); err != nil {
+		resp.Warnings = []string{fmt.Sprintf("node affinity not added: failed to decode pod: %v", err)}
+		return resp
+	}
+	logger := klog.LoggerWithValues(klog.Background(), "pod", klog.KRef(req.Namespace, podName(&pod)))
+
+	terms, err := w.requestedNodeSelectorTerms(&pod)
+	if err != nil {
+		logger.V(1).Info("pod denied by node affinity webhook", "reason", err)
+		resp.Allowed = false
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+		return resp
+	}
+	if len(terms) == 0 {
+		return resp
+	}
+
+	patch, err := json.Marshal(nodeAffinityPatch(&pod, terms))
+	if err != nil {
+		resp.Warnings = []string{fmt.Sprintf("node affinity not added: %v", err)}
+		return resp
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	resp.Patch = patch
+	resp.PatchType = &patchType
+	logger.V(2).Info("adding node affinity to pod", "numTerms", len(terms))
+	return resp
+}
+
+// requestedNodeSelectorTerms returns the node selector terms requested in the
+// annotations of the pod. The terms are ORed, the same as in a node
+// selector.
+func (w *imageCompatWebhook) requestedNodeSelectorTerms(pod *corev1.Pod) ([]corev1.NodeSelectorTerm, error) {
+	var terms []corev1.NodeSelectorTerm
+
+	if v, ok := pod.Annotations[nfdv1alpha1.PodRequiredFeaturesAnnotation]; ok {
+		reqs, err := parseRequiredFeatures(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", nfdv1alpha1.PodRequiredFeaturesAnnotation, err)
+		}
+		terms = andNodeSelectorTerms(terms, []corev1.NodeSelectorTerm{{MatchExpressions: reqs}})
+	}
+
+	if v, ok := pod.Annotations[nfdv1alpha1.PodSchedulingHintsAnnotation]; ok {
+		for _, name := range splitAnnotationList(v) {
+			i := slices.IndexFunc(w.m.config.SchedulingHints.Hints, func(h SchedulingHint) bool { return h.Name == name })
+			if i < 0 {
+				return nil, fmt.Errorf("invalid %s annotation: unknown scheduling hint %q", nfdv1alpha1.PodSchedulingHintsAnnotation, name)
+			}
+			if w.m.nfdController == nil {
+				return nil, fmt.Errorf("scheduling hint %q not available: NodeFeatureRules not available", name)
+			}
+			affinity, err := w.m.schedulingHintAffinity(w.m.config.SchedulingHints.Hints[i])
+			if err != nil {
+				return nil, fmt.Errorf("scheduling hint %q not available: %w", name, err)
+			}
+			terms = andNodeSelectorTerms(terms, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+		}
+	}
+	return terms, nil
+}
+
+// splitAnnotationList splits a comma-separated annotation value, dropping
+// empty items.
+func splitAnnotationList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRequiredFeatures parses the value of the PodRequiredFeaturesAnnotation
+// annotation into node selector requirements.
+func parseRequiredFeatures(v string) ([]corev1.NodeSelectorRequirement, error) {
+	items := splitAnnotationList(v)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no features listed")
+	}
+
+	reqs := make([]corev1.NodeSelectorRequirement, 0, len(items))
+	for _, item := range items {
+		req := corev1.NodeSelectorRequirement{}
+		var value string
+		switch {
+		case strings.HasPrefix(item, "!"):
+			req.Key, req.Operator = item[1:], corev1.NodeSelectorOpDoesNotExist
+		case strings.Contains(item, "!="):
+			req.Key, value, _ = strings.Cut(item, "!=")
+			req.Operator = corev1.NodeSelectorOpNotIn
+		case strings.Contains(item, "="):
+			req.Key, value, _ = strings.Cut(item, "=")
+			req.Operator = corev1.NodeSelectorOpIn
+		default:
+			// Feature labels of boolean features have the value "true"
+			req.Key, value, req.Operator = item, "true", corev1.NodeSelectorOpIn
+		}
+
+		req.Key = strings.TrimSpace(req.Key)
+		if !strings.Contains(req.Key, "/") {
+			req.Key = nfdv1alpha1.FeatureLabelNs + "/" + req.Key
+		}
+		if errs := validation.IsQualifiedName(req.Key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label %q: %s", req.Key, strings.Join(errs, "; "))
+		}
+		if req.Operator != corev1.NodeSelectorOpDoesNotExist {
+			value = strings.TrimSpace(value)
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid value %q of label %q: %s", value, req.Key, strings.Join(errs, "; "))
+			}
+			req.Values = []string{value}
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// andNodeSelectorTerms returns the node selector terms that match the nodes
+// matching both a and b. As terms are ORed, every term of a is combined with
+// every term of b.
+func andNodeSelectorTerms(a, b []corev1.NodeSelectorTerm) []corev1.NodeSelectorTerm {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	terms := make([]corev1.NodeSelectorTerm, 0, len(a)*len(b))
+	for _, ta := range a {
+		for _, tb := range b {
+			t := *ta.DeepCopy()
+			t.MatchExpressions = append(t.MatchExpressions, tb.DeepCopy().MatchExpressions...)
+			t.MatchFields = append(t.MatchFields, tb.DeepCopy().MatchFields...)
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// nodeAffinityPatch returns the JSON patch that adds the node selector terms
+// to the required node affinity of the pod. Existing required terms are
+// combined with the new ones so that the pod still requires them.
+func nodeAffinityPatch(pod *corev1.Pod, terms []corev1.NodeSelectorTerm) []jsonPatchOp {
+	newSelector := &corev1.NodeSelector{NodeSelectorTerms: terms}
+
+	switch {
+	case pod.Spec.Affinity == nil:
+		return []jsonPatchOp{{
+			Op:    "add",
+			Path:  "/spec/affinity",
+			Value: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: newSelector}},
+		}}
+	case pod.Spec.Affinity.NodeAffinity == nil:
+		return []jsonPatchOp{{
+			Op:    "add",
+			Path:  "/spec/affinity/nodeAffinity",
+			Value: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: newSelector},
+		}}
+	case pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil:
+		return []jsonPatchOp{{
+			Op:    "add",
+			Path:  "/spec/affinity/nodeAffinity/requiredDuringSchedulingIgnoredDuringExecution",
+			Value: newSelector,
+		}}
+	}
+	existing := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	return []jsonPatchOp{{
+		Op:    "replace",
+		Path:  "/spec/affinity/nodeAffinity/requiredDuringSchedulingIgnoredDuringExecution/nodeSelectorTerms",
+		Value: andNodeSelectorTerms(existing, terms),
+	}}
+}
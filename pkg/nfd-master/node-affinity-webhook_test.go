@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	. "github.com/smartystreets/goconvey/convey"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestNodeAffinityWebhook(t *testing.T) {
+	Convey("When mutating pods with the node affinity webhook", t, func() {
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.config.SchedulingHints = SchedulingHintsConfig{
+			ConfigMap: "hints",
+			Hints:     []SchedulingHint{{Name: "gpu", NodeFeatureRule: "gpu-rules"}},
+		}
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			&nfdv1alpha1.NodeFeatureRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "gpu-rules"},
+				Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+					Rules: []nfdv1alpha1.Rule{
+						{Name: "vendor-a", Labels: map[string]string{"example.com/gpu": "a"}},
+						{Name: "vendor-b", Labels: map[string]string{"example.com/gpu": "b"}},
+					},
+				},
+			},
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+		wh := &imageCompatWebhook{m: fakeMaster}
+
+		// mutate returns the pod after applying the patch of the webhook
+		mutate := func(pod *corev1.Pod) (*admissionv1.AdmissionResponse, *corev1.Pod) {
+			raw, err := json.Marshal(pod)
+			So(err, ShouldBeNil)
+			body, err := json.Marshal(admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:    "uid-1",
+					Kind:   metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					Object: runtime.RawExtension{Raw: raw},
+				},
+			})
+			So(err, ShouldBeNil)
+
+			rec := httptest.NewRecorder()
+			wh.nodeAffinityHandler(rec, httptest.NewRequest(http.MethodPost, nodeAffinityWebhookPath, bytes.NewReader(body)))
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			ar := admissionv1.AdmissionReview{}
+			So(json.Unmarshal(rec.Body.Bytes(), &ar), ShouldBeNil)
+			if ar.Response.Patch == nil {
+				return ar.Response, pod
+			}
+
+			patch, err := jsonpatch.DecodePatch(ar.Response.Patch)
+			So(err, ShouldBeNil)
+			patched, err := patch.Apply(raw)
+			So(err, ShouldBeNil)
+			out := &corev1.Pod{}
+			So(json.Unmarshal(patched, out), ShouldBeNil)
+			return ar.Response, out
+		}
+		newPod := func(annotations map[string]string) *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Annotations: annotations}}
+		}
+		requiredTerms := func(pod *corev1.Pod) []corev1.NodeSelectorTerm {
+			So(pod.Spec.Affinity, ShouldNotBeNil)
+			So(pod.Spec.Affinity.NodeAffinity, ShouldNotBeNil)
+			So(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution, ShouldNotBeNil)
+			return pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		}
+
+		Convey("pods without annotations should not be modified", func() {
+			resp, _ := mutate(newPod(nil))
+			So(resp.Allowed, ShouldBeTrue)
+			So(resp.Patch, ShouldBeNil)
+		})
+
+		Convey("required features should be turned into a node affinity", func() {
+			resp, pod := mutate(newPod(map[string]string{
+				nfdv1alpha1.PodRequiredFeaturesAnnotation: "cpu-cpuid.AVX512F, kernel-version.major=6,example.com/foo!=bar,!cpu-hardware_multithreading",
+			}))
+			So(resp.Allowed, ShouldBeTrue)
+			So(requiredTerms(pod), ShouldResemble, []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "feature.node.kubernetes.io/cpu-cpuid.AVX512F", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}},
+					{Key: "feature.node.kubernetes.io/kernel-version.major", Operator: corev1.NodeSelectorOpIn, Values: []string{"6"}},
+					{Key: "example.com/foo", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"bar"}},
+					{Key: "feature.node.kubernetes.io/cpu-hardware_multithreading", Operator: corev1.NodeSelectorOpDoesNotExist},
+				},
+			}})
+		})
+
+		Convey("scheduling hints should be combined with existing node affinity", func() {
+			pod := newPod(map[string]string{
+				nfdv1alpha1.PodSchedulingHintsAnnotation: "gpu",
+			})
+			pod.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"z1"}}},
+					}},
+				},
+			}}
+			resp, pod := mutate(pod)
+			So(resp.Allowed, ShouldBeTrue)
+			So(requiredTerms(pod), ShouldResemble, []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"z1"}},
+					{Key: "example.com/gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+				}},
+				{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"z1"}},
+					{Key: "example.com/gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}},
+				}},
+			})
+		})
+
+		Convey("pods with invalid annotations should be denied", func() {
+			for _, a := range []map[string]string{
+				{nfdv1alpha1.PodRequiredFeaturesAnnotation: ""},
+				{nfdv1alpha1.PodRequiredFeaturesAnnotation: "foo=-bar-"},
+				{nfdv1alpha1.PodRequiredFeaturesAnnotation: "a/b/c"},
+				{nfdv1alpha1.PodSchedulingHintsAnnotation: "no-such-hint"},
+			} {
+				resp, _ := mutate(newPod(a))
+				So(resp.Allowed, ShouldBeFalse)
+				So(resp.Patch, ShouldBeNil)
+			}
+		})
+	})
+}
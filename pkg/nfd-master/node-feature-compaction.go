@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"sync"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// maxInternedValueLen is the maximum length of feature values that are
+	// deduplicated. Longer values are unlikely to be shared between nodes.
+	maxInternedValueLen = 64
+	// maxStringPoolSize caps the number of distinct strings in the pool so
+	// that unique values cannot make it grow without bounds.
+	maxStringPoolSize = 1 << 16
+)
+
+// stringPool deduplicates strings so that equal strings share the same
+// memory.
+type stringPool struct {
+	lock    sync.Mutex
+	strings map[string]string
+}
+
+func newStringPool() *stringPool {
+	return &stringPool{strings: make(map[string]string)}
+}
+
+// intern returns the pooled copy of s, adding s to the pool if it's not
+// full.
+func (p *stringPool) intern(s string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if pooled, ok := p.strings[s]; ok {
+		return pooled
+	}
+	if len(p.strings) < maxStringPoolSize {
+		p.strings[s] = s
+	}
+	return s
+}
+
+// internValue interns s if it's short enough.
+func (p *stringPool) internValue(s string) string {
+	if len(s) > maxInternedValueLen {
+		return s
+	}
+	return p.intern(s)
+}
+
+// internMap interns the keys and values of a map in place.
+func (p *stringPool) internMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[p.intern(k)] = p.internValue(v)
+	}
+	return out
+}
+
+// compactNodeFeature is an informer transform function that reduces the
+// memory footprint of cached NodeFeature objects. The content of the objects
+// is not altered (apart from dropping the managedFields) but the feature
+// names and short values, which are mostly the same on all nodes, are
+// deduplicated across objects.
+//
+// NOTE: the apiserver serves custom resources as JSON only, so the
+// NodeFeature traffic cannot be protobuf-encoded.
+func (p *stringPool) compactNodeFeature(obj interface{}) (interface{}, error) {
+	nf, ok := obj.(*nfdv1alpha1.NodeFeature)
+	if !ok {
+		return obj, nil
+	}
+	// Managed fields are not sent back in updates so they can be dropped
+	nf.ManagedFields = nil
+
+	nf.Labels = p.internMap(nf.Labels)
+	nf.Spec.Labels = p.internMap(nf.Spec.Labels)
+
+	features := &nf.Spec.Features
+	if features.Flags != nil {
+		flags := make(map[string]nfdv1alpha1.FlagFeatureSet, len(features.Flags))
+		for name, set := range features.Flags {
+			if set.Elements != nil {
+				elements := make(map[string]nfdv1alpha1.Nil, len(set.Elements))
+				for k := range set.Elements {
+					elements[p.intern(k)] = nfdv1alpha1.Nil{}
+				}
+				set.Elements = elements
+			}
+			flags[p.intern(name)] = set
+		}
+		features.Flags = flags
+	}
+	if features.Attributes != nil {
+		attrs := make(map[string]nfdv1alpha1.AttributeFeatureSet, len(features.Attributes))
+		for name, set := range features.Attributes {
+			set.Elements = p.internMap(set.Elements)
+			attrs[p.intern(name)] = set
+		}
+		features.Attributes = attrs
+	}
+	if features.Instances != nil {
+		instances := make(map[string]nfdv1alpha1.InstanceFeatureSet, len(features.Instances))
+		for name, set := range features.Instances {
+			for i := range set.Elements {
+				set.Elements[i].Attributes = p.internMap(set.Elements[i].Attributes)
+			}
+			instances[p.intern(name)] = set
+		}
+		features.Instances = instances
+	}
+	return nf, nil
+}
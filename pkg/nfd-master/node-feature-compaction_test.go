@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newCompactionTestNodeFeature(nodeName string) *nfdv1alpha1.NodeFeature {
+	// Build all strings dynamically so that they don't share memory to
+	// begin with
+	s := func(v string) string { return strings.Clone(v) }
+
+	nf := &nfdv1alpha1.NodeFeature{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          nodeName,
+			Labels:        map[string]string{s(nfdv1alpha1.NodeFeatureObjNodeNameLabel): nodeName},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "nfd-worker"}},
+		},
+		Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+	}
+	nf.Spec.Labels[s("feature.node.kubernetes.io/foo")] = s("true")
+	nf.Spec.Features.Flags[s("cpu.cpuid")] = nfdv1alpha1.NewFlagFeatures(s("AVX"), s("AVX2"))
+	nf.Spec.Features.Flags[s("empty.flags")] = nfdv1alpha1.FlagFeatureSet{}
+	nf.Spec.Features.Attributes[s("kernel.version")] = nfdv1alpha1.NewAttributeFeatures(map[string]string{s("major"): s("6"), s("full"): s(strings.Repeat("x", maxInternedValueLen+1))})
+	nf.Spec.Features.Instances[s("pci.device")] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{*nfdv1alpha1.NewInstanceFeature(map[string]string{s("vendor"): s("8086")})})
+	return nf
+}
+
+// strData returns the address of the bytes of a string.
+func strData(s string) uintptr {
+	return uintptr(unsafe.Pointer(unsafe.StringData(s)))
+}
+
+func TestCompactNodeFeature(t *testing.T) {
+	Convey("When compacting NodeFeature objects", t, func() {
+		p := newStringPool()
+		orig := newCompactionTestNodeFeature("node-1")
+		obj, err := p.compactNodeFeature(orig.DeepCopy())
+		So(err, ShouldBeNil)
+		nf1 := obj.(*nfdv1alpha1.NodeFeature)
+		obj, err = p.compactNodeFeature(newCompactionTestNodeFeature("node-2"))
+		So(err, ShouldBeNil)
+		nf2 := obj.(*nfdv1alpha1.NodeFeature)
+
+		Convey("the content should be preserved", func() {
+			orig.ManagedFields = nil
+			So(nf1, ShouldResemble, orig)
+		})
+
+		Convey("equal strings should share memory", func() {
+			key := func(m map[string]string) string {
+				for k := range m {
+					return k
+				}
+				return ""
+			}
+			So(strData(key(nf1.Spec.Features.Instances["pci.device"].Elements[0].Attributes)), ShouldEqual,
+				strData(key(nf2.Spec.Features.Instances["pci.device"].Elements[0].Attributes)))
+			So(strData(nf1.Spec.Features.Attributes["kernel.version"].Elements["major"]), ShouldEqual,
+				strData(nf2.Spec.Features.Attributes["kernel.version"].Elements["major"]))
+			So(strData(nf1.Spec.Features.Attributes["kernel.version"].Elements["full"]), ShouldNotEqual,
+				strData(nf2.Spec.Features.Attributes["kernel.version"].Elements["full"]))
+		})
+	})
+}
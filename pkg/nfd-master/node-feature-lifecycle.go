@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"slices"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	nodeFeatureProcessedReason  = "NodeUpdated"
+	nodeFeatureFailedReason     = "NodeUpdateFailed"
+	nodeFeatureNotAllowedReason = "ProducerNotAllowed"
+	nodeFeatureUnverifiedReason = "SignatureVerificationFailed"
+)
+
+// updateNodeFeatureObjects makes the NodeFeature objects of a node owned by
+// the node object, so that they are garbage collected when the node is
+// deleted, and adds or removes the node cleanup finalizer. Failures are only
+// logged as any change of the objects triggers a new update.
+func (m *nfdMaster) updateNodeFeatureObjects(nodeName string) {
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	objs, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeature objects", "nodeName", nodeName)
+		return
+	}
+
+	var ownerRef *metav1.OwnerReference
+	for _, obj := range objs {
+		updated := obj.DeepCopy()
+
+		if obj.DeletionTimestamp != nil || !m.config.NodeFeatureFinalizer {
+			updated.Finalizers = slices.DeleteFunc(updated.Finalizers, func(f string) bool { return f == nfdv1alpha1.NodeCleanupFinalizer })
+		} else if !slices.Contains(updated.Finalizers, nfdv1alpha1.NodeCleanupFinalizer) {
+			updated.Finalizers = append(updated.Finalizers, nfdv1alpha1.NodeCleanupFinalizer)
+		}
+
+		if obj.DeletionTimestamp == nil && !hasNodeOwnerRef(obj, nodeName) {
+			if ownerRef == nil {
+				node, err := m.getNode(nodeName)
+				if err != nil {
+					klog.ErrorS(err, "failed to get node, not setting NodeFeature owner reference", "nodeName", nodeName)
+					continue
+				}
+				ownerRef = &metav1.OwnerReference{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       node.Name,
+					UID:        node.UID,
+				}
+			}
+			updated.OwnerReferences = append(updated.OwnerReferences, *ownerRef)
+		}
+
+		if slices.Equal(obj.Finalizers, updated.Finalizers) && len(obj.OwnerReferences) == len(updated.OwnerReferences) {
+			continue
+		}
+		klog.V(2).InfoS("updating NodeFeature owner references and finalizers", "nodefeature", klog.KObj(obj))
+		_, err := m.nfdController.nfdClient.NfdV1alpha1().NodeFeatures(obj.Namespace).Update(context.TODO(), updated, metav1.UpdateOptions{})
+		if err != nil {
+			klog.ErrorS(err, "failed to update NodeFeature object", "nodefeature", klog.KObj(obj))
+		}
+	}
+}
+
+// hasNodeOwnerRef returns true if the object is owned by the given node.
+func hasNodeOwnerRef(obj metav1.Object, nodeName string) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.APIVersion == "v1" && ref.Kind == "Node" && ref.Name == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// updateNodeFeatureStatus records the generation of the NodeFeature objects
+// of a node that nfd-master has processed, together with the result of the
+// processing in the Processed condition. Failures are only logged, the
+// status is updated again on the next update of the node.
+func (m *nfdMaster) updateNodeFeatureStatus(nodeName string, processErr error) {
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	objs, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeature objects", "nodeName", nodeName)
+		return
+	}
+
+	for _, obj := range objs {
+		if obj.DeletionTimestamp != nil {
+			continue
+		}
+
+		status := obj.Status.DeepCopy()
+		status.ObservedGeneration = obj.Generation
+		meta.SetStatusCondition(&status.Conditions, m.nodeFeatureProcessedCondition(obj, processErr))
+		if cond := m.nodeFeatureSchemaCondition(obj); cond != nil {
+			meta.SetStatusCondition(&status.Conditions, *cond)
+		} else {
+			meta.RemoveStatusCondition(&status.Conditions, nfdv1alpha1.NodeFeatureSchemaValidCondition)
+		}
+		if apiequality.Semantic.DeepEqual(&obj.Status, status) {
+			continue
+		}
+
+		updated := obj.DeepCopy()
+		updated.Status = *status
+		klog.V(2).InfoS("updating NodeFeature status", "nodefeature", klog.KObj(obj))
+		if _, err := m.nfdController.nfdClient.NfdV1alpha1().NodeFeatures(obj.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{}); err != nil {
+			klog.ErrorS(err, "failed to update NodeFeature status", "nodefeature", klog.KObj(obj))
+		}
+	}
+}
+
+// nodeFeatureProcessedCondition returns the Processed condition of a
+// NodeFeature object. Objects that were ignored because of an untrusted
+// producer or an invalid signature are reported as not processed.
+func (m *nfdMaster) nodeFeatureProcessedCondition(obj *nfdv1alpha1.NodeFeature, processErr error) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               nfdv1alpha1.NodeFeatureProcessedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: obj.Generation,
+		Reason:             nodeFeatureProcessedReason,
+		Message:            "Node updated",
+	}
+	if len(m.config.NodeFeatureProducers) > 0 && obj.Namespace != m.namespace && !m.fromAllowedProducer(obj) {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nodeFeatureNotAllowedReason
+		cond.Message = "Object not from an allowed producer, ignored"
+	} else if err := m.verifyNodeFeatureSignature(obj); err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nodeFeatureUnverifiedReason
+		cond.Message = err.Error()
+	} else if processErr != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = nodeFeatureFailedReason
+		cond.Message = processErr.Error()
+	}
+	return cond
+}
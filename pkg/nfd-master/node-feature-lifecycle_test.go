@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestUpdateNodeFeatureObjects(t *testing.T) {
+	Convey("When updating NodeFeature objects of a node", t, func() {
+		newNodeFeature := func(name string, deleting bool, finalizers ...string) *nfdv1alpha1.NodeFeature {
+			nf := &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       name,
+					Namespace:  "nfd",
+					Labels:     map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: testNodeName},
+					Finalizers: finalizers,
+				},
+			}
+			if deleting {
+				nf.DeletionTimestamp = &metav1.Time{}
+			}
+			return nf
+		}
+		node := newTestNode()
+		node.UID = types.UID("node-uid")
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(node))
+		fakeNfdCli := fakenfdclient.NewSimpleClientset(
+			newNodeFeature("nf-1", false),
+			newNodeFeature("nf-2", true, nfdv1alpha1.NodeCleanupFinalizer, "other"),
+		)
+		fakeMaster.nfdController = newFakeNfdAPIController(fakeNfdCli)
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		get := func(name string) *nfdv1alpha1.NodeFeature {
+			nf, err := fakeNfdCli.NfdV1alpha1().NodeFeatures("nfd").Get(context.TODO(), name, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			return nf
+		}
+
+		Convey("Node owner reference should be set", func() {
+			fakeMaster.updateNodeFeatureObjects(testNodeName)
+
+			nf := get("nf-1")
+			So(nf.OwnerReferences, ShouldHaveLength, 1)
+			So(nf.OwnerReferences[0].Kind, ShouldEqual, "Node")
+			So(nf.OwnerReferences[0].Name, ShouldEqual, testNodeName)
+			So(nf.OwnerReferences[0].UID, ShouldEqual, types.UID("node-uid"))
+			So(nf.Finalizers, ShouldBeEmpty)
+		})
+
+		Convey("Finalizer should be added if enabled", func() {
+			fakeMaster.config.NodeFeatureFinalizer = true
+			fakeMaster.updateNodeFeatureObjects(testNodeName)
+
+			So(get("nf-1").Finalizers, ShouldResemble, []string{nfdv1alpha1.NodeCleanupFinalizer})
+		})
+
+		Convey("Finalizer should be removed from objects being deleted", func() {
+			fakeMaster.config.NodeFeatureFinalizer = true
+			fakeMaster.updateNodeFeatureObjects(testNodeName)
+
+			nf := get("nf-2")
+			So(nf.Finalizers, ShouldResemble, []string{"other"})
+			So(nf.OwnerReferences, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestUpdateNodeFeatureStatus(t *testing.T) {
+	Convey("When updating the status of NodeFeature objects of a node", t, func() {
+		newNodeFeature := func(name, namespace string) *nfdv1alpha1.NodeFeature {
+			return &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       name,
+					Namespace:  namespace,
+					Generation: 2,
+					Labels:     map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: testNodeName},
+				},
+			}
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset(newTestNode()))
+		fakeMaster.namespace = "nfd"
+		fakeNfdCli := fakenfdclient.NewSimpleClientset(
+			newNodeFeature("nf-1", "nfd"),
+			newNodeFeature("nf-2", "vendor"),
+		)
+		fakeMaster.nfdController = newFakeNfdAPIController(fakeNfdCli)
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		getCondition := func(namespace, name string) (*nfdv1alpha1.NodeFeature, *metav1.Condition) {
+			nf, err := fakeNfdCli.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			return nf, meta.FindStatusCondition(nf.Status.Conditions, nfdv1alpha1.NodeFeatureProcessedCondition)
+		}
+
+		Convey("Successful processing should be reported", func() {
+			fakeMaster.updateNodeFeatureStatus(testNodeName, nil)
+
+			nf, cond := getCondition("nfd", "nf-1")
+			So(nf.Status.ObservedGeneration, ShouldEqual, 2)
+			So(cond, ShouldNotBeNil)
+			So(cond.Status, ShouldEqual, metav1.ConditionTrue)
+			So(cond.Reason, ShouldEqual, nodeFeatureProcessedReason)
+			So(cond.ObservedGeneration, ShouldEqual, 2)
+		})
+
+		Convey("Processing errors should be reported", func() {
+			fakeMaster.updateNodeFeatureStatus(testNodeName, fmt.Errorf("fake error"))
+
+			_, cond := getCondition("vendor", "nf-2")
+			So(cond, ShouldNotBeNil)
+			So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+			So(cond.Reason, ShouldEqual, nodeFeatureFailedReason)
+			So(cond.Message, ShouldEqual, "fake error")
+		})
+
+		Convey("Objects from disallowed producers should be reported", func() {
+			fakeMaster.config.NodeFeatureProducers = []NodeFeatureProducer{{Namespace: "other"}}
+			fakeMaster.updateNodeFeatureStatus(testNodeName, nil)
+
+			_, cond := getCondition("nfd", "nf-1")
+			So(cond.Status, ShouldEqual, metav1.ConditionTrue)
+			_, cond = getCondition("vendor", "nf-2")
+			So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+			So(cond.Reason, ShouldEqual, nodeFeatureNotAllowedReason)
+		})
+	})
+}
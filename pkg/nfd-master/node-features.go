@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+	taintutils "k8s.io/kubernetes/pkg/util/taints"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// addNodeFeatures adds the current labels, annotations, taints and selected
+// status fields of the node object as features in the "node" domain. Labels,
+// annotations, taints and extended resources managed by NFD itself are left
+// out so that rules cannot match on their own (previous) output. Any features
+// in the "node" domain published in NodeFeature objects are overridden.
+func (m *nfdMaster) addNodeFeatures(features *nfdv1alpha1.Features, node *corev1.Node) {
+	if features.Attributes == nil {
+		features.Attributes = make(map[string]nfdv1alpha1.AttributeFeatureSet)
+	}
+	if features.Instances == nil {
+		features.Instances = make(map[string]nfdv1alpha1.InstanceFeatureSet)
+	}
+
+	// Labels
+	managedLabels := m.labelOwnership().ownedNames(node.Annotations, keysOf(node.Labels))
+	labels := make(map[string]string, len(node.Labels))
+	for k, v := range node.Labels {
+		labels[k] = v
+	}
+	for _, k := range managedLabels {
+		delete(labels, k)
+	}
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeLabelsFeature)] = nfdv1alpha1.NewAttributeFeatures(labels)
+
+	// Annotations
+	managedAnnotations := m.annotationOwnership().ownedNames(node.Annotations, keysOf(node.Annotations))
+	annotations := make(map[string]string, len(node.Annotations))
+	for k, v := range node.Annotations {
+		if ns, _ := splitNs(k); ns == nfdv1alpha1.AnnotationNs || strings.HasSuffix(ns, "."+nfdv1alpha1.AnnotationNs) {
+			continue
+		}
+		annotations[k] = v
+	}
+	for _, k := range managedAnnotations {
+		delete(annotations, k)
+	}
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeAnnotationsFeature)] = nfdv1alpha1.NewAttributeFeatures(annotations)
+
+	// Taints
+	var managedTaints []corev1.Taint
+	if val := node.Annotations[nfdv1alpha1.NodeTaintsAnnotation]; val != "" {
+		var err error
+		managedTaints, _, err = taintutils.ParseTaints(strings.Split(val, ","))
+		if err != nil {
+			klog.ErrorS(err, "failed to parse taints annotation", "nodeName", node.Name)
+		}
+	}
+	taints := make([]nfdv1alpha1.InstanceFeature, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		if taintutils.TaintExists(managedTaints, &taint) {
+			continue
+		}
+		taints = append(taints, *nfdv1alpha1.NewInstanceFeature(map[string]string{
+			"key":    taint.Key,
+			"value":  taint.Value,
+			"effect": string(taint.Effect),
+		}))
+	}
+	features.Instances[nodeFeatureName(nfdv1alpha1.NodeTaintsFeature)] = nfdv1alpha1.NewInstanceFeatures(taints)
+
+	// Status
+	nodeInfo := node.Status.NodeInfo
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeInfoFeature)] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+		"architecture":            nodeInfo.Architecture,
+		"containerRuntimeVersion": nodeInfo.ContainerRuntimeVersion,
+		"kernelVersion":           nodeInfo.KernelVersion,
+		"kubeletVersion":          nodeInfo.KubeletVersion,
+		"operatingSystem":         nodeInfo.OperatingSystem,
+		"osImage":                 nodeInfo.OSImage,
+	})
+
+	kubeletVersion := map[string]string{"full": nodeInfo.KubeletVersion}
+	if v, err := version.ParseGeneric(nodeInfo.KubeletVersion); err == nil {
+		kubeletVersion["major"] = strconv.FormatUint(uint64(v.Major()), 10)
+		kubeletVersion["minor"] = strconv.FormatUint(uint64(v.Minor()), 10)
+		kubeletVersion["patch"] = strconv.FormatUint(uint64(v.Patch()), 10)
+	} else if nodeInfo.KubeletVersion != "" {
+		klog.V(2).InfoS("failed to parse kubelet version", "nodeName", node.Name, "kubeletVersion", nodeInfo.KubeletVersion, "error", err)
+	}
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeKubeletVersionFeature)] = nfdv1alpha1.NewAttributeFeatures(kubeletVersion)
+
+	managedResources := m.extendedResourceOwnership().ownedResources(node)
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeCapacityFeature)] = nfdv1alpha1.NewAttributeFeatures(resourceListToFeatures(node.Status.Capacity, managedResources))
+	features.Attributes[nodeFeatureName(nfdv1alpha1.NodeAllocatableFeature)] = nfdv1alpha1.NewAttributeFeatures(resourceListToFeatures(node.Status.Allocatable, managedResources))
+}
+
+// resourceListToFeatures converts a resource list into feature attributes.
+// The quantities are converted into integers (rounded up) in the base unit of
+// the resource, e.g. bytes for memory and cores for cpu, so that they can be
+// matched with the Gt and Lt operators.
+func resourceListToFeatures(resources corev1.ResourceList, exclude []string) map[string]string {
+	attrs := make(map[string]string, len(resources))
+	for name, q := range resources {
+		attrs[string(name)] = strconv.FormatInt(q.Value(), 10)
+	}
+	for _, name := range exclude {
+		delete(attrs, name)
+	}
+	return attrs
+}
+
+func nodeFeatureName(feature string) string {
+	return nfdv1alpha1.NodeFeatureDomain + "." + feature
+}
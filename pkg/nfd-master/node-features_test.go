@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+func TestAddNodeFeatures(t *testing.T) {
+	Convey("When adding node features", t, func() {
+		fakeMaster := newFakeMaster(nil)
+
+		node := newTestNode()
+		node.Labels["node-role.kubernetes.io/worker"] = ""
+		node.Labels[nfdv1alpha1.FeatureLabelNs+"/nfd-label"] = "true"
+		node.Annotations["example.com/annotation"] = "foo"
+		node.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = "nfd-label"
+		node.Annotations[nfdv1alpha1.FeatureAnnotationsTrackingAnnotation] = "nfd-annotation"
+		node.Annotations[nfdv1alpha1.FeatureAnnotationNs+"/nfd-annotation"] = "true"
+		node.Annotations[nfdv1alpha1.NodeTaintsAnnotation] = nfdv1alpha1.TaintNs + "/nfd-taint=true:NoSchedule"
+		node.Spec.Taints = []corev1.Taint{
+			{Key: "example.com/taint", Value: "foo", Effect: corev1.TaintEffectNoExecute},
+			{Key: nfdv1alpha1.TaintNs + "/nfd-taint", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+		}
+		node.Annotations[nfdv1alpha1.ExtendedResourceAnnotation] = "nfd-er"
+		node.Status.Capacity = corev1.ResourceList{
+			corev1.ResourceCPU:                     resource.MustParse("3500m"),
+			corev1.ResourceMemory:                  resource.MustParse("256Gi"),
+			nfdv1alpha1.FeatureLabelNs + "/nfd-er": resource.MustParse("1"),
+		}
+		node.Status.Allocatable = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("200Gi")}
+		node.Status.NodeInfo = corev1.NodeSystemInfo{
+			Architecture:   "amd64",
+			KubeletVersion: "v1.29.3",
+			OSImage:        "Red Hat Enterprise Linux CoreOS",
+		}
+
+		features := nfdv1alpha1.NewFeatures()
+		features.InsertAttributeFeatures(nfdv1alpha1.NodeFeatureDomain, nfdv1alpha1.NodeLabelsFeature, map[string]string{"spoofed": "true"})
+		fakeMaster.addNodeFeatures(features, node)
+
+		Convey("node labels, annotations and taints should be available", func() {
+			So(features.Attributes["node.labels"].Elements, ShouldResemble, map[string]string{"node-role.kubernetes.io/worker": ""})
+			So(features.Attributes["node.annotations"].Elements, ShouldResemble, map[string]string{"example.com/annotation": "foo"})
+			So(features.Instances["node.taints"].Elements, ShouldResemble, []nfdv1alpha1.InstanceFeature{
+				*nfdv1alpha1.NewInstanceFeature(map[string]string{"key": "example.com/taint", "value": "foo", "effect": "NoExecute"}),
+			})
+		})
+
+		Convey("node status should be available", func() {
+			nodeInfo := features.Attributes["node.nodeinfo"].Elements
+			So(nodeInfo["architecture"], ShouldEqual, "amd64")
+			So(nodeInfo["osImage"], ShouldEqual, "Red Hat Enterprise Linux CoreOS")
+			So(features.Attributes["node.kubeletversion"].Elements, ShouldResemble, map[string]string{
+				"full": "v1.29.3", "major": "1", "minor": "29", "patch": "3",
+			})
+			So(features.Attributes["node.capacity"].Elements, ShouldResemble, map[string]string{
+				"cpu": "4", "memory": "274877906944",
+			})
+			So(features.Attributes["node.allocatable"].Elements, ShouldResemble, map[string]string{"memory": "214748364800"})
+		})
+
+		Convey("rules should be able to match node status", func() {
+			rule := &nfdv1alpha1.Rule{
+				Name:   "big-node",
+				Labels: map[string]string{"big-node": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					nfdv1alpha1.FeatureMatcherTerm{
+						Feature: "node.kubeletversion",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"major": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchIn, Value: []string{"1"}},
+							"minor": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: []string{"28"}},
+						},
+					},
+					nfdv1alpha1.FeatureMatcherTerm{
+						Feature: "node.capacity",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"memory": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchGt, Value: []string{"137438953472"}},
+						},
+					},
+				},
+			}
+			out, err := nodefeaturerule.Execute(rule, features)
+			So(err, ShouldBeNil)
+			So(out.Labels, ShouldResemble, map[string]string{"big-node": "true"})
+		})
+
+		Convey("rules should be able to match node labels", func() {
+			rule := &nfdv1alpha1.Rule{
+				Name:   "worker",
+				Labels: map[string]string{"worker": "true"},
+				MatchFeatures: nfdv1alpha1.FeatureMatcher{
+					nfdv1alpha1.FeatureMatcherTerm{
+						Feature: "node.labels",
+						MatchExpressions: &nfdv1alpha1.MatchExpressionSet{
+							"node-role.kubernetes.io/worker": &nfdv1alpha1.MatchExpression{Op: nfdv1alpha1.MatchExists},
+						},
+					},
+				},
+			}
+			out, err := nodefeaturerule.Execute(rule, features)
+			So(err, ShouldBeNil)
+			So(out.Labels, ShouldResemble, map[string]string{"worker": "true"})
+		})
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
@@ -54,15 +55,31 @@ func (u *nodeUpdaterPool) processNodeUpdateRequest(queue workqueue.RateLimitingI
 			klog.InfoS("retrying node update", "nodeName", nodeName, "lastError", err)
 			queue.AddRateLimited(nodeName)
 			return true
-		} else {
-			klog.ErrorS(err, "failed to update node", "nodeName", nodeName)
-			nodeUpdateFailures.Inc()
 		}
+		klog.ErrorS(err, "failed to update node", "nodeName", nodeName)
+		nodeUpdateFailures.Inc()
 	}
 	queue.Forget(nodeName)
+	// Nodes that we gave up on are also retried on the next resync
+	u.scheduleResync(queue, nodeName.(string))
 	return true
 }
 
+// scheduleResync re-queues a node after the configured resync period. The
+// period is jittered per node so that the nodes of the cluster get evenly
+// spread out instead of all being reconciled at once. A node already waiting
+// for its resync keeps its earlier resync time.
+func (u *nodeUpdaterPool) scheduleResync(queue workqueue.RateLimitingInterface, nodeName string) {
+	if queue == nil || !u.nfdMaster.args.EnableNodeFeatureApi {
+		return
+	}
+	period := u.nfdMaster.config.ResyncPeriod.Duration
+	if period <= 0 {
+		return
+	}
+	queue.AddAfter(nodeName, wait.Jitter(period, u.nfdMaster.config.ResyncJitter))
+}
+
 func (u *nodeUpdaterPool) runNodeUpdater(queue workqueue.RateLimitingInterface) {
 	for u.processNodeUpdateRequest(queue) {
 	}
@@ -86,7 +103,10 @@ func (u *nodeUpdaterPool) start(parallelism int) {
 		workqueue.NewItemExponentialFailureRateLimiter(50*time.Millisecond, 100*time.Second),
 		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
 	)
-	u.queue = workqueue.NewRateLimitingQueue(rl)
+	u.queue = workqueue.NewRateLimitingQueueWithConfig(rl, workqueue.RateLimitingQueueConfig{
+		Name:            "nfd_node_update",
+		MetricsProvider: nodeUpdateQueueMetricsProvider{},
+	})
 
 	for i := 0; i < parallelism; i++ {
 		u.wg.Add(1)
@@ -94,6 +114,16 @@ func (u *nodeUpdaterPool) start(parallelism int) {
 	}
 }
 
+// enqueue queues a node for update if the pool is running.
+func (u *nodeUpdaterPool) enqueue(nodeName string) {
+	u.Lock()
+	defer u.Unlock()
+
+	if u.queue != nil && !u.queue.ShuttingDown() {
+		u.queue.Add(nodeName)
+	}
+}
+
 func (u *nodeUpdaterPool) stop() {
 	u.Lock()
 	defer u.Unlock()
@@ -107,3 +137,42 @@ func (u *nodeUpdaterPool) stop() {
 	u.queue.ShutDown()
 	u.wg.Wait()
 }
+
+// ruleEvaluationLimiter bounds the number of nodes whose rules are evaluated
+// concurrently. Rule evaluation is CPU bound while the rest of a node update
+// mostly waits for the API server, so the limit is separate from the size of
+// the node updater pool. The zero value does not limit concurrency.
+type ruleEvaluationLimiter struct {
+	mu    sync.RWMutex
+	slots chan struct{}
+}
+
+// setLimit sets the maximum number of concurrent rule evaluations, zero
+// meaning no limit. Evaluations running with the previous limit are not
+// affected.
+func (l *ruleEvaluationLimiter) setLimit(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n <= 0 {
+		l.slots = nil
+		return
+	}
+	l.slots = make(chan struct{}, n)
+}
+
+// acquire waits for a free evaluation slot. The returned function releases
+// the slot.
+func (l *ruleEvaluationLimiter) acquire() (release func()) {
+	l.mu.RLock()
+	slots := l.slots
+	l.mu.RUnlock()
+
+	if slots == nil {
+		return func() {}
+	}
+	start := time.Now()
+	slots <- struct{}{}
+	ruleEvaluationWaitTime.Observe(time.Since(start).Seconds())
+	return func() { <-slots }
+}
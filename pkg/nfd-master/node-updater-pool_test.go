@@ -23,7 +23,9 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 	fakek8sclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
 	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
 )
 
 func newFakeNodeUpdaterPool(nfdMaster *nfdMaster) *nodeUpdaterPool {
@@ -84,3 +86,137 @@ func TestRunNodeUpdater(t *testing.T) {
 			withTimeout, 2*time.Second, ShouldEqual, 0)
 	})
 }
+
+func TestResyncNode(t *testing.T) {
+	fakeMaster := newFakeMaster(nil)
+	fakeMaster.nodeUpdaterPool = newFakeNodeUpdaterPool(fakeMaster)
+
+	Convey("When a node resync is requested", t, func() {
+		Convey("Node should not be queued if the pool is not running", func() {
+			fakeMaster.updatingNodes.Store(true)
+			fakeMaster.resyncNode(testNodeName)
+			So(fakeMaster.nodeUpdaterPool.queue, ShouldBeNil)
+		})
+
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+		fakeMaster.nodeUpdaterPool.queue = queue
+
+		Convey("Node should not be queued if the instance does not handle node updates", func() {
+			fakeMaster.updatingNodes.Store(false)
+			fakeMaster.resyncNode(testNodeName)
+			So(queue.Len(), ShouldEqual, 0)
+		})
+		Convey("Node should be queued if the instance handles node updates", func() {
+			fakeMaster.updatingNodes.Store(true)
+			fakeMaster.resyncNode(testNodeName)
+			So(queue.Len(), ShouldEqual, 1)
+		})
+	})
+}
+
+func TestScheduleResync(t *testing.T) {
+	fakeMaster := newFakeMaster(nil)
+	fakeMaster.args.EnableNodeFeatureApi = true
+	fakeMaster.config.ResyncPeriod = utils.DurationVal{Duration: 100 * time.Millisecond}
+	fakeMaster.config.ResyncJitter = 0.5
+	nodeUpdaterPool := newFakeNodeUpdaterPool(fakeMaster)
+
+	Convey("When scheduling a resync of a node", t, func() {
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+
+		nodeUpdaterPool.scheduleResync(queue, testNodeName)
+		Convey("Node should not be queued immediately", func() {
+			So(queue.Len(), ShouldEqual, 0)
+		})
+		Convey("Node should be queued after the resync period", func() {
+			So(func() interface{} { return queue.Len() },
+				withTimeout, 2*time.Second, ShouldEqual, 1)
+		})
+	})
+
+	Convey("When resync is disabled", t, func() {
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		defer queue.ShutDown()
+
+		fakeMaster.config.ResyncPeriod = utils.DurationVal{}
+		nodeUpdaterPool.scheduleResync(queue, testNodeName)
+		Convey("Node should not be queued", func() {
+			time.Sleep(200 * time.Millisecond)
+			So(queue.Len(), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestResyncAfterFailure(t *testing.T) {
+	fakeMaster := newFakeMaster(fakek8sclient.NewSimpleClientset())
+	fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset())
+	fakeMaster.args.EnableNodeFeatureApi = true
+	fakeMaster.config.ResyncPeriod = utils.DurationVal{Duration: 100 * time.Millisecond}
+	nodeUpdaterPool := newFakeNodeUpdaterPool(fakeMaster)
+
+	Convey("When giving up on updating a node", t, func() {
+		queue := workqueue.NewRateLimitingQueue(workqueue.NewItemFastSlowRateLimiter(0, 0, 0))
+		defer queue.ShutDown()
+
+		// The node does not exist so updating it fails
+		queue.Add("no-such-node")
+		for i := 0; i <= 15; i++ {
+			So(nodeUpdaterPool.processNodeUpdateRequest(queue), ShouldBeTrue)
+		}
+		Convey("Node should be queued again after the resync period", func() {
+			So(queue.NumRequeues("no-such-node"), ShouldEqual, 0)
+			So(queue.Len(), ShouldEqual, 0)
+			So(func() interface{} { return queue.Len() },
+				withTimeout, 2*time.Second, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRuleEvaluationLimiter(t *testing.T) {
+	Convey("When limiting concurrent rule evaluations", t, func() {
+		l := ruleEvaluationLimiter{}
+
+		Convey("the zero value should not limit concurrency", func() {
+			for i := 0; i < 100; i++ {
+				defer l.acquire()()
+			}
+		})
+		Convey("no more evaluations than the limit should run concurrently", func() {
+			l.setLimit(2)
+			release1 := l.acquire()
+			release2 := l.acquire()
+
+			acquired := make(chan struct{})
+			go func() {
+				release := l.acquire()
+				close(acquired)
+				release()
+			}()
+			select {
+			case <-acquired:
+				t.Fatal("acquired an evaluation slot above the limit")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			release1()
+			So(func() interface{} {
+				select {
+				case <-acquired:
+					return true
+				default:
+					return false
+				}
+			}, withTimeout, 2*time.Second, ShouldBeTrue)
+			release2()
+		})
+		Convey("a changed limit should not affect running evaluations", func() {
+			l.setLimit(1)
+			release := l.acquire()
+			l.setLimit(0)
+			l.acquire()()
+			release()
+		})
+	})
+}
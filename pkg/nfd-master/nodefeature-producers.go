@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// NodeFeatureProducer specifies a trusted producer of NodeFeature objects.
+type NodeFeatureProducer struct {
+	// Namespace is the namespace the producer creates its objects in. The
+	// API server does not record the creator of an object, so namespaces,
+	// protected by RBAC, are what authenticates the producer.
+	Namespace string
+	// Annotations are the annotations the objects of the producer are
+	// required to have. An empty value matches any value.
+	Annotations map[string]string
+}
+
+// validate checks that the producer is well-formed.
+func (p *NodeFeatureProducer) validate() error {
+	if errs := validation.IsDNS1123Label(p.Namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", p.Namespace, strings.Join(errs, "; "))
+	}
+	for k := range p.Annotations {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation %q: %s", k, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// matches returns true if the NodeFeature object is from the producer.
+func (p *NodeFeatureProducer) matches(obj *nfdv1alpha1.NodeFeature) bool {
+	if obj.Namespace != p.Namespace {
+		return false
+	}
+	for k, v := range p.Annotations {
+		if a, ok := obj.Annotations[k]; !ok || (v != "" && a != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeFeatureAllowed returns true if the NodeFeature object is from an
+// allowed producer. All objects are allowed if no producers have been
+// configured. Objects in the namespace of nfd-master (i.e. created by
+// nfd-worker) are always allowed.
+func (m *nfdMaster) nodeFeatureAllowed(obj *nfdv1alpha1.NodeFeature) bool {
+	if len(m.config.NodeFeatureProducers) == 0 || obj.Namespace == m.namespace {
+		return true
+	}
+	if m.fromAllowedProducer(obj) {
+		return true
+	}
+	klog.ErrorS(nil, "NodeFeature object not from an allowed producer, ignoring", "nodefeature", klog.KObj(obj))
+	nodeFeaturesRejected.Inc()
+	return false
+}
+
+// fromAllowedProducer returns true if the NodeFeature object matches one of
+// the configured producers.
+func (m *nfdMaster) fromAllowedProducer(obj *nfdv1alpha1.NodeFeature) bool {
+	for i := range m.config.NodeFeatureProducers {
+		if m.config.NodeFeatureProducers[i].matches(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeFeatureVerified returns true if the NodeFeature object has a valid
+// signature, or if signature verification is disabled.
+func (m *nfdMaster) nodeFeatureVerified(obj *nfdv1alpha1.NodeFeature) bool {
+	if err := m.verifyNodeFeatureSignature(obj); err != nil {
+		klog.ErrorS(err, "NodeFeature signature verification failed, ignoring", "nodefeature", klog.KObj(obj))
+		nodeFeatureSignatureFailures.Inc()
+		return false
+	}
+	return true
+}
+
+// verifyNodeFeatureSignature verifies the signature of the NodeFeature
+// object. Nil is returned if signature verification is disabled.
+func (m *nfdMaster) verifyNodeFeatureSignature(obj *nfdv1alpha1.NodeFeature) error {
+	if m.signingKeys == nil {
+		return nil
+	}
+	return utils.VerifyNodeFeature(m.signingKeys, obj)
+}
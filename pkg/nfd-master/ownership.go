@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+const (
+	// OwnershipRecordFormatList records the names of NFD-managed node
+	// properties as a comma-separated list.
+	OwnershipRecordFormatList = "list"
+	// OwnershipRecordFormatHash records hashes of the names of NFD-managed
+	// node properties. The size of the record does not depend on the
+	// length of the names.
+	OwnershipRecordFormatHash = "hash"
+
+	// hashRecordPrefix identifies the hash function of a hashed ownership
+	// record.
+	hashRecordPrefix = "fnv64a:"
+)
+
+// ownershipRecord describes the node annotations that record which labels,
+// annotations or extended resources of a node are managed by nfd-master.
+type ownershipRecord struct {
+	// listAnnotation holds a comma-separated list of the names, with the
+	// default namespace omitted.
+	listAnnotation string
+	// hashAnnotation holds the hashes of the (fully qualified) names.
+	hashAnnotation string
+	// ns is the default namespace of the names.
+	ns string
+}
+
+func (m *nfdMaster) labelOwnership() ownershipRecord {
+	return ownershipRecord{
+		listAnnotation: m.instanceAnnotation(nfdv1alpha1.FeatureLabelsAnnotation),
+		hashAnnotation: m.instanceAnnotation(nfdv1alpha1.FeatureLabelsHashAnnotation),
+		ns:             nfdv1alpha1.FeatureLabelNs,
+	}
+}
+
+func (m *nfdMaster) annotationOwnership() ownershipRecord {
+	return ownershipRecord{
+		listAnnotation: m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsTrackingAnnotation),
+		hashAnnotation: m.instanceAnnotation(nfdv1alpha1.FeatureAnnotationsHashAnnotation),
+		ns:             nfdv1alpha1.FeatureAnnotationNs,
+	}
+}
+
+func (m *nfdMaster) extendedResourceOwnership() ownershipRecord {
+	return ownershipRecord{
+		listAnnotation: m.instanceAnnotation(nfdv1alpha1.ExtendedResourceAnnotation),
+		hashAnnotation: m.instanceAnnotation(nfdv1alpha1.ExtendedResourceHashAnnotation),
+		ns:             nfdv1alpha1.FeatureLabelNs,
+	}
+}
+
+// annotations returns the names of the annotations used by the record.
+func (r ownershipRecord) annotations() []string {
+	return []string{r.listAnnotation, r.hashAnnotation}
+}
+
+// ownedNames returns the names recorded in the node annotations. Hashed
+// records cannot be reversed and are resolved against candidates, i.e. the
+// names present on the node. Names from both annotations are returned so
+// that nodes are migrated seamlessly when the record format is changed.
+func (r ownershipRecord) ownedNames(annotations map[string]string, candidates []string) []string {
+	names := stringToNsNames(annotations[r.listAnnotation], r.ns)
+
+	if val, ok := annotations[r.hashAnnotation]; ok {
+		hashes, err := parseHashRecord(val)
+		if err != nil {
+			klog.ErrorS(err, "failed to parse ownership record", "annotation", r.hashAnnotation)
+		}
+		for _, name := range candidates {
+			if _, ok := slices.BinarySearch(hashes, hashName(name)); ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// setOwnedNames stores the names in the annotation of the given format. No
+// annotation is created if there are no names.
+func (r ownershipRecord) setOwnedNames(annotations Annotations, names []string, format string) {
+	if len(names) == 0 {
+		return
+	}
+	if format == OwnershipRecordFormatHash {
+		annotations[r.hashAnnotation] = formatHashRecord(names)
+		return
+	}
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		// Drop the ns part for names in the default ns
+		keys = append(keys, strings.TrimPrefix(name, r.ns+"/"))
+	}
+	sort.Strings(keys)
+	annotations[r.listAnnotation] = strings.Join(keys, ",")
+}
+
+// ownedLabels returns the labels of a node that are managed by nfd-master.
+func (r ownershipRecord) ownedLabels(node *corev1.Node) map[string]string {
+	labels := make(map[string]string)
+	for _, name := range r.ownedNames(node.Annotations, keysOf(node.Labels)) {
+		if value, ok := node.Labels[name]; ok {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// ownedResources returns the extended resources of a node that are managed
+// by nfd-master.
+func (r ownershipRecord) ownedResources(node *corev1.Node) []string {
+	candidates := keysOf(node.Status.Capacity)
+	for name := range node.Status.Allocatable {
+		if _, ok := node.Status.Capacity[name]; !ok {
+			candidates = append(candidates, string(name))
+		}
+	}
+	return r.ownedNames(node.Annotations, candidates)
+}
+
+func hashName(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
+// formatHashRecord encodes the sorted hashes of the names as base64.
+func formatHashRecord(names []string) string {
+	hashes := make([]uint64, 0, len(names))
+	for _, name := range names {
+		hashes = append(hashes, hashName(name))
+	}
+	slices.Sort(hashes)
+	hashes = slices.Compact(hashes)
+
+	buf := make([]byte, 0, 8*len(hashes))
+	for _, h := range hashes {
+		buf = binary.BigEndian.AppendUint64(buf, h)
+	}
+	return hashRecordPrefix + base64.RawStdEncoding.EncodeToString(buf)
+}
+
+// parseHashRecord decodes a record created by formatHashRecord.
+func parseHashRecord(val string) ([]uint64, error) {
+	encoded, ok := strings.CutPrefix(val, hashRecordPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported ownership record format %q", val)
+	}
+	buf, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ownership record: %w", err)
+	}
+	if len(buf)%8 != 0 {
+		return nil, fmt.Errorf("invalid ownership record length %d", len(buf))
+	}
+	hashes := make([]uint64, 0, len(buf)/8)
+	for i := 0; i < len(buf); i += 8 {
+		hashes = append(hashes, binary.BigEndian.Uint64(buf[i:]))
+	}
+	slices.Sort(hashes)
+	return hashes, nil
+}
+
+// keysOf returns the keys of a map as strings.
+func keysOf[M ~map[K]V, K ~string, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, string(k))
+	}
+	return keys
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestOwnershipRecord(t *testing.T) {
+	Convey("When using ownership records", t, func() {
+		r := (&nfdMaster{}).labelOwnership()
+		names := []string{nfdv1alpha1.FeatureLabelNs + "/cpu-foo", "example.com/bar"}
+		candidates := append([]string{"kubernetes.io/hostname"}, names...)
+
+		Convey("A list record should be resolved to the recorded names", func() {
+			annotations := Annotations{}
+			r.setOwnedNames(annotations, names, OwnershipRecordFormatList)
+			So(annotations, ShouldResemble, Annotations{nfdv1alpha1.FeatureLabelsAnnotation: "cpu-foo,example.com/bar"})
+			So(r.ownedNames(annotations, candidates), ShouldResemble, []string{nfdv1alpha1.FeatureLabelNs + "/cpu-foo", "example.com/bar"})
+		})
+
+		Convey("A hash record should be resolved against the candidates", func() {
+			annotations := Annotations{}
+			r.setOwnedNames(annotations, names, OwnershipRecordFormatHash)
+			So(annotations, ShouldHaveLength, 1)
+			So(annotations[nfdv1alpha1.FeatureLabelsHashAnnotation], ShouldStartWith, hashRecordPrefix)
+			So(r.ownedNames(annotations, candidates), ShouldResemble, names)
+		})
+
+		Convey("An invalid hash record should be ignored", func() {
+			annotations := map[string]string{nfdv1alpha1.FeatureLabelsHashAnnotation: "foo"}
+			So(r.ownedNames(annotations, candidates), ShouldBeEmpty)
+		})
+
+		Convey("No annotation should be created without names", func() {
+			annotations := Annotations{}
+			r.setOwnedNames(annotations, nil, OwnershipRecordFormatHash)
+			So(annotations, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestOwnershipRecordMigration(t *testing.T) {
+	Convey("When switching to hashed ownership records", t, func() {
+		testNode := newTestNode()
+		testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/old-feature"] = "old-value"
+		testNode.Labels[nfdv1alpha1.FeatureLabelNs+"/feature-1"] = "old-value"
+		testNode.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = "feature-1,old-feature"
+
+		fakeCli := fakeclient.NewSimpleClientset(testNode)
+		fakeMaster := newFakeMaster(fakeCli)
+		fakeMaster.config.OwnershipRecordFormat = OwnershipRecordFormatHash
+
+		labels := Labels{nfdv1alpha1.FeatureLabelNs + "/feature-1": "true"}
+		err := fakeMaster.updateNodeObject(testNodeName, labels, nil, nil, nil, nil, nil)
+		So(err, ShouldBeNil)
+
+		updatedNode, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+		So(err, ShouldBeNil)
+
+		Convey("Labels recorded in the old annotation should be managed", func() {
+			So(updatedNode.Labels, ShouldResemble, map[string]string(labels))
+		})
+		Convey("The old annotation should be replaced by the hash record", func() {
+			So(updatedNode.Annotations, ShouldNotContainKey, nfdv1alpha1.FeatureLabelsAnnotation)
+			So(updatedNode.Annotations, ShouldContainKey, nfdv1alpha1.FeatureLabelsHashAnnotation)
+			So(fakeMaster.labelOwnership().ownedNames(updatedNode.Annotations, keysOf(updatedNode.Labels)), ShouldResemble, keysOf(labels))
+		})
+	})
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	taintutils "k8s.io/kubernetes/pkg/util/taints"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// nodePruneItems describes the NFD-managed properties of a node object.
+type nodePruneItems struct {
+	Labels            []string
+	Annotations       []string
+	Taints            []string
+	ExtendedResources []string
+	Cordoned          bool
+}
+
+func (p nodePruneItems) empty() bool {
+	return len(p.Labels) == 0 && len(p.Annotations) == 0 && len(p.Taints) == 0 && len(p.ExtendedResources) == 0 && !p.Cordoned
+}
+
+// prune erases all NFD related properties from the node objects of the
+// cluster and deletes all NodeFeature objects and the NodeResourceTopology
+// objects created by nfd-topology-updater.
+func (m *nfdMaster) prune() error {
+	if m.config.NoPublish {
+		klog.InfoS("skipping pruning of nodes as noPublish config option is set")
+		return nil
+	}
+
+	kubeconfig, err := utils.GetKubeconfig(m.args.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	nfdCli, err := nfdclientset.NewForConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	topoCli, err := topologyclientset.NewForConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	return m.pruneCluster(nfdCli, topoCli)
+}
+
+func (m *nfdMaster) pruneCluster(nfdCli nfdclientset.Interface, topoCli topologyclientset.Interface) error {
+	// Prune all nodes, including the ones not matching the node selectors
+	nodes, err := m.k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if m.args.PruneDryRun {
+			if items := m.getNodePruneItems(node); !items.empty() {
+				klog.InfoS("would prune node", "nodeName", node.Name, "labels", items.Labels, "annotations", items.Annotations,
+					"taints", items.Taints, "extendedResources", items.ExtendedResources, "uncordon", items.Cordoned)
+			}
+			continue
+		}
+		if err := m.pruneNode(node.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := m.pruneNodeFeatures(nfdCli); err != nil {
+		return err
+	}
+	return m.pruneNodeResourceTopologies(topoCli, nodes.Items)
+}
+
+// pruneNode removes all NFD-managed labels, annotations, taints and extended
+// resources from a node and uncordons it if it was cordoned by NFD.
+func (m *nfdMaster) pruneNode(nodeName string) error {
+	klog.InfoS("pruning node...", "nodeName", nodeName)
+
+	// Prune labels, extended resources and taints
+	err := m.updateNodeObject(nodeName, Labels{}, Annotations{}, ExtendedResources{}, []corev1.Taint{}, nil, nil)
+	if err != nil {
+		nodeUpdateFailures.Inc()
+		return fmt.Errorf("failed to prune node %q: %v", nodeName, err)
+	}
+
+	// Prune annotations
+	node, err := m.getNode(nodeName)
+	if err != nil {
+		return err
+	}
+	maps.DeleteFunc(node.Annotations, func(k, v string) bool {
+		return strings.HasPrefix(k, m.instanceAnnotation(nfdv1alpha1.AnnotationNs))
+	})
+	_, err = m.k8sClient.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to prune annotations from node %q: %v", nodeName, err)
+	}
+	return nil
+}
+
+// getNodePruneItems returns the NFD-managed properties that pruneNode would
+// remove from a node.
+func (m *nfdMaster) getNodePruneItems(node *corev1.Node) nodePruneItems {
+	items := nodePruneItems{}
+
+	for l := range m.labelOwnership().ownedLabels(node) {
+		items.Labels = append(items.Labels, l)
+	}
+	slices.Sort(items.Labels)
+
+	for _, r := range m.extendedResourceOwnership().ownedResources(node) {
+		_, inCapacity := node.Status.Capacity[corev1.ResourceName(r)]
+		_, inAllocatable := node.Status.Allocatable[corev1.ResourceName(r)]
+		if inCapacity || inAllocatable {
+			items.ExtendedResources = append(items.ExtendedResources, r)
+		}
+	}
+
+	if val, ok := node.Annotations[nfdv1alpha1.NodeTaintsAnnotation]; ok {
+		taints, _, err := taintutils.ParseTaints(strings.Split(val, ","))
+		if err != nil {
+			klog.ErrorS(err, "failed to parse taints annotation", "nodeName", node.Name)
+		}
+		for _, t := range taints {
+			if taintutils.TaintExists(node.Spec.Taints, &t) {
+				items.Taints = append(items.Taints, t.ToString())
+			}
+		}
+	}
+
+	featureAnnotations := m.annotationOwnership().ownedNames(node.Annotations, keysOf(node.Annotations))
+	for a := range node.Annotations {
+		if strings.HasPrefix(a, m.instanceAnnotation(nfdv1alpha1.AnnotationNs)) || slices.Contains(featureAnnotations, a) {
+			items.Annotations = append(items.Annotations, a)
+		}
+	}
+	slices.Sort(items.Annotations)
+
+	_, owned := node.Annotations[m.instanceAnnotation(nfdv1alpha1.NodeCordonAnnotation)]
+	items.Cordoned = owned && node.Spec.Unschedulable
+
+	return items
+}
+
+// pruneNodeFeatures removes the node cleanup finalizer from all NodeFeature
+// objects of the cluster and deletes them.
+func (m *nfdMaster) pruneNodeFeatures(cli nfdclientset.Interface) error {
+	objs, err := cli.NfdV1alpha1().NodeFeatures(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeature objects: %w", err)
+	}
+	for i := range objs.Items {
+		obj := &objs.Items[i]
+		if m.args.PruneDryRun {
+			klog.InfoS("would delete NodeFeature object", "nodefeature", klog.KObj(obj))
+			continue
+		}
+
+		if slices.Contains(obj.Finalizers, nfdv1alpha1.NodeCleanupFinalizer) {
+			klog.InfoS("removing finalizer from NodeFeature object", "nodefeature", klog.KObj(obj))
+			obj.Finalizers = slices.DeleteFunc(obj.Finalizers, func(f string) bool { return f == nfdv1alpha1.NodeCleanupFinalizer })
+			if _, err := cli.NfdV1alpha1().NodeFeatures(obj.Namespace).Update(context.TODO(), obj, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to remove finalizer from NodeFeature object %s/%s: %w", obj.Namespace, obj.Name, err)
+			}
+		}
+
+		err := cli.NfdV1alpha1().NodeFeatures(obj.Namespace).Delete(context.TODO(), obj.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NodeFeature object %s/%s: %w", obj.Namespace, obj.Name, err)
+		}
+		klog.InfoS("NodeFeature object has been deleted", "nodefeature", klog.KObj(obj))
+	}
+	return nil
+}
+
+// pruneNodeResourceTopologies deletes the NodeResourceTopology objects of the
+// given nodes. Objects of other names are not managed by NFD and left intact.
+func (m *nfdMaster) pruneNodeResourceTopologies(cli topologyclientset.Interface, nodes []corev1.Node) error {
+	// Only delete the objects managed by nfd-topology-updater
+	objs, err := cli.TopologyV1alpha2().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{
+		LabelSelector: nfdv1alpha1.NodeResourceTopologyOwnerLabel + "=true",
+	})
+	if errors.IsNotFound(err) {
+		klog.V(1).InfoS("NodeResourceTopology API not available, skipping")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to list NodeResourceTopology objects: %w", err)
+	}
+
+	nodeNames := make(map[string]struct{}, len(nodes))
+	for _, n := range nodes {
+		nodeNames[n.Name] = struct{}{}
+	}
+
+	for _, obj := range objs.Items {
+		if _, ok := nodeNames[obj.Name]; !ok {
+			continue
+		}
+		if m.args.PruneDryRun {
+			klog.InfoS("would delete NodeResourceTopology object", "nodeName", obj.Name)
+			continue
+		}
+		err := cli.TopologyV1alpha2().NodeResourceTopologies().Delete(context.TODO(), obj.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NodeResourceTopology object %q: %w", obj.Name, err)
+		}
+		klog.InfoS("NodeResourceTopology object has been deleted", "nodeName", obj.Name)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
+	faketopologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned/fake"
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestPrune(t *testing.T) {
+	Convey("When pruning the cluster", t, func() {
+		extRes := corev1.ResourceName(nfdv1alpha1.FeatureLabelNs + "/res")
+		taint := corev1.Taint{Key: nfdv1alpha1.TaintNs + "/foo", Value: "bar", Effect: corev1.TaintEffectNoSchedule}
+
+		node := newTestNode()
+		node.Labels[nfdv1alpha1.FeatureLabelNs+"/foo"] = "bar"
+		node.Labels["other"] = "label"
+		node.Annotations[nfdv1alpha1.FeatureLabelsAnnotation] = "foo"
+		node.Annotations[nfdv1alpha1.ExtendedResourceAnnotation] = "res"
+		node.Annotations[nfdv1alpha1.NodeTaintsAnnotation] = taint.ToString()
+		node.Annotations["other"] = "annotation"
+		node.Spec.Taints = []corev1.Taint{taint}
+		node.Status.Capacity[extRes] = resource.MustParse("2")
+		node.Status.Allocatable = corev1.ResourceList{extRes: resource.MustParse("2")}
+
+		nf := &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "nf-1",
+				Namespace:  "nfd",
+				Finalizers: []string{nfdv1alpha1.NodeCleanupFinalizer},
+			},
+		}
+		nfdOwned := map[string]string{nfdv1alpha1.NodeResourceTopologyOwnerLabel: "true"}
+		nrts := []*v1alpha2.NodeResourceTopology{
+			{ObjectMeta: metav1.ObjectMeta{Name: testNodeName, Labels: nfdOwned}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "unknown-node", Labels: nfdOwned}},
+		}
+
+		fakeCli := fakeclient.NewSimpleClientset(node)
+		fakeNfdCli := fakenfdclient.NewSimpleClientset(nf)
+		fakeTopoCli := faketopologyv1alpha2.NewSimpleClientset(nrts[0], nrts[1])
+		fakeMaster := newFakeMaster(fakeCli)
+
+		Convey("NFD-managed properties of the node should be detected", func() {
+			items := fakeMaster.getNodePruneItems(node)
+			So(items, ShouldResemble, nodePruneItems{
+				Labels:            []string{nfdv1alpha1.FeatureLabelNs + "/foo"},
+				Annotations:       []string{nfdv1alpha1.ExtendedResourceAnnotation, nfdv1alpha1.FeatureLabelsAnnotation, nfdv1alpha1.NodeTaintsAnnotation},
+				Taints:            []string{taint.ToString()},
+				ExtendedResources: []string{string(extRes)},
+			})
+		})
+
+		Convey("Nothing should be modified in dry-run mode", func() {
+			fakeMaster.args.PruneDryRun = true
+			So(fakeMaster.pruneCluster(fakeNfdCli, fakeTopoCli), ShouldBeNil)
+
+			n, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(n, ShouldResemble, node)
+
+			_, err = fakeNfdCli.NfdV1alpha1().NodeFeatures("nfd").Get(context.TODO(), "nf-1", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			l, err := fakeTopoCli.TopologyV1alpha2().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(l.Items, ShouldHaveLength, 2)
+		})
+
+		Convey("All NFD-managed properties and objects should be removed", func() {
+			So(fakeMaster.pruneCluster(fakeNfdCli, fakeTopoCli), ShouldBeNil)
+
+			n, err := fakeCli.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(n.Labels, ShouldResemble, map[string]string{"other": "label"})
+			So(n.Annotations, ShouldResemble, map[string]string{"other": "annotation"})
+			So(n.Spec.Taints, ShouldBeEmpty)
+			So(n.Status.Capacity, ShouldNotContainKey, extRes)
+			So(n.Status.Allocatable, ShouldNotContainKey, extRes)
+
+			_, err = fakeNfdCli.NfdV1alpha1().NodeFeatures("nfd").Get(context.TODO(), "nf-1", metav1.GetOptions{})
+			So(errors.IsNotFound(err), ShouldBeTrue)
+
+			l, err := fakeTopoCli.TopologyV1alpha2().NodeResourceTopologies().List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(l.Items, ShouldHaveLength, 1)
+			So(l.Items[0].Name, ShouldEqual, "unknown-node")
+		})
+
+		Convey("NodeResourceTopology objects of other exporters should not be deleted", func() {
+			fakeTopoCli := faketopologyv1alpha2.NewSimpleClientset(&v1alpha2.NodeResourceTopology{ObjectMeta: metav1.ObjectMeta{Name: testNodeName}})
+			So(fakeMaster.pruneCluster(fakeNfdCli, fakeTopoCli), ShouldBeNil)
+
+			_, err := fakeTopoCli.TopologyV1alpha2().NodeResourceTopologies().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+		})
+	})
+}
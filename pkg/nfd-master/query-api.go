@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// matchNodesPath is the path of the node query API. It is served on the
+// metrics server.
+const matchNodesPath = "/api/v1alpha1/matchnodes"
+
+// maxMatchNodesRequestSize is the maximum size of a query request body.
+const maxMatchNodesRequestSize = 64 << 10
+
+// matchNodesRequest is the request of the node query API: a NodeFeatureRule
+// rule (only the matchFeatures and matchAny fields are relevant) and an
+// optional node selector, the same as in the NodeFeatureRule spec.
+type matchNodesRequest struct {
+	nfdv1alpha1.Rule `json:",inline"`
+	// NodeSelector restricts the query to the nodes matching it.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+}
+
+// matchNodesResponse is the response of the node query API.
+type matchNodesResponse struct {
+	// Nodes is the list of nodes matching the rule.
+	Nodes []string `json:"nodes"`
+	// Errors contains the nodes for which evaluating the rule failed, with
+	// the error message.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// matchNodesHandler serves the node query API. The request body is a
+// matchNodesRequest and the response lists the nodes matching the rule.
+func (m *nfdMaster) matchNodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := matchNodesRequest{}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMatchNodesRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse rule: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.MatchFeatures) == 0 && len(req.MatchAny) == 0 {
+		http.Error(w, "rule must specify matchFeatures or matchAny", http.StatusBadRequest)
+		return
+	}
+	nodeSelector := k8sLabels.Everything()
+	if req.NodeSelector != nil {
+		var err error
+		if nodeSelector, err = metav1.LabelSelectorAsSelector(req.NodeSelector); err != nil {
+			http.Error(w, fmt.Sprintf("invalid nodeSelector: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := m.matchNodes(&req.Rule, nodeSelector)
+	if err != nil {
+		klog.ErrorS(err, "failed to evaluate node query")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		klog.ErrorS(err, "failed to write node query response")
+	}
+}
+
+// matchNodes evaluates a rule against the features of all nodes that have
+// NodeFeature objects in the informer cache. The features are assembled the
+// same way as when updating nodes and nodes that have opted out of NFD are
+// skipped.
+func (m *nfdMaster) matchNodes(rule *nfdv1alpha1.Rule, nodeSelector k8sLabels.Selector) (*matchNodesResponse, error) {
+	objs, err := m.nfdController.featureLister.List(k8sLabels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NodeFeature resources: %w", err)
+	}
+
+	nodeNames := map[string]struct{}{}
+	for _, o := range objs {
+		if name := o.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]; name != "" {
+			nodeNames[name] = struct{}{}
+		}
+	}
+
+	nodes, err := m.getNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	resp := &matchNodesResponse{Nodes: []string{}}
+	numEvaluated := 0
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if _, ok := nodeNames[node.Name]; !ok || isNodeExcluded(node) || !nodeSelector.Matches(k8sLabels.Set(node.Labels)) {
+			continue
+		}
+		numEvaluated++
+
+		name := node.Name
+		features, err := m.getNodeFeatureSpec(name)
+		if err != nil {
+			return nil, err
+		}
+		m.addNodeFeatures(&features.Features, node)
+
+		out, err := nodefeaturerule.Evaluate(&features.Features, rule, nodefeaturerule.WithLogger(klog.Background()))
+		if err != nil {
+			if resp.Errors == nil {
+				resp.Errors = map[string]string{}
+			}
+			resp.Errors[name] = err.Error()
+		} else if out.Matched {
+			resp.Nodes = append(resp.Nodes, name)
+		}
+	}
+	sort.Strings(resp.Nodes)
+
+	klog.V(2).InfoS("node query evaluated", "numNodes", numEvaluated, "numMatched", len(resp.Nodes), "numErrors", len(resp.Errors))
+	return resp, nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// ruleObject is a NodeFeatureRule or a NamespacedNodeFeatureRule object.
+type ruleObject struct {
+	metav1.Object
+	spec *nfdv1alpha1.NodeFeatureRuleSpec
+	// policy restricts the output of namespaced rules. Nil for cluster-wide
+	// NodeFeatureRule objects.
+	policy *RuleDelegationPolicy
+}
+
+// key returns the cache key of the object, i.e. "<namespace>/<name>" or
+// "<name>" for cluster-scoped objects.
+func (o ruleObject) key() string {
+	return klog.KObj(o).String()
+}
+
+// compiledNodeFeatureRule contains the compiled rules of one NodeFeatureRule
+// (or NamespacedNodeFeatureRule) object.
+type compiledNodeFeatureRule struct {
+	resourceVersion string
+	nodeSelector    k8sLabels.Selector
+	rules           []*nodefeaturerule.CompiledRule
+	// features holds the names of the features referenced by each rule,
+	// i.e. the dependency index of the rules
+	features [][]string
+}
+
+// matchesNode returns true if the rules should be evaluated for a node with
+// the given labels.
+func (c *compiledNodeFeatureRule) matchesNode(nodeLabels map[string]string) bool {
+	return c.nodeSelector.Matches(k8sLabels.Set(nodeLabels))
+}
+
+// ruleCache caches the compiled form of rule objects so that the
+// rules are not re-compiled for every node. The rules of an object are
+// re-compiled when its resourceVersion changes. The zero value is ready for
+// use.
+type ruleCache struct {
+	sync.Mutex
+	objs map[string]*compiledNodeFeatureRule
+}
+
+// get returns the compiled form of a rule object, compiling it if needed.
+func (c *ruleCache) get(obj ruleObject) *compiledNodeFeatureRule {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.objs == nil {
+		c.objs = make(map[string]*compiledNodeFeatureRule)
+	}
+	key := obj.key()
+	if cached, ok := c.objs[key]; ok && cached.resourceVersion == obj.GetResourceVersion() {
+		return cached
+	}
+
+	compiled := &compiledNodeFeatureRule{
+		resourceVersion: obj.GetResourceVersion(),
+		nodeSelector:    k8sLabels.Everything(),
+		rules:           make([]*nodefeaturerule.CompiledRule, len(obj.spec.Rules)),
+		features:        make([][]string, len(obj.spec.Rules)),
+	}
+	if obj.spec.NodeSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(obj.spec.NodeSelector)
+		if err != nil {
+			// Do not apply the rules anywhere rather than everywhere
+			klog.ErrorS(err, "invalid nodeSelector", "nodefeaturerule", klog.KObj(obj))
+			sel = k8sLabels.Nothing()
+		}
+		compiled.nodeSelector = sel
+	}
+	for i := range obj.spec.Rules {
+		rule := &obj.spec.Rules[i]
+		cr, err := nodefeaturerule.Compile(rule)
+		if err != nil {
+			klog.ErrorS(err, "invalid rule", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj))
+		}
+		if rule.ExpireAfter != nil && rule.ExpireAfter.Duration < minRuleExpireAfter {
+			klog.InfoS("expireAfter of rule below the minimum, using the minimum instead", "ruleName", rule.Name, "nodefeaturerule", klog.KObj(obj), "expireAfter", rule.ExpireAfter.Duration, "minimum", minRuleExpireAfter)
+		}
+		compiled.rules[i] = cr
+		compiled.features[i] = cr.Features()
+	}
+	c.objs[key] = compiled
+	klog.V(2).InfoS("compiled NodeFeatureRule", "nodefeaturerule", klog.KObj(obj), "resourceVersion", obj.GetResourceVersion(), "ruleCount", len(compiled.rules))
+
+	return compiled
+}
+
+// prune drops the compiled rules of objects not in the given list.
+func (c *ruleCache) prune(objs []ruleObject) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.objs) == 0 {
+		return
+	}
+	keys := make(map[string]struct{}, len(objs))
+	for _, obj := range objs {
+		keys[obj.key()] = struct{}{}
+	}
+	for key := range c.objs {
+		if _, ok := keys[key]; !ok {
+			delete(c.objs, key)
+		}
+	}
+}
+
+// ruleDependenciesPath is the path of the debug endpoint showing the rules
+// depending on each feature. It is served on the metrics server if
+// profiling is enabled.
+const ruleDependenciesPath = "/debug/nfd/rule-dependencies"
+
+// ruleDependencies returns the dependency index of the rules, i.e. the
+// rules ("<object>/<rule>") referencing each feature.
+func (m *nfdMaster) ruleDependencies() (map[string][]string, error) {
+	objs, err := m.listRuleObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	deps := make(map[string][]string)
+	for _, obj := range objs {
+		compiled := m.ruleCache.get(obj)
+		for i, cr := range compiled.rules {
+			ruleRef := obj.key() + "/" + cr.Rule().Name
+			for _, name := range compiled.features[i] {
+				deps[name] = append(deps[name], ruleRef)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// ruleDependenciesHandler serves the dependency index of the rules as JSON.
+func (m *nfdMaster) ruleDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	if m.nfdController == nil {
+		http.Error(w, "NodeFeatureRule controller not running", http.StatusServiceUnavailable)
+		return
+	}
+	deps, err := m.ruleDependencies()
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeatureRule resources")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(deps); err != nil {
+		klog.ErrorS(err, "failed to write rule dependencies")
+	}
+}
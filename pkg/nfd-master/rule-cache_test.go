@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestNodeFeatureRule(name, resourceVersion string) *nfdv1alpha1.NodeFeatureRule {
+	return &nfdv1alpha1.NodeFeatureRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: resourceVersion},
+		Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+			Rules: []nfdv1alpha1.Rule{
+				{Name: "rule-1", Labels: map[string]string{"foo": "bar"}},
+				{Name: "rule-2", Labels: map[string]string{"baz": "qux"}},
+			},
+		},
+	}
+}
+
+func newTestRuleObject(nfr *nfdv1alpha1.NodeFeatureRule) ruleObject {
+	return ruleObject{Object: nfr, spec: &nfr.Spec}
+}
+
+func TestRuleCache(t *testing.T) {
+	Convey("When getting compiled rules", t, func() {
+		c := ruleCache{}
+		nfr := newTestNodeFeatureRule("nfr-1", "1")
+		rules := c.get(newTestRuleObject(nfr)).rules
+
+		Convey("all rules of the object should be compiled", func() {
+			So(rules, ShouldHaveLength, 2)
+			So(rules[0].Rule(), ShouldPointTo, &nfr.Spec.Rules[0])
+			So(rules[1].Rule(), ShouldPointTo, &nfr.Spec.Rules[1])
+		})
+		Convey("the compiled rules should be cached", func() {
+			So(c.get(newTestRuleObject(nfr)).rules[0], ShouldPointTo, rules[0])
+		})
+		Convey("the rules should be re-compiled when the object changes", func() {
+			nfr2 := newTestNodeFeatureRule("nfr-1", "2")
+			nfr2.Spec.Rules = nfr2.Spec.Rules[:1]
+			rules2 := c.get(newTestRuleObject(nfr2)).rules
+			So(rules2, ShouldHaveLength, 1)
+			So(rules2[0], ShouldNotPointTo, rules[0])
+			So(rules2[0].Rule(), ShouldPointTo, &nfr2.Spec.Rules[0])
+		})
+		Convey("rules of deleted objects should be pruned", func() {
+			nfr2 := newTestNodeFeatureRule("nfr-2", "1")
+			c.get(newTestRuleObject(nfr2))
+			So(c.objs, ShouldHaveLength, 2)
+			c.prune([]ruleObject{newTestRuleObject(nfr2)})
+			So(c.objs, ShouldHaveLength, 1)
+			So(c.objs, ShouldContainKey, "nfr-2")
+		})
+		Convey("namespaced objects should be cached separately", func() {
+			nnfr := &nfdv1alpha1.NamespacedNodeFeatureRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "nfr-1", Namespace: "team-a", ResourceVersion: "1"},
+				Spec:       nfr.Spec,
+			}
+			nnfrRules := c.get(ruleObject{Object: nnfr, spec: &nnfr.Spec}).rules
+			So(nnfrRules[0], ShouldNotPointTo, rules[0])
+			So(c.objs, ShouldContainKey, "team-a/nfr-1")
+		})
+	})
+}
+
+func TestRuleCacheNodeSelector(t *testing.T) {
+	Convey("When a NodeFeatureRule has a nodeSelector", t, func() {
+		c := ruleCache{}
+		nfr := newTestNodeFeatureRule("nfr-1", "1")
+
+		Convey("rules without a nodeSelector should match all nodes", func() {
+			So(c.get(newTestRuleObject(nfr)).matchesNode(nil), ShouldBeTrue)
+		})
+		Convey("rules should only match nodes selected by the nodeSelector", func() {
+			nfr.Spec.NodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}}
+			compiled := c.get(newTestRuleObject(nfr))
+			So(compiled.matchesNode(map[string]string{"pool": "gpu"}), ShouldBeTrue)
+			So(compiled.matchesNode(map[string]string{"pool": "cpu"}), ShouldBeFalse)
+			So(compiled.matchesNode(nil), ShouldBeFalse)
+		})
+		Convey("rules with an invalid nodeSelector should not match any node", func() {
+			nfr.Spec.NodeSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Foo"}},
+			}
+			So(c.get(newTestRuleObject(nfr)).matchesNode(map[string]string{"pool": "gpu"}), ShouldBeFalse)
+		})
+	})
+}
+
+func TestRuleDependencies(t *testing.T) {
+	Convey("When querying the rule dependencies", t, func() {
+		nfr := newTestNodeFeatureRule("nfr-1", "1")
+		nfr.Spec.Rules[0].MatchFeatures = nfdv1alpha1.FeatureMatcher{{Feature: "cpu.cpuid"}, {Feature: "local.label"}}
+		nfr.Spec.Rules[1].MatchFeatures = nfdv1alpha1.FeatureMatcher{{Feature: "cpu.cpuid"}}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+
+		Convey("the request should fail if the controller is not running", func() {
+			rec := httptest.NewRecorder()
+			fakeMaster.ruleDependenciesHandler(rec, httptest.NewRequest(http.MethodGet, ruleDependenciesPath, nil))
+			So(rec.Code, ShouldEqual, http.StatusServiceUnavailable)
+		})
+		Convey("the rules referencing each feature should be returned", func() {
+			fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(nfr))
+			So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+			rec := httptest.NewRecorder()
+			fakeMaster.ruleDependenciesHandler(rec, httptest.NewRequest(http.MethodGet, ruleDependenciesPath, nil))
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			deps := map[string][]string{}
+			So(json.Unmarshal(rec.Body.Bytes(), &deps), ShouldBeNil)
+			So(deps, ShouldResemble, map[string][]string{
+				"cpu.cpuid":   {"nfr-1/rule-1", "nfr-1/rule-2"},
+				"local.label": {"nfr-1/rule-1"},
+			})
+		})
+	})
+}
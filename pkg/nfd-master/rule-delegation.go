@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+// RuleDelegationPolicy specifies what the NamespacedNodeFeatureRule objects of
+// one namespace are allowed to create.
+type RuleDelegationPolicy struct {
+	// LabelNs is the list of namespaces of labels, annotations and extended
+	// resources that the rules may create. Sub-namespaces of the listed
+	// namespaces are allowed, too.
+	LabelNs []string
+	// TaintNs is the list of namespaces of taints that the rules may create.
+	// Sub-namespaces of the listed namespaces are allowed, too.
+	TaintNs []string
+}
+
+// validate checks that the policy is well-formed.
+func (p *RuleDelegationPolicy) validate() error {
+	for _, ns := range append(append([]string{}, p.LabelNs...), p.TaintNs...) {
+		if ns == "" || strings.Contains(ns, "/") {
+			return fmt.Errorf("invalid namespace %q", ns)
+		}
+	}
+	return nil
+}
+
+// filterRuleOutput drops everything not allowed by the policy from the output
+// of a rule. Unprefixed names are considered to be in the default namespace
+// if autoDefaultNs is true.
+func (p *RuleDelegationPolicy) filterRuleOutput(out nodefeaturerule.RuleOutput, autoDefaultNs bool, obj klog.KMetadata) nodefeaturerule.RuleOutput {
+	filter := func(in map[string]string, defaultNs, kind string, rejected prometheus.Counter) map[string]string {
+		filtered := make(map[string]string, len(in))
+		for name, value := range in {
+			ns, _ := splitNs(name)
+			if ns == "" && autoDefaultNs {
+				ns = defaultNs
+			}
+			if nsDelegated(ns, p.LabelNs) {
+				filtered[name] = value
+			} else {
+				klog.ErrorS(nil, "namespace not delegated, ignoring "+kind, "name", name, "namespacednodefeaturerule", klog.KObj(obj))
+				if rejected != nil {
+					rejected.Inc()
+				}
+			}
+		}
+		return filtered
+	}
+
+	filtered := nodefeaturerule.RuleOutput{
+		Matched:           out.Matched,
+		Labels:            filter(out.Labels, nfdv1alpha1.FeatureLabelNs, "label", nodeLabelsRejected),
+		Annotations:       filter(out.Annotations, nfdv1alpha1.FeatureAnnotationNs, "annotation", nil),
+		ExtendedResources: filter(out.ExtendedResources, nfdv1alpha1.ExtendedResourceNs, "extended resource", nodeERsRejected),
+		Vars:              out.Vars,
+	}
+	for _, taint := range out.Taints {
+		if ns, _ := splitNs(taint.Key); nsDelegated(ns, p.TaintNs) {
+			filtered.Taints = append(filtered.Taints, taint)
+		} else {
+			klog.ErrorS(nil, "namespace not delegated, ignoring taint", "taint", taint, "namespacednodefeaturerule", klog.KObj(obj))
+			nodeTaintsRejected.Inc()
+		}
+	}
+	if out.Cordon {
+		klog.ErrorS(nil, "cordoning is not delegated, ignoring cordon", "namespacednodefeaturerule", klog.KObj(obj))
+	}
+	return filtered
+}
+
+// nsDelegated returns true if ns is one of the delegated namespaces or a
+// sub-namespace of one.
+func nsDelegated(ns string, delegated []string) bool {
+	if ns == "" {
+		return false
+	}
+	for _, d := range delegated {
+		if ns == d || strings.HasSuffix(ns, "."+d) {
+			return true
+		}
+	}
+	return false
+}
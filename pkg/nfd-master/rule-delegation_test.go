@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+func TestRuleDelegationPolicy(t *testing.T) {
+	Convey("When filtering rule output with a delegation policy", t, func() {
+		policy := &RuleDelegationPolicy{
+			LabelNs: []string{"team-a.example.com"},
+			TaintNs: []string{"taints.example.com"},
+		}
+		obj := &metav1.ObjectMeta{Name: "rules", Namespace: "team-a"}
+		out := nodefeaturerule.RuleOutput{
+			Labels: map[string]string{
+				"team-a.example.com/label":     "true",
+				"sub.team-a.example.com/label": "true",
+				"team-b.example.com/label":     "true",
+				"unprefixed":                   "true",
+			},
+			Annotations:       map[string]string{"team-a.example.com/annotation": "1", "other.io/annotation": "2"},
+			ExtendedResources: map[string]string{"team-a.example.com/er": "1", "feature.node.kubernetes.io/er": "2"},
+			Vars:              map[string]string{"var": "1"},
+			Taints: []corev1.Taint{
+				{Key: "taints.example.com/taint", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "other.io/taint", Effect: corev1.TaintEffectNoSchedule},
+			},
+		}
+
+		Convey("only delegated namespaces should be allowed", func() {
+			filtered := policy.filterRuleOutput(out, false, obj)
+			So(filtered.Labels, ShouldResemble, map[string]string{
+				"team-a.example.com/label":     "true",
+				"sub.team-a.example.com/label": "true",
+			})
+			So(filtered.Annotations, ShouldResemble, map[string]string{"team-a.example.com/annotation": "1"})
+			So(filtered.ExtendedResources, ShouldResemble, map[string]string{"team-a.example.com/er": "1"})
+			So(filtered.Vars, ShouldResemble, out.Vars)
+			So(filtered.Taints, ShouldResemble, []corev1.Taint{{Key: "taints.example.com/taint", Effect: corev1.TaintEffectNoSchedule}})
+		})
+		Convey("unprefixed names should be in the default namespace with autoDefaultNs", func() {
+			policy.LabelNs = append(policy.LabelNs, "feature.node.kubernetes.io")
+			filtered := policy.filterRuleOutput(out, true, obj)
+			So(filtered.Labels, ShouldContainKey, "unprefixed")
+			So(filtered.ExtendedResources, ShouldContainKey, "feature.node.kubernetes.io/er")
+
+			filtered = policy.filterRuleOutput(out, false, obj)
+			So(filtered.Labels, ShouldNotContainKey, "unprefixed")
+		})
+	})
+
+	Convey("When validating a delegation policy", t, func() {
+		So((&RuleDelegationPolicy{LabelNs: []string{"example.com"}}).validate(), ShouldBeNil)
+		So((&RuleDelegationPolicy{LabelNs: []string{""}}).validate(), ShouldNotBeNil)
+		So((&RuleDelegationPolicy{TaintNs: []string{"example.com/foo"}}).validate(), ShouldNotBeNil)
+	})
+}
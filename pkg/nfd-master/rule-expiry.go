@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"time"
+
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// minRuleExpireAfter is the lower bound of the expireAfter of rules.
+// nfd-worker refreshes the heartbeat of its NodeFeature objects only on full
+// syncs, every 10 minutes plus up to one sleep interval, so shorter values
+// would expire rule outputs on healthy nodes.
+const minRuleExpireAfter = 15 * time.Minute
+
+// ruleExpireAfter returns the effective expireAfter of a rule.
+func ruleExpireAfter(rule *nfdv1alpha1.Rule) time.Duration {
+	return max(rule.ExpireAfter.Duration, minRuleExpireAfter)
+}
+
+// nodeFeatureHeartbeat returns the time nfd-worker last refreshed the
+// NodeFeature objects of a node. Zero time is returned if it is not known,
+// e.g. in gRPC mode or if the objects have no heartbeat annotation.
+func (m *nfdMaster) nodeFeatureHeartbeat(nodeName string) time.Time {
+	var heartbeat time.Time
+	if m.nfdController == nil || m.nfdController.featureLister == nil {
+		return heartbeat
+	}
+
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	objs, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		klog.ErrorS(err, "failed to get NodeFeature resources", "nodeName", nodeName)
+		return heartbeat
+	}
+	for _, obj := range objs {
+		val, ok := obj.Annotations[nfdv1alpha1.NodeFeatureHeartbeatAnnotation]
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			klog.ErrorS(err, "invalid heartbeat annotation", "nodefeature", klog.KObj(obj))
+			continue
+		}
+		if t.After(heartbeat) {
+			heartbeat = t
+		}
+	}
+	return heartbeat
+}
+
+// ruleOutputExpired returns true if the output of a rule has expired, i.e.
+// the features of the node have not been refreshed within expireAfter. If
+// the output has not expired yet, re-evaluation of the node is scheduled for
+// the moment it does.
+func (m *nfdMaster) ruleOutputExpired(nodeName string, expireAfter time.Duration, heartbeat time.Time) bool {
+	if heartbeat.IsZero() {
+		return false
+	}
+	remaining := time.Until(heartbeat.Add(expireAfter))
+	if remaining <= 0 {
+		return true
+	}
+	if m.nodeUpdaterPool != nil && m.nodeUpdaterPool.queue != nil {
+		m.nodeUpdaterPool.queue.AddAfter(nodeName, remaining)
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestNodeFeatureHeartbeat(t *testing.T) {
+	Convey("When determining the heartbeat of node features", t, func() {
+		newNodeFeature := func(name, nodeName, heartbeat string) *nfdv1alpha1.NodeFeature {
+			nf := &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "nfd",
+					Labels:      map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+					Annotations: map[string]string{},
+				},
+			}
+			if heartbeat != "" {
+				nf.Annotations[nfdv1alpha1.NodeFeatureHeartbeatAnnotation] = heartbeat
+			}
+			return nf
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			newNodeFeature("node-1", "node-1", "2024-05-01T10:00:00Z"),
+			newNodeFeature("node-1-1", "node-1", "2024-05-01T11:00:00Z"),
+			newNodeFeature("node-1-2", "node-1", "invalid"),
+			newNodeFeature("node-2", "node-2", ""),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		Convey("The latest heartbeat should be returned", func() {
+			So(fakeMaster.nodeFeatureHeartbeat("node-1"), ShouldEqual, time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC))
+		})
+		Convey("Zero time should be returned if there is no heartbeat", func() {
+			So(fakeMaster.nodeFeatureHeartbeat("node-2").IsZero(), ShouldBeTrue)
+			So(fakeMaster.nodeFeatureHeartbeat("node-3").IsZero(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestRuleOutputExpired(t *testing.T) {
+	Convey("When checking expiry of rule output", t, func() {
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+
+		Convey("Output should expire if features have not been refreshed", func() {
+			So(fakeMaster.ruleOutputExpired(testNodeName, time.Minute, time.Now().Add(-2*time.Minute)), ShouldBeTrue)
+		})
+		Convey("Output should not expire if features have been refreshed", func() {
+			So(fakeMaster.ruleOutputExpired(testNodeName, time.Minute, time.Now()), ShouldBeFalse)
+		})
+		Convey("Output should not expire if the heartbeat is unknown", func() {
+			So(fakeMaster.ruleOutputExpired(testNodeName, time.Minute, time.Time{}), ShouldBeFalse)
+		})
+	})
+}
+
+func TestRuleExpireAfter(t *testing.T) {
+	Convey("When determining the expireAfter of a rule", t, func() {
+		rule := &nfdv1alpha1.Rule{ExpireAfter: &metav1.Duration{Duration: time.Hour}}
+		Convey("The configured value should be used", func() {
+			So(ruleExpireAfter(rule), ShouldEqual, time.Hour)
+		})
+		Convey("Values below the minimum should be raised to the minimum", func() {
+			rule.ExpireAfter.Duration = time.Minute
+			So(ruleExpireAfter(rule), ShouldEqual, minRuleExpireAfter)
+		})
+	})
+}
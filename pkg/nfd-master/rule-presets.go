@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// rulePresetFS contains the built-in rule presets, one NodeFeatureRule
+// manifest per file, named after the preset.
+//
+//go:embed presets/*.yaml
+var rulePresetFS embed.FS
+
+// rulePresetPrefix is prepended to the object name of presets. It contains a
+// character that is invalid in object names so that presets never collide
+// with NodeFeatureRule objects in the cluster.
+const rulePresetPrefix = "preset:"
+
+// rulePresetNames returns the names of the built-in rule presets, sorted.
+func rulePresetNames() []string {
+	files, _ := rulePresetFS.ReadDir("presets")
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadRulePreset parses the built-in rule preset with the given name.
+func loadRulePreset(name string) (*nfdv1alpha1.NodeFeatureRule, error) {
+	data, err := rulePresetFS.ReadFile(path.Join("presets", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown rule preset %q, available presets: %s", name, strings.Join(rulePresetNames(), ", "))
+	}
+	nfr := &nfdv1alpha1.NodeFeatureRule{}
+	if err := yaml.UnmarshalStrict(data, nfr); err != nil {
+		return nil, fmt.Errorf("failed to parse rule preset %q: %w", name, err)
+	}
+	nfr.Name = rulePresetPrefix + name
+	// The content of a preset never changes at runtime
+	nfr.ResourceVersion = "preset"
+	return nfr, nil
+}
+
+// loadRulePresets parses the given built-in rule presets, in the given order.
+func loadRulePresets(names []string) ([]ruleObject, error) {
+	objs := make([]ruleObject, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("rule preset %q specified more than once", name)
+		}
+		seen[name] = struct{}{}
+
+		nfr, err := loadRulePreset(name)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, ruleObject{Object: nfr, spec: &nfr.Spec})
+	}
+	return objs, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+func TestRulePresets(t *testing.T) {
+	Convey("When loading the built-in rule presets", t, func() {
+		names := rulePresetNames()
+		So(names, ShouldContain, "confidential-computing")
+		So(names, ShouldContain, "dpdk-ready")
+		So(names, ShouldContain, "gpu-passthrough-ready")
+
+		Convey("All presets should parse and compile", func() {
+			objs, err := loadRulePresets(names)
+			So(err, ShouldBeNil)
+			So(objs, ShouldHaveLength, len(names))
+			for i, obj := range objs {
+				So(obj.GetName(), ShouldEqual, rulePresetPrefix+names[i])
+				So(obj.spec.Rules, ShouldNotBeEmpty)
+				for j := range obj.spec.Rules {
+					_, err := nodefeaturerule.Compile(&obj.spec.Rules[j])
+					So(err, ShouldBeNil)
+				}
+			}
+		})
+		Convey("Unknown presets should be rejected", func() {
+			_, err := loadRulePresets([]string{"dpdk-ready", "no-such-preset"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Duplicate presets should be rejected", func() {
+			_, err := loadRulePresets([]string{"dpdk-ready", "dpdk-ready"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("When evaluating the confidential-computing preset", t, func() {
+		objs, err := loadRulePresets([]string{"confidential-computing"})
+		So(err, ShouldBeNil)
+		rule := &objs[0].spec.Rules[0]
+
+		Convey("Nodes with SEV-SNP should match", func() {
+			features := nfdv1alpha1.NewFeatures()
+			features.InsertAttributeFeatures("cpu", "security", map[string]string{"sev.snp.enabled": "true"})
+			out, err := nodefeaturerule.Execute(rule, features)
+			So(err, ShouldBeNil)
+			So(out.Labels, ShouldResemble, map[string]string{"confidential-computing.enabled": "true"})
+		})
+		Convey("Nodes with plain SEV should not match", func() {
+			features := nfdv1alpha1.NewFeatures()
+			features.InsertAttributeFeatures("cpu", "security", map[string]string{"sev.enabled": "true"})
+			out, err := nodefeaturerule.Execute(rule, features)
+			So(err, ShouldBeNil)
+			So(out.Labels, ShouldBeEmpty)
+		})
+	})
+}
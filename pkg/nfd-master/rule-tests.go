@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+const (
+	// ruleTestsPassedReason is the reason of a true TestsPassed condition.
+	ruleTestsPassedReason = "TestsPassed"
+	// ruleTestsFailedReason is the reason of a false TestsPassed condition.
+	ruleTestsFailedReason = "TestsFailed"
+	// maxConditionMessageLen is the maximum length of condition messages
+	// accepted by the API server.
+	maxConditionMessageLen = 32768
+)
+
+// syncRuleTests runs the tests of the NodeFeatureRule objects whose
+// TestsPassed condition is out of date and updates the condition. The
+// condition is removed from objects without tests.
+func (m *nfdMaster) syncRuleTests() {
+	nfrs, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeatureRule resources")
+		return
+	}
+
+	for _, nfr := range nfrs {
+		cond := meta.FindStatusCondition(nfr.Status.Conditions, nfdv1alpha1.NodeFeatureRuleTestsPassedCondition)
+		if len(nfr.Spec.Tests) == 0 && cond == nil {
+			continue
+		}
+		if cond != nil && cond.ObservedGeneration == nfr.Generation {
+			continue
+		}
+
+		nfr = nfr.DeepCopy()
+		if len(nfr.Spec.Tests) == 0 {
+			meta.RemoveStatusCondition(&nfr.Status.Conditions, nfdv1alpha1.NodeFeatureRuleTestsPassedCondition)
+		} else {
+			meta.SetStatusCondition(&nfr.Status.Conditions, ruleTestsCondition(nfr))
+		}
+		if _, err := m.nfdController.nfdClient.NfdV1alpha1().NodeFeatureRules().UpdateStatus(context.TODO(), nfr, metav1.UpdateOptions{}); err != nil {
+			klog.ErrorS(err, "failed to update NodeFeatureRule status", "nodefeaturerule", klog.KObj(nfr))
+		}
+	}
+}
+
+// ruleTestsCondition runs the tests of a NodeFeatureRule and returns the
+// resulting TestsPassed condition.
+func ruleTestsCondition(nfr *nfdv1alpha1.NodeFeatureRule) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               nfdv1alpha1.NodeFeatureRuleTestsPassedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: nfr.Generation,
+		Reason:             ruleTestsPassedReason,
+		Message:            fmt.Sprintf("%d tests passed", len(nfr.Spec.Tests)),
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+	if errs := nodefeaturerule.RunTests(&nfr.Spec); len(errs) > 0 {
+		klog.InfoS("tests of NodeFeatureRule failed", "nodefeaturerule", klog.KObj(nfr), "failedCount", len(errs), "testCount", len(nfr.Spec.Tests))
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = ruleTestsFailedReason
+		cond.Message = errors.Join(errs...).Error()
+		if len(cond.Message) > maxConditionMessageLen {
+			cond.Message = cond.Message[:maxConditionMessageLen-3] + "..."
+		}
+	}
+	return cond
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestSyncRuleTests(t *testing.T) {
+	Convey("When syncing the status of NodeFeatureRule tests", t, func() {
+		newTestedRule := func(name string, expected map[string]string) *nfdv1alpha1.NodeFeatureRule {
+			return &nfdv1alpha1.NodeFeatureRule{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+				Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+					Rules: []nfdv1alpha1.Rule{{Name: "rule", Labels: map[string]string{"feature": "true"}}},
+					Tests: []nfdv1alpha1.RuleTest{{Name: "test", Features: *nfdv1alpha1.NewFeatures(), Labels: expected}},
+				},
+			}
+		}
+		stale := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale", Generation: 3},
+			Status: nfdv1alpha1.NodeFeatureRuleStatus{
+				Conditions: []metav1.Condition{{Type: nfdv1alpha1.NodeFeatureRuleTestsPassedCondition, ObservedGeneration: 2}},
+			},
+		}
+
+		nfdCli := fakenfdclient.NewSimpleClientset(
+			newTestedRule("pass", map[string]string{"feature": "true"}),
+			newTestedRule("fail", map[string]string{"feature": "false"}),
+			stale,
+		)
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.nfdController = newFakeNfdAPIController(nfdCli)
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		fakeMaster.syncRuleTests()
+
+		getCondition := func(name string) *metav1.Condition {
+			nfr, err := nfdCli.NfdV1alpha1().NodeFeatureRules().Get(context.TODO(), name, metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			return meta.FindStatusCondition(nfr.Status.Conditions, nfdv1alpha1.NodeFeatureRuleTestsPassedCondition)
+		}
+		Convey("passing tests should be reported", func() {
+			cond := getCondition("pass")
+			So(cond, ShouldNotBeNil)
+			So(cond.Status, ShouldEqual, metav1.ConditionTrue)
+			So(cond.ObservedGeneration, ShouldEqual, 2)
+		})
+		Convey("failing tests should be reported", func() {
+			cond := getCondition("fail")
+			So(cond, ShouldNotBeNil)
+			So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+			So(cond.Reason, ShouldEqual, ruleTestsFailedReason)
+			So(cond.Message, ShouldEqual, `test "test" failed: label "feature": expected "false", got "true"`)
+		})
+		Convey("the condition should be removed from objects without tests", func() {
+			So(getCondition("stale"), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// schedulingHintsSyncPeriod is the interval at which the scheduling hints
+// are refreshed.
+const schedulingHintsSyncPeriod = time.Minute
+
+// SchedulingHintsConfig contains the configuration for publishing node
+// affinities that select the nodes labeled by NodeFeatureRules, ready to be
+// used in pod specs.
+type SchedulingHintsConfig struct {
+	// ConfigMap is the name of the ConfigMap, in the namespace of
+	// nfd-master, the hints are written to.
+	ConfigMap string
+	// Hints are the node affinities to publish.
+	Hints []SchedulingHint
+}
+
+// SchedulingHint specifies one node affinity to publish. The node affinity
+// is stored under the key <name>.yaml of the ConfigMap and the number of
+// nodes it currently matches under <name>.nodes.
+type SchedulingHint struct {
+	// Name of the hint.
+	Name string
+	// NodeFeatureRule is the name of the NodeFeatureRule object whose labels
+	// select the nodes.
+	NodeFeatureRule string
+	// Rules are the names of the rules of the NodeFeatureRule to use, all
+	// rules if empty. The node affinity selects nodes having the labels of
+	// any one of the rules.
+	Rules []string
+}
+
+func (c *SchedulingHintsConfig) validate() error {
+	if len(c.Hints) > 0 && c.ConfigMap == "" {
+		return fmt.Errorf("configMap must not be empty")
+	}
+	names := make(map[string]struct{}, len(c.Hints))
+	for i, h := range c.Hints {
+		if errs := validation.IsConfigMapKey(h.Name + ".yaml"); len(errs) > 0 || h.Name == "" {
+			return fmt.Errorf("hints[%d]: invalid name %q", i, h.Name)
+		}
+		if _, ok := names[h.Name]; ok {
+			return fmt.Errorf("hints[%d]: duplicate name %q", i, h.Name)
+		}
+		names[h.Name] = struct{}{}
+		if h.NodeFeatureRule == "" {
+			return fmt.Errorf("hints[%d]: nodeFeatureRule must be specified", i)
+		}
+	}
+	return nil
+}
+
+// schedulingHintAffinity returns the node affinity of a hint. Each rule with
+// static labels becomes one node selector term requiring all the labels of
+// the rule. Labels with dynamic values and templates are left out as their
+// values are not known in advance.
+func (m *nfdMaster) schedulingHintAffinity(h SchedulingHint) (*corev1.Affinity, error) {
+	nfr, err := m.nfdController.ruleLister.Get(h.NodeFeatureRule)
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []corev1.NodeSelectorTerm
+	for _, rule := range nfr.Spec.Rules {
+		if len(h.Rules) > 0 && !slices.Contains(h.Rules, rule.Name) {
+			continue
+		}
+		labels := maps.Clone(rule.Labels)
+		maps.DeleteFunc(labels, func(_, v string) bool { return strings.HasPrefix(v, "@") })
+		if len(labels) == 0 {
+			klog.V(2).InfoS("rule has no static labels, leaving it out of scheduling hint", "hint", h.Name, "ruleName", rule.Name, "nodefeaturerule", klog.KObj(nfr))
+			continue
+		}
+		if nfr.Spec.LabelNamespace != "" {
+			labels = addNsToMapKeys(labels, nfr.Spec.LabelNamespace)
+		}
+		if m.config.AutoDefaultNs {
+			labels = addNsToMapKeys(labels, nfdv1alpha1.FeatureLabelNs)
+		}
+
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		term := corev1.NodeSelectorTerm{}
+		for _, k := range keys {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      k,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{labels[k]},
+			})
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("no rules with static labels in NodeFeatureRule %q", h.NodeFeatureRule)
+	}
+
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		},
+	}, nil
+}
+
+// countAffinityNodes returns the number of nodes matching the required node
+// affinity.
+func countAffinityNodes(nodes []*corev1.Node, affinity *corev1.Affinity) (int, error) {
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	selectors := make([]k8slabels.Selector, 0, len(terms))
+	for _, term := range terms {
+		sel := k8slabels.NewSelector()
+		for _, e := range term.MatchExpressions {
+			req, err := k8slabels.NewRequirement(e.Key, selection.In, e.Values)
+			if err != nil {
+				return 0, err
+			}
+			sel = sel.Add(*req)
+		}
+		selectors = append(selectors, sel)
+	}
+
+	count := 0
+	for _, node := range nodes {
+		if slices.ContainsFunc(selectors, func(sel k8slabels.Selector) bool { return sel.Matches(k8slabels.Set(node.Labels)) }) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// syncSchedulingHints updates the ConfigMap holding the scheduling hints.
+// The previous value of hints that fail is kept.
+func (m *nfdMaster) syncSchedulingHints() {
+	if m.nodeLister == nil || len(m.config.SchedulingHints.Hints) == 0 {
+		return
+	}
+	if !m.nodeInformerSynced() {
+		klog.V(2).InfoS("node cache not synced yet, skipping update of scheduling hints")
+		return
+	}
+	if err := m.syncSchedulingHintsConfigMap(); err != nil {
+		klog.ErrorS(err, "failed to update scheduling hints", "configmap", klog.KRef(m.namespace, m.config.SchedulingHints.ConfigMap))
+	}
+}
+
+func (m *nfdMaster) syncSchedulingHintsConfigMap() error {
+	name := m.config.SchedulingHints.ConfigMap
+	cli := m.k8sClient.CoreV1().ConfigMaps(m.namespace)
+
+	cm, err := cli.Get(context.TODO(), name, metav1.GetOptions{})
+	create := errors.IsNotFound(err)
+	if create {
+		cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: m.namespace}}
+	} else if err != nil {
+		return err
+	}
+
+	nodes, err := m.nodeLister.List(k8slabels.Everything())
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	data := make(map[string]string, 2*len(m.config.SchedulingHints.Hints))
+	for _, h := range m.config.SchedulingHints.Hints {
+		yamlKey, nodesKey := h.Name+".yaml", h.Name+".nodes"
+		affinity, err := m.schedulingHintAffinity(h)
+		var count int
+		var out []byte
+		if err == nil {
+			count, err = countAffinityNodes(nodes, affinity)
+		}
+		if err == nil {
+			out, err = yaml.Marshal(affinity)
+		}
+		if err != nil {
+			klog.ErrorS(err, "failed to generate scheduling hint", "hint", h.Name, "nodefeaturerule", klog.KRef("", h.NodeFeatureRule))
+			if v, ok := cm.Data[yamlKey]; ok {
+				data[yamlKey] = v
+				data[nodesKey] = cm.Data[nodesKey]
+			}
+			continue
+		}
+		data[yamlKey] = string(out)
+		data[nodesKey] = strconv.Itoa(count)
+	}
+
+	if !create && maps.Equal(cm.Data, data) {
+		return nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	if create {
+		_, err = cli.Create(context.TODO(), cm, metav1.CreateOptions{})
+	} else {
+		_, err = cli.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	klog.InfoS("scheduling hints updated", "configmap", klog.KObj(cm), "hintCount", len(m.config.SchedulingHints.Hints))
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestSchedulingHintsConfigValidate(t *testing.T) {
+	Convey("When validating scheduling hints config", t, func() {
+		c := SchedulingHintsConfig{ConfigMap: "hints", Hints: []SchedulingHint{{Name: "gpu", NodeFeatureRule: "gpu-rules"}}}
+
+		Convey("A valid config should be accepted", func() {
+			So(c.validate(), ShouldBeNil)
+		})
+		Convey("An empty configMap should be rejected", func() {
+			c.ConfigMap = ""
+			So(c.validate(), ShouldNotBeNil)
+		})
+		Convey("Invalid and duplicate names should be rejected", func() {
+			c.Hints[0].Name = "a/b"
+			So(c.validate(), ShouldNotBeNil)
+			c.Hints = []SchedulingHint{{Name: "gpu", NodeFeatureRule: "a"}, {Name: "gpu", NodeFeatureRule: "b"}}
+			So(c.validate(), ShouldNotBeNil)
+		})
+		Convey("A missing nodeFeatureRule should be rejected", func() {
+			c.Hints[0].NodeFeatureRule = ""
+			So(c.validate(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSchedulingHints(t *testing.T) {
+	Convey("When publishing scheduling hints", t, func() {
+		nfr := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu-rules"},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{Name: "nvidia", Labels: map[string]string{"gpu": "true", "vendor.example.com/gpu-vendor": "nvidia"}},
+					{Name: "amd", Labels: map[string]string{"gpu": "true", "vendor.example.com/gpu-vendor": "amd"}},
+					{Name: "templated", LabelsTemplate: "gpu-count=1"},
+					{Name: "dynamic", Labels: map[string]string{"gpu-memory": "@pci.device.memory"}},
+				},
+			},
+		}
+		newNode := func(name string, labels map[string]string) *corev1.Node {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		}
+		fakeCli := fakeclient.NewSimpleClientset(
+			newNode("node-1", map[string]string{nfdv1alpha1.FeatureLabelNs + "/gpu": "true", "vendor.example.com/gpu-vendor": "nvidia"}),
+			newNode("node-2", map[string]string{nfdv1alpha1.FeatureLabelNs + "/gpu": "true", "vendor.example.com/gpu-vendor": "amd"}),
+			newNode("node-3", map[string]string{nfdv1alpha1.FeatureLabelNs + "/gpu": "true"}),
+		)
+		fakeMaster := newFakeMaster(fakeCli)
+		fakeMaster.namespace = "nfd"
+		fakeMaster.config.AutoDefaultNs = true
+		fakeMaster.config.SchedulingHints = SchedulingHintsConfig{
+			ConfigMap: "nfd-scheduling-hints",
+			Hints: []SchedulingHint{
+				{Name: "any-gpu", NodeFeatureRule: "gpu-rules"},
+				{Name: "nvidia-gpu", NodeFeatureRule: "gpu-rules", Rules: []string{"nvidia"}},
+			},
+		}
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(nfr))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+		fakeMaster.startNodeInformer()
+		defer fakeMaster.stopNodeInformer()
+		So(func() interface{} { return fakeMaster.nodeInformerSynced() }, withTimeout, 2*time.Second, ShouldBeTrue)
+
+		Convey("Each rule with static labels should become a node selector term", func() {
+			affinity, err := fakeMaster.schedulingHintAffinity(fakeMaster.config.SchedulingHints.Hints[1])
+			So(err, ShouldBeNil)
+			So(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, ShouldResemble, []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: nfdv1alpha1.FeatureLabelNs + "/gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}},
+						{Key: "vendor.example.com/gpu-vendor", Operator: corev1.NodeSelectorOpIn, Values: []string{"nvidia"}},
+					},
+				},
+			})
+		})
+		Convey("Rules without static labels should fail", func() {
+			_, err := fakeMaster.schedulingHintAffinity(SchedulingHint{Name: "foo", NodeFeatureRule: "gpu-rules", Rules: []string{"templated", "dynamic"}})
+			So(err, ShouldNotBeNil)
+			_, err = fakeMaster.schedulingHintAffinity(SchedulingHint{Name: "foo", NodeFeatureRule: "missing"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("The hints and matching node counts should be written to the ConfigMap", func() {
+			fakeMaster.syncSchedulingHints()
+			cm, err := fakeCli.CoreV1().ConfigMaps("nfd").Get(context.TODO(), "nfd-scheduling-hints", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(cm.Data, ShouldContainKey, "any-gpu.yaml")
+			So(cm.Data["any-gpu.yaml"], ShouldContainSubstring, "requiredDuringSchedulingIgnoredDuringExecution")
+			So(cm.Data["any-gpu.nodes"], ShouldEqual, "2")
+			So(cm.Data["nvidia-gpu.nodes"], ShouldEqual, "1")
+
+			Convey("The previous value of failing hints should be kept", func() {
+				So(fakeMaster.nfdController.nfdClient.NfdV1alpha1().NodeFeatureRules().Delete(context.TODO(), "gpu-rules", metav1.DeleteOptions{}), ShouldBeNil)
+				So(func() interface{} {
+					_, err := fakeMaster.nfdController.ruleLister.Get("gpu-rules")
+					return err
+				}, withTimeout, 2*time.Second, ShouldNotBeNil)
+				fakeMaster.syncSchedulingHints()
+				cm2, err := fakeCli.CoreV1().ConfigMaps("nfd").Get(context.TODO(), "nfd-scheduling-hints", metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				So(cm2.Data, ShouldResemble, cm.Data)
+			})
+		})
+	})
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+)
+
+const (
+	// versionSkewSyncPeriod is the interval at which the
+	// MinFeatureVersionSatisfied condition of NodeFeatureRule objects is
+	// updated.
+	versionSkewSyncPeriod = 30 * time.Second
+	// minFeatureVersionSatisfiedReason is the reason of a true
+	// MinFeatureVersionSatisfied condition.
+	minFeatureVersionSatisfiedReason = "WorkerVersionSatisfied"
+	// workerVersionTooOldReason is the reason of a false
+	// MinFeatureVersionSatisfied condition.
+	workerVersionTooOldReason = "WorkerVersionTooOld"
+	// maxVersionSkewNodes is the maximum number of nodes listed in the
+	// message of the MinFeatureVersionSatisfied condition.
+	maxVersionSkewNodes = 10
+)
+
+// nodeWorkerVersion returns the version of nfd-worker running on a node, as
+// reported in the NodeFeature objects of the node. The lowest version is
+// returned if the objects disagree, e.g. in the middle of an upgrade. Nil is
+// returned if the version is not known, e.g. in gRPC mode or if nfd-worker
+// was built without version information.
+func (m *nfdMaster) nodeWorkerVersion(nodeName string) *version.Version {
+	var workerVersion *version.Version
+	if m.nfdController == nil || m.nfdController.featureLister == nil {
+		return nil
+	}
+
+	sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+	objs, err := m.nfdController.featureLister.List(sel)
+	if err != nil {
+		klog.ErrorS(err, "failed to get NodeFeature resources", "nodeName", nodeName)
+		return nil
+	}
+	for _, obj := range objs {
+		val, ok := obj.Annotations[nfdv1alpha1.WorkerVersionAnnotation]
+		if !ok {
+			continue
+		}
+		v, err := version.ParseGeneric(val)
+		if err != nil {
+			klog.V(4).InfoS("unable to parse nfd-worker version", "nodefeature", klog.KObj(obj), "version", val)
+			continue
+		}
+		if workerVersion == nil || v.LessThan(workerVersion) {
+			workerVersion = v
+		}
+	}
+	return workerVersion
+}
+
+// workerVersionTooOld returns true if a rule must not be evaluated on a node
+// running the given version of nfd-worker.
+func workerVersionTooOld(rule *nodefeaturerule.CompiledRule, workerVersion *version.Version) bool {
+	minVersion := rule.MinFeatureVersion()
+	return minVersion != nil && workerVersion != nil && !workerVersion.AtLeast(minVersion)
+}
+
+// versionSkew tracks the nodes where rules are not evaluated because
+// nfd-worker is older than the minFeatureVersion of the rules. The zero value
+// is ready for use.
+type versionSkew struct {
+	sync.Mutex
+	// objs maps the key of rule objects to nodes and their nfd-worker version
+	objs map[string]map[string]string
+}
+
+// setNode records the rule objects that were not (fully) evaluated on a node,
+// replacing the previously recorded ones.
+func (s *versionSkew) setNode(nodeName, workerVersion string, objKeys map[string]struct{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	for key, nodes := range s.objs {
+		if _, ok := objKeys[key]; !ok {
+			delete(nodes, nodeName)
+			if len(nodes) == 0 {
+				delete(s.objs, key)
+			}
+		}
+	}
+	if len(objKeys) == 0 {
+		return
+	}
+	if s.objs == nil {
+		s.objs = make(map[string]map[string]string)
+	}
+	for key := range objKeys {
+		if s.objs[key] == nil {
+			s.objs[key] = make(map[string]string)
+		}
+		s.objs[key][nodeName] = workerVersion
+	}
+}
+
+// nodes returns the nodes, and their nfd-worker version, where the rules of
+// an object were not evaluated.
+func (s *versionSkew) nodes(objKey string) map[string]string {
+	s.Lock()
+	defer s.Unlock()
+
+	return maps.Clone(s.objs[objKey])
+}
+
+// prune drops the records of rule objects and nodes for which keep returns
+// false.
+func (s *versionSkew) prune(keepObj func(objKey string) bool, keepNode func(nodeName string) bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	for key, nodes := range s.objs {
+		if !keepObj(key) {
+			delete(s.objs, key)
+			continue
+		}
+		maps.DeleteFunc(nodes, func(nodeName, _ string) bool { return !keepNode(nodeName) })
+		if len(nodes) == 0 {
+			delete(s.objs, key)
+		}
+	}
+}
+
+// syncVersionSkew updates the MinFeatureVersionSatisfied condition of the
+// NodeFeatureRule objects. The condition is removed from objects whose rules
+// do not specify minFeatureVersion.
+func (m *nfdMaster) syncVersionSkew() {
+	nfrs, err := m.nfdController.ruleLister.List(k8sLabels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "failed to list NodeFeatureRule resources")
+		return
+	}
+
+	// Forget deleted objects and nodes that have no NodeFeature objects
+	// anymore
+	names := make(map[string]struct{}, len(nfrs))
+	for _, nfr := range nfrs {
+		names[nfr.Name] = struct{}{}
+	}
+	m.versionSkew.prune(
+		func(objKey string) bool {
+			_, ok := names[objKey]
+			return ok
+		},
+		func(nodeName string) bool {
+			sel := k8sLabels.SelectorFromSet(k8sLabels.Set{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName})
+			objs, err := m.nfdController.featureLister.List(sel)
+			return err != nil || len(objs) > 0
+		})
+
+	for _, nfr := range nfrs {
+		cond := meta.FindStatusCondition(nfr.Status.Conditions, nfdv1alpha1.NodeFeatureRuleMinFeatureVersionCondition)
+		hasMinVersion := slices.ContainsFunc(nfr.Spec.Rules, func(r nfdv1alpha1.Rule) bool { return r.MinFeatureVersion != "" })
+		if !hasMinVersion && cond == nil {
+			continue
+		}
+
+		var newCond metav1.Condition
+		if hasMinVersion {
+			newCond = versionSkewCondition(nfr, m.versionSkew.nodes(nfr.Name))
+			if cond != nil && cond.Status == newCond.Status && cond.Reason == newCond.Reason && cond.Message == newCond.Message && cond.ObservedGeneration == newCond.ObservedGeneration {
+				continue
+			}
+		}
+
+		nfr = nfr.DeepCopy()
+		if hasMinVersion {
+			meta.SetStatusCondition(&nfr.Status.Conditions, newCond)
+		} else {
+			meta.RemoveStatusCondition(&nfr.Status.Conditions, nfdv1alpha1.NodeFeatureRuleMinFeatureVersionCondition)
+		}
+		if _, err := m.nfdController.nfdClient.NfdV1alpha1().NodeFeatureRules().UpdateStatus(context.TODO(), nfr, metav1.UpdateOptions{}); err != nil {
+			klog.ErrorS(err, "failed to update NodeFeatureRule status", "nodefeaturerule", klog.KObj(nfr))
+		}
+	}
+}
+
+// versionSkewCondition returns the MinFeatureVersionSatisfied condition of a
+// NodeFeatureRule, given the nodes where its rules were not evaluated.
+func versionSkewCondition(nfr *nfdv1alpha1.NodeFeatureRule, nodes map[string]string) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               nfdv1alpha1.NodeFeatureRuleMinFeatureVersionCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: nfr.Generation,
+		Reason:             minFeatureVersionSatisfiedReason,
+		Message:            "nfd-worker on all nodes satisfies the minFeatureVersion of the rules",
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+	if len(nodes) == 0 {
+		return cond
+	}
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	details := make([]string, 0, min(len(names), maxVersionSkewNodes))
+	for _, name := range names[:min(len(names), maxVersionSkewNodes)] {
+		details = append(details, fmt.Sprintf("%s (%s)", name, nodes[name]))
+	}
+	if len(names) > maxVersionSkewNodes {
+		details = append(details, fmt.Sprintf("and %d more", len(names)-maxVersionSkewNodes))
+	}
+	cond.Status = metav1.ConditionFalse
+	cond.Reason = workerVersionTooOldReason
+	cond.Message = fmt.Sprintf("rules not evaluated on %d nodes running an older nfd-worker than minFeatureVersion: %s", len(names), strings.Join(details, ", "))
+	return cond
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdmaster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestVersionSkew(t *testing.T) {
+	Convey("When processing rules with minFeatureVersion", t, func() {
+		newNodeFeature := func(name, nodeName, workerVersion string) *nfdv1alpha1.NodeFeature {
+			return &nfdv1alpha1.NodeFeature{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "nfd",
+					Labels:      map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+					Annotations: map[string]string{nfdv1alpha1.WorkerVersionAnnotation: workerVersion},
+				},
+			}
+		}
+		nfr := &nfdv1alpha1.NodeFeatureRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "nfr-1", ResourceVersion: "1", Generation: 1},
+			Spec: nfdv1alpha1.NodeFeatureRuleSpec{
+				Rules: []nfdv1alpha1.Rule{
+					{
+						Name:              "new-feature-rule",
+						Labels:            map[string]string{"new-feature": "true"},
+						MinFeatureVersion: "v0.17.0",
+						MatchFeatures: nfdv1alpha1.FeatureMatcher{
+							{Feature: "cpu.cpuid", MatchExpressions: &nfdv1alpha1.MatchExpressionSet{"AVX512F": {Op: nfdv1alpha1.MatchExists}}},
+						},
+					},
+				},
+			},
+		}
+		fakeMaster := newFakeMaster(fakeclient.NewSimpleClientset())
+		fakeMaster.nfdController = newFakeNfdAPIController(fakenfdclient.NewSimpleClientset(
+			nfr,
+			newNodeFeature("node-1", "node-1", "v0.17.0-devel"),
+			newNodeFeature("node-2", "node-2", "v0.17.1"),
+			newNodeFeature("node-2-1", "node-2", "v0.16.0"),
+			newNodeFeature("node-3", "node-3", "undefined"),
+		))
+		So(fakeMaster.nfdController.waitForCacheSync(), ShouldBeTrue)
+
+		newFeatures := func() *nfdv1alpha1.Features {
+			f := nfdv1alpha1.NewFeatures()
+			f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX512F")
+			return f
+		}
+
+		Convey("the lowest parseable worker version of a node should be used", func() {
+			So(fakeMaster.nodeWorkerVersion("node-1").String(), ShouldEqual, "0.17.0")
+			So(fakeMaster.nodeWorkerVersion("node-2").String(), ShouldEqual, "0.16.0")
+			So(fakeMaster.nodeWorkerVersion("node-3"), ShouldBeNil)
+			So(fakeMaster.nodeWorkerVersion("node-4"), ShouldBeNil)
+		})
+		Convey("rules should be evaluated on nodes running a new enough or unknown worker", func() {
+			for _, nodeName := range []string{"node-1", "node-3", "node-4"} {
+				labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule(nodeName, nil, newFeatures())
+				So(labels, ShouldResemble, Labels{"new-feature": "true"})
+			}
+			So(fakeMaster.versionSkew.nodes("nfr-1"), ShouldBeEmpty)
+		})
+		Convey("rules should not be evaluated on nodes running an older worker", func() {
+			labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule("node-2", nil, newFeatures())
+			So(labels, ShouldBeEmpty)
+			So(fakeMaster.versionSkew.nodes("nfr-1"), ShouldResemble, map[string]string{"node-2": "0.16.0"})
+
+			Convey("the previous output of the rule should be retained", func() {
+				_, _, _, _, _, _ = fakeMaster.processNodeFeatureRule("node-1", nil, newFeatures())
+				fakeMaster.ruleOutputCache.store("node-2", fakeMaster.ruleOutputCache.load("node-1"))
+
+				labels, _, _, _, _, _ := fakeMaster.processNodeFeatureRule("node-2", nil, nfdv1alpha1.NewFeatures())
+				So(labels, ShouldResemble, Labels{"new-feature": "true"})
+			})
+			Convey("the mismatch should be reported in the status of the rule", func() {
+				fakeMaster.syncVersionSkew()
+				updated, err := fakeMaster.nfdController.nfdClient.NfdV1alpha1().NodeFeatureRules().Get(context.TODO(), "nfr-1", metav1.GetOptions{})
+				So(err, ShouldBeNil)
+				cond := meta.FindStatusCondition(updated.Status.Conditions, nfdv1alpha1.NodeFeatureRuleMinFeatureVersionCondition)
+				So(cond, ShouldNotBeNil)
+				So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+				So(cond.Reason, ShouldEqual, workerVersionTooOldReason)
+				So(cond.Message, ShouldContainSubstring, "node-2 (0.16.0)")
+				So(cond.ObservedGeneration, ShouldEqual, 1)
+			})
+			Convey("the mismatch should be cleared when the node is evaluated again", func() {
+				fakeMaster.versionSkew.setNode("node-2", "0.17.0", nil)
+				So(fakeMaster.versionSkew.nodes("nfr-1"), ShouldBeEmpty)
+				So(versionSkewCondition(nfr, nil).Status, ShouldEqual, metav1.ConditionTrue)
+			})
+		})
+	})
+}
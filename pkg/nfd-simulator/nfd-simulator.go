@@ -0,0 +1,294 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdsimulator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// SimulatedNodeLabel is the label added to all objects created by the
+// simulator.
+const SimulatedNodeLabel = "nfd.node.kubernetes.io/simulated"
+
+// Args are the command line arguments
+type Args struct {
+	Kubeconfig string
+	Namespace  string
+	// NodeNamePrefix is the prefix of the simulated node names, the index
+	// of the node being appended to it.
+	NodeNamePrefix string
+	// Nodes is the number of simulated nodes.
+	Nodes int
+	// Features is the number of feature elements of each type (flag,
+	// attribute and instance) per node.
+	Features int
+	// Labels is the number of feature labels per node.
+	Labels int
+	// ValueCardinality is the number of distinct values of each attribute
+	// and label across the simulated nodes.
+	ValueCardinality int
+	// Rate is the maximum number of NodeFeature objects published per second.
+	Rate float64
+	// UpdateInterval is the interval at which the features of all nodes are
+	// re-generated and published. Zero publishes the features only once.
+	UpdateInterval time.Duration
+	// CreateNodes specifies whether Node objects are created for the
+	// simulated nodes. Without them nfd-master has no nodes to label.
+	CreateNodes bool
+	// Cleanup specifies whether the created objects are deleted on exit.
+	Cleanup bool
+	// Seed of the feature generator.
+	Seed int64
+}
+
+// NfdSimulator publishes synthetic NodeFeature objects for load-testing
+// nfd-master.
+type NfdSimulator interface {
+	Run() error
+	Stop()
+}
+
+type nfdSimulator struct {
+	args        *Args
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	k8sClient   kubernetes.Interface
+	nfdClient   nfdclientset.Interface
+	rateLimiter flowcontrol.RateLimiter
+	generation  int
+}
+
+// New creates a new simulator instance.
+func New(args *Args) (NfdSimulator, error) {
+	if err := validateArgs(args); err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := utils.GetKubeconfig(args.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	// Do not let client-side throttling limit the publishing rate
+	kubeconfig.QPS = float32(2 * args.Rate)
+	kubeconfig.Burst = int(2*args.Rate) + 1
+
+	return newSimulator(args, kubernetes.NewForConfigOrDie(kubeconfig), nfdclientset.NewForConfigOrDie(kubeconfig)), nil
+}
+
+func newSimulator(args *Args, k8sClient kubernetes.Interface, nfdClient nfdclientset.Interface) *nfdSimulator {
+	return &nfdSimulator{
+		args:        args,
+		stopChan:    make(chan struct{}),
+		k8sClient:   k8sClient,
+		nfdClient:   nfdClient,
+		rateLimiter: flowcontrol.NewTokenBucketRateLimiter(float32(args.Rate), 1),
+	}
+}
+
+func validateArgs(args *Args) error {
+	switch {
+	case args.Nodes <= 0:
+		return fmt.Errorf("number of nodes must be positive")
+	case args.Features < 0 || args.Labels < 0:
+		return fmt.Errorf("number of features and labels must not be negative")
+	case args.ValueCardinality <= 0:
+		return fmt.Errorf("value cardinality must be positive")
+	case args.Rate <= 0:
+		return fmt.Errorf("rate must be positive")
+	case args.UpdateInterval < 0:
+		return fmt.Errorf("update interval must not be negative")
+	case args.Namespace == "":
+		return fmt.Errorf("namespace must be specified")
+	}
+	return nil
+}
+
+// Run publishes the features of all simulated nodes and keeps updating them
+// until stopped.
+func (s *nfdSimulator) Run() error {
+	klog.InfoS("starting node feature simulator", "nodes", s.args.Nodes, "namespace", s.args.Namespace, "rate", s.args.Rate)
+
+	if s.args.Cleanup {
+		defer s.cleanup()
+	}
+
+	var tickChan <-chan time.Time
+	if s.args.UpdateInterval > 0 {
+		ticker := time.NewTicker(s.args.UpdateInterval)
+		defer ticker.Stop()
+		tickChan = ticker.C
+	}
+
+	for {
+		start := time.Now()
+		if !s.publishAll() {
+			return nil
+		}
+		klog.InfoS("published features of all simulated nodes", "generation", s.generation, "duration", time.Since(start))
+		s.generation++
+
+		select {
+		case <-tickChan:
+		case <-s.stopChan:
+			return nil
+		}
+	}
+}
+
+// Stop stops the simulator. It is safe to call Stop more than once, e.g. on
+// repeated signals.
+func (s *nfdSimulator) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+}
+
+// publishAll publishes the features of all nodes. Returns false if the
+// simulator was stopped.
+func (s *nfdSimulator) publishAll() bool {
+	for i := 0; i < s.args.Nodes; i++ {
+		select {
+		case <-s.stopChan:
+			return false
+		default:
+		}
+		s.rateLimiter.Accept()
+
+		nodeName := s.nodeName(i)
+		if s.args.CreateNodes && s.generation == 0 {
+			if err := s.createNode(nodeName); err != nil {
+				klog.ErrorS(err, "failed to create simulated node", "nodeName", nodeName)
+				continue
+			}
+		}
+		if err := s.publish(nodeName, s.generateSpec(i)); err != nil {
+			klog.ErrorS(err, "failed to publish simulated node features", "nodeName", nodeName)
+		}
+	}
+	return true
+}
+
+func (s *nfdSimulator) nodeName(index int) string {
+	return fmt.Sprintf("%s%d", s.args.NodeNamePrefix, index)
+}
+
+// generateSpec generates the features of one node. The result is
+// deterministic for the same seed, node and generation.
+func (s *nfdSimulator) generateSpec(index int) *nfdv1alpha1.NodeFeatureSpec {
+	rnd := rand.New(rand.NewSource(s.args.Seed + int64(index)*1000003 + int64(s.generation)))
+	value := func() string { return fmt.Sprintf("value-%d", rnd.Intn(s.args.ValueCardinality)) }
+
+	spec := nfdv1alpha1.NewNodeFeatureSpec()
+	flags := make([]string, s.args.Features)
+	attrs := make(map[string]string, s.args.Features)
+	instances := make([]nfdv1alpha1.InstanceFeature, s.args.Features)
+	for i := 0; i < s.args.Features; i++ {
+		flags[i] = fmt.Sprintf("flag-%d", i)
+		attrs[fmt.Sprintf("attr-%d", i)] = value()
+		instances[i] = *nfdv1alpha1.NewInstanceFeature(map[string]string{
+			"name":  fmt.Sprintf("instance-%d", i),
+			"value": value(),
+		})
+	}
+	spec.Features.Flags["simulated.flags"] = nfdv1alpha1.NewFlagFeatures(flags...)
+	spec.Features.Attributes["simulated.attributes"] = nfdv1alpha1.NewAttributeFeatures(attrs)
+	spec.Features.Instances["simulated.instances"] = nfdv1alpha1.NewInstanceFeatures(instances)
+
+	for i := 0; i < s.args.Labels; i++ {
+		spec.Labels[fmt.Sprintf("%s/simulated-%d", nfdv1alpha1.FeatureLabelNs, i)] = value()
+	}
+	return spec
+}
+
+// publish creates or updates the NodeFeature object of a node.
+func (s *nfdSimulator) publish(nodeName string, spec *nfdv1alpha1.NodeFeatureSpec) error {
+	cli := s.nfdClient.NfdV1alpha1().NodeFeatures(s.args.Namespace)
+
+	nf, err := cli.Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		nf = &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: nodeName,
+				Labels: map[string]string{
+					nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName,
+					SimulatedNodeLabel:                      "true",
+				},
+			},
+			Spec: *spec,
+		}
+		_, err = cli.Create(context.TODO(), nf, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	nf.Spec = *spec
+	_, err = cli.Update(context.TODO(), nf, metav1.UpdateOptions{})
+	return err
+}
+
+// createNode creates a Node object for a simulated node. The node is tainted
+// so that no workloads get scheduled on it.
+func (s *nfdSimulator) createNode(nodeName string) error {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: map[string]string{SimulatedNodeLabel: "true"},
+		},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{{Key: SimulatedNodeLabel, Value: "true", Effect: corev1.TaintEffectNoSchedule}},
+		},
+	}
+	_, err := s.k8sClient.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// cleanup deletes the NodeFeature and Node objects of the simulated nodes.
+func (s *nfdSimulator) cleanup() {
+	klog.InfoS("deleting simulated nodes")
+	for i := 0; i < s.args.Nodes; i++ {
+		s.rateLimiter.Accept()
+
+		nodeName := s.nodeName(i)
+		err := s.nfdClient.NfdV1alpha1().NodeFeatures(s.args.Namespace).Delete(context.TODO(), nodeName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.ErrorS(err, "failed to delete NodeFeature object", "nodefeature", klog.KRef(s.args.Namespace, nodeName))
+		}
+		if s.args.CreateNodes {
+			err := s.k8sClient.CoreV1().Nodes().Delete(context.TODO(), nodeName, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				klog.ErrorS(err, "failed to delete simulated node", "nodeName", nodeName)
+			}
+		}
+	}
+}
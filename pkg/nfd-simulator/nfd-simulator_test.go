@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdsimulator
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8sclientset "k8s.io/client-go/kubernetes/fake"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func newTestArgs() *Args {
+	return &Args{
+		Namespace:        "nfd",
+		NodeNamePrefix:   "sim-",
+		Nodes:            3,
+		Features:         4,
+		Labels:           2,
+		ValueCardinality: 5,
+		Rate:             1000,
+		CreateNodes:      true,
+		Cleanup:          true,
+	}
+}
+
+func TestGenerateSpec(t *testing.T) {
+	Convey("When generating node features", t, func() {
+		s := newSimulator(newTestArgs(), nil, nil)
+		spec := s.generateSpec(0)
+
+		Convey("the configured number of features and labels should be generated", func() {
+			So(spec.Features.Flags["simulated.flags"].Elements, ShouldHaveLength, 4)
+			So(spec.Features.Attributes["simulated.attributes"].Elements, ShouldHaveLength, 4)
+			So(spec.Features.Instances["simulated.instances"].Elements, ShouldHaveLength, 4)
+			So(spec.Labels, ShouldHaveLength, 2)
+			So(spec.Labels, ShouldContainKey, nfdv1alpha1.FeatureLabelNs+"/simulated-0")
+		})
+		Convey("the features should be deterministic", func() {
+			So(s.generateSpec(0), ShouldResemble, spec)
+		})
+		Convey("the features should change between generations", func() {
+			s.generation++
+			So(s.generateSpec(0), ShouldNotResemble, spec)
+		})
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	Convey("When validating arguments", t, func() {
+		args := newTestArgs()
+		So(validateArgs(args), ShouldBeNil)
+
+		args.Rate = 0
+		So(validateArgs(args), ShouldNotBeNil)
+
+		args = newTestArgs()
+		args.ValueCardinality = 0
+		So(validateArgs(args), ShouldNotBeNil)
+
+		args = newTestArgs()
+		args.Nodes = 0
+		So(validateArgs(args), ShouldNotBeNil)
+	})
+}
+
+func TestRun(t *testing.T) {
+	Convey("When running the simulator", t, func() {
+		k8sCli := fakek8sclientset.NewSimpleClientset()
+		nfdCli := fakenfdclientset.NewSimpleClientset()
+		s := newSimulator(newTestArgs(), k8sCli, nfdCli)
+
+		So(s.publishAll(), ShouldBeTrue)
+
+		Convey("NodeFeature and Node objects should be created", func() {
+			nfs, err := nfdCli.NfdV1alpha1().NodeFeatures("nfd").List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(nfs.Items, ShouldHaveLength, 3)
+			So(nfs.Items[0].Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel], ShouldEqual, nfs.Items[0].Name)
+
+			nodes, err := k8sCli.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(nodes.Items, ShouldHaveLength, 3)
+		})
+
+		Convey("NodeFeature objects should be updated", func() {
+			s.generation++
+			So(s.publishAll(), ShouldBeTrue)
+			nf, err := nfdCli.NfdV1alpha1().NodeFeatures("nfd").Get(context.TODO(), "sim-1", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(&nf.Spec, ShouldResemble, s.generateSpec(1))
+		})
+
+		Convey("all objects should be deleted on cleanup", func() {
+			s.cleanup()
+			nfs, err := nfdCli.NfdV1alpha1().NodeFeatures("nfd").List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(nfs.Items, ShouldBeEmpty)
+
+			nodes, err := k8sCli.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+			So(err, ShouldBeNil)
+			So(nodes.Items, ShouldBeEmpty)
+		})
+
+		Convey("publishing should be interrupted when stopped", func() {
+			s.Stop()
+			So(s.publishAll(), ShouldBeFalse)
+			So(s.Stop, ShouldNotPanic)
+		})
+	})
+}
@@ -32,6 +32,7 @@ type EventType string
 const (
 	IntervalBased EventType = "intervalBased"
 	FSUpdate      EventType = "fsUpdate"
+	PodUpdate     EventType = "podUpdate"
 
 	devicePluginsDirName = "device-plugins"
 )
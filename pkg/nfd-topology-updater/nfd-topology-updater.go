@@ -21,17 +21,26 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
 	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
 
 	"github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	topologyclientset "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/generated/clientset/versioned"
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	"github.com/openshift/node-feature-discovery/pkg/nfd-topology-updater/kubeletnotifier"
 	"github.com/openshift/node-feature-discovery/pkg/podres"
 	"github.com/openshift/node-feature-discovery/pkg/resourcemonitor"
@@ -57,6 +66,8 @@ type Args struct {
 	KubeConfigFile  string
 	ConfigFile      string
 	KubeletStateDir string
+	PodEvents       bool
+	MetricsSecurity utils.MetricsSecurityArgs
 
 	Klog map[string]*utils.KlogFlagVal
 }
@@ -64,6 +75,9 @@ type Args struct {
 // NFDConfig contains the configuration settings of NFDTopologyUpdater.
 type NFDConfig struct {
 	ExcludeList map[string][]string
+	// FeatureGates enables or disables feature gates. Gates specified with
+	// the -feature-gates command line flag take precedence.
+	FeatureGates map[string]bool
 }
 
 type NfdTopologyUpdater interface {
@@ -78,6 +92,7 @@ type nfdTopologyUpdater struct {
 	resourcemonitorArgs resourcemonitor.Args
 	stop                chan struct{} // channel for signaling stop
 	eventSource         <-chan kubeletnotifier.Info
+	podEvents           chan kubeletnotifier.Info
 	configFilePath      string
 	config              *NFDConfig
 	kubeletConfigFunc   func() (*kubeletconfigv1beta1.KubeletConfiguration, error)
@@ -93,7 +108,8 @@ func NewTopologyUpdater(args Args, resourcemonitorArgs resourcemonitor.Args) (Nf
 	}
 	go ntf.Run()
 
-	kubeletConfigFunc, err := getKubeletConfigFunc(resourcemonitorArgs.KubeletConfigURI, resourcemonitorArgs.APIAuthTokenFile)
+	nodeName := utils.NodeName()
+	kubeletConfigFunc, err := getKubeletConfigFunc(resourcemonitorArgs.KubeletConfigURI, resourcemonitorArgs.APIAuthTokenFile, args.KubeConfigFile, nodeName)
 	if err != nil {
 		return nil, err
 	}
@@ -102,8 +118,9 @@ func NewTopologyUpdater(args Args, resourcemonitorArgs resourcemonitor.Args) (Nf
 		args:                args,
 		resourcemonitorArgs: resourcemonitorArgs,
 		stop:                make(chan struct{}, 1),
-		nodeName:            utils.NodeName(),
+		nodeName:            nodeName,
 		eventSource:         eventSource,
+		podEvents:           make(chan kubeletnotifier.Info, 1),
 		config:              &NFDConfig{},
 		kubeletConfigFunc:   kubeletConfigFunc,
 	}
@@ -151,11 +168,19 @@ func (w *nfdTopologyUpdater) Run() error {
 		return fmt.Errorf("faild to configure Node Feature Discovery Topology Updater: %w", err)
 	}
 
+	if w.args.PodEvents && !w.args.Oneshot {
+		go w.watchPodResources(k8sClient)
+	}
+
 	// Register to metrics server
 	if w.args.MetricsPort > 0 {
 		m := utils.CreateMetricsServer(w.args.MetricsPort,
 			buildInfo,
-			scanErrors)
+			scanErrors,
+			features.FeatureEnabled)
+		if err := m.Secure(w.args.MetricsSecurity, w.args.KubeConfigFile); err != nil {
+			return fmt.Errorf("failed to configure metrics server: %w", err)
+		}
 		go m.Run()
 		registerVersion(version.Get())
 		defer m.Stop()
@@ -175,47 +200,140 @@ func (w *nfdTopologyUpdater) Run() error {
 	var zones v1alpha2.ZoneList
 
 	excludeList := resourcemonitor.NewExcludeResourceList(w.config.ExcludeList, w.nodeName)
-	resAggr, err := resourcemonitor.NewResourcesAggregator(podResClient, excludeList)
+	resAggr, err := resourcemonitor.NewResourcesAggregator(podResClient, excludeList, w.resourcemonitorArgs.MemoryManagerStateFile)
 	if err != nil {
 		return fmt.Errorf("failed to obtain node resource information: %w", err)
 	}
 
 	for {
+		var info kubeletnotifier.Info
 		select {
-		case info := <-w.eventSource:
-			klog.V(4).InfoS("event received, scanning...", "event", info.Event)
-			scanResponse, err := resScan.Scan()
-			klog.V(1).InfoS("received updated pod resources", "podResources", utils.DelayedDumper(scanResponse.PodResources))
-			if err != nil {
-				klog.ErrorS(err, "scan failed")
-				scanErrors.Inc()
-				continue
-			}
-			zones = resAggr.Aggregate(scanResponse.PodResources)
-			klog.V(1).InfoS("aggregated resources identified", "resourceZones", utils.DelayedDumper(zones))
-			readKubeletConfig := false
-			if info.Event == kubeletnotifier.IntervalBased {
-				readKubeletConfig = true
-			}
+		case info = <-w.eventSource:
+		case info = <-w.podEvents:
+		case <-w.stop:
+			klog.InfoS("shutting down nfd-topology-updater")
+			return nil
+		}
 
-			if !w.args.NoPublish {
-				if err = w.updateNodeResourceTopology(zones, scanResponse, readKubeletConfig); err != nil {
-					return err
-				}
-			}
+		klog.V(4).InfoS("event received, scanning...", "event", info.Event)
+		scanResponse, err := resScan.Scan()
+		klog.V(1).InfoS("received updated pod resources", "podResources", utils.DelayedDumper(scanResponse.PodResources))
+		if err != nil {
+			klog.ErrorS(err, "scan failed")
+			scanErrors.Inc()
+			continue
+		}
+		zones = resAggr.Aggregate(scanResponse.PodResources)
+		klog.V(1).InfoS("aggregated resources identified", "resourceZones", utils.DelayedDumper(zones))
+		readKubeletConfig := false
+		if info.Event == kubeletnotifier.IntervalBased {
+			readKubeletConfig = true
+		}
 
-			if w.args.Oneshot {
-				return nil
+		if !w.args.NoPublish {
+			if err = w.updateNodeResourceTopology(zones, scanResponse, readKubeletConfig); err != nil {
+				return err
 			}
+		}
 
-		case <-w.stop:
-			klog.InfoS("shutting down nfd-topology-updater")
+		if w.args.Oneshot {
 			return nil
 		}
 	}
 
 }
 
+// watchPodResources triggers re-scans from the Watch stream of the
+// podresources API. If the kubelet does not implement the Watch endpoint pod
+// events from the API server are used instead.
+func (w *nfdTopologyUpdater) watchPodResources(k8sClient k8sclient.Interface) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		err := podres.WatchPodResources(ctx, w.resourcemonitorArgs.PodResourceSocketPath, w.notifyPodEvent)
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			klog.InfoS("podresources Watch endpoint not available, watching pods from the API server instead")
+			if err := w.startPodInformer(k8sClient); err != nil {
+				klog.ErrorS(err, "failed to start pod informer")
+			}
+			return
+		}
+		if err != nil {
+			klog.ErrorS(err, "podresources watch failed, retrying", "retryInterval", w.resourcemonitorArgs.SleepInterval)
+		} else {
+			klog.V(2).InfoS("podresources watch stream closed, retrying", "retryInterval", w.resourcemonitorArgs.SleepInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.resourcemonitorArgs.SleepInterval):
+		}
+	}
+}
+
+// startPodInformer starts watching the pods running on this node. Pod
+// churn triggers a re-scan of the podresources API so that allocations are
+// reflected without waiting for the next sleep interval.
+func (w *nfdTopologyUpdater) startPodInformer(k8sClient k8sclient.Interface) error {
+	namespace := w.resourcemonitorArgs.Namespace
+	if namespace == "*" {
+		namespace = metav1.NamespaceAll
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k8sClient, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", w.nodeName).String()
+		}))
+	podInformer := factory.Core().V1().Pods().Informer()
+
+	if _, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			w.notifyPodEvent()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok1 := oldObj.(*corev1.Pod)
+			newPod, ok2 := newObj.(*corev1.Pod)
+			if !ok1 || !ok2 {
+				return
+			}
+			if oldPod.Status.Phase != newPod.Status.Phase || (oldPod.DeletionTimestamp == nil && newPod.DeletionTimestamp != nil) {
+				w.notifyPodEvent()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.notifyPodEvent()
+		},
+	}); err != nil {
+		return err
+	}
+
+	factory.Start(w.stop)
+	factory.WaitForCacheSync(w.stop)
+
+	return nil
+}
+
+// notifyPodEvent queues a re-scan. Events are coalesced so that at most one
+// scan is pending at any time.
+func (w *nfdTopologyUpdater) notifyPodEvent() {
+	select {
+	case w.podEvents <- kubeletnotifier.Info{Event: kubeletnotifier.PodUpdate}:
+		klog.V(5).InfoS("pod event received")
+	default:
+	}
+}
+
 // Stop NFD Topology Updater
 func (w *nfdTopologyUpdater) Stop() {
 	close(w.stop)
@@ -226,7 +344,8 @@ func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneLi
 	if errors.IsNotFound(err) {
 		nrtNew := v1alpha2.NodeResourceTopology{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: w.nodeName,
+				Name:   w.nodeName,
+				Labels: map[string]string{nfdv1alpha1.NodeResourceTopologyOwnerLabel: "true"},
 			},
 			Zones:      zoneInfo,
 			Attributes: v1alpha2.AttributeList{},
@@ -248,6 +367,11 @@ func (w *nfdTopologyUpdater) updateNodeResourceTopology(zoneInfo v1alpha2.ZoneLi
 
 	nrtMutated := nrt.DeepCopy()
 	nrtMutated.Zones = zoneInfo
+	// Objects created by older versions do not have the owner label
+	if nrtMutated.Labels == nil {
+		nrtMutated.Labels = map[string]string{}
+	}
+	nrtMutated.Labels[nfdv1alpha1.NodeResourceTopologyOwnerLabel] = "true"
 
 	attributes := scanResponse.Attributes
 
@@ -303,6 +427,9 @@ func (w *nfdTopologyUpdater) configure() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse configuration file %q: %w", w.configFilePath, err)
 	}
+	if err := features.Apply(w.config.FeatureGates); err != nil {
+		return err
+	}
 	klog.InfoS("configuration file parsed", "path", w.configFilePath, "config", w.config)
 	return nil
 }
@@ -339,7 +466,28 @@ func updateAttributes(lhs *v1alpha2.AttributeList, rhs v1alpha2.AttributeList) {
 	}
 }
 
-func getKubeletConfigFunc(uri, apiAuthTokenFile string) (func() (*kubeletconfigv1beta1.KubeletConfiguration, error), error) {
+func getKubeletConfigFunc(uri, apiAuthTokenFile, kubeConfigFile, nodeName string) (func() (*kubeletconfigv1beta1.KubeletConfiguration, error), error) {
+	// Without an explicit URI access the kubelet through the API server
+	if uri == "" {
+		kubeconfig, err := utils.GetKubeconfig(kubeConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		client, err := k8sclient.NewForConfig(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		klog.InfoS("reading kubelet config via the API server node proxy", "nodeName", nodeName)
+
+		return func() (*kubeletconfigv1beta1.KubeletConfiguration, error) {
+			klConfig, err := kubeconf.GetKubeletConfigurationViaAPIServer(client, nodeName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get kubelet config via API server: %w", err)
+			}
+			return klConfig, nil
+		}, nil
+	}
+
 	u, err := url.ParseRequestURI(uri)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse -kubelet-config-uri: %w", err)
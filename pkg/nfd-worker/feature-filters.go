@@ -0,0 +1,240 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// FeatureFilterAction is the operation a feature filter performs.
+type FeatureFilterAction string
+
+const (
+	// FeatureFilterDrop removes matching features or elements.
+	FeatureFilterDrop FeatureFilterAction = "Drop"
+	// FeatureFilterRename renames the matching feature or element.
+	FeatureFilterRename FeatureFilterAction = "Rename"
+	// FeatureFilterMapValue rewrites values of matching elements through a
+	// lookup table. Values not found in the table are left intact.
+	FeatureFilterMapValue FeatureFilterAction = "MapValue"
+	// FeatureFilterHash replaces values of matching elements with a keyed
+	// hash, using the per-cluster salt as the key. Equal values give equal
+	// hashes so rules can still match on equality and uniqueness.
+	FeatureFilterHash FeatureFilterAction = "Hash"
+)
+
+// featureFilter is one entry in the filter chain that is applied to the
+// discovered features before labels are created from them.
+type featureFilter struct {
+	// Feature is a glob pattern matched against feature names, e.g.
+	// "system.dmiid" or "pci.*".
+	Feature string `json:"feature"`
+	// Element is an optional glob pattern matched against element names,
+	// i.e. the keys of flag and attribute features and the attribute names of
+	// instance features. If empty, the filter applies to the feature as a
+	// whole.
+	Element string `json:"element,omitempty"`
+	// Action to perform.
+	Action FeatureFilterAction `json:"action"`
+	// Rename is the new name of the feature (or element), used with the
+	// Rename action.
+	Rename string `json:"rename,omitempty"`
+	// ValueMap is the lookup table used with the MapValue action.
+	ValueMap map[string]string `json:"valueMap,omitempty"`
+
+	// salt is the key used with the Hash action.
+	salt []byte
+}
+
+// validate checks that a feature filter is well-formed.
+func (f *featureFilter) validate() error {
+	if f.Feature == "" {
+		return fmt.Errorf("feature must be specified")
+	}
+	if _, err := path.Match(f.Feature, ""); err != nil {
+		return fmt.Errorf("invalid feature pattern %q: %w", f.Feature, err)
+	}
+	if _, err := path.Match(f.Element, ""); err != nil {
+		return fmt.Errorf("invalid element pattern %q: %w", f.Element, err)
+	}
+
+	switch f.Action {
+	case FeatureFilterDrop:
+	case FeatureFilterRename:
+		if f.Rename == "" {
+			return fmt.Errorf("rename must be specified for action %q", f.Action)
+		}
+		// Renaming multiple features (or elements) into one would silently
+		// lose data
+		if f.Element == "" && isPattern(f.Feature) {
+			return fmt.Errorf("feature must not be a pattern for action %q", f.Action)
+		}
+		if f.Element != "" && isPattern(f.Element) {
+			return fmt.Errorf("element must not be a pattern for action %q", f.Action)
+		}
+		// Features are filtered per source
+		if src, _, _ := strings.Cut(f.Feature, "."); f.Element == "" && !strings.HasPrefix(f.Rename, src+".") {
+			return fmt.Errorf("rename must have the same source prefix %q as the feature for action %q", src+".", f.Action)
+		}
+	case FeatureFilterMapValue:
+		if f.Element == "" {
+			return fmt.Errorf("element must be specified for action %q", f.Action)
+		}
+		if len(f.ValueMap) == 0 {
+			return fmt.Errorf("valueMap must be specified for action %q", f.Action)
+		}
+	case FeatureFilterHash:
+		if f.Element == "" {
+			return fmt.Errorf("element must be specified for action %q", f.Action)
+		}
+		if len(f.salt) == 0 {
+			return fmt.Errorf("core.featureHashSaltFile must be specified for action %q", f.Action)
+		}
+	default:
+		return fmt.Errorf("invalid action %q", f.Action)
+	}
+	return nil
+}
+
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+func globMatch(pattern, name string) bool {
+	match, _ := path.Match(pattern, name)
+	return match
+}
+
+// applyFeatureFilters runs the filter chain over a copy of the given features
+// and returns the result. Filters are applied in order.
+func applyFeatureFilters(features *nfdv1alpha1.Features, filters []featureFilter) *nfdv1alpha1.Features {
+	if len(filters) == 0 {
+		return features
+	}
+
+	out := features.DeepCopy()
+	for i := range filters {
+		filters[i].apply(out)
+	}
+	return out
+}
+
+// filterSourceFeatures applies the feature lists and the filter chain to the
+// features of one source, in place.
+func filterSourceFeatures(name string, features *nfdv1alpha1.Features, lists map[string]sourceFeatureLists, filters []featureFilter) {
+	prefix := name + "."
+	all := nfdv1alpha1.NewFeatures()
+	for k, v := range features.Flags {
+		all.Flags[prefix+k] = v
+	}
+	for k, v := range features.Attributes {
+		all.Attributes[prefix+k] = v
+	}
+	for k, v := range features.Instances {
+		all.Instances[prefix+k] = v
+	}
+
+	all = applyFeatureFilters(applySourceFeatureLists(all, lists), filters)
+
+	*features = *nfdv1alpha1.NewFeatures()
+	for k, v := range all.Flags {
+		features.Flags[strings.TrimPrefix(k, prefix)] = v
+	}
+	for k, v := range all.Attributes {
+		features.Attributes[strings.TrimPrefix(k, prefix)] = v
+	}
+	for k, v := range all.Instances {
+		features.Instances[strings.TrimPrefix(k, prefix)] = v
+	}
+}
+
+func (f *featureFilter) apply(features *nfdv1alpha1.Features) {
+	if f.Element == "" {
+		renameFeatures(f, features.Flags)
+		renameFeatures(f, features.Attributes)
+		renameFeatures(f, features.Instances)
+		return
+	}
+
+	for _, name := range matchingKeys(f.Feature, features.Flags) {
+		// Flag features have no values to map
+		if f.Action != FeatureFilterMapValue && f.Action != FeatureFilterHash {
+			filterElements(f, features.Flags[name].Elements)
+		}
+	}
+	for _, name := range matchingKeys(f.Feature, features.Attributes) {
+		filterElements(f, features.Attributes[name].Elements)
+	}
+	for _, name := range matchingKeys(f.Feature, features.Instances) {
+		for _, instance := range features.Instances[name].Elements {
+			filterElements(f, instance.Attributes)
+		}
+	}
+}
+
+// matchingKeys returns the keys of a map matching a glob pattern.
+func matchingKeys[T any](pattern string, m map[string]T) []string {
+	keys := []string{}
+	for k := range m {
+		if globMatch(pattern, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// renameFeatures drops or renames whole features.
+func renameFeatures[T any](f *featureFilter, features map[string]T) {
+	for _, name := range matchingKeys(f.Feature, features) {
+		set := features[name]
+		delete(features, name)
+		if f.Action == FeatureFilterRename {
+			features[f.Rename] = set
+		}
+	}
+}
+
+// filterElements applies the filter to the elements of one feature, or to the
+// attributes of one instance.
+func filterElements[T any](f *featureFilter, elements map[string]T) {
+	for _, name := range matchingKeys(f.Element, elements) {
+		switch f.Action {
+		case FeatureFilterDrop:
+			delete(elements, name)
+		case FeatureFilterRename:
+			value := elements[name]
+			delete(elements, name)
+			elements[f.Rename] = value
+		case FeatureFilterMapValue:
+			// Only string values can be mapped
+			if v, ok := any(elements[name]).(string); ok {
+				if mapped, ok := f.ValueMap[v]; ok {
+					elements[name] = any(mapped).(T)
+				}
+			}
+		case FeatureFilterHash:
+			if v, ok := any(elements[name]).(string); ok {
+				elements[name] = any(utils.HashValue(f.salt, v)).(T)
+			}
+		}
+	}
+}
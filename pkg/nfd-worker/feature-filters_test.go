@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func newFilterTestFeatures() *nfdv1alpha1.Features {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX", "AVX2")
+	f.Attributes["system.dmiid"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+		"sys_vendor":     "acme",
+		"product_serial": "12345",
+		"product_uuid":   "abcd-efgh",
+	})
+	f.Attributes["kernel.selinux"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"enabled": "true"})
+	f.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "8086", "serial": "x"}),
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "10de", "serial": "y"}),
+	})
+	return f
+}
+
+func TestFeatureFilters(t *testing.T) {
+	Convey("When applying feature filters", t, func() {
+		features := newFilterTestFeatures()
+
+		Convey("without filters the features should be returned as-is", func() {
+			So(applyFeatureFilters(features, nil), ShouldEqual, features)
+		})
+
+		Convey("dropping elements by pattern", func() {
+			out := applyFeatureFilters(features, []featureFilter{
+				{Feature: "system.*", Element: "product_*", Action: FeatureFilterDrop},
+				{Feature: "pci.device", Element: "serial", Action: FeatureFilterDrop},
+			})
+			So(out.Attributes["system.dmiid"].Elements, ShouldResemble, map[string]string{"sys_vendor": "acme"})
+			So(out.Instances["pci.device"].Elements[0].Attributes, ShouldResemble, map[string]string{"vendor": "8086"})
+			So(out.Instances["pci.device"].Elements[1].Attributes, ShouldResemble, map[string]string{"vendor": "10de"})
+			Convey("the original features should not be modified", func() {
+				So(features, ShouldResemble, newFilterTestFeatures())
+			})
+		})
+
+		Convey("dropping whole features", func() {
+			out := applyFeatureFilters(features, []featureFilter{
+				{Feature: "system.dmiid", Action: FeatureFilterDrop},
+				{Feature: "cpu.*", Action: FeatureFilterDrop},
+			})
+			So(out.Attributes, ShouldNotContainKey, "system.dmiid")
+			So(out.Flags, ShouldBeEmpty)
+		})
+
+		Convey("renaming features and elements", func() {
+			out := applyFeatureFilters(features, []featureFilter{
+				{Feature: "kernel.selinux", Action: FeatureFilterRename, Rename: "kernel.se"},
+				{Feature: "cpu.cpuid", Element: "AVX", Action: FeatureFilterRename, Rename: "AVX1"},
+			})
+			So(out.Attributes, ShouldNotContainKey, "kernel.selinux")
+			So(out.Attributes["kernel.se"].Elements, ShouldResemble, map[string]string{"enabled": "true"})
+			So(out.Flags["cpu.cpuid"].Elements, ShouldContainKey, "AVX1")
+			So(out.Flags["cpu.cpuid"].Elements, ShouldNotContainKey, "AVX")
+		})
+
+		Convey("mapping values", func() {
+			out := applyFeatureFilters(features, []featureFilter{
+				{Feature: "pci.device", Element: "vendor", Action: FeatureFilterMapValue, ValueMap: map[string]string{"8086": "intel"}},
+				{Feature: "system.dmiid", Element: "product_uuid", Action: FeatureFilterMapValue, ValueMap: map[string]string{"abcd-efgh": "redacted"}},
+			})
+			So(out.Instances["pci.device"].Elements[0].Attributes["vendor"], ShouldEqual, "intel")
+			So(out.Instances["pci.device"].Elements[1].Attributes["vendor"], ShouldEqual, "10de")
+			So(out.Attributes["system.dmiid"].Elements["product_uuid"], ShouldEqual, "redacted")
+		})
+
+		Convey("hashing values", func() {
+			filters := []featureFilter{
+				{Feature: "pci.device", Element: "serial", Action: FeatureFilterHash, salt: []byte("salt")},
+				{Feature: "system.dmiid", Element: "product_uuid", Action: FeatureFilterHash, salt: []byte("salt")},
+			}
+			out := applyFeatureFilters(features, filters)
+			hashed := out.Attributes["system.dmiid"].Elements["product_uuid"]
+			So(hashed, ShouldHaveLength, utils.HashedValueLen)
+			So(hashed, ShouldNotEqual, "abcd-efgh")
+			So(out.Instances["pci.device"].Elements[0].Attributes["serial"], ShouldNotEqual, out.Instances["pci.device"].Elements[1].Attributes["serial"])
+			So(out.Instances["pci.device"].Elements[0].Attributes["vendor"], ShouldEqual, "8086")
+			Convey("hashes should be stable", func() {
+				So(applyFeatureFilters(features, filters).Attributes["system.dmiid"].Elements["product_uuid"], ShouldEqual, hashed)
+			})
+			Convey("hashes should depend on the salt", func() {
+				filters[1].salt = []byte("other-salt")
+				So(applyFeatureFilters(features, filters).Attributes["system.dmiid"].Elements["product_uuid"], ShouldNotEqual, hashed)
+			})
+		})
+	})
+
+	Convey("When validating feature filters", t, func() {
+		valid := []featureFilter{
+			{Feature: "system.*", Action: FeatureFilterDrop},
+			{Feature: "system.dmiid", Element: "product_*", Action: FeatureFilterDrop},
+			{Feature: "system.dmiid", Action: FeatureFilterRename, Rename: "system.dmi"},
+			{Feature: "pci.*", Element: "vendor", Action: FeatureFilterRename, Rename: "vendor_id"},
+			{Feature: "pci.device", Element: "vendor", Action: FeatureFilterMapValue, ValueMap: map[string]string{"a": "b"}},
+			{Feature: "system.dmiid", Element: "product_uuid", Action: FeatureFilterHash, salt: []byte("salt")},
+		}
+		for _, f := range valid {
+			So(f.validate(), ShouldBeNil)
+		}
+
+		invalid := []featureFilter{
+			{Action: FeatureFilterDrop},
+			{Feature: "[", Action: FeatureFilterDrop},
+			{Feature: "system.dmiid", Element: "[", Action: FeatureFilterDrop},
+			{Feature: "system.dmiid", Action: "Unknown"},
+			{Feature: "system.dmiid", Action: FeatureFilterRename},
+			{Feature: "system.*", Action: FeatureFilterRename, Rename: "foo"},
+			{Feature: "system.dmiid", Action: FeatureFilterRename, Rename: "kernel.dmiid"},
+			{Feature: "system.dmiid", Element: "*", Action: FeatureFilterRename, Rename: "foo"},
+			{Feature: "system.dmiid", Action: FeatureFilterMapValue, ValueMap: map[string]string{"a": "b"}},
+			{Feature: "system.dmiid", Element: "foo", Action: FeatureFilterMapValue},
+			{Feature: "system.dmiid", Element: "product_uuid", Action: FeatureFilterHash},
+			{Feature: "system.dmiid", Action: FeatureFilterHash, salt: []byte("salt")},
+		}
+		for _, f := range invalid {
+			So(f.validate(), ShouldNotBeNil)
+		}
+	})
+}
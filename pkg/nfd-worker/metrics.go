@@ -25,6 +25,8 @@ import (
 const (
 	buildInfoQuery                = "nfd_worker_build_info"
 	featureDiscoveryDurationQuery = "nfd_feature_discovery_duration_seconds"
+	sourceInputAvailableQuery     = "nfd_worker_source_input_available"
+	nodeFeatureUpdatesQuery       = "nfd_worker_nodefeature_updates_total"
 )
 
 var (
@@ -36,6 +38,20 @@ var (
 		},
 		[]string{"node"},
 	)
+	sourceInputAvailable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: sourceInputAvailableQuery,
+			Help: "Whether an input of a feature source is available (1) or not (0).",
+		},
+		[]string{"source", "input"},
+	)
+	nodeFeatureUpdates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: nodeFeatureUpdatesQuery,
+			Help: "Number of NodeFeature object updates by result (created, updated, patched or skipped).",
+		},
+		[]string{"result"},
+	)
 	buildInfo = prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: buildInfoQuery,
 		Help: "Version from which Node Feature Discovery was built.",
@@ -17,6 +17,8 @@ limitations under the License.
 package nfdworker
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,6 +29,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
 	"github.com/vektra/errors"
+	"sigs.k8s.io/yaml"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/labeler"
@@ -145,7 +148,10 @@ sources:
       - "DMI"
   pci:
     deviceClassWhitelist:
-      - "ff"`)
+      - "ff"
+    featureDenyList: ["device.serial"]
+  custom:
+    - name: "my.rule"`)
 		f.Close()
 		So(err, ShouldBeNil)
 
@@ -167,12 +173,15 @@ sources:
 				So(c.(*kernel.Config).ConfigOpts, ShouldResemble, []string{"DMI"})
 				c = source.GetConfigurableSource("pci").GetConfig()
 				So(c.(*pci.Config).DeviceClassWhitelist, ShouldResemble, []string{"ff"})
+				So(worker.config.sourceFeatureLists, ShouldResemble, map[string]sourceFeatureLists{
+					"pci": {FeatureDenyList: []string{"device.serial"}},
+				})
 			})
 		})
 
 		Convey("and a proper config file and overrides are given", func() {
 			worker.args = Args{Overrides: ConfigOverrideArgs{FeatureSources: &utils.StringSliceVal{"cpu"}}}
-			overrides := `{"core": {"labelSources": ["fake"],"noPublish": true},"sources": {"pci": {"deviceClassWhitelist": ["03"]}}}`
+			overrides := `{"core": {"labelSources": ["fake"],"noPublish": true},"sources": {"pci": {"deviceClassWhitelist": ["03"], "featureAllowList": ["device"]}}}`
 			So(worker.configure(f.Name(), overrides), ShouldBeNil)
 
 			Convey("overrides should take precedence over the config file", func() {
@@ -188,6 +197,9 @@ sources:
 				So(c.(*kernel.Config).ConfigOpts, ShouldResemble, []string{"DMI"})
 				c = source.GetConfigurableSource("pci").GetConfig()
 				So(c.(*pci.Config).DeviceClassWhitelist, ShouldResemble, []string{"03"})
+				So(worker.config.sourceFeatureLists, ShouldResemble, map[string]sourceFeatureLists{
+					"pci": {FeatureAllowList: []string{"device"}, FeatureDenyList: []string{"device.serial"}},
+				})
 			})
 		})
 	})
@@ -338,6 +350,153 @@ func TestNewNfdWorker(t *testing.T) {
 				So(worker.config.Core.LabelWhiteList, ShouldResemble, emptyRegexp)
 			})
 		})
+
+		Convey("with minimal privileges", func() {
+			args := &Args{
+				MinimalPrivileges: true,
+				Overrides: ConfigOverrideArgs{
+					FeatureSources: &utils.StringSliceVal{"cpu", "local"}}}
+			w, err := NewNfdWorker(args)
+			Convey("no error should be returned", func() {
+				So(err, ShouldBeNil)
+			})
+			worker := w.(*nfdWorker)
+			So(worker.configure("", ""), ShouldBeNil)
+			Convey("sources with unprivileged inputs should stay enabled", func() {
+				So(len(worker.featureSources), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func TestWriteFeatures(t *testing.T) {
+	Convey("When writing discovered features to stdout", t, func() {
+		newWorker := func(output string) (*nfdWorker, *bytes.Buffer) {
+			noPublish := true
+			w, err := NewNfdWorker(&Args{
+				Output: output,
+				Overrides: ConfigOverrideArgs{
+					NoPublish:      &noPublish,
+					FeatureSources: &utils.StringSliceVal{"fake"},
+					LabelSources:   &utils.StringSliceVal{"fake"},
+				},
+			})
+			So(err, ShouldBeNil)
+			worker := w.(*nfdWorker)
+			So(worker.configure("", ""), ShouldBeNil)
+			out := &bytes.Buffer{}
+			worker.stdout = out
+			return worker, out
+		}
+
+		for _, format := range []string{"json", "yaml"} {
+			Convey("in "+format+" format", func() {
+				worker, out := newWorker(format)
+				So(worker.runFeatureDiscovery(), ShouldBeNil)
+
+				spec := nfdv1alpha1.NodeFeatureSpec{}
+				if format == "json" {
+					So(json.Unmarshal(out.Bytes(), &spec), ShouldBeNil)
+				} else {
+					So(yaml.Unmarshal(out.Bytes(), &spec), ShouldBeNil)
+				}
+				So(spec.Labels, ShouldContainKey, nfdv1alpha1.FeatureLabelNs+"/fake-fakefeature1")
+				So(spec.Features.Flags, ShouldContainKey, "fake.flag")
+			})
+		}
+
+		Convey("without output nothing should be written", func() {
+			worker, out := newWorker("")
+			So(worker.runFeatureDiscovery(), ShouldBeNil)
+			So(out.Len(), ShouldEqual, 0)
+		})
+
+		Convey("invalid formats should be rejected", func() {
+			_, err := NewNfdWorker(&Args{Output: "xml"})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestFilteredFeatureLabels(t *testing.T) {
+	Convey("When feature filters are configured", t, func() {
+		runDiscovery := func(core, sources string) (*nfdv1alpha1.NodeFeatureSpec, error) {
+			noPublish := true
+			w, err := NewNfdWorker(&Args{
+				Output: "json",
+				Overrides: ConfigOverrideArgs{
+					NoPublish:      &noPublish,
+					FeatureSources: &utils.StringSliceVal{"fake"},
+					LabelSources:   &utils.StringSliceVal{"custom"},
+				},
+			})
+			So(err, ShouldBeNil)
+			worker := w.(*nfdWorker)
+			if err := worker.configure("", core+`
+sources:`+sources+`
+  custom:
+    - name: "fake rule"
+      labels:
+        fake-attr: "true"
+      matchFeatures:
+        - feature: fake.attribute
+          matchExpressions:
+            attr_3: {op: In, value: ["10"]}
+`); err != nil {
+				return nil, err
+			}
+			out := &bytes.Buffer{}
+			worker.stdout = out
+			So(worker.runFeatureDiscovery(), ShouldBeNil)
+			spec := &nfdv1alpha1.NodeFeatureSpec{}
+			So(json.Unmarshal(out.Bytes(), spec), ShouldBeNil)
+			return spec, nil
+		}
+		label := "fake-attr"
+
+		Convey("custom rules should match unfiltered features", func() {
+			spec, err := runDiscovery("", "")
+			So(err, ShouldBeNil)
+			So(spec.Labels, ShouldContainKey, label)
+		})
+
+		Convey("custom rules should not see dropped features", func() {
+			spec, err := runDiscovery(`
+core:
+  featureFilters:
+    - feature: fake.attribute
+      element: attr_3
+      action: Drop
+`, "")
+			So(err, ShouldBeNil)
+			So(spec.Labels, ShouldNotContainKey, label)
+			So(spec.Features.Attributes["fake.attribute"].Elements, ShouldNotContainKey, "attr_3")
+		})
+
+		Convey("custom rules should only see hashed values", func() {
+			saltFile := filepath.Join(t.TempDir(), "salt")
+			So(os.WriteFile(saltFile, []byte("salt"), 0644), ShouldBeNil)
+			spec, err := runDiscovery(`
+core:
+  featureHashSaltFile: `+saltFile+`
+  featureFilters:
+    - feature: fake.attribute
+      element: attr_3
+      action: Hash
+`, "")
+			So(err, ShouldBeNil)
+			So(spec.Labels, ShouldNotContainKey, label)
+			So(spec.Features.Attributes["fake.attribute"].Elements["attr_3"], ShouldEqual, utils.HashValue([]byte("salt"), "10"))
+		})
+
+		Convey("custom rules should not see features denied by the source feature lists", func() {
+			spec, err := runDiscovery("", `
+  fake:
+    featureDenyList: ["attribute.attr_3"]`)
+			So(err, ShouldBeNil)
+			So(spec.Labels, ShouldNotContainKey, label)
+			So(spec.Features.Attributes["fake.attribute"].Elements, ShouldNotContainKey, "attr_3")
+		})
 	})
 }
 
@@ -17,13 +17,16 @@ limitations under the License.
 package nfdworker
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -34,6 +37,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
@@ -42,25 +46,24 @@ import (
         apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/features"
 	nfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
 	pb "github.com/openshift/node-feature-discovery/pkg/labeler"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/configobject"
 	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"	
 	"github.com/openshift/node-feature-discovery/pkg/version"
 	"github.com/openshift/node-feature-discovery/source"
 
 	// Register all source packages
+	_ "github.com/openshift/node-feature-discovery/source/cloud"
 	_ "github.com/openshift/node-feature-discovery/source/cpu"
 	_ "github.com/openshift/node-feature-discovery/source/custom"
 	_ "github.com/openshift/node-feature-discovery/source/fake"
-	_ "github.com/openshift/node-feature-discovery/source/kernel"
 	_ "github.com/openshift/node-feature-discovery/source/local"
 	_ "github.com/openshift/node-feature-discovery/source/memory"
 	_ "github.com/openshift/node-feature-discovery/source/network"
-	_ "github.com/openshift/node-feature-discovery/source/pci"
-	_ "github.com/openshift/node-feature-discovery/source/storage"
 	_ "github.com/openshift/node-feature-discovery/source/system"
-	_ "github.com/openshift/node-feature-discovery/source/usb"
 )
 
 // NfdWorker is the interface for nfd-worker daemon
@@ -73,16 +76,47 @@ type NfdWorker interface {
 type NFDConfig struct {
 	Core    coreConfig
 	Sources sourcesConfig
+
+	// sourceFeatureLists are the featureAllowList and featureDenyList
+	// options of the sources, parsed separately from the source-specific
+	// configuration.
+	sourceFeatureLists map[string]sourceFeatureLists
 }
 
 type coreConfig struct {
-	Klog           klogutils.KlogConfigOpts
+	Klog klogutils.KlogConfigOpts
+	// LogVerbosity overrides the log verbosity of individual feature
+	// sources (by source name) and custom rules ("custom/<rule name>").
+	LogVerbosity   klogutils.VerbosityOverrides
 	LabelWhiteList utils.RegexpVal
 	NoPublish      bool
 	FeatureSources []string
 	Sources        *[]string
 	LabelSources   []string
 	SleepInterval  utils.DurationVal
+	FeatureFilters []featureFilter
+	// FeatureHashSaltFile is a file containing the per-cluster salt used by
+	// feature filters with the Hash action and by the sources for hashing
+	// serial numbers.
+	FeatureHashSaltFile string
+	// MaxNodeFeatureObjectSize is the maximum size (in bytes) of one
+	// NodeFeature object. If the features don't fit, they are split over
+	// multiple objects. Zero disables splitting.
+	MaxNodeFeatureObjectSize int
+	// NodeFeatureGroups publishes the features of the listed sources in
+	// separate NodeFeature objects, one per group. This way frequently
+	// changing sources do not cause rewrites of the (large) static parts.
+	NodeFeatureGroups map[string][]string
+	// NodeFeatureSigningKeyFile is a file containing the key for signing
+	// the NodeFeature objects, verified by nfd-master. If the file contains
+	// multiple keys (one per line) the first one is used.
+	NodeFeatureSigningKeyFile string
+	// FeatureGates enables or disables feature gates. Gates specified with
+	// the -feature-gates command line flag take precedence.
+	FeatureGates map[string]bool
+	// Egress is the policy for outbound network traffic of the feature
+	// sources, e.g. to cloud metadata services.
+	Egress source.EgressPolicy
 }
 
 type sourcesConfig map[string]source.Config
@@ -104,6 +138,29 @@ type Args struct {
 	Server               string
 	ServerNameOverride   string
 	MetricsPort          int
+	MetricsSecurity      utils.MetricsSecurityArgs
+	// MinimalPrivileges makes the worker avoid all inputs that require
+	// elevated privileges, see source.ProbingSource.
+	MinimalPrivileges bool
+	// Output is the format ("json" or "yaml") in which the discovered
+	// features are written to stdout after each discovery. Empty disables
+	// the output.
+	Output string
+	// EnableProfiling enables the pprof and runtime stats endpoints on the
+	// metrics server.
+	EnableProfiling bool
+	// ConfigObject is the name of the NFDConfig object to read the
+	// configuration from, in addition to the configuration file.
+	ConfigObject string
+	// Standalone runs the worker without any Kubernetes API access, the
+	// features are only output locally (-output, -output-file, -socket).
+	Standalone bool
+	// OutputFile is a file where the discovered features are written in
+	// JSON format after each discovery.
+	OutputFile string
+	// Socket is the path of a unix socket on which the latest discovered
+	// features are served in standalone mode.
+	Socket string
 
 	Overrides ConfigOverrideArgs
 }
@@ -121,6 +178,7 @@ type nfdWorker struct {
 	certWatch           *utils.FsWatcher
 	clientConn          *grpc.ClientConn
 	configFilePath      string
+	configObject        *configobject.Watcher
 	config              *NFDConfig
 	kubernetesNamespace string
 	grpcClient          pb.LabelerClient
@@ -128,6 +186,23 @@ type nfdWorker struct {
 	stop                chan struct{} // channel for signaling stop
 	featureSources      []source.FeatureSource
 	labelSources        []source.LabelSource
+	// stdout is where the discovered features are written with -output
+	stdout io.Writer
+	// nodeFeatureObjects are the names of the NodeFeature objects written
+	// in the previous update
+	nodeFeatureObjects []string
+	// nodeFeatureCache holds the last written state of the NodeFeature
+	// objects of this node, indexed by object name
+	nodeFeatureCache map[string]*nodeFeatureState
+	// signingKey is the key for signing NodeFeature objects, nil if signing
+	// is disabled
+	signingKey []byte
+	// stats records the time spent in each feature source if profiling has
+	// been enabled
+	stats *utils.RuntimeStats
+	// featureServer serves the discovered features on a unix socket in
+	// standalone mode
+	featureServer *featureServer
 }
 
 // This ticker can represent infinite and normal intervals.
@@ -142,6 +217,7 @@ func NewNfdWorker(args *Args) (NfdWorker, error) {
 		config:              &NFDConfig{},
 		kubernetesNamespace: utils.GetKubernetesNamespace(),
 		stop:                make(chan struct{}, 1),
+		stdout:              os.Stdout,
 	}
 
 	// Check TLS related args
@@ -157,6 +233,28 @@ func NewNfdWorker(args *Args) (NfdWorker, error) {
 		}
 	}
 
+	switch args.Output {
+	case "", "json", "yaml":
+	default:
+		return nfd, fmt.Errorf("invalid -output format %q, must be one of json or yaml", args.Output)
+	}
+
+	if err := validateStandalone(args); err != nil {
+		return nfd, err
+	}
+	if args.Standalone {
+		// Nothing is published in standalone mode
+		noPublish := true
+		nfd.args.Overrides.NoPublish = &noPublish
+	}
+
+	if args.EnableProfiling {
+		if args.MetricsPort <= 0 {
+			return nfd, fmt.Errorf("-enable-profiling requires the metrics server to be enabled (-metrics)")
+		}
+		nfd.stats = utils.NewRuntimeStats()
+	}
+
 	if args.ConfigFile != "" {
 		nfd.configFilePath = filepath.Clean(args.ConfigFile)
 	}
@@ -193,9 +291,10 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 	for _, s := range w.featureSources {
 		currentSourceStart := time.Now()
 		if err := s.Discover(); err != nil {
-			klog.ErrorS(err, "feature discovery failed", "source", s.Name())
+			klog.ErrorS(err, "feature discovery failed", "featureSource", s.Name())
 		}
 		klog.V(3).InfoS("feature discovery completed", "featureSource", s.Name(), "duration", time.Since(currentSourceStart))
+		w.stats.Observe("source", s.Name(), time.Since(currentSourceStart))
 	}
 
 	discoveryDuration := time.Since(discoveryStart)
@@ -204,9 +303,15 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 	if w.config.Core.SleepInterval.Duration > 0 && discoveryDuration > w.config.Core.SleepInterval.Duration/2 {
 		klog.InfoS("feature discovery sources took over half of sleep interval ", "duration", discoveryDuration, "sleepInterval", w.config.Core.SleepInterval.Duration)
 	}
+	w.filterFeatures()
+
 	// Get the set of feature labels.
 	labels := createFeatureLabels(w.labelSources, w.config.Core.LabelWhiteList.Regexp)
 
+	if err := w.writeFeatures(labels); err != nil {
+		return err
+	}
+
 	// Update the node with the feature labels.
 	if !w.config.Core.NoPublish {
 		return w.advertiseFeatures(labels)
@@ -215,6 +320,46 @@ func (w *nfdWorker) runFeatureDiscovery() error {
 	return nil
 }
 
+// writeFeatures writes the discovered features and labels to stdout in the
+// format specified with -output, to the -output-file and to the feature
+// server.
+func (w *nfdWorker) writeFeatures(labels Labels) error {
+	if w.args.Output == "" && w.args.OutputFile == "" && w.featureServer == nil {
+		return nil
+	}
+	spec := &nfdv1alpha1.NodeFeatureSpec{Features: *source.GetAllFeatures(), Labels: labels}
+
+	if w.args.OutputFile != "" {
+		if err := writeFeaturesFile(w.args.OutputFile, spec); err != nil {
+			return err
+		}
+	}
+	if w.featureServer != nil {
+		if err := w.featureServer.update(spec); err != nil {
+			return err
+		}
+	}
+	if w.args.Output == "" {
+		return nil
+	}
+
+	var data []byte
+	var err error
+	switch w.args.Output {
+	case "json":
+		data, err = json.MarshalIndent(spec, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(spec)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal features: %w", err)
+	}
+
+	_, err = w.stdout.Write(data)
+	return err
+}
+
 // Run NfdWorker client. Returns if a fatal error is encountered, or, after
 // one request if OneShot is set to 'true' in the worker args.
 func (w *nfdWorker) Run() error {
@@ -225,6 +370,17 @@ func (w *nfdWorker) Run() error {
 	if err != nil {
 		return err
 	}
+	if w.args.ConfigObject != "" {
+		kubeconfig, err := utils.GetKubeconfig(w.args.Kubeconfig)
+		if err != nil {
+			return err
+		}
+		w.configObject, err = configobject.NewWatcher(kubeconfig, w.args.ConfigObject, configobject.Worker)
+		if err != nil {
+			return fmt.Errorf("failed to watch NFDConfig %q: %w", w.args.ConfigObject, err)
+		}
+		defer w.configObject.Stop()
+	}
 	if err := w.configure(w.configFilePath, w.args.Options); err != nil {
 		return err
 	}
@@ -237,6 +393,14 @@ func (w *nfdWorker) Run() error {
 
 	defer w.grpcDisconnect()
 
+	if w.args.Socket != "" {
+		w.featureServer, err = newFeatureServer(w.args.Socket)
+		if err != nil {
+			return err
+		}
+		defer w.featureServer.stop()
+	}
+
 	// Create ticker for feature discovery and run feature discovery once before the loop.
 	labelTrigger := infiniteTicker{Ticker: time.NewTicker(1)}
 	labelTrigger.Reset(w.config.Core.SleepInterval.Duration)
@@ -246,7 +410,18 @@ func (w *nfdWorker) Run() error {
 	if w.args.MetricsPort > 0 {
 		m := utils.CreateMetricsServer(w.args.MetricsPort,
 			buildInfo,
-			featureDiscoveryDuration)
+			featureDiscoveryDuration,
+			sourceInputAvailable,
+			nodeFeatureUpdates,
+			features.FeatureEnabled)
+		if w.args.EnableProfiling {
+			if err := m.EnableProfiling(w.args.MetricsSecurity, w.stats); err != nil {
+				return err
+			}
+		}
+		if err := m.Secure(w.args.MetricsSecurity, w.args.Kubeconfig); err != nil {
+			return fmt.Errorf("failed to configure metrics server: %w", err)
+		}
 		go m.Run()
 		registerVersion(version.Get())
 		defer m.Stop()
@@ -262,6 +437,18 @@ func (w *nfdWorker) Run() error {
 		return nil
 	}
 
+	reconfigured := func() error {
+		// Manage connection to master
+		if w.config.Core.NoPublish || !w.args.EnableNodeFeatureApi {
+			w.grpcDisconnect()
+		}
+
+		// Always re-label after a re-config event. This way the new config
+		// comes into effect even if the sleep interval is long (or infinite)
+		labelTrigger.Reset(w.config.Core.SleepInterval.Duration)
+		return w.runFeatureDiscovery()
+	}
+
 	for {
 		select {
 		case <-labelTrigger.C:
@@ -275,16 +462,19 @@ func (w *nfdWorker) Run() error {
 			if err := w.configure(w.configFilePath, w.args.Options); err != nil {
 				return err
 			}
-			// Manage connection to master
-			if w.config.Core.NoPublish || !w.args.EnableNodeFeatureApi {
-				w.grpcDisconnect()
+			if err := reconfigured(); err != nil {
+				return err
 			}
 
-			// Always re-label after a re-config event. This way the new config
-			// comes into effect even if the sleep interval is long (or infinite)
-			labelTrigger.Reset(w.config.Core.SleepInterval.Duration)
-			err = w.runFeatureDiscovery()
-			if err != nil {
+		case <-w.configObject.Changes():
+			klog.InfoS("reloading configuration", "nfdconfig", w.args.ConfigObject)
+			// Keep running with the previous configuration if the new one
+			// is invalid. The error is reported in the object status.
+			if err := w.configure(w.configFilePath, w.args.Options); err != nil {
+				klog.ErrorS(err, "failed to reload configuration", "nfdconfig", w.args.ConfigObject)
+				continue
+			}
+			if err := reconfigured(); err != nil {
 				return err
 			}
 
@@ -383,6 +573,12 @@ func (w *nfdWorker) configureCore(c coreConfig) error {
 	if err != nil {
 		return err
 	}
+	source.SetLogVerbosity(c.LogVerbosity)
+
+	// Handle feature gates
+	if err := features.Apply(c.FeatureGates); err != nil {
+		return err
+	}
 
 	// Determine enabled feature sources
 	featureSources := make(map[string]source.FeatureSource)
@@ -471,8 +667,50 @@ func (w *nfdWorker) configureCore(c coreConfig) error {
 	return nil
 }
 
+// probeSources detects the available inputs of the enabled feature sources
+// and disables sources that have nothing to read.
+func (w *nfdWorker) probeSources() {
+	featureSources := make([]source.FeatureSource, 0, len(w.featureSources))
+	for _, s := range w.featureSources {
+		if ps, ok := s.(source.ProbingSource); ok && !w.probeSource(ps) {
+			klog.InfoS("disabling feature source, it requires elevated privileges", "featureSource", s.Name())
+			continue
+		}
+		featureSources = append(featureSources, s)
+	}
+	w.featureSources = featureSources
+}
+
+// probeSource probes the inputs of a source and reports their status. Returns
+// false if the source should be disabled.
+func (w *nfdWorker) probeSource(s source.ProbingSource) bool {
+	inputs := s.Probe(w.args.MinimalPrivileges)
+	for _, i := range inputs {
+		if i.Err != nil {
+			klog.InfoS("feature source input not available", "featureSource", s.Name(), "input", i.Name, "path", i.Path, "privileged", i.Privileged, "reason", i.Err)
+			sourceInputAvailable.WithLabelValues(s.Name(), i.Name).Set(0)
+		} else {
+			klog.V(2).InfoS("feature source input available", "featureSource", s.Name(), "input", i.Name, "path", i.Path)
+			sourceInputAvailable.WithLabelValues(s.Name(), i.Name).Set(1)
+		}
+	}
+	return !inputs.AllPrivilegedUnavailable()
+}
+
+// filterFeatures applies the configured source feature lists and feature
+// filters to the discovered features. The features of the sources are
+// replaced so that labels and custom rules only see the filtered features.
+func (w *nfdWorker) filterFeatures() {
+	if len(w.config.sourceFeatureLists) == 0 && len(w.config.Core.FeatureFilters) == 0 {
+		return
+	}
+	for _, s := range w.featureSources {
+		filterSourceFeatures(s.Name(), s.GetFeatures(), w.config.sourceFeatureLists, w.config.Core.FeatureFilters)
+	}
+}
+
 // Parse configuration options
-func (w *nfdWorker) configure(filepath string, overrides string) error {
+func (w *nfdWorker) configure(filepath string, overrides string) (err error) {
 	// Create a new default config
 	c := newDefaultConfig()
 	confSources := source.GetAllConfigurableSources()
@@ -480,6 +718,7 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 	for _, s := range confSources {
 		c.Sources[s.Name()] = s.NewConfig()
 	}
+	c.sourceFeatureLists = make(map[string]sourceFeatureLists)
 
 	// Try to read and parse config file
 	if filepath != "" {
@@ -495,6 +734,9 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 			if err != nil {
 				return fmt.Errorf("failed to parse config file: %s", err)
 			}
+			if err := parseSourceFeatureLists(data, c.sourceFeatureLists); err != nil {
+				return fmt.Errorf("failed to parse config file: %s", err)
+			}
 
 			if c.Core.Sources != nil {
 				klog.InfoS("usage of deprecated 'core.sources' config file option, please use 'core.labelSources' instead")
@@ -505,10 +747,30 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 		}
 	}
 
+	// Parse the configuration of the NFDConfig object
+	objData, objGeneration, err := w.configObject.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read NFDConfig object: %w", err)
+	}
+	if objData != nil {
+		defer func() { w.configObject.SetStatus(objGeneration, err) }()
+
+		if err := yaml.Unmarshal(objData, c); err != nil {
+			return fmt.Errorf("failed to parse NFDConfig %q: %w", w.args.ConfigObject, err)
+		}
+		if err := parseSourceFeatureLists(objData, c.sourceFeatureLists); err != nil {
+			return fmt.Errorf("failed to parse NFDConfig %q: %w", w.args.ConfigObject, err)
+		}
+		klog.InfoS("NFDConfig object parsed", "nfdconfig", w.args.ConfigObject, "generation", objGeneration)
+	}
+
 	// Parse config overrides
 	if err := yaml.Unmarshal([]byte(overrides), c); err != nil {
 		return fmt.Errorf("failed to parse -options: %s", err)
 	}
+	if err := parseSourceFeatureLists([]byte(overrides), c.sourceFeatureLists); err != nil {
+		return fmt.Errorf("failed to parse -options: %s", err)
+	}
 
 	if w.args.Overrides.NoPublish != nil {
 		c.Core.NoPublish = *w.args.Overrides.NoPublish
@@ -522,8 +784,49 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 
 	c.Core.sanitize()
 
+	var salt []byte
+	if c.Core.FeatureHashSaltFile != "" {
+		data, err := os.ReadFile(c.Core.FeatureHashSaltFile)
+		if err != nil {
+			return fmt.Errorf("failed to read feature hash salt: %w", err)
+		}
+		salt = bytes.TrimSpace(data)
+	}
+	source.SetFeatureHashSalt(salt)
+	for i := range c.Core.FeatureFilters {
+		c.Core.FeatureFilters[i].salt = salt
+		if err := c.Core.FeatureFilters[i].validate(); err != nil {
+			return fmt.Errorf("invalid core.featureFilters[%d]: %w", i, err)
+		}
+	}
+	for name, l := range c.sourceFeatureLists {
+		if err := l.validate(); err != nil {
+			return fmt.Errorf("invalid feature lists of source %q: %w", name, err)
+		}
+	}
+	if err := validateNodeFeatureGroups(c.Core.NodeFeatureGroups); err != nil {
+		return fmt.Errorf("invalid core.nodeFeatureGroups: %w", err)
+	}
+	if err := c.Core.Egress.Validate(); err != nil {
+		return fmt.Errorf("invalid core.egress: %w", err)
+	}
+	var signingKey []byte
+	if c.Core.NodeFeatureSigningKeyFile != "" {
+		keys, err := utils.ReadSigningKeys(c.Core.NodeFeatureSigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("invalid core.nodeFeatureSigningKeyFile: %w", err)
+		}
+		signingKey = keys[0]
+	}
+
 	w.config = c
+	if !bytes.Equal(w.signingKey, signingKey) {
+		// Re-sign all objects on the next update
+		w.nodeFeatureCache = nil
+		w.signingKey = signingKey
+	}
 
+	source.SetEgressPolicy(c.Core.Egress)
 	if err := w.configureCore(c.Core); err != nil {
 		return err
 	}
@@ -533,6 +836,8 @@ func (w *nfdWorker) configure(filepath string, overrides string) error {
 		s.SetConfig(c.Sources[s.Name()])
 	}
 
+	w.probeSources()
+
 	klog.InfoS("configuration successfully updated", "configuration", w.config)
 	return nil
 }
@@ -547,7 +852,7 @@ func createFeatureLabels(sources []source.LabelSource, labelWhiteList regexp.Reg
 	for _, source := range sources {
 		labelsFromSource, err := getFeatureLabels(source, labelWhiteList)
 		if err != nil {
-			klog.ErrorS(err, "discovery failed", "source", source.Name())
+			klog.ErrorS(err, "discovery failed", "featureSource", source.Name())
 			continue
 		}
 
@@ -688,22 +993,96 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 		klog.InfoS("Cannot set NodeFeature owner reference, POD_NAME and/or POD_UID not specified")
 	}
 
-	// TODO: we could implement some simple caching of the object, only get it
-	// every 10 minutes or so because nobody else should really be modifying it
-	if nfr, err := cli.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), nodename, metav1.GetOptions{}); errors.IsNotFound(err) {
-		klog.InfoS("creating NodeFeature object", "nodefeature", klog.KObj(nfr))
+	var names []string
+	for _, g := range splitNodeFeatureGroups(&nfdv1alpha1.NodeFeatureSpec{Features: *features, Labels: labels}, m.config.Core.NodeFeatureGroups) {
+		specs, err := splitNodeFeatureSpec(&g.spec, m.config.Core.MaxNodeFeatureObjectSize)
+		if err != nil {
+			return fmt.Errorf("failed to split NodeFeature object: %w", err)
+		}
+		if len(specs) > 1 {
+			klog.V(1).InfoS("splitting node features into multiple NodeFeature objects", "nodeName", nodename, "group", g.name, "numObjects", len(specs))
+		}
+		for i := range specs {
+			name := nodeFeatureChunkName(nodeFeatureGroupName(nodename, g.name), i)
+			if err := m.updateNodeFeatureChunk(cli, namespace, name, g.name, ownerRefs, &specs[i]); err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+	}
+
+	// Clean up leftover chunks and groups if the set of objects changed
+	if !slices.Equal(names, m.nodeFeatureObjects) {
+		if err := m.deleteStaleNodeFeatureObjects(cli, namespace, names); err != nil {
+			return err
+		}
+		m.nodeFeatureObjects = names
+	}
+	return nil
+}
+
+// updateNodeFeatureChunk creates or updates one NodeFeature object of this
+// node. The last written state of the object is cached: the API is not
+// called at all if nothing has changed and only the changed sources are
+// patched otherwise.
+func (m *nfdWorker) updateNodeFeatureChunk(cli *nfdclient.Clientset, namespace, name, group string, ownerRefs []metav1.OwnerReference, spec *nfdv1alpha1.NodeFeatureSpec) error {
+	nodename := utils.NodeName()
+	meta := metav1.ObjectMeta{
+		Name:            name,
+		Annotations:     map[string]string{nfdv1alpha1.WorkerVersionAnnotation: version.Get()},
+		Labels:          map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename},
+		OwnerReferences: ownerRefs,
+	}
+	if group != "" {
+		meta.Labels[nfdv1alpha1.NodeFeatureObjGroupLabel] = group
+	}
+	metaHash, err := contentHash(&meta)
+	if err != nil {
+		return err
+	}
+	specHash, err := contentHash(spec)
+	if err != nil {
+		return err
+	}
+
+	// The signature changes along with the spec, it is not part of the
+	// cached meta hash
+	var signature string
+	if m.signingKey != nil {
+		if signature, err = utils.SignNodeFeature(m.signingKey, nodename, spec); err != nil {
+			return fmt.Errorf("failed to sign NodeFeature object %q: %w", name, err)
+		}
+		meta.Annotations[nfdv1alpha1.NodeFeatureSignatureAnnotation] = signature
+	}
+
+	if state, ok := m.nodeFeatureCache[name]; ok && state.metaHash == metaHash && time.Since(state.synced) < nodeFeatureCacheMaxAge {
+		if state.specHash == specHash {
+			klog.V(1).InfoS("no changes in NodeFeature object, not updating", "nodefeature", klog.KRef(namespace, name))
+			nodeFeatureUpdates.WithLabelValues("skipped").Inc()
+			return nil
+		}
+		nfrPatched, err := m.patchNodeFeature(cli, namespace, name, state, spec, signature)
+		if err == nil {
+			klog.V(4).InfoS("NodeFeature object patched", "nodeFeature", utils.DelayedDumper(nfrPatched))
+			nodeFeatureUpdates.WithLabelValues("patched").Inc()
+			m.cacheNodeFeature(nfrPatched, metaHash, specHash)
+			return nil
+		}
+		klog.InfoS("failed to patch NodeFeature object, falling back to full update", "nodefeature", klog.KRef(namespace, name), "error", err)
+	}
+	delete(m.nodeFeatureCache, name)
+
+	// Refresh the heartbeat on every full sync (at least every
+	// nodeFeatureCacheMaxAge), letting nfd-master know that the features are
+	// up-to-date. Not part of the cached meta hash.
+	meta.Annotations[nfdv1alpha1.NodeFeatureHeartbeatAnnotation] = time.Now().UTC().Format(time.RFC3339)
+
+	if nfr, err := cli.NfdV1alpha1().NodeFeatures(namespace).Get(context.TODO(), name, metav1.GetOptions{}); errors.IsNotFound(err) {
 		nfr = &nfdv1alpha1.NodeFeature{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:            nodename,
-				Annotations:     map[string]string{nfdv1alpha1.WorkerVersionAnnotation: version.Get()},
-				Labels:          map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename},
-				OwnerReferences: ownerRefs,
-			},
-			Spec: nfdv1alpha1.NodeFeatureSpec{
-				Features: *features,
-				Labels:   labels,
-			},
+			ObjectMeta: meta,
+			Spec:       *spec,
 		}
+		klog.InfoS("creating NodeFeature object", "nodefeature", klog.KObj(nfr))
 
 		nfrCreated, err := cli.NfdV1alpha1().NodeFeatures(namespace).Create(context.TODO(), nfr, metav1.CreateOptions{})
 		if err != nil {
@@ -711,17 +1090,24 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 		}
 
 		klog.V(4).InfoS("NodeFeature object created", "nodeFeature", utils.DelayedDumper(nfrCreated))
+		nodeFeatureUpdates.WithLabelValues("created").Inc()
+		m.cacheNodeFeature(nfrCreated, metaHash, specHash)
 	} else if err != nil {
 		return fmt.Errorf("failed to get NodeFeature object: %w", err)
 	} else {
+		logNodeFeatureStatus(nfr)
+
 		nfrUpdated := nfr.DeepCopy()
-		nfrUpdated.Annotations = map[string]string{nfdv1alpha1.WorkerVersionAnnotation: version.Get()}
-		nfrUpdated.Labels = map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodename}
-		nfrUpdated.OwnerReferences = ownerRefs
-		nfrUpdated.Spec = nfdv1alpha1.NodeFeatureSpec{
-			Features: *features,
-			Labels:   labels,
+		nfrUpdated.Annotations = meta.Annotations
+		nfrUpdated.Labels = meta.Labels
+		// Keep the Node owner reference set by nfd-master
+		nfrUpdated.OwnerReferences = append([]metav1.OwnerReference{}, meta.OwnerReferences...)
+		for _, ref := range nfr.OwnerReferences {
+			if ref.APIVersion == "v1" && ref.Kind == "Node" {
+				nfrUpdated.OwnerReferences = append(nfrUpdated.OwnerReferences, ref)
+			}
 		}
+		nfrUpdated.Spec = *spec
 
 		if !apiequality.Semantic.DeepEqual(nfr, nfrUpdated) {
 			klog.InfoS("updating NodeFeature object", "nodefeature", klog.KObj(nfr))
@@ -730,8 +1116,86 @@ func (m *nfdWorker) updateNodeFeatureObject(labels Labels) error {
 				return fmt.Errorf("failed to update NodeFeature object %q: %w", nfr.Name, err)
 			}
 			klog.V(4).InfoS("NodeFeature object updated", "nodeFeature", utils.DelayedDumper(nfrUpdated))
+			nodeFeatureUpdates.WithLabelValues("updated").Inc()
 		} else {
 			klog.V(1).InfoS("no changes in NodeFeature object, not updating", "nodefeature", klog.KObj(nfr))
+			nodeFeatureUpdates.WithLabelValues("skipped").Inc()
+		}
+		m.cacheNodeFeature(nfrUpdated, metaHash, specHash)
+	}
+	return nil
+}
+
+// patchNodeFeature patches the changed sources of a NodeFeature object. The
+// patch fails if the object was modified after it was cached. A non-empty
+// signature replaces the signature annotation of the object.
+func (m *nfdWorker) patchNodeFeature(cli *nfdclient.Clientset, namespace, name string, state *nodeFeatureState, spec *nfdv1alpha1.NodeFeatureSpec, signature string) (*nfdv1alpha1.NodeFeature, error) {
+	specPatches, err := nodeFeatureSpecPatches(&state.spec, spec)
+	if err != nil {
+		return nil, err
+	}
+	patches := append([]nodeFeaturePatch{{Op: "test", Path: "/metadata/resourceVersion", Value: state.resourceVersion}}, specPatches...)
+	if signature != "" {
+		patches = append(patches, nodeFeaturePatch{Op: "add", Path: "/metadata/annotations/" + escapeJsonPointer(nfdv1alpha1.NodeFeatureSignatureAnnotation), Value: signature})
+	}
+	data, err := json.Marshal(patches)
+	if err != nil {
+		return nil, err
+	}
+	klog.InfoS("patching NodeFeature object", "nodefeature", klog.KRef(namespace, name), "numPatches", len(patches)-1)
+	return cli.NfdV1alpha1().NodeFeatures(namespace).Patch(context.TODO(), name, types.JSONPatchType, data, metav1.PatchOptions{})
+}
+
+// logNodeFeatureStatus logs if nfd-master has not (successfully) processed
+// the current generation of a NodeFeature object.
+func logNodeFeatureStatus(nfr *nfdv1alpha1.NodeFeature) {
+	cond := meta.FindStatusCondition(nfr.Status.Conditions, nfdv1alpha1.NodeFeatureProcessedCondition)
+	switch {
+	case cond == nil || nfr.Status.ObservedGeneration < nfr.Generation:
+		klog.V(1).InfoS("NodeFeature object not yet processed by nfd-master", "nodefeature", klog.KObj(nfr), "generation", nfr.Generation, "observedGeneration", nfr.Status.ObservedGeneration)
+	case cond.Status != metav1.ConditionTrue:
+		klog.InfoS("nfd-master failed to process NodeFeature object", "nodefeature", klog.KObj(nfr), "reason", cond.Reason, "message", cond.Message)
+	}
+}
+
+// cacheNodeFeature stores the state of a NodeFeature object that was written
+// to (or read from) the API.
+func (m *nfdWorker) cacheNodeFeature(nfr *nfdv1alpha1.NodeFeature, metaHash, specHash string) {
+	if m.nodeFeatureCache == nil {
+		m.nodeFeatureCache = map[string]*nodeFeatureState{}
+	}
+	m.nodeFeatureCache[nfr.Name] = &nodeFeatureState{
+		metaHash:        metaHash,
+		specHash:        specHash,
+		spec:            *nfr.Spec.DeepCopy(),
+		resourceVersion: nfr.ResourceVersion,
+		synced:          time.Now(),
+	}
+}
+
+// deleteStaleNodeFeatureObjects deletes the NodeFeature objects of this node
+// that were written by nfd-worker but are not in the given list of current
+// objects, i.e. obsolete chunks and the objects of removed feature groups.
+func (m *nfdWorker) deleteStaleNodeFeatureObjects(cli *nfdclient.Clientset, namespace string, current []string) error {
+	nodename := utils.NodeName()
+	sel := nfdv1alpha1.NodeFeatureObjNodeNameLabel + "=" + nodename
+	nfs, err := cli.NfdV1alpha1().NodeFeatures(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: sel})
+	if err != nil {
+		return fmt.Errorf("failed to list NodeFeature objects: %w", err)
+	}
+	for _, nf := range nfs.Items {
+		if slices.Contains(current, nf.Name) {
+			continue
+		}
+		_, isChunk := nodeFeatureChunkIndex(nodename, nf.Name)
+		_, isGroup := nf.Labels[nfdv1alpha1.NodeFeatureObjGroupLabel]
+		if isChunk || isGroup {
+			klog.InfoS("deleting stale NodeFeature object", "nodefeature", klog.KObj(&nf))
+			err := cli.NfdV1alpha1().NodeFeatures(namespace).Delete(context.TODO(), nf.Name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete NodeFeature object %q: %w", nf.Name, err)
+			}
+			delete(m.nodeFeatureCache, nf.Name)
 		}
 	}
 	return nil
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// nodeFeatureChunkSuffix separates the node name and the chunk index in the
+// names of additional NodeFeature objects.
+const nodeFeatureChunkSuffix = "-chunk-"
+
+// nodeFeatureChunkName returns the name of the NodeFeature object holding the
+// chunk with the given index. The first chunk uses the plain node name.
+func nodeFeatureChunkName(nodeName string, index int) string {
+	if index == 0 {
+		return nodeName
+	}
+	return nodeName + nodeFeatureChunkSuffix + strconv.Itoa(index)
+}
+
+// nodeFeatureChunkIndex parses the chunk index from the name of a NodeFeature
+// object. Returns false if the name is not a chunk of the given node.
+func nodeFeatureChunkIndex(nodeName, name string) (int, bool) {
+	if name == nodeName {
+		return 0, true
+	}
+	s, ok := strings.CutPrefix(name, nodeName+nodeFeatureChunkSuffix)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 1 {
+		return 0, false
+	}
+	return i, true
+}
+
+// splitNodeFeatureSpec splits a NodeFeatureSpec into chunks whose (JSON
+// encoded) size stays below maxSize. Labels, flags and attributes are kept in
+// the first chunk and instances are spread over as many chunks as needed.
+// nfd-master merges the instances of all NodeFeature objects of a node so the
+// split is transparent. A non-positive maxSize disables splitting.
+func splitNodeFeatureSpec(spec *nfdv1alpha1.NodeFeatureSpec, maxSize int) ([]nfdv1alpha1.NodeFeatureSpec, error) {
+	if maxSize <= 0 {
+		return []nfdv1alpha1.NodeFeatureSpec{*spec}, nil
+	}
+	size, err := jsonSize(spec)
+	if err != nil {
+		return nil, err
+	} else if size <= maxSize {
+		return []nfdv1alpha1.NodeFeatureSpec{*spec}, nil
+	}
+
+	first := nfdv1alpha1.NodeFeatureSpec{
+		Features: nfdv1alpha1.Features{
+			Flags:      spec.Features.Flags,
+			Attributes: spec.Features.Attributes,
+			Instances:  map[string]nfdv1alpha1.InstanceFeatureSet{},
+		},
+		Labels: spec.Labels,
+	}
+	chunks := []nfdv1alpha1.NodeFeatureSpec{first}
+	size, err = jsonSize(&first)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSize {
+		klog.InfoS("NodeFeature flags, attributes and labels alone exceed the maximum object size", "size", size, "maxSize", maxSize)
+	}
+
+	// Iterate in a stable order to avoid needless object updates
+	names := make([]string, 0, len(spec.Features.Instances))
+	for name := range spec.Features.Instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	numElems := 0
+	for _, name := range names {
+		for _, e := range spec.Features.Instances[name].Elements {
+			// Rough estimate of the encoded size: the element itself plus
+			// the feature name in case a new key is needed
+			elemSize, err := jsonSize(&e)
+			if err != nil {
+				return nil, err
+			}
+			elemSize += len(name) + 16
+			// Start a new chunk if this one is full. Never leave an
+			// additional chunk empty, though.
+			if size+elemSize > maxSize && (numElems > 0 || len(chunks) == 1) {
+				chunks = append(chunks, nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures()})
+				if size, err = jsonSize(&chunks[len(chunks)-1]); err != nil {
+					return nil, err
+				}
+				numElems = 0
+			}
+			cur := &chunks[len(chunks)-1]
+			set := cur.Features.Instances[name]
+			set.Elements = append(set.Elements, e)
+			set.Types = spec.Features.Instances[name].Types
+			cur.Features.Instances[name] = set
+			size += elemSize
+			numElems++
+		}
+	}
+	return chunks, nil
+}
+
+// jsonSize returns the size of the JSON encoding of an object.
+func jsonSize(v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newChunkTestSpec(numInstances int) *nfdv1alpha1.NodeFeatureSpec {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX", "AVX2")
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6"})
+	for _, name := range []string{"pci.device", "usb.device"} {
+		instances := make([]nfdv1alpha1.InstanceFeature, numInstances)
+		for i := range instances {
+			instances[i] = *nfdv1alpha1.NewInstanceFeature(map[string]string{"address": fmt.Sprintf("0000:%02x:00.0", i), "vendor": "8086"})
+		}
+		f.Instances[name] = nfdv1alpha1.NewInstanceFeatures(instances)
+	}
+	return &nfdv1alpha1.NodeFeatureSpec{Features: *f, Labels: map[string]string{"foo": "bar"}}
+}
+
+func TestSplitNodeFeatureSpec(t *testing.T) {
+	Convey("When splitting NodeFeatureSpecs", t, func() {
+		spec := newChunkTestSpec(100)
+
+		Convey("splitting should be disabled with zero max size", func() {
+			chunks, err := splitNodeFeatureSpec(spec, 0)
+			So(err, ShouldBeNil)
+			So(chunks, ShouldHaveLength, 1)
+			So(chunks[0], ShouldResemble, *spec)
+		})
+
+		Convey("small specs should not be split", func() {
+			size, err := jsonSize(spec)
+			So(err, ShouldBeNil)
+			chunks, err := splitNodeFeatureSpec(spec, size)
+			So(err, ShouldBeNil)
+			So(chunks, ShouldHaveLength, 1)
+		})
+
+		Convey("large specs should be split into chunks below the max size", func() {
+			maxSize := 2048
+			chunks, err := splitNodeFeatureSpec(spec, maxSize)
+			So(err, ShouldBeNil)
+			So(len(chunks), ShouldBeGreaterThan, 1)
+			for i := range chunks {
+				size, err := jsonSize(&chunks[i])
+				So(err, ShouldBeNil)
+				So(size, ShouldBeLessThanOrEqualTo, maxSize)
+			}
+			So(chunks[0].Labels, ShouldResemble, spec.Labels)
+			So(chunks[0].Features.Flags, ShouldResemble, spec.Features.Flags)
+			So(chunks[0].Features.Attributes, ShouldResemble, spec.Features.Attributes)
+
+			Convey("merging the chunks should give the original spec", func() {
+				merged := nfdv1alpha1.NodeFeatureSpec{}
+				for i := range chunks {
+					chunks[i].MergeInto(&merged)
+				}
+				So(merged, ShouldResemble, *spec)
+			})
+
+			Convey("splitting should be stable", func() {
+				again, err := splitNodeFeatureSpec(spec, maxSize)
+				So(err, ShouldBeNil)
+				So(again, ShouldResemble, chunks)
+			})
+		})
+
+		Convey("elements exceeding the max size should still be included", func() {
+			chunks, err := splitNodeFeatureSpec(spec, 1)
+			So(err, ShouldBeNil)
+			numElems := 0
+			for i := range chunks[1:] {
+				for _, set := range chunks[i+1].Features.Instances {
+					So(set.Elements, ShouldHaveLength, 1)
+					numElems++
+				}
+			}
+			So(numElems, ShouldEqual, 200)
+		})
+	})
+}
+
+func TestNodeFeatureChunkName(t *testing.T) {
+	Convey("When handling NodeFeature chunk names", t, func() {
+		So(nodeFeatureChunkName("node-1", 0), ShouldEqual, "node-1")
+		So(nodeFeatureChunkName("node-1", 3), ShouldEqual, "node-1-chunk-3")
+
+		for _, i := range []int{0, 1, 12} {
+			idx, ok := nodeFeatureChunkIndex("node-1", nodeFeatureChunkName("node-1", i))
+			So(ok, ShouldBeTrue)
+			So(idx, ShouldEqual, i)
+		}
+
+		for _, name := range []string{"node-2", "node-1-chunk-", "node-1-chunk-0", "node-1-chunk-x", "node-1-foo"} {
+			_, ok := nodeFeatureChunkIndex("node-1", name)
+			So(ok, ShouldBeFalse)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// nodeFeatureCacheMaxAge is the time after which the cached state of a
+// NodeFeature object is discarded and the object is read back from the API,
+// e.g. to re-create it if it was deleted.
+const nodeFeatureCacheMaxAge = 10 * time.Minute
+
+// nodeFeatureState is the last known state of a NodeFeature object written
+// by the worker.
+type nodeFeatureState struct {
+	metaHash        string
+	specHash        string
+	spec            nfdv1alpha1.NodeFeatureSpec
+	resourceVersion string
+	synced          time.Time
+}
+
+// nodeFeaturePatch is one JSON patch operation on a NodeFeature object.
+type nodeFeaturePatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// contentHash returns a hash of the JSON encoding of an object. Map keys are
+// sorted by the encoder so the hash is stable.
+func contentHash(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// featureSourceName returns the name of the source of a feature, e.g. "cpu"
+// for "cpu.cpuid".
+func featureSourceName(featureName string) string {
+	src, _, _ := strings.Cut(featureName, ".")
+	return src
+}
+
+// sourceHashes returns a content hash of the features of each source.
+func sourceHashes(features *nfdv1alpha1.Features) (map[string]string, error) {
+	perSource := map[string]*nfdv1alpha1.Features{}
+	get := func(name string) *nfdv1alpha1.Features {
+		src := featureSourceName(name)
+		if _, ok := perSource[src]; !ok {
+			perSource[src] = nfdv1alpha1.NewFeatures()
+		}
+		return perSource[src]
+	}
+	for k, v := range features.Flags {
+		get(k).Flags[k] = v
+	}
+	for k, v := range features.Attributes {
+		get(k).Attributes[k] = v
+	}
+	for k, v := range features.Instances {
+		get(k).Instances[k] = v
+	}
+
+	hashes := make(map[string]string, len(perSource))
+	for src, f := range perSource {
+		h, err := contentHash(f)
+		if err != nil {
+			return nil, err
+		}
+		hashes[src] = h
+	}
+	return hashes, nil
+}
+
+// nodeFeatureSpecPatches returns the JSON patch operations needed to turn the
+// old spec into the new one. Only features of sources whose content hash
+// changed are touched.
+func nodeFeatureSpecPatches(old, new *nfdv1alpha1.NodeFeatureSpec) ([]nodeFeaturePatch, error) {
+	oldHashes, err := sourceHashes(&old.Features)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, err := sourceHashes(&new.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for src, h := range newHashes {
+		if oldHashes[src] != h {
+			changed[src] = true
+		}
+	}
+	for src := range oldHashes {
+		if _, ok := newHashes[src]; !ok {
+			changed[src] = true
+		}
+	}
+
+	patches := []nodeFeaturePatch{}
+	patches = append(patches, featureMapPatches("/spec/features/flags", old.Features.Flags, new.Features.Flags, changed)...)
+	patches = append(patches, featureMapPatches("/spec/features/attributes", old.Features.Attributes, new.Features.Attributes, changed)...)
+	patches = append(patches, featureMapPatches("/spec/features/instances", old.Features.Instances, new.Features.Instances, changed)...)
+
+	if !maps.Equal(old.Labels, new.Labels) {
+		labels := new.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		patches = append(patches, nodeFeaturePatch{Op: "add", Path: "/spec/labels", Value: labels})
+	}
+	return patches, nil
+}
+
+// featureMapPatches returns the patch operations for one of the feature maps
+// (flags, attributes or instances) of a NodeFeature object.
+func featureMapPatches[T any](path string, old, new map[string]T, changed map[string]bool) []nodeFeaturePatch {
+	patches := []nodeFeaturePatch{}
+	for _, k := range sortedKeys(new) {
+		if changed[featureSourceName(k)] {
+			// "add" replaces an existing member
+			patches = append(patches, nodeFeaturePatch{Op: "add", Path: path + "/" + escapeJsonPointer(k), Value: new[k]})
+		}
+	}
+	for _, k := range sortedKeys(old) {
+		if _, ok := new[k]; !ok && changed[featureSourceName(k)] {
+			patches = append(patches, nodeFeaturePatch{Op: "remove", Path: path + "/" + escapeJsonPointer(k)})
+		}
+	}
+	return patches
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeJsonPointer escapes a reference token of a JSON pointer (RFC 6901).
+func escapeJsonPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
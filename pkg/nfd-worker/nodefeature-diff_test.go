@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newDiffTestSpec() *nfdv1alpha1.NodeFeatureSpec {
+	f := nfdv1alpha1.NewFeatures()
+	f.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX", "AVX2")
+	f.Attributes["cpu.topology"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"hardware_multithreading": "true"})
+	f.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6"})
+	f.Instances["pci.device"] = nfdv1alpha1.NewInstanceFeatures([]nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"vendor": "8086"}),
+	})
+	return &nfdv1alpha1.NodeFeatureSpec{Features: *f, Labels: map[string]string{"foo": "bar"}}
+}
+
+func TestNodeFeatureSpecPatches(t *testing.T) {
+	Convey("When computing NodeFeature patches", t, func() {
+		old := newDiffTestSpec()
+		new := newDiffTestSpec()
+
+		patches := func() []nodeFeaturePatch {
+			p, err := nodeFeatureSpecPatches(old, new)
+			So(err, ShouldBeNil)
+			return p
+		}
+		hash := func(spec *nfdv1alpha1.NodeFeatureSpec) string {
+			h, err := contentHash(spec)
+			So(err, ShouldBeNil)
+			return h
+		}
+
+		Convey("unchanged specs should give no patches", func() {
+			So(patches(), ShouldBeEmpty)
+			So(hash(new), ShouldEqual, hash(old))
+		})
+
+		Convey("all features of a changed source should be patched", func() {
+			new.Features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures("AVX")
+			So(hash(new), ShouldNotEqual, hash(old))
+			So(patches(), ShouldResemble, []nodeFeaturePatch{
+				{Op: "add", Path: "/spec/features/flags/cpu.cpuid", Value: new.Features.Flags["cpu.cpuid"]},
+				{Op: "add", Path: "/spec/features/attributes/cpu.topology", Value: new.Features.Attributes["cpu.topology"]},
+			})
+		})
+
+		Convey("removed features should be removed", func() {
+			delete(new.Features.Instances, "pci.device")
+			new.Features.Attributes["custom.rule"] = nfdv1alpha1.NewAttributeFeatures(nil)
+			So(patches(), ShouldResemble, []nodeFeaturePatch{
+				{Op: "add", Path: "/spec/features/attributes/custom.rule", Value: new.Features.Attributes["custom.rule"]},
+				{Op: "remove", Path: "/spec/features/instances/pci.device"},
+			})
+		})
+
+		Convey("unmarshalable values should give an error", func() {
+			_, err := contentHash(func() {})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("changed labels should be replaced", func() {
+			new.Labels = nil
+			So(patches(), ShouldResemble, []nodeFeaturePatch{
+				{Op: "add", Path: "/spec/labels", Value: map[string]string{}},
+			})
+		})
+	})
+
+	Convey("When escaping JSON pointers", t, func() {
+		So(escapeJsonPointer("local.label"), ShouldEqual, "local.label")
+		So(escapeJsonPointer("a/b~c"), ShouldEqual, "a~1b~0c")
+	})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// nodeFeatureGroupSuffix separates the node name and the group name in the
+// names of the NodeFeature objects holding a feature group.
+const nodeFeatureGroupSuffix = "-group-"
+
+// nodeFeatureGroup is the part of the node features published in one
+// (possibly chunked) NodeFeature object. The default group has an empty name.
+type nodeFeatureGroup struct {
+	name string
+	spec nfdv1alpha1.NodeFeatureSpec
+}
+
+// nodeFeatureGroupName returns the name of the NodeFeature object holding
+// the given feature group. The default group uses the plain node name.
+func nodeFeatureGroupName(nodeName, group string) string {
+	if group == "" {
+		return nodeName
+	}
+	return nodeName + nodeFeatureGroupSuffix + group
+}
+
+// validateNodeFeatureGroups validates the core.nodeFeatureGroups config
+// option. Group names are used in object names and each feature source may
+// only be assigned to one group.
+func validateNodeFeatureGroups(groups map[string][]string) error {
+	assigned := make(map[string]string)
+	for group, sources := range groups {
+		if errs := validation.IsDNS1123Label(group); len(errs) > 0 {
+			return fmt.Errorf("invalid group name %q: %s", group, strings.Join(errs, "; "))
+		}
+		if len(sources) == 0 {
+			return fmt.Errorf("no feature sources specified for group %q", group)
+		}
+		for _, name := range sources {
+			if other, ok := assigned[name]; ok {
+				return fmt.Errorf("feature source %q specified in groups %q and %q", name, other, group)
+			}
+			assigned[name] = group
+			if source.GetFeatureSource(name) == nil {
+				klog.InfoS("unknown source specified in core.nodeFeatureGroups", "featureSource", name, "group", group)
+			}
+		}
+	}
+	return nil
+}
+
+// splitNodeFeatureGroups splits a NodeFeatureSpec by the feature source into
+// the configured groups. Features of sources not assigned to any group, and
+// all labels, are kept in the default group that is always the first one
+// returned. Groups without any features are omitted so that their objects
+// get garbage collected.
+func splitNodeFeatureGroups(spec *nfdv1alpha1.NodeFeatureSpec, groups map[string][]string) []nodeFeatureGroup {
+	if len(groups) == 0 {
+		return []nodeFeatureGroup{{spec: *spec}}
+	}
+
+	groupOf := make(map[string]string)
+	for group, sources := range groups {
+		for _, name := range sources {
+			groupOf[name] = group
+		}
+	}
+
+	specs := map[string]*nfdv1alpha1.NodeFeatureSpec{
+		"": {Features: *nfdv1alpha1.NewFeatures(), Labels: spec.Labels},
+	}
+	get := func(feature string) *nfdv1alpha1.NodeFeatureSpec {
+		src, _, _ := strings.Cut(feature, ".")
+		group := groupOf[src]
+		if _, ok := specs[group]; !ok {
+			specs[group] = &nfdv1alpha1.NodeFeatureSpec{Features: *nfdv1alpha1.NewFeatures()}
+		}
+		return specs[group]
+	}
+	for name, f := range spec.Features.Flags {
+		get(name).Features.Flags[name] = f
+	}
+	for name, f := range spec.Features.Attributes {
+		get(name).Features.Attributes[name] = f
+	}
+	for name, f := range spec.Features.Instances {
+		get(name).Features.Instances[name] = f
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]nodeFeatureGroup, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, nodeFeatureGroup{name: name, spec: *specs[name]})
+	}
+	return ret
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestNodeFeatureGroups(t *testing.T) {
+	Convey("When validating node feature groups", t, func() {
+		Convey("valid groups should be accepted", func() {
+			So(validateNodeFeatureGroups(nil), ShouldBeNil)
+			So(validateNodeFeatureGroups(map[string][]string{"dynamic": {"local", "memory"}, "pci": {"pci"}}), ShouldBeNil)
+		})
+		Convey("invalid group names should be rejected", func() {
+			So(validateNodeFeatureGroups(map[string][]string{"Foo_bar": {"pci"}}), ShouldNotBeNil)
+		})
+		Convey("empty groups should be rejected", func() {
+			So(validateNodeFeatureGroups(map[string][]string{"pci": {}}), ShouldNotBeNil)
+		})
+		Convey("sources assigned to multiple groups should be rejected", func() {
+			So(validateNodeFeatureGroups(map[string][]string{"a": {"pci"}, "b": {"usb", "pci"}}), ShouldNotBeNil)
+		})
+	})
+
+	Convey("When splitting node features into groups", t, func() {
+		spec := newChunkTestSpec(2)
+
+		Convey("no groups should return the spec as-is", func() {
+			groups := splitNodeFeatureGroups(spec, nil)
+			So(groups, ShouldHaveLength, 1)
+			So(groups[0].name, ShouldEqual, "")
+			So(groups[0].spec, ShouldResemble, *spec)
+		})
+
+		Convey("features should be split by source", func() {
+			groups := splitNodeFeatureGroups(spec, map[string][]string{"buses": {"pci", "usb"}, "cpu": {"cpu"}, "unused": {"local"}})
+			So(groups, ShouldHaveLength, 3)
+
+			So(groups[0].name, ShouldEqual, "")
+			So(groups[0].spec.Labels, ShouldResemble, spec.Labels)
+			So(groups[0].spec.Features.Attributes, ShouldContainKey, "kernel.version")
+			So(groups[0].spec.Features.Flags, ShouldBeEmpty)
+			So(groups[0].spec.Features.Instances, ShouldBeEmpty)
+
+			So(groups[1].name, ShouldEqual, "buses")
+			So(groups[1].spec.Labels, ShouldBeNil)
+			So(groups[1].spec.Features.Instances, ShouldHaveLength, 2)
+
+			So(groups[2].name, ShouldEqual, "cpu")
+			So(groups[2].spec.Features.Flags, ShouldResemble, map[string]nfdv1alpha1.FlagFeatureSet{"cpu.cpuid": spec.Features.Flags["cpu.cpuid"]})
+		})
+	})
+
+	Convey("Group objects should be named after the node", t, func() {
+		So(nodeFeatureGroupName("node-1", ""), ShouldEqual, "node-1")
+		So(nodeFeatureGroupName("node-1", "pci"), ShouldEqual, "node-1-group-pci")
+		So(nodeFeatureChunkName(nodeFeatureGroupName("node-1", "pci"), 1), ShouldEqual, "node-1-group-pci-chunk-1")
+	})
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// sourceFeatureLists holds the featureAllowList and featureDenyList options
+// of one source, restricting the features the source publishes. Entries are
+// glob patterns of the form <feature>[.<element>], relative to the source,
+// e.g. "cpuid.AVX512*" for the cpu source. An entry without the element part
+// applies to the feature as a whole. The deny list has priority over the
+// allow list and an empty allow list allows everything.
+type sourceFeatureLists struct {
+	FeatureAllowList []string
+	FeatureDenyList  []string
+}
+
+// parseSourceFeatureLists parses the feature lists of all sources from
+// configuration data, merging them into lists. Lists specified in the data
+// replace the existing ones.
+func parseSourceFeatureLists(data []byte, lists map[string]sourceFeatureLists) error {
+	c := struct {
+		Sources map[string]json.RawMessage
+	}{}
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	for name, raw := range c.Sources {
+		// The configuration of some sources (e.g. custom) is not an object
+		if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			continue
+		}
+		l := sourceFeatureLists{}
+		if err := yaml.Unmarshal(raw, &l); err != nil {
+			return fmt.Errorf("failed to parse %q source config: %w", name, err)
+		}
+		if l.FeatureAllowList == nil && l.FeatureDenyList == nil {
+			continue
+		}
+		old := lists[name]
+		if l.FeatureAllowList != nil {
+			old.FeatureAllowList = l.FeatureAllowList
+		}
+		if l.FeatureDenyList != nil {
+			old.FeatureDenyList = l.FeatureDenyList
+		}
+		lists[name] = old
+	}
+	return nil
+}
+
+// validate checks that all entries of the lists are valid patterns.
+func (l *sourceFeatureLists) validate() error {
+	for _, e := range append(append([]string{}, l.FeatureAllowList...), l.FeatureDenyList...) {
+		feature, element := splitFeatureListEntry(e)
+		if feature == "" {
+			return fmt.Errorf("invalid entry %q: feature must be specified", e)
+		}
+		if _, err := path.Match(feature, ""); err != nil {
+			return fmt.Errorf("invalid entry %q: %w", e, err)
+		}
+		if _, err := path.Match(element, ""); err != nil {
+			return fmt.Errorf("invalid entry %q: %w", e, err)
+		}
+	}
+	return nil
+}
+
+func splitFeatureListEntry(e string) (feature, element string) {
+	feature, element, _ = strings.Cut(e, ".")
+	return feature, element
+}
+
+// featureAllowed returns true if the feature (or some of its elements) is
+// allowed.
+func (l *sourceFeatureLists) featureAllowed(feature string) bool {
+	for _, e := range l.FeatureDenyList {
+		if fp, ep := splitFeatureListEntry(e); ep == "" && globMatch(fp, feature) {
+			return false
+		}
+	}
+	if len(l.FeatureAllowList) == 0 {
+		return true
+	}
+	for _, e := range l.FeatureAllowList {
+		if fp, _ := splitFeatureListEntry(e); globMatch(fp, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementAllowed returns true if an element of an allowed feature is allowed.
+func (l *sourceFeatureLists) elementAllowed(feature, element string) bool {
+	match := func(e string) bool {
+		fp, ep := splitFeatureListEntry(e)
+		return globMatch(fp, feature) && (ep == "" || globMatch(ep, element))
+	}
+	for _, e := range l.FeatureDenyList {
+		if match(e) {
+			return false
+		}
+	}
+	if len(l.FeatureAllowList) == 0 {
+		return true
+	}
+	for _, e := range l.FeatureAllowList {
+		if match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// applySourceFeatureLists returns a copy of the features with the feature
+// lists of the sources applied.
+func applySourceFeatureLists(features *nfdv1alpha1.Features, lists map[string]sourceFeatureLists) *nfdv1alpha1.Features {
+	if len(lists) == 0 {
+		return features
+	}
+
+	out := features.DeepCopy()
+	for name, l := range lists {
+		prefix := name + "."
+		for fullName, f := range out.Flags {
+			if feature, ok := strings.CutPrefix(fullName, prefix); ok {
+				if !l.featureAllowed(feature) {
+					delete(out.Flags, fullName)
+					continue
+				}
+				filterListedElements(&l, feature, f.Elements)
+			}
+		}
+		for fullName, f := range out.Attributes {
+			if feature, ok := strings.CutPrefix(fullName, prefix); ok {
+				if !l.featureAllowed(feature) {
+					delete(out.Attributes, fullName)
+					continue
+				}
+				filterListedElements(&l, feature, f.Elements)
+			}
+		}
+		for fullName, f := range out.Instances {
+			if feature, ok := strings.CutPrefix(fullName, prefix); ok {
+				if !l.featureAllowed(feature) {
+					delete(out.Instances, fullName)
+					continue
+				}
+				for _, instance := range f.Elements {
+					filterListedElements(&l, feature, instance.Attributes)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func filterListedElements[T any](l *sourceFeatureLists, feature string, elements map[string]T) {
+	for element := range elements {
+		if !l.elementAllowed(feature, element) {
+			delete(elements, element)
+		}
+	}
+}
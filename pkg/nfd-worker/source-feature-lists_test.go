@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceFeatureLists(t *testing.T) {
+	Convey("When applying source feature lists", t, func() {
+		features := newFilterTestFeatures()
+
+		Convey("without lists the features should be returned as-is", func() {
+			So(applySourceFeatureLists(features, nil), ShouldEqual, features)
+		})
+
+		Convey("denied elements should be dropped", func() {
+			out := applySourceFeatureLists(features, map[string]sourceFeatureLists{
+				"cpu":    {FeatureDenyList: []string{"cpuid.AVX2"}},
+				"system": {FeatureDenyList: []string{"dmiid.product_*"}},
+				"pci":    {FeatureDenyList: []string{"device.serial"}},
+			})
+			So(out.Flags["cpu.cpuid"].Elements, ShouldNotContainKey, "AVX2")
+			So(out.Attributes["system.dmiid"].Elements, ShouldResemble, map[string]string{"sys_vendor": "acme"})
+			So(out.Instances["pci.device"].Elements[0].Attributes, ShouldResemble, map[string]string{"vendor": "8086"})
+			Convey("the original features should not be modified", func() {
+				So(features, ShouldResemble, newFilterTestFeatures())
+			})
+		})
+
+		Convey("denied features should be dropped", func() {
+			out := applySourceFeatureLists(features, map[string]sourceFeatureLists{
+				"kernel": {FeatureDenyList: []string{"selinux"}},
+			})
+			So(out.Attributes, ShouldNotContainKey, "kernel.selinux")
+			So(out.Attributes, ShouldContainKey, "system.dmiid")
+		})
+
+		Convey("only allowed features and elements should be kept", func() {
+			out := applySourceFeatureLists(features, map[string]sourceFeatureLists{
+				"cpu":    {FeatureAllowList: []string{"cpuid.AVX"}},
+				"system": {FeatureAllowList: []string{"other"}},
+				"pci":    {FeatureAllowList: []string{"device"}, FeatureDenyList: []string{"device.serial"}},
+			})
+			So(out.Flags["cpu.cpuid"].Elements, ShouldContainKey, "AVX")
+			So(out.Flags["cpu.cpuid"].Elements, ShouldHaveLength, 1)
+			So(out.Attributes, ShouldNotContainKey, "system.dmiid")
+			So(out.Attributes, ShouldContainKey, "kernel.selinux")
+			So(out.Instances["pci.device"].Elements[1].Attributes, ShouldResemble, map[string]string{"vendor": "10de"})
+		})
+	})
+
+	Convey("When validating source feature lists", t, func() {
+		So((&sourceFeatureLists{FeatureAllowList: []string{"cpuid.AVX*"}}).validate(), ShouldBeNil)
+		So((&sourceFeatureLists{FeatureAllowList: []string{".AVX"}}).validate(), ShouldNotBeNil)
+		So((&sourceFeatureLists{FeatureDenyList: []string{"cpuid.["}}).validate(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+// Feature sources that are only available on Linux
+import (
+	_ "github.com/openshift/node-feature-discovery/source/crypto"
+	_ "github.com/openshift/node-feature-discovery/source/dpdk"
+	_ "github.com/openshift/node-feature-discovery/source/kernel"
+	_ "github.com/openshift/node-feature-discovery/source/pci"
+	_ "github.com/openshift/node-feature-discovery/source/power"
+	_ "github.com/openshift/node-feature-discovery/source/storage"
+	_ "github.com/openshift/node-feature-discovery/source/usb"
+	_ "github.com/openshift/node-feature-discovery/source/virtualization"
+)
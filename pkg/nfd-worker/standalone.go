@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// validateStandalone checks the arguments of standalone mode, where
+// nfd-worker runs without any access to the Kubernetes API and only
+// outputs the discovered features locally.
+func validateStandalone(args *Args) error {
+	if !args.Standalone {
+		if args.Socket != "" {
+			return fmt.Errorf("-socket is only supported in standalone mode (-standalone)")
+		}
+		return nil
+	}
+	if args.Output == "" && args.OutputFile == "" && args.Socket == "" {
+		return fmt.Errorf("-standalone requires at least one of -output, -output-file or -socket")
+	}
+	if args.Oneshot && args.Socket != "" {
+		return fmt.Errorf("-socket cannot be used with -oneshot")
+	}
+	if args.ConfigObject != "" {
+		return fmt.Errorf("-config-object cannot be used with -standalone")
+	}
+	if args.MetricsSecurity.TokenAuth {
+		return fmt.Errorf("-metrics-token-auth cannot be used with -standalone")
+	}
+	return nil
+}
+
+// writeFeaturesFile writes the discovered features to a file in JSON format.
+// The file is replaced atomically so that readers never see partial content.
+func writeFeaturesFile(path string, spec *nfdv1alpha1.NodeFeatureSpec) error {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal features: %w", err)
+	}
+	data = append(data, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write features file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write features file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write features file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write features file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write features file: %w", err)
+	}
+	klog.V(2).InfoS("features written", "path", path)
+	return nil
+}
+
+// featureServer serves the latest discovered features in JSON format over
+// HTTP on a unix socket.
+type featureServer struct {
+	path     string
+	server   *http.Server
+	listener net.Listener
+
+	mu   sync.RWMutex
+	data []byte
+}
+
+// newFeatureServer creates a unix socket at the given path and starts
+// serving features on it. A stale socket left behind by a previous instance
+// is removed.
+func newFeatureServer(path string) (*featureServer, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("failed to create socket %q: file exists", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %q: %w", path, err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket %q: %w", path, err)
+	}
+
+	s := &featureServer{path: path, listener: l}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/features", s.serveFeatures)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.ErrorS(err, "feature server failed", "path", path)
+		}
+	}()
+	klog.InfoS("serving features", "socket", path)
+	return s, nil
+}
+
+// update replaces the served features.
+func (s *featureServer) update(spec *nfdv1alpha1.NodeFeatureSpec) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal features: %w", err)
+	}
+	s.mu.Lock()
+	s.data = data
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *featureServer) serveFeatures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.RLock()
+	data := s.data
+	s.mu.RUnlock()
+	if data == nil {
+		http.Error(w, "feature discovery not completed", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// stop shuts down the server and removes the socket.
+func (s *featureServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		klog.ErrorS(err, "failed to shut down feature server")
+	}
+	// The listener normally unlinks the socket on close
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		klog.ErrorS(err, "failed to remove socket", "path", s.path)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfdworker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func TestValidateStandalone(t *testing.T) {
+	Convey("When validating standalone mode arguments", t, func() {
+		Convey("valid arguments should be accepted", func() {
+			So(validateStandalone(&Args{}), ShouldBeNil)
+			So(validateStandalone(&Args{Standalone: true, Output: "json"}), ShouldBeNil)
+			So(validateStandalone(&Args{Standalone: true, OutputFile: "/tmp/features.json", Oneshot: true}), ShouldBeNil)
+			So(validateStandalone(&Args{Standalone: true, Socket: "/tmp/features.sock"}), ShouldBeNil)
+		})
+		Convey("standalone mode without any output should be rejected", func() {
+			So(validateStandalone(&Args{Standalone: true}), ShouldNotBeNil)
+		})
+		Convey("options requiring the Kubernetes API should be rejected", func() {
+			So(validateStandalone(&Args{Standalone: true, Output: "json", ConfigObject: "foo"}), ShouldNotBeNil)
+			So(validateStandalone(&Args{Standalone: true, Output: "json", MetricsSecurity: utils.MetricsSecurityArgs{TokenAuth: true}}), ShouldNotBeNil)
+		})
+		Convey("socket should be rejected outside standalone mode and with oneshot", func() {
+			So(validateStandalone(&Args{Socket: "/tmp/features.sock"}), ShouldNotBeNil)
+			So(validateStandalone(&Args{Standalone: true, Socket: "/tmp/features.sock", Oneshot: true}), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestStandaloneOutput(t *testing.T) {
+	Convey("When running in standalone mode", t, func() {
+		dir := t.TempDir()
+		w, err := NewNfdWorker(&Args{
+			Standalone: true,
+			OutputFile: filepath.Join(dir, "features.json"),
+			Socket:     filepath.Join(dir, "features.sock"),
+			Overrides: ConfigOverrideArgs{
+				FeatureSources: &utils.StringSliceVal{"fake"},
+				LabelSources:   &utils.StringSliceVal{"fake"},
+			},
+		})
+		So(err, ShouldBeNil)
+		worker := w.(*nfdWorker)
+		So(worker.configure("", ""), ShouldBeNil)
+		So(worker.config.Core.NoPublish, ShouldBeTrue)
+
+		worker.featureServer, err = newFeatureServer(worker.args.Socket)
+		So(err, ShouldBeNil)
+		defer worker.featureServer.stop()
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", worker.args.Socket)
+			},
+		}}
+		getFeatures := func() (int, []byte) {
+			resp, err := client.Get("http://nfd/features")
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			return resp.StatusCode, data
+		}
+
+		Convey("features should not be served before discovery", func() {
+			code, _ := getFeatures()
+			So(code, ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		Convey("features should be written to the file and served on the socket", func() {
+			So(worker.runFeatureDiscovery(), ShouldBeNil)
+
+			data, err := os.ReadFile(worker.args.OutputFile)
+			So(err, ShouldBeNil)
+			spec := nfdv1alpha1.NodeFeatureSpec{}
+			So(json.Unmarshal(data, &spec), ShouldBeNil)
+			So(spec.Labels, ShouldContainKey, nfdv1alpha1.FeatureLabelNs+"/fake-fakefeature1")
+
+			code, data := getFeatures()
+			So(code, ShouldEqual, http.StatusOK)
+			spec = nfdv1alpha1.NodeFeatureSpec{}
+			So(json.Unmarshal(data, &spec), ShouldBeNil)
+			So(spec.Features.Flags, ShouldContainKey, "fake.flag")
+		})
+	})
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podres
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"k8s.io/kubernetes/pkg/kubelet/apis/podresources"
+)
+
+// watchMethod is the server-streaming Watch endpoint of the podresources API
+// (KEP-2043). It is not implemented by all kubelet versions.
+const watchMethod = "/v1.PodResourcesLister/Watch"
+
+// rawCodec passes messages through undecoded. The content of the Watch
+// messages is not needed as they only trigger a re-scan with List.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name returns "proto" so that the kubelet accepts the requests.
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// WatchPodResources streams change notifications from the Watch endpoint of
+// the podresources API. It blocks until the context is cancelled or the
+// stream ends and calls notify for each message received. Kubelets without
+// the Watch endpoint make it return an error with codes.Unimplemented.
+func WatchPodResources(ctx context.Context, socketPath string, notify func()) error {
+	_, conn, err := podresources.GetV1Client(socketPath, defaultPodResourcesTimeout, defaultPodResourcesMaxSize)
+	if err != nil {
+		return fmt.Errorf("failed to create podresource client: %w", err)
+	}
+	defer conn.Close()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, watchMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+	// The request is empty, i.e. watch all pods
+	if err := stream.SendMsg(&[]byte{}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	var msg []byte
+	for {
+		if err := stream.RecvMsg(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		notify()
+	}
+}
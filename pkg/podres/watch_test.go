@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podres
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func startTestServer(t *testing.T, opts ...grpc.ServerOption) string {
+	socket := filepath.Join(t.TempDir(), "kubelet.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return socket
+}
+
+func TestWatchPodResources(t *testing.T) {
+	Convey("When the kubelet implements the Watch endpoint", t, func() {
+		socket := startTestServer(t,
+			grpc.ForceServerCodec(rawCodec{}),
+			grpc.UnknownServiceHandler(func(srv any, stream grpc.ServerStream) error {
+				method, _ := grpc.MethodFromServerStream(stream)
+				if method != watchMethod {
+					return status.Error(codes.Unimplemented, method)
+				}
+				var req []byte
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+				for i := 0; i < 3; i++ {
+					if err := stream.SendMsg(&[]byte{0x08, byte(i)}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		n := 0
+		err := WatchPodResources(ctx, socket, func() { n++ })
+		Convey("Every message should be notified", func() {
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 3)
+		})
+	})
+
+	Convey("When the kubelet does not implement the Watch endpoint", t, func() {
+		socket := startTestServer(t)
+
+		err := WatchPodResources(context.Background(), socket, func() {})
+		Convey("An Unimplemented error should be returned", func() {
+			So(status.Code(err), ShouldEqual, codes.Unimplemented)
+		})
+	})
+}
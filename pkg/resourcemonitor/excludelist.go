@@ -1,6 +1,9 @@
 package resourcemonitor
 
 import (
+	"path"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -9,19 +12,32 @@ import (
 // ExcludeResourceList contains a list of resources to ignore during resources scan
 type ExcludeResourceList struct {
 	excludeList sets.Set[string]
+	patterns    []string
 }
 
-// NewExcludeResourceList returns new ExcludeList with values with set.String types
+// NewExcludeResourceList returns new ExcludeList with values with set.String types.
+// Values containing glob meta characters (see path.Match) are treated as
+// patterns, e.g. "example.com/*" excludes all resources of a vendor.
 func NewExcludeResourceList(resMap map[string][]string, nodeName string) ExcludeResourceList {
 	excludeList := make(sets.Set[string])
+	patterns := []string{}
 
 	for k, v := range resMap {
 		if k == nodeName || k == "*" {
-			excludeList.Insert(v...)
+			for _, name := range v {
+				if !isPattern(name) {
+					excludeList.Insert(name)
+				} else if _, err := path.Match(name, ""); err != nil {
+					klog.ErrorS(err, "invalid pattern in exclude list, ignoring", "pattern", name)
+				} else {
+					patterns = append(patterns, name)
+				}
+			}
 		}
 	}
 	return ExcludeResourceList{
 		excludeList: excludeList,
+		patterns:    patterns,
 	}
 }
 
@@ -30,5 +46,16 @@ func (rl *ExcludeResourceList) IsExcluded(resource corev1.ResourceName) bool {
 		klog.V(5).InfoS("resource excluded", "resourceName", resource)
 		return true
 	}
+	for _, p := range rl.patterns {
+		// Pattern has been validated in NewExcludeResourceList
+		if match, _ := path.Match(p, string(resource)); match {
+			klog.V(5).InfoS("resource excluded", "resourceName", resource, "pattern", p)
+			return true
+		}
+	}
 	return false
 }
+
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
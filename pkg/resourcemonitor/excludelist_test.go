@@ -19,6 +19,7 @@ func TestNewExcludeResourceList(t *testing.T) {
 		excludeListConfig         map[string][]string
 		nodeName                  string
 		expectedExcludedResources []string
+		expectedIncludedResources []string
 	}{
 		{
 
@@ -56,6 +57,32 @@ func TestNewExcludeResourceList(t *testing.T) {
 			nodeName:                  "node1",
 			expectedExcludedResources: []string{},
 		},
+
+		{
+			desc: "exclude list with patterns",
+			excludeListConfig: map[string][]string{
+				"*": {
+					"vendor/*", "hugepages-*",
+				},
+				"node1": {
+					cpu,
+				},
+			},
+			nodeName:                  "node2",
+			expectedExcludedResources: []string{nicResourceName, hugepages2Mi},
+			expectedIncludedResources: []string{cpu, memory},
+		},
+		{
+			desc: "exclude list with invalid pattern",
+			excludeListConfig: map[string][]string{
+				"*": {
+					"vendor/[", memory,
+				},
+			},
+			nodeName:                  "node1",
+			expectedExcludedResources: []string{memory},
+			expectedIncludedResources: []string{nicResourceName},
+		},
 	}
 
 	for _, tt := range tests {
@@ -66,5 +93,10 @@ func TestNewExcludeResourceList(t *testing.T) {
 				t.Errorf("resource: %q expected to be excluded from node: %q", res, tt.nodeName)
 			}
 		}
+		for _, res := range tt.expectedIncludedResources {
+			if excludeList.IsExcluded(corev1.ResourceName(res)) {
+				t.Errorf("resource: %q expected not to be excluded from node: %q", res, tt.nodeName)
+			}
+		}
 	}
 }
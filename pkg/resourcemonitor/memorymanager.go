@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+const (
+	// MemoryManagerStateFileName is the name of the state file of the
+	// kubelet memory manager in the kubelet state directory.
+	MemoryManagerStateFileName = "memory_manager_state"
+
+	// memoryManagerPolicyStatic is the memory manager policy reserving
+	// memory per NUMA node.
+	memoryManagerPolicyStatic = "Static"
+)
+
+// memoryManagerCheckpoint is the subset of the memory manager state file
+// (checkpoint) of the kubelet used by the resource monitor.
+type memoryManagerCheckpoint struct {
+	PolicyName   string                              `json:"policyName"`
+	MachineState map[int]*memoryManagerNUMANodeState `json:"machineState"`
+}
+
+// memoryManagerNUMANodeState is the state of the memory of one NUMA node.
+type memoryManagerNUMANodeState struct {
+	MemoryMap map[corev1.ResourceName]*memoryManagerMemoryTable `json:"memoryMap"`
+}
+
+// memoryManagerMemoryTable is the state of one memory resource (memory or
+// hugepages of one size) of a NUMA node.
+type memoryManagerMemoryTable struct {
+	TotalMemSize   uint64 `json:"total"`
+	SystemReserved uint64 `json:"systemReserved"`
+}
+
+// readMemoryManagerReserved reads the memory reserved for the system per NUMA
+// node from the kubelet memory manager state file. Nil is returned if the
+// file does not exist or the memory manager does not use the static policy,
+// in which case no memory is reserved per NUMA node.
+func readMemoryManagerReserved(path string) (utils.NumaMemoryResources, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		klog.V(2).InfoS("memory manager state file not found, not accounting reserved memory", "path", path)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	checkpoint := memoryManagerCheckpoint{}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse memory manager state file %q: %w", path, err)
+	}
+	if checkpoint.PolicyName != memoryManagerPolicyStatic {
+		klog.V(2).InfoS("memory manager policy is not static, not accounting reserved memory", "path", path, "policyName", checkpoint.PolicyName)
+		return nil, nil
+	}
+
+	reserved := make(utils.NumaMemoryResources, len(checkpoint.MachineState))
+	for nodeID, nodeState := range checkpoint.MachineState {
+		if nodeState == nil {
+			continue
+		}
+		reserved[nodeID] = make(utils.MemoryResourceInfo, len(nodeState.MemoryMap))
+		for resName, table := range nodeState.MemoryMap {
+			if table == nil {
+				continue
+			}
+			reserved[nodeID][resName] = int64(table.SystemReserved)
+		}
+	}
+	return reserved, nil
+}
+
+// subtractMemoryReserved subtracts the memory reserved for the system from
+// the allocatable memory of each NUMA node.
+func subtractMemoryReserved(perNUMAAllocatable map[int]map[corev1.ResourceName]int64, memoryReserved utils.NumaMemoryResources) {
+	for nodeID, resources := range memoryReserved {
+		nodeRes, ok := perNUMAAllocatable[nodeID]
+		if !ok {
+			continue
+		}
+		for resName, reserved := range resources {
+			allocatable, ok := nodeRes[resName]
+			if !ok {
+				continue
+			}
+			nodeRes[resName] = max(allocatable-reserved, 0)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemonitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+const testMemoryManagerState = `{
+  "policyName": "Static",
+  "machineState": {
+    "0": {
+      "numberOfAssignments": 0,
+      "memoryMap": {
+        "hugepages-2Mi": {"total": 1073741824, "systemReserved": 0, "allocatable": 1073741824, "reserved": 0, "free": 1073741824},
+        "memory": {"total": 4294967296, "systemReserved": 1073741824, "allocatable": 3221225472, "reserved": 0, "free": 3221225472}
+      },
+      "cells": [0]
+    },
+    "1": {
+      "numberOfAssignments": 0,
+      "memoryMap": {
+        "memory": {"total": 4294967296, "systemReserved": 536870912, "allocatable": 3758096384, "reserved": 0, "free": 3758096384}
+      },
+      "cells": [1]
+    }
+  },
+  "entries": {},
+  "checksum": 1234567890
+}`
+
+func TestMemoryManagerReserved(t *testing.T) {
+	dir := t.TempDir()
+	writeState := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		So(os.WriteFile(path, []byte(data), 0644), ShouldBeNil)
+		return path
+	}
+
+	Convey("When reading the memory manager state", t, func() {
+		Convey("Reserved memory of the static policy is returned", func() {
+			reserved, err := readMemoryManagerReserved(writeState("static", testMemoryManagerState))
+			So(err, ShouldBeNil)
+			So(reserved, ShouldResemble, utils.NumaMemoryResources{
+				0: {corev1.ResourceMemory: 1073741824, "hugepages-2Mi": 0},
+				1: {corev1.ResourceMemory: 536870912},
+			})
+		})
+		Convey("Nothing is reserved with the none policy", func() {
+			reserved, err := readMemoryManagerReserved(writeState("none", `{"policyName":"None","machineState":{},"checksum":0}`))
+			So(err, ShouldBeNil)
+			So(reserved, ShouldBeNil)
+		})
+		Convey("Nothing is reserved if the state file does not exist", func() {
+			reserved, err := readMemoryManagerReserved(filepath.Join(dir, "missing"))
+			So(err, ShouldBeNil)
+			So(reserved, ShouldBeNil)
+		})
+		Convey("Invalid state files are reported", func() {
+			_, err := readMemoryManagerReserved(writeState("invalid", "{"))
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("When subtracting reserved memory", t, func() {
+		allocatable := map[int]map[corev1.ResourceName]int64{
+			0: {corev1.ResourceCPU: 4, corev1.ResourceMemory: 4096, "hugepages-2Mi": 1024},
+			1: {corev1.ResourceMemory: 512},
+		}
+		subtractMemoryReserved(allocatable, utils.NumaMemoryResources{
+			0: {corev1.ResourceMemory: 1024, "hugepages-1Gi": 1024},
+			1: {corev1.ResourceMemory: 1024},
+			2: {corev1.ResourceMemory: 1024},
+		})
+		So(allocatable, ShouldResemble, map[int]map[corev1.ResourceName]int64{
+			0: {corev1.ResourceCPU: 4, corev1.ResourceMemory: 3072, "hugepages-2Mi": 1024},
+			1: {corev1.ResourceMemory: 0},
+		})
+	})
+}
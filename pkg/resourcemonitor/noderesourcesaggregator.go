@@ -19,15 +19,18 @@ package resourcemonitor
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jaypipes/ghw"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
 	topologyv1alpha2 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/cpuset"
 
 	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
@@ -37,6 +40,15 @@ import (
 const (
 	// obtained these values from node e2e tests : https://github.com/kubernetes/kubernetes/blob/82baa26905c94398a0d19e1b1ecf54eb8acb6029/test/e2e_node/util.go#L70
 	defaultPodResourcesTimeout = 10 * time.Second
+
+	// ZoneL3CacheDomainsAttributeName is the zone attribute listing the CPUs
+	// of each L3 cache domain of a NUMA node, separated by semicolons, e.g.
+	// "0-7,64-71;8-15,72-79".
+	ZoneL3CacheDomainsAttributeName = "l3CacheDomains"
+	// ZoneL3CacheSizesAttributeName is the zone attribute listing the sizes
+	// in bytes of the L3 cache domains of a NUMA node, in the same order as
+	// ZoneL3CacheDomainsAttributeName.
+	ZoneL3CacheSizesAttributeName = "l3CacheSizes"
 )
 
 type nodeResources struct {
@@ -47,6 +59,8 @@ type nodeResources struct {
 	reservedCPUIDPerNUMA           map[int][]string
 	memoryResourcesCapacityPerNUMA utils.NumaMemoryResources
 	excludeList                    ExcludeResourceList
+	// mapping: nodeID -> attributes of the zone
+	zoneAttributes map[int]topologyv1alpha2.AttributeList
 }
 
 type resourceData struct {
@@ -55,7 +69,7 @@ type resourceData struct {
 	capacity    int64
 }
 
-func NewResourcesAggregator(podResourceClient podresourcesapi.PodResourcesListerClient, excludeList ExcludeResourceList) (ResourcesAggregator, error) {
+func NewResourcesAggregator(podResourceClient podresourcesapi.PodResourcesListerClient, excludeList ExcludeResourceList, memoryManagerStateFile string) (ResourcesAggregator, error) {
 	var err error
 
 	topo, err := ghw.Topology(ghw.WithPathOverrides(ghw.PathOverrides{
@@ -70,6 +84,11 @@ func NewResourcesAggregator(podResourceClient podresourcesapi.PodResourcesLister
 		return nil, err
 	}
 
+	memoryResourcesReservedPerNUMA, err := readMemoryManagerReserved(memoryManagerStateFile)
+	if err != nil {
+		klog.ErrorS(err, "failed to read memory manager state, not accounting reserved memory", "path", memoryManagerStateFile)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), defaultPodResourcesTimeout)
 	defer cancel()
 
@@ -86,26 +105,37 @@ func NewResourcesAggregator(podResourceClient podresourcesapi.PodResourcesLister
 		return nil, fmt.Errorf("failed to get allocatable resources (ensure that KubeletPodResourcesGetAllocatable feature gate is enabled): %w", err)
 	}
 
-	return NewResourcesAggregatorFromData(topo, resp, memoryResourcesCapacityPerNUMA, excludeList), nil
+	return NewResourcesAggregatorFromData(topo, resp, memoryResourcesCapacityPerNUMA, memoryResourcesReservedPerNUMA, excludeList), nil
 }
 
-// NewResourcesAggregatorFromData is used to aggregate resource information based on the received data from underlying hardware and podresource API
-func NewResourcesAggregatorFromData(topo *ghw.TopologyInfo, resp *podresourcesapi.AllocatableResourcesResponse, memoryResourceCapacity utils.NumaMemoryResources, excludeList ExcludeResourceList) ResourcesAggregator {
+// NewResourcesAggregatorFromData is used to aggregate resource information based on the received data from underlying hardware and podresource API.
+// The memory reserved per NUMA node by the memory manager (static policy) is subtracted from the capacity if the kubelet does not report the allocatable memory.
+func NewResourcesAggregatorFromData(topo *ghw.TopologyInfo, resp *podresourcesapi.AllocatableResourcesResponse, memoryResourceCapacity utils.NumaMemoryResources, memoryResourceReserved utils.NumaMemoryResources, excludeList ExcludeResourceList) ResourcesAggregator {
 	allDevs := getContainerDevicesFromAllocatableResources(resp, topo)
+	perNUMAAllocatable := makeNodeAllocatable(allDevs, resp.GetMemory())
+	if len(resp.GetMemory()) == 0 {
+		// Kubelet does not report memory blocks (memory manager policy None,
+		// or not supported by the kubelet), fall back to the capacity from
+		// sysfs minus the memory reserved by the memory manager, if any
+		addMemoryAllocatableFromCapacity(perNUMAAllocatable, memoryResourceCapacity)
+		subtractMemoryReserved(perNUMAAllocatable, memoryResourceReserved)
+	}
 	return &nodeResources{
 		topo:                           topo,
 		resourceID2NUMAID:              makeResourceMap(len(topo.Nodes), allDevs),
-		perNUMAAllocatable:             makeNodeAllocatable(allDevs, resp.GetMemory()),
+		perNUMAAllocatable:             perNUMAAllocatable,
 		reservedCPUIDPerNUMA:           makeReservedCPUMap(topo.Nodes, allDevs),
 		memoryResourcesCapacityPerNUMA: memoryResourceCapacity,
 		excludeList:                    excludeList,
+		zoneAttributes:                 makeZoneAttributes(topo.Nodes),
 	}
 }
 
 // Aggregate provides the mapping (numa zone name) -> Zone from the given PodResources.
 func (noderesourceData *nodeResources) Aggregate(podResData []PodResources) topologyv1alpha2.ZoneList {
 	perNuma := make(map[int]map[corev1.ResourceName]*resourceData)
-	for nodeID := range noderesourceData.topo.Nodes {
+	for _, node := range noderesourceData.topo.Nodes {
+		nodeID := node.ID
 		nodeRes, ok := noderesourceData.perNUMAAllocatable[nodeID]
 		if ok {
 			perNuma[nodeID] = make(map[corev1.ResourceName]*resourceData)
@@ -143,6 +173,14 @@ func (noderesourceData *nodeResources) Aggregate(podResData []PodResources) topo
 					}
 				}
 			}
+			// NUMA node has allocatable memory but all its CPUs are reserved
+			if _, ok := nodeRes[corev1.ResourceCPU]; !ok && len(noderesourceData.reservedCPUIDPerNUMA[nodeID]) > 0 {
+				perNuma[nodeID]["cpu"] = &resourceData{
+					allocatable: int64(0),
+					available:   int64(0),
+					capacity:    int64(len(noderesourceData.reservedCPUIDPerNUMA[nodeID])),
+				}
+			}
 			// NUMA node doesn't have any allocatable resources, but yet it exists in the topology
 			// thus all its CPUs are reserved
 		} else {
@@ -182,6 +220,9 @@ func (noderesourceData *nodeResources) Aggregate(podResData []PodResources) topo
 		} else {
 			zone.Costs = costs
 		}
+		if attrs, ok := noderesourceData.zoneAttributes[nodeID]; ok {
+			zone.Attributes = append(topologyv1alpha2.AttributeList{}, attrs...)
+		}
 
 		for name, resData := range resList {
 			allocatableQty := *resource.NewQuantity(resData.allocatable, resource.DecimalSI)
@@ -311,6 +352,23 @@ func makeNodeAllocatable(devices []*podresourcesapi.ContainerDevices, memoryBloc
 	return perNUMAAllocatable
 }
 
+// addMemoryAllocatableFromCapacity adds memory and hugepages of each NUMA node
+// to the allocatable resources, using the full capacity as allocatable.
+func addMemoryAllocatableFromCapacity(perNUMAAllocatable map[int]map[corev1.ResourceName]int64, memoryResourceCapacity utils.NumaMemoryResources) {
+	for nodeID, resources := range memoryResourceCapacity {
+		if _, ok := perNUMAAllocatable[nodeID]; !ok {
+			perNUMAAllocatable[nodeID] = make(map[corev1.ResourceName]int64)
+		}
+		for resName, capacity := range resources {
+			// Skip zero-sized hugepages pools
+			if capacity == 0 {
+				continue
+			}
+			perNUMAAllocatable[nodeID][resName] = capacity
+		}
+	}
+}
+
 func MakeLogicalCoreIDToNodeIDMap(topo *ghw.TopologyInfo) map[int]int {
 	core2node := make(map[int]int)
 	for _, node := range topo.Nodes {
@@ -350,17 +408,61 @@ func makeCostsPerNumaNode(nodes []*ghw.TopologyNode, nodeIDSrc int) ([]topologyv
 	if nodeSrc == nil {
 		return nil, fmt.Errorf("unknown node: %d", nodeIDSrc)
 	}
-	nodeCosts := make([]topologyv1alpha2.CostInfo, 0)
-	for nodeIDDst, dist := range nodeSrc.Distances {
-		// TODO: this assumes there are no holes (= no offline node) in the distance vector
+	// The distance vector from sysfs has an entry for each online node in
+	// ascending order of node ID, which may have holes
+	nodeIDs := make([]int, len(nodes))
+	for i, node := range nodes {
+		nodeIDs[i] = node.ID
+	}
+	sort.Ints(nodeIDs)
+	if len(nodeSrc.Distances) != len(nodeIDs) {
+		return nil, fmt.Errorf("distance vector of node %d has %d entries, expected %d", nodeIDSrc, len(nodeSrc.Distances), len(nodeIDs))
+	}
+	nodeCosts := make([]topologyv1alpha2.CostInfo, 0, len(nodeIDs))
+	for i, dist := range nodeSrc.Distances {
 		nodeCosts = append(nodeCosts, topologyv1alpha2.CostInfo{
-			Name:  makeZoneName(nodeIDDst),
+			Name:  makeZoneName(nodeIDs[i]),
 			Value: int64(dist),
 		})
 	}
 	return nodeCosts, nil
 }
 
+// makeZoneAttributes builds the attributes of the NUMA zones from the
+// hardware topology, i.e. the L3 cache domains of each NUMA node. NUMA nodes
+// without L3 caches (e.g. memory-only nodes) have no attributes.
+func makeZoneAttributes(nodes []*ghw.TopologyNode) map[int]topologyv1alpha2.AttributeList {
+	zoneAttributes := make(map[int]topologyv1alpha2.AttributeList)
+	for _, node := range nodes {
+		var domains, sizes []string
+		for _, cache := range node.Caches {
+			if cache.Level != 3 || cache.Type == ghwmemory.CACHE_TYPE_INSTRUCTION {
+				continue
+			}
+			cpus := make([]int, len(cache.LogicalProcessors))
+			for i, cpu := range cache.LogicalProcessors {
+				cpus[i] = int(cpu)
+			}
+			domains = append(domains, cpuset.New(cpus...).String())
+			sizes = append(sizes, strconv.FormatUint(cache.SizeBytes, 10))
+		}
+		if len(domains) == 0 {
+			continue
+		}
+		zoneAttributes[node.ID] = topologyv1alpha2.AttributeList{
+			{
+				Name:  ZoneL3CacheDomainsAttributeName,
+				Value: strings.Join(domains, ";"),
+			},
+			{
+				Name:  ZoneL3CacheSizesAttributeName,
+				Value: strings.Join(sizes, ";"),
+			},
+		}
+	}
+	return zoneAttributes
+}
+
 func findNodeByID(nodes []*ghw.TopologyNode, nodeID int) *ghw.TopologyNode {
 	for _, node := range nodes {
 		if node.ID == nodeID {
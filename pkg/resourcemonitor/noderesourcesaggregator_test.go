@@ -24,6 +24,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/jaypipes/ghw"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
 	. "github.com/smartystreets/goconvey/convey"
 
 	corev1 "k8s.io/api/core/v1"
@@ -178,7 +179,7 @@ func TestResourcesAggregator(t *testing.T) {
 				corev1.ResourceName("hugepages-2Mi"): 2048,
 			},
 		}
-		resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, NewExcludeResourceList(map[string][]string{}, ""))
+		resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, nil, NewExcludeResourceList(map[string][]string{}, ""))
 
 		Convey("When aggregating resources", func() {
 			expected := topologyv1alpha2.ZoneList{
@@ -376,7 +377,7 @@ func TestResourcesAggregator(t *testing.T) {
 			},
 		}
 
-		resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, NewExcludeResourceList(map[string][]string{}, ""))
+		resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, nil, NewExcludeResourceList(map[string][]string{}, ""))
 
 		Convey("When aggregating resources", func() {
 			podRes := []PodResources{
@@ -515,6 +516,121 @@ func TestResourcesAggregator(t *testing.T) {
 		})
 	})
 
+	Convey("When I aggregate the node resources fake data and memory manager reports no memory", t, func() {
+		availRes := &v1.AllocatableResourcesResponse{}
+
+		memoryResourcesCapacity := utils.NumaMemoryResources{
+			0: map[corev1.ResourceName]int64{
+				corev1.ResourceMemory:                2048,
+				corev1.ResourceName("hugepages-2Mi"): 0,
+			},
+			1: map[corev1.ResourceName]int64{
+				corev1.ResourceMemory:                2048,
+				corev1.ResourceName("hugepages-2Mi"): 1024,
+			},
+		}
+		resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, nil, NewExcludeResourceList(map[string][]string{}, ""))
+
+		Convey("When aggregating resources", func() {
+			res := resAggr.Aggregate(nil)
+			sort.Slice(res, func(i, j int) bool {
+				return res[i].Name < res[j].Name
+			})
+
+			resources := make(map[string]map[string]*topologyv1alpha2.ResourceInfo)
+			for _, zone := range res {
+				resources[zone.Name] = make(map[string]*topologyv1alpha2.ResourceInfo)
+				for i := range zone.Resources {
+					resources[zone.Name][zone.Resources[i].Name] = &zone.Resources[i]
+				}
+			}
+
+			So(resources["node-0"]["memory"].Capacity.Value(), ShouldEqual, 2048)
+			So(resources["node-0"]["memory"].Allocatable.Value(), ShouldEqual, 2048)
+			So(resources["node-0"], ShouldNotContainKey, "hugepages-2Mi")
+			So(resources["node-1"]["memory"].Available.Value(), ShouldEqual, 2048)
+			So(resources["node-1"]["hugepages-2Mi"].Capacity.Value(), ShouldEqual, 1024)
+			// All CPUs are reserved
+			So(resources["node-0"]["cpu"].Allocatable.Value(), ShouldEqual, 0)
+			So(resources["node-1"]["cpu"].Allocatable.Value(), ShouldEqual, 0)
+			So(resources["node-1"]["cpu"].Capacity.Value(), ShouldEqual, 12)
+		})
+
+		Convey("When the memory manager reserves memory per NUMA node", func() {
+			memoryResourcesReserved := utils.NumaMemoryResources{
+				0: map[corev1.ResourceName]int64{corev1.ResourceMemory: 512},
+				1: map[corev1.ResourceName]int64{corev1.ResourceMemory: 256, corev1.ResourceName("hugepages-2Mi"): 0},
+			}
+			resAggr = NewResourcesAggregatorFromData(&fakeTopo, availRes, memoryResourcesCapacity, memoryResourcesReserved, NewExcludeResourceList(map[string][]string{}, ""))
+			res := resAggr.Aggregate(nil)
+
+			resources := make(map[string]map[string]*topologyv1alpha2.ResourceInfo)
+			for _, zone := range res {
+				resources[zone.Name] = make(map[string]*topologyv1alpha2.ResourceInfo)
+				for i := range zone.Resources {
+					resources[zone.Name][zone.Resources[i].Name] = &zone.Resources[i]
+				}
+			}
+
+			So(resources["node-0"]["memory"].Capacity.Value(), ShouldEqual, 2048)
+			So(resources["node-0"]["memory"].Allocatable.Value(), ShouldEqual, 1536)
+			So(resources["node-0"]["memory"].Available.Value(), ShouldEqual, 1536)
+			So(resources["node-1"]["memory"].Allocatable.Value(), ShouldEqual, 1792)
+			So(resources["node-1"]["hugepages-2Mi"].Allocatable.Value(), ShouldEqual, 1024)
+		})
+	})
+
+}
+
+func TestZoneTopology(t *testing.T) {
+	// NUMA node 1 is offline and node 3 is a memory-only node
+	nodes := []*ghw.TopologyNode{
+		{
+			ID: 0,
+			Caches: []*ghwmemory.Cache{
+				{Level: 2, Type: ghwmemory.CACHE_TYPE_UNIFIED, SizeBytes: 1 << 20, LogicalProcessors: []uint32{0, 4}},
+				{Level: 3, Type: ghwmemory.CACHE_TYPE_UNIFIED, SizeBytes: 32 << 20, LogicalProcessors: []uint32{0, 1, 4, 5}},
+				{Level: 3, Type: ghwmemory.CACHE_TYPE_UNIFIED, SizeBytes: 16 << 20, LogicalProcessors: []uint32{2, 3, 6, 7}},
+			},
+			Distances: []int{10, 21, 30},
+		},
+		{
+			ID:        2,
+			Caches:    []*ghwmemory.Cache{{Level: 3, Type: ghwmemory.CACHE_TYPE_UNIFIED, SizeBytes: 32 << 20, LogicalProcessors: []uint32{8, 9, 10, 11}}},
+			Distances: []int{21, 10, 30},
+		},
+		{
+			ID:        3,
+			Distances: []int{30, 30, 10},
+		},
+	}
+
+	Convey("When building the costs of the NUMA zones", t, func() {
+		costs, err := makeCostsPerNumaNode(nodes, 2)
+		So(err, ShouldBeNil)
+		So(costs, ShouldResemble, []topologyv1alpha2.CostInfo{
+			{Name: "node-0", Value: 21},
+			{Name: "node-2", Value: 10},
+			{Name: "node-3", Value: 30},
+		})
+
+		_, err = makeCostsPerNumaNode(nodes, 1)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("When building the attributes of the NUMA zones", t, func() {
+		attrs := makeZoneAttributes(nodes)
+		So(attrs, ShouldResemble, map[int]topologyv1alpha2.AttributeList{
+			0: {
+				{Name: ZoneL3CacheDomainsAttributeName, Value: "0-1,4-5;2-3,6-7"},
+				{Name: ZoneL3CacheSizesAttributeName, Value: "33554432;16777216"},
+			},
+			2: {
+				{Name: ZoneL3CacheDomainsAttributeName, Value: "8-11"},
+				{Name: ZoneL3CacheSizesAttributeName, Value: "33554432"},
+			},
+		})
+	})
 }
 
 // ghwc topology -f json
@@ -32,6 +32,9 @@ type Args struct {
 	KubeletConfigURI      string
 	APIAuthTokenFile      string
 	PodSetFingerprint     bool
+	// MemoryManagerStateFile is the state file of the kubelet memory
+	// manager, used for the memory reserved per NUMA node.
+	MemoryManagerStateFile string
 }
 
 // ResourceInfo stores information of resources and their corresponding IDs obtained from PodResource API
@@ -0,0 +1,395 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schedulerplugin implements the NodeFeatures scheduler framework
+// plugin. The plugin filters and scores nodes by evaluating the node feature
+// requirements of pods directly against the NodeFeature objects, without the
+// features needing to be published as node labels.
+package schedulerplugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/helper"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1/nodefeaturerule"
+	"github.com/openshift/node-feature-discovery/pkg/apis/nfd/validate"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdinformers "github.com/openshift/node-feature-discovery/pkg/generated/informers/externalversions"
+)
+
+const (
+	// Name is the name of the plugin used in the scheduler configuration.
+	Name = "NodeFeatures"
+
+	stateKey framework.StateKey = Name
+
+	// nodeNameIndex is the name of the informer index of NodeFeature objects
+	// by node name.
+	nodeNameIndex = "nodeName"
+
+	// maxPreferredWeight is the maximum weight of a preferred requirement.
+	maxPreferredWeight = 100
+)
+
+// Args are the arguments of the plugin.
+type Args struct {
+	// Namespace restricts the NodeFeature objects considered to one
+	// namespace, all namespaces if empty. Restricting to the namespace of
+	// nfd-worker is recommended where other users can create NodeFeature
+	// objects.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// FeatureRequirements are the node feature requirements of a pod, specified
+// in the PodFeatureRequirementsAnnotation annotation. The matchers are the
+// same as in NodeFeatureRule rules.
+type FeatureRequirements struct {
+	// Required must be satisfied by the node for the pod to be scheduled
+	// on it.
+	// +optional
+	Required *FeatureMatcher `json:"required,omitempty"`
+	// Preferred requirements increase the score of the nodes satisfying
+	// them by their weight.
+	// +optional
+	Preferred []WeightedFeatureMatcher `json:"preferred,omitempty"`
+}
+
+// FeatureMatcher is a set of feature matchers.
+type FeatureMatcher struct {
+	// MatchFeatures specifies a set of matcher terms all of which must match.
+	// +optional
+	MatchFeatures nfdv1alpha1.FeatureMatcher `json:"matchFeatures,omitempty"`
+	// MatchAny specifies a list of matchers one of which must match.
+	// +optional
+	MatchAny []nfdv1alpha1.MatchAnyElem `json:"matchAny,omitempty"`
+}
+
+// WeightedFeatureMatcher is a preferred feature matcher with a weight.
+type WeightedFeatureMatcher struct {
+	// Weight in the range 1-100.
+	Weight int64 `json:"weight"`
+	FeatureMatcher `json:",inline"`
+}
+
+// NodeFeatures is the scheduler plugin.
+type NodeFeatures struct {
+	namespace string
+	indexer   cache.Indexer
+}
+
+var (
+	_ framework.PreFilterPlugin   = &NodeFeatures{}
+	_ framework.FilterPlugin      = &NodeFeatures{}
+	_ framework.PreScorePlugin    = &NodeFeatures{}
+	_ framework.ScorePlugin       = &NodeFeatures{}
+	_ framework.EnqueueExtensions = &NodeFeatures{}
+)
+
+// New creates a new instance of the plugin. It is the plugin factory to
+// register in the scheduler, e.g. with app.WithPlugin(Name, New).
+func New(ctx context.Context, obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
+	args := Args{}
+	if obj != nil {
+		if err := frameworkruntime.DecodeInto(obj, &args); err != nil {
+			return nil, fmt.Errorf("invalid %s plugin args: %w", Name, err)
+		}
+	}
+
+	cli, err := nfdclientset.NewForConfig(h.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	informerFactory := nfdinformers.NewSharedInformerFactoryWithOptions(cli, 0, nfdinformers.WithNamespace(args.Namespace))
+	informer := informerFactory.Nfd().V1alpha1().NodeFeatures().Informer()
+	if err := informer.AddIndexers(cache.Indexers{nodeNameIndex: nodeNameIndexFunc}); err != nil {
+		return nil, err
+	}
+
+	informerFactory.Start(ctx.Done())
+	for typ, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return nil, fmt.Errorf("failed to sync %v informer cache", typ)
+		}
+	}
+
+	return newNodeFeatures(args, informer.GetIndexer()), nil
+}
+
+func newNodeFeatures(args Args, indexer cache.Indexer) *NodeFeatures {
+	return &NodeFeatures{namespace: args.Namespace, indexer: indexer}
+}
+
+// nodeNameIndexFunc indexes NodeFeature objects by the node they target.
+func nodeNameIndexFunc(obj interface{}) ([]string, error) {
+	nf, ok := obj.(*nfdv1alpha1.NodeFeature)
+	if !ok {
+		return nil, nil
+	}
+	if name := nf.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]; name != "" {
+		return []string{name}, nil
+	}
+	return nil, nil
+}
+
+// Name returns the name of the plugin.
+func (p *NodeFeatures) Name() string {
+	return Name
+}
+
+// preFilterState holds the compiled requirements of the pod being
+// scheduled.
+type preFilterState struct {
+	required  *nodefeaturerule.CompiledRule
+	preferred []weightedRule
+}
+
+type weightedRule struct {
+	weight int64
+	rule   *nodefeaturerule.CompiledRule
+}
+
+// Clone implements framework.StateData. The state is not modified after
+// PreFilter so it is safe to share.
+func (s *preFilterState) Clone() framework.StateData {
+	return s
+}
+
+// ParseFeatureRequirements parses and validates the feature requirements of
+// a pod. It returns nil if the pod has no requirements.
+func ParseFeatureRequirements(pod *corev1.Pod) (*FeatureRequirements, error) {
+	v, ok := pod.Annotations[nfdv1alpha1.PodFeatureRequirementsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	reqs := &FeatureRequirements{}
+	if err := yaml.UnmarshalStrict([]byte(v), reqs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation: %w", nfdv1alpha1.PodFeatureRequirementsAnnotation, err)
+	}
+
+	var errs []error
+	if reqs.Required != nil {
+		errs = append(errs, validateMatcher(reqs.Required)...)
+	}
+	for i, p := range reqs.Preferred {
+		if p.Weight < 1 || p.Weight > maxPreferredWeight {
+			errs = append(errs, fmt.Errorf("preferred[%d]: weight must be in the range 1-%d", i, maxPreferredWeight))
+		}
+		errs = append(errs, validateMatcher(&p.FeatureMatcher)...)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid %s annotation: %v", nfdv1alpha1.PodFeatureRequirementsAnnotation, errs)
+	}
+	return reqs, nil
+}
+
+func validateMatcher(m *FeatureMatcher) []error {
+	if len(m.MatchFeatures) == 0 && len(m.MatchAny) == 0 {
+		return []error{fmt.Errorf("matchFeatures or matchAny must be specified")}
+	}
+	return append(validate.MatchFeatures(m.MatchFeatures), validate.MatchAny(m.MatchAny)...)
+}
+
+func (m *FeatureMatcher) compile() (*nodefeaturerule.CompiledRule, error) {
+	return nodefeaturerule.Compile(&nfdv1alpha1.Rule{
+		Name:          Name,
+		MatchFeatures: m.MatchFeatures,
+		MatchAny:      m.MatchAny,
+	})
+}
+
+// PreFilter parses the feature requirements of the pod. The plugin is
+// skipped for pods without requirements.
+func (p *NodeFeatures) PreFilter(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	reqs, err := ParseFeatureRequirements(pod)
+	if err != nil {
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	if reqs == nil {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+
+	s := &preFilterState{}
+	if reqs.Required != nil {
+		if s.required, err = reqs.Required.compile(); err != nil {
+			return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+		}
+	}
+	for _, pref := range reqs.Preferred {
+		r, err := pref.compile()
+		if err != nil {
+			return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+		}
+		s.preferred = append(s.preferred, weightedRule{weight: pref.Weight, rule: r})
+	}
+	cycleState.Write(stateKey, s)
+	return nil, nil
+}
+
+// PreFilterExtensions returns nil, the requirements do not depend on other
+// pods.
+func (p *NodeFeatures) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+func getPreFilterState(cycleState *framework.CycleState) (*preFilterState, error) {
+	c, err := cycleState.Read(stateKey)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := c.(*preFilterState)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s state type %T", Name, c)
+	}
+	return s, nil
+}
+
+// Filter rejects nodes that do not satisfy the required features of the
+// pod.
+func (p *NodeFeatures) Filter(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if s.required == nil {
+		return nil
+	}
+
+	node := nodeInfo.Node()
+	if node == nil {
+		return framework.NewStatus(framework.Error, "node not found")
+	}
+	features, err := p.nodeFeatures(node.Name)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if features == nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "node has no NodeFeature objects")
+	}
+
+	out, err := s.required.Evaluate(features)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("failed to evaluate feature requirements: %v", err))
+	}
+	if !out.Matched {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "node does not have the required features")
+	}
+	return nil
+}
+
+// PreScore skips scoring for pods without preferred features.
+func (p *NodeFeatures) PreScore(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod, nodes []*corev1.Node) *framework.Status {
+	s, err := getPreFilterState(cycleState)
+	if err != nil || len(s.preferred) == 0 {
+		return framework.NewStatus(framework.Skip)
+	}
+	return nil
+}
+
+// Score returns the sum of the weights of the preferred requirements the
+// node satisfies.
+func (p *NodeFeatures) Score(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod, nodeName string) (int64, *framework.Status) {
+	s, err := getPreFilterState(cycleState)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	features, err := p.nodeFeatures(nodeName)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+	if features == nil {
+		return 0, nil
+	}
+
+	var score int64
+	for _, pref := range s.preferred {
+		out, err := pref.rule.Evaluate(features)
+		if err != nil {
+			klog.FromContext(ctx).V(4).Info("failed to evaluate preferred feature requirement", "pod", klog.KObj(pod), "node", nodeName, "err", err)
+			continue
+		}
+		if out.Matched {
+			score += pref.weight
+		}
+	}
+	return score, nil
+}
+
+// ScoreExtensions returns the score normalization of the plugin.
+func (p *NodeFeatures) ScoreExtensions() framework.ScoreExtensions {
+	return p
+}
+
+// NormalizeScore scales the scores to the range of the framework.
+func (p *NodeFeatures) NormalizeScore(ctx context.Context, cycleState *framework.CycleState, pod *corev1.Pod, scores framework.NodeScoreList) *framework.Status {
+	return helper.DefaultNormalizeScore(framework.MaxNodeScore, false, scores)
+}
+
+// EventsToRegister returns the events that may make pods rejected by the
+// plugin schedulable: changes in the NodeFeature objects and new nodes.
+func (p *NodeFeatures) EventsToRegister() []framework.ClusterEventWithHint {
+	nodeFeatureGVK := framework.GVK("nodefeatures." + nfdv1alpha1.SchemeGroupVersion.Version + "." + nfdv1alpha1.SchemeGroupVersion.Group)
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: nodeFeatureGVK, ActionType: framework.Add | framework.Update}},
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add}},
+	}
+}
+
+// nodeFeatures returns the merged features of all NodeFeature objects of a
+// node, nil if there are none. Objects are merged in the order of their
+// namespace and name, later objects taking precedence.
+func (p *NodeFeatures) nodeFeatures(nodeName string) (*nfdv1alpha1.Features, error) {
+	objs, err := p.indexer.ByIndex(nodeNameIndex, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	nfs := make([]*nfdv1alpha1.NodeFeature, 0, len(objs))
+	for _, o := range objs {
+		if nf, ok := o.(*nfdv1alpha1.NodeFeature); ok && nf.DeletionTimestamp == nil {
+			nfs = append(nfs, nf)
+		}
+	}
+	if len(nfs) == 0 {
+		return nil, nil
+	}
+	sort.Slice(nfs, func(i, j int) bool {
+		if nfs[i].Namespace != nfs[j].Namespace {
+			return nfs[i].Namespace < nfs[j].Namespace
+		}
+		return nfs[i].Name < nfs[j].Name
+	})
+
+	if len(nfs) == 1 {
+		// Evaluation does not modify the features, no need to copy
+		return &nfs[0].Spec.Features, nil
+	}
+	spec := nfs[0].Spec.DeepCopy()
+	for _, nf := range nfs[1:] {
+		nf.Spec.DeepCopy().MergeInto(spec)
+	}
+	return &spec.Features, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schedulerplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func newTestPlugin(t *testing.T) *NodeFeatures {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{nodeNameIndex: nodeNameIndexFunc})
+	add := func(name, nodeName string, kernelMajor string, flags ...string) {
+		nf := &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "nfd",
+				Labels:    map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+			},
+			Spec: *nfdv1alpha1.NewNodeFeatureSpec(),
+		}
+		if kernelMajor != "" {
+			nf.Spec.Features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": kernelMajor})
+		}
+		if len(flags) > 0 {
+			nf.Spec.Features.Flags["cpu.cpuid"] = nfdv1alpha1.NewFlagFeatures(flags...)
+		}
+		assert.NoError(t, indexer.Add(nf))
+	}
+	add("node-1", "node-1", "6", "AVX512_VNNI")
+	add("node-2", "node-2", "5", "AVX512_VNNI")
+	// Features of node-3 are split across two objects
+	add("node-3", "node-3", "6")
+	add("node-3-cpu", "node-3", "", "AVX2")
+
+	return newNodeFeatures(Args{}, indexer)
+}
+
+func newTestPod(requirements string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	if requirements != "" {
+		pod.Annotations = map[string]string{nfdv1alpha1.PodFeatureRequirementsAnnotation: requirements}
+	}
+	return pod
+}
+
+func newNodeInfo(name string) *framework.NodeInfo {
+	ni := framework.NewNodeInfo()
+	ni.SetNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	return ni
+}
+
+const testRequirements = `
+required:
+  matchFeatures:
+    - feature: cpu.cpuid
+      matchExpressions:
+        AVX512_VNNI: {op: Exists}
+preferred:
+  - weight: 50
+    matchFeatures:
+      - feature: kernel.version
+        matchExpressions:
+          major: {op: Gt, value: ["5"]}
+`
+
+func TestFilter(t *testing.T) {
+	p := newTestPlugin(t)
+	ctx := context.Background()
+
+	// Pods without requirements skip the plugin
+	_, status := p.PreFilter(ctx, framework.NewCycleState(), newTestPod(""))
+	assert.Equal(t, framework.Skip, status.Code())
+
+	// Invalid requirements make the pod unschedulable
+	_, status = p.PreFilter(ctx, framework.NewCycleState(), newTestPod("required: {}"))
+	assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+	_, status = p.PreFilter(ctx, framework.NewCycleState(), newTestPod("foo: bar"))
+	assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+
+	state := framework.NewCycleState()
+	_, status = p.PreFilter(ctx, state, newTestPod(testRequirements))
+	assert.True(t, status.IsSuccess(), status.Message())
+
+	for node, expected := range map[string]framework.Code{
+		"node-1": framework.Success,
+		"node-2": framework.Success,
+		"node-3": framework.UnschedulableAndUnresolvable,
+		"no-nfd": framework.UnschedulableAndUnresolvable,
+	} {
+		status := p.Filter(ctx, state, newTestPod(testRequirements), newNodeInfo(node))
+		assert.Equal(t, expected, status.Code(), node)
+	}
+}
+
+func TestScore(t *testing.T) {
+	p := newTestPlugin(t)
+	ctx := context.Background()
+	pod := newTestPod(testRequirements)
+
+	state := framework.NewCycleState()
+	_, status := p.PreFilter(ctx, state, pod)
+	assert.True(t, status.IsSuccess(), status.Message())
+	assert.True(t, p.PreScore(ctx, state, pod, nil).IsSuccess())
+
+	scores := framework.NodeScoreList{}
+	for _, node := range []string{"node-1", "node-2", "node-3"} {
+		score, status := p.Score(ctx, state, pod, node)
+		assert.True(t, status.IsSuccess(), status.Message())
+		scores = append(scores, framework.NodeScore{Name: node, Score: score})
+	}
+	assert.Equal(t, framework.NodeScoreList{{Name: "node-1", Score: 50}, {Name: "node-2", Score: 0}, {Name: "node-3", Score: 50}}, scores)
+
+	assert.True(t, p.NormalizeScore(ctx, state, pod, scores).IsSuccess())
+	assert.Equal(t, framework.MaxNodeScore, scores[0].Score)
+	assert.Equal(t, int64(0), scores[1].Score)
+
+	// Pods without preferred requirements are not scored
+	state = framework.NewCycleState()
+	pod = newTestPod(`{"required": {"matchFeatures": [{"feature": "cpu.cpuid"}]}}`)
+	_, status = p.PreFilter(ctx, state, pod)
+	assert.True(t, status.IsSuccess(), status.Message())
+	assert.Equal(t, framework.Skip, p.PreScore(ctx, state, pod, nil).Code())
+}
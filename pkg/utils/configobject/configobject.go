@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configobject
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	nfdclientset "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned"
+	nfdinformers "github.com/openshift/node-feature-discovery/pkg/generated/informers/externalversions/nfd/v1alpha1"
+	nfdlisters "github.com/openshift/node-feature-discovery/pkg/generated/listers/nfd/v1alpha1"
+)
+
+const (
+	// ReasonConfigApplied is the condition reason of a valid configuration.
+	ReasonConfigApplied = "ConfigApplied"
+	// ReasonInvalidConfig is the condition reason of an invalid
+	// configuration.
+	ReasonInvalidConfig = "InvalidConfig"
+)
+
+// Component selects the part of the NFDConfig object used by a daemon.
+type Component struct {
+	// Condition is the status condition maintained by the daemon.
+	Condition string
+	// Spec returns the configuration of the daemon from the object spec.
+	Spec func(*nfdv1alpha1.NFDConfigSpec) *runtime.RawExtension
+}
+
+var (
+	// Master is the nfd-master part of the NFDConfig object.
+	Master = Component{
+		Condition: nfdv1alpha1.NFDConfigMasterValidCondition,
+		Spec:      func(s *nfdv1alpha1.NFDConfigSpec) *runtime.RawExtension { return s.Master },
+	}
+	// Worker is the nfd-worker part of the NFDConfig object.
+	Worker = Component{
+		Condition: nfdv1alpha1.NFDConfigWorkerValidCondition,
+		Spec:      func(s *nfdv1alpha1.NFDConfigSpec) *runtime.RawExtension { return s.Worker },
+	}
+)
+
+// Watcher follows one (cluster-scoped) NFDConfig object and reports the
+// result of applying its configuration in the status of the object.
+type Watcher struct {
+	events    chan struct{}
+	client    nfdclientset.Interface
+	lister    nfdlisters.NFDConfigLister
+	name      string
+	component Component
+	stop      chan struct{}
+}
+
+// NewWatcher creates a watcher for the NFDConfig object with the given name
+// and waits for the initial sync of its cache.
+func NewWatcher(kubeconfig *restclient.Config, name string, component Component) (*Watcher, error) {
+	cli, err := nfdclientset.NewForConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return newWatcher(cli, name, component)
+}
+
+func newWatcher(cli nfdclientset.Interface, name string, component Component) (*Watcher, error) {
+	w := &Watcher{
+		events:    make(chan struct{}, 1),
+		client:    cli,
+		name:      name,
+		component: component,
+		stop:      make(chan struct{}),
+	}
+
+	informer := nfdinformers.NewFilteredNFDConfigInformer(cli, 0, cache.Indexers{}, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	})
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { w.notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Status updates don't change the generation
+			if oldObj.(*nfdv1alpha1.NFDConfig).Generation != newObj.(*nfdv1alpha1.NFDConfig).Generation {
+				w.notify()
+			}
+		},
+		DeleteFunc: func(obj interface{}) { w.notify() },
+	}); err != nil {
+		return nil, err
+	}
+	w.lister = nfdlisters.NewNFDConfigLister(informer.GetIndexer())
+
+	go informer.Run(w.stop)
+	if !cache.WaitForCacheSync(w.stop, informer.HasSynced) {
+		return nil, fmt.Errorf("failed to sync NFDConfig cache")
+	}
+	// The initial configuration is read explicitly, drop the event of the
+	// initial sync
+	select {
+	case <-w.events:
+	default:
+	}
+	return w, nil
+}
+
+func (w *Watcher) notify() {
+	select {
+	case w.events <- struct{}{}:
+	default:
+	}
+}
+
+// Changes returns a channel that receives a value whenever the spec of the
+// object changes or the object is created or deleted. Returns nil (a channel
+// that never receives) if the watcher is nil.
+func (w *Watcher) Changes() <-chan struct{} {
+	if w == nil {
+		return nil
+	}
+	return w.events
+}
+
+// Stop stops the watcher.
+func (w *Watcher) Stop() {
+	if w != nil {
+		close(w.stop)
+	}
+}
+
+// Config returns the configuration of the component (JSON) and the
+// generation of the object. Returns nil if the watcher is nil, the object
+// does not exist or does not have a configuration for the component.
+func (w *Watcher) Config() ([]byte, int64, error) {
+	if w == nil {
+		return nil, 0, nil
+	}
+	obj, err := w.lister.Get(w.name)
+	if errors.IsNotFound(err) {
+		klog.InfoS("NFDConfig object not found, using defaults", "nfdconfig", w.name)
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	raw := w.component.Spec(&obj.Spec)
+	if raw == nil {
+		return nil, obj.Generation, nil
+	}
+	return raw.Raw, obj.Generation, nil
+}
+
+// SetStatus updates the status condition of the component to reflect the
+// result of applying the configuration of the given generation. The object
+// is only updated if the condition changes.
+func (w *Watcher) SetStatus(generation int64, configErr error) {
+	if w == nil {
+		return
+	}
+	cond := metav1.Condition{
+		Type:               w.component.Condition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             ReasonConfigApplied,
+		Message:            "configuration applied",
+		LastTransitionTime: metav1.NewTime(time.Now()),
+	}
+	if configErr != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = ReasonInvalidConfig
+		cond.Message = configErr.Error()
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := w.client.NfdV1alpha1().NFDConfigs().Get(context.TODO(), w.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !meta.SetStatusCondition(&obj.Status.Conditions, cond) {
+			return nil
+		}
+		_, err = w.client.NfdV1alpha1().NFDConfigs().UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return
+	} else if err != nil {
+		klog.ErrorS(err, "failed to update NFDConfig status", "nfdconfig", w.name)
+	}
+}
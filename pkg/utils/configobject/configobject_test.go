@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configobject
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	fakenfdclient "github.com/openshift/node-feature-discovery/pkg/generated/clientset/versioned/fake"
+)
+
+func TestWatcher(t *testing.T) {
+	Convey("When watching an NFDConfig object", t, func() {
+		obj := &nfdv1alpha1.NFDConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "nfd", Generation: 1},
+			Spec: nfdv1alpha1.NFDConfigSpec{
+				Master: &runtime.RawExtension{Raw: []byte(`{"noPublish":true}`)},
+			},
+		}
+		cli := fakenfdclient.NewSimpleClientset(obj)
+
+		w, err := newWatcher(cli, "nfd", Master)
+		So(err, ShouldBeNil)
+		defer w.Stop()
+
+		Convey("The configuration of the component should be returned", func() {
+			data, generation, err := w.Config()
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"noPublish":true}`)
+			So(generation, ShouldEqual, 1)
+		})
+
+		Convey("Nothing should be returned for a component without configuration", func() {
+			w, err := newWatcher(cli, "nfd", Worker)
+			So(err, ShouldBeNil)
+			defer w.Stop()
+			data, _, err := w.Config()
+			So(err, ShouldBeNil)
+			So(data, ShouldBeNil)
+		})
+
+		Convey("Spec changes should be notified", func() {
+			obj.Generation = 2
+			_, err := cli.NfdV1alpha1().NFDConfigs().Update(context.TODO(), obj, metav1.UpdateOptions{})
+			So(err, ShouldBeNil)
+			select {
+			case <-w.Changes():
+			case <-time.After(5 * time.Second):
+				t.Fatal("change not notified")
+			}
+		})
+
+		Convey("The status condition should be updated", func() {
+			w.SetStatus(1, errors.New("invalid"))
+			updated, err := cli.NfdV1alpha1().NFDConfigs().Get(context.TODO(), "nfd", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			cond := meta.FindStatusCondition(updated.Status.Conditions, nfdv1alpha1.NFDConfigMasterValidCondition)
+			So(cond, ShouldNotBeNil)
+			So(cond.Status, ShouldEqual, metav1.ConditionFalse)
+			So(cond.Reason, ShouldEqual, ReasonInvalidConfig)
+			So(cond.Message, ShouldEqual, "invalid")
+
+			w.SetStatus(1, nil)
+			updated, err = cli.NfdV1alpha1().NFDConfigs().Get(context.TODO(), "nfd", metav1.GetOptions{})
+			So(err, ShouldBeNil)
+			So(meta.IsStatusConditionTrue(updated.Status.Conditions, nfdv1alpha1.NFDConfigMasterValidCondition), ShouldBeTrue)
+		})
+	})
+
+	Convey("A nil watcher should be a no-op", t, func() {
+		var w *Watcher
+		data, _, err := w.Config()
+		So(err, ShouldBeNil)
+		So(data, ShouldBeNil)
+		So(w.Changes(), ShouldBeNil)
+		w.SetStatus(1, nil)
+		w.Stop()
+	})
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashedValueLen is the length of hashed values, short enough to fit in a
+// label value.
+const HashedValueLen = 32
+
+// HashValue returns a truncated, hex-encoded HMAC-SHA256 of the value, using
+// the salt as the key.
+func HashValue(salt []byte, value string) string {
+	h := hmac.New(sha256.New, salt)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))[:HashedValueLen]
+}
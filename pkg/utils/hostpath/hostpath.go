@@ -24,6 +24,8 @@ var (
 	pathPrefix = "/host-"
 	// BootPath is where the /boot directory of the system to be inspected is located
 	BootDir = HostDir(pathPrefix + "boot")
+	// DevDir is where the /dev directory of the system to be inspected is located
+	DevDir = HostDir(pathPrefix + "dev")
 	// EtcDir is where the /etc directory of the system to be inspected is located
 	EtcDir = HostDir(pathPrefix + "etc")
 	// SysfsDir is where the /sys directory of the system to be inspected is located
@@ -34,8 +36,40 @@ var (
 	VarDir = HostDir(pathPrefix + "var")
 	// LibDir is where the /lib directory of the system to be inspected is located
 	LibDir = HostDir(pathPrefix + "lib")
+	// OstreeDir is where the /ostree directory of the system to be inspected is located
+	OstreeDir = HostDir(pathPrefix + "ostree")
+	// ProcfsDir is where the /proc directory of the system to be inspected is located
+	ProcfsDir = HostDir("/proc")
 )
 
+// SetRoot makes all host directories point to the corresponding
+// subdirectories (e.g. <root>/sys) of the given root directory. It is intended
+// for running feature sources against a recorded filesystem tree. An empty
+// root restores the defaults.
+func SetRoot(root string) {
+	if root == "" {
+		BootDir = HostDir(pathPrefix + "boot")
+		DevDir = HostDir(pathPrefix + "dev")
+		EtcDir = HostDir(pathPrefix + "etc")
+		SysfsDir = HostDir(pathPrefix + "sys")
+		UsrDir = HostDir(pathPrefix + "usr")
+		VarDir = HostDir(pathPrefix + "var")
+		LibDir = HostDir(pathPrefix + "lib")
+		OstreeDir = HostDir(pathPrefix + "ostree")
+		ProcfsDir = HostDir("/proc")
+		return
+	}
+	BootDir = HostDir(filepath.Join(root, "boot"))
+	DevDir = HostDir(filepath.Join(root, "dev"))
+	EtcDir = HostDir(filepath.Join(root, "etc"))
+	SysfsDir = HostDir(filepath.Join(root, "sys"))
+	UsrDir = HostDir(filepath.Join(root, "usr"))
+	VarDir = HostDir(filepath.Join(root, "var"))
+	LibDir = HostDir(filepath.Join(root, "lib"))
+	OstreeDir = HostDir(filepath.Join(root, "ostree"))
+	ProcfsDir = HostDir(filepath.Join(root, "proc"))
+}
+
 // HostDir is a helper for handling host system directories
 type HostDir string
 
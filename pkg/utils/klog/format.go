@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klog
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// LoggingFormatText is the default klog text format.
+	LoggingFormatText = "text"
+	// LoggingFormatJSON writes one JSON object per log entry, with the
+	// key-value pairs of structured log calls as fields.
+	LoggingFormatJSON = "json"
+)
+
+// InitLoggingFormatFlag registers the -logging-format flag.
+func InitLoggingFormatFlag(flagset *flag.FlagSet) *string {
+	return flagset.String("logging-format", LoggingFormatText,
+		"Format of the log output. Supported formats are \"text\" and \"json\".")
+}
+
+// SetLoggingFormat makes klog write log entries in the given format. It
+// should be called right after parsing the command line flags.
+func SetLoggingFormat(format string) error {
+	switch format {
+	case LoggingFormatText:
+	case LoggingFormatJSON:
+		klog.SetLogger(newJSONLogger(os.Stderr))
+	default:
+		return fmt.Errorf("unsupported logging format %q", format)
+	}
+	return nil
+}
+
+// newJSONLogger returns a logger writing JSON objects to w. Verbosity is
+// checked by klog (the -v and -vmodule flags) so the logger itself prints
+// everything it receives.
+func newJSONLogger(w io.Writer) logr.Logger {
+	return funcr.NewJSON(func(obj string) {
+		fmt.Fprintln(w, obj)
+	}, funcr.Options{
+		LogCaller:    funcr.All,
+		LogTimestamp: true,
+		Verbosity:    math.MaxInt32,
+	})
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newJSONLogger(buf)
+
+	logger.V(2).Info("node updated", "nodeName", "node-1", "ruleName", "rule-1")
+	logger.Error(errors.New("boom"), "discovery failed", "featureSource", "cpu")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	entry := map[string]any{}
+	assert.NoError(t, json.Unmarshal(lines[0], &entry))
+	assert.Equal(t, "node updated", entry["msg"])
+	assert.Equal(t, "node-1", entry["nodeName"])
+	assert.Equal(t, "rule-1", entry["ruleName"])
+	assert.Equal(t, float64(2), entry["level"])
+	assert.Contains(t, entry, "ts")
+
+	entry = map[string]any{}
+	assert.NoError(t, json.Unmarshal(lines[1], &entry))
+	assert.Equal(t, "boom", entry["error"])
+	assert.Equal(t, "cpu", entry["featureSource"])
+}
+
+func TestSetLoggingFormat(t *testing.T) {
+	assert.NoError(t, SetLoggingFormat(LoggingFormatText))
+	assert.Error(t, SetLoggingFormat("xml"))
+}
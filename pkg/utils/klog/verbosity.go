@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klog
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// VerbosityOverrides sets the log verbosity of individual modules, e.g.
+// feature sources or rules, overriding the global verbosity (-v) for them.
+// This makes it possible to get verbose logs of one module without
+// raising the verbosity of everything else.
+type VerbosityOverrides map[string]int
+
+// Logger returns a logger with the verbosity raised to the override of the
+// first of the given modules that has one. The logger is returned as is if
+// none of the modules has an override.
+func (o VerbosityOverrides) Logger(logger logr.Logger, modules ...string) logr.Logger {
+	for _, m := range modules {
+		if v, ok := o[m]; ok {
+			return WithVerbosity(logger, v)
+		}
+	}
+	return logger
+}
+
+// WithVerbosity returns a logger that logs messages up to verbosity v
+// regardless of the verbosity of the underlying logger. Messages of higher
+// verbosity are still logged if the underlying logger has them enabled.
+func WithVerbosity(logger logr.Logger, v int) logr.Logger {
+	sink := logger.GetSink()
+	if sink == nil {
+		return logger
+	}
+	// Account for the extra call frame of verbositySink
+	if s, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = s.WithCallDepth(1)
+	}
+	return logr.New(verbositySink{LogSink: sink, verbosity: v})
+}
+
+// verbositySink passes messages up to its verbosity to the underlying sink
+// as non-verbose messages so that the sink does not filter them out.
+type verbositySink struct {
+	logr.LogSink
+	verbosity int
+}
+
+// Init is a no-op as the underlying sink has been initialized already.
+func (s verbositySink) Init(logr.RuntimeInfo) {}
+
+func (s verbositySink) Enabled(level int) bool {
+	return level <= s.verbosity || s.LogSink.Enabled(level)
+}
+
+func (s verbositySink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if level <= s.verbosity {
+		level = 0
+	}
+	s.LogSink.Info(level, msg, keysAndValues...)
+}
+
+func (s verbositySink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return verbositySink{LogSink: s.LogSink.WithValues(keysAndValues...), verbosity: s.verbosity}
+}
+
+func (s verbositySink) WithName(name string) logr.LogSink {
+	return verbositySink{LogSink: s.LogSink.WithName(name), verbosity: s.verbosity}
+}
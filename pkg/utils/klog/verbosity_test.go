@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package klog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(out *[]string) logr.Logger {
+	return funcr.New(func(prefix, args string) {
+		*out = append(*out, args)
+	}, funcr.Options{LogCaller: funcr.Info, Verbosity: 1})
+}
+
+func TestVerbosityOverrides(t *testing.T) {
+	var out []string
+	overrides := VerbosityOverrides{"cpu": 3, "custom/rule-1": 4, "custom": 2}
+
+	// Modules without an override use the verbosity of the logger
+	logger := overrides.Logger(newTestLogger(&out), "kernel")
+	logger.V(1).Info("shown")
+	logger.V(2).Info("hidden")
+	assert.Len(t, out, 1)
+
+	// The first module with an override takes precedence
+	out = nil
+	logger = overrides.Logger(newTestLogger(&out), "custom/rule-1", "custom")
+	logger.V(4).Info("shown")
+	logger.V(5).Info("hidden")
+	logger.WithValues("key", "value").V(4).Info("shown")
+	logger.WithName("name").V(5).Info("hidden")
+	assert.Len(t, out, 2)
+	assert.Contains(t, out[1], `"key"="value"`)
+
+	out = nil
+	logger = overrides.Logger(newTestLogger(&out), "custom/rule-2", "custom")
+	logger.V(2).Info("shown")
+	logger.V(3).Info("hidden")
+	assert.Len(t, out, 1)
+
+	// The caller is reported correctly
+	assert.True(t, strings.Contains(out[0], "verbosity_test.go"), out[0])
+
+	// Nil overrides do not change the logger
+	out = nil
+	logger = VerbosityOverrides(nil).Logger(newTestLogger(&out), "cpu")
+	logger.V(2).Info("hidden")
+	assert.Empty(t, out)
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	kubeletconfigv1beta1 "k8s.io/kubelet/config/v1beta1"
 )
@@ -36,11 +37,6 @@ func GetKubeletConfiguration(restConfig *rest.Config) (*kubeletconfigv1beta1.Kub
 	}
 
 	var timeout time.Duration
-	// This hack because /configz reports the following structure:
-	// {"kubeletconfig": {the JSON representation of kubeletconfigv1beta1.KubeletConfiguration}}
-	type configzWrapper struct {
-		ComponentConfig kubeletconfigv1beta1.KubeletConfiguration `json:"kubeletconfig"`
-	}
 	bytes, err := discoveryClient.RESTClient().
 		Get().
 		Timeout(timeout).
@@ -50,8 +46,38 @@ func GetKubeletConfiguration(restConfig *rest.Config) (*kubeletconfigv1beta1.Kub
 		return nil, err
 	}
 
+	return parseConfigz(bytes)
+}
+
+// GetKubeletConfigurationViaAPIServer returns the kubelet configuration of a
+// node by accessing the configz endpoint of the kubelet through the node
+// proxy of the API server. This does not require direct network access to
+// the kubelet.
+func GetKubeletConfigurationViaAPIServer(client kubernetes.Interface, nodeName string) (*kubeletconfigv1beta1.KubeletConfiguration, error) {
+	bytes, err := client.CoreV1().RESTClient().
+		Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("configz").
+		Do(context.TODO()).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseConfigz(bytes)
+}
+
+func parseConfigz(data []byte) (*kubeletconfigv1beta1.KubeletConfiguration, error) {
+	// This hack because /configz reports the following structure:
+	// {"kubeletconfig": {the JSON representation of kubeletconfigv1beta1.KubeletConfiguration}}
+	type configzWrapper struct {
+		ComponentConfig kubeletconfigv1beta1.KubeletConfiguration `json:"kubeletconfig"`
+	}
+
 	configz := configzWrapper{}
-	if err = json.Unmarshal(bytes, &configz); err != nil {
+	if err := json.Unmarshal(data, &configz); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal json for kubelet config: %w", err)
 	}
 
@@ -0,0 +1,38 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconf
+
+import (
+	"testing"
+)
+
+func TestParseConfigz(t *testing.T) {
+	cfg, err := parseConfigz([]byte(`{"kubeletconfig":{"topologyManagerPolicy":"single-numa-node","topologyManagerScope":"pod"}}`))
+	if err != nil {
+		t.Fatalf("failed to parse configz data: %v", err)
+	}
+	if cfg.TopologyManagerPolicy != "single-numa-node" {
+		t.Errorf("TM policy mismatch, found %q expected %q", cfg.TopologyManagerPolicy, "single-numa-node")
+	}
+	if cfg.TopologyManagerScope != "pod" {
+		t.Errorf("TM scope mismatch, found %q expected %q", cfg.TopologyManagerScope, "pod")
+	}
+
+	if _, err := parseConfigz([]byte(`{"kubeletconfig":`)); err == nil {
+		t.Errorf("expected error when parsing invalid data")
+	}
+}
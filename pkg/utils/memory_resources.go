@@ -69,12 +69,8 @@ func GetNumaMemoryResources() (NumaMemoryResources, error) {
 
 		// Get hugepages
 		hugepageBytes, err := getHugepagesBytes(filepath.Join(sysBusNodeBasepath, numaNode, "hugepages"))
-		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			} else {
-				return nil, err
-			}
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
 		}
 		maps.Copy(info, hugepageBytes)
 
@@ -17,16 +17,60 @@ limitations under the License.
 package utils
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	k8sclient "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
 type MetricsServer struct {
 	srv *http.Server
+	mux *http.ServeMux
+}
+
+// MetricsSecurityArgs holds the optional TLS and authentication settings of
+// the metrics server.
+type MetricsSecurityArgs struct {
+	// CertFile and KeyFile enable TLS on the metrics endpoint.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS, requiring clients to present a certificate
+	// signed by the given CA.
+	ClientCAFile string
+	// TokenAuth enables authentication and authorization of bearer tokens
+	// using the TokenReview and SubjectAccessReview APIs.
+	TokenAuth bool
+}
+
+// InitMetricsSecurityFlags registers command line flags for configuring the
+// TLS and authentication settings of the metrics server.
+func InitMetricsSecurityFlags(flagset *flag.FlagSet, args *MetricsSecurityArgs) {
+	flagset.StringVar(&args.CertFile, "metrics-cert-file", "",
+		"Certificate used for serving the metrics endpoint over TLS.")
+	flagset.StringVar(&args.KeyFile, "metrics-key-file", "",
+		"Private key matching -metrics-cert-file.")
+	flagset.StringVar(&args.ClientCAFile, "metrics-client-ca-file", "",
+		"CA certificate for verifying client certificates of metrics scrapers. Requires TLS to be enabled.")
+	flagset.BoolVar(&args.TokenAuth, "metrics-token-auth", false,
+		"Require metrics scrapers to authenticate with a bearer token that is authorized (with TokenReview and "+
+			"SubjectAccessReview) to get the metrics path. Requires TLS to be enabled and permission to create "+
+			"TokenReview and SubjectAccessReview objects.")
 }
 
 // RunMetricsServer starts a new http server to expose metrics.
@@ -36,13 +80,216 @@ func CreateMetricsServer(port int, cs ...prometheus.Collector) *MetricsServer {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(r, promhttp.HandlerOpts{}))
 
-	return &MetricsServer{srv: &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}}
+	return &MetricsServer{srv: &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}, mux: mux}
+}
+
+// Handle registers an additional handler on the metrics server. The same TLS
+// and authentication settings apply as for the metrics endpoint.
+func (s *MetricsServer) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Secure enables TLS and/or authentication on the metrics server according
+// to the given args. The kubeconfig is only used if token authentication has
+// been enabled.
+func (s *MetricsServer) Secure(args MetricsSecurityArgs, kubeconfig string) error {
+	if (args.CertFile == "") != (args.KeyFile == "") {
+		return fmt.Errorf("-metrics-cert-file and -metrics-key-file must be specified together")
+	}
+	if args.ClientCAFile != "" && args.CertFile == "" {
+		return fmt.Errorf("-metrics-client-ca-file requires -metrics-cert-file and -metrics-key-file")
+	}
+	// Don't let bearer tokens travel in plaintext
+	if args.TokenAuth && args.CertFile == "" {
+		return fmt.Errorf("-metrics-token-auth requires -metrics-cert-file and -metrics-key-file")
+	}
+
+	if args.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(args.CertFile, args.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load metrics server certificate: %w", err)
+		}
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		if args.ClientCAFile != "" {
+			caCert, err := os.ReadFile(args.ClientCAFile)
+			if err != nil {
+				return fmt.Errorf("failed to read metrics client CA file: %w", err)
+			}
+			caPool := x509.NewCertPool()
+			if ok := caPool.AppendCertsFromPEM(caCert); !ok {
+				return fmt.Errorf("failed to add certificate from '%s'", args.ClientCAFile)
+			}
+			tlsConfig.ClientCAs = caPool
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		s.srv.TLSConfig = tlsConfig
+	}
+
+	if args.TokenAuth {
+		config, err := GetKubeconfig(kubeconfig)
+		if err != nil {
+			return err
+		}
+		cli, err := k8sclient.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		s.srv.Handler = newTokenAuthHandler(cli, s.srv.Handler)
+	}
+
+	return nil
+}
+
+const (
+	// Same cache settings as the kubelet uses for webhook authentication
+	// and authorization of its endpoints
+	authnCacheTTL        = 2 * time.Minute
+	authzAllowedCacheTTL = 5 * time.Minute
+	authzDeniedCacheTTL  = 30 * time.Second
+	authCacheSize        = 1024
+)
+
+// tokenAuthenticator authenticates and authorizes bearer tokens against the
+// Kubernetes API. Results are cached so that not every request hits the API
+// server.
+type tokenAuthenticator struct {
+	cli        k8sclient.Interface
+	authnCache *cache.LRUExpireCache
+	authzCache *cache.LRUExpireCache
+}
+
+// newTokenAuthHandler wraps an http handler, only letting through requests
+// with a bearer token that is authorized to get the requested (non-resource)
+// path.
+func newTokenAuthHandler(cli k8sclient.Interface, next http.Handler) http.Handler {
+	a := &tokenAuthenticator{
+		cli:        cli,
+		authnCache: cache.NewLRUExpireCache(authCacheSize),
+		authzCache: cache.NewLRUExpireCache(authCacheSize),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.authenticate(r.Context(), token)
+		if err != nil {
+			klog.ErrorS(err, "failed to review metrics client token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if allowed, err := a.authorize(r.Context(), *user, r.URL.Path, nonResourceVerb(r.Method)); err != nil {
+			klog.ErrorS(err, "failed to authorize metrics client", "user", user.Username)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		} else if !allowed {
+			klog.V(2).InfoS("metrics client not authorized", "user", user.Username, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns the user a token belongs to, or nil if the token is
+// not valid.
+func (a *tokenAuthenticator) authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	key := sha256.Sum256([]byte(token))
+	if v, ok := a.authnCache.Get(key); ok {
+		return v.(*authenticationv1.UserInfo), nil
+	}
+
+	tr, err := a.cli.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var user *authenticationv1.UserInfo
+	if tr.Status.Authenticated {
+		user = &tr.Status.User
+	}
+	a.authnCache.Add(key, user, authnCacheTTL)
+	return user, nil
+}
+
+// authorize returns true if the user is allowed to access the given path.
+func (a *tokenAuthenticator) authorize(ctx context.Context, user authenticationv1.UserInfo, path, verb string) (bool, error) {
+	key, err := json.Marshal([]any{user, path, verb})
+	if err != nil {
+		return false, err
+	}
+	if v, ok := a.authzCache.Get(string(key)); ok {
+		return v.(bool), nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar, err := a.cli.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: verb,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	ttl := authzDeniedCacheTTL
+	if sar.Status.Allowed {
+		ttl = authzAllowedCacheTTL
+	}
+	a.authzCache.Add(string(key), sar.Status.Allowed, ttl)
+	return sar.Status.Allowed, nil
+}
+
+// nonResourceVerb returns the authorization verb corresponding to an HTTP
+// method, the same way the Kubernetes API server does for non-resource paths.
+func nonResourceVerb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
 }
 
 // Run runs the metrics server.
 func (s *MetricsServer) Run() {
-	klog.InfoS("metrics server starting", "port", s.srv.Addr)
-	klog.InfoS("metrics server stopped", "exitCode", s.srv.ListenAndServe())
+	klog.InfoS("metrics server starting", "port", s.srv.Addr, "tls", s.srv.TLSConfig != nil)
+	if s.srv.TLSConfig != nil {
+		klog.InfoS("metrics server stopped", "exitCode", s.srv.ListenAndServeTLS("", ""))
+	} else {
+		klog.InfoS("metrics server stopped", "exitCode", s.srv.ListenAndServe())
+	}
 }
 
 // Stop stops the metrics server.
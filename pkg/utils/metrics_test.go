@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newFakeAuthClient() *fakeclient.Clientset {
+	cli := fakeclient.NewSimpleClientset()
+	cli.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		tr := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch tr.Spec.Token {
+		case "allowed-token":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "prometheus"}}
+		case "denied-token":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "someone"}}
+		}
+		return true, tr, nil
+	})
+	cli.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attrs := sar.Spec.NonResourceAttributes
+		sar.Status.Allowed = sar.Spec.User == "prometheus" && attrs.Path == "/metrics" && attrs.Verb == "get"
+		return true, sar, nil
+	})
+	return cli
+}
+
+func TestTokenAuthHandler(t *testing.T) {
+	h := newTokenAuthHandler(newFakeAuthClient(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tcs := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		expected int
+	}{
+		{name: "no token", path: "/metrics", expected: http.StatusUnauthorized},
+		{name: "invalid token", path: "/metrics", header: "Bearer invalid-token", expected: http.StatusUnauthorized},
+		{name: "not a bearer token", path: "/metrics", header: "Basic allowed-token", expected: http.StatusUnauthorized},
+		{name: "unauthorized user", path: "/metrics", header: "Bearer denied-token", expected: http.StatusForbidden},
+		{name: "unauthorized path", path: "/other", header: "Bearer allowed-token", expected: http.StatusForbidden},
+		{name: "unauthorized verb", method: http.MethodPost, path: "/metrics", header: "Bearer allowed-token", expected: http.StatusForbidden},
+		{name: "authorized", path: "/metrics", header: "Bearer allowed-token", expected: http.StatusOK},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.expected {
+				t.Errorf("expected status %d, got %d", tc.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestTokenAuthHandlerCache(t *testing.T) {
+	cli := newFakeAuthClient()
+	h := newTokenAuthHandler(cli, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, token := range []string{"allowed-token", "denied-token", "invalid-token"} {
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	reviews := map[string]int{}
+	for _, a := range cli.Actions() {
+		reviews[a.GetResource().Resource]++
+	}
+	// One review per token, the invalid token never gets authorized
+	if reviews["tokenreviews"] != 3 {
+		t.Errorf("expected 3 TokenReviews, got %d", reviews["tokenreviews"])
+	}
+	if reviews["subjectaccessreviews"] != 2 {
+		t.Errorf("expected 2 SubjectAccessReviews, got %d", reviews["subjectaccessreviews"])
+	}
+}
+
+func TestMetricsServerSecureArgs(t *testing.T) {
+	tcs := []struct {
+		name string
+		args MetricsSecurityArgs
+	}{
+		{name: "cert without key", args: MetricsSecurityArgs{CertFile: "cert.pem"}},
+		{name: "key without cert", args: MetricsSecurityArgs{KeyFile: "key.pem"}},
+		{name: "client ca without tls", args: MetricsSecurityArgs{ClientCAFile: "ca.pem"}},
+		{name: "token auth without tls", args: MetricsSecurityArgs{TokenAuth: true}},
+		{name: "non-existent cert", args: MetricsSecurityArgs{CertFile: "/non-existent/cert.pem", KeyFile: "/non-existent/key.pem"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := CreateMetricsServer(0)
+			if err := s.Secure(tc.args, ""); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+
+	s := CreateMetricsServer(0)
+	if err := s.Secure(MetricsSecurityArgs{}, ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if s.srv.TLSConfig != nil {
+		t.Errorf("TLS should not be enabled")
+	}
+}
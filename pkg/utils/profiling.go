@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// RuntimeStatsPath is the path of the runtime stats endpoint.
+const RuntimeStatsPath = "/debug/nfd/stats"
+
+// RuntimeStats accumulates the time spent in named units of work (e.g.
+// rules or feature sources) grouped by category. A nil RuntimeStats discards
+// all observations.
+type RuntimeStats struct {
+	lock  sync.Mutex
+	since time.Time
+	stats map[string]map[string]*RuntimeStat
+}
+
+// RuntimeStat is the accumulated time spent in one unit of work.
+type RuntimeStat struct {
+	Count int64         `json:"count"`
+	Total time.Duration `json:"totalNanoseconds"`
+	Max   time.Duration `json:"maxNanoseconds"`
+}
+
+// NewRuntimeStats creates a new, empty RuntimeStats.
+func NewRuntimeStats() *RuntimeStats {
+	return &RuntimeStats{since: time.Now(), stats: make(map[string]map[string]*RuntimeStat)}
+}
+
+// Observe adds one observation of the given unit of work.
+func (s *RuntimeStats) Observe(category, name string, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c, ok := s.stats[category]
+	if !ok {
+		c = make(map[string]*RuntimeStat)
+		s.stats[category] = c
+	}
+	stat, ok := c[name]
+	if !ok {
+		stat = &RuntimeStat{}
+		c[name] = stat
+	}
+	stat.Count++
+	stat.Total += d
+	if d > stat.Max {
+		stat.Max = d
+	}
+}
+
+// Reset drops all observations.
+func (s *RuntimeStats) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.since = time.Now()
+	s.stats = make(map[string]map[string]*RuntimeStat)
+}
+
+// runtimeStatsEntry is one unit of work in the runtime stats response.
+type runtimeStatsEntry struct {
+	Name string `json:"name"`
+	RuntimeStat
+}
+
+// runtimeStatsResponse is the response of the runtime stats endpoint.
+type runtimeStatsResponse struct {
+	Since time.Time `json:"since"`
+	// Stats contains the units of work of each category, sorted by the
+	// total time spent, highest first.
+	Stats map[string][]runtimeStatsEntry `json:"stats"`
+}
+
+// ServeHTTP returns the accumulated stats as JSON. A DELETE request resets
+// the stats, e.g. before deploying a new set of rules.
+func (s *RuntimeStats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.lock.Lock()
+	resp := runtimeStatsResponse{Since: s.since, Stats: make(map[string][]runtimeStatsEntry, len(s.stats))}
+	for category, c := range s.stats {
+		entries := make([]runtimeStatsEntry, 0, len(c))
+		for name, stat := range c {
+			entries = append(entries, runtimeStatsEntry{Name: name, RuntimeStat: *stat})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Total != entries[j].Total {
+				return entries[i].Total > entries[j].Total
+			}
+			return entries[i].Name < entries[j].Name
+		})
+		resp.Stats[category] = entries
+	}
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		klog.ErrorS(err, "failed to write runtime stats")
+	}
+}
+
+// EnableProfiling registers the pprof handlers under /debug/pprof/ and the
+// runtime stats endpoint on the metrics server. The profiles and stats
+// reveal internals of the daemon so client authentication is required.
+func (s *MetricsServer) EnableProfiling(args MetricsSecurityArgs, stats *RuntimeStats) error {
+	if !args.TokenAuth && args.ClientCAFile == "" {
+		return fmt.Errorf("-enable-profiling requires client authentication (-metrics-token-auth or -metrics-client-ca-file)")
+	}
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle(RuntimeStatsPath, stats)
+	return nil
+}
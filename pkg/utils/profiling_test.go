@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getRuntimeStats(t *testing.T, s *RuntimeStats) runtimeStatsResponse {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, RuntimeStatsPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	resp := runtimeStatsResponse{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestRuntimeStats(t *testing.T) {
+	var nilStats *RuntimeStats
+	nilStats.Observe("rule", "a", time.Second)
+
+	s := NewRuntimeStats()
+	s.Observe("rule", "a", 1*time.Second)
+	s.Observe("rule", "b", 2*time.Second)
+	s.Observe("rule", "a", 3*time.Second)
+	s.Observe("node", "node-1", 5*time.Second)
+
+	resp := getRuntimeStats(t, s)
+	assert.Equal(t, map[string][]runtimeStatsEntry{
+		"rule": {
+			{Name: "a", RuntimeStat: RuntimeStat{Count: 2, Total: 4 * time.Second, Max: 3 * time.Second}},
+			{Name: "b", RuntimeStat: RuntimeStat{Count: 1, Total: 2 * time.Second, Max: 2 * time.Second}},
+		},
+		"node": {
+			{Name: "node-1", RuntimeStat: RuntimeStat{Count: 1, Total: 5 * time.Second, Max: 5 * time.Second}},
+		},
+	}, resp.Stats)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, RuntimeStatsPath, nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, getRuntimeStats(t, s).Stats)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, RuntimeStatsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestEnableProfiling(t *testing.T) {
+	s := CreateMetricsServer(0)
+	assert.Error(t, s.EnableProfiling(MetricsSecurityArgs{}, NewRuntimeStats()))
+	assert.NoError(t, s.EnableProfiling(MetricsSecurityArgs{ClientCAFile: "/ca.crt"}, NewRuntimeStats()))
+
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// nodeFeatureSignatureAlg is the prefix of NodeFeature signatures
+// identifying the signing algorithm.
+const nodeFeatureSignatureAlg = "hmac-sha256:"
+
+// ReadSigningKeys reads the NodeFeature signing keys from a file, one key per
+// line. Multiple keys make it possible to rotate keys without downtime: the
+// first key is used for signing and all of them are accepted in verification.
+func ReadSigningKeys(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing keys: %w", err)
+	}
+	keys := [][]byte{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %q", path)
+	}
+	return keys, nil
+}
+
+// SignNodeFeature returns the signature of the spec of a NodeFeature object
+// targeting the given node. The node name is included in the signed data so
+// that the spec cannot be replayed for another node.
+func SignNodeFeature(key []byte, nodeName string, spec *nfdv1alpha1.NodeFeatureSpec) (string, error) {
+	mac, err := nodeFeatureMAC(key, nodeName, spec)
+	if err != nil {
+		return "", err
+	}
+	return nodeFeatureSignatureAlg + base64.StdEncoding.EncodeToString(mac), nil
+}
+
+// VerifyNodeFeature checks that the NodeFeature object has a valid signature
+// made with one of the keys.
+func VerifyNodeFeature(keys [][]byte, obj *nfdv1alpha1.NodeFeature) error {
+	sig, ok := obj.Annotations[nfdv1alpha1.NodeFeatureSignatureAnnotation]
+	if !ok {
+		return fmt.Errorf("signature missing")
+	}
+	enc, ok := strings.CutPrefix(sig, nodeFeatureSignatureAlg)
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm")
+	}
+	got, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	nodeName := obj.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel]
+	for _, key := range keys {
+		want, err := nodeFeatureMAC(key, nodeName, &obj.Spec)
+		if err != nil {
+			return err
+		}
+		if hmac.Equal(got, want) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature mismatch")
+}
+
+func nodeFeatureMAC(key []byte, nodeName string, spec *nfdv1alpha1.NodeFeatureSpec) ([]byte, error) {
+	data, err := canonicalJSON(spec)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(nodeName))
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// canonicalJSON returns a JSON encoding of an object where nulls and empty
+// maps and lists are dropped. This way the encoding survives the round-trip
+// through the API server which does not preserve the difference between
+// missing, null and empty fields.
+func canonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(pruneEmpty(generic))
+}
+
+func pruneEmpty(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if e = pruneEmpty(e); e == nil {
+				delete(t, k)
+			} else {
+				t[k] = e
+			}
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	case []interface{}:
+		// Keep the elements of lists in place, only drop empty lists
+		for i := range t {
+			t[i] = pruneEmpty(t[i])
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+func TestNodeFeatureSignature(t *testing.T) {
+	newObj := func(nodeName string) *nfdv1alpha1.NodeFeature {
+		spec := nfdv1alpha1.NewNodeFeatureSpec()
+		spec.Labels = map[string]string{"foo": "bar"}
+		spec.Features.Attributes["kernel.version"] = nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "6"})
+		return &nfdv1alpha1.NodeFeature{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeName,
+				Labels:      map[string]string{nfdv1alpha1.NodeFeatureObjNodeNameLabel: nodeName},
+				Annotations: map[string]string{},
+			},
+			Spec: *spec,
+		}
+	}
+	sign := func(key string, obj *nfdv1alpha1.NodeFeature) {
+		sig, err := SignNodeFeature([]byte(key), obj.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel], &obj.Spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj.Annotations[nfdv1alpha1.NodeFeatureSignatureAnnotation] = sig
+	}
+	keys := [][]byte{[]byte("new-key"), []byte("old-key")}
+
+	tcs := []struct {
+		name   string
+		modify func(obj *nfdv1alpha1.NodeFeature)
+		valid  bool
+	}{
+		{name: "valid", modify: func(obj *nfdv1alpha1.NodeFeature) { sign("new-key", obj) }, valid: true},
+		{name: "rotated key", modify: func(obj *nfdv1alpha1.NodeFeature) { sign("old-key", obj) }, valid: true},
+		{name: "unknown key", modify: func(obj *nfdv1alpha1.NodeFeature) { sign("other-key", obj) }},
+		{name: "missing signature", modify: func(obj *nfdv1alpha1.NodeFeature) {}},
+		{name: "malformed signature", modify: func(obj *nfdv1alpha1.NodeFeature) {
+			obj.Annotations[nfdv1alpha1.NodeFeatureSignatureAnnotation] = "hmac-sha256:%%%"
+		}},
+		{name: "tampered spec", modify: func(obj *nfdv1alpha1.NodeFeature) {
+			sign("new-key", obj)
+			obj.Spec.Labels["foo"] = "baz"
+		}},
+		{name: "replayed for another node", modify: func(obj *nfdv1alpha1.NodeFeature) {
+			sign("new-key", obj)
+			obj.Labels[nfdv1alpha1.NodeFeatureObjNodeNameLabel] = "node-2"
+		}},
+		{name: "empty maps dropped by the API server", modify: func(obj *nfdv1alpha1.NodeFeature) {
+			sign("new-key", obj)
+			obj.Spec.Features.Flags = nil
+			obj.Spec.Features.Instances = nil
+		}, valid: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := newObj("node-1")
+			tc.modify(obj)
+			err := VerifyNodeFeature(keys, obj)
+			if tc.valid && err != nil {
+				t.Errorf("expected a valid signature, got %v", err)
+			} else if !tc.valid && err == nil {
+				t.Errorf("expected an invalid signature")
+			}
+		})
+	}
+}
+
+func TestReadSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys")
+	if err := os.WriteFile(path, []byte("key-1\n\n  key-2 \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadSigningKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[0]) != "key-1" || string(keys[1]) != "key-2" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+
+	if err := os.WriteFile(path, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSigningKeys(path); err == nil {
+		t.Errorf("expected an error for an empty key file")
+	}
+}
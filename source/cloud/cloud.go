@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "cloud"
+
+const (
+	// InstanceFeature holds the provider, instance type, region and zone of
+	// the cloud instance.
+	InstanceFeature = "instance"
+	// AcceleratorFeature holds the accelerators attached to the cloud
+	// instance.
+	AcceleratorFeature = "accelerator"
+)
+
+// Config holds the configuration parameters of this source.
+type Config struct {
+	// Providers is the list of cloud providers whose metadata service is
+	// queried, in order, until one of them responds. Supported providers are
+	// aws, gcp, azure and openstack. The metadata services are not queried
+	// if the list is empty.
+	Providers []string `json:"providers,omitempty"`
+	// Timeout of each metadata request.
+	Timeout utils.DurationVal `json:"timeout,omitempty"`
+}
+
+// newDefaultConfig returns a new config with pre-populated defaults
+func newDefaultConfig() *Config {
+	return &Config{
+		Timeout: utils.DurationVal{Duration: 2 * time.Second},
+	}
+}
+
+// cloudSource implements the FeatureSource and ConfigurableSource interfaces.
+type cloudSource struct {
+	config   *Config
+	features *nfdv1alpha1.Features
+	// cached holds the features discovered from the metadata service, which
+	// do not change during the lifetime of the instance.
+	cached *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                           = cloudSource{config: newDefaultConfig()}
+	_   source.FeatureSource      = &src
+	_   source.ConfigurableSource = &src
+)
+
+// Name returns the name of the feature source
+func (s *cloudSource) Name() string { return Name }
+
+// NewConfig method of the ConfigurableSource interface
+func (s *cloudSource) NewConfig() source.Config { return newDefaultConfig() }
+
+// GetConfig method of the ConfigurableSource interface
+func (s *cloudSource) GetConfig() source.Config { return s.config }
+
+// SetConfig method of the ConfigurableSource interface
+func (s *cloudSource) SetConfig(conf source.Config) {
+	switch v := conf.(type) {
+	case *Config:
+		s.config = v
+		s.cached = nil
+	default:
+		panic(fmt.Sprintf("invalid config type: %T", conf))
+	}
+}
+
+// Discover method of the FeatureSource interface
+func (s *cloudSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	if len(s.config.Providers) == 0 {
+		return nil
+	}
+	if source.EgressDisabled() {
+		source.Logger(Name).V(2).Info("outbound network traffic disabled, not querying cloud metadata services")
+		return nil
+	}
+
+	if s.cached == nil {
+		client := source.NewHTTPClient(s.config.Timeout.Duration)
+		for _, name := range s.config.Providers {
+			p, ok := providers[name]
+			if !ok {
+				klog.InfoS("skipping unknown cloud provider", "provider", name)
+				continue
+			}
+			md, err := p(client)
+			if err != nil {
+				source.Logger(Name).V(2).Info("cloud metadata not available", "provider", name, "error", err)
+				continue
+			}
+			md.instance["provider"] = name
+			s.cached = nfdv1alpha1.NewFeatures()
+			s.cached.Attributes[InstanceFeature] = nfdv1alpha1.NewAttributeFeatures(md.instance)
+			s.cached.Instances[AcceleratorFeature] = nfdv1alpha1.NewInstanceFeatures(md.accelerators)
+			break
+		}
+		if s.cached == nil {
+			return fmt.Errorf("none of the cloud metadata services %v responded", s.config.Providers)
+		}
+	}
+
+	s.features = s.cached.DeepCopy()
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *cloudSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+func init() {
+	source.Register(&src)
+}
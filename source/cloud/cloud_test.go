@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+func newTestServer(t *testing.T, header, value string, responses map[string]string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header != "" && r.Header.Get(header) != value {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		resp, ok := responses[r.Method+" "+r.URL.RequestURI()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestAwsMetadata(t *testing.T) {
+	awsEndpoint = newTestServer(t, "", "", map[string]string{
+		"PUT /latest/api/token":                             "token",
+		"GET /latest/meta-data/instance-type":               "p4d.24xlarge",
+		"GET /latest/meta-data/placement/region":            "us-east-1",
+		"GET /latest/meta-data/placement/availability-zone": "us-east-1a",
+	})
+
+	md, err := awsMetadata(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"instance_type": "p4d.24xlarge", "region": "us-east-1", "zone": "us-east-1a"}, md.instance)
+}
+
+func TestGcpMetadata(t *testing.T) {
+	gcpEndpoint = newTestServer(t, "Metadata-Flavor", "Google", map[string]string{
+		"GET /computeMetadata/v1/instance/machine-type":                       "projects/123/machineTypes/n1-standard-8",
+		"GET /computeMetadata/v1/instance/zone":                               "projects/123/zones/us-central1-a",
+		"GET /computeMetadata/v1/instance/guest-accelerators/?recursive=true": `[{"acceleratorCount":2,"acceleratorType":"projects/123/zones/us-central1-a/acceleratorTypes/nvidia-tesla-t4"}]`,
+	})
+
+	md, err := gcpMetadata(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"instance_type": "n1-standard-8", "region": "us-central1", "zone": "us-central1-a"}, md.instance)
+	assert.Equal(t, []nfdv1alpha1.InstanceFeature{
+		*nfdv1alpha1.NewInstanceFeature(map[string]string{"type": "nvidia-tesla-t4", "count": "2"}),
+	}, md.accelerators)
+}
+
+func TestAzureMetadata(t *testing.T) {
+	azureEndpoint = newTestServer(t, "Metadata", "true", map[string]string{
+		"GET /metadata/instance/compute?api-version=2021-02-01": `{"vmSize":"Standard_NC6s_v3","location":"westeurope","zone":"2"}`,
+	})
+
+	md, err := azureMetadata(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"instance_type": "Standard_NC6s_v3", "region": "westeurope", "zone": "2"}, md.instance)
+}
+
+func TestOpenstackMetadata(t *testing.T) {
+	openstackEndpoint = newTestServer(t, "", "", map[string]string{
+		"GET /openstack/latest/meta_data.json": `{"availability_zone":"nova"}`,
+		"GET /latest/meta-data/instance-type":  "m1.large",
+	})
+
+	md, err := openstackMetadata(http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"instance_type": "m1.large", "zone": "nova"}, md.instance)
+}
+
+func TestCloudSource(t *testing.T) {
+	s := &cloudSource{}
+
+	// The metadata services are not queried by default
+	s.SetConfig(s.NewConfig())
+	assert.NoError(t, s.Discover())
+	assert.Empty(t, s.GetFeatures().Attributes)
+
+	// Providers are tried in order until one responds
+	azureEndpoint = newTestServer(t, "Metadata", "true", map[string]string{
+		"GET /metadata/instance/compute?api-version=2021-02-01": `{"vmSize":"Standard_D4s_v5","location":"eastus"}`,
+	})
+	awsEndpoint = newTestServer(t, "", "", nil)
+	s.SetConfig(&Config{Providers: []string{"foo", "aws", "azure"}, Timeout: utils.DurationVal{Duration: time.Second}})
+	assert.NoError(t, s.Discover())
+	assert.Equal(t, map[string]string{"provider": "azure", "instance_type": "Standard_D4s_v5", "region": "eastus"}, s.GetFeatures().Attributes[InstanceFeature].Elements)
+
+	// Discovered metadata is cached
+	azureEndpoint = newTestServer(t, "", "", nil)
+	assert.NoError(t, s.Discover())
+	assert.Equal(t, "Standard_D4s_v5", s.GetFeatures().Attributes[InstanceFeature].Elements["instance_type"])
+
+	// Error if none of the metadata services respond
+	s.SetConfig(&Config{Providers: []string{"azure"}, Timeout: utils.DurationVal{Duration: time.Second}})
+	assert.Error(t, s.Discover())
+	assert.Empty(t, s.GetFeatures().Attributes)
+}
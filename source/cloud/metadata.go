@@ -0,0 +1,200 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+)
+
+// maxResponseSize is the maximum size of a metadata response that is read.
+const maxResponseSize = 64 * 1024
+
+// metadata holds the features read from a metadata service.
+type metadata struct {
+	instance     map[string]string
+	accelerators []nfdv1alpha1.InstanceFeature
+}
+
+// provider reads the instance metadata of one cloud provider.
+type provider func(client *http.Client) (*metadata, error)
+
+var providers = map[string]provider{
+	"aws":       awsMetadata,
+	"gcp":       gcpMetadata,
+	"azure":     azureMetadata,
+	"openstack": openstackMetadata,
+}
+
+// Base URLs of the metadata services, variables for testing.
+var (
+	awsEndpoint       = "http://169.254.169.254"
+	gcpEndpoint       = "http://metadata.google.internal"
+	azureEndpoint     = "http://169.254.169.254"
+	openstackEndpoint = "http://169.254.169.254"
+)
+
+// get makes a metadata request and returns the response body.
+func get(client *http.Client, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: unexpected status %s", method, url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// awsMetadata reads the instance metadata using IMDSv2.
+func awsMetadata(client *http.Client) (*metadata, error) {
+	token, err := get(client, http.MethodPut, awsEndpoint+"/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return nil, err
+	}
+	header := map[string]string{"X-aws-ec2-metadata-token": token}
+
+	instance := map[string]string{}
+	for attr, p := range map[string]string{
+		"instance_type": "instance-type",
+		"region":        "placement/region",
+		"zone":          "placement/availability-zone",
+	} {
+		v, err := get(client, http.MethodGet, awsEndpoint+"/latest/meta-data/"+p, header)
+		if err != nil {
+			return nil, err
+		}
+		instance[attr] = v
+	}
+	return &metadata{instance: instance}, nil
+}
+
+// gcpMetadata reads the instance metadata of a GCE instance.
+func gcpMetadata(client *http.Client) (*metadata, error) {
+	header := map[string]string{"Metadata-Flavor": "Google"}
+	base := gcpEndpoint + "/computeMetadata/v1/instance/"
+
+	// Machine type and zone are in the form projects/<id>/<kind>/<name>
+	machineType, err := get(client, http.MethodGet, base+"machine-type", header)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := get(client, http.MethodGet, base+"zone", header)
+	if err != nil {
+		return nil, err
+	}
+	zone = path.Base(zone)
+	instance := map[string]string{
+		"instance_type": path.Base(machineType),
+		"zone":          zone,
+	}
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		instance["region"] = zone[:i]
+	}
+
+	data, err := get(client, http.MethodGet, base+"guest-accelerators/?recursive=true", header)
+	if err != nil {
+		return nil, err
+	}
+	accelerators := []struct {
+		AcceleratorCount int    `json:"acceleratorCount"`
+		AcceleratorType  string `json:"acceleratorType"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &accelerators); err != nil {
+		return nil, fmt.Errorf("failed to parse guest accelerators: %w", err)
+	}
+	md := &metadata{instance: instance}
+	for _, a := range accelerators {
+		md.accelerators = append(md.accelerators, *nfdv1alpha1.NewInstanceFeature(map[string]string{
+			"type":  path.Base(a.AcceleratorType),
+			"count": strconv.Itoa(a.AcceleratorCount),
+		}))
+	}
+	return md, nil
+}
+
+// azureMetadata reads the instance metadata of an Azure virtual machine.
+func azureMetadata(client *http.Client) (*metadata, error) {
+	data, err := get(client, http.MethodGet, azureEndpoint+"/metadata/instance/compute?api-version=2021-02-01", map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+	compute := struct {
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &compute); err != nil {
+		return nil, fmt.Errorf("failed to parse compute metadata: %w", err)
+	}
+
+	instance := map[string]string{
+		"instance_type": compute.VMSize,
+		"region":        compute.Location,
+	}
+	if compute.Zone != "" {
+		instance["zone"] = compute.Zone
+	}
+	return &metadata{instance: instance}, nil
+}
+
+// openstackMetadata reads the instance metadata of an OpenStack instance.
+func openstackMetadata(client *http.Client) (*metadata, error) {
+	data, err := get(client, http.MethodGet, openstackEndpoint+"/openstack/latest/meta_data.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	md := struct {
+		AvailabilityZone string `json:"availability_zone"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &md); err != nil {
+		return nil, fmt.Errorf("failed to parse openstack metadata: %w", err)
+	}
+
+	// The flavor is only available through the EC2 compatible API
+	instanceType, err := get(client, http.MethodGet, openstackEndpoint+"/latest/meta-data/instance-type", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := map[string]string{"instance_type": instanceType}
+	if md.AvailabilityZone != "" {
+		instance["zone"] = md.AvailabilityZone
+	}
+	return &metadata{instance: instance}, nil
+}
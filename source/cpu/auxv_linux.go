@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+// Types of the ELF auxiliary vector entries, see
+// https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/include/uapi/linux/auxvec.h
+const (
+	atNull     = 0
+	atPlatform = 15
+	atHwcap    = 16
+	atHwcap2   = 26
+)
+
+// maxPlatformLen is the maximum length of the platform string read from the
+// process memory.
+const maxPlatformLen = 64
+
+// auxv returns the ELF auxiliary vector of the running process. It is read
+// from procfs (and not with getauxval from libc) so that no cgo is needed.
+var auxv = sync.OnceValue(func() map[uint64]uint64 {
+	data, err := os.ReadFile("/proc/self/auxv")
+	if err != nil {
+		klog.ErrorS(err, "failed to read auxiliary vector")
+		return nil
+	}
+	return parseAuxv(data, strconv.IntSize/8, binary.NativeEndian)
+})
+
+// parseAuxv parses the auxiliary vector consisting of pairs of machine words
+// (type and value), terminated by an AT_NULL entry.
+func parseAuxv(data []byte, wordSize int, order binary.ByteOrder) map[uint64]uint64 {
+	word := func(b []byte) uint64 {
+		if wordSize == 4 {
+			return uint64(order.Uint32(b))
+		}
+		return order.Uint64(b)
+	}
+
+	ret := make(map[uint64]uint64)
+	for len(data) >= 2*wordSize {
+		tag, val := word(data), word(data[wordSize:])
+		if tag == atNull {
+			break
+		}
+		ret[tag] = val
+		data = data[2*wordSize:]
+	}
+	return ret
+}
+
+// getHwcap returns the AT_HWCAP entry of the auxiliary vector.
+func getHwcap() uint64 { return auxv()[atHwcap] }
+
+// getHwcap2 returns the AT_HWCAP2 entry of the auxiliary vector.
+func getHwcap2() uint64 { return auxv()[atHwcap2] }
+
+// getPlatform returns the string pointed to by the AT_PLATFORM entry of the
+// auxiliary vector, or an empty string if it is not available.
+func getPlatform() string {
+	addr, ok := auxv()[atPlatform]
+	if !ok || addr == 0 {
+		return ""
+	}
+	p, err := readProcessString(addr)
+	if err != nil {
+		klog.ErrorS(err, "failed to read platform string")
+		return ""
+	}
+	return p
+}
+
+// readProcessString reads a NUL-terminated string from the memory of the
+// running process. The memory is read with process_vm_readv(2) instead of
+// dereferencing the address so that an invalid address results in an error
+// and not in a crash.
+func readProcessString(addr uint64) (string, error) {
+	buf := make([]byte, maxPlatformLen)
+	local := []unix.Iovec{{Base: &buf[0]}}
+	local[0].SetLen(len(buf))
+	remote := []unix.RemoteIovec{{Base: uintptr(addr), Len: len(buf)}}
+
+	n, err := unix.ProcessVMReadv(os.Getpid(), local, remote, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read memory at %#x: %w", addr, err)
+	}
+	s, _, found := bytes.Cut(buf[:n], []byte{0})
+	if !found {
+		return "", fmt.Errorf("string at %#x not terminated in %d bytes", addr, maxPlatformLen)
+	}
+	return string(s), nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuxv(t *testing.T) {
+	// 64-bit little-endian vector with a trailing entry after AT_NULL
+	data := []byte{}
+	for _, w := range []uint64{atHwcap, 0x10, atHwcap2, 0x2, atNull, 0, atPlatform, 0x1234} {
+		data = binary.LittleEndian.AppendUint64(data, w)
+	}
+	assert.Equal(t, map[uint64]uint64{atHwcap: 0x10, atHwcap2: 0x2}, parseAuxv(data, 8, binary.LittleEndian))
+
+	// 32-bit big-endian vector, truncated
+	data = []byte{}
+	for _, w := range []uint32{atHwcap, 0x80000000, atHwcap2} {
+		data = binary.BigEndian.AppendUint32(data, w)
+	}
+	assert.Equal(t, map[uint64]uint64{atHwcap: 0x80000000}, parseAuxv(data, 4, binary.BigEndian))
+}
+
+func TestGetPlatform(t *testing.T) {
+	// The platform string is not provided on all architectures but reading
+	// it must not fail if it is
+	if _, ok := auxv()[atPlatform]; ok {
+		assert.NotEmpty(t, getPlatform())
+	}
+}
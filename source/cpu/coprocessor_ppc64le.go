@@ -20,9 +20,9 @@ limitations under the License.
 package cpu
 
 import (
-	"k8s.io/klog/v2"
 	"os"
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
 	"strconv"
 )
 
@@ -34,7 +34,7 @@ func discoverCoprocessor() map[string]string {
 
 	_, err := os.Stat(nxGzipPath)
 	if err != nil {
-		klog.V(5).ErrorS(err, "Failed to detect nx_gzip for Nest Accelerator")
+		source.Logger(Name).V(5).Info("Failed to detect nx_gzip for Nest Accelerator", "err", err)
 	} else {
 		features["nx_gzip"] = strconv.FormatBool(true)
 	}
@@ -0,0 +1,97 @@
+//go:build s390x
+// +build s390x
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	syscpu "golang.org/x/sys/cpu"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+// Detect CPACF crypto functions and zEDC compression
+func discoverCoprocessor() map[string]string {
+	features := make(map[string]string)
+
+	// CPACF (CP Assist for Cryptographic Functions)
+	if syscpu.S390X.HasMSA {
+		cpacf := map[string]bool{
+			"aes":     syscpu.S390X.HasAES,
+			"aes_cbc": syscpu.S390X.HasAESCBC,
+			"aes_ctr": syscpu.S390X.HasAESCTR,
+			"aes_gcm": syscpu.S390X.HasAESGCM,
+			"ghash":   syscpu.S390X.HasGHASH,
+			"sha1":    syscpu.S390X.HasSHA1,
+			"sha256":  syscpu.S390X.HasSHA256,
+			"sha512":  syscpu.S390X.HasSHA512,
+			"sha3":    syscpu.S390X.HasSHA3,
+		}
+		features["cpacf"] = strconv.FormatBool(true)
+		for k, v := range cpacf {
+			if v {
+				features["cpacf."+k] = strconv.FormatBool(true)
+			}
+		}
+	}
+
+	// On-chip zEDC compression (DEFLATE Conversion Facility, z15 and later)
+	if cpuinfoHasFeature("dflt") {
+		features["dfltcc"] = strconv.FormatBool(true)
+	}
+
+	// zEDC Express PCIe adapters
+	if cards, err := filepath.Glob(hostpath.SysfsDir.Path("class/genwqe/genwqe*")); err == nil && len(cards) > 0 {
+		features["zedc_express"] = strconv.FormatBool(true)
+	}
+
+	return features
+}
+
+// cpuinfoHasFeature checks if the given facility is listed on the features
+// line of /proc/cpuinfo.
+func cpuinfoHasFeature(name string) bool {
+	f, err := os.Open(hostpath.ProcfsDir.Path("cpuinfo"))
+	if err != nil {
+		klog.ErrorS(err, "failed to open cpuinfo")
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(key) != "features" {
+			continue
+		}
+		for _, v := range strings.Fields(value) {
+			if v == name {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
@@ -1,5 +1,5 @@
-//go:build !ppc64le
-// +build !ppc64le
+//go:build !(ppc64le || s390x)
+// +build !ppc64le,!s390x
 
 /*
 Copyright 2023 The Kubernetes Authors.
@@ -18,9 +18,7 @@ package cpu
 
 import (
 	"fmt"
-	"os"
 	"strconv"
-	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -38,6 +36,7 @@ const Name = "cpu"
 
 const (
 	CpuidFeature       = "cpuid"
+	CpuidValuesFeature = "cpuidvalues"
 	Cpumodel           = "model"
 	CstateFeature      = "cstate"
 	PstateFeature      = "pstate"
@@ -46,8 +45,15 @@ const (
 	SstFeature         = "sst"
 	TopologyFeature    = "topology"
 	CoprocessorFeature = "coprocessor"
+	IsolationFeature   = "isolation"
 )
 
+// topologyTypes are the types of the attributes of the topology feature.
+var topologyTypes = map[string]nfdv1alpha1.AttributeType{
+	"hardware_multithreading": nfdv1alpha1.AttributeTypeBool,
+	"socket_count":            nfdv1alpha1.AttributeTypeInt,
+}
+
 // Configuration file options
 type cpuidConfig struct {
 	AttributeBlacklist []string `json:"attributeBlacklist,omitempty"`
@@ -57,6 +63,9 @@ type cpuidConfig struct {
 // Config holds configuration for the cpu source.
 type Config struct {
 	Cpuid cpuidConfig `json:"cpuid,omitempty"`
+	// KubeletConfigFile is the kubelet configuration file to read the
+	// reserved system cpus from. Empty disables reading it.
+	KubeletConfigFile string `json:"kubeletConfigFile,omitempty"`
 }
 
 // newDefaultConfig returns a new config with pre-populated defaults
@@ -92,6 +101,7 @@ func newDefaultConfig() *Config {
 			},
 			AttributeWhitelist: []string{},
 		},
+		hostpath.VarDir.Path("lib/kubelet/config.yaml"),
 	}
 }
 
@@ -106,6 +116,7 @@ type cpuSource struct {
 	config      *Config
 	cpuidFilter *keyFilter
 	features    *nfdv1alpha1.Features
+	inputs      source.InputStatuses
 }
 
 // Singleton source instance
@@ -114,6 +125,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.ProbingSource      = &src
 )
 
 func (s *cpuSource) Name() string { return Name }
@@ -189,38 +201,49 @@ func (s *cpuSource) GetLabels() (source.FeatureLabels, error) {
 		labels["hardware_multithreading"] = v
 	}
 
-	// NX
-	if v, ok := features.Attributes[CoprocessorFeature].Elements["nx_gzip"]; ok {
-		labels["coprocessor.nx_gzip"] = v
+	// Coprocessors, e.g. NX on ppc64le and CPACF/zEDC on s390x
+	for k, v := range features.Attributes[CoprocessorFeature].Elements {
+		labels["coprocessor."+k] = v
 	}
 
 	return labels, nil
 }
 
+// Probe method of the ProbingSource Interface
+func (s *cpuSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = probeInputs(minimalPrivileges)
+	return s.inputs
+}
+
 // Discover method of the FeatureSource Interface
 func (s *cpuSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
 	// Detect CPUID
 	s.features.Flags[CpuidFeature] = nfdv1alpha1.NewFlagFeatures(getCpuidFlags()...)
+	s.features.Attributes[CpuidValuesFeature] = nfdv1alpha1.NewAttributeFeatures(getCpuidAttributes())
 
 	// Detect CPU model
 	s.features.Attributes[Cpumodel] = nfdv1alpha1.NewAttributeFeatures(getCPUModel())
 
 	// Detect cstate configuration
-	cstate, err := detectCstate()
-	if err != nil {
-		klog.ErrorS(err, "failed to detect cstate")
-	} else {
-		s.features.Attributes[CstateFeature] = nfdv1alpha1.NewAttributeFeatures(cstate)
+	if s.inputs.Available(CstateFeature) {
+		cstate, err := detectCstate()
+		if err != nil {
+			klog.ErrorS(err, "failed to detect cstate")
+		} else {
+			s.features.Attributes[CstateFeature] = nfdv1alpha1.NewAttributeFeatures(cstate)
+		}
 	}
 
 	// Detect pstate features
-	pstate, err := detectPstate()
-	if err != nil {
-		klog.ErrorS(err, "failed to detect pstate")
+	if s.inputs.Available(PstateFeature) {
+		pstate, err := detectPstate()
+		if err != nil {
+			klog.ErrorS(err, "failed to detect pstate")
+		}
+		s.features.Attributes[PstateFeature] = nfdv1alpha1.NewAttributeFeatures(pstate)
 	}
-	s.features.Attributes[PstateFeature] = nfdv1alpha1.NewAttributeFeatures(pstate)
 
 	// Detect RDT features
 	s.features.Attributes[RdtFeature] = nfdv1alpha1.NewAttributeFeatures(discoverRDT())
@@ -232,12 +255,17 @@ func (s *cpuSource) Discover() error {
 	s.features.Attributes[SstFeature] = nfdv1alpha1.NewAttributeFeatures(discoverSST())
 
 	// Detect hyper-threading
-	s.features.Attributes[TopologyFeature] = nfdv1alpha1.NewAttributeFeatures(discoverTopology())
+	if s.inputs.Available(TopologyFeature) {
+		s.features.Attributes[TopologyFeature] = nfdv1alpha1.NewAttributeFeatures(discoverTopology()).WithTypes(topologyTypes)
+	}
 
 	// Detect Coprocessor features
 	s.features.Attributes[CoprocessorFeature] = nfdv1alpha1.NewAttributeFeatures(discoverCoprocessor())
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	// Detect isolated and reserved cpus
+	s.features.Attributes[IsolationFeature] = nfdv1alpha1.NewAttributeFeatures(discoverIsolation(s.config.KubeletConfigFile)).WithTypes(isolationTypes)
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -259,49 +287,6 @@ func getCPUModel() map[string]string {
 	return cpuModelInfo
 }
 
-func discoverTopology() map[string]string {
-	features := make(map[string]string)
-
-	files, err := os.ReadDir(hostpath.SysfsDir.Path("bus/cpu/devices"))
-	if err != nil {
-		klog.ErrorS(err, "failed to read devices folder")
-		return features
-	}
-
-	ht := false
-	uniquePhysicalIDs := sets.NewString()
-
-	for _, file := range files {
-		// Try to read siblings from topology
-		siblings, err := os.ReadFile(hostpath.SysfsDir.Path("bus/cpu/devices", file.Name(), "topology/thread_siblings_list"))
-		if err != nil {
-			klog.ErrorS(err, "error while reading thread_sigblings_list file")
-			return map[string]string{}
-		}
-		for _, char := range siblings {
-			// If list separator found, we determine that there are multiple siblings
-			if char == ',' || char == '-' {
-				ht = true
-				break
-			}
-		}
-
-		// Try to read physical_package_id from topology
-		physicalID, err := os.ReadFile(hostpath.SysfsDir.Path("bus/cpu/devices", file.Name(), "topology/physical_package_id"))
-		if err != nil {
-			klog.ErrorS(err, "error while reading physical_package_id file")
-			return map[string]string{}
-		}
-		id := strings.TrimSpace(string(physicalID))
-		uniquePhysicalIDs.Insert(id)
-	}
-
-	features["hardware_multithreading"] = strconv.FormatBool(ht)
-	features["socket_count"] = strconv.FormatInt(int64(uniquePhysicalIDs.Len()), 10)
-
-	return features
-}
-
 func (s *cpuSource) initCpuidFilter() {
 	newFilter := keyFilter{keys: map[string]struct{}{}}
 	if len(s.config.Cpuid.AttributeWhitelist) > 0 {
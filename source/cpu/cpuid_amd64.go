@@ -17,10 +17,71 @@ limitations under the License.
 package cpu
 
 import (
+	"strconv"
+
 	"github.com/klauspost/cpuid/v2"
 )
 
+// avx512Features are the AVX-512 subfeatures in the order of their bits in
+// the avx512_mask attribute.
+var avx512Features = []cpuid.FeatureID{
+	cpuid.AVX512F,
+	cpuid.AVX512CD,
+	cpuid.AVX512ER,
+	cpuid.AVX512PF,
+	cpuid.AVX512DQ,
+	cpuid.AVX512BW,
+	cpuid.AVX512VL,
+	cpuid.AVX512IFMA,
+	cpuid.AVX512VBMI,
+	cpuid.AVX512VBMI2,
+	cpuid.AVX512VNNI,
+	cpuid.AVX512BITALG,
+	cpuid.AVX512VPOPCNTDQ,
+	cpuid.AVX512BF16,
+	cpuid.AVX512FP16,
+	cpuid.AVX512VP2INTERSECT,
+}
+
 // getCpuidFlags returns feature names for all the supported CPU features.
 func getCpuidFlags() []string {
 	return cpuid.CPU.FeatureSet()
 }
+
+// getCpuidAttributes returns selected values read with cpuid.
+func getCpuidAttributes() map[string]string {
+	return cpuidAttributes(&cpuid.CPU)
+}
+
+func cpuidAttributes(c *cpuid.CPUInfo) map[string]string {
+	attrs := map[string]string{
+		"vendor_string": c.VendorString,
+		"family":        strconv.Itoa(c.Family),
+		"model":         strconv.Itoa(c.Model),
+		"stepping":      strconv.Itoa(c.Stepping),
+	}
+
+	// Sizes are in bytes, undetected values are omitted
+	sizes := map[string]int{
+		"cache_line": c.CacheLine,
+		"cache_l1d":  c.Cache.L1D,
+		"cache_l1i":  c.Cache.L1I,
+		"cache_l2":   c.Cache.L2,
+		"cache_l3":   c.Cache.L3,
+	}
+	for k, v := range sizes {
+		if v > 0 {
+			attrs[k] = strconv.Itoa(v)
+		}
+	}
+
+	var mask uint64
+	for i, f := range avx512Features {
+		if c.Supports(f) {
+			mask |= 1 << i
+		}
+	}
+	attrs["avx512_mask"] = strconv.FormatUint(mask, 10)
+
+	return attrs
+}
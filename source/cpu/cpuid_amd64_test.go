@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"testing"
+
+	"github.com/klauspost/cpuid/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCpuidAttributes(t *testing.T) {
+	c := cpuid.CPUInfo{
+		VendorString: "GenuineIntel",
+		Family:       6,
+		Model:        143,
+		Stepping:     8,
+		CacheLine:    64,
+	}
+	c.Cache.L1D = 48 * 1024
+	c.Cache.L1I = 32 * 1024
+	c.Cache.L2 = 2 * 1024 * 1024
+	c.Cache.L3 = -1
+
+	assert.Equal(t, map[string]string{
+		"vendor_string": "GenuineIntel",
+		"family":        "6",
+		"model":         "143",
+		"stepping":      "8",
+		"cache_line":    "64",
+		"cache_l1d":     "49152",
+		"cache_l1i":     "32768",
+		"cache_l2":      "2097152",
+		"avx512_mask":   "0",
+	}, cpuidAttributes(&c))
+
+	// The attributes of the host CPU must not fail
+	assert.Contains(t, getCpuidAttributes(), "avx512_mask")
+}
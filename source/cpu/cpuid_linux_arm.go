@@ -16,18 +16,6 @@ limitations under the License.
 
 package cpu
 
-/*
-#include <sys/auxv.h>
-
-unsigned long gethwcap() {
-	return getauxval(AT_HWCAP);
-}
-unsigned long gethwcap2() {
-	return getauxval(AT_HWCAP2);
-}
-*/
-import "C"
-
 /*
 all special features for arm should be defined here; canonical list:
 https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/arch/arm/include/uapi/asm/hwcap.h
@@ -101,8 +89,8 @@ var flag2Names_arm = map[uint64]string{
 
 func getCpuidFlags() []string {
 	r := make([]string, 0, 20)
-	hwcap := uint64(C.gethwcap())
-	hwcap2 := uint64(C.gethwcap2())
+	hwcap := getHwcap()
+	hwcap2 := getHwcap2()
 	for i := uint(0); i < 64; i++ {
 		key := uint64(1 << i)
 		val, ok := flagNames_arm[key]
@@ -119,3 +107,9 @@ func getCpuidFlags() []string {
 	}
 	return r
 }
+
+// getCpuidAttributes returns selected values read with cpuid. Not available
+// on this architecture.
+func getCpuidAttributes() map[string]string {
+	return map[string]string{}
+}
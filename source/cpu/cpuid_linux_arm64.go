@@ -16,18 +16,13 @@ limitations under the License.
 
 package cpu
 
-/*
-#include <sys/auxv.h>
-#define HWCAP_CPUID	(1 << 11)
+import (
+	"runtime"
+	"strconv"
 
-unsigned long gethwcap() {
-	return getauxval(AT_HWCAP);
-}
-unsigned long gethwcap2() {
-	return getauxval(AT_HWCAP2);
-}
-*/
-import "C"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
 
 /*
 all special features for arm64 should be defined here; canonical list:
@@ -181,8 +176,8 @@ var flag2Names_arm64 = map[uint64]string{
 
 func getCpuidFlags() []string {
 	r := make([]string, 0, 20)
-	hwcap := uint64(C.gethwcap())
-	hwcap2 := uint64(C.gethwcap2())
+	hwcap := getHwcap()
+	hwcap2 := getHwcap2()
 	for i := uint(0); i < 64; i++ {
 		key := uint64(1 << i)
 		val, ok := flagNames_arm64[key]
@@ -199,3 +194,40 @@ func getCpuidFlags() []string {
 	}
 	return r
 }
+
+// getCpuidAttributes returns the MIDR-derived identification and the
+// topology of the CPU cores, plus the maximum SVE vector length in bits
+// (sve_vector_length) if SVE is supported.
+func getCpuidAttributes() map[string]string {
+	attrs := getArmCpuAttributes()
+	if getHwcap()&CPU_ARM64_FEATURE_SVE != 0 {
+		if vl, err := sveMaxVectorLength(); err != nil {
+			klog.ErrorS(err, "failed to get SVE vector length")
+		} else {
+			attrs["sve_vector_length"] = strconv.Itoa(vl * 8)
+		}
+	}
+	return attrs
+}
+
+// sveMaxVectorLength returns the maximum SVE vector length in bytes. The
+// kernel clamps the requested length to the maximum supported by the
+// hardware so request the largest possible and restore the original length
+// of the thread afterwards.
+func sveMaxVectorLength() (int, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	orig, err := unix.PrctlRetInt(unix.PR_SVE_GET_VL, 0, 0, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	vl, err := unix.PrctlRetInt(unix.PR_SVE_SET_VL, unix.PR_SVE_VL_LEN_MASK, 0, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := unix.PrctlRetInt(unix.PR_SVE_SET_VL, uintptr(orig), 0, 0, 0); err != nil {
+		klog.ErrorS(err, "failed to restore SVE vector length")
+	}
+	return vl & unix.PR_SVE_VL_LEN_MASK, nil
+}
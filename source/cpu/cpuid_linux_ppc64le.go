@@ -16,18 +16,6 @@ limitations under the License.
 
 package cpu
 
-/*
-#include <sys/auxv.h>
-
-unsigned long gethwcap() {
-	return getauxval(AT_HWCAP);
-}
-unsigned long gethwcap2() {
-	return getauxval(AT_HWCAP2);
-}
-*/
-import "C"
-
 /*
 all special features for ppc64le should be defined here; canonical list:
 https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/arch/powerpc/include/uapi/asm/cputable.h
@@ -135,8 +123,8 @@ var flag2Names_ppc64le = map[uint64]string{
 
 func getCpuidFlags() []string {
 	r := make([]string, 0, 30)
-	hwcap := uint64(C.gethwcap())
-	hwcap2 := uint64(C.gethwcap2())
+	hwcap := getHwcap()
+	hwcap2 := getHwcap2()
 	for i := uint(0); i < 64; i++ {
 		key := uint64(1 << i)
 		val, ok := flagNames_ppc64le[key]
@@ -153,3 +141,23 @@ func getCpuidFlags() []string {
 	}
 	return r
 }
+
+// getCpuidAttributes returns the platform (processor generation, e.g.
+// power10) reported by the kernel and the highest supported Power ISA level.
+func getCpuidAttributes() map[string]string {
+	attrs := map[string]string{}
+	if p := getPlatform(); p != "" {
+		attrs["platform"] = p
+	}
+
+	hwcap2 := getHwcap2()
+	switch {
+	case hwcap2&PPC_FEATURE2_ARCH_3_1 != 0:
+		attrs["isa"] = "3.1"
+	case hwcap2&PPC_FEATURE2_ARCH_3_00 != 0:
+		attrs["isa"] = "3.0"
+	case hwcap2&PPC_FEATURE2_ARCH_2_07 != 0:
+		attrs["isa"] = "2.07"
+	}
+	return attrs
+}
@@ -16,15 +16,6 @@ limitations under the License.
 
 package cpu
 
-/*
-#include <sys/auxv.h>
-
-unsigned long gethwcap() {
-	return getauxval(AT_HWCAP);
-}
-*/
-import "C"
-
 /*
 all special features for s390x should be defined here; canonical list:
 https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/arch/s390/include/asm/elf.h
@@ -85,7 +76,7 @@ var flagNames_s390x = map[uint64]string{
 
 func getCpuidFlags() []string {
 	r := make([]string, 0, 20)
-	hwcap := uint64(C.gethwcap())
+	hwcap := getHwcap()
 	for i := uint(0); i < 64; i++ {
 		key := uint64(1 << i)
 		val, ok := flagNames_s390x[key]
@@ -95,3 +86,9 @@ func getCpuidFlags() []string {
 	}
 	return r
 }
+
+// getCpuidAttributes returns selected values read with cpuid. Not available
+// on this architecture.
+func getCpuidAttributes() map[string]string {
+	return map[string]string{}
+}
@@ -23,9 +23,8 @@ import (
 	"strconv"
 	"strings"
 
-	"k8s.io/klog/v2"
-
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
 )
 
 // Discover if c-states are enabled
@@ -39,7 +38,7 @@ func detectCstate() (map[string]string, error) {
 	}
 	cpuidleDir := filepath.Join(sysfsBase, "cpuidle")
 	if _, err := os.Stat(cpuidleDir); os.IsNotExist(err) {
-		klog.V(1).InfoS("cpuidle disabled in the kernel")
+		source.Logger(Name).V(1).Info("cpuidle disabled in the kernel")
 		return cstate, nil
 	}
 
@@ -51,7 +50,7 @@ func detectCstate() (map[string]string, error) {
 
 	if d := strings.TrimSpace(string(driver)); d != "intel_idle" {
 		// Currently only checking intel_idle driver for cstates
-		klog.V(1).InfoS("intel_idle driver is not in use", "currentIdleDriver", d)
+		source.Logger(Name).V(1).Info("intel_idle driver is not in use", "currentIdleDriver", d)
 		return cstate, nil
 	}
 
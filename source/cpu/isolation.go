@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/cpuset"
+	"sigs.k8s.io/yaml"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// isolcpusFlags are the flags that may precede the cpu list of the isolcpus
+// kernel parameter.
+var isolcpusFlags = map[string]bool{"nohz": true, "domain": true, "managed_irq": true}
+
+// isolationCmdlineArgs are the kernel command line parameters isolating cpus
+// from the general scheduler, timer ticks and RCU callbacks.
+var isolationCmdlineArgs = []string{"isolcpus", "nohz_full", "rcu_nocbs"}
+
+// isolationTypes are the types of the attributes of the isolation feature.
+var isolationTypes = map[string]nfdv1alpha1.AttributeType{
+	"isolcpus_count":             nfdv1alpha1.AttributeTypeInt,
+	"nohz_full_count":            nfdv1alpha1.AttributeTypeInt,
+	"rcu_nocbs_count":            nfdv1alpha1.AttributeTypeInt,
+	"isolated_count":             nfdv1alpha1.AttributeTypeInt,
+	"reserved_system_cpus_count": nfdv1alpha1.AttributeTypeInt,
+}
+
+// discoverIsolation detects the cpus isolated with kernel command line
+// parameters and the cpus reserved for system daemons in the kubelet
+// configuration. Each cpu list is published in canonical form together with
+// the number of cpus in it.
+func discoverIsolation(kubeletConfigFile string) map[string]string {
+	attrs := map[string]string{}
+	addCpus := func(name, list string) {
+		cpus, err := cpuset.Parse(list)
+		if err != nil {
+			source.Logger(Name).V(2).Info("failed to parse cpu list", "name", name, "cpuList", list, "err", err)
+			return
+		}
+		attrs[name] = cpus.String()
+		attrs[name+"_count"] = strconv.Itoa(cpus.Size())
+	}
+
+	if cmdline, err := os.ReadFile(hostpath.ProcfsDir.Path("cmdline")); err != nil {
+		source.Logger(Name).V(2).Info("failed to read kernel command line", "err", err)
+	} else {
+		for _, arg := range strings.Fields(string(cmdline)) {
+			k, v, ok := strings.Cut(arg, "=")
+			if !ok {
+				continue
+			}
+			for _, name := range isolationCmdlineArgs {
+				if k != name {
+					continue
+				}
+				if name == "isolcpus" {
+					v = stripIsolcpusFlags(v)
+				}
+				addCpus(name, v)
+			}
+		}
+	}
+
+	// The isolated cpus in effect
+	if isolated, err := os.ReadFile(hostpath.SysfsDir.Path("devices/system/cpu/isolated")); err == nil {
+		addCpus("isolated", strings.TrimSpace(string(isolated)))
+	}
+
+	if kubeletConfigFile != "" {
+		if reserved, err := readReservedSystemCPUs(kubeletConfigFile); err != nil {
+			source.Logger(Name).V(2).Info("failed to read kubelet config", "path", kubeletConfigFile, "err", err)
+		} else if reserved != "" {
+			addCpus("reserved_system_cpus", reserved)
+		}
+	}
+
+	return attrs
+}
+
+// stripIsolcpusFlags removes the flags, e.g. "domain,managed_irq,", from the
+// value of the isolcpus kernel parameter.
+func stripIsolcpusFlags(val string) string {
+	parts := strings.Split(val, ",")
+	for len(parts) > 0 && isolcpusFlags[parts[0]] {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, ",")
+}
+
+// readReservedSystemCPUs reads the reservedSystemCPUs setting from a kubelet
+// configuration file.
+func readReservedSystemCPUs(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	config := struct {
+		ReservedSystemCPUs string `json:"reservedSystemCPUs"`
+	}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", err
+	}
+	return config.ReservedSystemCPUs, nil
+}
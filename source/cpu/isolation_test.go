@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestStripIsolcpusFlags(t *testing.T) {
+	assert.Equal(t, "2-5", stripIsolcpusFlags("2-5"))
+	assert.Equal(t, "2-5,8", stripIsolcpusFlags("nohz,domain,managed_irq,2-5,8"))
+}
+
+func TestDiscoverIsolation(t *testing.T) {
+	hostpath.SetRoot("testdata/isolation")
+	defer hostpath.SetRoot("")
+
+	assert.Equal(t, map[string]string{
+		"isolcpus":                   "2-9,12-19",
+		"isolcpus_count":             "16",
+		"nohz_full":                  "2-9,12-19",
+		"nohz_full_count":            "16",
+		"rcu_nocbs":                  "2-9,12-19",
+		"rcu_nocbs_count":            "16",
+		"isolated":                   "2-9,12-19",
+		"isolated_count":             "16",
+		"reserved_system_cpus":       "0-1,10-11",
+		"reserved_system_cpus_count": "4",
+	}, discoverIsolation(hostpath.VarDir.Path("lib/kubelet/config.yaml")))
+
+	// Nothing is detected on a system without isolated cpus
+	hostpath.SetRoot("testdata/nonexistent")
+	assert.Empty(t, discoverIsolation(hostpath.VarDir.Path("lib/kubelet/config.yaml")))
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+// midr holds the fields of the MIDR_EL1 (Main ID Register) of an Arm CPU.
+type midr struct {
+	implementer  uint64
+	variant      uint64
+	architecture uint64
+	part         uint64
+	revision     uint64
+}
+
+// parseMidr parses the hexadecimal MIDR_EL1 value exposed in sysfs.
+func parseMidr(s string) (midr, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(s), "0x"), 16, 64)
+	if err != nil {
+		return midr{}, fmt.Errorf("invalid MIDR value %q: %w", s, err)
+	}
+	return midr{
+		implementer:  (v >> 24) & 0xff,
+		variant:      (v >> 20) & 0xf,
+		architecture: (v >> 16) & 0xf,
+		part:         (v >> 4) & 0xfff,
+		revision:     v & 0xf,
+	}, nil
+}
+
+// getArmCpuAttributes returns attributes describing the Arm CPU cores of the
+// system, read from sysfs: the MIDR fields of the boot CPU (implementer,
+// variant, part and revision), the number of distinct core types
+// (core_types), the number of clusters (cluster_count) and whether the cores
+// have different capacities (big_little). Attributes that cannot be
+// determined are omitted.
+func getArmCpuAttributes() map[string]string {
+	attrs := map[string]string{}
+
+	cpus, err := filepath.Glob(hostpath.SysfsDir.Path("devices/system/cpu/cpu[0-9]*"))
+	if err != nil || len(cpus) == 0 {
+		klog.ErrorS(err, "failed to list cpus")
+		return attrs
+	}
+	// Sort numerically so that cpu0 (the boot CPU) comes first
+	sort.Slice(cpus, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(cpus[i]), "cpu"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(cpus[j]), "cpu"))
+		return a < b
+	})
+
+	coreTypes := sets.New[midr]()
+	clusters := sets.New[string]()
+	capacities := sets.New[string]()
+	for _, cpu := range cpus {
+		if data, err := os.ReadFile(filepath.Join(cpu, "regs/identification/midr_el1")); err == nil {
+			m, err := parseMidr(string(data))
+			if err != nil {
+				klog.ErrorS(err, "failed to parse midr_el1", "path", cpu)
+				continue
+			}
+			if coreTypes.Len() == 0 {
+				attrs["implementer"] = fmt.Sprintf("0x%02x", m.implementer)
+				attrs["variant"] = fmt.Sprintf("0x%x", m.variant)
+				attrs["part"] = fmt.Sprintf("0x%03x", m.part)
+				attrs["revision"] = strconv.FormatUint(m.revision, 10)
+			}
+			// Revision and variant do not make a different core type
+			coreTypes.Insert(midr{implementer: m.implementer, architecture: m.architecture, part: m.part})
+		}
+		if data, err := os.ReadFile(filepath.Join(cpu, "topology/cluster_id")); err == nil {
+			clusters.Insert(strings.TrimSpace(string(data)))
+		}
+		if data, err := os.ReadFile(filepath.Join(cpu, "cpu_capacity")); err == nil {
+			capacities.Insert(strings.TrimSpace(string(data)))
+		}
+	}
+
+	if coreTypes.Len() > 0 {
+		attrs["core_types"] = strconv.Itoa(coreTypes.Len())
+	}
+	if clusters.Len() > 0 {
+		attrs["cluster_count"] = strconv.Itoa(clusters.Len())
+	}
+	if capacities.Len() > 0 {
+		attrs["big_little"] = strconv.FormatBool(capacities.Len() > 1 || coreTypes.Len() > 1)
+	}
+
+	return attrs
+}
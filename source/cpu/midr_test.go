@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestParseMidr(t *testing.T) {
+	m, err := parseMidr("0x00000000410fd0c1\n")
+	assert.NoError(t, err)
+	assert.Equal(t, midr{implementer: 0x41, variant: 0, architecture: 0xf, part: 0xd0c, revision: 1}, m)
+
+	_, err = parseMidr("foo")
+	assert.Error(t, err)
+}
+
+func TestGetArmCpuAttributes(t *testing.T) {
+	hostpath.SetRoot("testdata/arm64")
+	defer hostpath.SetRoot("")
+
+	assert.Equal(t, map[string]string{
+		"implementer":   "0x41",
+		"variant":       "0x1",
+		"part":          "0xd05",
+		"revision":      "0",
+		"core_types":    "2",
+		"cluster_count": "1",
+		"big_little":    "true",
+	}, getArmCpuAttributes())
+
+	// Nothing is detected on a system without the sysfs files
+	hostpath.SetRoot("testdata/nonexistent")
+	assert.Empty(t, getArmCpuAttributes())
+}
@@ -24,6 +24,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
 )
 
 // Discover p-state related features such as turbo boost.
@@ -35,7 +36,7 @@ func detectPstate() (map[string]string, error) {
 	}
 	pstateDir := filepath.Join(sysfsBase, "intel_pstate")
 	if _, err := os.Stat(pstateDir); os.IsNotExist(err) {
-		klog.V(1).InfoS("intel pstate driver not enabled")
+		source.Logger(Name).V(1).Info("intel pstate driver not enabled")
 		return nil, nil
 	}
 
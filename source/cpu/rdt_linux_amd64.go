@@ -21,13 +21,13 @@ package cpu
 
 import (
 	"github.com/openshift/node-feature-discovery/pkg/cpuid"
+	"github.com/openshift/node-feature-discovery/source"
 	"bytes"
 	"os"
 	"path/filepath"
 	"strconv"
 
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -102,28 +102,29 @@ func discoverRDT() map[string]string {
 }
 
 func getNumClosID(level string) int64 {
+	logger := source.Logger(Name)
 	resctrlRootDir, err := intelrdt.Root()
 	if err != nil {
-		klog.V(4).ErrorS(err, "can't find resctrl filesystem")
+		logger.V(4).Info("can't find resctrl filesystem", "err", err)
 		return -1
 	}
 
 	closidFile := filepath.Join(resctrlRootDir, "info", level, "num_closids")
 
 	if _, err := os.Stat(closidFile); err != nil {
-		klog.V(4).ErrorS(err, "failed to stat file", "path", closidFile)
+		logger.V(4).Info("failed to stat file", "path", closidFile, "err", err)
 		return -1
 	}
 
 	closidsBytes, err := os.ReadFile(filepath.Join(resctrlRootDir, "info", level, "num_closids"))
 	if err != nil {
-		klog.V(4).ErrorS(err, "failed to read file", "path", closidFile)
+		logger.V(4).Info("failed to read file", "path", closidFile, "err", err)
 		return -1
 	}
 
 	numClosIDs, err := strconv.ParseInt(string(bytes.TrimSpace(closidsBytes)), 10, 64)
 	if err != nil {
-		klog.V(4).ErrorS(err, "failed to parse num_closids", "value", string(bytes.TrimSpace(closidsBytes)))
+		logger.V(4).Info("failed to parse num_closids", "value", string(bytes.TrimSpace(closidsBytes)), "err", err)
 		return -1
 	}
 
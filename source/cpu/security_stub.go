@@ -1,5 +1,6 @@
-//go:build !(amd64 || s390x)
-// +build !amd64,!s390x
+//go:build !((amd64 && linux) || s390x)
+// +build !amd64 !linux
+// +build !s390x
 
 /*
 Copyright 2021 The Kubernetes Authors.
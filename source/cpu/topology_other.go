@@ -0,0 +1,86 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// probeInputs checks the sysfs inputs of the cstate, pstate and topology
+// features. The rest of the features are read with CPUID.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	cpuDir := hostpath.SysfsDir.Path("devices/system/cpu")
+	return source.InputStatuses{
+		source.ProbeInput(CstateFeature, cpuDir, false, minimalPrivileges),
+		source.ProbeInput(PstateFeature, cpuDir, false, minimalPrivileges),
+		source.ProbeInput(TopologyFeature, hostpath.SysfsDir.Path("bus/cpu/devices"), false, minimalPrivileges),
+	}
+}
+
+func discoverTopology() map[string]string {
+	features := make(map[string]string)
+
+	files, err := os.ReadDir(hostpath.SysfsDir.Path("bus/cpu/devices"))
+	if err != nil {
+		klog.ErrorS(err, "failed to read devices folder")
+		return features
+	}
+
+	ht := false
+	uniquePhysicalIDs := sets.NewString()
+
+	for _, file := range files {
+		// Try to read siblings from topology
+		siblings, err := os.ReadFile(hostpath.SysfsDir.Path("bus/cpu/devices", file.Name(), "topology/thread_siblings_list"))
+		if err != nil {
+			klog.ErrorS(err, "error while reading thread_sigblings_list file")
+			return map[string]string{}
+		}
+		for _, char := range siblings {
+			// If list separator found, we determine that there are multiple siblings
+			if char == ',' || char == '-' {
+				ht = true
+				break
+			}
+		}
+
+		// Try to read physical_package_id from topology
+		physicalID, err := os.ReadFile(hostpath.SysfsDir.Path("bus/cpu/devices", file.Name(), "topology/physical_package_id"))
+		if err != nil {
+			klog.ErrorS(err, "error while reading physical_package_id file")
+			return map[string]string{}
+		}
+		id := strings.TrimSpace(string(physicalID))
+		uniquePhysicalIDs.Insert(id)
+	}
+
+	features["hardware_multithreading"] = strconv.FormatBool(ht)
+	features["socket_count"] = strconv.FormatInt(int64(uniquePhysicalIDs.Len()), 10)
+
+	return features
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cpu
+
+import (
+	"encoding/binary"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// probeInputs returns no inputs on Windows, the topology is read with the
+// Win32 API.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	return nil
+}
+
+const (
+	relationProcessorCore    = 0
+	relationProcessorPackage = 3
+
+	// ltpPcSmt is set in the flags of a processor core that has more than
+	// one logical processor
+	ltpPcSmt = 0x1
+)
+
+var procGetLogicalProcessorInformationEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetLogicalProcessorInformationEx")
+
+func discoverTopology() map[string]string {
+	features := make(map[string]string)
+
+	buf, err := getLogicalProcessorInformationEx()
+	if err != nil {
+		klog.ErrorS(err, "failed to get logical processor information")
+		return features
+	}
+
+	ht := false
+	sockets := 0
+	// The buffer is a sequence of variable-sized
+	// SYSTEM_LOGICAL_PROCESSOR_INFORMATION_EX structures: relationship
+	// (uint32), size (uint32) and the relationship specific data
+	for len(buf) >= 8 {
+		relationship := binary.LittleEndian.Uint32(buf[0:4])
+		size := binary.LittleEndian.Uint32(buf[4:8])
+		if size < 8 || int(size) > len(buf) {
+			break
+		}
+		switch relationship {
+		case relationProcessorCore:
+			if size > 8 && buf[8]&ltpPcSmt != 0 {
+				ht = true
+			}
+		case relationProcessorPackage:
+			sockets++
+		}
+		buf = buf[size:]
+	}
+
+	features["hardware_multithreading"] = strconv.FormatBool(ht)
+	features["socket_count"] = strconv.Itoa(sockets)
+
+	return features
+}
+
+// getLogicalProcessorInformationEx returns the raw processor core and package
+// information of all processor groups.
+func getLogicalProcessorInformationEx() ([]byte, error) {
+	const relationAll = 0xffff
+
+	var size uint32
+	// The first call returns the required buffer size
+	r, _, err := procGetLogicalProcessorInformationEx.Call(relationAll, 0, uintptr(unsafe.Pointer(&size)))
+	if r == 0 && err != windows.ERROR_INSUFFICIENT_BUFFER {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	r, _, err = procGetLogicalProcessorInformationEx.Call(relationAll, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return nil, err
+	}
+	return buf[:size], nil
+}
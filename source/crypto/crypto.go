@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "crypto"
+
+const (
+	// AlgorithmFeature holds the algorithm implementations registered in
+	// the kernel crypto API.
+	AlgorithmFeature = "algorithm"
+	// AcceleratedFeature holds the names of the algorithms that have a
+	// hardware accelerated implementation.
+	AcceleratedFeature = "accelerated"
+	// OffloadFeature holds the presence of crypto offload devices.
+	OffloadFeature = "offload"
+)
+
+// acceleratedDriverRe matches the drivers of algorithm implementations that
+// use cpu crypto instructions or offload devices.
+var acceleratedDriverRe = regexp.MustCompile(`aesni|avx|ssse3|sse2|sse4|pclmul|clmul|vaes|intel|-ce\b|-ce-|neon|padlock|s390|qat|caam|ccp|ccree|hisi|nx-|octeontx|safexcel`)
+
+// qatDrivers are the names of the PCI drivers of Intel QuickAssist
+// Technology (QAT) devices, physical and virtual functions.
+var qatDrivers = []string{"4xxx", "4xxxvf", "420xx", "c3xxx", "c3xxxvf", "c62x", "c62xvf", "c6xx", "c6xxvf", "dh895xcc", "dh895xccvf"}
+
+// caamDrivers are the names of the platform drivers of NXP Cryptographic
+// Acceleration and Assurance Module (CAAM) devices.
+var caamDrivers = []string{"caam", "caam_jr"}
+
+// offloadTypes are the types of the attributes of the offload feature.
+var offloadTypes = map[string]nfdv1alpha1.AttributeType{
+	"qat":         nfdv1alpha1.AttributeTypeBool,
+	"qat_devices": nfdv1alpha1.AttributeTypeInt,
+	"caam":        nfdv1alpha1.AttributeTypeBool,
+}
+
+// cryptoSource implements the FeatureSource interface.
+type cryptoSource struct {
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                      = cryptoSource{}
+	_   source.FeatureSource = &src
+)
+
+// Name returns the name of the feature source
+func (s *cryptoSource) Name() string { return Name }
+
+// Discover method of the FeatureSource interface
+func (s *cryptoSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	if algs, err := discoverAlgorithms(); err != nil {
+		klog.ErrorS(err, "failed to detect crypto algorithms")
+	} else {
+		s.features.Instances[AlgorithmFeature] = nfdv1alpha1.NewTypedInstanceFeatures(algs)
+		s.features.Flags[AcceleratedFeature] = nfdv1alpha1.NewFlagFeatures(accelerated(algs)...)
+	}
+
+	s.features.Attributes[OffloadFeature] = nfdv1alpha1.NewAttributeFeatures(discoverOffload()).WithTypes(offloadTypes)
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *cryptoSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// discoverAlgorithms parses the algorithm implementations from /proc/crypto.
+// Internal implementations, only usable by other implementations, are not
+// published.
+func discoverAlgorithms() ([]map[string]interface{}, error) {
+	f, err := os.Open(hostpath.ProcfsDir.Path("crypto"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []map[string]interface{}{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	algs := []map[string]interface{}{}
+	seen := map[string]bool{}
+	add := func(entry map[string]string) {
+		if entry["name"] == "" || entry["driver"] == "" || entry["internal"] == "yes" {
+			return
+		}
+		if seen[entry["driver"]] {
+			return
+		}
+		seen[entry["driver"]] = true
+		attrs := map[string]interface{}{
+			"accelerated": acceleratedDriverRe.MatchString(entry["driver"]),
+		}
+		for _, k := range []string{"name", "driver", "module", "type"} {
+			if v, ok := entry[k]; ok {
+				attrs[k] = v
+			}
+		}
+		if p, err := strconv.Atoi(entry["priority"]); err == nil {
+			attrs["priority"] = p
+		}
+		algs = append(algs, attrs)
+	}
+
+	entry := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			add(entry)
+			entry = map[string]string{}
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			entry[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	add(entry)
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return algs, nil
+}
+
+// accelerated returns the names of the algorithms that have an accelerated
+// implementation.
+func accelerated(algs []map[string]interface{}) []string {
+	names := map[string]bool{}
+	for _, a := range algs {
+		if a["accelerated"] == true {
+			names[a["name"].(string)] = true
+		}
+	}
+	ret := make([]string, 0, len(names))
+	for name := range names {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// discoverOffload detects the presence of QAT and CAAM crypto offload
+// devices, i.e. devices bound to their drivers.
+func discoverOffload() map[string]string {
+	qat := countBoundDevices(hostpath.SysfsDir.Path("bus/pci/drivers"), qatDrivers)
+	caam := countBoundDevices(hostpath.SysfsDir.Path("bus/platform/drivers"), caamDrivers)
+	return map[string]string{
+		"qat":         strconv.FormatBool(qat > 0),
+		"qat_devices": strconv.Itoa(qat),
+		"caam":        strconv.FormatBool(caam > 0),
+	}
+}
+
+// driverAttrs are the entries of sysfs driver directories that are not
+// bound devices.
+var driverAttrs = map[string]bool{"bind": true, "unbind": true, "uevent": true, "module": true, "new_id": true, "remove_id": true}
+
+// countBoundDevices returns the number of devices bound to the given drivers.
+func countBoundDevices(driversDir string, drivers []string) int {
+	count := 0
+	for _, driver := range drivers {
+		entries, err := os.ReadDir(filepath.Join(driversDir, driver))
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !driverAttrs[e.Name()] && !strings.HasPrefix(e.Name(), ".") {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func init() {
+	source.Register(&src)
+}
@@ -51,6 +51,37 @@ type Rule struct {
 	// MatchAny specifies a list of matchers one of which must match.
 	// +optional
 	MatchAny []MatchAnyElem `json:"matchAny"`
+
+	// Probes to run before evaluating the rule. The values captured by each
+	// probe are stored as attributes of the probe.<name> feature that can be
+	// matched by this rule and all subsequent rules.
+	// +optional
+	Probes []Probe `json:"probes"`
+}
+
+// Probe captures values from the content of a file or a sysctl. Exactly one
+// of File and Sysctl must be specified. Only the first 1 MiB of the content
+// is read. Invalid probes are ignored when the configuration is loaded.
+type Probe struct {
+	// Name of the probe.
+	Name string `json:"name"`
+
+	// File is the absolute path of the host file to read.
+	// +optional
+	File string `json:"file"`
+
+	// Sysctl is the name of the kernel parameter to read, e.g.
+	// net.ipv4.ip_forward.
+	// +optional
+	Sysctl string `json:"sysctl"`
+
+	// Regexp is matched against the content. The named capture groups are
+	// stored as attributes, or the whole match in the "value" attribute if
+	// there are no named groups. Nothing is stored if the regexp does not
+	// match. If empty, the content with leading and trailing whitespace
+	// removed is stored in the "value" attribute.
+	// +optional
+	Regexp string `json:"regexp"`
 }
 
 // MatchAnyElem specifies one sub-matcher of MatchAny.
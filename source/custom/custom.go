@@ -40,19 +40,26 @@ func newDefaultConfig() *config {
 	return &config{}
 }
 
+// customRule is a rule in the NFD API format with the probes to run before
+// evaluating it.
+type customRule struct {
+	nfdv1alpha1.Rule
+	Probes []compiledProbe `json:"probes,omitempty"`
+}
+
 // customSource implements the LabelSource and ConfigurableSource interfaces.
 type customSource struct {
 	config *config
 	// The rules are stored in the NFD API format that is a superset of our
 	// internal API and provides the functions for rule matching.
-	rules []nfdv1alpha1.Rule
+	rules []customRule
 }
 
 // Singleton source instance
 var (
 	src = customSource{
 		config: &config{},
-		rules:  []nfdv1alpha1.Rule{},
+		rules:  []customRule{},
 	}
 	_ source.LabelSource        = &src
 	_ source.ConfigurableSource = &src
@@ -88,12 +95,20 @@ func (s *customSource) GetLabels() (source.FeatureLabels, error) {
 	features := source.GetAllFeatures()
 
 	labels := source.FeatureLabels{}
-	allFeatureConfig := append(getStaticRules(), s.rules...)
+	allFeatureConfig := []customRule{}
+	for _, rule := range getStaticRules() {
+		allFeatureConfig = append(allFeatureConfig, customRule{Rule: rule})
+	}
+	allFeatureConfig = append(allFeatureConfig, s.rules...)
 	allFeatureConfig = append(allFeatureConfig, getDropinDirRules()...)
-	klog.V(2).InfoS("resolving custom features", "configuration", utils.DelayedDumper(allFeatureConfig))
+	source.Logger(Name).V(2).Info("resolving custom features", "configuration", utils.DelayedDumper(allFeatureConfig))
 	// Iterate over features
 	for _, rule := range allFeatureConfig {
-		ruleOut, err := nodefeaturerule.Execute(&rule, features)
+		// Make the values captured by the probes available for matching
+		runProbes(rule.Probes, features)
+
+		logger := source.Logger(Name+"/"+rule.Name, Name)
+		ruleOut, err := nodefeaturerule.Evaluate(features, &rule.Rule, nodefeaturerule.WithLogger(logger))
 		if err != nil {
 			klog.ErrorS(err, "failed to execute rule")
 			continue
@@ -110,13 +125,14 @@ func (s *customSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
-func convertInternalRulesToNfdApi(in *[]api.Rule) []nfdv1alpha1.Rule {
-	out := make([]nfdv1alpha1.Rule, len(*in))
+func convertInternalRulesToNfdApi(in *[]api.Rule) []customRule {
+	out := make([]customRule, len(*in))
 	for i := range *in {
-		if err := api.ConvertRuleToV1alpha1(&(*in)[i], &out[i]); err != nil {
+		if err := api.ConvertRuleToV1alpha1(&(*in)[i], &out[i].Rule); err != nil {
 			klog.ErrorS(err, "FATAL: API conversion failed")
 			os.Exit(255)
 		}
+		out[i].Probes = compileProbes((*in)[i].Probes)
 	}
 	return out
 }
@@ -22,8 +22,8 @@ import (
 	"strings"
 
 	"k8s.io/klog/v2"
-	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	api "github.com/openshift/node-feature-discovery/source/custom/api"
+	"github.com/openshift/node-feature-discovery/source"
 	"sigs.k8s.io/yaml"
 )
 
@@ -32,20 +32,21 @@ const Directory = "/etc/kubernetes/node-feature-discovery/custom.d"
 
 // getDropinDirRules returns features configured in the "/etc/kubernetes/node-feature-discovery/custom.d"
 // host directory and its 1st level subdirectories, which can be populated e.g. by ConfigMaps
-func getDropinDirRules() []nfdv1alpha1.Rule {
+func getDropinDirRules() []customRule {
 	features := readDir(Directory, true)
-	klog.V(3).InfoS("all custom feature specs from config dir", "featureSpecs", features)
+	source.Logger(Name).V(3).Info("all custom feature specs from config dir", "featureSpecs", features)
 	return features
 }
 
-func readDir(dirName string, recursive bool) []nfdv1alpha1.Rule {
-	features := make([]nfdv1alpha1.Rule, 0)
+func readDir(dirName string, recursive bool) []customRule {
+	logger := source.Logger(Name)
+	features := make([]customRule, 0)
 
-	klog.V(4).InfoS("reading directory", "path", dirName)
+	logger.V(4).Info("reading directory", "path", dirName)
 	files, err := os.ReadDir(dirName)
 	if err != nil {
 		if os.IsNotExist(err) {
-			klog.V(4).InfoS("directory does not exist", "path", dirName)
+			logger.V(4).Info("directory does not exist", "path", dirName)
 		} else {
 			klog.ErrorS(err, "unable to access directory", "path", dirName)
 		}
@@ -57,18 +58,18 @@ func readDir(dirName string, recursive bool) []nfdv1alpha1.Rule {
 
 		if file.IsDir() {
 			if recursive {
-				klog.V(4).InfoS("processing directory", "path", fileName)
+				logger.V(4).Info("processing directory", "path", fileName)
 				features = append(features, readDir(fileName, false)...)
 			} else {
-				klog.V(4).InfoS("skipping directory", "path", fileName)
+				logger.V(4).Info("skipping directory", "path", fileName)
 			}
 			continue
 		}
 		if strings.HasPrefix(file.Name(), ".") {
-			klog.V(4).InfoS("skipping hidden file", "path", fileName)
+			logger.V(4).Info("skipping hidden file", "path", fileName)
 			continue
 		}
-		klog.V(4).InfoS("processing file", "path", fileName)
+		logger.V(4).Info("processing file", "path", fileName)
 
 		bytes, err := os.ReadFile(fileName)
 		if err != nil {
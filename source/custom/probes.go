@@ -0,0 +1,175 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package custom
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+	api "github.com/openshift/node-feature-discovery/source/custom/api"
+)
+
+// ProbeDomain is the feature domain of the values captured by probes.
+const ProbeDomain = "probe"
+
+// hostDirs maps top-level host directories to where they are available to
+// nfd-worker.
+var hostDirs = map[string]*hostpath.HostDir{
+	"boot": &hostpath.BootDir,
+	"etc":  &hostpath.EtcDir,
+	"lib":  &hostpath.LibDir,
+	"proc": &hostpath.ProcfsDir,
+	"sys":  &hostpath.SysfsDir,
+	"usr":  &hostpath.UsrDir,
+	"var":  &hostpath.VarDir,
+}
+
+// maxProbeReadSize is the maximum number of bytes read from the file of a
+// probe. The rest of the content is ignored.
+const maxProbeReadSize = 1 << 20
+
+// compiledProbe is a validated probe with its regexp compiled.
+type compiledProbe struct {
+	api.Probe
+	re *regexp.Regexp
+}
+
+// compileProbes validates the given probes and compiles their regexps.
+// Invalid probes are dropped.
+func compileProbes(probes []api.Probe) []compiledProbe {
+	out := make([]compiledProbe, 0, len(probes))
+	for _, p := range probes {
+		c, err := compileProbe(p)
+		if err != nil {
+			klog.ErrorS(err, "invalid probe, ignoring", "probeName", p.Name)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func compileProbe(p api.Probe) (compiledProbe, error) {
+	c := compiledProbe{Probe: p}
+	if _, err := probePath(&p); err != nil {
+		return c, err
+	}
+	if p.Regexp != "" {
+		re, err := regexp.Compile(p.Regexp)
+		if err != nil {
+			return c, fmt.Errorf("invalid regexp %q: %w", p.Regexp, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
+
+// runProbes runs the given probes and stores the captured values in the
+// features.
+func runProbes(probes []compiledProbe, features *nfdv1alpha1.Features) {
+	for i := range probes {
+		p := &probes[i]
+		values, err := runProbe(p)
+		if err != nil {
+			klog.ErrorS(err, "probe failed", "probeName", p.Name)
+			continue
+		}
+		if values != nil {
+			features.InsertAttributeFeatures(ProbeDomain, p.Name, values)
+		}
+	}
+}
+
+// runProbe reads the content of a probe and returns the captured values.
+func runProbe(p *compiledProbe) (map[string]string, error) {
+	path, err := probePath(&p.Probe)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxProbeReadSize))
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+
+	if p.re == nil {
+		return map[string]string{"value": strings.TrimSpace(content)}, nil
+	}
+
+	m := p.re.FindStringSubmatch(content)
+	if m == nil {
+		source.Logger(Name).V(3).Info("probe regexp did not match", "probeName", p.Name)
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	for i, name := range p.re.SubexpNames() {
+		if i != 0 && name != "" {
+			values[name] = m[i]
+		}
+	}
+	if len(values) == 0 {
+		values["value"] = m[0]
+	}
+	return values, nil
+}
+
+// probePath returns the path of the file to read for a probe.
+func probePath(p *api.Probe) (string, error) {
+	switch {
+	case p.Name == "":
+		return "", fmt.Errorf("probe name must be specified")
+	case p.File != "" && p.Sysctl != "":
+		return "", fmt.Errorf("only one of file and sysctl may be specified")
+	case p.Sysctl != "":
+		return hostpath.ProcfsDir.Path("sys", strings.ReplaceAll(p.Sysctl, ".", "/")), nil
+	case p.File != "":
+		return hostFilePath(p.File)
+	}
+	return "", fmt.Errorf("either file or sysctl must be specified")
+}
+
+// hostFilePath translates an absolute path on the host to the path under
+// which it is available to nfd-worker.
+func hostFilePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("file path %q is not absolute", path)
+	}
+	path = filepath.Clean(path)
+
+	top, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	dir, ok := hostDirs[top]
+	if !ok {
+		return "", fmt.Errorf("file %q is not in any of the host directories available to nfd-worker", path)
+	}
+	return dir.Path(rest), nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package custom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	api "github.com/openshift/node-feature-discovery/source/custom/api"
+)
+
+func TestRunProbes(t *testing.T) {
+	root := t.TempDir()
+	hostpath.SetRoot(root)
+	defer hostpath.SetRoot("")
+
+	writeFile := func(path, content string) {
+		path = filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	writeFile("etc/vendor/version", "product: acme-nic\nfirmware: 3.14\n")
+	writeFile("proc/sys/net/ipv4/ip_forward", "1\n")
+
+	writeFile("etc/vendor/big", strings.Repeat("x", maxProbeReadSize)+"serial: 1234\n")
+
+	probes := compileProbes([]api.Probe{
+		{Name: "fw", File: "/etc/vendor/version", Regexp: `firmware: (?P<major>\d+)\.(?P<minor>\d+)`},
+		{Name: "product", File: "/etc/vendor/version", Regexp: `acme-\w+`},
+		{Name: "forward", Sysctl: "net.ipv4.ip_forward"},
+		{Name: "nomatch", File: "/etc/vendor/version", Regexp: `serial: (?P<serial>\w+)`},
+		{Name: "missing", File: "/etc/vendor/missing"},
+		{Name: "notavailable", File: "/home/user/file"},
+		{Name: "both", File: "/etc/vendor/version", Sysctl: "net.ipv4.ip_forward"},
+		{Name: "badregexp", File: "/etc/vendor/version", Regexp: `(`},
+		{Name: "big", File: "/etc/vendor/big", Regexp: `serial: (?P<serial>\w+)`},
+	})
+	// Invalid probes are dropped when compiled
+	assert.Len(t, probes, 6)
+
+	features := nfdv1alpha1.NewFeatures()
+	runProbes(probes, features)
+
+	// Content beyond maxProbeReadSize is not read
+	assert.Equal(t, map[string]nfdv1alpha1.AttributeFeatureSet{
+		"probe.fw":      nfdv1alpha1.NewAttributeFeatures(map[string]string{"major": "3", "minor": "14"}),
+		"probe.product": nfdv1alpha1.NewAttributeFeatures(map[string]string{"value": "acme-nic"}),
+		"probe.forward": nfdv1alpha1.NewAttributeFeatures(map[string]string{"value": "1"}),
+	}, features.Attributes)
+}
+
+func TestHostFilePath(t *testing.T) {
+	hostpath.SetRoot("/rootfs")
+	defer hostpath.SetRoot("")
+
+	p, err := hostFilePath("/sys/class/net/../net/eth0/mtu")
+	assert.NoError(t, err)
+	assert.Equal(t, "/rootfs/sys/class/net/eth0/mtu", p)
+
+	_, err = hostFilePath("/root/.bashrc")
+	assert.Error(t, err)
+
+	_, err = hostFilePath("etc/os-release")
+	assert.Error(t, err)
+}
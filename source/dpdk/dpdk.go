@@ -0,0 +1,312 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dpdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "dpdk"
+
+const (
+	// ReadyFeature holds the overall DPDK readiness of the node and the
+	// status of each of the requirements.
+	ReadyFeature = "ready"
+	// NicFeature holds the network controllers supported by a DPDK poll
+	// mode driver.
+	NicFeature = "nic"
+)
+
+// pmdDevices maps the PCI vendor and device IDs of common network
+// controllers to the DPDK poll mode driver supporting them.
+var pmdDevices = map[string]string{
+	// Intel
+	"8086:10fb": "ixgbe",
+	"8086:1528": "ixgbe",
+	"8086:1563": "ixgbe",
+	"8086:1572": "i40e",
+	"8086:1583": "i40e",
+	"8086:1584": "i40e",
+	"8086:158b": "i40e",
+	"8086:37d2": "i40e",
+	"8086:154c": "iavf",
+	"8086:1889": "iavf",
+	"8086:1592": "ice",
+	"8086:1593": "ice",
+	"8086:159b": "ice",
+	"8086:188a": "ice",
+	// NVIDIA/Mellanox
+	"15b3:1015": "mlx5",
+	"15b3:1016": "mlx5",
+	"15b3:1017": "mlx5",
+	"15b3:1018": "mlx5",
+	"15b3:1019": "mlx5",
+	"15b3:101b": "mlx5",
+	"15b3:101d": "mlx5",
+	"15b3:101e": "mlx5",
+	"15b3:101f": "mlx5",
+	"15b3:1021": "mlx5",
+	"15b3:a2d6": "mlx5",
+	// Broadcom
+	"14e4:16d7": "bnxt",
+	"14e4:1750": "bnxt",
+	"14e4:1751": "bnxt",
+	"14e4:1752": "bnxt",
+	// Virtual devices
+	"1af4:1000": "virtio",
+	"1af4:1041": "virtio",
+	"1d0f:ec20": "ena",
+	"1d0f:efa0": "ena",
+}
+
+// readyTypes are the types of the attributes of the ready feature.
+var readyTypes = map[string]nfdv1alpha1.AttributeType{
+	"ready":         nfdv1alpha1.AttributeTypeBool,
+	"hugepages":     nfdv1alpha1.AttributeTypeInt,
+	"iommu":         nfdv1alpha1.AttributeTypeBool,
+	"vfio_pci":      nfdv1alpha1.AttributeTypeBool,
+	"pmd_nics":      nfdv1alpha1.AttributeTypeInt,
+	"isolated_cpus": nfdv1alpha1.AttributeTypeInt,
+}
+
+// Config holds the configuration parameters of this source.
+type Config struct {
+	// PmdDevices are additional PCI devices, in "<vendor>:<device>" format,
+	// supported by a DPDK poll mode driver. The values are the names of the
+	// drivers.
+	PmdDevices map[string]string `json:"pmdDevices,omitempty"`
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		PmdDevices: map[string]string{},
+	}
+}
+
+// dpdkSource implements the FeatureSource and ConfigurableSource interfaces.
+type dpdkSource struct {
+	config   *Config
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                           = dpdkSource{config: newDefaultConfig()}
+	_   source.FeatureSource      = &src
+	_   source.ConfigurableSource = &src
+)
+
+// Name returns the name of the feature source
+func (s *dpdkSource) Name() string { return Name }
+
+// NewConfig method of the ConfigurableSource interface
+func (s *dpdkSource) NewConfig() source.Config { return newDefaultConfig() }
+
+// GetConfig method of the ConfigurableSource interface
+func (s *dpdkSource) GetConfig() source.Config { return s.config }
+
+// SetConfig method of the ConfigurableSource interface
+func (s *dpdkSource) SetConfig(conf source.Config) {
+	switch v := conf.(type) {
+	case *Config:
+		s.config = v
+	default:
+		panic(fmt.Sprintf("invalid config type: %T", conf))
+	}
+}
+
+// Discover method of the FeatureSource interface
+func (s *dpdkSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	nics, err := discoverNics(s.config.PmdDevices)
+	if err != nil {
+		klog.ErrorS(err, "failed to detect DPDK compatible network controllers")
+		nics = []nfdv1alpha1.InstanceFeature{}
+	}
+	s.features.Instances[NicFeature] = nfdv1alpha1.NewInstanceFeatures(nics)
+
+	hugepages := discoverHugepages()
+	isolated := discoverIsolatedCpus()
+	iommu := discoverIommu()
+	vfioPci := discoverVfioPci()
+	ready := hugepages > 0 && iommu && vfioPci && len(nics) > 0 && isolated > 0
+
+	s.features.Attributes[ReadyFeature] = nfdv1alpha1.NewAttributeFeatures(map[string]string{
+		"ready":         strconv.FormatBool(ready),
+		"hugepages":     strconv.FormatInt(hugepages, 10),
+		"iommu":         strconv.FormatBool(iommu),
+		"vfio_pci":      strconv.FormatBool(vfioPci),
+		"pmd_nics":      strconv.Itoa(len(nics)),
+		"isolated_cpus": strconv.Itoa(isolated),
+	}).WithTypes(readyTypes)
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *dpdkSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// readTrimmed returns the whitespace-trimmed content of a (sysfs) file.
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// discoverHugepages returns the total number of hugepages (of any size)
+// reserved on the node.
+func discoverHugepages() int64 {
+	hugepagesDir := hostpath.SysfsDir.Path("kernel/mm/hugepages")
+	sizes, err := os.ReadDir(hugepagesDir)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, size := range sizes {
+		val, err := readTrimmed(filepath.Join(hugepagesDir, size.Name(), "nr_hugepages"))
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+			total += n
+		}
+	}
+	return total
+}
+
+// discoverIsolatedCpus returns the number of cpus isolated from the general
+// scheduler, with the isolcpus or nohz_full kernel parameters.
+func discoverIsolatedCpus() int {
+	isolated := 0
+	for _, file := range []string{"isolated", "nohz_full"} {
+		val, err := readTrimmed(hostpath.SysfsDir.Path("devices/system/cpu", file))
+		if err != nil || val == "" || val == "(null)" {
+			continue
+		}
+		if n, err := countCpuList(val); err == nil {
+			isolated = max(isolated, n)
+		}
+	}
+	return isolated
+}
+
+// countCpuList returns the number of cpus in a cpu list, e.g. "2-5,8".
+func countCpuList(list string) (int, error) {
+	count := 0
+	for _, r := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(r, "-")
+		if !isRange {
+			last = first
+		}
+		f, err := strconv.Atoi(first)
+		if err != nil {
+			return 0, err
+		}
+		l, err := strconv.Atoi(last)
+		if err != nil {
+			return 0, err
+		}
+		count += l - f + 1
+	}
+	return count, nil
+}
+
+// discoverIommu detects if an IOMMU is enabled.
+func discoverIommu() bool {
+	iommus, err := os.ReadDir(hostpath.SysfsDir.Path("class/iommu"))
+	return err == nil && len(iommus) > 0
+}
+
+// discoverVfioPci detects if the vfio-pci driver is available.
+func discoverVfioPci() bool {
+	_, err := os.Stat(hostpath.SysfsDir.Path("bus/pci/drivers/vfio-pci"))
+	return err == nil
+}
+
+// discoverNics returns the network controllers supported by a DPDK poll
+// mode driver, together with the kernel driver currently bound to them.
+func discoverNics(extraDevices map[string]string) ([]nfdv1alpha1.InstanceFeature, error) {
+	devicesDir := hostpath.SysfsDir.Path("bus/pci/devices")
+	devices, err := os.ReadDir(devicesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []nfdv1alpha1.InstanceFeature{}, nil
+		}
+		return nil, err
+	}
+
+	nics := []nfdv1alpha1.InstanceFeature{}
+	for _, dev := range devices {
+		devPath := filepath.Join(devicesDir, dev.Name())
+		class, err := readTrimmed(filepath.Join(devPath, "class"))
+		if err != nil || !strings.HasPrefix(class, "0x02") {
+			continue
+		}
+		vendor, err := readTrimmed(filepath.Join(devPath, "vendor"))
+		if err != nil {
+			continue
+		}
+		device, err := readTrimmed(filepath.Join(devPath, "device"))
+		if err != nil {
+			continue
+		}
+		id := strings.TrimPrefix(vendor, "0x") + ":" + strings.TrimPrefix(device, "0x")
+		pmd, ok := extraDevices[id]
+		if !ok {
+			if pmd, ok = pmdDevices[id]; !ok {
+				continue
+			}
+		}
+		attrs := map[string]string{
+			"address": dev.Name(),
+			"vendor":  strings.TrimPrefix(vendor, "0x"),
+			"device":  strings.TrimPrefix(device, "0x"),
+			"pmd":     pmd,
+		}
+		if driver, err := os.Readlink(filepath.Join(devPath, "driver")); err == nil {
+			attrs["driver"] = filepath.Base(driver)
+		}
+		nics = append(nics, *nfdv1alpha1.NewInstanceFeature(attrs))
+	}
+	return nics, nil
+}
+
+func init() {
+	source.Register(&src)
+}
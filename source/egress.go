@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+)
+
+// ErrEgressDisabled is returned for network requests of the sources when all
+// outbound traffic has been disabled in the egress policy.
+var ErrEgressDisabled = errors.New("outbound network traffic disabled by the egress policy")
+
+// EgressPolicy controls the outbound network traffic of the feature sources.
+// It is enforced by the http clients created with NewHTTPClient.
+type EgressPolicy struct {
+	// DisableAll disables all outbound network traffic, e.g. in airgapped
+	// clusters.
+	DisableAll bool `json:"disableAll,omitempty"`
+	// Proxy is the URL of the proxy for all requests. If empty, the proxy is
+	// determined by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables.
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy lists the hosts that are accessed directly when Proxy is set.
+	// Entries are host names, domain suffixes starting with a dot, IP
+	// addresses or CIDRs.
+	NoProxy []string `json:"noProxy,omitempty"`
+	// Timeout is the upper limit of the timeouts of the sources for one
+	// request. Zero means no limit.
+	Timeout utils.DurationVal `json:"timeout,omitempty"`
+}
+
+// Validate checks that the policy is valid.
+func (p *EgressPolicy) Validate() error {
+	if p.Proxy != "" {
+		u, err := url.Parse(p.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy %q: scheme and host must be specified", p.Proxy)
+		}
+	}
+	for _, n := range p.NoProxy {
+		if strings.Contains(n, "/") {
+			if _, _, err := net.ParseCIDR(n); err != nil {
+				return fmt.Errorf("invalid noProxy entry %q: %w", n, err)
+			}
+		}
+	}
+	if p.Timeout.Duration < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// egressPolicy is the policy in effect, changed on re-configuration.
+var (
+	egressPolicy   EgressPolicy
+	egressPolicyMu sync.RWMutex
+)
+
+// SetEgressPolicy sets the egress policy of the feature sources. Disabling
+// outbound traffic also applies to the clients created before, the other
+// settings to the clients created after the call.
+func SetEgressPolicy(p EgressPolicy) {
+	egressPolicyMu.Lock()
+	defer egressPolicyMu.Unlock()
+	egressPolicy = p
+}
+
+// getEgressPolicy returns the egress policy in effect.
+func getEgressPolicy() EgressPolicy {
+	egressPolicyMu.RLock()
+	defer egressPolicyMu.RUnlock()
+	return egressPolicy
+}
+
+// EgressDisabled returns true if all outbound network traffic has been
+// disabled. Sources that only use the network should skip discovery then.
+func EgressDisabled() bool {
+	return getEgressPolicy().DisableAll
+}
+
+// NewHTTPClient returns an http client for the network requests of a feature
+// source. The client applies the egress policy in effect: it uses the
+// configured proxy and limits the timeout of the source to the timeout of
+// the policy. Requests fail with ErrEgressDisabled whenever outbound traffic
+// is disabled, also if the policy changes after creating the client.
+// Requests honor the context of the request, so sources can cancel them
+// earlier.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	policy := getEgressPolicy()
+	if policy.Timeout.Duration > 0 && (timeout <= 0 || policy.Timeout.Duration < timeout) {
+		timeout = policy.Timeout.Duration
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(policy)
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &egressTransport{transport: transport},
+	}
+}
+
+// egressTransport is an http.RoundTripper refusing all requests when
+// outbound traffic is disabled.
+type egressTransport struct {
+	transport http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if EgressDisabled() {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Redacted(), ErrEgressDisabled)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// proxyFunc returns the proxy function of the http transport for the policy.
+func proxyFunc(policy EgressPolicy) func(*http.Request) (*url.URL, error) {
+	if policy.Proxy == "" {
+		return http.ProxyFromEnvironment
+	}
+	// Validated in Validate()
+	proxyURL, _ := url.Parse(policy.Proxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy(req.URL.Hostname(), policy.NoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+}
+
+// noProxy returns true if the host matches one of the noProxy entries.
+func noProxy(host string, entries []string) bool {
+	ip := net.ParseIP(host)
+	for _, e := range entries {
+		switch {
+		case e == "*":
+			return true
+		case strings.Contains(e, "/"):
+			if _, cidr, err := net.ParseCIDR(e); err == nil && ip != nil && cidr.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(e, "."):
+			if strings.HasSuffix(host, e) || host == e[1:] {
+				return true
+			}
+		case host == e:
+			return true
+		}
+	}
+	return false
+}
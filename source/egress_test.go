@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	source "github.com/openshift/node-feature-discovery/source"
+)
+
+func TestEgressPolicy(t *testing.T) {
+	t.Cleanup(func() { source.SetEgressPolicy(source.EgressPolicy{}) })
+
+	var proxied bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+		_, _ = w.Write([]byte("proxy"))
+	}))
+	t.Cleanup(proxy.Close)
+	target := "http://metadata.example.com/latest"
+
+	// Requests go through the proxy
+	source.SetEgressPolicy(source.EgressPolicy{Proxy: proxy.URL})
+	client := source.NewHTTPClient(2 * time.Second)
+	resp, err := client.Get(target)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, proxied)
+
+	// Disabling outbound traffic applies to existing clients, too
+	source.SetEgressPolicy(source.EgressPolicy{DisableAll: true})
+	assert.True(t, source.EgressDisabled())
+	_, err = client.Get(target)
+	assert.ErrorIs(t, err, source.ErrEgressDisabled)
+
+	// Hosts in noProxy are accessed directly
+	proxied = false
+	source.SetEgressPolicy(source.EgressPolicy{Proxy: proxy.URL, NoProxy: []string{"127.0.0.0/8"}})
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(direct.Close)
+	resp, err = source.NewHTTPClient(0).Get(direct.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.False(t, proxied)
+
+	// The timeout of the policy limits the timeout of the source
+	source.SetEgressPolicy(source.EgressPolicy{Timeout: utils.DurationVal{Duration: time.Second}})
+	assert.Equal(t, time.Second, source.NewHTTPClient(0).Timeout)
+	assert.Equal(t, time.Second, source.NewHTTPClient(time.Minute).Timeout)
+	assert.Equal(t, time.Millisecond, source.NewHTTPClient(time.Millisecond).Timeout)
+}
+
+func TestEgressPolicyValidate(t *testing.T) {
+	assert.NoError(t, (&source.EgressPolicy{Proxy: "http://proxy:3128", NoProxy: []string{"10.0.0.0/8", ".internal"}}).Validate())
+	assert.Error(t, (&source.EgressPolicy{Proxy: "proxy"}).Validate())
+	assert.Error(t, (&source.EgressPolicy{NoProxy: []string{"10.0.0.0/33"}}).Validate())
+	assert.Error(t, (&source.EgressPolicy{Timeout: utils.DurationVal{Duration: -time.Second}}).Validate())
+}
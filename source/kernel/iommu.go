@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// iommuCmdlineArgs are the kernel command line parameters controlling IOMMU
+// operation that are published as-is.
+var iommuCmdlineArgs = []string{"intel_iommu", "amd_iommu", "iommu"}
+
+// discoverIommu detects IOMMU enablement, the number of IOMMU groups and
+// the readiness of the vfio-pci driver for device passthrough.
+func discoverIommu() (map[string]string, error) {
+	iommus, err := os.ReadDir(hostpath.SysfsDir.Path("class/iommu"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read iommu class: %w", err)
+	}
+
+	attrs := map[string]string{
+		"enabled": strconv.FormatBool(len(iommus) > 0),
+	}
+
+	if len(iommus) > 0 {
+		attrs["type"] = iommuType(iommus[0].Name())
+	}
+
+	groups, err := os.ReadDir(hostpath.SysfsDir.Path("kernel/iommu_groups"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read iommu groups: %w", err)
+	}
+	attrs["group_count"] = strconv.Itoa(len(groups))
+
+	if cmdline, err := os.ReadFile(hostpath.ProcfsDir.Path("cmdline")); err != nil {
+		source.Logger(Name).V(2).Info("failed to read kernel command line", "err", err)
+	} else {
+		for _, arg := range strings.Fields(string(cmdline)) {
+			k, v, ok := strings.Cut(arg, "=")
+			if !ok {
+				continue
+			}
+			for _, name := range iommuCmdlineArgs {
+				if k == name {
+					attrs[name] = v
+				}
+			}
+			if k == "iommu" && v == "pt" {
+				attrs["passthrough"] = "true"
+			}
+		}
+	}
+
+	_, err = os.Stat(hostpath.SysfsDir.Path("bus/pci/drivers/vfio-pci"))
+	attrs["vfio_pci"] = strconv.FormatBool(err == nil)
+
+	if noiommu, err := os.ReadFile(hostpath.SysfsDir.Path("module/vfio/parameters/enable_unsafe_noiommu_mode")); err == nil {
+		attrs["vfio_noiommu"] = strconv.FormatBool(strings.TrimSpace(string(noiommu)) == "Y")
+	}
+
+	return attrs, nil
+}
+
+// iommuType maps the name of an iommu class device to the IOMMU
+// implementation.
+func iommuType(name string) string {
+	switch {
+	case strings.HasPrefix(name, "dmar"):
+		return "intel"
+	case strings.HasPrefix(name, "ivhd"):
+		return "amd"
+	case strings.HasPrefix(name, "smmu"):
+		return "arm-smmu"
+	default:
+		return strings.TrimRight(name, "0123456789.")
+	}
+}
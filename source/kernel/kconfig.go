@@ -23,6 +23,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
@@ -47,39 +48,60 @@ func readKconfigGzip(filename string) ([]byte, error) {
 	return io.ReadAll(r)
 }
 
-// parseKconfig reads Linux kernel configuration and returns all set options
-// and their values. It returns two copies of the parsed options: one with
-// values exactly as they are presented in the kernel configuration file (with
-// the exception that leading and trailing quotes are stripped) and one where
-// '=y' and '=m' are converted to 'true'.
-func parseKconfig(configPath string) (realKconfig, legacyKconfig map[string]string, err error) {
-	realKconfig = map[string]string{}
-	legacyKconfig = map[string]string{}
-
-	raw := []byte(nil)
-	var searchPaths []string
+// kconfig holds the parsed Linux kernel configuration.
+type kconfig struct {
+	// real contains all set options with values exactly as they are
+	// presented in the kernel configuration file (with the exception that
+	// leading and trailing quotes are stripped).
+	real map[string]string
+	// legacy is a copy of real where '=y' and '=m' are converted to 'true'.
+	legacy map[string]string
+	// tristate contains all bool and tristate options with values 'y', 'm'
+	// or 'n', including the options that are not set.
+	tristate map[string]string
+	// number contains all int and hex options, converted to decimal.
+	number map[string]string
+}
 
+// kconfigSearchPaths returns the locations where the kernel config is looked
+// for, in order of preference.
+func kconfigSearchPaths() []string {
 	kVer, err := getVersion()
 	if err != nil {
-		searchPaths = []string{
-			"/proc/config.gz",
-			hostpath.UsrDir.Path("src/linux/.config"),
-		}
-	} else {
-		// from k8s.io/system-validator used by kubeadm
-		// preflight checks
-		searchPaths = []string{
-			"/proc/config.gz",
-			hostpath.UsrDir.Path("src/linux-" + kVer + "/.config"),
+		return []string{
+			hostpath.ProcfsDir.Path("config.gz"),
 			hostpath.UsrDir.Path("src/linux/.config"),
-			hostpath.UsrDir.Path("lib/modules/" + kVer + "/config"),
-			hostpath.UsrDir.Path("lib/ostree-boot/config-" + kVer),
-			hostpath.UsrDir.Path("lib/kernel/config-" + kVer),
-			hostpath.UsrDir.Path("src/linux-headers-" + kVer + "/.config"),
-			"/lib/modules/" + kVer + "/build/.config",
-			hostpath.BootDir.Path("config-" + kVer),
 		}
 	}
+	// from k8s.io/system-validator used by kubeadm
+	// preflight checks
+	return []string{
+		hostpath.ProcfsDir.Path("config.gz"),
+		hostpath.UsrDir.Path("src/linux-" + kVer + "/.config"),
+		hostpath.UsrDir.Path("src/linux/.config"),
+		hostpath.UsrDir.Path("lib/modules/" + kVer + "/config"),
+		hostpath.LibDir.Path("modules/" + kVer + "/config"),
+		hostpath.UsrDir.Path("lib/ostree-boot/config-" + kVer),
+		hostpath.UsrDir.Path("lib/kernel/config-" + kVer),
+		hostpath.UsrDir.Path("src/linux-headers-" + kVer + "/.config"),
+		"/lib/modules/" + kVer + "/build/.config",
+		hostpath.BootDir.Path("config-" + kVer),
+	}
+}
+
+// parseKconfig reads Linux kernel configuration and returns all options and
+// their values.
+func parseKconfig(configPath string) (*kconfig, error) {
+	kc := &kconfig{
+		real:     map[string]string{},
+		legacy:   map[string]string{},
+		tristate: map[string]string{},
+		number:   map[string]string{},
+	}
+
+	raw := []byte(nil)
+	searchPaths := kconfigSearchPaths()
+	var err error
 
 	for _, path := range append([]string{configPath}, searchPaths...) {
 		if len(path) > 0 {
@@ -96,7 +118,7 @@ func parseKconfig(configPath string) (realKconfig, legacyKconfig map[string]stri
 	}
 
 	if raw == nil {
-		return nil, nil, fmt.Errorf("failed to read kernel config from %+v", append([]string{configPath}, searchPaths...))
+		return nil, fmt.Errorf("failed to read kernel config from %+v", append([]string{configPath}, searchPaths...))
 	}
 
 	// Process data, line-by-line
@@ -112,16 +134,27 @@ func parseKconfig(configPath string) (realKconfig, legacyKconfig map[string]stri
 			name := split[0][7:]
 			value := strings.Trim(split[1], `"`)
 
-			realKconfig[name] = value
+			kc.real[name] = value
 
 			// Provide the "mangled" kconfig values for backwards compatibility
 			if split[1] == "y" || split[1] == "m" {
-				legacyKconfig[name] = "true"
+				kc.legacy[name] = "true"
 			} else {
-				legacyKconfig[name] = value
+				kc.legacy[name] = value
+			}
+
+			if split[1] == "y" || split[1] == "m" {
+				kc.tristate[name] = split[1]
+			} else if !strings.HasPrefix(split[1], `"`) {
+				if n, err := strconv.ParseInt(split[1], 0, 64); err == nil {
+					kc.number[name] = strconv.FormatInt(n, 10)
+				}
 			}
+		} else if strings.HasPrefix(str, "# CONFIG_") && strings.HasSuffix(str, " is not set") {
+			name := strings.TrimSuffix(strings.TrimPrefix(str, "# CONFIG_"), " is not set")
+			kc.tristate[name] = "n"
 		}
 	}
 
-	return realKconfig, legacyKconfig, nil
+	return kc, nil
 }
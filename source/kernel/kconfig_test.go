@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKconfigGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.gz")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte("CONFIG_BPF=y\nCONFIG_KVM_INTEL=m\n# CONFIG_KVM_AMD is not set\nCONFIG_HZ=1000\nCONFIG_CMDLINE=\"quiet\"\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	kc, err := parseKconfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"BPF": "y", "KVM_INTEL": "m", "HZ": "1000", "CMDLINE": "quiet"}, kc.real)
+	assert.Equal(t, map[string]string{"BPF": "true", "KVM_INTEL": "true", "HZ": "1000", "CMDLINE": "quiet"}, kc.legacy)
+	assert.Equal(t, map[string]string{"BPF": "y", "KVM_INTEL": "m", "KVM_AMD": "n"}, kc.tristate)
+	assert.Equal(t, map[string]string{"HZ": "1000"}, kc.number)
+}
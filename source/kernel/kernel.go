@@ -18,12 +18,13 @@ package kernel
 
 import (
 	"fmt"
-	"strconv"
+	"strings"
 
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
@@ -31,17 +32,45 @@ import (
 const Name = "kernel"
 
 const (
-	ConfigFeature        = "config"
-	LoadedModuleFeature  = "loadedmodule"
-	SelinuxFeature       = "selinux"
-	VersionFeature       = "version"
-	EnabledModuleFeature = "enabledmodule"
+	ConfigFeature         = "config"
+	ConfigTristateFeature = "configtristate"
+	ConfigNumberFeature   = "confignumber"
+	LoadedModuleFeature   = "loadedmodule"
+	SelinuxFeature        = "selinux"
+	VersionFeature        = "version"
+	EnabledModuleFeature  = "enabledmodule"
+	IommuFeature          = "iommu"
+	LsmFeature            = "lsm"
+	AppArmorFeature       = "apparmor"
+	SeccompFeature        = "seccomp"
+	RealtimeFeature       = "realtime"
+	SysctlFeature         = "sysctl"
 )
 
+// versionTypes are the types of the attributes of the version feature.
+var versionTypes = map[string]nfdv1alpha1.AttributeType{
+	"major":    nfdv1alpha1.AttributeTypeInt,
+	"minor":    nfdv1alpha1.AttributeTypeInt,
+	"revision": nfdv1alpha1.AttributeTypeInt,
+}
+
+// iommuTypes are the types of the attributes of the iommu feature.
+var iommuTypes = map[string]nfdv1alpha1.AttributeType{
+	"enabled":      nfdv1alpha1.AttributeTypeBool,
+	"group_count":  nfdv1alpha1.AttributeTypeInt,
+	"passthrough":  nfdv1alpha1.AttributeTypeBool,
+	"vfio_pci":     nfdv1alpha1.AttributeTypeBool,
+	"vfio_noiommu": nfdv1alpha1.AttributeTypeBool,
+}
+
 // Configuration file options
 type Config struct {
 	KconfigFile string
 	ConfigOpts  []string `json:"configOpts,omitempty"`
+	// TypedKconfig enables the configtristate and confignumber features.
+	TypedKconfig bool `json:"typedKconfig,omitempty"`
+	// Sysctls is the list of sysctls published in the sysctl feature.
+	Sysctls []string `json:"sysctls,omitempty"`
 }
 
 // newDefaultConfig returns a new config with pre-populated defaults
@@ -64,6 +93,9 @@ type kernelSource struct {
 	// legacyKconfig contains mangled kconfig values used for
 	// kernel.config-<flag> labels and legacy kConfig custom rules.
 	legacyKconfig map[string]string
+	inputs        source.InputStatuses
+	// kconfigPath is the kernel config file found when probing
+	kconfigPath string
 }
 
 // Singleton source instance
@@ -72,6 +104,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.ProbingSource      = &src
 )
 
 func (s *kernelSource) Name() string { return Name }
@@ -114,52 +147,160 @@ func (s *kernelSource) GetLabels() (source.FeatureLabels, error) {
 		labels["selinux.enabled"] = "true"
 	}
 
+	if enabled, ok := features.Attributes[AppArmorFeature].Elements["enabled"]; ok && enabled == "true" {
+		labels["apparmor.enabled"] = "true"
+	}
+
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface
+func (s *kernelSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	// Use the first kernel config found. The /boot/config-* files are only
+	// readable by root on some distributions.
+	var kconfig source.InputStatus
+	s.kconfigPath = ""
+	for _, path := range kconfigSearchPaths() {
+		privileged := strings.HasPrefix(path, hostpath.BootDir.Path(""))
+		if kconfig = source.ProbeInput(ConfigFeature, path, privileged, minimalPrivileges); kconfig.Err == nil {
+			s.kconfigPath = path
+			break
+		}
+	}
+
+	s.inputs = source.InputStatuses{
+		source.ProbeInput(VersionFeature, hostpath.ProcfsDir.Path("sys/kernel/osrelease"), false, minimalPrivileges),
+		kconfig,
+		source.ProbeInput(LoadedModuleFeature, hostpath.ProcfsDir.Path("modules"), false, minimalPrivileges),
+		source.ProbeInput(EnabledModuleFeature, hostpath.LibDir.Path("modules"), false, minimalPrivileges),
+		source.ProbeInput(SelinuxFeature, hostpath.SysfsDir.Path("fs"), false, minimalPrivileges),
+		source.ProbeInput(IommuFeature, hostpath.SysfsDir.Path("kernel"), false, minimalPrivileges),
+		source.ProbeInput(LsmFeature, hostpath.SysfsDir.Path("kernel/security/lsm"), false, minimalPrivileges),
+		source.ProbeInput(AppArmorFeature, hostpath.SysfsDir.Path("module"), false, minimalPrivileges),
+		source.ProbeInput(SeccompFeature, hostpath.ProcfsDir.Path("sys/kernel"), false, minimalPrivileges),
+		source.ProbeInput(RealtimeFeature, hostpath.ProcfsDir.Path("sys/kernel/version"), false, minimalPrivileges),
+		source.ProbeInput(SysctlFeature, hostpath.ProcfsDir.Path("sys"), false, minimalPrivileges),
+	}
+	return s.inputs
+}
+
+// kconfigFile returns the kernel config file to read, the configured one or
+// the one found when probing.
+func (s *kernelSource) kconfigFile() string {
+	if s.config.KconfigFile != "" {
+		return s.config.KconfigFile
+	}
+	return s.kconfigPath
+}
+
 // Discover method of the FeatureSource interface
 func (s *kernelSource) Discover() error {
+	logger := source.Logger(Name)
 	s.features = nfdv1alpha1.NewFeatures()
 
 	// Read kernel version
-	if version, err := discoverVersion(); err != nil {
+	if !s.inputs.Available(VersionFeature) {
+		logger.V(2).Info("kernel version input not available, skipping")
+	} else if version, err := discoverVersion(); err != nil {
 		klog.ErrorS(err, "failed to get kernel version")
 	} else {
-		s.features.Attributes[VersionFeature] = nfdv1alpha1.NewAttributeFeatures(version)
+		s.features.Attributes[VersionFeature] = nfdv1alpha1.NewAttributeFeatures(version).WithTypes(versionTypes)
 	}
 
 	// Read kconfig
-	if realKconfig, legacyKconfig, err := parseKconfig(s.config.KconfigFile); err != nil {
+	var kconfig map[string]string
+	if !s.inputs.Available(ConfigFeature) && s.config.KconfigFile == "" {
+		s.legacyKconfig = nil
+		logger.V(2).Info("kernel config input not available, skipping")
+	} else if kc, err := parseKconfig(s.kconfigFile()); err != nil {
 		s.legacyKconfig = nil
 		klog.ErrorS(err, "failed to read kconfig")
 	} else {
-		s.features.Attributes[ConfigFeature] = nfdv1alpha1.NewAttributeFeatures(realKconfig)
-		s.legacyKconfig = legacyKconfig
+		s.features.Attributes[ConfigFeature] = nfdv1alpha1.NewAttributeFeatures(kc.real)
+		if s.config.TypedKconfig {
+			s.features.Attributes[ConfigTristateFeature] = nfdv1alpha1.NewAttributeFeatures(kc.tristate)
+			s.features.Attributes[ConfigNumberFeature] = nfdv1alpha1.NewAttributeFeatures(kc.number)
+		}
+		s.legacyKconfig = kc.legacy
+		kconfig = kc.real
 	}
 
 	var enabledModules []string
-	if kmods, err := getLoadedModules(); err != nil {
+	if !s.inputs.Available(LoadedModuleFeature) {
+		logger.V(2).Info("loaded kernel modules input not available, skipping")
+	} else if kmods, err := getLoadedModules(); err != nil {
 		klog.ErrorS(err, "failed to get loaded kernel modules")
 	} else {
 		enabledModules = append(enabledModules, kmods...)
 		s.features.Flags[LoadedModuleFeature] = nfdv1alpha1.NewFlagFeatures(kmods...)
 	}
 
-	if builtinMods, err := getBuiltinModules(); err != nil {
+	if !s.inputs.Available(EnabledModuleFeature) {
+		logger.V(2).Info("builtin kernel modules input not available, skipping")
+	} else if builtinMods, err := getBuiltinModules(); err != nil {
 		klog.ErrorS(err, "failed to get builtin kernel modules")
 	} else {
 		enabledModules = append(enabledModules, builtinMods...)
 		s.features.Flags[EnabledModuleFeature] = nfdv1alpha1.NewFlagFeatures(enabledModules...)
 	}
 
-	if selinux, err := SelinuxEnabled(); err != nil {
+	if !s.inputs.Available(SelinuxFeature) {
+		logger.V(2).Info("selinux input not available, skipping")
+	} else if selinux, err := SelinuxEnabled(); err != nil {
 		klog.ErrorS(err, "failed to detect selinux status")
 	} else {
-		s.features.Attributes[SelinuxFeature] = nfdv1alpha1.NewAttributeFeatures(nil)
-		s.features.Attributes[SelinuxFeature].Elements["enabled"] = strconv.FormatBool(selinux)
+		s.features.Attributes[SelinuxFeature] = nfdv1alpha1.NewTypedAttributeFeatures(map[string]interface{}{"enabled": selinux})
+	}
+
+	if !s.inputs.Available(IommuFeature) {
+		logger.V(2).Info("iommu input not available, skipping")
+	} else if iommu, err := discoverIommu(); err != nil {
+		klog.ErrorS(err, "failed to detect iommu status")
+	} else {
+		s.features.Attributes[IommuFeature] = nfdv1alpha1.NewAttributeFeatures(iommu).WithTypes(iommuTypes)
+	}
+
+	if !s.inputs.Available(LsmFeature) {
+		logger.V(2).Info("lsm input not available, skipping")
+	} else if lsms, err := discoverLsm(); err != nil {
+		klog.ErrorS(err, "failed to detect active LSMs")
+	} else {
+		s.features.Flags[LsmFeature] = nfdv1alpha1.NewFlagFeatures(lsms...)
+	}
+
+	if !s.inputs.Available(AppArmorFeature) {
+		logger.V(2).Info("apparmor input not available, skipping")
+	} else if apparmor, err := discoverAppArmor(); err != nil {
+		klog.ErrorS(err, "failed to detect apparmor status")
+	} else {
+		s.features.Attributes[AppArmorFeature] = nfdv1alpha1.NewAttributeFeatures(apparmor).WithTypes(map[string]nfdv1alpha1.AttributeType{"enabled": nfdv1alpha1.AttributeTypeBool})
+	}
+
+	if !s.inputs.Available(SeccompFeature) {
+		logger.V(2).Info("seccomp input not available, skipping")
+	} else if seccomp, err := discoverSeccomp(); err != nil {
+		klog.ErrorS(err, "failed to detect seccomp status")
+	} else {
+		s.features.Attributes[SeccompFeature] = nfdv1alpha1.NewAttributeFeatures(seccomp).WithTypes(seccompTypes)
+	}
+
+	if !s.inputs.Available(RealtimeFeature) {
+		logger.V(2).Info("realtime input not available, skipping")
+	} else if realtime, err := discoverRealtime(kconfig); err != nil {
+		klog.ErrorS(err, "failed to detect realtime kernel status")
+	} else {
+		s.features.Attributes[RealtimeFeature] = nfdv1alpha1.NewAttributeFeatures(realtime).WithTypes(realtimeTypes)
+	}
+
+	if len(s.config.Sysctls) > 0 {
+		if !s.inputs.Available(SysctlFeature) {
+			logger.V(2).Info("sysctl input not available, skipping")
+		} else {
+			s.features.Attributes[SysctlFeature] = nfdv1alpha1.NewAttributeFeatures(discoverSysctls(s.config.Sysctls))
+		}
 	}
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	logger.V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
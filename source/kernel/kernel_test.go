@@ -17,9 +17,13 @@ limitations under the License.
 package kernel
 
 import (
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/source/sourcetest"
 )
 
 func TestKernelSource(t *testing.T) {
@@ -33,3 +37,8 @@ func TestKernelSource(t *testing.T) {
 	assert.Empty(t, l)
 
 }
+
+func TestKernelSourceGolden(t *testing.T) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	sourcetest.RunGoldenTests(t, &kernelSource{config: newDefaultConfig()}, filepath.Join(filepath.Dir(thisFile), "testdata"))
+}
@@ -25,9 +25,8 @@ import (
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 )
 
-const kmodProcfsPath = "/proc/modules"
-
 func getLoadedModules() ([]string, error) {
+	kmodProcfsPath := hostpath.ProcfsDir.Path("modules")
 	out, err := os.ReadFile(kmodProcfsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %s", kmodProcfsPath, err.Error())
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// realtimeTypes are the types of the attributes of the realtime feature.
+var realtimeTypes = map[string]nfdv1alpha1.AttributeType{
+	"enabled":  nfdv1alpha1.AttributeTypeBool,
+	"dynamic":  nfdv1alpha1.AttributeTypeBool,
+	"timer_hz": nfdv1alpha1.AttributeTypeInt,
+}
+
+// preemptModels are the preemption models selectable with the preempt=
+// kernel command line parameter of PREEMPT_DYNAMIC kernels.
+var preemptModels = []string{"none", "voluntary", "full"}
+
+// discoverRealtime detects if the kernel is a realtime (PREEMPT_RT) kernel,
+// its preemption model and timer frequency, and the tuned profile in effect.
+// The kconfig argument holds the kernel config options, if available.
+func discoverRealtime(kconfig map[string]string) (map[string]string, error) {
+	version, err := os.ReadFile(hostpath.ProcfsDir.Path("sys/kernel/version"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel version string: %w", err)
+	}
+	// The build flags of the kernel, e.g. "#1 SMP PREEMPT_DYNAMIC Thu ..."
+	flags := map[string]bool{}
+	for _, f := range strings.Fields(string(version)) {
+		flags[f] = true
+	}
+
+	realtime := flags["PREEMPT_RT"] || kconfig["PREEMPT_RT"] == "y"
+	if data, err := os.ReadFile(hostpath.SysfsDir.Path("kernel/realtime")); err == nil {
+		realtime = realtime || strings.TrimSpace(string(data)) == "1"
+	}
+	dynamic := flags["PREEMPT_DYNAMIC"] || kconfig["PREEMPT_DYNAMIC"] == "y"
+
+	attrs := map[string]string{
+		"enabled": strconv.FormatBool(realtime),
+		"dynamic": strconv.FormatBool(dynamic),
+	}
+
+	switch {
+	case realtime:
+		attrs["preempt"] = "rt"
+	case dynamic:
+		if model := preemptModelDynamic(); model != "" {
+			attrs["preempt"] = model
+			break
+		}
+		fallthrough
+	default:
+		if model := preemptModelStatic(flags, kconfig); model != "" {
+			attrs["preempt"] = model
+		}
+	}
+
+	if hz, ok := kconfig["HZ"]; ok {
+		attrs["timer_hz"] = hz
+	}
+
+	if profile, err := os.ReadFile(hostpath.EtcDir.Path("tuned/active_profile")); err != nil {
+		if !os.IsNotExist(err) {
+			source.Logger(Name).V(2).Info("failed to read active tuned profile", "err", err)
+		}
+	} else if p := strings.TrimSpace(string(profile)); p != "" {
+		attrs["tuned_profile"] = p
+	}
+
+	return attrs, nil
+}
+
+// preemptModelDynamic returns the preemption model of a PREEMPT_DYNAMIC
+// kernel. The model in effect is available in debugfs, which is usually only
+// readable by root, and falls back to the preempt= kernel command line
+// parameter.
+func preemptModelDynamic() string {
+	// The file lists all models, the one in effect in parentheses, e.g.
+	// "none voluntary (full)"
+	if data, err := os.ReadFile(hostpath.SysfsDir.Path("kernel/debug/sched/preempt")); err == nil {
+		for _, m := range strings.Fields(string(data)) {
+			if strings.HasPrefix(m, "(") && strings.HasSuffix(m, ")") {
+				return strings.Trim(m, "()")
+			}
+		}
+	}
+
+	if cmdline, err := os.ReadFile(hostpath.ProcfsDir.Path("cmdline")); err == nil {
+		for _, arg := range strings.Fields(string(cmdline)) {
+			if v, ok := strings.CutPrefix(arg, "preempt="); ok {
+				for _, m := range preemptModels {
+					if v == m {
+						return m
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// preemptModelStatic returns the preemption model the kernel was built with.
+func preemptModelStatic(versionFlags map[string]bool, kconfig map[string]string) string {
+	switch {
+	case kconfig["PREEMPT"] == "y":
+		return "full"
+	case kconfig["PREEMPT_VOLUNTARY"] == "y":
+		return "voluntary"
+	case kconfig["PREEMPT_NONE"] == "y":
+		return "none"
+	case versionFlags["PREEMPT"]:
+		return "full"
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestDiscoverRealtime(t *testing.T) {
+	hostpath.SetRoot("testdata/realtime")
+	defer hostpath.SetRoot("")
+
+	attrs, err := discoverRealtime(map[string]string{"PREEMPT_RT": "y", "HZ": "1000"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"enabled":  "true",
+		"dynamic":  "false",
+		"preempt":  "rt",
+		"timer_hz": "1000",
+	}, attrs)
+
+	// The preemption model of PREEMPT_DYNAMIC kernels falls back to the
+	// kernel config if it is not set on the kernel command line
+	hostpath.SetRoot("testdata/basic")
+	attrs, err = discoverRealtime(map[string]string{"PREEMPT_DYNAMIC": "y", "PREEMPT_VOLUNTARY": "y"})
+	assert.NoError(t, err)
+	assert.Equal(t, "voluntary", attrs["preempt"])
+	assert.Equal(t, "cpu-partitioning", attrs["tuned_profile"])
+
+	hostpath.SetRoot("testdata/nonexistent")
+	_, err = discoverRealtime(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+// seccompActions are the seccomp filter return actions published as
+// attributes of the seccomp feature.
+var seccompActions = []string{"allow", "errno", "kill_process", "kill_thread", "log", "trace", "trap", "user_notif"}
+
+// seccompTypes are the types of the attributes of the seccomp feature.
+var seccompTypes = func() map[string]nfdv1alpha1.AttributeType {
+	types := map[string]nfdv1alpha1.AttributeType{"enabled": nfdv1alpha1.AttributeTypeBool}
+	for _, a := range seccompActions {
+		types[a] = nfdv1alpha1.AttributeTypeBool
+	}
+	return types
+}()
+
+// discoverLsm returns the active Linux security modules, in the order the
+// kernel invokes them.
+func discoverLsm() ([]string, error) {
+	data, err := os.ReadFile(hostpath.SysfsDir.Path("kernel/security/lsm"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read active LSMs: %w", err)
+	}
+	lsms := []string{}
+	for _, lsm := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		if lsm != "" {
+			lsms = append(lsms, lsm)
+		}
+	}
+	return lsms, nil
+}
+
+// discoverAppArmor detects if AppArmor has been enabled in the kernel.
+func discoverAppArmor() (map[string]string, error) {
+	enabled, err := os.ReadFile(hostpath.SysfsDir.Path("module/apparmor/parameters/enabled"))
+	if os.IsNotExist(err) {
+		return map[string]string{"enabled": "false"}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read apparmor status: %w", err)
+	}
+	return map[string]string{"enabled": strconv.FormatBool(strings.TrimSpace(string(enabled)) == "Y")}, nil
+}
+
+// discoverSeccomp detects seccomp filter support and the filter return
+// actions available.
+func discoverSeccomp() (map[string]string, error) {
+	actions, err := os.ReadFile(hostpath.ProcfsDir.Path("sys/kernel/seccomp/actions_avail"))
+	if os.IsNotExist(err) {
+		return map[string]string{"enabled": "false"}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read available seccomp actions: %w", err)
+	}
+
+	avail := strings.Fields(string(actions))
+	attrs := map[string]string{"enabled": "true"}
+	for _, a := range seccompActions {
+		attrs[a] = "false"
+		for _, b := range avail {
+			if a == b {
+				attrs[a] = "true"
+			}
+		}
+	}
+	return attrs, nil
+}
@@ -20,9 +20,8 @@ import (
 	"os"
 	"path/filepath"
 
-	"k8s.io/klog/v2"
-
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
 )
 
 // SelinuxEnabled detects if selinux has been enabled in the kernel
@@ -34,7 +33,7 @@ func SelinuxEnabled() (bool, error) {
 
 	selinuxBase := filepath.Join(sysfsBase, "selinux")
 	if _, err := os.Stat(selinuxBase); os.IsNotExist(err) {
-		klog.V(1).InfoS("selinux not available on the system")
+		source.Logger(Name).V(1).Info("selinux not available on the system")
 		return false, nil
 	}
 
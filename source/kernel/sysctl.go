@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// sysctlPath returns the path of a sysctl under /proc/sys. Like in sysctl(8)
+// the components of the name are separated by dots, or by slashes in which
+// case dots are part of the component (e.g. net/ipv4/conf/eth0.100/forwarding).
+func sysctlPath(name string) (string, error) {
+	p := name
+	if !strings.Contains(name, "/") {
+		p = strings.ReplaceAll(name, ".", "/")
+	}
+	p = strings.Trim(p, "/")
+	if p == "" || p != filepath.Clean(p) || strings.HasPrefix(p, "../") || p == ".." {
+		return "", fmt.Errorf("invalid sysctl name %q", name)
+	}
+	return hostpath.ProcfsDir.Path(filepath.Join("sys", p)), nil
+}
+
+// discoverSysctls reads the given sysctls. Values consisting of multiple
+// fields (e.g. net.ipv4.tcp_rmem) are normalized to be separated by single
+// spaces. Sysctls that do not exist on the system are skipped.
+func discoverSysctls(names []string) map[string]string {
+	attrs := make(map[string]string, len(names))
+	for _, name := range names {
+		path, err := sysctlPath(name)
+		if err != nil {
+			klog.ErrorS(err, "skipping sysctl")
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			source.Logger(Name).V(2).Info("sysctl not found", "sysctl", name)
+			continue
+		} else if err != nil {
+			klog.ErrorS(err, "failed to read sysctl", "sysctl", name)
+			continue
+		}
+		attrs[name] = strings.Join(strings.Fields(string(data)), " ")
+	}
+	return attrs
+}
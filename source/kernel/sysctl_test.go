@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+func TestDiscoverSysctls(t *testing.T) {
+	hostpath.SetRoot("testdata/basic")
+	defer hostpath.SetRoot("")
+
+	assert.Equal(t, map[string]string{
+		"net.core.busy_poll":                "50",
+		"vm.nr_hugepages":                   "1024",
+		"net.ipv4.tcp_rmem":                 "4096 131072 6291456",
+		"net/ipv4/conf/eth0.100/forwarding": "1",
+	}, discoverSysctls([]string{
+		"net.core.busy_poll",
+		"vm.nr_hugepages",
+		"net.ipv4.tcp_rmem",
+		"net/ipv4/conf/eth0.100/forwarding",
+		"vm.nonexistent",
+		"../../etc/passwd",
+	}))
+}
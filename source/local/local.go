@@ -71,6 +71,7 @@ var (
 type localSource struct {
 	features *nfdv1alpha1.Features
 	config   *Config
+	inputs   source.InputStatuses
 }
 
 type Config struct {
@@ -90,6 +91,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.ProbingSource      = &src
 )
 
 // Name method of the LabelSource interface
@@ -132,16 +134,35 @@ func newDefaultConfig() *Config {
 	}
 }
 
+// Probe method of the ProbingSource interface
+func (s *localSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		source.ProbeInput("featurefiles", featureFilesDir, false, minimalPrivileges),
+	}
+	// Hooks are host binaries executed inside the worker
+	if s.config.HooksEnabled {
+		s.inputs = append(s.inputs, source.ProbeInput("hooks", hookDir, true, minimalPrivileges))
+	}
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface
 func (s *localSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
-	featuresFromFiles, labelsFromFiles, err := getFeaturesFromFiles()
-	if err != nil {
-		klog.ErrorS(err, "failed to read feature files")
+	featuresFromFiles := make(map[string]string)
+	labelsFromFiles := make(map[string]string)
+	if s.inputs.Available("featurefiles") {
+		var err error
+		featuresFromFiles, labelsFromFiles, err = getFeaturesFromFiles()
+		if err != nil {
+			klog.ErrorS(err, "failed to read feature files")
+		}
+	} else {
+		source.Logger(Name).V(2).Info("feature files input not available, skipping")
 	}
 
-	if s.config.HooksEnabled {
+	if s.config.HooksEnabled && s.inputs.Available("hooks") {
 
 		klog.InfoS("starting hooks...")
 		klog.InfoS("NOTE: hooks are deprecated and will be completely removed in a future release.")
@@ -171,7 +192,7 @@ func (s *localSource) Discover() error {
 	s.features.Attributes[LabelFeature] = nfdv1alpha1.NewAttributeFeatures(labelsFromFiles)
 	s.features.Attributes[RawFeature] = nfdv1alpha1.NewAttributeFeatures(featuresFromFiles)
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -323,7 +344,7 @@ func getFeaturesFromHooks() (map[string]string, map[string]string, error) {
 
 		// Append features
 		fileFeatures, fileLabels := parseFeatureFile(lines, fileName)
-		klog.V(4).InfoS("hook executed", "fileName", fileName, "features", utils.DelayedDumper(fileFeatures), "labels", utils.DelayedDumper(fileLabels))
+		source.Logger(Name).V(4).Info("hook executed", "fileName", fileName, "features", utils.DelayedDumper(fileFeatures), "labels", utils.DelayedDumper(fileLabels))
 		for k, v := range fileFeatures {
 			if old, ok := features[k]; ok {
 				klog.InfoS("overriding feature value from another hook", "featureKey", k, "oldValue", old, "newValue", v, "fileName", fileName)
@@ -411,7 +432,7 @@ func getFeaturesFromFiles() (map[string]string, map[string]string, error) {
 
 		// Append features
 		fileFeatures, fileLabels := parseFeatureFile(lines, fileName)
-		klog.V(4).InfoS("feature file read", "fileName", fileName, "features", utils.DelayedDumper(fileFeatures))
+		source.Logger(Name).V(4).Info("feature file read", "fileName", fileName, "features", utils.DelayedDumper(fileFeatures))
 		for k, v := range fileFeatures {
 			if old, ok := features[k]; ok {
 				klog.InfoS("overriding label value from another feature file", "featureKey", k, "oldValue", old, "newValue", v, "fileName", fileName)
@@ -37,6 +37,18 @@ func TestLocalSource(t *testing.T) {
 
 }
 
+func TestProbe(t *testing.T) {
+	pwd, _ := os.Getwd()
+	featureFilesDir = filepath.Join(pwd, "testdata/features.d")
+	src.config.HooksEnabled = true
+	defer func() { src.config.HooksEnabled = false }()
+
+	inputs := src.Probe(true)
+	assert.True(t, inputs.Available("featurefiles"))
+	assert.False(t, inputs.Available("hooks"))
+	assert.False(t, inputs.AllPrivilegedUnavailable())
+}
+
 func TestGetExpirationDate(t *testing.T) {
 	expectedFeaturesLen := 7
 	expectedLabelsLen := 9
@@ -17,17 +17,10 @@ limitations under the License.
 package memory
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
-
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
-	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
@@ -40,9 +33,16 @@ const NvFeature = "nv"
 // NumaFeature is the name of the feature set that holds all NUMA related features.
 const NumaFeature = "numa"
 
+// numaTypes are the types of the attributes of the numa feature.
+var numaTypes = map[string]nfdv1alpha1.AttributeType{
+	"is_numa":    nfdv1alpha1.AttributeTypeBool,
+	"node_count": nfdv1alpha1.AttributeTypeInt,
+}
+
 // memorySource implements the FeatureSource and LabelSource interfaces.
 type memorySource struct {
 	features *nfdv1alpha1.Features
+	inputs   source.InputStatuses
 }
 
 // Singleton source instance
@@ -50,6 +50,7 @@ var (
 	src memorySource
 	_   source.FeatureSource = &src
 	_   source.LabelSource   = &src
+	_   source.ProbingSource = &src
 )
 
 // Name returns an identifier string for this feature source.
@@ -82,15 +83,23 @@ func (s *memorySource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface
+func (s *memorySource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = probeInputs(minimalPrivileges)
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface
 func (s *memorySource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
 	// Detect NUMA
-	if numa, err := detectNuma(); err != nil {
+	if !s.inputs.Available(NumaFeature) {
+		source.Logger(Name).V(2).Info("numa input not available, skipping")
+	} else if numa, err := detectNuma(); err != nil {
 		klog.ErrorS(err, "failed to detect NUMA nodes")
 	} else {
-		s.features.Attributes[NumaFeature] = nfdv1alpha1.AttributeFeatureSet{Elements: numa}
+		s.features.Attributes[NumaFeature] = nfdv1alpha1.NewAttributeFeatures(numa).WithTypes(numaTypes)
 	}
 
 	// Detect NVDIMM
@@ -100,7 +109,7 @@ func (s *memorySource) Discover() error {
 		s.features.Instances[NvFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: nv}
 	}
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -113,59 +122,6 @@ func (s *memorySource) GetFeatures() *nfdv1alpha1.Features {
 	return s.features
 }
 
-// detectNuma detects NUMA node information
-func detectNuma() (map[string]string, error) {
-	sysfsBasePath := hostpath.SysfsDir.Path("bus/node/devices")
-
-	nodes, err := os.ReadDir(sysfsBasePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list numa nodes: %w", err)
-	}
-
-	return map[string]string{
-		"is_numa":    strconv.FormatBool(len(nodes) > 1),
-		"node_count": strconv.Itoa(len(nodes)),
-	}, nil
-}
-
-// detectNv detects NVDIMM devices
-func detectNv() ([]nfdv1alpha1.InstanceFeature, error) {
-	sysfsBasePath := hostpath.SysfsDir.Path("bus/nd/devices")
-	info := make([]nfdv1alpha1.InstanceFeature, 0)
-
-	devices, err := os.ReadDir(sysfsBasePath)
-	if os.IsNotExist(err) {
-		klog.V(1).InfoS("No NVDIMM devices present")
-		return info, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("failed to list nvdimm devices: %w", err)
-	}
-
-	// Iterate over devices
-	for _, device := range devices {
-		i := readNdDeviceInfo(filepath.Join(sysfsBasePath, device.Name()))
-		info = append(info, i)
-	}
-
-	return info, nil
-}
-
-// ndDevAttrs is the list of sysfs files (under each nd device) that we're trying to read
-var ndDevAttrs = []string{"devtype", "mode"}
-
-func readNdDeviceInfo(path string) nfdv1alpha1.InstanceFeature {
-	attrs := map[string]string{"name": filepath.Base(path)}
-	for _, attrName := range ndDevAttrs {
-		data, err := os.ReadFile(filepath.Join(path, attrName))
-		if err != nil {
-			klog.V(3).ErrorS(err, "failed to read nd device attribute", "attributeName", attrName)
-			continue
-		}
-		attrs[attrName] = strings.TrimSpace(string(data))
-	}
-	return *nfdv1alpha1.NewInstanceFeature(attrs)
-}
-
 func init() {
 	source.Register(&src)
 }
@@ -0,0 +1,93 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// probeInputs checks the sysfs inputs of the memory source. NVDIMM devices
+// are not probed as a missing bus/nd simply means that there are none.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	return source.InputStatuses{
+		source.ProbeInput(NumaFeature, hostpath.SysfsDir.Path("bus/node/devices"), false, minimalPrivileges),
+	}
+}
+
+// detectNuma detects NUMA node information
+func detectNuma() (map[string]string, error) {
+	sysfsBasePath := hostpath.SysfsDir.Path("bus/node/devices")
+
+	nodes, err := os.ReadDir(sysfsBasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list numa nodes: %w", err)
+	}
+
+	return map[string]string{
+		"is_numa":    strconv.FormatBool(len(nodes) > 1),
+		"node_count": strconv.Itoa(len(nodes)),
+	}, nil
+}
+
+// detectNv detects NVDIMM devices
+func detectNv() ([]nfdv1alpha1.InstanceFeature, error) {
+	sysfsBasePath := hostpath.SysfsDir.Path("bus/nd/devices")
+	info := make([]nfdv1alpha1.InstanceFeature, 0)
+
+	devices, err := os.ReadDir(sysfsBasePath)
+	if os.IsNotExist(err) {
+		source.Logger(Name).V(1).Info("No NVDIMM devices present")
+		return info, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to list nvdimm devices: %w", err)
+	}
+
+	// Iterate over devices
+	for _, device := range devices {
+		i := readNdDeviceInfo(filepath.Join(sysfsBasePath, device.Name()))
+		info = append(info, i)
+	}
+
+	return info, nil
+}
+
+// ndDevAttrs is the list of sysfs files (under each nd device) that we're trying to read
+var ndDevAttrs = []string{"devtype", "mode"}
+
+func readNdDeviceInfo(path string) nfdv1alpha1.InstanceFeature {
+	attrs := map[string]string{"name": filepath.Base(path)}
+	for _, attrName := range ndDevAttrs {
+		data, err := os.ReadFile(filepath.Join(path, attrName))
+		if err != nil {
+			source.Logger(Name).V(3).Info("failed to read nd device attribute", "attributeName", attrName, "err", err)
+			continue
+		}
+		attrs[attrName] = strings.TrimSpace(string(data))
+	}
+	return *nfdv1alpha1.NewInstanceFeature(attrs)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// probeInputs returns no inputs on Windows, the NUMA topology is read with
+// the Win32 API.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	return nil
+}
+
+var procGetNumaHighestNodeNumber = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetNumaHighestNodeNumber")
+
+// detectNuma detects NUMA node information
+func detectNuma() (map[string]string, error) {
+	var highest uint32
+	if r, _, err := procGetNumaHighestNodeNumber.Call(uintptr(unsafe.Pointer(&highest))); r == 0 {
+		return nil, fmt.Errorf("failed to get numa nodes: %w", err)
+	}
+	nodes := int(highest) + 1
+
+	return map[string]string{
+		"is_numa":    strconv.FormatBool(nodes > 1),
+		"node_count": strconv.Itoa(nodes),
+	}, nil
+}
+
+// detectNv detects NVDIMM devices. Not supported on Windows.
+func detectNv() ([]nfdv1alpha1.InstanceFeature, error) {
+	return []nfdv1alpha1.InstanceFeature{}, nil
+}
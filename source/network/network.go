@@ -17,19 +17,13 @@ limitations under the License.
 package network
 
 import (
-	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"syscall"
 
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
-	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
@@ -43,11 +37,10 @@ const (
 	VirtualFeature = "virtual"
 )
 
-const sysfsBaseDir = "class/net"
-
 // networkSource implements the FeatureSource and LabelSource interfaces.
 type networkSource struct {
 	features *nfdv1alpha1.Features
+	inputs   source.InputStatuses
 }
 
 // Singleton source instance
@@ -55,14 +48,7 @@ var (
 	src networkSource
 	_   source.FeatureSource = &src
 	_   source.LabelSource   = &src
-)
-
-var (
-	// devIfaceAttrs is the list of files under /sys/class/net/<iface> that we're reading
-	devIfaceAttrs = []string{"operstate", "speed", "device/sriov_numvfs", "device/sriov_totalvfs"}
-
-	// virtualIfaceAttrs is the list of files under /sys/class/net/<iface> that we're reading
-	virtualIfaceAttrs = []string{"operstate", "speed"}
+	_   source.ProbingSource = &src
 )
 
 // Name returns an identifier string for this feature source.
@@ -97,10 +83,21 @@ func (s *networkSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface.
+func (s *networkSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = probeInputs(minimalPrivileges)
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface.
 func (s *networkSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
+	if !s.inputs.Available(DeviceFeature) {
+		source.Logger(Name).V(2).Info("network devices input not available, skipping")
+		return nil
+	}
+
 	devs, virts, err := detectNetDevices()
 	if err != nil {
 		return fmt.Errorf("failed to detect network devices: %w", err)
@@ -108,7 +105,7 @@ func (s *networkSource) Discover() error {
 	s.features.Instances[DeviceFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: devs}
 	s.features.Instances[VirtualFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: virts}
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -121,48 +118,6 @@ func (s *networkSource) GetFeatures() *nfdv1alpha1.Features {
 	return s.features
 }
 
-func detectNetDevices() ([]nfdv1alpha1.InstanceFeature, []nfdv1alpha1.InstanceFeature, error) {
-	sysfsBasePath := hostpath.SysfsDir.Path(sysfsBaseDir)
-
-	ifaces, err := os.ReadDir(sysfsBasePath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to list network interfaces: %w", err)
-	}
-
-	// Iterate over devices
-	devIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0, len(ifaces))
-	virtualIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0, len(ifaces))
-
-	for _, iface := range ifaces {
-		name := iface.Name()
-		if _, err := os.Stat(filepath.Join(sysfsBasePath, name, "device")); err == nil {
-			devIfacesinfo = append(devIfacesinfo, readIfaceInfo(filepath.Join(sysfsBasePath, name), devIfaceAttrs))
-		} else {
-			virtualIfacesinfo = append(virtualIfacesinfo, readIfaceInfo(filepath.Join(sysfsBasePath, name), virtualIfaceAttrs))
-		}
-	}
-
-	return devIfacesinfo, virtualIfacesinfo, nil
-}
-
-func readIfaceInfo(path string, attrFiles []string) nfdv1alpha1.InstanceFeature {
-	attrs := map[string]string{"name": filepath.Base(path)}
-	for _, attrFile := range attrFiles {
-		data, err := os.ReadFile(filepath.Join(path, attrFile))
-		if err != nil {
-			if !os.IsNotExist(err) && !errors.Is(err, syscall.EINVAL) {
-				klog.ErrorS(err, "failed to read net iface attribute", "attributeName", attrFile)
-			}
-			continue
-		}
-		attrName := filepath.Base(attrFile)
-		attrs[attrName] = strings.TrimSpace(string(data))
-	}
-
-	return *nfdv1alpha1.NewInstanceFeature(attrs)
-
-}
-
 func init() {
 	source.Register(&src)
 }
@@ -0,0 +1,93 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+const sysfsBaseDir = "class/net"
+
+var (
+	// devIfaceAttrs is the list of files under /sys/class/net/<iface> that we're reading
+	devIfaceAttrs = []string{"operstate", "speed", "device/sriov_numvfs", "device/sriov_totalvfs"}
+
+	// virtualIfaceAttrs is the list of files under /sys/class/net/<iface> that we're reading
+	virtualIfaceAttrs = []string{"operstate", "speed"}
+)
+
+// probeInputs checks the sysfs input of the network source.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	return source.InputStatuses{
+		source.ProbeInput(DeviceFeature, hostpath.SysfsDir.Path(sysfsBaseDir), false, minimalPrivileges),
+	}
+}
+
+func detectNetDevices() ([]nfdv1alpha1.InstanceFeature, []nfdv1alpha1.InstanceFeature, error) {
+	sysfsBasePath := hostpath.SysfsDir.Path(sysfsBaseDir)
+
+	ifaces, err := os.ReadDir(sysfsBasePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	// Iterate over devices
+	devIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0, len(ifaces))
+	virtualIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0, len(ifaces))
+
+	for _, iface := range ifaces {
+		name := iface.Name()
+		if _, err := os.Stat(filepath.Join(sysfsBasePath, name, "device")); err == nil {
+			devIfacesinfo = append(devIfacesinfo, readIfaceInfo(filepath.Join(sysfsBasePath, name), devIfaceAttrs))
+		} else {
+			virtualIfacesinfo = append(virtualIfacesinfo, readIfaceInfo(filepath.Join(sysfsBasePath, name), virtualIfaceAttrs))
+		}
+	}
+
+	return devIfacesinfo, virtualIfacesinfo, nil
+}
+
+func readIfaceInfo(path string, attrFiles []string) nfdv1alpha1.InstanceFeature {
+	attrs := map[string]string{"name": filepath.Base(path)}
+	for _, attrFile := range attrFiles {
+		data, err := os.ReadFile(filepath.Join(path, attrFile))
+		if err != nil {
+			if !os.IsNotExist(err) && !errors.Is(err, syscall.EINVAL) {
+				klog.ErrorS(err, "failed to read net iface attribute", "attributeName", attrFile)
+			}
+			continue
+		}
+		attrName := filepath.Base(attrFile)
+		attrs[attrName] = strings.TrimSpace(string(data))
+	}
+
+	return *nfdv1alpha1.NewInstanceFeature(attrs)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// probeInputs returns no inputs on Windows, the network adapters are read
+// with the Win32 API.
+func probeInputs(minimalPrivileges bool) source.InputStatuses {
+	return nil
+}
+
+// operStates maps IF_OPER_STATUS values to the operstate names used in sysfs
+// on Linux
+var operStates = map[uint32]string{
+	1: "up",
+	2: "down",
+	3: "testing",
+	4: "unknown",
+	5: "dormant",
+	6: "notpresent",
+	7: "lowerlayerdown",
+}
+
+func detectNetDevices() ([]nfdv1alpha1.InstanceFeature, []nfdv1alpha1.InstanceFeature, error) {
+	adapters, err := getAdapterAddresses()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	devIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0)
+	virtualIfacesinfo := make([]nfdv1alpha1.InstanceFeature, 0)
+
+	for aa := adapters; aa != nil; aa = aa.Next {
+		attrs := map[string]string{"name": windows.UTF16PtrToString(aa.FriendlyName)}
+		if s, ok := operStates[aa.OperStatus]; ok {
+			attrs["operstate"] = s
+		}
+		// Speed is in Mbit/s, like in sysfs on Linux
+		if aa.TransmitLinkSpeed > 0 && aa.TransmitLinkSpeed != ^uint64(0) {
+			attrs["speed"] = strconv.FormatUint(aa.TransmitLinkSpeed/1000000, 10)
+		}
+
+		// Consider ethernet and wireless adapters with a hardware address
+		// physical devices
+		physical := aa.PhysicalAddressLength > 0 &&
+			(aa.IfType == windows.IF_TYPE_ETHERNET_CSMACD || aa.IfType == windows.IF_TYPE_IEEE80211)
+		if physical {
+			devIfacesinfo = append(devIfacesinfo, *nfdv1alpha1.NewInstanceFeature(attrs))
+		} else {
+			virtualIfacesinfo = append(virtualIfacesinfo, *nfdv1alpha1.NewInstanceFeature(attrs))
+		}
+	}
+
+	return devIfacesinfo, virtualIfacesinfo, nil
+}
+
+// getAdapterAddresses returns the linked list of all network adapters.
+func getAdapterAddresses() (*windows.IpAdapterAddresses, error) {
+	size := uint32(15000)
+	for {
+		buf := make([]byte, size)
+		aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_PREFIX, 0, aa, &size)
+		switch err {
+		case nil:
+			return aa, nil
+		case windows.ERROR_NO_DATA:
+			return nil, nil
+		case windows.ERROR_BUFFER_OVERFLOW:
+			// Retry with the required buffer size
+		default:
+			return nil, err
+		}
+	}
+}
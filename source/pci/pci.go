@@ -25,6 +25,7 @@ import (
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
@@ -38,6 +39,13 @@ const DeviceFeature = "device"
 type Config struct {
 	DeviceClassWhitelist []string `json:"deviceClassWhitelist,omitempty"`
 	DeviceLabelFields    []string `json:"deviceLabelFields,omitempty"`
+	// DecodeNames enables publishing the vendor and device names, looked up
+	// from the embedded PCI ID database, as device attributes.
+	DecodeNames bool `json:"decodeNames,omitempty"`
+	// LinkStatus enables publishing the current PCIe link speed and width
+	// and whether the link is degraded. These may change at runtime, e.g.
+	// with link power management, causing frequent feature updates.
+	LinkStatus bool `json:"linkStatus,omitempty"`
 }
 
 // newDefaultConfig returns a new config with pre-populated defaults
@@ -52,6 +60,7 @@ func newDefaultConfig() *Config {
 type pciSource struct {
 	config   *Config
 	features *nfdv1alpha1.Features
+	inputs   source.InputStatuses
 }
 
 // Singleton source instance
@@ -60,6 +69,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.ProbingSource      = &src
 )
 
 // Name returns the name of the feature source
@@ -135,17 +145,38 @@ func (s *pciSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface
+func (s *pciSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		source.ProbeInput(DeviceFeature, hostpath.SysfsDir.Path("bus/pci/devices"), false, minimalPrivileges),
+	}
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface
 func (s *pciSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
-	devs, err := detectPci()
+	if !s.inputs.Available(DeviceFeature) {
+		source.Logger(Name).V(2).Info("pci devices input not available, skipping")
+		return nil
+	}
+
+	devs, err := detectPci(s.config.LinkStatus)
 	if err != nil {
 		return fmt.Errorf("failed to detect PCI devices: %s", err.Error())
 	}
+	if s.config.DecodeNames {
+		for _, dev := range devs {
+			if err := addPciNames(dev.Attributes); err != nil {
+				klog.ErrorS(err, "failed to decode PCI device names")
+				break
+			}
+		}
+	}
 	s.features.Instances[DeviceFeature] = nfdv1alpha1.NewInstanceFeatures(devs)
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -21,10 +21,11 @@ import (
 	"runtime"
 	"testing"
 
-	"github.com/stretchr/testify/assert"
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
+	"github.com/openshift/node-feature-discovery/source/sourcetest"
+	"github.com/stretchr/testify/assert"
 )
 
 var packagePath string
@@ -64,6 +65,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0880",
 								"device":           "2021",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -73,6 +75,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "ff00",
 								"device":           "a1ed",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -82,6 +85,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0106",
 								"device":           "a1d2",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -91,6 +95,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "1180",
 								"device":           "a1b1",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -100,6 +105,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0780",
 								"device":           "a1ba",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -109,6 +115,10 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0604",
 								"device":           "a193",
+								"max_link_gen":     "3",
+								"max_link_speed":   "8.0",
+								"max_link_width":   "1",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -118,6 +128,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0c80",
 								"device":           "a1a4",
+								"numa_node":        "0",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
 								"vendor":           "8086",
@@ -127,6 +138,7 @@ func TestPciSource(t *testing.T) {
 							Attributes: map[string]string{
 								"class":            "0300",
 								"device":           "2000",
+								"numa_node":        "0",
 								"subsystem_device": "2000",
 								"subsystem_vendor": "1a03",
 								"vendor":           "1a03",
@@ -134,19 +146,30 @@ func TestPciSource(t *testing.T) {
 						},
 						{
 							Attributes: map[string]string{
-								"class":                     "0b40",
-								"device":                    "37c8",
-								"iommu_group/type":          "identity",
-								"sriov_totalvfs":            "16",
-								"subsystem_device":          "35cf",
-								"subsystem_vendor":          "8086",
-								"vendor":                    "8086",
+								"aer_correctable_errors": "true",
+								"aer_fatal_errors":       "false",
+								"aer_nonfatal_errors":    "false",
+								"class":                  "0b40",
+								"device":                 "37c8",
+								"iommu_group/type":       "identity",
+								"max_link_gen":           "2",
+								"max_link_speed":         "5.0",
+								"max_link_width":         "16",
+								"numa_node":              "0",
+								"sriov_totalvfs":         "16",
+								"subsystem_device":       "35cf",
+								"subsystem_vendor":       "8086",
+								"vendor":                 "8086",
 							},
 						},
 						{
 							Attributes: map[string]string{
 								"class":            "0200",
 								"device":           "37d2",
+								"max_link_gen":     "1",
+								"max_link_speed":   "2.5",
+								"max_link_width":   "1",
+								"numa_node":        "0",
 								"sriov_totalvfs":   "32",
 								"subsystem_device": "35cf",
 								"subsystem_vendor": "8086",
@@ -258,3 +281,26 @@ func TestPciSource(t *testing.T) {
 		})
 	}
 }
+
+func TestPciSourceGolden(t *testing.T) {
+	sourcetest.RunGoldenTests(t, &pciSource{config: newDefaultConfig()}, filepath.Join(packagePath, "testdata"))
+}
+
+func TestPciLinkStatus(t *testing.T) {
+	hostpath.SysfsDir = hostpath.HostDir(filepath.Join(packagePath, "testdata", "rootfs-1", "sys"))
+
+	testSrc := pciSource{config: &Config{LinkStatus: true}}
+	assert.Nil(t, testSrc.Discover())
+
+	found := false
+	for _, dev := range testSrc.GetFeatures().Instances[DeviceFeature].Elements {
+		if dev.Attributes["device"] == "a193" {
+			found = true
+			assert.Equal(t, "5.0", dev.Attributes["current_link_speed"])
+			assert.Equal(t, "2", dev.Attributes["current_link_gen"])
+			assert.Equal(t, "1", dev.Attributes["current_link_width"])
+			assert.Equal(t, "true", dev.Attributes["link_degraded"])
+		}
+	}
+	assert.True(t, found)
+}
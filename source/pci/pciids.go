@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pci
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//go:generate ../../hack/update-pci-ids.sh pci.ids.gz
+
+// pciIDsData is the gzip-compressed PCI ID database, see
+// https://pci-ids.ucw.cz/. It is refreshed with 'make update-pci-ids' and
+// 'make verify-pci-ids' fails if only the seed subset is embedded.
+//
+//go:embed pci.ids.gz
+var pciIDsData []byte
+
+// pciVendor holds the names of one vendor and its devices.
+type pciVendor struct {
+	name    string
+	devices map[string]string
+}
+
+var (
+	pciIDsOnce sync.Once
+	pciIDs     map[string]*pciVendor
+	pciIDsErr  error
+)
+
+// getPciIDs returns the parsed PCI ID database. The database is parsed only
+// on first use.
+func getPciIDs() (map[string]*pciVendor, error) {
+	pciIDsOnce.Do(func() {
+		pciIDs, pciIDsErr = parsePciIDs(pciIDsData)
+	})
+	return pciIDs, pciIDsErr
+}
+
+// parsePciIDs parses gzip-compressed data in the pci.ids format. Only the
+// vendor and device names are parsed, subsystems and the class code section
+// are skipped.
+func parsePciIDs(data []byte) (map[string]*pciVendor, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress PCI ID database: %w", err)
+	}
+	defer r.Close()
+
+	vendors := map[string]*pciVendor{}
+	var vendor *pciVendor
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		// The class code section is the last one in the file
+		if strings.HasPrefix(line, "C ") {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "\t\t"):
+			// Subsystem
+			continue
+		case line[0] == '\t':
+			id, name, ok := strings.Cut(line[1:], "  ")
+			if !ok || vendor == nil {
+				continue
+			}
+			vendor.devices[strings.ToLower(id)] = name
+		default:
+			id, name, ok := strings.Cut(line, "  ")
+			if !ok {
+				vendor = nil
+				continue
+			}
+			vendor = &pciVendor{name: name, devices: map[string]string{}}
+			vendors[strings.ToLower(id)] = vendor
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse PCI ID database: %w", err)
+	}
+	return vendors, nil
+}
+
+// companySuffixes are words that end the significant part of a vendor name.
+var companySuffixes = map[string]bool{
+	"ab": true, "ag": true, "and": true, "co": true, "corp": true,
+	"corporation": true, "gmbh": true, "inc": true, "incorporated": true,
+	"limited": true, "llc": true, "ltd": true, "sa": true,
+	"technologies": true, "technology": true,
+}
+
+var (
+	vendorBracketRe  = regexp.MustCompile(`\[([^\]/]+)[^\]]*\]`)
+	nonLabelCharsRe  = regexp.MustCompile(`[^a-z0-9]+`)
+	vendorWordTrimRe = regexp.MustCompile(`[.,]+$`)
+)
+
+// vendorShortName returns a short, label value compatible name of a vendor,
+// e.g. "nvidia" for "NVIDIA Corporation" and "amd" for "Advanced Micro
+// Devices, Inc. [AMD/ATI]".
+func vendorShortName(name string) string {
+	// Prefer the common short name given in brackets
+	if m := vendorBracketRe.FindStringSubmatch(name); m != nil {
+		name = m[1]
+	} else {
+		name, _, _ = strings.Cut(name, ",")
+	}
+
+	words := []string{}
+	for _, w := range strings.Fields(strings.ToLower(name)) {
+		w = vendorWordTrimRe.ReplaceAllString(w, "")
+		if companySuffixes[w] && len(words) > 0 {
+			break
+		}
+		words = append(words, w)
+	}
+	short := nonLabelCharsRe.ReplaceAllString(strings.Join(words, "-"), "-")
+	return strings.Trim(short, "-")
+}
+
+// addPciNames adds the vendor and device names found in the PCI ID database
+// to the attributes of a device.
+func addPciNames(attrs map[string]string) error {
+	vendors, err := getPciIDs()
+	if err != nil {
+		return err
+	}
+	vendor, ok := vendors[attrs["vendor"]]
+	if !ok {
+		return nil
+	}
+	attrs["vendor_name"] = vendor.name
+	attrs["vendor_short_name"] = vendorShortName(vendor.name)
+	if device, ok := vendor.devices[attrs["device"]]; ok {
+		attrs["device_name"] = device
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVendorShortName(t *testing.T) {
+	tcs := map[string]string{
+		"NVIDIA Corporation":                     "nvidia",
+		"Intel Corporation":                      "intel",
+		"Advanced Micro Devices, Inc. [AMD/ATI]": "amd",
+		"Advanced Micro Devices, Inc. [AMD]":     "amd",
+		"Mellanox Technologies":                  "mellanox",
+		"Broadcom Inc. and subsidiaries":         "broadcom",
+		"Red Hat, Inc.":                          "red-hat",
+		"Habana Labs Ltd.":                       "habana-labs",
+		"Samsung Electronics Co Ltd":             "samsung-electronics",
+		"Amazon.com, Inc.":                       "amazon-com",
+	}
+	for name, expected := range tcs {
+		assert.Equal(t, expected, vendorShortName(name), name)
+	}
+}
+
+func TestAddPciNames(t *testing.T) {
+	attrs := map[string]string{"vendor": "10de", "device": "20b0"}
+	assert.NoError(t, addPciNames(attrs))
+	assert.Equal(t, map[string]string{
+		"vendor":            "10de",
+		"device":            "20b0",
+		"vendor_name":       "NVIDIA Corporation",
+		"vendor_short_name": "nvidia",
+		"device_name":       "GA100 [A100 SXM4 40GB]",
+	}, attrs)
+
+	// Unknown device of a known vendor
+	attrs = map[string]string{"vendor": "8086", "device": "ffff"}
+	assert.NoError(t, addPciNames(attrs))
+	assert.Equal(t, "intel", attrs["vendor_short_name"])
+	assert.NotContains(t, attrs, "device_name")
+
+	// Unknown vendor
+	attrs = map[string]string{"vendor": "ffff", "device": "ffff"}
+	assert.NoError(t, addPciNames(attrs))
+	assert.Len(t, attrs, 2)
+}
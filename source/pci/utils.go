@@ -17,9 +17,11 @@ limitations under the License.
 package pci
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -31,6 +33,24 @@ import (
 var mandatoryDevAttrs = []string{"class", "vendor", "device", "subsystem_vendor", "subsystem_device"}
 var optionalDevAttrs = []string{"sriov_totalvfs", "iommu_group/type"}
 
+// pcieGenerations maps PCIe link speeds (in GT/s) to PCIe generations.
+var pcieGenerations = map[string]string{
+	"2.5":  "1",
+	"5.0":  "2",
+	"8.0":  "3",
+	"16.0": "4",
+	"32.0": "5",
+	"64.0": "6",
+}
+
+// aerAttrs maps the AER error counter sysfs files to the name of the total
+// counter in the file and the corresponding device attribute.
+var aerAttrs = map[string][2]string{
+	"aer_dev_correctable": {"TOTAL_ERR_COR", "aer_correctable_errors"},
+	"aer_dev_fatal":       {"TOTAL_ERR_FATAL", "aer_fatal_errors"},
+	"aer_dev_nonfatal":    {"TOTAL_ERR_NONFATAL", "aer_nonfatal_errors"},
+}
+
 // Read a single PCI device attribute
 // A PCI attribute in this context, maps to the corresponding sysfs file
 func readSinglePciAttribute(devPath string, attrName string) (string, error) {
@@ -50,7 +70,7 @@ func readSinglePciAttribute(devPath string, attrName string) (string, error) {
 }
 
 // Read information of one PCI device
-func readPciDevInfo(devPath string) (*nfdv1alpha1.InstanceFeature, error) {
+func readPciDevInfo(devPath string, linkStatus bool) (*nfdv1alpha1.InstanceFeature, error) {
 	attrs := make(map[string]string)
 	for _, attr := range mandatoryDevAttrs {
 		attrVal, err := readSinglePciAttribute(devPath, attr)
@@ -65,12 +85,79 @@ func readPciDevInfo(devPath string) (*nfdv1alpha1.InstanceFeature, error) {
 			attrs[attr] = attrVal
 		}
 	}
+	// NUMA node -1 means that the device has no NUMA affinity
+	if numaNode, err := readSinglePciAttribute(devPath, "numa_node"); err == nil && numaNode != "-1" {
+		attrs["numa_node"] = numaNode
+	}
+	readPciLinkInfo(devPath, attrs, linkStatus)
+	readPciAerInfo(devPath, attrs)
 	return nfdv1alpha1.NewInstanceFeature(attrs), nil
 }
 
+// readPciLinkInfo reads the maximum PCIe link speed and width of a device.
+// The current link speed and width, and the link being marked degraded if it
+// has been trained at a lower speed or width than supported, are read only if
+// linkStatus is set as they may change at runtime, e.g. with link power
+// management.
+func readPciLinkInfo(devPath string, attrs map[string]string, linkStatus bool) {
+	valid := true
+	prefixes := []string{"max"}
+	if linkStatus {
+		prefixes = append(prefixes, "current")
+	}
+	for _, prefix := range prefixes {
+		// The speed is e.g. "8.0 GT/s PCIe" or "Unknown"
+		speed, err := readSinglePciAttribute(devPath, prefix+"_link_speed")
+		speed, _, _ = strings.Cut(speed, " ")
+		if gen, ok := pcieGenerations[speed]; err == nil && ok {
+			attrs[prefix+"_link_speed"] = speed
+			attrs[prefix+"_link_gen"] = gen
+		} else {
+			valid = false
+		}
+
+		// Width 0 (and 255 for max width) means unknown
+		width, err := readSinglePciAttribute(devPath, prefix+"_link_width")
+		if w, convErr := strconv.Atoi(width); err == nil && convErr == nil && w > 0 && w < 255 {
+			attrs[prefix+"_link_width"] = width
+		} else {
+			valid = false
+		}
+	}
+
+	if valid && linkStatus {
+		curWidth, _ := strconv.Atoi(attrs["current_link_width"])
+		maxWidth, _ := strconv.Atoi(attrs["max_link_width"])
+		curGen, _ := strconv.Atoi(attrs["current_link_gen"])
+		maxGen, _ := strconv.Atoi(attrs["max_link_gen"])
+		attrs["link_degraded"] = strconv.FormatBool(curWidth < maxWidth || curGen < maxGen)
+	}
+}
+
+// readPciAerInfo reads whether AER (Advanced Error Reporting) errors have
+// been reported for a device. Only the presence of errors is published as the
+// counters would change the features on every error.
+func readPciAerInfo(devPath string, attrs map[string]string) {
+	for file, names := range aerAttrs {
+		f, err := os.Open(filepath.Join(devPath, file))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && fields[0] == names[0] {
+				attrs[names[1]] = strconv.FormatBool(fields[1] != "0")
+				break
+			}
+		}
+		f.Close()
+	}
+}
+
 // detectPci detects available PCI devices and retrieves their device attributes.
 // An error is returned if reading any of the mandatory attributes fails.
-func detectPci() ([]nfdv1alpha1.InstanceFeature, error) {
+func detectPci(linkStatus bool) ([]nfdv1alpha1.InstanceFeature, error) {
 	sysfsBasePath := hostpath.SysfsDir.Path("bus/pci/devices")
 
 	devices, err := os.ReadDir(sysfsBasePath)
@@ -81,7 +168,7 @@ func detectPci() ([]nfdv1alpha1.InstanceFeature, error) {
 	// Iterate over devices
 	devInfo := make([]nfdv1alpha1.InstanceFeature, 0, len(devices))
 	for _, device := range devices {
-		info, err := readPciDevInfo(filepath.Join(sysfsBasePath, device.Name()))
+		info, err := readPciDevInfo(filepath.Join(sysfsBasePath, device.Name()), linkStatus)
 		if err != nil {
 			klog.ErrorS(err, "failed to read PCI device info")
 			continue
@@ -0,0 +1,324 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "power"
+
+const (
+	// CpufreqFeature holds the cpufreq scaling driver, governor and
+	// frequency limits.
+	CpufreqFeature = "cpufreq"
+	// PstateFeature holds the status of the p-state drivers.
+	PstateFeature = "pstate"
+	// CpuidleFeature holds the cpuidle driver and governor.
+	CpuidleFeature = "cpuidle"
+	// CstateFeature holds the names of the enabled idle states.
+	CstateFeature = "cstate"
+	// RaplFeature holds the RAPL (Running Average Power Limit) power
+	// domains.
+	RaplFeature = "rapl"
+	// TdpFeature holds the thermal design power of the processor packages.
+	TdpFeature = "tdp"
+)
+
+// powerSource implements the FeatureSource interface.
+type powerSource struct {
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                      = powerSource{}
+	_   source.FeatureSource = &src
+)
+
+// Name returns the name of the feature source
+func (s *powerSource) Name() string { return Name }
+
+// Discover method of the FeatureSource interface
+func (s *powerSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	if cpufreq, err := discoverCpufreq(); err != nil {
+		klog.ErrorS(err, "failed to detect cpufreq configuration")
+	} else {
+		s.features.Attributes[CpufreqFeature] = nfdv1alpha1.NewAttributeFeatures(cpufreq)
+	}
+
+	s.features.Attributes[PstateFeature] = nfdv1alpha1.NewAttributeFeatures(discoverPstate())
+
+	if cpuidle, cstates, err := discoverCpuidle(); err != nil {
+		klog.ErrorS(err, "failed to detect cpuidle configuration")
+	} else {
+		s.features.Attributes[CpuidleFeature] = nfdv1alpha1.NewAttributeFeatures(cpuidle)
+		s.features.Flags[CstateFeature] = nfdv1alpha1.NewFlagFeatures(cstates...)
+	}
+
+	if rapl, err := discoverRapl(); err != nil {
+		klog.ErrorS(err, "failed to detect rapl domains")
+	} else {
+		s.features.Instances[RaplFeature] = nfdv1alpha1.NewInstanceFeatures(rapl)
+		s.features.Attributes[TdpFeature] = nfdv1alpha1.NewAttributeFeatures(tdp(rapl))
+	}
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *powerSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// readTrimmed returns the whitespace-trimmed content of a (sysfs) file.
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// discoverCpufreq detects the cpufreq scaling driver and governor and the
+// frequency limits of the cpus. Attributes that differ between the cpufreq
+// policies are not published.
+func discoverCpufreq() (map[string]string, error) {
+	cpufreqDir := hostpath.SysfsDir.Path("devices/system/cpu/cpufreq")
+	policies, err := os.ReadDir(cpufreqDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	// Map sysfs attribute files to feature attribute names
+	files := map[string]string{
+		"scaling_driver":                "driver",
+		"scaling_governor":              "governor",
+		"scaling_available_governors":   "available_governors",
+		"energy_performance_preference": "energy_performance_preference",
+		"cpuinfo_min_freq":              "min_freq_khz",
+		"cpuinfo_max_freq":              "max_freq_khz",
+		"base_frequency":                "base_freq_khz",
+	}
+
+	attrs := map[string]string{}
+	mismatch := map[string]bool{}
+	for _, policy := range policies {
+		if !strings.HasPrefix(policy.Name(), "policy") {
+			continue
+		}
+		for file, name := range files {
+			val, err := readTrimmed(filepath.Join(cpufreqDir, policy.Name(), file))
+			if err != nil {
+				continue
+			}
+			if file == "scaling_available_governors" {
+				// Make the list usable with the InRegexp operator
+				val = strings.Join(strings.Fields(val), ",")
+			}
+			if prev, ok := attrs[name]; ok && prev != val {
+				mismatch[name] = true
+			}
+			attrs[name] = val
+		}
+	}
+	for name := range mismatch {
+		source.Logger(Name).V(2).Info("cpufreq attribute differs between policies, not publishing", "attribute", name)
+		delete(attrs, name)
+	}
+
+	if boost, err := readTrimmed(filepath.Join(cpufreqDir, "boost")); err == nil {
+		attrs["boost"] = strconv.FormatBool(boost == "1")
+	}
+
+	return attrs, nil
+}
+
+// discoverPstate detects the status of the intel_pstate and amd_pstate
+// drivers.
+func discoverPstate() map[string]string {
+	attrs := map[string]string{}
+	cpuDir := hostpath.SysfsDir.Path("devices/system/cpu")
+	for _, driver := range []string{"intel_pstate", "amd_pstate"} {
+		status, err := readTrimmed(filepath.Join(cpuDir, driver, "status"))
+		if err != nil {
+			continue
+		}
+		attrs[driver] = status
+		if driver == "intel_pstate" {
+			if noTurbo, err := readTrimmed(filepath.Join(cpuDir, driver, "no_turbo")); err == nil {
+				attrs["turbo"] = strconv.FormatBool(noTurbo == "0")
+			}
+		}
+	}
+	return attrs
+}
+
+// discoverCpuidle detects the cpuidle driver and governor and the idle states
+// enabled on the first cpu.
+func discoverCpuidle() (map[string]string, []string, error) {
+	cpuDir := hostpath.SysfsDir.Path("devices/system/cpu")
+	attrs := map[string]string{}
+
+	if driver, err := readTrimmed(filepath.Join(cpuDir, "cpuidle/current_driver")); err == nil {
+		attrs["driver"] = driver
+	}
+	// The governor is read-only unless cpuidle_sysfs_switch is enabled
+	for _, file := range []string{"current_governor_ro", "current_governor"} {
+		if governor, err := readTrimmed(filepath.Join(cpuDir, "cpuidle", file)); err == nil {
+			attrs["governor"] = governor
+			break
+		}
+	}
+
+	statesDir := filepath.Join(cpuDir, "cpu0/cpuidle")
+	states, err := os.ReadDir(statesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return attrs, []string{}, nil
+		}
+		return nil, nil, err
+	}
+
+	cstates := []string{}
+	for _, state := range states {
+		if !strings.HasPrefix(state.Name(), "state") {
+			continue
+		}
+		name, err := readTrimmed(filepath.Join(statesDir, state.Name(), "name"))
+		if err != nil {
+			source.Logger(Name).V(2).Info("failed to read idle state name", "state", state.Name(), "err", err)
+			continue
+		}
+		if disabled, err := readTrimmed(filepath.Join(statesDir, state.Name(), "disable")); err == nil && disabled != "0" {
+			continue
+		}
+		cstates = append(cstates, name)
+	}
+	if len(cstates) > 0 {
+		attrs["max_cstate"] = cstates[len(cstates)-1]
+	}
+
+	return attrs, cstates, nil
+}
+
+// discoverRapl detects the RAPL power domains exposed through the powercap
+// framework, together with the long term power limit of each domain.
+func discoverRapl() ([]nfdv1alpha1.InstanceFeature, error) {
+	powercapDir := hostpath.SysfsDir.Path("class/powercap")
+	zones, err := os.ReadDir(powercapDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []nfdv1alpha1.InstanceFeature{}, nil
+		}
+		return nil, err
+	}
+
+	instances := []nfdv1alpha1.InstanceFeature{}
+	for _, zone := range zones {
+		// Skip the control types, e.g. "intel-rapl", which are not zones
+		if !strings.Contains(zone.Name(), ":") {
+			continue
+		}
+		zoneDir := filepath.Join(powercapDir, zone.Name())
+		name, err := readTrimmed(filepath.Join(zoneDir, "name"))
+		if err != nil {
+			source.Logger(Name).V(2).Info("failed to read powercap zone name", "zone", zone.Name(), "err", err)
+			continue
+		}
+		attrs := map[string]string{
+			"zone": zone.Name(),
+			"name": name,
+		}
+		if enabled, err := readTrimmed(filepath.Join(zoneDir, "enabled")); err == nil {
+			attrs["enabled"] = strconv.FormatBool(enabled == "1")
+		}
+		for i := 0; ; i++ {
+			prefix := "constraint_" + strconv.Itoa(i) + "_"
+			cname, err := readTrimmed(filepath.Join(zoneDir, prefix+"name"))
+			if err != nil {
+				break
+			}
+			if cname != "long_term" {
+				continue
+			}
+			if limit, err := readTrimmed(filepath.Join(zoneDir, prefix+"power_limit_uw")); err == nil {
+				attrs["power_limit_uw"] = limit
+			}
+			if maxPower, err := readTrimmed(filepath.Join(zoneDir, prefix+"max_power_uw")); err == nil {
+				attrs["max_power_uw"] = maxPower
+			}
+		}
+		instances = append(instances, *nfdv1alpha1.NewInstanceFeature(attrs))
+	}
+	return instances, nil
+}
+
+// tdp returns the thermal design power of the processor packages, i.e. the
+// highest maximum long term power limit of the package RAPL domains, in
+// watts.
+func tdp(rapl []nfdv1alpha1.InstanceFeature) map[string]string {
+	attrs := map[string]string{}
+	var tdpUw, packages int64
+	for _, domain := range rapl {
+		if !strings.HasPrefix(domain.Attributes["name"], "package-") {
+			continue
+		}
+		packages++
+		val, ok := domain.Attributes["max_power_uw"]
+		if !ok {
+			val = domain.Attributes["power_limit_uw"]
+		}
+		uw, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			continue
+		}
+		tdpUw = max(tdpUw, uw)
+	}
+	if packages > 0 {
+		attrs["packages"] = strconv.FormatInt(packages, 10)
+	}
+	if tdpUw > 0 {
+		attrs["package_watts"] = strconv.FormatInt(tdpUw/1000000, 10)
+	}
+	return attrs
+}
+
+func init() {
+	source.Register(&src)
+}
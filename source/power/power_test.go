@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package power
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/source/sourcetest"
+)
+
+func TestPowerSource(t *testing.T) {
+	assert.Equal(t, src.Name(), Name)
+
+	// Check that GetFeatures works without discovery
+	src.features = nil
+	assert.NotNil(t, src.GetFeatures())
+}
+
+func TestPowerSourceGolden(t *testing.T) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	sourcetest.RunGoldenTests(t, &powerSource{}, filepath.Join(filepath.Dir(thisFile), "testdata"))
+}
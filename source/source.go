@@ -19,9 +19,16 @@ package source
 //go:generate mockery --name=LabelSource --inpackage
 
 import (
+	"errors"
 	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	klogutils "github.com/openshift/node-feature-discovery/pkg/utils/klog"
 )
 
 // Source is the base interface for all other source interfaces
@@ -74,6 +81,40 @@ type TestSource interface {
 	IsTestSource() bool
 }
 
+// ProbingSource is an interface for a source that is able to detect which of
+// its inputs are accessible. Unavailable inputs are skipped in feature
+// discovery.
+type ProbingSource interface {
+	Source
+
+	// Probe checks the availability of the inputs of the source. If
+	// minimalPrivileges is true, privileged inputs are treated as unavailable
+	// without trying to access them.
+	Probe(minimalPrivileges bool) InputStatuses
+}
+
+// InputStatus describes the availability of one input of a source
+type InputStatus struct {
+	// Name of the input, unique within one source
+	Name string
+	// Path is the file or directory that was probed
+	Path string
+	// Privileged is true if reading the input requires elevated privileges,
+	// i.e. running as root or with additional capabilities. Files that are
+	// readable by everyone on the host are not privileged, even if they are
+	// accessed through a host mount.
+	Privileged bool
+	// Err tells why the input is not available, nil if it is
+	Err error
+}
+
+// InputStatuses is the collection of input statuses of one source
+type InputStatuses []InputStatus
+
+// ErrMinimalPrivileges is the reason reported for privileged inputs when
+// running with minimal privileges.
+var ErrMinimalPrivileges = errors.New("privileged input disabled in minimal privileges mode")
+
 // FeatureLabelValue represents the value of one feature label
 type FeatureLabelValue interface{}
 
@@ -87,6 +128,13 @@ type Config interface {
 // sources contain all registered sources
 var sources = make(map[string]Source)
 
+// featureHashSalt is the per-cluster key used for hashing sensitive values,
+// e.g. serial numbers, published by the sources.
+var featureHashSalt []byte
+
+// logVerbosity holds the per-source log verbosity overrides.
+var logVerbosity klogutils.VerbosityOverrides
+
 // Register registers a source.
 func Register(s Source) {
 	if name, ok := sources[s.Name()]; ok {
@@ -152,6 +200,57 @@ func GetAllConfigurableSources() map[string]ConfigurableSource {
 	return all
 }
 
+// GetAllProbingSources returns all registered probing sources
+func GetAllProbingSources() map[string]ProbingSource {
+	all := make(map[string]ProbingSource)
+	for k, v := range sources {
+		if s, ok := v.(ProbingSource); ok {
+			all[k] = s
+		}
+	}
+	return all
+}
+
+// ProbeInput checks if the given path can be opened for reading.
+func ProbeInput(name, path string, privileged, minimalPrivileges bool) InputStatus {
+	status := InputStatus{Name: name, Path: path, Privileged: privileged}
+	if privileged && minimalPrivileges {
+		status.Err = ErrMinimalPrivileges
+		return status
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	f.Close()
+
+	return status
+}
+
+// Available returns true if the named input is available. Inputs that have
+// not been probed are expected to be available.
+func (l InputStatuses) Available(name string) bool {
+	for _, s := range l {
+		if s.Name == name {
+			return s.Err == nil
+		}
+	}
+	return true
+}
+
+// AllPrivilegedUnavailable returns true if all inputs are unavailable because
+// of running with minimal privileges.
+func (l InputStatuses) AllPrivilegedUnavailable() bool {
+	for _, s := range l {
+		if !errors.Is(s.Err, ErrMinimalPrivileges) {
+			return false
+		}
+	}
+	return len(l) > 0
+}
+
 // GetAllFeatures returns a combined set of all features from all feature
 // sources.
 func GetAllFeatures() *nfdv1alpha1.Features {
@@ -185,3 +284,31 @@ func GetAllFeatures() *nfdv1alpha1.Features {
 	}
 	return features
 }
+
+// SetFeatureHashSalt sets the per-cluster key used by HashFeatureValue.
+func SetFeatureHashSalt(salt []byte) {
+	featureHashSalt = salt
+}
+
+// HashFeatureValue returns a salted hash of a sensitive value. The second
+// return value is false if no salt has been configured in which case the
+// value should not be published at all.
+func HashFeatureValue(value string) (string, bool) {
+	if len(featureHashSalt) == 0 {
+		return "", false
+	}
+	return utils.HashValue(featureHashSalt, value), true
+}
+
+// SetLogVerbosity sets the per-module log verbosity overrides used by Logger.
+func SetLogVerbosity(overrides klogutils.VerbosityOverrides) {
+	logVerbosity = overrides
+}
+
+// Logger returns the logger to be used by a source. The modules are the
+// name of the source, optionally preceded by more specific names, e.g. a
+// custom rule. The verbosity of the logger is raised to the override of the
+// first module that has one.
+func Logger(modules ...string) logr.Logger {
+	return logVerbosity.Logger(klog.Background(), modules...)
+}
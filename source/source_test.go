@@ -18,6 +18,7 @@ package source_test
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,6 +39,40 @@ import (
 	_ "github.com/openshift/node-feature-discovery/source/usb"
 )
 
+func TestProbeInput(t *testing.T) {
+	dir := t.TempDir()
+
+	s := source.ProbeInput("dir", dir, false, true)
+	assert.Nil(t, s.Err)
+
+	s = source.ProbeInput("missing", filepath.Join(dir, "missing"), false, false)
+	assert.NotNil(t, s.Err)
+
+	s = source.ProbeInput("privileged", dir, true, false)
+	assert.Nil(t, s.Err)
+
+	s = source.ProbeInput("privileged", dir, true, true)
+	assert.ErrorIs(t, s.Err, source.ErrMinimalPrivileges)
+}
+
+func TestInputStatuses(t *testing.T) {
+	dir := t.TempDir()
+
+	inputs := source.InputStatuses{
+		source.ProbeInput("a", dir, true, true),
+		source.ProbeInput("b", dir, true, true),
+	}
+	assert.False(t, inputs.Available("a"))
+	assert.True(t, inputs.Available("not-probed"))
+	assert.True(t, inputs.AllPrivilegedUnavailable())
+
+	inputs = append(inputs, source.ProbeInput("c", dir, false, true))
+	assert.True(t, inputs.Available("c"))
+	assert.False(t, inputs.AllPrivilegedUnavailable())
+
+	assert.False(t, source.InputStatuses{}.AllPrivilegedUnavailable())
+}
+
 func TestLabelSources(t *testing.T) {
 	sources := source.GetAllLabelSources()
 	assert.NotZero(t, len(sources))
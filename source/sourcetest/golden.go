@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sourcetest implements a golden-file test harness for feature
+// sources. Each fixture is a recorded filesystem tree of a (real) system,
+// e.g. captured with hack/capture-source-fixture.sh, and a golden file
+// containing the features expected to be discovered from it:
+//
+//	testdata/<fixture>/{sys,proc,etc,...}
+//	testdata/<fixture>.golden.json
+//
+// The golden files can be (re-)generated by running the tests with the
+// -update-golden flag.
+package sourcetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// GoldenSuffix is the file name suffix of golden files.
+const GoldenSuffix = ".golden.json"
+
+var updateGolden = flag.Bool("update-golden", false, "update the golden files of feature source tests")
+
+// Fixtures returns the names of all fixtures, i.e. subdirectories, found
+// under dir.
+func Fixtures(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read fixture directory: %v", err)
+	}
+	fixtures := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			fixtures = append(fixtures, e.Name())
+		}
+	}
+	return fixtures
+}
+
+// RunGoldenTests runs feature discovery of a source against each fixture
+// found under dir and compares the discovered features against the golden
+// file of the fixture. Fixtures without a golden file are skipped unless the
+// golden files are being updated.
+func RunGoldenTests(t *testing.T, src source.FeatureSource, dir string) {
+	t.Helper()
+
+	for _, fixture := range Fixtures(t, dir) {
+		t.Run(fixture, func(t *testing.T) {
+			goldenPath := filepath.Join(dir, fixture+GoldenSuffix)
+			if _, err := os.Stat(goldenPath); os.IsNotExist(err) && !*updateGolden {
+				t.Skipf("no golden file %s", goldenPath)
+			}
+
+			actual, err := Discover(src, filepath.Join(dir, fixture))
+			if err != nil {
+				t.Fatalf("feature discovery failed: %v", err)
+			}
+
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("discovered features differ from %s (re-run with -update-golden to update):\n%s",
+					goldenPath, diffLines(string(expected), string(actual)))
+			}
+		})
+	}
+}
+
+// Discover runs feature discovery of a source with all host directories
+// pointing under root and returns the discovered features in JSON format.
+func Discover(src source.FeatureSource, root string) ([]byte, error) {
+	hostpath.SetRoot(root)
+	defer hostpath.SetRoot("")
+
+	if err := src.Discover(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(src.GetFeatures(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// diffLines returns a minimal line-based diff of two strings, showing the
+// lines that only exist in one of them.
+func diffLines(expected, actual string) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	// Longest common subsequence
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		default:
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		}
+	}
+	return sb.String()
+}
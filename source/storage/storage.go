@@ -22,8 +22,6 @@ import (
 	"path/filepath"
 	"strings"
 
-	"k8s.io/klog/v2"
-
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
@@ -38,6 +36,7 @@ const BlockFeature = "block"
 // storageSource implements the FeatureSource and LabelSource interfaces.
 type storageSource struct {
 	features *nfdv1alpha1.Features
+	inputs   source.InputStatuses
 }
 
 // Singleton source instance
@@ -45,6 +44,7 @@ var (
 	src storageSource
 	_   source.FeatureSource = &src
 	_   source.LabelSource   = &src
+	_   source.ProbingSource = &src
 )
 
 // queueAttrs is the list of files under /sys/block/<dev>/queue that we're trying to read
@@ -71,17 +71,30 @@ func (s *storageSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface
+func (s *storageSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		source.ProbeInput(BlockFeature, hostpath.SysfsDir.Path("block"), false, minimalPrivileges),
+	}
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface
 func (s *storageSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
+	if !s.inputs.Available(BlockFeature) {
+		source.Logger(Name).V(2).Info("block devices input not available, skipping")
+		return nil
+	}
+
 	devs, err := detectBlock()
 	if err != nil {
 		return fmt.Errorf("failed to detect block devices: %w", err)
 	}
 	s.features.Instances[BlockFeature] = nfdv1alpha1.InstanceFeatureSet{Elements: devs}
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -116,7 +129,7 @@ func readBlockDevQueueInfo(path string) *nfdv1alpha1.InstanceFeature {
 	for _, attrName := range queueAttrs {
 		data, err := os.ReadFile(filepath.Join(path, "queue", attrName))
 		if err != nil {
-			klog.V(3).ErrorS(err, "failed to read block device queue attribute", "attributeName", attrName)
+			source.Logger(Name).V(3).Info("failed to read block device queue attribute", "attributeName", attrName, "err", err)
 			continue
 		}
 		attrs[attrName] = strings.TrimSpace(string(data))
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package system
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+)
+
+// discoverOstree returns the identity of the booted ostree deployment. It
+// returns nil if the system was not booted from an ostree deployment.
+func discoverOstree() (map[string]string, error) {
+	cmdline, err := os.ReadFile(hostpath.ProcfsDir.Path("cmdline"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var bootPath string
+	for _, arg := range strings.Fields(string(cmdline)) {
+		if v, ok := strings.CutPrefix(arg, "ostree="); ok {
+			bootPath = v
+		}
+	}
+	if bootPath == "" {
+		return nil, nil
+	}
+
+	attrs := map[string]string{"booted": "true"}
+
+	// The boot path, e.g. /ostree/boot.1/rhcos/<bootcsum>/0, is a symlink to
+	// the deployment directory /ostree/deploy/<stateroot>/deploy/<checksum>.<serial>
+	target, err := os.Readlink(hostpath.OstreeDir.Path(strings.TrimPrefix(bootPath, "/ostree/")))
+	if err != nil {
+		return attrs, fmt.Errorf("failed to resolve booted ostree deployment: %w", err)
+	}
+	deployment := filepath.Base(target)
+	stateroot := filepath.Base(filepath.Dir(filepath.Dir(target)))
+	checksum, serial, _ := strings.Cut(deployment, ".")
+	attrs["stateroot"] = stateroot
+	attrs["checksum"] = checksum
+	attrs["serial"] = serial
+
+	origin, err := parseOstreeOrigin(hostpath.OstreeDir.Path("deploy", stateroot, "deploy", deployment+".origin"))
+	if err != nil {
+		return attrs, fmt.Errorf("failed to read ostree origin: %w", err)
+	}
+
+	if ref := origin["origin"]["container-image-reference"]; ref != "" {
+		attrs["image_reference"] = ref
+		if _, digest, ok := strings.Cut(ref, "@"); ok {
+			attrs["image_digest"] = digest
+		}
+	}
+	if refspec := origin["origin"]["refspec"]; refspec != "" {
+		attrs["refspec"] = refspec
+	}
+
+	// Packages layered on top of the base image
+	count := 0
+	for _, key := range []string{"requested", "requested-local"} {
+		for _, pkg := range strings.Split(origin["packages"][key], ";") {
+			if pkg != "" {
+				count++
+			}
+		}
+	}
+	attrs["layered_packages"] = strconv.FormatBool(count > 0)
+	attrs["layered_package_count"] = strconv.Itoa(count)
+
+	return attrs, nil
+}
+
+// parseOstreeOrigin parses an ostree origin file (in the GKeyFile format)
+// into a map of sections.
+func parseOstreeOrigin(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	origin := map[string]map[string]string{}
+	section := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+		default:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				if origin[section] == nil {
+					origin[section] = map[string]string{}
+				}
+				origin[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return origin, s.Err()
+}
+
+// discoverMachineConfig returns the machine config currently applied by the
+// machine-config-daemon. It returns nil if the node is not managed by the
+// machine-config-daemon.
+func discoverMachineConfig() (map[string]string, error) {
+	data, err := os.ReadFile(hostpath.EtcDir.Path("machine-config-daemon/currentconfig"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	mc := struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(data, &mc); err != nil {
+		return nil, fmt.Errorf("failed to parse machine config: %w", err)
+	}
+	if mc.Metadata.Name == "" {
+		return nil, fmt.Errorf("machine config name is empty")
+	}
+
+	attrs := map[string]string{"current": mc.Metadata.Name}
+	// Rendered configs are named rendered-<pool>-<hash>
+	if v, ok := strings.CutPrefix(mc.Metadata.Name, "rendered-"); ok {
+		if i := strings.LastIndex(v, "-"); i > 0 {
+			attrs["pool"] = v[:i]
+		}
+	}
+	return attrs, nil
+}
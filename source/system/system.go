@@ -17,16 +17,12 @@ limitations under the License.
 package system
 
 import (
-	"bufio"
-	"os"
 	"regexp"
-	"strings"
 
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
-	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
@@ -44,14 +40,17 @@ var osReleaseFields = [...]string{
 const Name = "system"
 
 const (
-	OsReleaseFeature = "osrelease"
-	NameFeature      = "name"
-	DmiIdFeature     = "dmiid"
+	OsReleaseFeature     = "osrelease"
+	NameFeature          = "name"
+	DmiIdFeature         = "dmiid"
+	OstreeFeature        = "ostree"
+	MachineConfigFeature = "machineconfig"
 )
 
 // systemSource implements the FeatureSource and LabelSource interfaces.
 type systemSource struct {
 	features *nfdv1alpha1.Features
+	inputs   source.InputStatuses
 }
 
 // Singleton source instance
@@ -59,6 +58,7 @@ var (
 	src systemSource
 	_   source.FeatureSource = &src
 	_   source.LabelSource   = &src
+	_   source.ProbingSource = &src
 )
 
 func (s *systemSource) Name() string { return Name }
@@ -82,6 +82,7 @@ func (s *systemSource) GetLabels() (source.FeatureLabels, error) {
 
 // Discover method of the FeatureSource interface
 func (s *systemSource) Discover() error {
+	logger := source.Logger(Name)
 	s.features = nfdv1alpha1.NewFeatures()
 
 	// Get node name
@@ -89,8 +90,9 @@ func (s *systemSource) Discover() error {
 	s.features.Attributes[NameFeature].Elements["nodename"] = utils.NodeName()
 
 	// Get os-release information
-	release, err := parseOSRelease()
-	if err != nil {
+	if !s.inputs.Available(OsReleaseFeature) {
+		logger.V(2).Info("os-release input not available, skipping")
+	} else if release, err := parseOSRelease(); err != nil {
 		klog.ErrorS(err, "failed to get os-release")
 	} else {
 		s.features.Attributes[OsReleaseFeature] = nfdv1alpha1.NewAttributeFeatures(release)
@@ -108,6 +110,10 @@ func (s *systemSource) Discover() error {
 	// Get DMI ID attributes
 	dmiIDAttributeNames := []string{"sys_vendor"}
 	dmiAttrs := make(map[string]string)
+	if !s.inputs.Available(DmiIdFeature) {
+		logger.V(2).Info("DMI ID input not available, skipping")
+		dmiIDAttributeNames = nil
+	}
 	for _, name := range dmiIDAttributeNames {
 		val, err := getDmiIDAttribute(name)
 		if err != nil {
@@ -121,7 +127,30 @@ func (s *systemSource) Discover() error {
 		s.features.Attributes[DmiIdFeature] = nfdv1alpha1.NewAttributeFeatures(dmiAttrs)
 	}
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	// Get the identity of the booted ostree deployment
+	// (partial information is published if e.g. the origin is not readable)
+	if !s.inputs.Available(OstreeFeature) {
+		logger.V(2).Info("ostree input not available, skipping")
+	} else {
+		ostree, err := discoverOstree()
+		if err != nil {
+			klog.ErrorS(err, "failed to detect ostree deployment")
+		}
+		if ostree != nil {
+			s.features.Attributes[OstreeFeature] = nfdv1alpha1.NewAttributeFeatures(ostree)
+		}
+	}
+
+	// Get the machine config applied by the machine-config-daemon
+	if !s.inputs.Available(MachineConfigFeature) {
+		logger.V(2).Info("machine config input not available, skipping")
+	} else if mc, err := discoverMachineConfig(); err != nil {
+		klog.ErrorS(err, "failed to detect machine config")
+	} else if mc != nil {
+		s.features.Attributes[MachineConfigFeature] = nfdv1alpha1.NewAttributeFeatures(mc)
+	}
+
+	logger.V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
@@ -134,29 +163,6 @@ func (s *systemSource) GetFeatures() *nfdv1alpha1.Features {
 	return s.features
 }
 
-// Read and parse os-release file
-func parseOSRelease() (map[string]string, error) {
-	release := map[string]string{}
-
-	f, err := os.Open(hostpath.EtcDir.Path("os-release"))
-	if err != nil {
-		return nil, err
-	}
-
-	re := regexp.MustCompile(`^(?P<key>\w+)=(?P<value>.+)`)
-
-	// Read line-by-line
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		line := s.Text()
-		if m := re.FindStringSubmatch(line); m != nil {
-			release[m[1]] = strings.Trim(m[2], `"'`)
-		}
-	}
-
-	return release, nil
-}
-
 // Split version number into sub-components. Verifies that they are numerical
 // so that they can be fully utilized in k8s nodeAffinity
 func splitVersion(version string) map[string]string {
@@ -173,16 +179,6 @@ func splitVersion(version string) map[string]string {
 	return components
 }
 
-// Read /sys/devices/virtual/dmi/id attribute
-func getDmiIDAttribute(name string) (string, error) {
-	s, err := os.ReadFile(hostpath.SysfsDir.Path("devices/virtual/dmi/id/", name))
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSpace(string(s)), nil
-}
-
 func init() {
 	source.Register(&src)
 }
@@ -0,0 +1,76 @@
+//go:build !windows
+// +build !windows
+
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package system
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Probe method of the ProbingSource interface
+func (s *systemSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		source.ProbeInput(OsReleaseFeature, hostpath.EtcDir.Path("os-release"), false, minimalPrivileges),
+		source.ProbeInput(DmiIdFeature, hostpath.SysfsDir.Path("devices/virtual/dmi/id"), false, minimalPrivileges),
+		// The /ostree directory is only mounted on hosts booted from ostree
+		source.ProbeInput(OstreeFeature, hostpath.OstreeDir.Path(), false, minimalPrivileges),
+		// Only present on nodes managed by the machine-config-daemon
+		source.ProbeInput(MachineConfigFeature, hostpath.EtcDir.Path("machine-config-daemon"), false, minimalPrivileges),
+	}
+	return s.inputs
+}
+
+// Read and parse os-release file
+func parseOSRelease() (map[string]string, error) {
+	release := map[string]string{}
+
+	f, err := os.Open(hostpath.EtcDir.Path("os-release"))
+	if err != nil {
+		return nil, err
+	}
+
+	re := regexp.MustCompile(`^(?P<key>\w+)=(?P<value>.+)`)
+
+	// Read line-by-line
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if m := re.FindStringSubmatch(line); m != nil {
+			release[m[1]] = strings.Trim(m[2], `"'`)
+		}
+	}
+
+	return release, nil
+}
+
+// Read /sys/devices/virtual/dmi/id attribute
+func getDmiIDAttribute(name string) (string, error) {
+	s, err := os.ReadFile(hostpath.SysfsDir.Path("devices/virtual/dmi/id/", name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(s)), nil
+}
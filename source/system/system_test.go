@@ -17,9 +17,13 @@ limitations under the License.
 package system
 
 import (
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/source/sourcetest"
 )
 
 func TestSystemSource(t *testing.T) {
@@ -33,3 +37,9 @@ func TestSystemSource(t *testing.T) {
 	assert.Empty(t, l)
 
 }
+
+func TestSystemSourceGolden(t *testing.T) {
+	t.Setenv("NODE_NAME", "golden-node")
+	_, thisFile, _, _ := runtime.Caller(0)
+	sourcetest.RunGoldenTests(t, &systemSource{}, filepath.Join(filepath.Dir(thisFile), "testdata"))
+}
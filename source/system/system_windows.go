@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package system
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+const (
+	currentVersionKey = `SOFTWARE\Microsoft\Windows NT\CurrentVersion`
+	biosKey           = `HARDWARE\DESCRIPTION\System\BIOS`
+)
+
+// dmiIDRegistryValues maps DMI ID attribute names to the corresponding
+// registry values under the BIOS key
+var dmiIDRegistryValues = map[string]string{
+	"sys_vendor":   "SystemManufacturer",
+	"product_name": "SystemProductName",
+	"bios_vendor":  "BIOSVendor",
+	"board_vendor": "BaseBoardManufacturer",
+	"board_name":   "BaseBoardProduct",
+}
+
+// Probe method of the ProbingSource interface
+func (s *systemSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		probeRegistryKey(OsReleaseFeature, currentVersionKey),
+		probeRegistryKey(DmiIdFeature, biosKey),
+	}
+	return s.inputs
+}
+
+func probeRegistryKey(name, path string) source.InputStatus {
+	status := source.InputStatus{Name: name, Path: `HKLM\` + path}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	k.Close()
+	return status
+}
+
+// Read the Windows version from the registry, using the same keys as the
+// os-release file on Linux
+func parseOSRelease() (map[string]string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, currentVersionKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	major, _, err := k.GetIntegerValue("CurrentMajorVersionNumber")
+	if err != nil {
+		return nil, err
+	}
+	minor, _, err := k.GetIntegerValue("CurrentMinorVersionNumber")
+	if err != nil {
+		return nil, err
+	}
+	build, _, err := k.GetStringValue("CurrentBuildNumber")
+	if err != nil {
+		return nil, err
+	}
+
+	release := map[string]string{
+		"ID":         "windows",
+		"VERSION_ID": fmt.Sprintf("%d.%d.%s", major, minor, build),
+	}
+	if v, _, err := k.GetStringValue("ProductName"); err == nil {
+		release["NAME"] = v
+	}
+	if v, _, err := k.GetStringValue("DisplayVersion"); err == nil {
+		release["VERSION"] = v
+	}
+	if ubr, _, err := k.GetIntegerValue("UBR"); err == nil {
+		release["BUILD_ID"] = fmt.Sprintf("%s.%d", build, ubr)
+	}
+
+	return release, nil
+}
+
+// Read DMI ID attribute from the registry
+func getDmiIDAttribute(name string) (string, error) {
+	value, ok := dmiIDRegistryValues[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported DMI ID attribute %q", name)
+	}
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, biosKey, registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+
+	s, _, err := k.GetStringValue(value)
+	return s, err
+}
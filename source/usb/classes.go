@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usb
+
+// usbClassNames maps the USB class codes to human readable names, see
+// https://www.usb.org/defined-class-codes
+var usbClassNames = map[string]string{
+	"01": "audio",
+	"02": "communications",
+	"03": "hid",
+	"05": "physical",
+	"06": "image",
+	"07": "printer",
+	"08": "mass-storage",
+	"09": "hub",
+	"0a": "cdc-data",
+	"0b": "smart-card",
+	"0d": "content-security",
+	"0e": "video",
+	"0f": "personal-healthcare",
+	"10": "audio-video",
+	"11": "billboard",
+	"12": "type-c-bridge",
+	"3c": "i3c",
+	"dc": "diagnostic",
+	"e0": "wireless-controller",
+	"ef": "miscellaneous",
+	"fe": "application-specific",
+	"ff": "vendor-specific",
+}
+
+// usbSubclassNames maps the well-known class/subclass code pairs to human
+// readable names.
+var usbSubclassNames = map[string]string{
+	"01/01": "audio-control",
+	"01/02": "audio-streaming",
+	"01/03": "midi-streaming",
+	"02/02": "acm",
+	"02/06": "ethernet-networking",
+	"02/0d": "network-control",
+	"03/01": "boot-interface",
+	"06/01": "still-image-capture",
+	"08/01": "rbc",
+	"08/02": "mmc-5",
+	"08/04": "ufi",
+	"08/06": "scsi",
+	"0e/01": "video-control",
+	"0e/02": "video-streaming",
+	"0e/03": "video-interface-collection",
+	"e0/01": "radio-frequency",
+	"fe/01": "device-firmware-upgrade",
+	"fe/02": "irda-bridge",
+	"fe/03": "test-and-measurement",
+}
+
+// addClassNames adds the human readable names of the class and subclass
+// codes of a device or interface to its attributes.
+func addClassNames(attrs map[string]string) {
+	if name, ok := usbClassNames[attrs["class"]]; ok {
+		attrs["class_name"] = name
+	}
+	if name, ok := usbSubclassNames[attrs["class"]+"/"+attrs["subclass"]]; ok {
+		attrs["subclass_name"] = name
+	}
+}
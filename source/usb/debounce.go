@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package usb
+
+import (
+	"sort"
+	"time"
+)
+
+// timeNow is the clock used for debouncing, replaced in tests.
+var timeNow = time.Now
+
+// debouncer suppresses flapping USB devices. A device that appears is
+// reported only after it has been present for the debounce window, and a
+// device that disappears keeps being reported until it has been gone for
+// the debounce window.
+type debouncer struct {
+	devices map[string]*debounceState
+}
+
+type debounceState struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	dev       *usbDevice
+}
+
+// filter returns the devices to be reported, given the devices detected at
+// the given time.
+func (d *debouncer) filter(devs []*usbDevice, window time.Duration, now time.Time) []*usbDevice {
+	if window <= 0 {
+		d.devices = nil
+		return devs
+	}
+
+	// Devices present at the first round are reported immediately so that
+	// a restart of nfd-worker does not cause label churn.
+	firstRound := d.devices == nil
+	if firstRound {
+		d.devices = make(map[string]*debounceState, len(devs))
+	}
+
+	for _, dev := range devs {
+		state, ok := d.devices[dev.id]
+		if !ok || now.Sub(state.lastSeen) > window {
+			state = &debounceState{firstSeen: now}
+			if firstRound {
+				state.firstSeen = now.Add(-window)
+			}
+			d.devices[dev.id] = state
+		}
+		state.lastSeen = now
+		state.dev = dev
+	}
+
+	ids := make([]string, 0, len(d.devices))
+	for id, state := range d.devices {
+		if now.Sub(state.lastSeen) > window {
+			delete(d.devices, id)
+			continue
+		}
+		if now.Sub(state.firstSeen) >= window {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	ret := make([]*usbDevice, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, d.devices[id].dev)
+	}
+	return ret
+}
@@ -25,17 +25,28 @@ import (
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
 	"github.com/openshift/node-feature-discovery/source"
 )
 
 // Name of this feature source
 const Name = "usb"
 
-const DeviceFeature = "device"
+const (
+	// DeviceFeature holds one instance per device and class, devices with
+	// interface level classes producing one instance per interface class.
+	DeviceFeature = "device"
+	// AttachedFeature holds one instance per attached physical device.
+	AttachedFeature = "attached"
+)
 
 type Config struct {
 	DeviceClassWhitelist []string `json:"deviceClassWhitelist,omitempty"`
 	DeviceLabelFields    []string `json:"deviceLabelFields,omitempty"`
+	// DebounceWindow is the time a device must be continuously present
+	// before, or absent after, it is reported, in order to prevent label
+	// churn caused by flapping devices. Zero disables debouncing.
+	DebounceWindow utils.DurationVal `json:"debounceWindow,omitempty"`
 }
 
 // newDefaultConfig returns a new config with pre-populated defaults
@@ -53,8 +64,10 @@ func defaultDeviceLabelFields() []string { return []string{"class", "vendor", "d
 
 // usbSource implements the LabelSource and ConfigurableSource interfaces.
 type usbSource struct {
-	config   *Config
-	features *nfdv1alpha1.Features
+	config    *Config
+	features  *nfdv1alpha1.Features
+	debouncer debouncer
+	inputs    source.InputStatuses
 }
 
 // Singleton source instance
@@ -63,6 +76,7 @@ var (
 	_   source.FeatureSource      = &src
 	_   source.LabelSource        = &src
 	_   source.ConfigurableSource = &src
+	_   source.ProbingSource      = &src
 )
 
 // Name returns the name of the feature source
@@ -134,17 +148,39 @@ func (s *usbSource) GetLabels() (source.FeatureLabels, error) {
 	return labels, nil
 }
 
+// Probe method of the ProbingSource interface
+func (s *usbSource) Probe(minimalPrivileges bool) source.InputStatuses {
+	s.inputs = source.InputStatuses{
+		source.ProbeInput(DeviceFeature, hostpath.SysfsDir.Path("bus/usb/devices"), false, minimalPrivileges),
+	}
+	return s.inputs
+}
+
 // Discover method of the FeatureSource interface
 func (s *usbSource) Discover() error {
 	s.features = nfdv1alpha1.NewFeatures()
 
+	if !s.inputs.Available(DeviceFeature) {
+		source.Logger(Name).V(2).Info("usb devices input not available, skipping")
+		return nil
+	}
+
 	devs, err := detectUsb()
 	if err != nil {
 		return fmt.Errorf("failed to detect USB devices: %s", err.Error())
 	}
-	s.features.Instances[DeviceFeature] = nfdv1alpha1.NewInstanceFeatures(devs)
+	devs = s.debouncer.filter(devs, s.config.DebounceWindow.Duration, timeNow())
+
+	instances := make([]nfdv1alpha1.InstanceFeature, 0, len(devs))
+	attached := make([]nfdv1alpha1.InstanceFeature, 0, len(devs))
+	for _, dev := range devs {
+		instances = append(instances, dev.instances...)
+		attached = append(attached, *nfdv1alpha1.NewInstanceFeature(dev.attrs))
+	}
+	s.features.Instances[DeviceFeature] = nfdv1alpha1.NewInstanceFeatures(instances)
+	s.features.Instances[AttachedFeature] = nfdv1alpha1.NewInstanceFeatures(attached)
 
-	klog.V(3).InfoS("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
 
 	return nil
 }
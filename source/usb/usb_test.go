@@ -17,9 +17,15 @@ limitations under the License.
 package usb
 
 import (
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/openshift/node-feature-discovery/source"
+	"github.com/openshift/node-feature-discovery/source/sourcetest"
 )
 
 func TestUsbSource(t *testing.T) {
@@ -33,3 +39,51 @@ func TestUsbSource(t *testing.T) {
 	assert.Empty(t, l)
 
 }
+
+func TestUsbSourceGolden(t *testing.T) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	source.SetFeatureHashSalt([]byte("salt"))
+	defer source.SetFeatureHashSalt(nil)
+	sourcetest.RunGoldenTests(t, &usbSource{config: newDefaultConfig()}, filepath.Join(filepath.Dir(thisFile), "testdata"))
+}
+
+func TestDebounce(t *testing.T) {
+	window := time.Minute
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	dev := func(id string) *usbDevice { return &usbDevice{id: id} }
+	ids := func(devs []*usbDevice) []string {
+		ret := []string{}
+		for _, d := range devs {
+			ret = append(ret, d.id)
+		}
+		return ret
+	}
+
+	d := debouncer{}
+
+	// Devices present at the first round are reported immediately
+	assert.Equal(t, []string{"a"}, ids(d.filter([]*usbDevice{dev("a")}, window, at(0))))
+
+	// New device is reported only after it has been present for the window
+	assert.Equal(t, []string{"a"}, ids(d.filter([]*usbDevice{dev("a"), dev("b")}, window, at(30*time.Second))))
+	assert.Equal(t, []string{"a"}, ids(d.filter([]*usbDevice{dev("a"), dev("b")}, window, at(60*time.Second))))
+	assert.Equal(t, []string{"a", "b"}, ids(d.filter([]*usbDevice{dev("a"), dev("b")}, window, at(90*time.Second))))
+
+	// Removed device is reported until it has been gone for the window
+	assert.Equal(t, []string{"a", "b"}, ids(d.filter([]*usbDevice{dev("b")}, window, at(120*time.Second))))
+	assert.Equal(t, []string{"b"}, ids(d.filter([]*usbDevice{dev("b")}, window, at(160*time.Second))))
+
+	// Flapping device is never reported
+	for i := 0; i < 5; i++ {
+		devs := []*usbDevice{dev("b")}
+		if i%2 == 0 {
+			devs = append(devs, dev("c"))
+		}
+		assert.Equal(t, []string{"b"}, ids(d.filter(devs, window, at(time.Duration(170+i*10)*time.Second))))
+	}
+
+	// Zero window disables debouncing
+	assert.Equal(t, []string{"c"}, ids(d.filter([]*usbDevice{dev("c")}, 0, at(230*time.Second))))
+	assert.Nil(t, d.devices)
+}
@@ -21,23 +21,47 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"k8s.io/klog/v2"
 
 	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
 	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
 )
 
-var devAttrs = []string{"class", "vendor", "device", "serial"}
+var devAttrs = []string{"class", "vendor", "device", "subclass", "protocol"}
 
 // The USB device sysfs files do not have terribly user friendly names, map
 // these for consistency with the PCI matcher.
 var devAttrFileMap = map[string]string{
-	"class":  "bDeviceClass",
-	"device": "idProduct",
-	"vendor": "idVendor",
-	"serial": "serial",
+	"class":    "bDeviceClass",
+	"device":   "idProduct",
+	"vendor":   "idVendor",
+	"subclass": "bDeviceSubClass",
+	"protocol": "bDeviceProtocol",
+}
+
+// attachedDevAttrFileMap maps the additional attributes of the attached
+// feature to the corresponding sysfs files.
+var attachedDevAttrFileMap = map[string]string{
+	"manufacturer": "manufacturer",
+	"product":      "product",
+	"speed":        "speed",
+}
+
+// serialFile is the sysfs file of the serial number of a USB device.
+const serialFile = "serial"
+
+// usbDevice holds the discovered information of one physical USB device.
+type usbDevice struct {
+	// id uniquely identifies the device across discovery rounds.
+	id string
+	// attrs are the attributes of the device-level attached feature.
+	attrs map[string]string
+	// instances are the per-class device features.
+	instances []nfdv1alpha1.InstanceFeature
 }
 
 func readSingleUsbSysfsAttribute(path string) (string, error) {
@@ -58,7 +82,7 @@ func readSingleUsbAttribute(devPath string, attrName string) (string, error) {
 }
 
 // Read information of one USB device
-func readUsbDevInfo(devPath string) ([]nfdv1alpha1.InstanceFeature, error) {
+func readUsbDevInfo(devPath string) (*usbDevice, error) {
 	instances := make([]nfdv1alpha1.InstanceFeature, 0)
 	attrs := make(map[string]string)
 
@@ -69,17 +93,26 @@ func readUsbDevInfo(devPath string) ([]nfdv1alpha1.InstanceFeature, error) {
 		}
 	}
 
+	// The serial number is only used for identifying the device and never
+	// published as-is
+	serial, _ := readSingleUsbSysfsAttribute(path.Join(devPath, serialFile))
+
+	interfaces, err := filepath.Glob(devPath + "/*/bInterfaceClass")
+	if err != nil {
+		return nil, err
+	}
+
 	// USB devices encode their class information either at the device or the interface level. If the device class
 	// is set, return as-is.
 	if attrs["class"] != "00" {
-		instances = append(instances, *nfdv1alpha1.NewInstanceFeature(attrs))
+		classAttrs := make(map[string]string, len(attrs)+2)
+		for k, v := range attrs {
+			classAttrs[k] = v
+		}
+		addClassNames(classAttrs)
+		instances = append(instances, *nfdv1alpha1.NewInstanceFeature(classAttrs))
 	} else {
 		// Otherwise, if a 00 is presented at the device level, descend to the interface level.
-		interfaces, err := filepath.Glob(devPath + "/*/bInterfaceClass")
-		if err != nil {
-			return nil, err
-		}
-
 		// A device may, notably, have multiple interfaces with mixed classes, so we create a unique device for each
 		// unique interface class.
 		for _, intf := range interfaces {
@@ -89,21 +122,82 @@ func readUsbDevInfo(devPath string) ([]nfdv1alpha1.InstanceFeature, error) {
 				return nil, err
 			}
 
-			subdevAttrs := make(map[string]string, len(attrs))
+			subdevAttrs := make(map[string]string, len(attrs)+2)
 			for k, v := range attrs {
 				subdevAttrs[k] = v
 			}
 			subdevAttrs["class"] = attrVal
+			delete(subdevAttrs, "subclass")
+			delete(subdevAttrs, "protocol")
+			if val, err := readSingleUsbSysfsAttribute(filepath.Join(filepath.Dir(intf), "bInterfaceSubClass")); err == nil {
+				subdevAttrs["subclass"] = val
+			}
+			if val, err := readSingleUsbSysfsAttribute(filepath.Join(filepath.Dir(intf), "bInterfaceProtocol")); err == nil {
+				subdevAttrs["protocol"] = val
+			}
+			addClassNames(subdevAttrs)
 
 			instances = append(instances, *nfdv1alpha1.NewInstanceFeature(subdevAttrs))
 		}
 	}
 
-	return instances, nil
+	dev := &usbDevice{
+		id:        strings.Join([]string{filepath.Base(devPath), attrs["vendor"], attrs["device"], serial}, "/"),
+		attrs:     attachedDevAttrs(devPath, attrs, serial, interfaces),
+		instances: instances,
+	}
+
+	return dev, nil
+}
+
+// attachedDevAttrs returns the attributes of the attached feature of one
+// physical USB device. The serial number is published only as a salted hash,
+// and only if the feature hash salt has been configured, so that the same
+// device can be identified without exposing the serial number.
+func attachedDevAttrs(devPath string, attrs map[string]string, serial string, interfaces []string) map[string]string {
+	attached := map[string]string{"busid": filepath.Base(devPath)}
+	for k, v := range attrs {
+		attached[k] = v
+	}
+	if serial != "" {
+		if h, ok := source.HashFeatureValue(serial); ok {
+			attached["serial_hash"] = h
+		}
+	}
+	addClassNames(attached)
+
+	for attr, file := range attachedDevAttrFileMap {
+		if val, err := readSingleUsbSysfsAttribute(path.Join(devPath, file)); err == nil && len(val) > 0 {
+			attached[attr] = val
+		}
+	}
+
+	if len(interfaces) > 0 {
+		names := make([]string, 0, len(interfaces))
+		seen := map[string]bool{}
+		for _, intf := range interfaces {
+			class, err := readSingleUsbSysfsAttribute(intf)
+			if err != nil {
+				continue
+			}
+			name, ok := usbClassNames[class]
+			if !ok {
+				name = class
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		attached["interface_classes"] = strings.Join(names, ",")
+	}
+
+	return attached
 }
 
 // detectUsb detects available USB devices and retrieves their device attributes.
-func detectUsb() ([]nfdv1alpha1.InstanceFeature, error) {
+func detectUsb() ([]*usbDevice, error) {
 	// Unlike PCI, the USB sysfs interface includes entries not just for
 	// devices. We work around this by globbing anything that includes a
 	// valid product ID.
@@ -114,15 +208,15 @@ func detectUsb() ([]nfdv1alpha1.InstanceFeature, error) {
 	}
 
 	// Iterate over devices
-	devInfo := make([]nfdv1alpha1.InstanceFeature, 0)
+	devInfo := make([]*usbDevice, 0, len(devPaths))
 	for _, devPath := range devPaths {
-		devs, err := readUsbDevInfo(filepath.Dir(devPath))
+		dev, err := readUsbDevInfo(filepath.Dir(devPath))
 		if err != nil {
 			klog.ErrorS(err, "failed to read USB device info")
 			continue
 		}
 
-		devInfo = append(devInfo, devs...)
+		devInfo = append(devInfo, dev)
 	}
 
 	return devInfo, nil
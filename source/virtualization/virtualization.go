@@ -0,0 +1,249 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virtualization
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	nfdv1alpha1 "github.com/openshift/node-feature-discovery/pkg/apis/nfd/v1alpha1"
+	"github.com/openshift/node-feature-discovery/pkg/utils"
+	"github.com/openshift/node-feature-discovery/pkg/utils/hostpath"
+	"github.com/openshift/node-feature-discovery/source"
+)
+
+// Name of this feature source
+const Name = "virtualization"
+
+const (
+	// KvmFeature holds the presence and permissions of the kvm device.
+	KvmFeature = "kvm"
+	// ExtensionsFeature holds the hardware virtualization extensions of the
+	// cpu and whether they are usable, i.e. enabled in firmware.
+	ExtensionsFeature = "extensions"
+	// NestedFeature holds the nested virtualization support of kvm.
+	NestedFeature = "nested"
+	// MdevFeature holds the mediated device types available on the node.
+	MdevFeature = "mdev"
+)
+
+// kvmTypes are the types of the attributes of the kvm feature.
+var kvmTypes = map[string]nfdv1alpha1.AttributeType{
+	"present":          nfdv1alpha1.AttributeTypeBool,
+	"world_accessible": nfdv1alpha1.AttributeTypeBool,
+}
+
+// nestedTypes are the types of the attributes of the nested feature.
+var nestedTypes = map[string]nfdv1alpha1.AttributeType{
+	"enabled": nfdv1alpha1.AttributeTypeBool,
+}
+
+// kvmModules maps the cpu virtualization extension flags to the
+// corresponding vendor specific kvm modules.
+var kvmModules = map[string]string{
+	"vmx": "kvm_intel",
+	"svm": "kvm_amd",
+}
+
+// virtualizationSource implements the FeatureSource interface.
+type virtualizationSource struct {
+	features *nfdv1alpha1.Features
+}
+
+// Singleton source instance
+var (
+	src                      = virtualizationSource{}
+	_   source.FeatureSource = &src
+)
+
+// Name returns the name of the feature source
+func (s *virtualizationSource) Name() string { return Name }
+
+// Discover method of the FeatureSource interface
+func (s *virtualizationSource) Discover() error {
+	s.features = nfdv1alpha1.NewFeatures()
+
+	kvm := discoverKvm()
+	s.features.Attributes[KvmFeature] = nfdv1alpha1.NewAttributeFeatures(kvm).WithTypes(kvmTypes)
+
+	ext, err := discoverExtensions(kvm["present"] == "true")
+	if err != nil {
+		klog.ErrorS(err, "failed to detect cpu virtualization extensions")
+	} else {
+		s.features.Attributes[ExtensionsFeature] = nfdv1alpha1.NewAttributeFeatures(ext)
+	}
+
+	s.features.Attributes[NestedFeature] = nfdv1alpha1.NewAttributeFeatures(discoverNested()).WithTypes(nestedTypes)
+
+	mdev, err := discoverMdevTypes()
+	if err != nil {
+		klog.ErrorS(err, "failed to detect mediated device types")
+	} else {
+		s.features.Instances[MdevFeature] = nfdv1alpha1.NewInstanceFeatures(mdev)
+	}
+
+	source.Logger(Name).V(3).Info("discovered features", "featureSource", s.Name(), "features", utils.DelayedDumper(s.features))
+
+	return nil
+}
+
+// GetFeatures method of the FeatureSource Interface
+func (s *virtualizationSource) GetFeatures() *nfdv1alpha1.Features {
+	if s.features == nil {
+		s.features = nfdv1alpha1.NewFeatures()
+	}
+	return s.features
+}
+
+// discoverKvm detects the presence of the kvm device from sysfs and its
+// permissions, if /dev/kvm is mounted into the container.
+func discoverKvm() map[string]string {
+	if _, err := os.Stat(hostpath.SysfsDir.Path("class/misc/kvm")); err != nil {
+		if !os.IsNotExist(err) {
+			klog.ErrorS(err, "failed to detect kvm device")
+		}
+		return map[string]string{"present": "false"}
+	}
+	attrs := map[string]string{"present": "true"}
+
+	fi, err := os.Stat(hostpath.DevDir.Path("kvm"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.ErrorS(err, "failed to stat kvm device")
+		}
+		return attrs
+	}
+	perm := fi.Mode().Perm()
+	attrs["mode"] = fmt.Sprintf("%04o", perm)
+	attrs["world_accessible"] = strconv.FormatBool(perm&0006 == 0006)
+
+	return attrs
+}
+
+// discoverExtensions detects the virtualization extensions advertised by the
+// cpu. The extensions are usable only if the vendor kvm module has been
+// loaded, which fails if they have been disabled in firmware, and the kvm
+// device is present.
+func discoverExtensions(kvmPresent bool) (map[string]string, error) {
+	flags, err := cpuFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]string{"usable": "false"}
+	for flag, module := range kvmModules {
+		if _, ok := flags[flag]; !ok {
+			continue
+		}
+		attrs["cpu_flag"] = flag
+		if _, err := os.Stat(hostpath.SysfsDir.Path("module", module)); err == nil {
+			attrs["module"] = module
+			attrs["usable"] = strconv.FormatBool(kvmPresent)
+		}
+	}
+	return attrs, nil
+}
+
+// cpuFlags returns the flags of the first cpu listed in /proc/cpuinfo.
+func cpuFlags() (map[string]struct{}, error) {
+	f, err := os.Open(hostpath.ProcfsDir.Path("cpuinfo"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	flags := map[string]struct{}{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		for _, flag := range strings.Fields(value) {
+			flags[flag] = struct{}{}
+		}
+		break
+	}
+	return flags, scanner.Err()
+}
+
+// discoverNested detects if nested virtualization has been enabled in the
+// vendor kvm module.
+func discoverNested() map[string]string {
+	attrs := map[string]string{"enabled": "false"}
+	for _, module := range kvmModules {
+		data, err := os.ReadFile(hostpath.SysfsDir.Path("module", module, "parameters/nested"))
+		if err != nil {
+			continue
+		}
+		// The parameter is boolean (Y/N) in kvm_intel and integer in kvm_amd
+		switch strings.TrimSpace(string(data)) {
+		case "Y", "y", "1":
+			attrs["enabled"] = "true"
+		}
+		attrs["module"] = module
+	}
+	return attrs
+}
+
+// discoverMdevTypes detects the mediated device types supported by the
+// parent devices of the node, e.g. vGPUs.
+func discoverMdevTypes() ([]nfdv1alpha1.InstanceFeature, error) {
+	basePath := hostpath.SysfsDir.Path("class/mdev_bus")
+	parents, err := os.ReadDir(basePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []nfdv1alpha1.InstanceFeature{}, nil
+		}
+		return nil, err
+	}
+
+	instances := []nfdv1alpha1.InstanceFeature{}
+	for _, parent := range parents {
+		typesPath := filepath.Join(basePath, parent.Name(), "mdev_supported_types")
+		types, err := os.ReadDir(typesPath)
+		if err != nil {
+			source.Logger(Name).V(2).Info("failed to read mdev types", "device", parent.Name(), "err", err)
+			continue
+		}
+		for _, t := range types {
+			attrs := map[string]string{
+				"parent": parent.Name(),
+				"type":   t.Name(),
+			}
+			for _, attr := range []string{"name", "available_instances", "device_api"} {
+				data, err := os.ReadFile(filepath.Join(typesPath, t.Name(), attr))
+				if err != nil {
+					continue
+				}
+				attrs[attr] = strings.TrimSpace(string(data))
+			}
+			instances = append(instances, *nfdv1alpha1.NewInstanceFeature(attrs))
+		}
+	}
+	return instances, nil
+}
+
+func init() {
+	source.Register(&src)
+}